@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+// sendAccountError 账户和联系人相关错误的 HTTP 状态码
+func sendAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccountNotFound), errors.Is(err, service.ErrContactNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrAccountInUse):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidTimeline):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListAccounts 账户列表（?search= 匹配名称和信用代码）
+func (h *AccountHandler) ListAccounts(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var query dto.AccountQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	accounts, totalPages, total, err := h.accountService.ListAccounts(userID, &query)
+	if err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, accounts, &utils.Meta{
+		Page:       query.Page,
+		PerPage:    query.PerPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// GetAccount 账户详情，包括联系人和客户记录
+func (h *AccountHandler) GetAccount(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	account, err := h.accountService.GetAccount(id, userID)
+	if err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, account)
+}
+
+// CreateAccount 新建账户
+func (h *AccountHandler) CreateAccount(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.CreateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	account, err := h.accountService.CreateAccount(userID, &req)
+	if err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Account created", account)
+}
+
+// UpdateAccount 修改账户
+func (h *AccountHandler) UpdateAccount(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	var req dto.UpdateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	account, err := h.accountService.UpdateAccount(id, userID, &req)
+	if err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Account updated", account)
+}
+
+// DeleteAccount 删除没有客户记录的账户
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	if err := h.accountService.DeleteAccount(id, userID); err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Account deleted", nil)
+}
+
+// GetTimeline 账户时间线（?types=interaction,deal,activity&contact_id=）
+func (h *AccountHandler) GetTimeline(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	var query dto.AccountTimelineQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	items, totalPages, total, err := h.accountService.Timeline(id, userID, &query)
+	if err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, items, &utils.Meta{
+		Page:       query.Page,
+		PerPage:    query.PerPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// ListContacts 账户的联系人
+func (h *AccountHandler) ListContacts(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	contacts, err := h.accountService.ListContacts(id, userID)
+	if err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, contacts)
+}
+
+// CreateContact 在账户下新建联系人
+func (h *AccountHandler) CreateContact(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	var req dto.CreateContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	contact, err := h.accountService.CreateContact(id, userID, &req)
+	if err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Contact created", contact)
+}
+
+// UpdateContact 修改联系人（包括角色和主要联系人）
+func (h *AccountHandler) UpdateContact(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid contact ID")
+		return
+	}
+
+	var req dto.UpdateContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	contact, err := h.accountService.UpdateContact(id, userID, &req)
+	if err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Contact updated", contact)
+}
+
+// DeleteContact 删除联系人
+func (h *AccountHandler) DeleteContact(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid contact ID")
+		return
+	}
+
+	if err := h.accountService.DeleteContact(id, userID); err != nil {
+		sendAccountError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Contact deleted", nil)
+}
@@ -1,11 +1,18 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
 	"github.com/xia/nextcrm/internal/dto"
 	"github.com/xia/nextcrm/internal/service"
 	"github.com/xia/nextcrm/pkg/utils"
@@ -21,6 +28,46 @@ func NewAIHandler(aiService *service.AIService) *AIHandler {
 	}
 }
 
+// aiContext 带上当前用户的请求 ctx，用于 AI 用量计量和额度检查
+func aiContext(c *gin.Context) context.Context {
+	userID, _ := middleware.GetUserID(c)
+	return service.WithAIUser(c.Request.Context(), userID)
+}
+
+// analyzeContext 客户分析请求带 refresh 时跳过缓存
+func analyzeContext(c *gin.Context, req *dto.AnalyzeCustomerRequest) context.Context {
+	ctx := aiContext(c)
+	if req.Refresh {
+		ctx = service.WithoutAICache(ctx)
+	}
+	return ctx
+}
+
+// sendAIError 额度用完返回 429，模型输出不合格返回 502，无权访问返回 403，客户不存在返回 404，其余按 500 处理
+func sendAIError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrUnauthorized) {
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+		return
+	}
+	if errors.Is(err, service.ErrCustomerNotFound) {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		utils.SendError(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrInvalidAIOutput) {
+		utils.SendError(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrAIImageBlocked) {
+		utils.SendError(c, http.StatusForbidden, err.Error())
+		return
+	}
+	utils.SendError(c, http.StatusInternalServerError, err.Error())
+}
+
 // GenerateScript handles generating sales scripts
 func (h *AIHandler) GenerateScript(c *gin.Context) {
 	var req dto.GenerateScriptRequest
@@ -29,9 +76,9 @@ func (h *AIHandler) GenerateScript(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.aiService.GenerateScript(&req)
+	resp, err := h.aiService.GenerateScript(aiContext(c), &req)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		sendAIError(c, err)
 		return
 	}
 
@@ -53,18 +100,120 @@ func (h *AIHandler) AnalyzeCustomer(c *gin.Context) {
 		req.AnalysisType = "comprehensive" // Default
 	}
 
-	// Verify customer belongs to user
-	// (This would be handled by the service layer)
+	userID, _ := middleware.GetUserID(c)
+	resp, err := h.aiService.AnalyzeCustomer(analyzeContext(c, &req), customerID, userID, req.AnalysisType)
+	if err != nil {
+		sendAIError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// ListCustomerAnalyses 客户的历次 AI 分析快照（?limit=，默认 20，最多 100）
+func (h *AIHandler) ListCustomerAnalyses(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	customerID, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
 
-	resp, err := h.aiService.AnalyzeCustomer(customerID, req.AnalysisType)
+	resp, err := h.aiService.ListCustomerAnalyses(customerID, userID, limit)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		if err == service.ErrUnauthorized {
+			utils.SendError(c, http.StatusForbidden, "Access denied")
+		} else {
+			utils.SendError(c, http.StatusNotFound, "Customer not found")
+		}
 		return
 	}
 
 	utils.SendSuccess(c, resp)
 }
 
+// NaturalLanguageQuery 自然语言查询：返回模型解析出的结构化查询及其结果
+func (h *AIHandler) NaturalLanguageQuery(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.NaturalLanguageQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	resp, err := h.aiService.NaturalLanguageQuery(aiContext(c), userID, req.Question)
+	if err != nil {
+		sendAIError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// GenerateScriptStream 流式生成话术（SSE）：delta 事件下发话术正文，result 事件下发完整结果
+func (h *AIHandler) GenerateScriptStream(c *gin.Context) {
+	var req dto.GenerateScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	h.streamSSE(c, func(onDelta func(string) error) (interface{}, error) {
+		return h.aiService.GenerateScriptStream(aiContext(c), &req, onDelta)
+	})
+}
+
+// AnalyzeCustomerStream 流式客户分析（SSE）
+func (h *AIHandler) AnalyzeCustomerStream(c *gin.Context) {
+	id := c.Param("id")
+
+	var customerID uint64
+	if _, err := fmt.Sscanf(id, "%d", &customerID); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	var req dto.AnalyzeCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		req.AnalysisType = "comprehensive" // Default
+	}
+
+	// 开始推流前检查归属，无权访问时返回普通的 403
+	userID, _ := middleware.GetUserID(c)
+	if err := h.aiService.CheckCustomerAccess(customerID, userID); err != nil {
+		sendAIError(c, err)
+		return
+	}
+
+	h.streamSSE(c, func(onDelta func(string) error) (interface{}, error) {
+		return h.aiService.AnalyzeCustomerStream(analyzeContext(c, &req), customerID, userID, req.AnalysisType, onDelta)
+	})
+}
+
+// audioFormat 根据 Content-Type 或扩展名判断音频格式，默认 webm
+func audioFormat(fileHeader *multipart.FileHeader) string {
+	contentType := fileHeader.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "audio/webm"):
+		return "webm"
+	case contentType == "audio/mp3", contentType == "audio/mpeg":
+		return "mp3"
+	case contentType == "audio/wav", contentType == "audio/x-wav":
+		return "wav"
+	}
+	switch ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileHeader.Filename), ".")); ext {
+	case "mp3", "wav", "m4a", "ogg", "webm":
+		return ext
+	}
+	return "webm"
+}
+
 // GenerateEmbedding handles generating embeddings
 func (h *AIHandler) GenerateEmbedding(c *gin.Context) {
 	var req dto.GenerateEmbeddingRequest
@@ -73,9 +222,9 @@ func (h *AIHandler) GenerateEmbedding(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.aiService.GenerateEmbedding(req.Text)
+	resp, err := h.aiService.GenerateEmbedding(aiContext(c), req.Text)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		sendAIError(c, err)
 		return
 	}
 
@@ -118,22 +267,15 @@ func (h *AIHandler) SpeechToText(c *gin.Context) {
 	}
 
 	// 检测音频格式
-	format := "webm" // 默认格式
-	contentType := fileHeader.Header.Get("Content-Type")
-	if contentType != "" {
-		// 根据 Content-Type 判断格式
-		if contentType == "audio/webm" || contentType == "audio/webm;codecs=opus" {
-			format = "webm"
-		} else if contentType == "audio/mp3" {
-			format = "mp3"
-		} else if contentType == "audio/wav" {
-			format = "wav"
-		}
-	}
+	format := audioFormat(fileHeader)
 
 	// 调用服务
-	result, err := h.aiService.SpeechToText(audioData, format, language)
+	result, err := h.aiService.SpeechToText(aiContext(c), audioData, format, language)
 	if err != nil {
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			sendAIError(c, err)
+			return
+		}
 		utils.SendError(c, http.StatusInternalServerError, "Speech recognition failed: "+err.Error())
 		return
 	}
@@ -156,15 +298,75 @@ func (h *AIHandler) CustomerIntakeChat(c *gin.Context) {
 		req.CurrentFields = make(map[string]string)
 	}
 
-	resp, err := h.aiService.CustomerIntakeChat(&req)
+	resp, err := h.aiService.CustomerIntakeChat(aiContext(c), &req)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		sendAIError(c, err)
 		return
 	}
 
 	utils.SendSuccess(c, resp)
 }
 
+// CustomerIntakeChatStream 流式新建客户对话（SSE）：delta 事件下发回复文案，
+// result 事件下发 ExtractedFields / Status / Summary
+func (h *AIHandler) CustomerIntakeChatStream(c *gin.Context) {
+	var req dto.CustomerIntakeChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+	if len(req.Messages) == 0 {
+		utils.SendError(c, http.StatusBadRequest, "messages is required")
+		return
+	}
+	if req.CurrentFields == nil {
+		req.CurrentFields = make(map[string]string)
+	}
+
+	h.streamSSE(c, func(onDelta func(string) error) (interface{}, error) {
+		return h.aiService.CustomerIntakeChatStream(aiContext(c), &req, onDelta)
+	})
+}
+
+func (h *AIHandler) streamSSE(c *gin.Context, run func(onDelta func(string) error) (interface{}, error)) {
+	streamSSE(c, h.aiService, run)
+}
+
+// streamSSE 以 Server-Sent Events 输出：每个片段一个 delta 事件，
+// 结束后一个 result 事件（完整结构化结果），出错时一个 error 事件
+func streamSSE(c *gin.Context, aiService *service.AIService, run func(onDelta func(string) error) (interface{}, error)) {
+	// 额度不足时还能返回 429，开始推流后只能发 error 事件
+	if err := aiService.CheckQuota(aiContext(c)); err != nil {
+		sendAIError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	c.Status(http.StatusOK)
+
+	send := func(event string, data interface{}) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	result, err := run(func(delta string) error {
+		// 客户端断开后停止生成
+		if err := c.Request.Context().Err(); err != nil {
+			return err
+		}
+		send("delta", gin.H{"content": delta})
+		return nil
+	})
+	if err != nil {
+		send("error", gin.H{"error": err.Error()})
+		return
+	}
+	send("result", result)
+}
+
 // OCRBusinessCard handles business card OCR
 func (h *AIHandler) OCRBusinessCard(c *gin.Context) {
 	// 解析 multipart form
@@ -195,8 +397,13 @@ func (h *AIHandler) OCRBusinessCard(c *gin.Context) {
 	}
 
 	// 调用服务
-	result, err := h.aiService.RecognizeBusinessCard(imageData)
+	result, err := h.aiService.RecognizeBusinessCard(aiContext(c), imageData)
 	if err != nil {
+		if errors.Is(err, service.ErrQuotaExceeded) || errors.Is(err, service.ErrAIImageBlocked) ||
+			errors.Is(err, service.ErrInvalidAIOutput) {
+			sendAIError(c, err)
+			return
+		}
 		utils.SendError(c, http.StatusInternalServerError, "OCR failed: "+err.Error())
 		return
 	}
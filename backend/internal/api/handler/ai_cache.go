@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type AICacheHandler struct {
+	cacheService *service.AICacheService
+}
+
+func NewAICacheHandler(cacheService *service.AICacheService) *AICacheHandler {
+	return &AICacheHandler{cacheService: cacheService}
+}
+
+// GetStats AI 缓存条目数和命中情况（管理员）
+func (h *AICacheHandler) GetStats(c *gin.Context) {
+	stats, err := h.cacheService.GetStats()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, stats)
+}
+
+// Purge 清空对话缓存，?embeddings=true 时同时清空向量缓存（管理员）
+func (h *AICacheHandler) Purge(c *gin.Context) {
+	deleted, err := h.cacheService.Purge(c.Query("embeddings") == "true")
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "AI cache purged", gin.H{"embeddings_deleted": deleted})
+}
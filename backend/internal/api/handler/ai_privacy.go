@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+	"gorm.io/gorm"
+)
+
+type AIPrivacyHandler struct {
+	privacyService *service.PrivacyService
+}
+
+func NewAIPrivacyHandler(privacyService *service.PrivacyService) *AIPrivacyHandler {
+	return &AIPrivacyHandler{privacyService: privacyService}
+}
+
+// GetPolicies 默认隐私策略和各团队的覆盖（管理员）
+func (h *AIPrivacyHandler) GetPolicies(c *gin.Context) {
+	policies, err := h.privacyService.GetPolicies()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, policies)
+}
+
+// SetPolicy 设置团队的隐私策略（管理员）
+func (h *AIPrivacyHandler) SetPolicy(c *gin.Context) {
+	var req dto.SetAIPrivacyPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	policy, err := h.privacyService.SetPolicy(&req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPrivacyPolicy) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Privacy policy updated successfully", policy)
+}
+
+// DeletePolicy 删除团队的隐私策略，恢复默认配置（管理员）
+func (h *AIPrivacyHandler) DeletePolicy(c *gin.Context) {
+	teamID, ok := parseUint64Param(c, "teamId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	if err := h.privacyService.DeletePolicy(teamID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "Privacy policy not found")
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Privacy policy deleted successfully", nil)
+}
+
+// ListAuditLogs 发往外部模型的请求审计日志，可按用户、团队、功能和日期过滤（管理员）
+func (h *AIPrivacyHandler) ListAuditLogs(c *gin.Context) {
+	var query dto.AIAuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	logs, err := h.privacyService.ListAuditLogs(&query)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, logs)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+	"gorm.io/gorm"
+)
+
+type AIUsageHandler struct {
+	usageService *service.AIUsageService
+}
+
+func NewAIUsageHandler(usageService *service.AIUsageService) *AIUsageHandler {
+	return &AIUsageHandler{usageService: usageService}
+}
+
+// GetMyUsage 当前用户及其团队今日 / 本月的 AI 用量和额度
+func (h *AIUsageHandler) GetMyUsage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	summary, err := h.usageService.GetUsageSummary(userID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, summary)
+}
+
+// GetReport 按团队和功能汇总的 AI 成本报表（管理员）
+func (h *AIUsageHandler) GetReport(c *gin.Context) {
+	var query dto.AIUsageReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	report, err := h.usageService.GetReport(&query)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, report)
+}
+
+// ListQuotas 列出所有用户 / 团队额度覆盖（管理员）
+func (h *AIUsageHandler) ListQuotas(c *gin.Context) {
+	quotas, err := h.usageService.ListQuotas()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, quotas)
+}
+
+// SetQuota 设置用户或团队的额度（管理员）
+func (h *AIUsageHandler) SetQuota(c *gin.Context) {
+	var req dto.SetAIQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	quota, err := h.usageService.SetQuota(&req)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Quota updated successfully", quota)
+}
+
+// DeleteQuota 删除额度覆盖，恢复默认额度（管理员）
+func (h *AIUsageHandler) DeleteQuota(c *gin.Context) {
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid quota ID")
+		return
+	}
+
+	if err := h.usageService.DeleteQuota(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "Quota not found")
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Quota deleted successfully", nil)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type BulkHandler struct {
+	bulkService *service.BulkService
+}
+
+func NewBulkHandler(bulkService *service.BulkService) *BulkHandler {
+	return &BulkHandler{bulkService: bulkService}
+}
+
+// sendBulkError 批量操作相关错误的 HTTP 状态码
+func sendBulkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrBulkJobNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidBulk), errors.Is(err, service.ErrInvalidTag):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	case isSegmentError(err):
+		sendSegmentError(c, err)
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// SubmitBulk 客户批量操作；选中的客户较少时直接返回逐条结果，否则返回后台任务，之后轮询进度
+func (h *BulkHandler) SubmitBulk(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.BulkCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	job, err := h.bulkService.Submit(userID, &req)
+	if err != nil {
+		sendBulkError(c, err)
+		return
+	}
+
+	if job.Inline {
+		utils.SendSuccessWithMessage(c, "Bulk operation completed", job)
+		return
+	}
+	utils.SendSuccessWithMessage(c, "Bulk operation queued", job)
+}
+
+// ListBulkJobs 当前用户最近的批量操作
+func (h *BulkHandler) ListBulkJobs(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var query dto.BulkJobListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	jobs, err := h.bulkService.ListJobs(userID, query.Limit)
+	if err != nil {
+		sendBulkError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, jobs)
+}
+
+// GetBulkJob 批量操作的进度和逐条结果
+func (h *BulkHandler) GetBulkJob(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "jobId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := h.bulkService.GetJob(id, userID)
+	if err != nil {
+		sendBulkError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, job)
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type CallRecordingHandler struct {
+	callService *service.CallRecordingService
+}
+
+func NewCallRecordingHandler(callService *service.CallRecordingService) *CallRecordingHandler {
+	return &CallRecordingHandler{callService: callService}
+}
+
+// sendCallRecordingError 通话录音相关错误的 HTTP 状态码
+func sendCallRecordingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrCustomerNotFound), errors.Is(err, service.ErrCallRecordingNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCallRecordingNotPending):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrEmptyTranscript):
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		sendAIError(c, err)
+	}
+}
+
+// UploadRecording 上传通话录音（multipart: audio, language），返回转写、总结和跟进记录草稿
+func (h *CallRecordingHandler) UploadRecording(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	customerID, ok := parseUint64Param(c, "customerId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	fileHeader, err := c.FormFile("audio")
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid audio file: "+err.Error())
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to open audio file: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	audioData, err := io.ReadAll(file)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to read audio file: "+err.Error())
+		return
+	}
+
+	language := c.PostForm("language")
+	if language == "" {
+		language = "zh" // 默认中文
+	}
+
+	resp, err := h.callService.Upload(aiContext(c), userID, customerID, fileHeader.Filename, audioData, audioFormat(fileHeader), language)
+	if err != nil {
+		sendCallRecordingError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Call recording processed, please confirm the interaction", resp)
+}
+
+// ListRecordings 客户的通话录音
+func (h *CallRecordingHandler) ListRecordings(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	customerID, ok := parseUint64Param(c, "customerId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	resp, err := h.callService.ListByCustomer(customerID, userID)
+	if err != nil {
+		sendCallRecordingError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// GetRecording 通话录音详情
+func (h *CallRecordingHandler) GetRecording(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid recording ID")
+		return
+	}
+
+	resp, err := h.callService.GetRecording(id, userID)
+	if err != nil {
+		sendCallRecordingError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// ConfirmRecording 一键确认草稿，创建跟进记录（可选覆盖 content / outcome / next_action / next_date）
+func (h *CallRecordingHandler) ConfirmRecording(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid recording ID")
+		return
+	}
+
+	var req dto.ConfirmCallRecordingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	interaction, err := h.callService.Confirm(id, userID, &req)
+	if err != nil {
+		sendCallRecordingError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Interaction created", interaction)
+}
+
+// DiscardRecording 放弃草稿
+func (h *CallRecordingHandler) DiscardRecording(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid recording ID")
+		return
+	}
+
+	if err := h.callService.Discard(id, userID); err != nil {
+		sendCallRecordingError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Draft discarded", nil)
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type CardScanHandler struct {
+	cardScanService *service.CardScanService
+}
+
+func NewCardScanHandler(cardScanService *service.CardScanService) *CardScanHandler {
+	return &CardScanHandler{cardScanService: cardScanService}
+}
+
+// sendCardScanError 批量名片识别相关错误的 HTTP 状态码
+func sendCardScanError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrCardScanNotFound), errors.Is(err, service.ErrCardItemNotFound),
+		errors.Is(err, service.ErrCustomerNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCardNotReviewable):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrTooManyCards), errors.Is(err, service.ErrCardUploadTooLarge):
+		utils.SendError(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, service.ErrNoCardImages), errors.Is(err, service.ErrCardNameRequired),
+		errors.Is(err, service.ErrCardNoMatch):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		sendAIError(c, err)
+	}
+}
+
+// SubmitCardScan 批量上传名片（multipart: images 可多个，图片或 ZIP），立即返回批次，之后轮询查询进度
+func (h *CardScanHandler) SubmitCardScan(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid upload: "+err.Error())
+		return
+	}
+
+	var images []dto.CardScanImage
+	for _, fileHeader := range form.File["images"] {
+		file, err := fileHeader.Open()
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "Failed to open image file: "+err.Error())
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "Failed to read image file: "+err.Error())
+			return
+		}
+
+		if strings.EqualFold(filepath.Ext(fileHeader.Filename), ".zip") {
+			extracted, err := h.cardScanService.ExtractCardImages(data)
+			if err != nil {
+				if errors.Is(err, service.ErrTooManyCards) || errors.Is(err, service.ErrCardUploadTooLarge) {
+					sendCardScanError(c, err)
+					return
+				}
+				utils.SendError(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			images = append(images, extracted...)
+			continue
+		}
+		images = append(images, dto.CardScanImage{FileName: fileHeader.Filename, Data: data})
+	}
+
+	batch, err := h.cardScanService.Submit(aiContext(c), userID, images)
+	if err != nil {
+		sendCardScanError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Card scan queued", batch)
+}
+
+// ListCardScans 当前用户最近的批次
+func (h *CardScanHandler) ListCardScans(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	batches, err := h.cardScanService.ListBatches(userID, limit)
+	if err != nil {
+		sendCardScanError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, batches)
+}
+
+// GetCardScan 批次进度和名片；?status=review 只返回等待审核的名片
+func (h *CardScanHandler) GetCardScan(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid batch ID")
+		return
+	}
+
+	batch, err := h.cardScanService.GetBatch(id, userID, c.Query("status"))
+	if err != nil {
+		sendCardScanError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, batch)
+}
+
+// ApproveUnmatched 批量确认没有匹配到已有客户的名片
+func (h *CardScanHandler) ApproveUnmatched(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid batch ID")
+		return
+	}
+
+	resp, err := h.cardScanService.ApproveUnmatched(userID, id)
+	if err != nil {
+		sendCardScanError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// ApproveCard 确认名片并创建客户，可带修改后的字段
+func (h *CardScanHandler) ApproveCard(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	batchID, itemID, ok := cardItemParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ApproveCardRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	resp, err := h.cardScanService.Approve(userID, batchID, itemID, &req)
+	if err != nil {
+		sendCardScanError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Customer created", resp)
+}
+
+// MergeCard 把名片合并到已有客户
+func (h *CardScanHandler) MergeCard(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	batchID, itemID, ok := cardItemParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.MergeCardRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	resp, err := h.cardScanService.Merge(userID, batchID, itemID, &req)
+	if err != nil {
+		sendCardScanError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Card merged into customer", resp)
+}
+
+// DiscardCard 丢弃名片
+func (h *CardScanHandler) DiscardCard(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	batchID, itemID, ok := cardItemParams(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.cardScanService.Discard(userID, batchID, itemID)
+	if err != nil {
+		sendCardScanError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Card discarded", item)
+}
+
+// cardItemParams 解析批次 ID 和名片 ID，失败时已写入 400 响应
+func cardItemParams(c *gin.Context) (uint64, uint64, bool) {
+	batchID, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid batch ID")
+		return 0, 0, false
+	}
+	itemID, ok := parseUint64Param(c, "itemId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid card ID")
+		return 0, 0, false
+	}
+	return batchID, itemID, true
+}
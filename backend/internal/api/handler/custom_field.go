@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type CustomFieldHandler struct {
+	customFieldService *service.CustomFieldService
+}
+
+func NewCustomFieldHandler(customFieldService *service.CustomFieldService) *CustomFieldHandler {
+	return &CustomFieldHandler{customFieldService: customFieldService}
+}
+
+// sendCustomFieldError 自定义字段相关错误的 HTTP 状态码
+func sendCustomFieldError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCustomFieldNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrCustomFieldExists):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidCustomField):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// GetDefinitions 当前用户可用的自定义字段（?entity=customer|deal|interaction），用于渲染表单和列表列
+func (h *CustomFieldHandler) GetDefinitions(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	defs, err := h.customFieldService.Definitions(userID, c.DefaultQuery("entity", "customer"))
+	if err != nil {
+		sendCustomFieldError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, defs)
+}
+
+// ListDefinitions 管理端列出定义（?entity= / ?team_id= 过滤）
+func (h *CustomFieldHandler) ListDefinitions(c *gin.Context) {
+	var query dto.CustomFieldListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	defs, err := h.customFieldService.ListDefinitions(&query)
+	if err != nil {
+		sendCustomFieldError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, defs)
+}
+
+// CreateDefinition 新建自定义字段
+func (h *CustomFieldHandler) CreateDefinition(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.CreateCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	def, err := h.customFieldService.CreateDefinition(userID, &req)
+	if err != nil {
+		sendCustomFieldError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Custom field created", def)
+}
+
+// UpdateDefinition 修改自定义字段
+func (h *CustomFieldHandler) UpdateDefinition(c *gin.Context) {
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid custom field ID")
+		return
+	}
+
+	var req dto.UpdateCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	def, err := h.customFieldService.UpdateDefinition(id, &req)
+	if err != nil {
+		sendCustomFieldError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Custom field updated", def)
+}
+
+// DeleteDefinition 删除自定义字段（已保存的取值保留）
+func (h *CustomFieldHandler) DeleteDefinition(c *gin.Context) {
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid custom field ID")
+		return
+	}
+
+	if err := h.customFieldService.DeleteDefinition(id); err != nil {
+		sendCustomFieldError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Custom field deleted", nil)
+}
@@ -1,23 +1,28 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xia/nextcrm/internal/api/middleware"
 	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
 	"github.com/xia/nextcrm/internal/service"
 	"github.com/xia/nextcrm/pkg/utils"
 )
 
 type CustomerHandler struct {
-	customerService *service.CustomerService
+	customerService  *service.CustomerService
+	duplicateService *service.DuplicateService
 }
 
-func NewCustomerHandler(customerService *service.CustomerService) *CustomerHandler {
+func NewCustomerHandler(customerService *service.CustomerService, duplicateService *service.DuplicateService) *CustomerHandler {
 	return &CustomerHandler{
-		customerService: customerService,
+		customerService:  customerService,
+		duplicateService: duplicateService,
 	}
 }
 
@@ -30,14 +35,43 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
 		return
 	}
+	// 通过 API 新建的记录都检查必填自定义字段（nil 表示系统生成的记录，不检查）
+	if req.CustomFields == nil {
+		req.CustomFields = map[string]interface{}{}
+	}
 
 	customer, err := h.customerService.CreateCustomer(userID, &req)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		if err == service.ErrUnauthorized {
+			utils.SendError(c, http.StatusForbidden, "Access denied")
+		} else if errors.Is(err, service.ErrInvalidCustomFieldValue) || errors.Is(err, service.ErrAccountNotFound) || errors.Is(err, service.ErrInvalidTag) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	utils.SendSuccessWithMessage(c, "Customer created successfully", customer)
+	// 查重只提示，不阻止创建；查重失败不影响结果
+	resp := &dto.CreateCustomerResponse{CustomerResponse: customer}
+	duplicates, err := h.duplicateService.Check(userID, &models.Customer{
+		Name:       req.Name,
+		Company:    req.Company,
+		Phone:      req.Phone,
+		Email:      req.Email,
+		WechatID:   req.WechatID,
+		CreditCode: req.CreditCode,
+	}, customer.ID)
+	if err != nil {
+		log.Printf("Duplicate check failed for customer %d: %v", customer.ID, err)
+	}
+	resp.Duplicates = duplicates
+
+	message := "Customer created successfully"
+	if len(duplicates) > 0 {
+		message = fmt.Sprintf("Customer created; %d possible duplicate(s) found", len(duplicates))
+	}
+	utils.SendSuccessWithMessage(c, message, resp)
 }
 
 // GetCustomer handles getting a customer by ID
@@ -73,10 +107,19 @@ func (h *CustomerHandler) ListCustomers(c *gin.Context) {
 		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
 		return
 	}
+	query.CustomFields = c.QueryMap("cf")
 
 	customers, totalPages, total, err := h.customerService.ListCustomers(userID, &query)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, service.ErrInvalidCustomFieldValue) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		} else if isViewError(err) {
+			sendViewError(c, err)
+		} else if isSegmentError(err) {
+			sendSegmentError(c, err)
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
@@ -111,6 +154,8 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 	if err != nil {
 		if err == service.ErrUnauthorized {
 			utils.SendError(c, http.StatusForbidden, "Access denied")
+		} else if errors.Is(err, service.ErrInvalidCustomFieldValue) || errors.Is(err, service.ErrAccountNotFound) || errors.Is(err, service.ErrInvalidTag) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
 		} else {
 			utils.SendError(c, http.StatusInternalServerError, err.Error())
 		}
@@ -225,10 +270,17 @@ func (h *CustomerHandler) ListArchivedCustomers(c *gin.Context) {
 		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
 		return
 	}
+	query.CustomFields = c.QueryMap("cf")
 
 	customers, totalPages, total, err := h.customerService.ListArchivedCustomers(userID, &query)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, service.ErrInvalidCustomFieldValue) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		} else if isViewError(err) {
+			sendViewError(c, err)
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
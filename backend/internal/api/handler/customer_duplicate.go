@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type DuplicateHandler struct {
+	duplicateService *service.DuplicateService
+}
+
+func NewDuplicateHandler(duplicateService *service.DuplicateService) *DuplicateHandler {
+	return &DuplicateHandler{duplicateService: duplicateService}
+}
+
+// sendDuplicateError 查重与合并相关错误的 HTTP 状态码
+func sendDuplicateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrDuplicateClusterNotFound), errors.Is(err, service.ErrMergeNotFound),
+		errors.Is(err, service.ErrCustomerNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrDuplicateClusterClosed), errors.Is(err, service.ErrMergeUndone),
+		errors.Is(err, service.ErrMergeUndoExpired), errors.Is(err, service.ErrMergeSurvivorGone):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidMerge):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// CheckDuplicates 新建 / 编辑客户时实时查重
+func (h *DuplicateHandler) CheckDuplicates(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.CheckDuplicatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	matches, err := h.duplicateService.CheckRequest(userID, &req)
+	if err != nil {
+		sendDuplicateError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, matches)
+}
+
+// ListClusters 未处理的疑似重复客户组
+func (h *DuplicateHandler) ListClusters(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	clusters, err := h.duplicateService.ListClusters(userID, limit)
+	if err != nil {
+		sendDuplicateError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, clusters)
+}
+
+// ScanDuplicates 立即重新扫描当前用户的客户（每晚也会自动扫描）
+func (h *DuplicateHandler) ScanDuplicates(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	clusters, err := h.duplicateService.Scan(userID)
+	if err != nil {
+		sendDuplicateError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, clusters)
+}
+
+// DismissCluster 忽略一组疑似重复客户
+func (h *DuplicateHandler) DismissCluster(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "clusterId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	cluster, err := h.duplicateService.DismissCluster(userID, id)
+	if err != nil {
+		sendDuplicateError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Duplicate cluster dismissed", cluster)
+}
+
+// MergeCustomers 合并客户
+func (h *DuplicateHandler) MergeCustomers(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.MergeCustomersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	resp, err := h.duplicateService.Merge(userID, &req)
+	if err != nil {
+		sendDuplicateError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Customers merged", resp)
+}
+
+// ListMerges 最近的合并记录和撤销截止时间
+func (h *DuplicateHandler) ListMerges(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	merges, err := h.duplicateService.ListMerges(userID, limit)
+	if err != nil {
+		sendDuplicateError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, merges)
+}
+
+// UndoMerge 撤销期内撤销合并
+func (h *DuplicateHandler) UndoMerge(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "mergeId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid merge ID")
+		return
+	}
+
+	resp, err := h.duplicateService.UndoMerge(userID, id)
+	if err != nil {
+		sendDuplicateError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Merge undone", resp)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -37,11 +38,17 @@ func (h *DealHandler) CreateDeal(c *gin.Context) {
 		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
 		return
 	}
+	// 通过 API 新建的记录都检查必填自定义字段（nil 表示系统生成的记录，不检查）
+	if req.CustomFields == nil {
+		req.CustomFields = map[string]interface{}{}
+	}
 
 	deal, err := h.dealService.CreateDeal(userID, &req)
 	if err != nil {
 		if err == service.ErrDealUnauthorized {
 			utils.SendError(c, http.StatusForbidden, "Customer not found or access denied")
+		} else if errors.Is(err, service.ErrInvalidCustomFieldValue) || errors.Is(err, service.ErrInvalidContact) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
 		} else {
 			utils.SendError(c, http.StatusInternalServerError, err.Error())
 		}
@@ -71,6 +78,8 @@ func (h *DealHandler) UpdateDeal(c *gin.Context) {
 			utils.SendError(c, http.StatusNotFound, "Deal not found")
 		} else if err == service.ErrDealUnauthorized {
 			utils.SendError(c, http.StatusForbidden, "Access denied")
+		} else if errors.Is(err, service.ErrInvalidCustomFieldValue) || errors.Is(err, service.ErrInvalidContact) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
 		} else {
 			utils.SendError(c, http.StatusInternalServerError, err.Error())
 		}
@@ -117,12 +126,10 @@ func (h *DealHandler) ListDeals(c *gin.Context) {
 	if query.PerPage < 1 || query.PerPage > 100 {
 		query.PerPage = 20
 	}
-	if query.SortBy == "" {
-		query.SortBy = "deal_at"
-	}
 	if query.SortOrder == "" {
 		query.SortOrder = "desc"
 	}
+	query.CustomFields = c.QueryMap("cf")
 
 	if cid := c.Query("customer_id"); cid != "" {
 		if id, err := strconv.ParseUint(cid, 10, 64); err == nil {
@@ -132,7 +139,13 @@ func (h *DealHandler) ListDeals(c *gin.Context) {
 
 	deals, totalPages, total, err := h.dealService.ListDeals(userID, &query)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, service.ErrInvalidCustomFieldValue) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		} else if isViewError(err) {
+			sendViewError(c, err)
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type DocumentHandler struct {
+	documentService *service.DocumentService
+}
+
+func NewDocumentHandler(documentService *service.DocumentService) *DocumentHandler {
+	return &DocumentHandler{documentService: documentService}
+}
+
+// sendDocumentError 合同 / 发票识别相关错误的 HTTP 状态码
+func sendDocumentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrDocumentNotFound), errors.Is(err, service.ErrCustomerNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrDocumentNotDraft):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrDocumentTooLarge):
+		utils.SendError(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, service.ErrUnsupportedDocument), errors.Is(err, service.ErrDocumentCustomerRequired),
+		errors.Is(err, service.ErrDocumentIncomplete), errors.Is(err, service.ErrUnknownDocumentField):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		sendAIError(c, err)
+	}
+}
+
+// ExtractDocument 上传合同或增值税发票（multipart: file 为图片或 PDF，type、customer_id、party），返回待确认的识别结果
+func (h *DocumentHandler) ExtractDocument(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.ExtractDocumentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	data, fileHeader, ok := readFormFile(c, "file")
+	if !ok {
+		return
+	}
+
+	resp, err := h.documentService.Extract(aiContext(c), userID, fileHeader.Filename, data, &req)
+	if err != nil {
+		sendDocumentError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// ListDocuments 最近的识别结果，可按 customer_id、status 过滤
+func (h *DocumentHandler) ListDocuments(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	customerID, _ := strconv.ParseUint(c.Query("customer_id"), 10, 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	docs, err := h.documentService.List(userID, customerID, c.Query("status"), limit)
+	if err != nil {
+		sendDocumentError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, docs)
+}
+
+// GetDocument 识别结果、确认后将要写入的草稿和需要核对的字段
+func (h *DocumentHandler) GetDocument(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	resp, err := h.documentService.Get(userID, id)
+	if err != nil {
+		sendDocumentError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// ConfirmDocument 确认识别结果（可带修正后的字段）：合同创建成交记录，发票更新客户开票信息
+func (h *DocumentHandler) ConfirmDocument(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	var req dto.ConfirmDocumentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	resp, err := h.documentService.Confirm(userID, id, &req)
+	if err != nil {
+		sendDocumentError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Document confirmed", resp)
+}
+
+// DiscardDocument 放弃识别结果
+func (h *DocumentHandler) DiscardDocument(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	doc, err := h.documentService.Discard(userID, id)
+	if err != nil {
+		sendDocumentError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Document discarded", doc)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type FollowUpHandler struct {
+	followUpService *service.FollowUpService
+}
+
+func NewFollowUpHandler(followUpService *service.FollowUpService) *FollowUpHandler {
+	return &FollowUpHandler{followUpService: followUpService}
+}
+
+// sendFollowUpError 跟进消息草稿相关错误的 HTTP 状态码
+func sendFollowUpError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrCustomerNotFound), errors.Is(err, service.ErrDealNotFound),
+		errors.Is(err, service.ErrFollowUpDraftNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrFollowUpDraftNotPending):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	default:
+		sendAIError(c, err)
+	}
+}
+
+// DraftFollowUp 根据客户历史生成跟进邮件 / 微信消息草稿
+func (h *FollowUpHandler) DraftFollowUp(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	customerID, ok := parseUint64Param(c, "customerId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	var req dto.DraftFollowUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	resp, err := h.followUpService.Draft(aiContext(c), userID, customerID, &req)
+	if err != nil {
+		sendFollowUpError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// ListDrafts 客户的跟进消息草稿
+func (h *FollowUpHandler) ListDrafts(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	customerID, ok := parseUint64Param(c, "customerId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	resp, err := h.followUpService.ListByCustomer(customerID, userID)
+	if err != nil {
+		sendFollowUpError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// GetDraft 草稿详情
+func (h *FollowUpHandler) GetDraft(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid draft ID")
+		return
+	}
+
+	resp, err := h.followUpService.GetDraft(id, userID)
+	if err != nil {
+		sendFollowUpError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// MarkSent 消息发出后保存为跟进记录（可选传入实际发出的 subject / body 及 outcome / next_action / next_date）
+func (h *FollowUpHandler) MarkSent(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid draft ID")
+		return
+	}
+
+	var req dto.MarkFollowUpSentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	interaction, err := h.followUpService.MarkSent(id, userID, &req)
+	if err != nil {
+		sendFollowUpError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Interaction created", interaction)
+}
+
+// DiscardDraft 放弃草稿
+func (h *FollowUpHandler) DiscardDraft(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid draft ID")
+		return
+	}
+
+	if err := h.followUpService.Discard(id, userID); err != nil {
+		sendFollowUpError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Draft discarded", nil)
+}
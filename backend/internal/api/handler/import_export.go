@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -71,7 +72,7 @@ func (h *ImportExportHandler) ImportCustomers(c *gin.Context) {
 		result)
 }
 
-// ExportCustomers exports customers to Excel or CSV
+// ExportCustomers exports customers to Excel or CSV (?segment_id= 只导出分群中的客户)
 func (h *ImportExportHandler) ExportCustomers(c *gin.Context) {
 	userID, _ := middleware.GetUserID(c)
 
@@ -85,17 +86,31 @@ func (h *ImportExportHandler) ExportCustomers(c *gin.Context) {
 		return
 	}
 
+	var segmentID uint64
+	if raw := c.Query("segment_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid segment ID")
+			return
+		}
+		segmentID = id
+	}
+
 	var fileData []byte
 	var filename string
 	var err error
 
 	if format == "xlsx" {
-		fileData, filename, err = h.importExportService.ExportCustomersToExcel(userID)
+		fileData, filename, err = h.importExportService.ExportCustomersToExcel(userID, segmentID)
 	} else {
-		fileData, filename, err = h.importExportService.ExportCustomersToCSV(userID)
+		fileData, filename, err = h.importExportService.ExportCustomersToCSV(userID, segmentID)
 	}
 
 	if err != nil {
+		if isSegmentError(err) {
+			sendSegmentError(c, err)
+			return
+		}
 		utils.SendError(c, http.StatusInternalServerError, "导出失败: "+err.Error())
 		return
 	}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type IntakeHandler struct {
+	intakeService *service.IntakeService
+	aiService     *service.AIService
+}
+
+func NewIntakeHandler(intakeService *service.IntakeService, aiService *service.AIService) *IntakeHandler {
+	return &IntakeHandler{intakeService: intakeService, aiService: aiService}
+}
+
+// sendIntakeError 新建客户对话会话相关错误的 HTTP 状态码；疑似重复时附带匹配到的客户
+func sendIntakeError(c *gin.Context, err error) {
+	var dup *service.DuplicateCustomerError
+	switch {
+	case errors.As(err, &dup):
+		c.JSON(http.StatusConflict, utils.Response{
+			Success: false,
+			Error:   err.Error(),
+			Data:    gin.H{"duplicates": dup.Matches},
+		})
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrIntakeSessionNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrIntakeSessionExpired):
+		utils.SendError(c, http.StatusGone, err.Error())
+	case errors.Is(err, service.ErrIntakeSessionConfirmed):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrIntakeNotReady), errors.Is(err, service.ErrIntakeNothingRecognized):
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		sendAIError(c, err)
+	}
+}
+
+// StartSession 开始新建客户对话，可带第一条消息和已知字段
+func (h *IntakeHandler) StartSession(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.StartIntakeSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	resp, err := h.intakeService.Start(aiContext(c), userID, &req)
+	if err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// ListSessions 进行中的会话
+func (h *IntakeHandler) ListSessions(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	sessions, err := h.intakeService.List(userID)
+	if err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, sessions)
+}
+
+// GetSession 会话详情：消息历史、已提取字段和状态
+func (h *IntakeHandler) GetSession(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	session, err := h.intakeService.Get(userID, id)
+	if err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, session)
+}
+
+// SendMessage 发送一条消息
+func (h *IntakeHandler) SendMessage(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	var req dto.IntakeMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	resp, err := h.intakeService.Send(aiContext(c), userID, id, req.Content)
+	if err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// SendMessageStream 流式发送消息（SSE）：delta 事件下发回复文案，result 事件下发会话
+func (h *IntakeHandler) SendMessageStream(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	var req dto.IntakeMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+	// 会话不存在、已过期等错误在推流前返回对应的状态码
+	if _, err := h.intakeService.Get(userID, id); err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	streamSSE(c, h.aiService, func(onDelta func(string) error) (interface{}, error) {
+		return h.intakeService.SendStream(aiContext(c), userID, id, req.Content, onDelta)
+	})
+}
+
+// AttachBusinessCard 上传名片（multipart 字段 image），识别结果补充到会话
+func (h *IntakeHandler) AttachBusinessCard(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	imageData, _, ok := readFormFile(c, "image")
+	if !ok {
+		return
+	}
+
+	resp, err := h.intakeService.AttachBusinessCard(aiContext(c), userID, id, imageData)
+	if err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// AttachVoice 上传语音（multipart 字段 audio，可选 language），转写后作为消息发送
+func (h *IntakeHandler) AttachVoice(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	audioData, fileHeader, ok := readFormFile(c, "audio")
+	if !ok {
+		return
+	}
+	language := c.PostForm("language")
+	if language == "" {
+		language = "zh"
+	}
+
+	resp, err := h.intakeService.AttachVoice(aiContext(c), userID, id, audioData, audioFormat(fileHeader), language)
+	if err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
+
+// Confirm 确认创建客户；疑似重复时返回 409 和匹配的客户，带 force 再次确认可继续创建
+func (h *IntakeHandler) Confirm(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	var req dto.ConfirmIntakeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	resp, err := h.intakeService.Confirm(userID, id, &req)
+	if err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Customer created", resp)
+}
+
+// CancelSession 放弃会话
+func (h *IntakeHandler) CancelSession(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	if err := h.intakeService.Cancel(userID, id); err != nil {
+		sendIntakeError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Session cancelled", nil)
+}
+
+// readFormFile 读取 multipart 文件字段，失败时已写入 400 / 500 响应
+func readFormFile(c *gin.Context, field string) ([]byte, *multipart.FileHeader, bool) {
+	fileHeader, err := c.FormFile(field)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid "+field+" file: "+err.Error())
+		return nil, nil, false
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to open "+field+" file: "+err.Error())
+		return nil, nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to read "+field+" file: "+err.Error())
+		return nil, nil, false
+	}
+	return data, fileHeader, true
+}
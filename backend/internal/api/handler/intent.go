@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type IntentHandler struct {
+	intentService *service.IntentService
+}
+
+func NewIntentHandler(intentService *service.IntentService) *IntentHandler {
+	return &IntentHandler{intentService: intentService}
+}
+
+// sendIntentError 跟进信号和意向建议相关错误的 HTTP 状态码
+func sendIntentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrCustomerNotFound), errors.Is(err, service.ErrInteractionNotFound),
+		errors.Is(err, service.ErrIntentProposalNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrIntentProposalNotPending):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	default:
+		sendAIError(c, err)
+	}
+}
+
+// AnalyzeInteraction 立即（重新）提取一条跟进记录的信号
+func (h *IntentHandler) AnalyzeInteraction(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid interaction ID")
+		return
+	}
+
+	signals, err := h.intentService.AnalyzeInteraction(aiContext(c), id, userID)
+	if err != nil {
+		sendIntentError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, signals)
+}
+
+// ListProposals 客户的意向等级 / 潜力评分建议
+func (h *IntentHandler) ListProposals(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	customerID, ok := parseUint64Param(c, "customerId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	proposals, err := h.intentService.ListProposals(customerID, userID, limit)
+	if err != nil {
+		sendIntentError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, proposals)
+}
+
+// AcceptProposal 采纳建议，更新客户的意向等级和潜力评分
+func (h *IntentHandler) AcceptProposal(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	proposal, err := h.intentService.Accept(id, userID)
+	if err != nil {
+		sendIntentError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Customer intent updated", proposal)
+}
+
+// RejectProposal 拒绝建议
+func (h *IntentHandler) RejectProposal(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid proposal ID")
+		return
+	}
+
+	proposal, err := h.intentService.Reject(id, userID)
+	if err != nil {
+		sendIntentError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Proposal rejected", proposal)
+}
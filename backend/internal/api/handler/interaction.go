@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -31,6 +32,10 @@ func (h *InteractionHandler) CreateInteraction(c *gin.Context) {
 		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
 		return
 	}
+	// 通过 API 新建的记录都检查必填自定义字段（nil 表示系统生成的记录，不检查）
+	if req.CustomFields == nil {
+		req.CustomFields = map[string]interface{}{}
+	}
 
 	// If customer_id is in URL path, use it
 	if customerID := c.Param("customerId"); customerID != "" {
@@ -42,7 +47,11 @@ func (h *InteractionHandler) CreateInteraction(c *gin.Context) {
 
 	interaction, err := h.interactionService.CreateInteraction(userID, &req)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, service.ErrInvalidCustomFieldValue) || errors.Is(err, service.ErrInvalidContact) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
@@ -60,9 +69,22 @@ func (h *InteractionHandler) GetInteractionsByCustomerID(c *gin.Context) {
 		return
 	}
 
-	interactions, err := h.interactionService.GetInteractionsByCustomerID(id, userID)
+	var query dto.InteractionListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	query.CustomFields = c.QueryMap("cf")
+
+	interactions, err := h.interactionService.GetInteractionsByCustomerID(id, userID, &query)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, service.ErrInvalidCustomFieldValue) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		} else if isViewError(err) {
+			sendViewError(c, err)
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
@@ -114,6 +136,8 @@ func (h *InteractionHandler) UpdateInteraction(c *gin.Context) {
 	if err != nil {
 		if err == service.ErrInteractionNotFound || err == service.ErrUnauthorized {
 			utils.SendError(c, http.StatusNotFound, "Interaction not found")
+		} else if errors.Is(err, service.ErrInvalidCustomFieldValue) || errors.Is(err, service.ErrInvalidContact) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
 		} else {
 			utils.SendError(c, http.StatusInternalServerError, err.Error())
 		}
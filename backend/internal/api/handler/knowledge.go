@@ -76,7 +76,11 @@ func (h *KnowledgeHandler) ListKnowledge(c *gin.Context) {
 
 	knowledges, totalPages, total, err := h.knowledgeService.ListKnowledge(userID, &query)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		if isViewError(err) {
+			sendViewError(c, err)
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
@@ -156,7 +160,7 @@ func (h *KnowledgeHandler) SearchKnowledge(c *gin.Context) {
 
 	results, err := h.knowledgeService.SearchKnowledge(userID, &req)
 	if err != nil {
-		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		sendAIError(c, err)
 		return
 	}
 
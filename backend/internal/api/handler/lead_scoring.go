@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type LeadScoringHandler struct {
+	leadScoringService *service.LeadScoringService
+}
+
+func NewLeadScoringHandler(leadScoringService *service.LeadScoringService) *LeadScoringHandler {
+	return &LeadScoringHandler{leadScoringService: leadScoringService}
+}
+
+// sendLeadScoringError 线索评分相关错误的 HTTP 状态码
+func sendLeadScoringError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrCustomerNotFound), errors.Is(err, service.ErrLeadScoreNotFound),
+		errors.Is(err, service.ErrLeadScoringModelNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrNotEnoughTrainingData):
+		utils.SendError(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, "Lead scoring failed")
+	}
+}
+
+// ListScores 当前用户的未结客户按模型评分排序（?limit=，默认 50，最多 200）
+func (h *LeadScoringHandler) ListScores(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	scores, err := h.leadScoringService.ListScores(userID, limit)
+	if err != nil {
+		sendLeadScoringError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, scores)
+}
+
+// GetCustomerScore 客户的模型评分及各特征贡献
+func (h *LeadScoringHandler) GetCustomerScore(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	customerID, ok := parseUint64Param(c, "customerId")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	score, err := h.leadScoringService.GetScore(customerID, userID)
+	if err != nil {
+		sendLeadScoringError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, score)
+}
+
+// GetModel 当前模型的参数和留出集指标（管理员）
+func (h *LeadScoringHandler) GetModel(c *gin.Context) {
+	model, err := h.leadScoringService.LatestModel()
+	if err != nil {
+		sendLeadScoringError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, model)
+}
+
+// Train 立即重新训练并打分，不必等夜间任务（管理员）
+func (h *LeadScoringHandler) Train(c *gin.Context) {
+	model, scored, err := h.leadScoringService.Run()
+	if err != nil {
+		sendLeadScoringError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Lead scoring model trained", &dto.LeadScoringRunResponse{Model: model, Scored: scored})
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type NextActionHandler struct {
+	nextActionService *service.NextActionService
+}
+
+func NewNextActionHandler(nextActionService *service.NextActionService) *NextActionHandler {
+	return &NextActionHandler{nextActionService: nextActionService}
+}
+
+// sendNextActionError 行动清单相关错误的 HTTP 状态码
+func sendNextActionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrNextActionNotFound), errors.Is(err, service.ErrInteractionNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrNextActionNotPending):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	default:
+		sendAIError(c, err)
+	}
+}
+
+// GetToday 当天的行动清单（当天第一次查看时生成）
+func (h *NextActionHandler) GetToday(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	actions, err := h.nextActionService.Today(aiContext(c), userID)
+	if err != nil {
+		sendNextActionError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, actions)
+}
+
+// Refresh 重新生成当天的行动清单
+func (h *NextActionHandler) Refresh(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	actions, err := h.nextActionService.Refresh(aiContext(c), userID)
+	if err != nil {
+		sendNextActionError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, actions)
+}
+
+// Accept 采纳建议并生成待办（可选传入调整后的 action / due_date）
+func (h *NextActionHandler) Accept(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid next action ID")
+		return
+	}
+
+	var req dto.AcceptNextActionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	task, err := h.nextActionService.Accept(id, userID, &req)
+	if err != nil {
+		sendNextActionError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Task created", task)
+}
+
+// Dismiss 忽略建议（可选传入原因）
+func (h *NextActionHandler) Dismiss(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid next action ID")
+		return
+	}
+
+	var req dto.DismissNextActionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	if err := h.nextActionService.Dismiss(id, userID, req.Reason); err != nil {
+		sendNextActionError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Next action dismissed", nil)
+}
+
+// GetReport 最近 N 天建议的采纳情况（?days=，默认 30），按排名、来源和信号汇总（管理员）
+func (h *NextActionHandler) GetReport(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+
+	report, err := h.nextActionService.Report(days)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, report)
+}
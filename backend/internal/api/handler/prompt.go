@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type PromptHandler struct {
+	promptService *service.PromptService
+}
+
+func NewPromptHandler(promptService *service.PromptService) *PromptHandler {
+	return &PromptHandler{promptService: promptService}
+}
+
+// ListPrompts 列出所有提示词模板的生效版本（?team_id= 查看团队覆盖）
+func (h *PromptHandler) ListPrompts(c *gin.Context) {
+	var query dto.PromptTeamQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	prompts, err := h.promptService.ListPrompts(query.TeamID)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, prompts)
+}
+
+// GetPrompt 模板详情和所有版本
+func (h *PromptHandler) GetPrompt(c *gin.Context) {
+	var query dto.PromptTeamQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	prompt, err := h.promptService.GetPrompt(c.Param("key"), query.TeamID)
+	if err != nil {
+		sendPromptError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, prompt)
+}
+
+// CreateVersion 提交模板新版本
+func (h *PromptHandler) CreateVersion(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.CreatePromptVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	tpl, err := h.promptService.CreateVersion(userID, c.Param("key"), &req)
+	if err != nil {
+		sendPromptError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Prompt version created successfully", tpl)
+}
+
+// PinVersion 固定 / 回滚到某个版本
+func (h *PromptHandler) PinVersion(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.PinPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	if err := h.promptService.Pin(userID, c.Param("key"), &req); err != nil {
+		sendPromptError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Prompt version pinned successfully", nil)
+}
+
+// Unpin 取消固定，恢复使用最新版本
+func (h *PromptHandler) Unpin(c *gin.Context) {
+	var query dto.PromptTeamQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	if err := h.promptService.Unpin(c.Param("key"), query.TeamID); err != nil {
+		sendPromptError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Prompt unpinned successfully", nil)
+}
+
+func sendPromptError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPromptNotFound):
+		utils.SendError(c, http.StatusNotFound, "Prompt template not found")
+	case errors.Is(err, service.ErrPromptVersionNotFound):
+		utils.SendError(c, http.StatusNotFound, "Prompt template version not found")
+	case errors.Is(err, service.ErrPromptInvalid):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
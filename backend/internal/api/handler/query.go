@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type QueryHandler struct {
+	queryService *service.QueryService
+}
+
+func NewQueryHandler(queryService *service.QueryService) *QueryHandler {
+	return &QueryHandler{queryService: queryService}
+}
+
+// ListFields 各实体可用于过滤和排序的字段
+func (h *QueryHandler) ListFields(c *gin.Context) {
+	utils.SendSuccess(c, h.queryService.Fields())
+}
+
+// RunQuery 执行结构化查询（如自然语言查询返回的 query，检查或修改后重跑）
+func (h *QueryHandler) RunQuery(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var spec dto.QuerySpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	resp, err := h.queryService.Run(userID, &spec)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidQuery) {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, resp)
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type SegmentHandler struct {
+	segmentService *service.SegmentService
+}
+
+func NewSegmentHandler(segmentService *service.SegmentService) *SegmentHandler {
+	return &SegmentHandler{segmentService: segmentService}
+}
+
+// isSegmentError 是否为分群相关错误（导出、批量操作等以分群为目标时使用）
+func isSegmentError(err error) bool {
+	return errors.Is(err, service.ErrSegmentNotFound) ||
+		errors.Is(err, service.ErrInvalidSegment) ||
+		errors.Is(err, service.ErrUnauthorized)
+}
+
+// sendSegmentError 分群相关错误的 HTTP 状态码
+func sendSegmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSegmentNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrInvalidSegment):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListSegments 自己的和同团队共享的分群，带当前客户数
+func (h *SegmentHandler) ListSegments(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	segments, err := h.segmentService.ListSegments(userID)
+	if err != nil {
+		sendSegmentError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, segments)
+}
+
+// GetSegment 分群详情
+func (h *SegmentHandler) GetSegment(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid segment ID")
+		return
+	}
+
+	segment, err := h.segmentService.GetSegment(id, userID)
+	if err != nil {
+		sendSegmentError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, segment)
+}
+
+// CreateSegment 保存分群
+func (h *SegmentHandler) CreateSegment(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.CreateSegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	segment, err := h.segmentService.CreateSegment(userID, &req)
+	if err != nil {
+		sendSegmentError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Segment created", segment)
+}
+
+// UpdateSegment 修改分群
+func (h *SegmentHandler) UpdateSegment(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid segment ID")
+		return
+	}
+
+	var req dto.UpdateSegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	segment, err := h.segmentService.UpdateSegment(id, userID, &req)
+	if err != nil {
+		sendSegmentError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Segment updated", segment)
+}
+
+// DeleteSegment 删除分群
+func (h *SegmentHandler) DeleteSegment(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid segment ID")
+		return
+	}
+
+	if err := h.segmentService.DeleteSegment(id, userID); err != nil {
+		sendSegmentError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Segment deleted", nil)
+}
+
+// PreviewSegment 保存前预览过滤条件匹配的客户数
+func (h *SegmentHandler) PreviewSegment(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.SegmentPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	count, err := h.segmentService.Preview(userID, req.Filter)
+	if err != nil {
+		sendSegmentError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, gin.H{"count": count})
+}
+
+// ListSegmentCustomers 分群中的客户（分页），也是群发等操作的收件人列表
+func (h *SegmentHandler) ListSegmentCustomers(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid segment ID")
+		return
+	}
+
+	var query dto.SegmentCustomersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	customers, totalPages, total, err := h.segmentService.Customers(id, userID, &query)
+	if err != nil {
+		sendSegmentError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, customers, &utils.Meta{
+		Page:       query.Page,
+		PerPage:    query.PerPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
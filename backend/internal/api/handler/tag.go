@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type TagHandler struct {
+	tagService *service.TagService
+}
+
+func NewTagHandler(tagService *service.TagService) *TagHandler {
+	return &TagHandler{tagService: tagService}
+}
+
+// sendTagError 标签相关错误的 HTTP 状态码
+func sendTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTagGroupNotFound), errors.Is(err, service.ErrTagNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrTagExists):
+		utils.SendError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidTag):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	case isSegmentError(err):
+		sendSegmentError(c, err)
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// GetCatalog 当前用户可用的标签，按分组排列
+func (h *TagHandler) GetCatalog(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	groups, err := h.tagService.Catalog(userID)
+	if err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, groups)
+}
+
+// BulkUpdate 批量给客户（customer_ids 或 segment_id）加上 / 去掉标签
+func (h *TagHandler) BulkUpdate(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.BulkTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.tagService.BulkUpdate(userID, &req)
+	if err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Tags updated", result)
+}
+
+// ListGroups 管理端列出标签分组及标签（?team_id= 过滤）
+func (h *TagHandler) ListGroups(c *gin.Context) {
+	var query dto.TagGroupListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	groups, err := h.tagService.ListGroups(&query)
+	if err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, groups)
+}
+
+// CreateGroup 新建标签分组
+func (h *TagHandler) CreateGroup(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.CreateTagGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	group, err := h.tagService.CreateGroup(userID, &req)
+	if err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Tag group created", group)
+}
+
+// UpdateGroup 修改标签分组
+func (h *TagHandler) UpdateGroup(c *gin.Context) {
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid tag group ID")
+		return
+	}
+
+	var req dto.UpdateTagGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	group, err := h.tagService.UpdateGroup(id, &req)
+	if err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Tag group updated", group)
+}
+
+// DeleteGroup 删除标签分组及其标签
+func (h *TagHandler) DeleteGroup(c *gin.Context) {
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid tag group ID")
+		return
+	}
+
+	if err := h.tagService.DeleteGroup(id); err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Tag group deleted", nil)
+}
+
+// CreateTag 在分组下新建标签
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var req dto.CreateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	tag, err := h.tagService.CreateTag(&req)
+	if err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Tag created", tag)
+}
+
+// UpdateTag 修改标签（改名同步到客户）
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+
+	var req dto.UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	tag, err := h.tagService.UpdateTag(id, &req)
+	if err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Tag updated", tag)
+}
+
+// DeleteTag 删除标签（从客户上一起去掉）
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+
+	if err := h.tagService.DeleteTag(id); err != nil {
+		sendTagError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Tag deleted", nil)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type TeamHandler struct {
+	teamService *service.TeamService
+}
+
+func NewTeamHandler(teamService *service.TeamService) *TeamHandler {
+	return &TeamHandler{teamService: teamService}
+}
+
+// CreateTeam creates a team (admin)
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	var req dto.CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	team, err := h.teamService.CreateTeam(req.Name)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Team created successfully", team)
+}
+
+// ListTeams lists all teams (admin)
+func (h *TeamHandler) ListTeams(c *gin.Context) {
+	teams, err := h.teamService.ListTeams()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, teams)
+}
+
+// AssignUserTeam moves a user into a team (admin)
+func (h *TeamHandler) AssignUserTeam(c *gin.Context) {
+	userID, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req dto.AssignUserTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	if err := h.teamService.AssignUser(userID, req.TeamID); err != nil {
+		if err == service.ErrTeamNotFound {
+			utils.SendError(c, http.StatusNotFound, "Team not found")
+		} else if err == service.ErrUserNotFound {
+			utils.SendError(c, http.StatusNotFound, "User not found")
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "User team updated successfully", nil)
+}
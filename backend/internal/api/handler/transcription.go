@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type TranscriptionHandler struct {
+	transcriptionService *service.TranscriptionService
+}
+
+func NewTranscriptionHandler(transcriptionService *service.TranscriptionService) *TranscriptionHandler {
+	return &TranscriptionHandler{transcriptionService: transcriptionService}
+}
+
+// SubmitTranscription 提交长录音转写任务（multipart: audio, language），立即返回任务，之后轮询查询进度
+func (h *TranscriptionHandler) SubmitTranscription(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	fileHeader, err := c.FormFile("audio")
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid audio file: "+err.Error())
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to open audio file: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	audioData, err := io.ReadAll(file)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to read audio file: "+err.Error())
+		return
+	}
+
+	language := c.PostForm("language")
+	if language == "" {
+		language = "zh" // 默认中文
+	}
+
+	job, err := h.transcriptionService.Submit(aiContext(c), userID, fileHeader.Filename, audioData, audioFormat(fileHeader), language)
+	if err != nil {
+		if errors.Is(err, service.ErrAudioTooLarge) {
+			utils.SendError(c, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		sendAIError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "Transcription job queued", job)
+}
+
+// GetTranscription 查询转写任务状态、进度和结果
+func (h *TranscriptionHandler) GetTranscription(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid transcription job ID")
+		return
+	}
+
+	job, err := h.transcriptionService.GetJob(id, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnauthorized):
+			utils.SendError(c, http.StatusForbidden, "Access denied")
+		case errors.Is(err, service.ErrTranscriptionNotFound):
+			utils.SendError(c, http.StatusNotFound, err.Error())
+		default:
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SendSuccess(c, job)
+}
+
+// ListTranscriptions 当前用户最近的转写任务
+func (h *TranscriptionHandler) ListTranscriptions(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	jobs, err := h.transcriptionService.ListJobs(userID, limit)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, jobs)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/api/middleware"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/utils"
+)
+
+type ViewHandler struct {
+	viewService *service.ViewService
+}
+
+func NewViewHandler(viewService *service.ViewService) *ViewHandler {
+	return &ViewHandler{viewService: viewService}
+}
+
+// isViewError 是否为视图或列表高级过滤（filter / view_id / sort_by）相关错误
+func isViewError(err error) bool {
+	return errors.Is(err, service.ErrViewNotFound) ||
+		errors.Is(err, service.ErrInvalidView) ||
+		errors.Is(err, service.ErrInvalidQuery) ||
+		errors.Is(err, service.ErrUnauthorized)
+}
+
+// sendViewError 视图相关错误的 HTTP 状态码
+func sendViewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrViewNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrUnauthorized):
+		utils.SendError(c, http.StatusForbidden, "Access denied")
+	case errors.Is(err, service.ErrInvalidView), errors.Is(err, service.ErrInvalidQuery):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListViews 自己的和同团队共享的视图（?entity= 过滤）
+func (h *ViewHandler) ListViews(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var query dto.ViewListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	views, err := h.viewService.ListViews(userID, query.Entity)
+	if err != nil {
+		sendViewError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, views)
+}
+
+// GetView 视图详情
+func (h *ViewHandler) GetView(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+
+	view, err := h.viewService.GetView(id, userID)
+	if err != nil {
+		sendViewError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, view)
+}
+
+// CreateView 保存视图
+func (h *ViewHandler) CreateView(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	var req dto.CreateViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	view, err := h.viewService.CreateView(userID, &req)
+	if err != nil {
+		sendViewError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "View created", view)
+}
+
+// UpdateView 修改视图
+func (h *ViewHandler) UpdateView(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+
+	var req dto.UpdateViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	view, err := h.viewService.UpdateView(id, userID, &req)
+	if err != nil {
+		sendViewError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "View updated", view)
+}
+
+// DeleteView 删除视图
+func (h *ViewHandler) DeleteView(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	id, ok := parseUint64Param(c, "id")
+	if !ok {
+		utils.SendError(c, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+
+	if err := h.viewService.DeleteView(id, userID); err != nil {
+		sendViewError(c, err)
+		return
+	}
+
+	utils.SendSuccessWithMessage(c, "View deleted", nil)
+}
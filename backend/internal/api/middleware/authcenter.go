@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xia/nextcrm/internal/models"
 	"github.com/xia/nextcrm/internal/repository"
 	"github.com/xia/nextcrm/pkg/authcenter"
 )
@@ -98,3 +99,17 @@ func GetUser(c *gin.Context) (*interface{}, bool) {
 	}
 	return &user, true
 }
+
+// RequireAdmin only lets ADMIN users through (must run after AuthCenterMiddleware)
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("user")
+		user, ok := value.(*models.User)
+		if !exists || !ok || user.Role != "ADMIN" {
+			c.JSON(403, gin.H{"error": "需要管理员权限"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
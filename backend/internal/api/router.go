@@ -1,15 +1,27 @@
 package api
 
 import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/xia/nextcrm/internal/api/handler"
 	"github.com/xia/nextcrm/internal/api/middleware"
 	"github.com/xia/nextcrm/internal/config"
 	"github.com/xia/nextcrm/internal/repository"
 	"github.com/xia/nextcrm/internal/service"
+	"github.com/xia/nextcrm/pkg/audio"
 	"github.com/xia/nextcrm/pkg/authcenter"
 	"github.com/xia/nextcrm/pkg/deepseek"
 	"github.com/xia/nextcrm/pkg/doubao"
+	"github.com/xia/nextcrm/pkg/llm"
+	"github.com/xia/nextcrm/pkg/openai"
+	"github.com/xia/nextcrm/pkg/replay"
+	"github.com/xia/nextcrm/pkg/volcengine"
 	"gorm.io/gorm"
 )
 
@@ -29,6 +41,29 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	vectorRepo := repository.NewVectorRepository(db)
 	activityRepo := repository.NewActivityRepository(db)
 	dealRepo := repository.NewDealRepository(db)
+	teamRepo := repository.NewTeamRepository(db)
+	aiUsageRepo := repository.NewAIUsageRepository(db)
+	promptRepo := repository.NewPromptRepository(db)
+	customerAnalysisRepo := repository.NewCustomerAnalysisRepository(db)
+	filterRepo := repository.NewFilterRepository(db)
+	callRecordingRepo := repository.NewCallRecordingRepository(db)
+	transcriptionJobRepo := repository.NewTranscriptionJobRepository(db)
+	followUpDraftRepo := repository.NewFollowUpDraftRepository(db)
+	intentProposalRepo := repository.NewIntentProposalRepository(db)
+	leadScoringRepo := repository.NewLeadScoringRepository(db)
+	nextActionRepo := repository.NewNextActionRepository(db)
+	aiPrivacyRepo := repository.NewAIPrivacyRepository(db)
+	aiCacheRepo := repository.NewAICacheRepository(db)
+	intakeSessionRepo := repository.NewIntakeSessionRepository(db)
+	cardScanRepo := repository.NewCardScanRepository(db)
+	documentRepo := repository.NewDocumentExtractionRepository(db)
+	duplicateRepo := repository.NewCustomerDuplicateRepository(db)
+	customFieldRepo := repository.NewCustomFieldRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	segmentRepo := repository.NewSegmentRepository(db)
+	viewRepo := repository.NewViewRepository(db)
+	bulkJobRepo := repository.NewBulkJobRepository(db)
 
 	// Initialize auth-center service
 	authCenterService := authcenter.NewService(&authcenter.Config{
@@ -40,38 +75,158 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 
 	// Initialize services
 	// authService := service.NewAuthService(userRepo, jwtManager) // Disabled - using Auth Center
-	customerService := service.NewCustomerService(customerRepo)
+	// 管理员定义的自定义字段，创建 / 修改 / 导入时校验，列表可按其过滤和排序
+	customFieldService := service.NewCustomFieldService(customFieldRepo, userRepo)
+	// 账户（公司）和联系人：客户归入账户，跟进记录和成交关联账户和具体的联系人
+	accountService := service.NewAccountService(accountRepo)
+	// 客户标签（管理员按团队维护的分组）和分群（保存的过滤条件，可作为导出、批量操作的目标）
+	segmentService := service.NewSegmentService(segmentRepo, filterRepo, userRepo)
+	viewService := service.NewViewService(viewRepo, userRepo)
+	tagService := service.NewTagService(tagRepo, userRepo)
+	tagService.SetSegments(segmentService)
+	customerService := service.NewCustomerService(customerRepo, activityRepo)
+	customerService.SetCustomFields(customFieldService)
+	customerService.SetAccounts(accountService)
+	customerService.SetTags(tagService)
+	customerService.SetSegments(segmentService)
+	customerService.SetViews(viewService)
 	interactionService := service.NewInteractionService(interactionRepo, customerRepo)
+	interactionService.SetCustomFields(customFieldService)
+	interactionService.SetAccounts(accountService)
+	interactionService.SetViews(viewService)
+	bulkService := service.NewBulkService(bulkJobRepo, filterRepo, userRepo, activityRepo, customerService)
+	bulkService.SetTags(tagService)
+	bulkService.SetSegments(segmentService)
+	bulkService.RecoverUnfinished()
 	importExportService := service.NewImportExportService(customerRepo)
-
-	// Initialize DeepSeek client
-	deepseekClient := deepseek.NewClient(
-		cfg.DeepSeek.APIKey,
-		cfg.DeepSeek.BaseURL,
-		cfg.DeepSeek.Model,
-		cfg.DeepSeek.EmbeddingModel,
+	importExportService.SetCustomFields(customFieldService)
+	importExportService.SetAccounts(accountService)
+	importExportService.SetSegments(segmentService)
+	duplicateService := service.NewDuplicateService(
+		customerRepo, duplicateRepo, activityRepo, customerService,
+		time.Duration(cfg.Duplicates.MergeUndoHours)*time.Hour,
 	)
+	if cfg.Duplicates.ScanEnabled {
+		duplicateService.StartNightly(cfg.Duplicates.ScanHour)
+	}
 
-	// Initialize Doubao multimodal client
-	doubaoClient := doubao.NewClient(
-		cfg.Doubao.BaseURL,
-		cfg.Doubao.APIKey,
-		cfg.Doubao.Model,
-	)
+	// Initialize AI provider chains (DeepSeek / Doubao / OpenAI-compatible)
+	llmRouter := setupLLMRouter(cfg)
+
+	// 每次厂商调用都记录用量（用户、功能、厂商、模型、token、耗时、成败）
+	aiUsageService := service.NewAIUsageService(aiUsageRepo, userRepo, cfg.AI)
+	llmRouter.SetObserver(aiUsageService.Record)
+
+	// 发往外部模型前脱敏并记录审计日志；配置有误时拒绝启动，避免未脱敏的数据外发
+	privacyService, err := service.NewPrivacyService(aiPrivacyRepo, userRepo, cfg.AI)
+	if err != nil {
+		log.Fatalf("Invalid AI privacy config: %v", err)
+	}
+
+	// 对话结果按功能缓存，向量持久化缓存；客户、跟进、成交变化时失效
+	aiCacheService := service.NewAICacheService(aiCacheRepo, llmRouter, cfg.AI)
+	customerService.SetChangeObserver(aiCacheService.InvalidateCustomer)
+	interactionService.SetChangeObserver(aiCacheService.InvalidateCustomer)
+	tagService.SetChangeObserver(aiCacheService.InvalidateCustomer)
+	dealService := service.NewDealService(dealRepo, customerRepo)
+	dealService.SetChangeObserver(aiCacheService.InvalidateCustomer)
+	dealService.SetCustomFields(customFieldService)
+	dealService.SetAccounts(accountService)
+	dealService.SetViews(viewService)
 
-	aiService := service.NewAIService(deepseekClient, customerRepo, doubaoClient)
+	promptService := service.NewPromptService(promptRepo, userRepo)
+	queryService := service.NewQueryService(filterRepo)
+	aiService := service.NewAIService(
+		llmRouter, aiUsageService, privacyService, aiCacheService, promptService, queryService,
+		customerRepo, interactionRepo, dealRepo, activityRepo, customerAnalysisRepo,
+		cfg.AI.AnalysisHistoryTokens,
+	)
+	aiService.SetCustomFields(customFieldService)
+	// 火山引擎名片 OCR，图片理解模型都不可用时识别名片
+	if cfg.VolcEngine.AccessKeyID != "" && cfg.VolcEngine.OCR.AppID != "" {
+		ocrClient := volcengine.NewOCRClient(
+			cfg.VolcEngine.AccessKeyID,
+			cfg.VolcEngine.AccessKeySecret,
+			cfg.VolcEngine.Region,
+			cfg.VolcEngine.OCR.AppID,
+		)
+		useFixtures(cfg, "volcengine_ocr", ocrClient)
+		aiService.SetCardOCR(ocrClient)
+	}
+	teamService := service.NewTeamService(teamRepo, userRepo)
 	knowledgeService := service.NewKnowledgeService(knowledgeRepo, vectorRepo, aiService)
+	knowledgeService.SetViews(viewService)
+	callRecordingService := service.NewCallRecordingService(aiService, callRecordingRepo, customerRepo, interactionService)
+	transcriptionService := service.NewTranscriptionService(
+		aiService, transcriptionJobRepo,
+		audio.NewSplitter(cfg.AI.FFmpegPath, cfg.AI.ASRChunkSeconds),
+		cfg.AI.ASRWorkers,
+		time.Duration(cfg.AI.ASRJobTimeoutMinutes)*time.Minute,
+		int64(cfg.AI.ASRMaxUploadMB)<<20,
+	)
+	transcriptionService.RecoverUnfinished()
+	followUpService := service.NewFollowUpService(
+		aiService, followUpDraftRepo, customerRepo, interactionRepo, dealRepo, knowledgeRepo, interactionService,
+	)
+	intentService := service.NewIntentService(aiService, interactionRepo, customerRepo, intentProposalRepo, activityRepo)
+	if cfg.AI.InteractionSignals {
+		interactionService.SetObserver(intentService.OnInteractionSaved)
+	}
+	leadScoringService := service.NewLeadScoringService(
+		leadScoringRepo, customerRepo, cfg.LeadScoring.LostAfterDays, cfg.LeadScoring.MinSamples,
+	)
+	if cfg.LeadScoring.Enabled {
+		leadScoringService.StartNightly(cfg.LeadScoring.Hour)
+	}
+	nextActionService := service.NewNextActionService(
+		aiService, nextActionRepo, customerRepo, interactionRepo, dealRepo, leadScoringRepo, interactionService,
+	)
+	intakeService := service.NewIntakeService(
+		aiService, intakeSessionRepo, customerRepo, customerService,
+		time.Duration(cfg.AI.IntakeSessionTTLMinutes)*time.Minute,
+	)
+	intakeService.StartCleanup()
+	cardScanService := service.NewCardScanService(
+		aiService, cardScanRepo, customerRepo, customerService,
+		cfg.AI.CardScanWorkers,
+		cfg.AI.CardScanMaxCards,
+		int64(cfg.AI.CardScanMaxUploadMB)<<20,
+	)
+	cardScanService.RecoverUnfinished()
+	documentService := service.NewDocumentService(aiService, documentRepo, customerRepo, customerService, dealService)
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authCenterService) // Re-enabled for /auth/me endpoint
-	customerHandler := handler.NewCustomerHandler(customerService)
+	customerHandler := handler.NewCustomerHandler(customerService, duplicateService)
+	duplicateHandler := handler.NewDuplicateHandler(duplicateService)
+	customFieldHandler := handler.NewCustomFieldHandler(customFieldService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	tagHandler := handler.NewTagHandler(tagService)
+	segmentHandler := handler.NewSegmentHandler(segmentService)
+	viewHandler := handler.NewViewHandler(viewService)
+	bulkHandler := handler.NewBulkHandler(bulkService)
 	interactionHandler := handler.NewInteractionHandler(interactionService)
 	importExportHandler := handler.NewImportExportHandler(importExportService)
 	knowledgeHandler := handler.NewKnowledgeHandler(knowledgeService)
 	aiHandler := handler.NewAIHandler(aiService)
+	aiUsageHandler := handler.NewAIUsageHandler(aiUsageService)
+	aiPrivacyHandler := handler.NewAIPrivacyHandler(privacyService)
+	aiCacheHandler := handler.NewAICacheHandler(aiCacheService)
+	teamHandler := handler.NewTeamHandler(teamService)
+	queryHandler := handler.NewQueryHandler(queryService)
+	callRecordingHandler := handler.NewCallRecordingHandler(callRecordingService)
+	transcriptionHandler := handler.NewTranscriptionHandler(transcriptionService)
+	followUpHandler := handler.NewFollowUpHandler(followUpService)
+	intentHandler := handler.NewIntentHandler(intentService)
+	leadScoringHandler := handler.NewLeadScoringHandler(leadScoringService)
+	nextActionHandler := handler.NewNextActionHandler(nextActionService)
+	intakeHandler := handler.NewIntakeHandler(intakeService, aiService)
+	cardScanHandler := handler.NewCardScanHandler(cardScanService)
+	documentHandler := handler.NewDocumentHandler(documentService)
+	promptHandler := handler.NewPromptHandler(promptService)
 	dashboardHandler := handler.NewDashboardHandler(customerRepo)
 	activityHandler := handler.NewActivityHandler(activityRepo, userRepo)
-	dealHandler := handler.NewDealHandler(service.NewDealService(dealRepo, customerRepo))
+	dealHandler := handler.NewDealHandler(dealService)
 	wechatAuthHandler := handler.NewWechatAuthHandler(authCenterService)
 
 	// Auth middleware
@@ -108,6 +263,12 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			protected.GET("/dashboard/revenue-history", activityHandler.GetRevenueHistory)
 			protected.GET("/dashboard/pipeline-risks", activityHandler.GetPipelineRisks)
 
+			// Custom field definitions in effect for the current user (自定义字段)
+			protected.GET("/custom-fields", customFieldHandler.GetDefinitions)
+
+			// Customer tags available to the current user (客户标签)
+			protected.GET("/tags", tagHandler.GetCatalog)
+
 			// Activity routes
 			activities := protected.Group("/activities")
 			{
@@ -148,9 +309,115 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 				customers.GET("/export", importExportHandler.ExportCustomers)
 				customers.GET("/template", importExportHandler.GetImportTemplate)
 
+				// Bulk tagging (批量打标签)
+				customers.POST("/tags/bulk", tagHandler.BulkUpdate)
+
+				// Bulk operations (批量更新、归档、恢复、删除、转移负责人)
+				customers.POST("/bulk", bulkHandler.SubmitBulk)
+				customers.GET("/bulk/jobs", bulkHandler.ListBulkJobs)
+				customers.GET("/bulk/jobs/:jobId", bulkHandler.GetBulkJob)
+
+				// Duplicate detection and merge (客户查重与合并)
+				customers.POST("/duplicates/check", duplicateHandler.CheckDuplicates)
+				customers.GET("/duplicates", duplicateHandler.ListClusters)
+				customers.POST("/duplicates/scan", duplicateHandler.ScanDuplicates)
+				customers.POST("/duplicates/:clusterId/dismiss", duplicateHandler.DismissCluster)
+				customers.POST("/merge", duplicateHandler.MergeCustomers)
+				customers.GET("/merges", duplicateHandler.ListMerges)
+				customers.POST("/merges/:mergeId/undo", duplicateHandler.UndoMerge)
+
 				// Interaction routes (nested under customers)
 				customers.POST("/:customerId/interactions", interactionHandler.CreateInteraction)
 				customers.GET("/:customerId/interactions", interactionHandler.GetInteractionsByCustomerID)
+
+				// Call recordings (通话录音转写 + AI 总结，确认后生成跟进记录)
+				customers.POST("/:customerId/call-recordings", callRecordingHandler.UploadRecording)
+				customers.GET("/:customerId/call-recordings", callRecordingHandler.ListRecordings)
+
+				// Follow-up drafts (AI 跟进邮件 / 微信消息，发出后生成跟进记录)
+				customers.POST("/:customerId/follow-ups", followUpHandler.DraftFollowUp)
+				customers.GET("/:customerId/follow-ups", followUpHandler.ListDrafts)
+
+				// Intent proposals (根据跟进信号建议意向等级 / 潜力评分)
+				customers.GET("/:customerId/intent-proposals", intentHandler.ListProposals)
+
+				// Lead score (基于历史成交训练的模型评分)
+				customers.GET("/:customerId/lead-score", leadScoringHandler.GetCustomerScore)
+			}
+
+			// Account routes (公司账户、联系人和账户时间线)
+			accounts := protected.Group("/accounts")
+			{
+				accounts.GET("", accountHandler.ListAccounts)
+				accounts.POST("", accountHandler.CreateAccount)
+				accounts.GET("/:id", accountHandler.GetAccount)
+				accounts.PUT("/:id", accountHandler.UpdateAccount)
+				accounts.DELETE("/:id", accountHandler.DeleteAccount)
+				accounts.GET("/:id/timeline", accountHandler.GetTimeline)
+				accounts.GET("/:id/contacts", accountHandler.ListContacts)
+				accounts.POST("/:id/contacts", accountHandler.CreateContact)
+			}
+			contacts := protected.Group("/contacts")
+			{
+				contacts.PUT("/:id", accountHandler.UpdateContact)
+				contacts.DELETE("/:id", accountHandler.DeleteContact)
+			}
+
+			// Segment routes (客户分群)
+			segments := protected.Group("/segments")
+			{
+				segments.GET("", segmentHandler.ListSegments)
+				segments.POST("", segmentHandler.CreateSegment)
+				segments.POST("/preview", segmentHandler.PreviewSegment)
+				segments.GET("/:id", segmentHandler.GetSegment)
+				segments.PUT("/:id", segmentHandler.UpdateSegment)
+				segments.DELETE("/:id", segmentHandler.DeleteSegment)
+				segments.GET("/:id/customers", segmentHandler.ListSegmentCustomers)
+			}
+
+			// Saved view routes (列表视图：过滤条件、显示列、排序)
+			views := protected.Group("/views")
+			{
+				views.GET("", viewHandler.ListViews)
+				views.POST("", viewHandler.CreateView)
+				views.GET("/:id", viewHandler.GetView)
+				views.PUT("/:id", viewHandler.UpdateView)
+				views.DELETE("/:id", viewHandler.DeleteView)
+			}
+
+			// Lead scoring routes
+			protected.GET("/leads/scores", leadScoringHandler.ListScores)
+
+			// Next-best-action routes (每日行动清单)
+			nextActions := protected.Group("/next-actions")
+			{
+				nextActions.GET("", nextActionHandler.GetToday)
+				nextActions.POST("/refresh", nextActionHandler.Refresh)
+				nextActions.POST("/:id/accept", nextActionHandler.Accept)
+				nextActions.POST("/:id/dismiss", nextActionHandler.Dismiss)
+			}
+
+			// Call recording routes
+			callRecordings := protected.Group("/call-recordings")
+			{
+				callRecordings.GET("/:id", callRecordingHandler.GetRecording)
+				callRecordings.POST("/:id/confirm", callRecordingHandler.ConfirmRecording)
+				callRecordings.POST("/:id/discard", callRecordingHandler.DiscardRecording)
+			}
+
+			// Intent proposal routes
+			intentProposals := protected.Group("/intent-proposals")
+			{
+				intentProposals.POST("/:id/accept", intentHandler.AcceptProposal)
+				intentProposals.POST("/:id/reject", intentHandler.RejectProposal)
+			}
+
+			// Follow-up draft routes
+			followUps := protected.Group("/follow-ups")
+			{
+				followUps.GET("/:id", followUpHandler.GetDraft)
+				followUps.POST("/:id/sent", followUpHandler.MarkSent)
+				followUps.POST("/:id/discard", followUpHandler.DiscardDraft)
 			}
 
 			// Interaction routes
@@ -160,6 +427,7 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 				interactions.GET("/:id", interactionHandler.GetInteraction)
 				interactions.PUT("/:id", interactionHandler.UpdateInteraction)
 				interactions.DELETE("/:id", interactionHandler.DeleteInteraction)
+				interactions.POST("/:id/signals", intentHandler.AnalyzeInteraction)
 			}
 
 			// Knowledge base routes
@@ -173,18 +441,222 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 				knowledge.POST("/search", knowledgeHandler.SearchKnowledge)
 			}
 
+			// Structured query routes (过滤树查询)
+			query := protected.Group("/query")
+			{
+				query.GET("/fields", queryHandler.ListFields)
+				query.POST("", queryHandler.RunQuery)
+			}
+
 			// AI routes
 			ai := protected.Group("/ai")
 			{
 				ai.POST("/scripts/generate", aiHandler.GenerateScript)
+				ai.POST("/scripts/generate/stream", aiHandler.GenerateScriptStream)
 				ai.POST("/customers/:id/analyze", aiHandler.AnalyzeCustomer)
+				ai.POST("/customers/:id/analyze/stream", aiHandler.AnalyzeCustomerStream)
+				ai.GET("/customers/:id/analyses", aiHandler.ListCustomerAnalyses)
 				ai.POST("/knowledge/embed", aiHandler.GenerateEmbedding)
 				ai.POST("/speech-to-text", aiHandler.SpeechToText)
+				ai.POST("/transcriptions", transcriptionHandler.SubmitTranscription)
+				ai.GET("/transcriptions", transcriptionHandler.ListTranscriptions)
+				ai.GET("/transcriptions/:id", transcriptionHandler.GetTranscription)
 				ai.POST("/ocr-card", aiHandler.OCRBusinessCard)
+				ai.POST("/card-scans", cardScanHandler.SubmitCardScan)
+				ai.GET("/card-scans", cardScanHandler.ListCardScans)
+				ai.GET("/card-scans/:id", cardScanHandler.GetCardScan)
+				ai.POST("/card-scans/:id/approve-unmatched", cardScanHandler.ApproveUnmatched)
+				ai.POST("/card-scans/:id/items/:itemId/approve", cardScanHandler.ApproveCard)
+				ai.POST("/card-scans/:id/items/:itemId/merge", cardScanHandler.MergeCard)
+				ai.POST("/card-scans/:id/items/:itemId/discard", cardScanHandler.DiscardCard)
+				ai.POST("/documents", documentHandler.ExtractDocument)
+				ai.GET("/documents", documentHandler.ListDocuments)
+				ai.GET("/documents/:id", documentHandler.GetDocument)
+				ai.POST("/documents/:id/confirm", documentHandler.ConfirmDocument)
+				ai.POST("/documents/:id/discard", documentHandler.DiscardDocument)
 				ai.POST("/customer-intake/chat", aiHandler.CustomerIntakeChat)
+				ai.POST("/customer-intake/chat/stream", aiHandler.CustomerIntakeChatStream)
+				ai.POST("/intake-sessions", intakeHandler.StartSession)
+				ai.GET("/intake-sessions", intakeHandler.ListSessions)
+				ai.GET("/intake-sessions/:id", intakeHandler.GetSession)
+				ai.DELETE("/intake-sessions/:id", intakeHandler.CancelSession)
+				ai.POST("/intake-sessions/:id/messages", intakeHandler.SendMessage)
+				ai.POST("/intake-sessions/:id/messages/stream", intakeHandler.SendMessageStream)
+				ai.POST("/intake-sessions/:id/business-card", intakeHandler.AttachBusinessCard)
+				ai.POST("/intake-sessions/:id/voice", intakeHandler.AttachVoice)
+				ai.POST("/intake-sessions/:id/confirm", intakeHandler.Confirm)
+				ai.GET("/usage", aiUsageHandler.GetMyUsage)
+				ai.POST("/query", aiHandler.NaturalLanguageQuery)
+			}
+
+			// Admin routes
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequireAdmin())
+			{
+				admin.POST("/teams", teamHandler.CreateTeam)
+				admin.GET("/teams", teamHandler.ListTeams)
+				admin.PUT("/users/:id/team", teamHandler.AssignUserTeam)
+
+				admin.GET("/ai/usage/report", aiUsageHandler.GetReport)
+				admin.GET("/ai/quotas", aiUsageHandler.ListQuotas)
+				admin.PUT("/ai/quotas", aiUsageHandler.SetQuota)
+				admin.DELETE("/ai/quotas/:id", aiUsageHandler.DeleteQuota)
+
+				// AI privacy (脱敏策略和外发审计)
+				admin.GET("/ai/privacy", aiPrivacyHandler.GetPolicies)
+				admin.PUT("/ai/privacy", aiPrivacyHandler.SetPolicy)
+				admin.DELETE("/ai/privacy/:teamId", aiPrivacyHandler.DeletePolicy)
+				admin.GET("/ai/audit", aiPrivacyHandler.ListAuditLogs)
+
+				// AI response cache (响应缓存)
+				admin.GET("/ai/cache", aiCacheHandler.GetStats)
+				admin.DELETE("/ai/cache", aiCacheHandler.Purge)
+
+				// Prompt templates (提示词模板版本管理)
+				admin.GET("/prompts", promptHandler.ListPrompts)
+				admin.GET("/prompts/:key", promptHandler.GetPrompt)
+				admin.POST("/prompts/:key/versions", promptHandler.CreateVersion)
+				admin.PUT("/prompts/:key/pin", promptHandler.PinVersion)
+				admin.DELETE("/prompts/:key/pin", promptHandler.Unpin)
+
+				// Custom fields (自定义字段定义)
+				admin.GET("/custom-fields", customFieldHandler.ListDefinitions)
+				admin.POST("/custom-fields", customFieldHandler.CreateDefinition)
+				admin.PUT("/custom-fields/:id", customFieldHandler.UpdateDefinition)
+				admin.DELETE("/custom-fields/:id", customFieldHandler.DeleteDefinition)
+
+				// Customer tags (客户标签分组和标签)
+				admin.GET("/tag-groups", tagHandler.ListGroups)
+				admin.POST("/tag-groups", tagHandler.CreateGroup)
+				admin.PUT("/tag-groups/:id", tagHandler.UpdateGroup)
+				admin.DELETE("/tag-groups/:id", tagHandler.DeleteGroup)
+				admin.POST("/tags", tagHandler.CreateTag)
+				admin.PUT("/tags/:id", tagHandler.UpdateTag)
+				admin.DELETE("/tags/:id", tagHandler.DeleteTag)
+
+				// Lead scoring model (线索评分模型)
+				admin.GET("/lead-scoring/model", leadScoringHandler.GetModel)
+				admin.POST("/lead-scoring/train", leadScoringHandler.Train)
+
+				// Next-best-action feedback (行动建议采纳情况)
+				admin.GET("/next-actions/report", nextActionHandler.GetReport)
+			}
+		}
+	}
+
+	return router
+}
+
+// setupLLMRouter builds the per-capability provider fallback chains from config
+func setupLLMRouter(cfg *config.Config) *llm.Router {
+	providers := make(map[string]llm.Provider)
+	timeouts := make(map[string]time.Duration)
+
+	if cfg.DeepSeek.APIKey != "" {
+		client := deepseek.NewClient(
+			cfg.DeepSeek.APIKey,
+			cfg.DeepSeek.BaseURL,
+			cfg.DeepSeek.Model,
+			cfg.DeepSeek.EmbeddingModel,
+		)
+		useFixtures(cfg, "deepseek", client)
+		providers["deepseek"] = llm.NewDeepSeekProvider(client)
+		timeouts["deepseek"] = time.Duration(cfg.DeepSeek.TimeoutSeconds) * time.Second
+	}
+
+	if cfg.Doubao.APIKey != "" {
+		client := doubao.NewClient(
+			cfg.Doubao.BaseURL,
+			cfg.Doubao.APIKey,
+			cfg.Doubao.Model,
+		)
+		useFixtures(cfg, "doubao", client)
+		providers["doubao"] = llm.NewDoubaoProvider(client)
+		timeouts["doubao"] = time.Duration(cfg.Doubao.TimeoutSeconds) * time.Second
+	}
+
+	if cfg.OpenAI.APIKey != "" {
+		client := openai.NewClient(
+			cfg.OpenAI.APIKey,
+			cfg.OpenAI.BaseURL,
+			cfg.OpenAI.Model,
+			cfg.OpenAI.EmbeddingModel,
+			cfg.OpenAI.TranscriptionModel,
+		)
+		useFixtures(cfg, cfg.OpenAI.Name, client)
+		providers[cfg.OpenAI.Name] = llm.NewOpenAIProvider(cfg.OpenAI.Name, client)
+		timeouts[cfg.OpenAI.Name] = time.Duration(cfg.OpenAI.TimeoutSeconds) * time.Second
+	}
+
+	// 火山引擎录音文件识别，只提供语音识别能力，作为豆包的备用
+	if cfg.VolcEngine.AccessKeyID != "" && cfg.VolcEngine.ASR.AppID != "" {
+		client := volcengine.NewASRClient(
+			cfg.VolcEngine.AccessKeyID,
+			cfg.VolcEngine.AccessKeySecret,
+			cfg.VolcEngine.Region,
+			cfg.VolcEngine.ASR.AppID,
+			cfg.VolcEngine.ASR.UID,
+		)
+		useFixtures(cfg, "volcengine", client)
+		providers["volcengine"] = llm.NewVolcEngineProvider(client)
+		timeouts["volcengine"] = time.Duration(cfg.VolcEngine.TimeoutSeconds) * time.Second
+	}
+
+	router := llm.NewRouter()
+	chains := map[llm.Capability][]string{
+		llm.CapabilityChat:      cfg.AI.ChatProviders,
+		llm.CapabilityEmbedding: cfg.AI.EmbeddingProviders,
+		llm.CapabilitySpeech:    cfg.AI.SpeechProviders,
+		llm.CapabilityVision:    cfg.AI.VisionProviders,
+	}
+	for capability, names := range chains {
+		for _, name := range names {
+			p, ok := providers[name]
+			if !ok {
+				log.Printf("AI provider %q is not configured, skipped for %s", name, capability)
+				continue
+			}
+			err := router.Register(capability, p, llm.Options{
+				Timeout:          timeouts[name],
+				FailureThreshold: cfg.AI.BreakerThreshold,
+				Cooldown:         time.Duration(cfg.AI.BreakerCooldownSeconds) * time.Second,
+			})
+			if err != nil {
+				log.Printf("AI provider %q skipped for %s: %v", name, capability, err)
 			}
 		}
 	}
 
 	return router
 }
+
+// useFixtures 按 AI_FIXTURE_MODE 把厂商客户端的 HTTP 传输换成录制 / 回放，
+// 录制文件为 AI_FIXTURE_DIR/<厂商>.json
+func useFixtures(cfg *config.Config, name string, client interface{ SetTransport(http.RoundTripper) }) {
+	path := filepath.Join(cfg.AI.FixtureDir, name+".json")
+	opts := replay.Options{Scrub: replay.ScrubPII}
+	switch cfg.AI.FixtureMode {
+	case "":
+		return
+	case "record":
+		t, err := replay.NewRecorder(path, nil, opts)
+		if err != nil {
+			log.Fatalf("Failed to open AI fixture %s: %v", path, err)
+		}
+		log.Printf("AI provider %q: recording to %s", name, path)
+		client.SetTransport(t)
+	case "replay":
+		t, err := replay.OpenReplayer(path, opts)
+		if errors.Is(err, os.ErrNotExist) {
+			// 没有录制时仍然回放（所有请求都会失败），保证不访问网络
+			t, err = replay.NewReplayer(&replay.Cassette{}, opts), nil
+		}
+		if err != nil {
+			log.Fatalf("Failed to open AI fixture %s: %v", path, err)
+		}
+		log.Printf("AI provider %q: replaying from %s", name, path)
+		client.SetTransport(t)
+	default:
+		log.Fatalf("Unknown AI_FIXTURE_MODE %q (expected record or replay)", cfg.AI.FixtureMode)
+	}
+}
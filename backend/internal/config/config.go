@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,7 +15,11 @@ type Config struct {
 	JWT       JWTConfig
 	DeepSeek  DeepSeekConfig
 	Doubao    DoubaoConfig
+	OpenAI    OpenAIConfig
 	VolcEngine VolcEngineConfig
+	AI        AIConfig
+	LeadScoring LeadScoringConfig
+	Duplicates  DuplicateConfig
 }
 
 type ServerConfig struct {
@@ -40,18 +46,109 @@ type DeepSeekConfig struct {
 	BaseURL     string
 	Model       string
 	EmbeddingModel string
+	TimeoutSeconds int
 }
 
 type DoubaoConfig struct {
 	APIKey  string
 	BaseURL string
 	Model   string
+	TimeoutSeconds int
+}
+
+// OpenAIConfig configures an optional OpenAI-compatible provider
+type OpenAIConfig struct {
+	Name               string // provider name used in the AI_*_PROVIDERS chains
+	APIKey             string
+	BaseURL            string // including version prefix, e.g. https://api.openai.com/v1
+	Model              string
+	EmbeddingModel     string
+	TranscriptionModel string
+	TimeoutSeconds     int
+}
+
+// AIConfig configures provider priority per capability and circuit breaking
+type AIConfig struct {
+	ChatProviders      []string
+	EmbeddingProviders []string
+	SpeechProviders    []string
+	VisionProviders    []string
+	BreakerThreshold       int
+	BreakerCooldownSeconds int
+	// 默认 token 额度（0 表示不限），可在 ai_quotas 表中按用户 / 团队覆盖
+	UserDailyTokenLimit   int64
+	UserMonthlyTokenLimit int64
+	TeamDailyTokenLimit   int64
+	TeamMonthlyTokenLimit int64
+	// 各厂商每千 token 单价（元），用于成本报表
+	Pricing map[string]AIPrice
+
+	// 客户分析时附带的历史记录（跟进、成交、阶段变更）token 预算
+	AnalysisHistoryTokens int
+
+	// 长录音异步转写：切片时长、并发识别数、单任务超时、上传上限；非 WAV 格式需要 ffmpeg 转码后才能切片
+	ASRChunkSeconds      int
+	ASRWorkers           int
+	ASRJobTimeoutMinutes int
+	ASRMaxUploadMB       int
+	FFmpegPath           string
+
+	// 跟进记录保存后自动提取情绪和意向信号（每条记录一次 AI 调用）
+	InteractionSignals bool
+
+	// 发往外部模型前的隐私处理（默认策略，可在 ai_privacy_policies 表中按团队覆盖）：
+	// 个人信息替换为占位符、禁止外发的客户字段、是否允许发送图片；审计日志是否保存脱敏后的内容
+	Redaction       bool
+	RedactTypes     []string
+	ForbiddenFields []string
+	BlockImages     bool
+	AuditPayload    bool
+
+	// 响应缓存：对话结果按功能设置 TTL（秒，未列出的功能不缓存），向量结果持久化到数据库
+	CacheEnabled    bool
+	CacheMaxEntries int
+	CacheTTLs       map[string]time.Duration
+	EmbeddingCache  bool
+
+	// 新建客户对话会话无操作多久后过期
+	IntakeSessionTTLMinutes int
+
+	// 批量名片识别：并发识别数、单批最多名片数、单批上传上限（含 ZIP 解压后）
+	CardScanWorkers     int
+	CardScanMaxCards    int
+	CardScanMaxUploadMB int
+
+	// 录制 / 回放厂商接口：record 把请求和响应写入 FixtureDir/<厂商>.json，replay 只从文件回放，不访问网络
+	FixtureMode string
+	FixtureDir  string
+}
+
+// LeadScoringConfig 线索评分模型：每晚定时用成交 / 流失客户重新训练并给未结客户打分
+type LeadScoringConfig struct {
+	Enabled       bool
+	Hour          int // 每天运行的小时（服务器本地时间）
+	LostAfterDays int // 未成交且超过该天数没有任何动静的客户视为流失
+	MinSamples    int // 成交 + 流失样本少于该数时不训练
+}
+
+// DuplicateConfig 客户查重：每晚扫描生成疑似重复客户组，合并后在撤销期内可以撤销
+type DuplicateConfig struct {
+	ScanEnabled    bool
+	ScanHour       int // 每天扫描的小时（服务器本地时间）
+	MergeUndoHours int
+}
+
+// AIPrice 厂商每千 token 单价
+type AIPrice struct {
+	InputPer1K  float64
+	OutputPer1K float64
 }
 
 type VolcEngineConfig struct {
 	AccessKeyID     string
 	AccessKeySecret string
 	Region          string
+	TimeoutSeconds  int
 	ASR             VolcEngineASRConfig
 	OCR             VolcEngineOCRConfig
 }
@@ -93,16 +190,28 @@ func Load() (*Config, error) {
 			BaseURL:       getEnv("DEEPSEEK_BASE_URL", "https://api.deepseek.com"),
 			Model:         getEnv("DEEPSEEK_MODEL", "deepseek-chat"),
 			EmbeddingModel: getEnv("DEEPSEEK_EMBEDDING_MODEL", "deepseek-embedding"),
+			TimeoutSeconds: getEnvAsInt("DEEPSEEK_TIMEOUT_SECONDS", 60),
 		},
 		Doubao: DoubaoConfig{
 			APIKey:  getEnv("DOUBAO_API_KEY", ""),
 			BaseURL: getEnv("DOUBAO_BASE_URL", "https://ark.cn-beijing.volces.com/api/v3"),
 			Model:   getEnv("DOUBAO_MODEL", "doubao-seed-1-8-251228"),
+			TimeoutSeconds: getEnvAsInt("DOUBAO_TIMEOUT_SECONDS", 60),
+		},
+		OpenAI: OpenAIConfig{
+			Name:               getEnv("OPENAI_PROVIDER_NAME", "openai"),
+			APIKey:             getEnv("OPENAI_API_KEY", ""),
+			BaseURL:            getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+			Model:              getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+			EmbeddingModel:     getEnv("OPENAI_EMBEDDING_MODEL", "text-embedding-3-small"),
+			TranscriptionModel: getEnv("OPENAI_TRANSCRIPTION_MODEL", "whisper-1"),
+			TimeoutSeconds:     getEnvAsInt("OPENAI_TIMEOUT_SECONDS", 60),
 		},
 		VolcEngine: VolcEngineConfig{
 			AccessKeyID:     getEnv("VOLCENGINE_ACCESS_KEY_ID", ""),
 			AccessKeySecret: getEnv("VOLCENGINE_ACCESS_KEY_SECRET", ""),
 			Region:          getEnv("VOLCENGINE_REGION", "cn-north-1"),
+			TimeoutSeconds:  getEnvAsInt("VOLCENGINE_TIMEOUT_SECONDS", 60),
 			ASR: VolcEngineASRConfig{
 				AppID: getEnv("VOLCENGINE_ASR_APP_ID", ""),
 				UID:   getEnv("VOLCENGINE_ASR_UID", "nextcrm_user"),
@@ -111,6 +220,53 @@ func Load() (*Config, error) {
 				AppID: getEnv("VOLCENGINE_OCR_APP_ID", ""),
 			},
 		},
+		AI: AIConfig{
+			// 按优先级排列，前一个失败时降级到下一个
+			ChatProviders:      getEnvAsList("AI_CHAT_PROVIDERS", "doubao,deepseek"),
+			EmbeddingProviders: getEnvAsList("AI_EMBEDDING_PROVIDERS", "deepseek"),
+			SpeechProviders:    getEnvAsList("AI_SPEECH_PROVIDERS", "doubao,volcengine"),
+			VisionProviders:    getEnvAsList("AI_VISION_PROVIDERS", "doubao"),
+			BreakerThreshold:       getEnvAsInt("AI_BREAKER_THRESHOLD", 5),
+			BreakerCooldownSeconds: getEnvAsInt("AI_BREAKER_COOLDOWN_SECONDS", 30),
+			UserDailyTokenLimit:    int64(getEnvAsInt("AI_USER_DAILY_TOKEN_LIMIT", 0)),
+			UserMonthlyTokenLimit:  int64(getEnvAsInt("AI_USER_MONTHLY_TOKEN_LIMIT", 0)),
+			TeamDailyTokenLimit:    int64(getEnvAsInt("AI_TEAM_DAILY_TOKEN_LIMIT", 0)),
+			TeamMonthlyTokenLimit:  int64(getEnvAsInt("AI_TEAM_MONTHLY_TOKEN_LIMIT", 0)),
+			Pricing:                getEnvAsPricing("AI_PRICING", ""),
+			AnalysisHistoryTokens:  getEnvAsInt("AI_ANALYSIS_HISTORY_TOKENS", 1500),
+			ASRChunkSeconds:        getEnvAsInt("AI_ASR_CHUNK_SECONDS", 60),
+			ASRWorkers:             getEnvAsInt("AI_ASR_WORKERS", 4),
+			ASRJobTimeoutMinutes:   getEnvAsInt("AI_ASR_JOB_TIMEOUT_MINUTES", 30),
+			ASRMaxUploadMB:         getEnvAsInt("AI_ASR_MAX_UPLOAD_MB", 200),
+			FFmpegPath:             getEnv("FFMPEG_PATH", "ffmpeg"),
+			InteractionSignals:     getEnvAsBool("AI_INTERACTION_SIGNALS", true),
+			Redaction:              getEnvAsBool("AI_REDACTION", true),
+			RedactTypes:            getEnvAsList("AI_REDACT_TYPES", "name,phone,email,wechat,id_number,credit_code,bank_account"),
+			ForbiddenFields:        getEnvAsList("AI_FORBIDDEN_FIELDS", ""),
+			BlockImages:            getEnvAsBool("AI_BLOCK_IMAGES", false),
+			AuditPayload:           getEnvAsBool("AI_AUDIT_PAYLOAD", true),
+			CacheEnabled:           getEnvAsBool("AI_CACHE_ENABLED", true),
+			CacheMaxEntries:        getEnvAsInt("AI_CACHE_MAX_ENTRIES", 1000),
+			CacheTTLs:              getEnvAsTTLs("AI_CACHE_TTLS", "analyze:3600,query:600,signals:86400"),
+			EmbeddingCache:         getEnvAsBool("AI_EMBEDDING_CACHE", true),
+			IntakeSessionTTLMinutes: getEnvAsInt("AI_INTAKE_SESSION_TTL_MINUTES", 60),
+			CardScanWorkers:        getEnvAsInt("AI_CARD_SCAN_WORKERS", 4),
+			CardScanMaxCards:       getEnvAsInt("AI_CARD_SCAN_MAX_CARDS", 300),
+			CardScanMaxUploadMB:    getEnvAsInt("AI_CARD_SCAN_MAX_UPLOAD_MB", 200),
+			FixtureMode:            getEnv("AI_FIXTURE_MODE", ""),
+			FixtureDir:             getEnv("AI_FIXTURE_DIR", "testdata/fixtures"),
+		},
+		LeadScoring: LeadScoringConfig{
+			Enabled:       getEnvAsBool("LEAD_SCORING_ENABLED", true),
+			Hour:          getEnvAsInt("LEAD_SCORING_HOUR", 2),
+			LostAfterDays: getEnvAsInt("LEAD_SCORING_LOST_AFTER_DAYS", 180),
+			MinSamples:    getEnvAsInt("LEAD_SCORING_MIN_SAMPLES", 30),
+		},
+		Duplicates: DuplicateConfig{
+			ScanEnabled:    getEnvAsBool("DUPLICATE_SCAN_ENABLED", true),
+			ScanHour:       getEnvAsInt("DUPLICATE_SCAN_HOUR", 3),
+			MergeUndoHours: getEnvAsInt("CUSTOMER_MERGE_UNDO_HOURS", 72),
+		},
 	}
 
 	return cfg, nil
@@ -131,3 +287,60 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList reads a comma-separated list, dropping empty items
+func getEnvAsList(key, defaultValue string) []string {
+	var out []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// getEnvAsTTLs reads per-feature cache TTLs in seconds in the form
+// "feature:seconds,..." e.g. "analyze:3600,query:600"
+func getEnvAsTTLs(key, defaultValue string) map[string]time.Duration {
+	ttls := make(map[string]time.Duration)
+	for _, item := range getEnvAsList(key, defaultValue) {
+		parts := strings.Split(item, ":")
+		if len(parts) != 2 {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || seconds <= 0 {
+			continue
+		}
+		ttls[strings.TrimSpace(parts[0])] = time.Duration(seconds) * time.Second
+	}
+	return ttls
+}
+
+// getEnvAsPricing reads per-provider prices in the form
+// "provider:input_per_1k:output_per_1k,..." e.g. "deepseek:0.002:0.003"
+func getEnvAsPricing(key, defaultValue string) map[string]AIPrice {
+	prices := make(map[string]AIPrice)
+	for _, item := range getEnvAsList(key, defaultValue) {
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			continue
+		}
+		in, err1 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		out, err2 := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		prices[strings.TrimSpace(parts[0])] = AIPrice{InputPer1K: in, OutputPer1K: out}
+	}
+	return prices
+}
@@ -0,0 +1,98 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+)
+
+// CreateAccountRequest 新建账户（公司）
+type CreateAccountRequest struct {
+	Name              string `json:"name" binding:"required"`
+	Industry          string `json:"industry"`
+	CompanyScale      string `json:"company_scale"`
+	RegisteredCapital string `json:"registered_capital"`
+	LegalPerson       string `json:"legal_person"`
+	CreditCode        string `json:"credit_code"`
+	Address           string `json:"address"`
+	InvoiceTitle      string `json:"invoice_title"`
+	TaxNumber         string `json:"tax_number"`
+	BankAccount       string `json:"bank_account"`
+	Notes             string `json:"notes"`
+}
+
+// UpdateAccountRequest 修改账户，未提供的项保持不变
+type UpdateAccountRequest struct {
+	Name              *string `json:"name"`
+	Industry          *string `json:"industry"`
+	CompanyScale      *string `json:"company_scale"`
+	RegisteredCapital *string `json:"registered_capital"`
+	LegalPerson       *string `json:"legal_person"`
+	CreditCode        *string `json:"credit_code"`
+	Address           *string `json:"address"`
+	InvoiceTitle      *string `json:"invoice_title"`
+	TaxNumber         *string `json:"tax_number"`
+	BankAccount       *string `json:"bank_account"`
+	Notes             *string `json:"notes"`
+}
+
+// AccountQuery 账户列表参数；search 匹配账户名和信用代码
+type AccountQuery struct {
+	Page    int    `form:"page,default=1"`
+	PerPage int    `form:"per_page,default=10"`
+	Search  string `form:"search"`
+}
+
+// AccountDetail 账户和它的联系人、客户记录
+type AccountDetail struct {
+	*models.Account
+	Contacts  []*models.Contact  `json:"contacts"`
+	Customers []*CustomerSummary `json:"customers"`
+}
+
+// CreateContactRequest 在账户下新建联系人
+type CreateContactRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Position  string `json:"position"`
+	Phone     string `json:"phone"`
+	Email     string `json:"email"`
+	WechatID  string `json:"wechat_id"`
+	Role      string `json:"role" binding:"omitempty,oneof=decision_maker influencer champion blocker"`
+	IsPrimary bool   `json:"is_primary"`
+	Notes     string `json:"notes"`
+}
+
+// UpdateContactRequest 修改联系人，未提供的项保持不变；role 为空字符串表示清除角色
+type UpdateContactRequest struct {
+	Name      *string `json:"name"`
+	Position  *string `json:"position"`
+	Phone     *string `json:"phone"`
+	Email     *string `json:"email"`
+	WechatID  *string `json:"wechat_id"`
+	Role      *string `json:"role" binding:"omitempty,oneof=decision_maker influencer champion blocker"`
+	IsPrimary *bool   `json:"is_primary"`
+	Notes     *string `json:"notes"`
+}
+
+// AccountTimelineQuery 账户时间线参数：types 为逗号分隔的 interaction / deal / activity（默认全部），
+// contact_id 只看涉及该联系人的跟进记录和成交
+type AccountTimelineQuery struct {
+	Page      int    `form:"page,default=1"`
+	PerPage   int    `form:"per_page,default=20"`
+	Types     string `form:"types"`
+	ContactID uint64 `form:"contact_id"`
+}
+
+// AccountTimelineItem 账户时间线中的一条：跟进记录（title 为类型）、成交（title 为产品，带金额）
+// 或客户动态（title 为动作类型），按发生时间倒序
+type AccountTimelineItem struct {
+	Type       string    `json:"type"`
+	ID         uint64    `json:"id"`
+	CustomerID uint64    `json:"customer_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content,omitempty"`
+	Amount     *float64  `json:"amount,omitempty"`
+	Currency   string    `json:"currency,omitempty"`
+	ContactIDs []uint64  `gorm:"-" json:"contact_ids,omitempty"`
+}
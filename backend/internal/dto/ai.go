@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/pkg/schema"
+)
+
 // GenerateScriptRequest represents a request to generate sales script
 type GenerateScriptRequest struct {
 	Context      string `json:"context" binding:"required"`
@@ -16,14 +21,32 @@ type GenerateScriptResponse struct {
 	Tips        []string `json:"tips"`
 }
 
+// 话术生成的 JSON 输出结构；流式接口话术正文以纯文本下发，JSON 块中不含 script
+var (
+	scriptKeyPoints = schema.Array(schema.String(), 1, 10)
+	scriptTips      = schema.Array(schema.String(), 1, 10)
+
+	GenerateScriptSchema = schema.Object(map[string]*schema.Schema{
+		"script":     schema.String(),
+		"key_points": scriptKeyPoints,
+		"tips":       scriptTips,
+	})
+	GenerateScriptStreamSchema = schema.Object(map[string]*schema.Schema{
+		"key_points": scriptKeyPoints,
+		"tips":       scriptTips,
+	})
+)
+
 // AnalyzeCustomerRequest represents a request to analyze customer
 type AnalyzeCustomerRequest struct {
 	CustomerID   uint64  `json:"customer_id" binding:"required"`
 	AnalysisType string  `json:"analysis_type"` // intent, risk, opportunity, comprehensive
+	Refresh      bool    `json:"refresh"`       // 忽略缓存，重新分析
 }
 
 // AnalyzeCustomerResponse represents the response from customer analysis
 type AnalyzeCustomerResponse struct {
+	AnalysisID     uint64   `json:"analysis_id,omitempty"` // 保存的分析快照 ID
 	CustomerID     uint64   `json:"customer_id"`
 	AnalysisType   string   `json:"analysis_type"`
 	Summary        string   `json:"summary"`
@@ -34,6 +57,38 @@ type AnalyzeCustomerResponse struct {
 	NextActions    []string `json:"next_actions"`
 }
 
+// 客户分析的 JSON 输出结构；流式接口摘要以纯文本下发，JSON 块中不含 summary
+var (
+	analyzeFields = map[string]*schema.Schema{
+		"intent_score":    schema.Integer(0, 100),
+		"risk_level":      schema.String("low", "medium", "high"),
+		"opportunities":   schema.Array(schema.String(), 0, 10),
+		"recommendations": schema.Array(schema.String(), 0, 10),
+		"next_actions":    schema.Array(schema.String(), 0, 10),
+	}
+
+	AnalyzeCustomerSchema       = schema.Object(withProperty(analyzeFields, "summary", schema.String()))
+	AnalyzeCustomerStreamSchema = schema.Object(analyzeFields)
+)
+
+func withProperty(props map[string]*schema.Schema, name string, s *schema.Schema) map[string]*schema.Schema {
+	out := make(map[string]*schema.Schema, len(props)+1)
+	for k, v := range props {
+		out[k] = v
+	}
+	out[name] = s
+	return out
+}
+
+// CustomerAnalysisHistoryResponse 客户的历次 AI 分析快照及当前实际状态
+type CustomerAnalysisHistoryResponse struct {
+	CustomerID     uint64                     `json:"customer_id"`
+	Stage          string                     `json:"stage"`           // 当前阶段
+	ContractStatus string                     `json:"contract_status"` // 当前合同状态
+	Probability    int                        `json:"probability"`     // 当前成交概率
+	Analyses       []*models.CustomerAnalysis `json:"analyses"`        // 按时间倒序
+}
+
 // GenerateEmbeddingRequest represents a request to generate embedding
 type GenerateEmbeddingRequest struct {
 	Text string `json:"text" binding:"required"`
@@ -47,23 +102,34 @@ type GenerateEmbeddingResponse struct {
 
 // SpeechToTextResponse represents the response from speech recognition
 type SpeechToTextResponse struct {
-	Text       string  `json:"text"`
-	Confidence float64 `json:"confidence"`
-	Duration   float64 `json:"duration"` // 秒
+	Text       string                     `json:"text"`
+	Confidence *float64                   `json:"confidence"` // 厂商不返回置信度时为 null
+	Duration   float64                    `json:"duration"`   // 秒
+	Segments   []models.TranscriptSegment `json:"segments,omitempty"`
 }
 
 // BusinessCardOCRResponse represents the response from business card OCR
 type BusinessCardOCRResponse struct {
-	Name       string  `json:"name"`
-	Company    string  `json:"company"`
-	Position   string  `json:"position"`
-	Phone      string  `json:"phone"`
-	Email      string  `json:"email"`
-	Address    string  `json:"address"`
-	Confidence float64 `json:"confidence"`
-	RawText    string  `json:"raw_text,omitempty"` // 原始识别文本（当解析失败时）
+	Name       string   `json:"name"`
+	Company    string   `json:"company"`
+	Position   string   `json:"position"`
+	Phone      string   `json:"phone"`
+	Email      string   `json:"email"`
+	Address    string   `json:"address"`
+	Confidence *float64 `json:"confidence,omitempty"` // 厂商不返回置信度时为空
+	Provider   string   `json:"provider,omitempty"`   // 实际完成识别的厂商（含降级到火山引擎 OCR）
 }
 
+// BusinessCardSchema 名片识别的 JSON 输出结构；名片上没有的字段可以省略
+var BusinessCardSchema = schema.Object(map[string]*schema.Schema{
+	"name":     schema.String(),
+	"company":  schema.String(),
+	"position": schema.String(),
+	"phone":    schema.String(),
+	"email":    schema.String(),
+	"address":  schema.String(),
+}, "name", "company", "position", "phone", "email", "address")
+
 // CustomerIntakeChatMessage 新建客户对话消息
 type CustomerIntakeChatMessage struct {
 	Role    string `json:"role"`    // user | assistant | system
@@ -84,6 +150,25 @@ type CustomerIntakeChatResponse struct {
 	Summary          string            `json:"summary,omitempty"` // AI 生成的信息总结（当 status=ready_for_confirmation 时）
 }
 
+// CustomerIntakeFieldsSchema 新建客户对话回复末尾 JSON 块的结构：status 必填，
+// 字段均为字符串，未确认的可省略或留空
+var CustomerIntakeFieldsSchema = func() *schema.Schema {
+	s := schema.Object(map[string]*schema.Schema{
+		"status":       schema.String("collecting", "ready_for_confirmation"),
+		"name":         schema.String(),
+		"company":      schema.String(),
+		"position":     schema.String(),
+		"phone":        schema.String(),
+		"email":        schema.String(),
+		"wechat_id":    schema.String(),
+		"budget":       schema.String(),
+		"intent_level": schema.String(),
+		"notes":        schema.String(),
+	}, "name", "company", "position", "phone", "email", "wechat_id", "budget", "intent_level", "notes")
+	s.AdditionalProperties = schema.String()
+	return s
+}()
+
 // CreateCustomerFromChatRequest 从 AI 对话创建客户的请求
 type CreateCustomerFromChatRequest struct {
 	Name        string `json:"name" binding:"required"`
@@ -0,0 +1,14 @@
+package dto
+
+// AICacheStatsResponse AI 缓存状态；命中数为进程启动以来的累计值
+type AICacheStatsResponse struct {
+	Enabled         bool  `json:"enabled"`
+	Entries         int   `json:"entries"`
+	Hits            int64 `json:"hits"`
+	Misses          int64 `json:"misses"`
+	Shared          int64 `json:"shared"` // 与并发的相同请求共享结果的次数
+	EmbeddingCache  bool  `json:"embedding_cache"`
+	Embeddings      int64 `json:"embeddings"`
+	EmbeddingHits   int64 `json:"embedding_hits"`
+	EmbeddingMisses int64 `json:"embedding_misses"`
+}
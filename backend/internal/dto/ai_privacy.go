@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+)
+
+// SetAIPrivacyPolicyRequest 设置团队的 AI 隐私策略，整体覆盖默认配置
+type SetAIPrivacyPolicyRequest struct {
+	TeamID          uint64   `json:"team_id" binding:"required"`
+	Redaction       bool     `json:"redaction"`
+	RedactTypes     []string `json:"redact_types"`     // name, phone, email, wechat, id_number, credit_code, bank_account
+	ForbiddenFields []string `json:"forbidden_fields"` // 客户字段的 JSON 名，如 notes, bank_account
+	BlockImages     bool     `json:"block_images"`
+}
+
+// AIPrivacyPolicyView 生效中的隐私策略
+type AIPrivacyPolicyView struct {
+	Redaction       bool     `json:"redaction"`
+	RedactTypes     []string `json:"redact_types"`
+	ForbiddenFields []string `json:"forbidden_fields"`
+	BlockImages     bool     `json:"block_images"`
+}
+
+// AIPrivacyPoliciesResponse 默认策略和各团队的覆盖
+type AIPrivacyPoliciesResponse struct {
+	Default AIPrivacyPolicyView       `json:"default"`
+	Teams   []*models.AIPrivacyPolicy `json:"teams"`
+}
+
+// AIAuditLogQuery 审计日志查询条件
+type AIAuditLogQuery struct {
+	UserID  uint64     `form:"user_id"`
+	TeamID  uint64     `form:"team_id"`
+	Feature string     `form:"feature"`
+	From    *time.Time `form:"from" time_format:"2006-01-02"`
+	To      *time.Time `form:"to" time_format:"2006-01-02"` // 包含当天
+	Page    int        `form:"page"`
+	PerPage int        `form:"per_page"`
+}
+
+// AIAuditLogListResponse 分页的审计日志
+type AIAuditLogListResponse struct {
+	Logs    []*models.AIAuditLog `json:"logs"`
+	Total   int64                `json:"total"`
+	Page    int                  `json:"page"`
+	PerPage int                  `json:"per_page"`
+}
@@ -0,0 +1,54 @@
+package dto
+
+import "time"
+
+// AIUsageReportQuery 成本报表查询区间，默认本月
+type AIUsageReportQuery struct {
+	From *time.Time `form:"from" time_format:"2006-01-02"`
+	To   *time.Time `form:"to" time_format:"2006-01-02"` // 包含当天
+}
+
+// AIUsageReportRow 按团队和功能汇总的用量与成本
+type AIUsageReportRow struct {
+	TeamID           *uint64 `json:"team_id"`
+	TeamName         string  `json:"team_name"`
+	Feature          string  `json:"feature"`
+	Calls            int64   `json:"calls"`
+	FailedCalls      int64   `json:"failed_calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// AIUsageReportResponse 成本报表
+type AIUsageReportResponse struct {
+	From      time.Time           `json:"from"`
+	To        time.Time           `json:"to"`
+	Rows      []*AIUsageReportRow `json:"rows"`
+	TotalCost float64             `json:"total_cost"`
+}
+
+// AIQuotaUsage 某个范围（用户 / 团队）的额度使用情况，limit 为 0 表示不限
+type AIQuotaUsage struct {
+	DailyUsed    int64 `json:"daily_used"`
+	DailyLimit   int64 `json:"daily_limit"`
+	MonthlyUsed  int64 `json:"monthly_used"`
+	MonthlyLimit int64 `json:"monthly_limit"`
+}
+
+// AIUsageSummaryResponse 当前用户的 AI 额度使用情况
+type AIUsageSummaryResponse struct {
+	User   AIQuotaUsage  `json:"user"`
+	TeamID *uint64       `json:"team_id,omitempty"`
+	Team   *AIQuotaUsage `json:"team,omitempty"`
+}
+
+// SetAIQuotaRequest 设置用户或团队的 token 额度（0 表示不限）
+type SetAIQuotaRequest struct {
+	Scope             string `json:"scope" binding:"required,oneof=user team"`
+	ScopeID           uint64 `json:"scope_id" binding:"required"`
+	DailyTokenLimit   int64  `json:"daily_token_limit" binding:"min=0"`
+	MonthlyTokenLimit int64  `json:"monthly_token_limit" binding:"min=0"`
+}
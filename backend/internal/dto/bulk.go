@@ -0,0 +1,44 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// BulkCustomerRequest 客户批量操作。目标为 customer_ids、segment_id、filter（customers 过滤树）三者之一；
+// operation 为 update / archive / restore / delete / reassign。
+// restore 的 filter 按已归档客户计算，不支持 segment_id
+type BulkCustomerRequest struct {
+	Operation   string      `json:"operation" binding:"required"`
+	CustomerIDs []uint64    `json:"customer_ids"`
+	SegmentID   uint64      `json:"segment_id"`
+	Filter      *FilterNode `json:"filter"`
+
+	Update   *BulkCustomerUpdate `json:"update"`     // operation = update
+	ToUserID uint64              `json:"to_user_id"` // operation = reassign，须与当前用户同一团队
+}
+
+// BulkCustomerUpdate 批量更新的字段，未提供的保持不变；标签为加上 / 去掉，不整体替换
+type BulkCustomerUpdate struct {
+	Stage          *string  `json:"stage,omitempty"`
+	IntentLevel    *string  `json:"intent_level,omitempty"`
+	CustomerLevel  *string  `json:"customer_level,omitempty"`
+	CustomerStatus *string  `json:"customer_status,omitempty"`
+	Source         *string  `json:"source,omitempty"`
+	Industry       *string  `json:"industry,omitempty"`
+	AddTags        []string `json:"add_tags,omitempty"`
+	RemoveTags     []string `json:"remove_tags,omitempty"`
+}
+
+// BulkReassignParams 转移负责人任务的参数
+type BulkReassignParams struct {
+	ToUserID uint64 `json:"to_user_id"`
+}
+
+// BulkJobListQuery 最近的批量操作
+type BulkJobListQuery struct {
+	Limit int `form:"limit,default=20"`
+}
+
+// BulkJobResponse 批量操作任务；inline 表示已在请求内执行完毕，否则在后台执行，需轮询进度
+type BulkJobResponse struct {
+	*models.BulkJob
+	Inline bool `json:"inline"`
+}
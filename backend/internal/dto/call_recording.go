@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/pkg/schema"
+)
+
+// CallSummary AI 对通话转写的总结，同时给出跟进记录草稿所需的结果和下一步
+type CallSummary struct {
+	Summary     string   `json:"summary"`
+	KeyPoints   []string `json:"key_points"`
+	Objections  []string `json:"objections"`
+	Commitments []string `json:"commitments"`
+	NextSteps   []string `json:"next_steps"`
+	Outcome     string   `json:"outcome"`     // positive, neutral, negative
+	NextAction  string   `json:"next_action"` // 最重要的下一步动作，没有则为空
+	NextDate    string   `json:"next_date"`   // 2006-01-02，没有约定时间则为空
+}
+
+// CallSummarySchema 通话总结的 JSON 输出结构
+var CallSummarySchema = schema.Object(map[string]*schema.Schema{
+	"summary":     schema.String(),
+	"key_points":  schema.Array(schema.String(), 0, 10),
+	"objections":  schema.Array(schema.String(), 0, 10),
+	"commitments": schema.Array(schema.String(), 0, 10),
+	"next_steps":  schema.Array(schema.String(), 0, 10),
+	"outcome":     schema.String("positive", "neutral", "negative"),
+	"next_action": schema.String(),
+	"next_date":   schema.String(),
+})
+
+// CallRecordingResponse 通话录音处理结果；待确认时附带将要创建的跟进记录草稿
+type CallRecordingResponse struct {
+	*models.CallRecording
+	Draft *CreateInteractionRequest `json:"draft,omitempty"`
+}
+
+// ConfirmCallRecordingRequest 确认草稿时可覆盖的字段，不传则使用 AI 生成的内容
+type ConfirmCallRecordingRequest struct {
+	Content    *string    `json:"content"`
+	Outcome    *string    `json:"outcome"`
+	NextAction *string    `json:"next_action"`
+	NextDate   *time.Time `json:"next_date"`
+}
@@ -0,0 +1,51 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// CardScanImage 批量上传中的一张名片图片
+type CardScanImage struct {
+	FileName string
+	Data     []byte
+}
+
+// CardEdits 审核时对识别结果的修改，未提供的字段保持识别值
+type CardEdits struct {
+	Name     *string `json:"name"`
+	Company  *string `json:"company"`
+	Position *string `json:"position"`
+	Phone    *string `json:"phone"`
+	Email    *string `json:"email"`
+	Address  *string `json:"address"`
+}
+
+// ApproveCardRequest 确认名片并创建新客户
+type ApproveCardRequest struct {
+	CardEdits
+}
+
+// MergeCardRequest 把名片合并到已有客户：customer_id 为空时使用匹配度最高的客户；
+// 默认只补充客户的空字段，overwrite 为 true 时用名片上的值覆盖
+type MergeCardRequest struct {
+	CardEdits
+	CustomerID uint64 `json:"customer_id"`
+	Overwrite  bool   `json:"overwrite"`
+}
+
+// CardScanBatchResponse 批次进度和其中的名片
+type CardScanBatchResponse struct {
+	*models.CardScanBatch
+	Items []*models.CardScanItem `json:"items"`
+}
+
+// CardReviewResponse 审核后的名片和创建 / 合并的客户
+type CardReviewResponse struct {
+	Item     *models.CardScanItem `json:"item"`
+	Customer *CustomerResponse    `json:"customer,omitempty"`
+}
+
+// ApproveUnmatchedResponse 批量确认没有匹配客户的名片
+type ApproveUnmatchedResponse struct {
+	Approved int                    `json:"approved"`
+	Skipped  int                    `json:"skipped"` // 缺少姓名或创建失败，留在审核队列
+	Items    []*models.CardScanItem `json:"items"`
+}
@@ -0,0 +1,61 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// CreateCustomFieldRequest 新建自定义字段定义；entity、team_id、key、type 创建后不可修改
+type CreateCustomFieldRequest struct {
+	Entity     string                       `json:"entity" binding:"required,oneof=customer deal interaction"`
+	TeamID     *uint64                      `json:"team_id"`
+	Key        string                       `json:"key" binding:"required"`
+	Label      string                       `json:"label" binding:"required"`
+	Type       string                       `json:"type" binding:"required,oneof=text number date select multi_select user"`
+	Options    []string                     `json:"options"`
+	Required   bool                         `json:"required"`
+	Validation models.CustomFieldValidation `json:"validation"`
+	Position   int                          `json:"position"`
+}
+
+// UpdateCustomFieldRequest 修改自定义字段定义，未提供的项保持不变
+type UpdateCustomFieldRequest struct {
+	Label      *string                       `json:"label"`
+	Options    []string                      `json:"options"`
+	Required   *bool                         `json:"required"`
+	Validation *models.CustomFieldValidation `json:"validation"`
+	Position   *int                          `json:"position"`
+}
+
+// CustomFieldListQuery 管理端按实体 / 团队列出定义（都为空时列出全部）
+type CustomFieldListQuery struct {
+	Entity string  `form:"entity"`
+	TeamID *uint64 `form:"team_id"`
+}
+
+// CustomFieldFilter 按自定义字段过滤：Values 非空时匹配其中任一值（多选字段为包含任一值），
+// 否则按 From / To 取范围（数字或日期，可只给一端）
+type CustomFieldFilter struct {
+	Key    string
+	Type   string
+	Values []interface{}
+	From   interface{}
+	To     interface{}
+}
+
+// CustomFieldSort 按自定义字段排序，空值排在最后
+type CustomFieldSort struct {
+	Key  string
+	Type string
+	Desc bool
+}
+
+// InteractionListQuery 客户跟进记录列表参数：sort_by 只接受 cf.<key>，默认按时间倒序
+type InteractionListQuery struct {
+	SortBy    string `form:"sort_by"`
+	SortOrder string `form:"sort_order"`
+
+	// 高级过滤（filter=<FilterNode JSON>）和保存的视图（view_id）
+	ListFilter
+
+	CustomFields       map[string]string   `form:"-"` // cf[key]=value 原始参数
+	CustomFieldFilters []CustomFieldFilter `form:"-"`
+	CustomFieldSort    *CustomFieldSort    `form:"-"`
+}
@@ -36,6 +36,12 @@ type CreateCustomerRequest struct {
 	TaxNumber         string `json:"tax_number"`
 	BankAccount       string `json:"bank_account"`
 	PaymentTerms      string `json:"payment_terms"`
+	// 所属账户；为空时按公司名归入已有账户或新建账户
+	AccountID *uint64 `json:"account_id"`
+	// 自定义字段 key → 值，按字段定义校验
+	CustomFields map[string]interface{} `json:"custom_fields"`
+	// 标签名，须为当前用户可用的标签
+	Tags []string `json:"tags"`
 }
 
 // UpdateCustomerRequest represents a request to update a customer
@@ -75,6 +81,12 @@ type UpdateCustomerRequest struct {
 	TaxNumber         *string `json:"tax_number"`
 	BankAccount       *string `json:"bank_account"`
 	PaymentTerms      *string `json:"payment_terms"`
+	// 移到另一个账户，客户的跟进记录、成交和联系人一起移动
+	AccountID *uint64 `json:"account_id"`
+	// 只修改提供的自定义字段，值为 null 表示清空
+	CustomFields map[string]interface{} `json:"custom_fields"`
+	// 替换全部标签；未提供时不变，[] 表示清空
+	Tags []string `json:"tags"`
 }
 
 // CustomerQuery represents query parameters for listing customers
@@ -86,8 +98,22 @@ type CustomerQuery struct {
 	IntentLevel string `form:"intent_level"`
 	Source     string `form:"source"`
 	Industry   string `form:"industry"`
-	SortBy     string `form:"sort_by,default=created_at"`
+	AccountID  uint64 `form:"account_id"`
+	Tags       string `form:"tags"`       // 逗号分隔，有其中任一标签
+	SegmentID  uint64 `form:"segment_id"` // 只列出分群中的客户
+	SortBy     string `form:"sort_by"` // 白名单字段，默认 created_at
 	SortOrder  string `form:"sort_order,default=desc"`
+
+	// 高级过滤（filter=<FilterNode JSON>）和保存的视图（view_id）
+	ListFilter
+
+	// 自定义字段过滤（cf[key]=value）和排序（sort_by=cf.<key>），由 service 按字段定义解析
+	CustomFields       map[string]string   `form:"-"`
+	CustomFieldFilters []CustomFieldFilter `form:"-"`
+	CustomFieldSort    *CustomFieldSort    `form:"-"`
+
+	// 限定客户 ID（由 service 按 segment_id 解析），非 nil 时只列出其中的客户
+	IDs []uint64 `form:"-"`
 }
 
 // CustomerResponse represents a customer response
@@ -129,6 +155,9 @@ type CustomerResponse struct {
 	TaxNumber         string   `json:"tax_number"`
 	BankAccount       string   `json:"bank_account"`
 	PaymentTerms      string   `json:"payment_terms"`
+	AccountID         *uint64  `json:"account_id,omitempty"`
+	CustomFields      map[string]interface{} `json:"custom_fields,omitempty"`
+	Tags              []string `json:"tags"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
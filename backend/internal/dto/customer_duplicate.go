@@ -0,0 +1,57 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// CheckDuplicatesRequest 新建 / 编辑客户时实时查重；exclude_id 为正在编辑的客户
+type CheckDuplicatesRequest struct {
+	Name       string `json:"name"`
+	Company    string `json:"company"`
+	Phone      string `json:"phone"`
+	Email      string `json:"email"`
+	WechatID   string `json:"wechat_id"`
+	CreditCode string `json:"credit_code"`
+	ExcludeID  uint64 `json:"exclude_id"`
+}
+
+// DuplicateMatch 一个疑似重复的客户；matched_on 为规范化后相同的字段，姓名和公司都相似时包含 name_company；
+// score 在有字段精确相同时为 1，否则为姓名和公司相似度的平均值
+type DuplicateMatch struct {
+	Customer          *CustomerResponse `json:"customer"`
+	MatchedOn         []string          `json:"matched_on"`
+	NameSimilarity    float64           `json:"name_similarity"`
+	CompanySimilarity float64           `json:"company_similarity"`
+	Score             float64           `json:"score"`
+}
+
+// CreateCustomerResponse 新建的客户和疑似重复的已有客户（只提示，不阻止创建）
+type CreateCustomerResponse struct {
+	*CustomerResponse
+	Duplicates []*DuplicateMatch `json:"duplicates,omitempty"`
+}
+
+// DuplicateClusterResponse 一组疑似重复客户及其当前资料
+type DuplicateClusterResponse struct {
+	*models.DuplicateCluster
+	Customers []*CustomerResponse `json:"customers"`
+}
+
+// MergeCustomersRequest 把 merged_ids 合并到 survivor_id；fields 为字段 JSON 名 → 取值的客户 ID，
+// 未指定的字段默认取保留客户的值，为空时取第一个有值的被合并客户
+type MergeCustomersRequest struct {
+	SurvivorID uint64            `json:"survivor_id" binding:"required"`
+	MergedIDs  []uint64          `json:"merged_ids" binding:"required,min=1"`
+	Fields     map[string]uint64 `json:"fields"`
+	ClusterID  uint64            `json:"cluster_id"`
+}
+
+// MergeCustomersResponse 合并记录（含撤销截止时间）和合并后的客户
+type MergeCustomersResponse struct {
+	Merge    *models.CustomerMerge `json:"merge"`
+	Customer *CustomerResponse     `json:"customer"`
+}
+
+// UndoMergeResponse 撤销后的合并记录和恢复的客户（保留客户在前）
+type UndoMergeResponse struct {
+	Merge     *models.CustomerMerge `json:"merge"`
+	Customers []*CustomerResponse   `json:"customers"`
+}
@@ -19,6 +19,8 @@ type CreateDealRequest struct {
 	IsRepeatPurchase bool       `json:"is_repeat_purchase"`
 	DealAt           time.Time  `json:"deal_at" binding:"required"`
 	Notes            string     `json:"notes"`
+	ContactIDs       []uint64   `json:"contact_ids"` // 须属于客户所在的账户；不提供时关联由该客户记录拆分出的联系人
+	CustomFields     map[string]interface{} `json:"custom_fields"`
 }
 
 // UpdateDealRequest represents a request to update a deal
@@ -37,6 +39,8 @@ type UpdateDealRequest struct {
 	IsRepeatPurchase *bool     `json:"is_repeat_purchase"`
 	DealAt           *time.Time `json:"deal_at"`
 	Notes            *string    `json:"notes"`
+	ContactIDs       []uint64   `json:"contact_ids"` // 提供时替换涉及的联系人，空数组表示清空
+	CustomFields     map[string]interface{} `json:"custom_fields"` // 只修改提供的字段，null 表示清空
 }
 
 // DealResponse represents a deal in API response
@@ -45,6 +49,8 @@ type DealResponse struct {
 	RecordNo         string     `json:"record_no"`
 	UserID           uint64     `json:"user_id"`
 	CustomerID       uint64     `json:"customer_id"`
+	AccountID        *uint64    `json:"account_id,omitempty"`
+	ContactIDs       []uint64   `json:"contact_ids,omitempty"`
 	DealType         string     `json:"deal_type"`
 	ProductOrService string     `json:"product_or_service"`
 	Quantity         float64    `json:"quantity"`
@@ -59,6 +65,7 @@ type DealResponse struct {
 	IsRepeatPurchase bool       `json:"is_repeat_purchase"`
 	DealAt           time.Time  `json:"deal_at"`
 	Notes            string     `json:"notes,omitempty"`
+	CustomFields     map[string]interface{} `json:"custom_fields,omitempty"`
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
 	// Optional: customer name for list views
@@ -70,10 +77,19 @@ type DealListQuery struct {
 	Page       int    `form:"page"`
 	PerPage    int    `form:"per_page"`
 	CustomerID uint64 `form:"customer_id"`
+	AccountID  uint64 `form:"account_id"`
 	UserID     uint64 `form:"user_id"` // filter by owner
 	DealType   string `form:"deal_type"`
 	SortBy     string `form:"sort_by"`
 	SortOrder  string `form:"sort_order"`
+
+	// 高级过滤（filter=<FilterNode JSON>）和保存的视图（view_id）
+	ListFilter
+
+	// 自定义字段过滤（cf[key]=value）和排序（sort_by=cf.<key>），由 service 按字段定义解析
+	CustomFields       map[string]string   `form:"-"`
+	CustomFieldFilters []CustomFieldFilter `form:"-"`
+	CustomFieldSort    *CustomFieldSort    `form:"-"`
 }
 
 // DealListResponse represents paginated list of deals
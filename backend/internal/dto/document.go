@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/pkg/schema"
+)
+
+// 合同和增值税发票识别的字段
+var (
+	ContractFields = []string{
+		"contract_no", "party_a", "party_b", "subject", "amount", "currency", "signing_date", "payment_terms",
+	}
+	VATInvoiceFields = []string{
+		"invoice_type", "invoice_code", "invoice_number", "invoice_date",
+		"buyer_name", "buyer_tax_number", "buyer_bank_account",
+		"seller_name", "seller_tax_number", "seller_bank_account",
+		"amount", "tax_amount", "total_amount",
+	}
+)
+
+// DocumentFieldsSchema 单据识别的 JSON 输出结构：每个字段为 {value, confidence}，缺失的字段视为未识别
+func DocumentFieldsSchema(fields []string) *schema.Schema {
+	field := schema.Object(map[string]*schema.Schema{
+		"value":      schema.String(),
+		"confidence": schema.Number(0, 1),
+	})
+	props := make(map[string]*schema.Schema, len(fields))
+	for _, name := range fields {
+		props[name] = field
+	}
+	return schema.Object(props, fields...)
+}
+
+// ExtractDocumentRequest 上传单据（multipart 表单字段）
+type ExtractDocumentRequest struct {
+	DocType    string `form:"type" binding:"required,oneof=contract vat_invoice"`
+	CustomerID uint64 `form:"customer_id"`
+	Party      string `form:"party" binding:"omitempty,oneof=buyer seller"` // 发票：客户是购买方还是销售方，默认购买方
+}
+
+// ConfirmDocumentRequest 确认识别结果：fields 为用户修正后的值（置信度视为 1），
+// customer_id 在上传时未指定客户时必填
+type ConfirmDocumentRequest struct {
+	CustomerID uint64            `json:"customer_id"`
+	Party      string            `json:"party" binding:"omitempty,oneof=buyer seller"`
+	Fields     map[string]string `json:"fields"`
+}
+
+// CustomerBilling 客户的开票信息
+type CustomerBilling struct {
+	InvoiceTitle string `json:"invoice_title"`
+	TaxNumber    string `json:"tax_number"`
+	BankAccount  string `json:"bank_account"`
+}
+
+// DocumentExtractionResponse 识别结果和确认后将要写入的内容：合同对应成交记录草稿，
+// 发票对应客户开票信息（附客户当前的值）；needs_review 为置信度低或必填但缺失的字段
+type DocumentExtractionResponse struct {
+	*models.DocumentExtraction
+	DealDraft      *CreateDealRequest `json:"deal_draft,omitempty"`
+	BillingDraft   *CustomerBilling   `json:"billing_draft,omitempty"`
+	CurrentBilling *CustomerBilling   `json:"current_billing,omitempty"`
+	NeedsReview    []string           `json:"needs_review"`
+}
+
+// ConfirmDocumentResponse 确认后的识别结果和创建的成交记录 / 更新的客户
+type ConfirmDocumentResponse struct {
+	Document *models.DocumentExtraction `json:"document"`
+	Deal     *DealResponse              `json:"deal,omitempty"`
+	Customer *CustomerResponse          `json:"customer,omitempty"`
+}
@@ -0,0 +1,108 @@
+package dto
+
+import (
+	"encoding/json"
+
+	"github.com/xia/nextcrm/pkg/schema"
+)
+
+// FilterOps 叶子节点支持的操作符
+var FilterOps = []string{
+	"eq", "ne", "gt", "gte", "lt", "lte", "between", "in", "not_in",
+	"contains", "is_empty", "not_empty", "older_than", "within",
+}
+
+// FilterNode 过滤条件树。分组节点只设置 and / or / not 之一；
+// 叶子节点为 field + op + value，如 {"field":"stage","op":"in","value":["Qualified","Proposal"]}
+type FilterNode struct {
+	And   []*FilterNode   `json:"and,omitempty"`
+	Or    []*FilterNode   `json:"or,omitempty"`
+	Not   *FilterNode     `json:"not,omitempty"`
+	Field string          `json:"field,omitempty"`
+	Op    string          `json:"op,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// SortSpec 排序字段，order 为 asc / desc
+type SortSpec struct {
+	Field string `json:"field"`
+	Order string `json:"order,omitempty"`
+}
+
+// QuerySpec 针对单个实体的结构化查询，结果总是限定在当前用户可见范围内
+type QuerySpec struct {
+	Entity string      `json:"entity"` // customers, deals, interactions, knowledge
+	Filter *FilterNode `json:"filter,omitempty"`
+	Sort   []SortSpec  `json:"sort,omitempty"`
+	Limit  int         `json:"limit,omitempty"`
+}
+
+// ListFilter 列表接口共用的高级过滤参数，嵌入各列表的 query 结构。
+// filter 为 JSON 编码的 FilterNode；view_id 为保存的视图，其过滤条件与 filter 取 AND，
+// 排序在没有 sort_by 时生效。Filter / Sort 由 service 解析校验后填入
+type ListFilter struct {
+	FilterJSON string      `form:"filter"`
+	ViewID     uint64      `form:"view_id"`
+	Filter     *FilterNode `form:"-"`
+	Sort       []SortSpec  `form:"-"`
+}
+
+// FilterFieldInfo 可用于过滤 / 排序的字段说明
+type FilterFieldInfo struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // string, number, time, bool, tags
+	Enum        []string `json:"enum,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// QueryResultResponse 结构化查询结果
+type QueryResultResponse struct {
+	Query   *QuerySpec  `json:"query"`
+	Total   int64       `json:"total"`
+	Results interface{} `json:"results"`
+}
+
+// NaturalLanguageQueryRequest 自然语言查询请求
+type NaturalLanguageQueryRequest struct {
+	Question string `json:"question" binding:"required"`
+}
+
+// NaturalLanguageQueryResponse 自然语言查询结果，附模型解析出的结构化查询，可检查后直接保存或修改重跑
+type NaturalLanguageQueryResponse struct {
+	Question    string      `json:"question"`
+	Explanation string      `json:"explanation"`
+	Query       *QuerySpec  `json:"query"`
+	Total       int64       `json:"total"`
+	Results     interface{} `json:"results"`
+}
+
+// NaturalLanguageQuerySchema 自然语言查询时模型输出的结构：QuerySpec 加一句说明。
+// 过滤树只约束前两层，更深的节点由 ValidateQuerySpec 校验
+var NaturalLanguageQuerySchema = func() *schema.Schema {
+	s := schema.Object(map[string]*schema.Schema{
+		"entity":      schema.String("customers", "deals", "interactions"),
+		"filter":      filterNodeSchema(2),
+		"sort":        schema.Array(schema.Object(map[string]*schema.Schema{"field": schema.String(), "order": schema.String("asc", "desc")}, "order"), 0, 3),
+		"limit":       schema.Integer(1, 200),
+		"explanation": schema.String(),
+	}, "filter", "sort", "limit")
+	return s
+}()
+
+func filterNodeSchema(depth int) *schema.Schema {
+	if depth == 0 {
+		return &schema.Schema{Type: "object"}
+	}
+	child := filterNodeSchema(depth - 1)
+	return &schema.Schema{
+		Type: "object",
+		Properties: map[string]*schema.Schema{
+			"and":   schema.Array(child, 1, 0),
+			"or":    schema.Array(child, 1, 0),
+			"not":   child,
+			"field": schema.String(),
+			"op":    schema.String(FilterOps...),
+			"value": {},
+		},
+	}
+}
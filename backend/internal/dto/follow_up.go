@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/pkg/schema"
+)
+
+// DraftFollowUpRequest 生成跟进消息草稿
+type DraftFollowUpRequest struct {
+	Channel          string  `json:"channel" binding:"required,oneof=email wechat"`
+	Tone             string  `json:"tone"`              // 如 professional, friendly, concise，默认 professional
+	Language         string  `json:"language"`          // 如 zh, en，默认 zh
+	InteractionLimit int     `json:"interaction_limit"` // 参考最近 N 条跟进记录，默认 5，最多 20
+	DealID           *uint64 `json:"deal_id"`           // 不传则使用最近一笔未结清的成交
+	Instructions     string  `json:"instructions"`      // 本次额外要求，如「提醒对方确认报价」
+}
+
+// FollowUpContent AI 生成的跟进消息
+type FollowUpContent struct {
+	Subject string   `json:"subject"` // 微信消息为空
+	Body    string   `json:"body"`
+	Facts   []string `json:"facts"` // 正文引用的历史事实
+}
+
+// FollowUpContentSchema 跟进消息的 JSON 输出结构
+var FollowUpContentSchema = schema.Object(map[string]*schema.Schema{
+	"subject": schema.String(),
+	"body":    schema.String(),
+	"facts":   schema.Array(schema.String(), 0, 10),
+})
+
+// FollowUpDraftResponse 跟进消息草稿；草稿状态下附带发送后将要创建的跟进记录
+type FollowUpDraftResponse struct {
+	*models.FollowUpDraft
+	Interaction *CreateInteractionRequest `json:"interaction,omitempty"`
+}
+
+// MarkFollowUpSentRequest 标记已发送，可传入实际发出的内容和下一步，不传则使用草稿
+type MarkFollowUpSentRequest struct {
+	Subject    *string    `json:"subject"`
+	Body       *string    `json:"body"`
+	Outcome    *string    `json:"outcome"`
+	NextAction *string    `json:"next_action"`
+	NextDate   *time.Time `json:"next_date"`
+}
@@ -35,4 +35,6 @@ type CustomerRow struct {
 	Stage       string `json:"stage"`
 	Source      string `json:"source"`
 	Notes       string `json:"notes"`
+	// 固定列之后的列：表头 → 单元格内容，按自定义字段的标签或 key 匹配
+	Extra       map[string]string `json:"extra,omitempty"`
 }
@@ -0,0 +1,36 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// StartIntakeSessionRequest 开始新建客户对话；可带上第一条消息和已知字段
+type StartIntakeSessionRequest struct {
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields"`
+}
+
+// IntakeMessageRequest 会话中的一条用户消息
+type IntakeMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// ConfirmIntakeRequest 确认创建客户：fields 为用户在确认前的最后修改，
+// force 为 true 时忽略疑似重复的客户继续创建
+type ConfirmIntakeRequest struct {
+	Fields map[string]string `json:"fields"`
+	Force  bool              `json:"force"`
+}
+
+// IntakeSessionResponse 会话当前状态；reply 为本轮 AI 回复，
+// card / transcript 为本轮上传的名片识别结果或语音转写
+type IntakeSessionResponse struct {
+	*models.IntakeSession
+	Reply      string                   `json:"reply,omitempty"`
+	Card       *BusinessCardOCRResponse `json:"card,omitempty"`
+	Transcript string                   `json:"transcript,omitempty"`
+}
+
+// ConfirmIntakeResponse 确认后的会话和新建的客户
+type ConfirmIntakeResponse struct {
+	Session  *models.IntakeSession `json:"session"`
+	Customer *CustomerResponse     `json:"customer"`
+}
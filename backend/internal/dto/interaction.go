@@ -1,6 +1,10 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+)
 
 // CreateInteractionRequest represents a request to create an interaction
 type CreateInteractionRequest struct {
@@ -11,6 +15,10 @@ type CreateInteractionRequest struct {
 	NextAction string                 `json:"next_action,omitempty"`
 	NextDate   *time.Time             `json:"next_date,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	// 涉及的联系人，须属于客户所在的账户；不提供时关联由该客户记录拆分出的联系人
+	ContactIDs []uint64 `json:"contact_ids"`
+	// 自定义字段 key → 值，按字段定义校验
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
 }
 
 // UpdateInteractionRequest represents a request to update an interaction
@@ -21,21 +29,30 @@ type UpdateInteractionRequest struct {
 	NextAction string                 `json:"next_action,omitempty"`
 	NextDate   *time.Time             `json:"next_date,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	// 提供时替换涉及的联系人，空数组表示清空
+	ContactIDs []uint64 `json:"contact_ids"`
+	// 只修改提供的自定义字段，值为 null 表示清空
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
 }
 
 // InteractionResponse represents an interaction response
 type InteractionResponse struct {
-	ID          uint64                 `json:"id"`
-	CustomerID  uint64                 `json:"customer_id"`
-	Customer    *CustomerSummary       `json:"customer,omitempty"`
-	Type        string                 `json:"type"`
-	Content     string                 `json:"content"`
-	Outcome     string                 `json:"outcome,omitempty"`
-	NextAction  string                 `json:"next_action,omitempty"`
-	NextDate    *time.Time             `json:"next_date,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	ID                uint64                     `json:"id"`
+	CustomerID        uint64                     `json:"customer_id"`
+	AccountID         *uint64                    `json:"account_id,omitempty"`
+	ContactIDs        []uint64                   `json:"contact_ids,omitempty"`
+	Customer          *CustomerSummary           `json:"customer,omitempty"`
+	Type              string                     `json:"type"`
+	Content           string                     `json:"content"`
+	Outcome           string                     `json:"outcome,omitempty"`
+	NextAction        string                     `json:"next_action,omitempty"`
+	NextDate          *time.Time                 `json:"next_date,omitempty"`
+	Metadata          map[string]interface{}     `json:"metadata,omitempty"`
+	Signals           *models.InteractionSignals `json:"signals,omitempty"`
+	SignalsAnalyzedAt *time.Time                 `json:"signals_analyzed_at,omitempty"`
+	CustomFields      map[string]interface{}     `json:"custom_fields,omitempty"`
+	CreatedAt         time.Time                  `json:"created_at"`
+	UpdatedAt         time.Time                  `json:"updated_at"`
 }
 
 // CustomerSummary represents a minimal customer info
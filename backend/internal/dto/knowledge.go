@@ -25,6 +25,11 @@ type KnowledgeQuery struct {
 	Search  string   `form:"search"`
 	Type    string   `form:"type"`
 	Tags    []string `form:"tags"`
+	SortBy    string `form:"sort_by"`    // 白名单字段，默认 created_at
+	SortOrder string `form:"sort_order"` // asc / desc，默认 desc
+
+	// 高级过滤（filter=<FilterNode JSON>）和保存的视图（view_id）
+	ListFilter
 }
 
 // KnowledgeResponse represents a knowledge base entry response
@@ -0,0 +1,9 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// LeadScoringRunResponse 手动训练的结果：新模型及重新打分的未结客户数
+type LeadScoringRunResponse struct {
+	Model  *models.LeadScoringModel `json:"model"`
+	Scored int                      `json:"scored"`
+}
@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/pkg/schema"
+)
+
+// NextActionSuggestion AI 给出的一条建议行动，按重要性排序
+type NextActionSuggestion struct {
+	CustomerID uint64 `json:"customer_id"`
+	Priority   string `json:"priority"`
+	Reason     string `json:"reason"`
+	Action     string `json:"action"`
+	ActionType string `json:"action_type"`
+	DueInDays  int    `json:"due_in_days"`
+}
+
+// NextActionPlan AI 输出的行动清单
+type NextActionPlan struct {
+	Actions []NextActionSuggestion `json:"actions"`
+}
+
+// NextActionPlanSchema 行动清单的 JSON 输出结构
+var NextActionPlanSchema = schema.Object(map[string]*schema.Schema{
+	"actions": schema.Array(schema.Object(map[string]*schema.Schema{
+		"customer_id": schema.Integer(1, 1e18),
+		"priority":    schema.String("High", "Medium", "Low"),
+		"reason":      schema.String(),
+		"action":      schema.String(),
+		"action_type": schema.String("call", "email", "meeting", "wechat", "visit"),
+		"due_in_days": schema.Integer(0, 30),
+	}), 0, 0),
+})
+
+// NextActionResponse 行动清单中的一项；未处理时附带采纳后将要创建的待办
+type NextActionResponse struct {
+	*models.NextAction
+	Task *CreateInteractionRequest `json:"task,omitempty"`
+}
+
+// AcceptNextActionRequest 采纳建议，可调整行动内容和日期，不传则使用建议
+type AcceptNextActionRequest struct {
+	Action  *string    `json:"action"`
+	DueDate *time.Time `json:"due_date"`
+}
+
+// DismissNextActionRequest 忽略建议，可附原因用于评估
+type DismissNextActionRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// NextActionStat 按某个维度（排名、来源、信号）汇总的建议处理情况
+type NextActionStat struct {
+	Key       string `json:"key"`
+	Total     int    `json:"total"`
+	Accepted  int    `json:"accepted"`
+	Dismissed int    `json:"dismissed"`
+	Expired   int    `json:"expired"`
+	Pending   int    `json:"pending"`
+	// 采纳数 / (采纳数 + 忽略数)，没有反馈时为 0
+	AcceptRate float64 `json:"accept_rate"`
+}
+
+// NextActionReport 建议的采纳情况，用于评估排序效果：排名靠前的建议应当有更高的采纳率
+type NextActionReport struct {
+	Since    time.Time         `json:"since"`
+	ByRank   []*NextActionStat `json:"by_rank"`
+	BySource []*NextActionStat `json:"by_source"`
+	BySignal []*NextActionStat `json:"by_signal"`
+}
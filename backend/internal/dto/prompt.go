@@ -0,0 +1,39 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// PromptTeamQuery 指定团队（为空表示全局模板）
+type PromptTeamQuery struct {
+	TeamID *uint64 `form:"team_id"`
+}
+
+// PromptSummary 模板当前生效情况
+type PromptSummary struct {
+	Key           string  `json:"key"`
+	Description   string  `json:"description"`
+	TeamID        *uint64 `json:"team_id,omitempty"`
+	ActiveVersion int     `json:"active_version"` // 0 表示内置模板
+	ActiveSource  string  `json:"active_source"`  // builtin, global, team
+	LatestVersion int     `json:"latest_version"`
+	Pinned        bool    `json:"pinned"`
+}
+
+// PromptDetailResponse 模板详情，包含内置内容和所有版本
+type PromptDetailResponse struct {
+	PromptSummary
+	BuiltinContent string                   `json:"builtin_content"`
+	Versions       []*models.PromptTemplate `json:"versions"`
+}
+
+// CreatePromptVersionRequest 提交新版本（Go text/template 语法）
+type CreatePromptVersionRequest struct {
+	TeamID      *uint64 `json:"team_id"`
+	Content     string  `json:"content" binding:"required"`
+	Description string  `json:"description"`
+}
+
+// PinPromptRequest 固定到某个版本（回滚即固定到旧版本，0 为内置模板）
+type PinPromptRequest struct {
+	TeamID  *uint64 `json:"team_id"`
+	Version int     `json:"version" binding:"min=0"`
+}
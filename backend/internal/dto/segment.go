@@ -0,0 +1,37 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// CreateSegmentRequest 保存客户分群；filter 与 /query 中 customers 的过滤树相同
+type CreateSegmentRequest struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	Filter      *FilterNode `json:"filter" binding:"required"`
+	Shared      bool        `json:"shared"`
+}
+
+// UpdateSegmentRequest 修改分群，未提供的项保持不变
+type UpdateSegmentRequest struct {
+	Name        *string     `json:"name"`
+	Description *string     `json:"description"`
+	Filter      *FilterNode `json:"filter"`
+	Shared      *bool       `json:"shared"`
+}
+
+// SegmentResponse 分群及当前匹配的客户数；editable 表示当前用户是创建人
+type SegmentResponse struct {
+	*models.Segment
+	Count    int64 `json:"count"`
+	Editable bool  `json:"editable"`
+}
+
+// SegmentPreviewRequest 保存前预览过滤条件匹配的客户数
+type SegmentPreviewRequest struct {
+	Filter *FilterNode `json:"filter" binding:"required"`
+}
+
+// SegmentCustomersQuery 分群成员分页参数
+type SegmentCustomersQuery struct {
+	Page    int `form:"page,default=1"`
+	PerPage int `form:"per_page,default=20"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/xia/nextcrm/pkg/schema"
+
+// InteractionSignalsSchema 跟进信号（models.InteractionSignals）的 JSON 输出结构
+var InteractionSignalsSchema = schema.Object(map[string]*schema.Schema{
+	"sentiment":      schema.String("positive", "neutral", "negative"),
+	"intent_level":   schema.String("High", "Medium", "Low"),
+	"buying_signals": schema.Array(schema.String(), 0, 10),
+	"objections":     schema.Array(schema.String(), 0, 10),
+	"competitors":    schema.Array(schema.String(), 0, 10),
+	"budget":         schema.String(),
+	"timeline":       schema.String(),
+	"evidence":       schema.Array(schema.String(), 0, 10),
+})
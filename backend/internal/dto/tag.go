@@ -0,0 +1,60 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// CreateTagGroupRequest 新建标签分组；team_id 为空表示全局分组，创建后不可修改
+type CreateTagGroupRequest struct {
+	TeamID    *uint64 `json:"team_id"`
+	Name      string  `json:"name" binding:"required"`
+	Color     string  `json:"color"`
+	Exclusive bool    `json:"exclusive"`
+	Position  int     `json:"position"`
+}
+
+// UpdateTagGroupRequest 修改标签分组，未提供的项保持不变；改为互斥时不会调整客户已有的标签
+type UpdateTagGroupRequest struct {
+	Name      *string `json:"name"`
+	Color     *string `json:"color"`
+	Exclusive *bool   `json:"exclusive"`
+	Position  *int    `json:"position"`
+}
+
+// TagGroupListQuery 管理端按团队列出分组（为空时列出全部）
+type TagGroupListQuery struct {
+	TeamID *uint64 `form:"team_id"`
+}
+
+// CreateTagRequest 在分组下新建标签
+type CreateTagRequest struct {
+	GroupID  uint64 `json:"group_id" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Color    string `json:"color"`
+	Position int    `json:"position"`
+}
+
+// UpdateTagRequest 修改标签，改名同步到已打此标签的客户
+type UpdateTagRequest struct {
+	Name     *string `json:"name"`
+	Color    *string `json:"color"`
+	Position *int    `json:"position"`
+}
+
+// TagGroupWithTags 分组及其标签
+type TagGroupWithTags struct {
+	*models.TagGroup
+	Tags []*models.Tag `json:"tags"`
+}
+
+// BulkTagRequest 批量打标签 / 去标签：目标为 customer_ids 或 segment_id 之一
+type BulkTagRequest struct {
+	CustomerIDs []uint64 `json:"customer_ids"`
+	SegmentID   uint64   `json:"segment_id"`
+	Add         []string `json:"add"`
+	Remove      []string `json:"remove"`
+}
+
+// BulkTagResult 批量标签结果：targeted 为目标客户数，updated 为标签实际有变化的客户数
+type BulkTagResult struct {
+	Targeted int   `json:"targeted"`
+	Updated  int64 `json:"updated"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// CreateTeamRequest represents a request to create a team
+type CreateTeamRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// AssignUserTeamRequest assigns a user to a team; null removes the user from any team
+type AssignUserTeamRequest struct {
+	TeamID *uint64 `json:"team_id"`
+}
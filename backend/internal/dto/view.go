@@ -0,0 +1,35 @@
+package dto
+
+import "github.com/xia/nextcrm/internal/models"
+
+// CreateViewRequest 保存列表视图。entity 为 customers / deals / interactions / knowledge；
+// columns 为可过滤字段名或 cf.<key>，sort 最多 3 个字段
+type CreateViewRequest struct {
+	Entity  string      `json:"entity" binding:"required"`
+	Name    string      `json:"name" binding:"required"`
+	Filter  *FilterNode `json:"filter"`
+	Columns []string    `json:"columns"`
+	Sort    []SortSpec  `json:"sort"`
+	Shared  bool        `json:"shared"`
+}
+
+// UpdateViewRequest 修改视图，未提供的项保持不变；clear_filter 去掉过滤条件
+type UpdateViewRequest struct {
+	Name        *string     `json:"name"`
+	Filter      *FilterNode `json:"filter"`
+	ClearFilter bool        `json:"clear_filter"`
+	Columns     []string    `json:"columns"`
+	Sort        []SortSpec  `json:"sort"`
+	Shared      *bool       `json:"shared"`
+}
+
+// ViewListQuery 列出某个实体的视图
+type ViewListQuery struct {
+	Entity string `form:"entity"`
+}
+
+// ViewResponse 视图；editable 表示当前用户是创建人
+type ViewResponse struct {
+	*models.SavedView
+	Editable bool `json:"editable"`
+}
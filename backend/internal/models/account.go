@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 联系人在采购决策中的角色；为空表示未标注
+const (
+	ContactRoleDecisionMaker = "decision_maker"
+	ContactRoleInfluencer    = "influencer"
+	ContactRoleChampion      = "champion"
+	ContactRoleBlocker       = "blocker"
+)
+
+// Account 客户公司；同一公司的多个客户记录和联系人归在一个账户下
+type Account struct {
+	ID                uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID            uint64         `gorm:"not null;index" json:"user_id"`
+	Name              string         `gorm:"not null;size:255" json:"name"`
+	Industry          string         `json:"industry,omitempty"`
+	CompanyScale      string         `json:"company_scale,omitempty"`
+	RegisteredCapital string         `json:"registered_capital,omitempty"`
+	LegalPerson       string         `json:"legal_person,omitempty"`
+	CreditCode        string         `json:"credit_code,omitempty"`
+	Address           string         `json:"address,omitempty"`
+	InvoiceTitle      string         `json:"invoice_title,omitempty"`
+	TaxNumber         string         `json:"tax_number,omitempty"`
+	BankAccount       string         `json:"bank_account,omitempty"`
+	Notes             string         `json:"notes,omitempty"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName specifies the table name for Account model
+func (Account) TableName() string {
+	return "accounts"
+}
+
+// Contact 账户中的联系人；CustomerID 不为空时由该客户记录拆分而来，客户的个人信息修改后同步
+type Contact struct {
+	ID         uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	AccountID  uint64         `gorm:"not null;index" json:"account_id"`
+	UserID     uint64         `gorm:"not null;index" json:"user_id"`
+	CustomerID *uint64        `gorm:"index" json:"customer_id,omitempty"`
+	Name       string         `gorm:"not null;size:255" json:"name"`
+	Position   string         `json:"position,omitempty"`
+	Phone      string         `json:"phone,omitempty"`
+	Email      string         `json:"email,omitempty"`
+	WechatID   string         `json:"wechat_id,omitempty"`
+	Role       string         `gorm:"size:32" json:"role,omitempty"`
+	IsPrimary  bool           `gorm:"not null;default:false" json:"is_primary"`
+	Notes      string         `json:"notes,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName specifies the table name for Contact model
+func (Contact) TableName() string {
+	return "contacts"
+}
+
+// InteractionContact 跟进记录涉及的联系人
+type InteractionContact struct {
+	InteractionID uint64 `gorm:"primaryKey"`
+	ContactID     uint64 `gorm:"primaryKey"`
+}
+
+// TableName specifies the table name for InteractionContact model
+func (InteractionContact) TableName() string {
+	return "interaction_contacts"
+}
+
+// DealContact 成交涉及的联系人
+type DealContact struct {
+	DealID    uint64 `gorm:"primaryKey"`
+	ContactID uint64 `gorm:"primaryKey"`
+}
+
+// TableName specifies the table name for DealContact model
+func (DealContact) TableName() string {
+	return "deal_contacts"
+}
@@ -4,6 +4,12 @@ import (
 	"time"
 )
 
+// ActivityStageChange 客户阶段变更，由后端在更新客户时自动记录
+const ActivityStageChange = "stage_change"
+
+// ActivityIntentChange 客户意向等级 / 潜力评分变更，由销售采纳意向建议时记录
+const ActivityIntentChange = "intent_change"
+
 // Activity represents a user action or AI-generated event
 type Activity struct {
 	ID             uint           `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AIEmbeddingCache 按厂商、模型和文本摘要缓存的向量
+type AIEmbeddingCache struct {
+	ID        uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	Provider  string          `gorm:"not null;size:64;uniqueIndex:idx_ai_embedding_cache_key" json:"provider"`
+	Model     string          `gorm:"not null;size:128;uniqueIndex:idx_ai_embedding_cache_key" json:"model"`
+	TextHash  string          `gorm:"not null;size:64;uniqueIndex:idx_ai_embedding_cache_key;index" json:"text_hash"`
+	Embedding pq.Float32Array `gorm:"type:real[];not null" json:"embedding"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// TableName specifies the table name for AIEmbeddingCache model
+func (AIEmbeddingCache) TableName() string {
+	return "ai_embedding_cache"
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AIPrivacyPolicy 团队的 AI 隐私策略，整体覆盖全局默认配置
+type AIPrivacyPolicy struct {
+	ID              uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	TeamID          uint64         `gorm:"not null;uniqueIndex" json:"team_id"`
+	Redaction       bool           `gorm:"not null;default:true" json:"redaction"`     // 是否脱敏
+	RedactTypes     pq.StringArray `gorm:"type:text[]" json:"redact_types"`            // 脱敏的信息类型
+	ForbiddenFields pq.StringArray `gorm:"type:text[]" json:"forbidden_fields"`        // 禁止外发的客户字段（JSON 名）
+	BlockImages     bool           `gorm:"not null;default:false" json:"block_images"` // 禁止把图片（如名片）发给外部模型
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for AIPrivacyPolicy model
+func (AIPrivacyPolicy) TableName() string {
+	return "ai_privacy_policies"
+}
+
+// AIAuditLog 一次发往外部模型的请求，Payload 为脱敏后实际发送的内容
+type AIAuditLog struct {
+	ID            uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID        *uint64        `gorm:"index" json:"user_id,omitempty"`
+	TeamID        *uint64        `gorm:"index" json:"team_id,omitempty"`
+	Feature       string         `gorm:"not null;size:32" json:"feature"`
+	Capability    string         `gorm:"not null;size:16" json:"capability"`
+	Provider      string         `gorm:"size:64" json:"provider"`
+	Success       bool           `gorm:"not null;default:true" json:"success"`
+	ErrorMessage  string         `gorm:"type:text" json:"error_message,omitempty"`
+	Payload       string         `gorm:"type:text" json:"payload,omitempty"`
+	Redactions    map[string]int `gorm:"type:jsonb;serializer:json" json:"redactions,omitempty"` // 各类型被替换的不同值个数
+	DroppedFields pq.StringArray `gorm:"type:text[]" json:"dropped_fields,omitempty"`
+	ImageSHA256   string         `gorm:"size:64" json:"image_sha256,omitempty"`
+	ImageBytes    int            `gorm:"not null;default:0" json:"image_bytes,omitempty"`
+	CreatedAt     time.Time      `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for AIAuditLog model
+func (AIAuditLog) TableName() string {
+	return "ai_audit_logs"
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// AI 功能（用于计量和成本报表）
+const (
+	AIFeatureScript     = "script"
+	AIFeatureAnalyze    = "analyze"
+	AIFeatureIntake     = "intake"
+	AIFeatureOCR        = "ocr"
+	AIFeatureASR        = "asr"
+	AIFeatureEmbedding  = "embedding"
+	AIFeatureQuery      = "query"
+	AIFeatureCall       = "call"
+	AIFeatureFollowUp   = "follow_up"
+	AIFeatureSignals    = "signals"
+	AIFeatureNextAction = "next_action"
+	AIFeatureDocument   = "document"
+)
+
+// 额度作用范围
+const (
+	AIQuotaScopeUser = "user"
+	AIQuotaScopeTeam = "team"
+)
+
+// AIUsageLog 一次 AI 厂商调用记录
+type AIUsageLog struct {
+	ID               uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID           *uint64   `gorm:"index" json:"user_id,omitempty"`
+	TeamID           *uint64   `gorm:"index" json:"team_id,omitempty"`
+	Feature          string    `gorm:"not null;size:32" json:"feature"`
+	Capability       string    `gorm:"not null;size:16" json:"capability"`
+	Provider         string    `gorm:"not null;size:64" json:"provider"`
+	Model            string    `gorm:"size:128" json:"model"`
+	PromptTokens     int       `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int       `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens      int       `gorm:"not null;default:0" json:"total_tokens"`
+	LatencyMs        int64     `gorm:"not null;default:0" json:"latency_ms"`
+	Success          bool      `gorm:"not null;default:true" json:"success"`
+	ErrorMessage     string    `gorm:"type:text" json:"error_message,omitempty"`
+	Cost             float64   `gorm:"type:decimal(18,6);not null;default:0" json:"cost"`
+	PromptVersions   string    `gorm:"size:255" json:"prompt_versions,omitempty"` // 如 script.system@0,script.user@3
+	CreatedAt        time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for AIUsageLog model
+func (AIUsageLog) TableName() string {
+	return "ai_usage_logs"
+}
+
+// AIQuota 用户或团队的 token 额度，覆盖全局默认值，0 表示不限
+type AIQuota struct {
+	ID                uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Scope             string    `gorm:"not null;size:16;uniqueIndex:idx_ai_quotas_scope" json:"scope"`
+	ScopeID           uint64    `gorm:"not null;uniqueIndex:idx_ai_quotas_scope" json:"scope_id"`
+	DailyTokenLimit   int64     `gorm:"not null;default:0" json:"daily_token_limit"`
+	MonthlyTokenLimit int64     `gorm:"not null;default:0" json:"monthly_token_limit"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for AIQuota model
+func (AIQuota) TableName() string {
+	return "ai_quotas"
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 客户批量操作
+const (
+	BulkUpdate   = "update"
+	BulkArchive  = "archive"
+	BulkRestore  = "restore"
+	BulkDelete   = "delete"
+	BulkReassign = "reassign"
+)
+
+// 批量操作任务状态
+const (
+	BulkJobQueued    = "queued"
+	BulkJobRunning   = "running"
+	BulkJobCompleted = "completed"
+	BulkJobFailed    = "failed"
+)
+
+// ActivityBulkOperation 批量操作完成后的汇总记录，EntityID 为任务 ID
+const ActivityBulkOperation = "bulk_operation"
+
+// BulkRowResult 单个客户的处理结果
+type BulkRowResult struct {
+	CustomerID uint64 `json:"customer_id"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BulkJob 一次客户批量操作。目标客户在提交时确定（ID 列表、分群或过滤条件），
+// 逐个调用单条操作的 service 方法，所有权检查与单条操作一致；选中较多时在后台执行
+type BulkJob struct {
+	ID          uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint64          `gorm:"not null;index" json:"user_id"`
+	Operation   string          `gorm:"not null;size:16" json:"operation"`
+	Status      string          `gorm:"not null;size:16;default:'queued'" json:"status"`
+	Params      json.RawMessage `gorm:"type:jsonb;serializer:json" json:"params,omitempty"`
+	CustomerIDs []uint64        `gorm:"type:jsonb;serializer:json" json:"-"`
+
+	// Progress
+	Total     int `gorm:"not null;default:0" json:"total"`
+	Done      int `gorm:"not null;default:0" json:"done"`
+	Succeeded int `gorm:"not null;default:0" json:"succeeded"`
+	Failed    int `gorm:"not null;default:0" json:"failed"`
+
+	Results    []BulkRowResult `gorm:"type:jsonb;serializer:json" json:"results,omitempty"`
+	ActivityID *uint64         `json:"activity_id,omitempty"`
+	Error      string          `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// TableName specifies the table name for BulkJob model
+func (BulkJob) TableName() string {
+	return "bulk_jobs"
+}
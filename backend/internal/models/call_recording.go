@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 通话录音处理状态
+const (
+	CallRecordingPending   = "pending_confirmation" // AI 已生成跟进记录草稿，等待销售确认
+	CallRecordingConfirmed = "confirmed"
+	CallRecordingDiscarded = "discarded"
+)
+
+// TranscriptSegment 转写片段；Start / End 为秒，未知时为 0；Speaker 仅在区分说话人时有值
+type TranscriptSegment struct {
+	Start   float64 `json:"start,omitempty"`
+	End     float64 `json:"end,omitempty"`
+	Speaker string  `json:"speaker,omitempty"`
+	Text    string  `json:"text"`
+}
+
+// CallRecording 通话录音的转写、AI 总结和跟进记录草稿（录音文件本身不保存）
+type CallRecording struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint64 `gorm:"not null;index" json:"user_id"`
+	CustomerID uint64 `gorm:"not null;index" json:"customer_id"`
+	FileName   string `gorm:"size:255" json:"file_name"`
+	Status     string `gorm:"not null;size:32;default:'pending_confirmation'" json:"status"`
+
+	// Transcript
+	Provider   string              `gorm:"size:64" json:"provider"`
+	Duration   float64             `gorm:"not null;default:0" json:"duration"` // 秒，未知时为 0
+	Confidence *float64            `json:"confidence,omitempty"`               // 厂商不返回时为空
+	Transcript string              `gorm:"type:text" json:"transcript"`
+	Segments   []TranscriptSegment `gorm:"type:jsonb;serializer:json" json:"segments,omitempty"`
+
+	// AI summary
+	Summary     string         `gorm:"type:text" json:"summary"`
+	KeyPoints   pq.StringArray `gorm:"type:text[]" json:"key_points"`
+	Objections  pq.StringArray `gorm:"type:text[]" json:"objections"`
+	Commitments pq.StringArray `gorm:"type:text[]" json:"commitments"`
+	NextSteps   pq.StringArray `gorm:"type:text[]" json:"next_steps"`
+
+	// Interaction draft
+	Outcome       string     `gorm:"size:16" json:"outcome"` // positive, neutral, negative
+	NextAction    string     `json:"next_action"`
+	NextDate      *time.Time `json:"next_date,omitempty"`
+	InteractionID *uint64    `json:"interaction_id,omitempty"` // 确认后生成的跟进记录
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for CallRecording model
+func (CallRecording) TableName() string {
+	return "call_recordings"
+}
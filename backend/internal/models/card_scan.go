@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// 批量名片识别任务状态
+const (
+	CardScanQueued    = "queued"
+	CardScanRunning   = "running"
+	CardScanCompleted = "completed"
+	CardScanFailed    = "failed"
+)
+
+// 单张名片状态：pending 等待识别，failed 识别失败，review 等待审核，
+// approved 已创建客户，merged 已合并到已有客户，discarded 已丢弃
+const (
+	CardItemPending   = "pending"
+	CardItemFailed    = "failed"
+	CardItemReview    = "review"
+	CardItemApproved  = "approved"
+	CardItemMerged    = "merged"
+	CardItemDiscarded = "discarded"
+)
+
+// CardScanBatch 一次批量上传的名片（多张图片或 ZIP），由 worker 池并行识别（图片本身不保存）
+type CardScanBatch struct {
+	ID     uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID uint64 `gorm:"not null;index" json:"user_id"`
+	Status string `gorm:"not null;size:16;default:'queued'" json:"status"`
+
+	// Progress
+	TotalCards  int `gorm:"not null;default:0" json:"total_cards"`
+	DoneCards   int `gorm:"not null;default:0" json:"done_cards"`
+	FailedCards int `gorm:"not null;default:0" json:"failed_cards"`
+
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for CardScanBatch model
+func (CardScanBatch) TableName() string {
+	return "card_scan_batches"
+}
+
+// CardMatch 与名片匹配的已有客户；MatchedOn 为命中的字段（phone / email / company）
+type CardMatch struct {
+	CustomerID uint64   `json:"customer_id"`
+	Name       string   `json:"name"`
+	Company    string   `json:"company"`
+	Phone      string   `json:"phone,omitempty"`
+	Email      string   `json:"email,omitempty"`
+	MatchedOn  []string `json:"matched_on"`
+}
+
+// CardScanItem 批次中的一张名片：识别结果和匹配到的已有客户，等待审核
+type CardScanItem struct {
+	ID       uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	BatchID  uint64 `gorm:"not null;index" json:"batch_id"`
+	UserID   uint64 `gorm:"not null;index" json:"user_id"`
+	FileName string `gorm:"size:255" json:"file_name"`
+	Status   string `gorm:"not null;size:16;default:'pending'" json:"status"`
+
+	// Recognized fields
+	Name       string  `gorm:"size:255" json:"name"`
+	Company    string  `gorm:"size:255" json:"company"`
+	Position   string  `gorm:"size:255" json:"position"`
+	Phone      string  `gorm:"size:64" json:"phone"`
+	Email      string  `gorm:"size:255" json:"email"`
+	Address    string  `gorm:"type:text" json:"address"`
+	RawText    string  `gorm:"type:text" json:"raw_text,omitempty"`
+	Provider   string  `gorm:"size:32" json:"provider,omitempty"`
+	Confidence float64 `gorm:"not null;default:0" json:"confidence"`
+
+	Matches []CardMatch `gorm:"type:jsonb;serializer:json" json:"matches,omitempty"`
+	Error   string      `gorm:"type:text" json:"error,omitempty"`
+
+	// Review
+	CustomerID *uint64    `json:"customer_id,omitempty"` // 创建或合并到的客户
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for CardScanItem model
+func (CardScanItem) TableName() string {
+	return "card_scan_items"
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// 可以定义自定义字段的实体
+const (
+	CustomFieldEntityCustomer    = "customer"
+	CustomFieldEntityDeal        = "deal"
+	CustomFieldEntityInteraction = "interaction"
+)
+
+// 自定义字段类型；取值在 jsonb 中分别保存为字符串、数字、YYYY-MM-DD、字符串、字符串数组和用户 ID
+const (
+	CustomFieldText        = "text"
+	CustomFieldNumber      = "number"
+	CustomFieldDate        = "date"
+	CustomFieldSelect      = "select"
+	CustomFieldMultiSelect = "multi_select"
+	CustomFieldUser        = "user"
+)
+
+// CustomFieldValidation 取值校验规则：Min / Max 用于数字，MaxLength / Pattern 用于文本
+type CustomFieldValidation struct {
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"` // Go 正则，须匹配整个值
+}
+
+// CustomFieldDefinition 管理员定义的自定义字段；TeamID 为空表示全局，团队定义覆盖同 key 的全局定义
+type CustomFieldDefinition struct {
+	ID         uint64                `gorm:"primaryKey;autoIncrement" json:"id"`
+	Entity     string                `gorm:"not null;size:16" json:"entity"`
+	TeamID     *uint64               `gorm:"index" json:"team_id,omitempty"`
+	Key        string                `gorm:"not null;size:64" json:"key"`
+	Label      string                `gorm:"not null;size:128" json:"label"`
+	Type       string                `gorm:"not null;size:16" json:"type"`
+	Options    []string              `gorm:"type:jsonb;serializer:json" json:"options,omitempty"`
+	Required   bool                  `gorm:"not null;default:false" json:"required"`
+	Validation CustomFieldValidation `gorm:"type:jsonb;serializer:json" json:"validation"`
+	Position   int                   `gorm:"not null;default:0" json:"position"`
+	CreatedBy  uint64                `json:"created_by"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// TableName specifies the table name for CustomFieldDefinition model
+func (CustomFieldDefinition) TableName() string {
+	return "custom_field_definitions"
+}
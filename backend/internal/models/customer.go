@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -55,6 +56,18 @@ type Customer struct {
 	BankAccount        string `json:"bank_account,omitempty"`
 	PaymentTerms       string `json:"payment_terms,omitempty"`
 
+	// 所属账户（公司），个人信息同时保存为账户下的联系人
+	AccountID *uint64 `gorm:"index" json:"account_id,omitempty"`
+
+	// 合并后指向保留的客户，本记录同时软删除
+	MergedIntoID *uint64 `json:"merged_into_id,omitempty"`
+
+	// 自定义字段取值（key → 值），定义见 CustomFieldDefinition
+	CustomFields map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"custom_fields,omitempty"`
+
+	// 标签名，取自 Tag；只在创建时随记录写入，之后通过 TagRepository 单独修改
+	Tags pq.StringArray `gorm:"type:text[];default:'{}';<-:create" json:"tags"`
+
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
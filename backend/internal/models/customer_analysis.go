@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CustomerAnalysis AI 客户分析快照，按时间保留，便于对比 AI 判断与实际结果
+type CustomerAnalysis struct {
+	ID              uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	CustomerID      uint64         `gorm:"not null;index" json:"customer_id"`
+	UserID          uint64         `gorm:"not null;index" json:"user_id"`
+	AnalysisType    string         `gorm:"size:32" json:"analysis_type"`
+	Summary         string         `gorm:"type:text" json:"summary"`
+	IntentScore     int            `gorm:"not null;default:0" json:"intent_score"`
+	RiskLevel       string         `gorm:"size:16" json:"risk_level"`
+	Opportunities   pq.StringArray `gorm:"type:text[]" json:"opportunities"`
+	Recommendations pq.StringArray `gorm:"type:text[]" json:"recommendations"`
+	NextActions     pq.StringArray `gorm:"type:text[]" json:"next_actions"`
+
+	// 分析时客户所处阶段和成交概率
+	Stage       string `gorm:"size:50" json:"stage"`
+	Probability int    `gorm:"not null;default:0" json:"probability"`
+
+	PromptVersions string    `gorm:"size:255" json:"prompt_versions,omitempty"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for CustomerAnalysis model
+func (CustomerAnalysis) TableName() string {
+	return "customer_analyses"
+}
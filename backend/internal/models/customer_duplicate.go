@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// 疑似重复客户组状态：open 待处理，dismissed 已忽略（再次扫描到相同的组时不再提示），merged 已合并
+const (
+	DuplicateClusterOpen      = "open"
+	DuplicateClusterDismissed = "dismissed"
+	DuplicateClusterMerged    = "merged"
+)
+
+// 合并记录状态
+const (
+	CustomerMergeMerged = "merged"
+	CustomerMergeUndone = "undone"
+)
+
+// ActivityCustomerMerge 客户合并，记录在保留的客户上
+const ActivityCustomerMerge = "customer_merge"
+
+// 判定重复的依据
+const (
+	DuplicateOnPhone      = "phone"
+	DuplicateOnEmail      = "email"
+	DuplicateOnWechatID   = "wechat_id"
+	DuplicateOnCreditCode = "credit_code"
+	DuplicateOnName       = "name_company" // 姓名和公司都相似
+)
+
+// DuplicateLink 组内两个客户之间的重复依据；Similarity 为姓名和公司相似度的平均值（精确匹配时为 1）
+type DuplicateLink struct {
+	CustomerID uint64   `json:"customer_id"`
+	OtherID    uint64   `json:"other_id"`
+	MatchedOn  []string `json:"matched_on"`
+	Similarity float64  `json:"similarity"`
+}
+
+// DuplicateCluster 后台扫描得到的一组疑似重复客户
+type DuplicateCluster struct {
+	ID          uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint64          `gorm:"not null;index" json:"user_id"`
+	MemberKey   string          `gorm:"not null" json:"-"`
+	CustomerIDs []uint64        `gorm:"type:jsonb;serializer:json;not null" json:"customer_ids"`
+	Links       []DuplicateLink `gorm:"type:jsonb;serializer:json" json:"links"`
+	Score       float64         `gorm:"not null;default:0" json:"score"`
+	Status      string          `gorm:"not null;size:16;default:'open'" json:"status"`
+	ScannedAt   time.Time       `json:"scanned_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// TableName specifies the table name for DuplicateCluster model
+func (DuplicateCluster) TableName() string {
+	return "customer_duplicate_clusters"
+}
+
+// MergeMovedRows 合并时从被合并客户迁移到保留客户的一张表里的记录，撤销时按此迁回
+type MergeMovedRows struct {
+	Table          string   `json:"table"`
+	FromCustomerID uint64   `json:"from_customer_id"`
+	IDs            []uint64 `json:"ids"`
+}
+
+// CustomerMerge 一次客户合并；撤销期内可以恢复被合并的客户、迁回关联记录并还原保留客户的字段
+type CustomerMerge struct {
+	ID             uint64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID         uint64            `gorm:"not null;index" json:"user_id"`
+	SurvivorID     uint64            `gorm:"not null" json:"survivor_id"`
+	MergedIDs      []uint64          `gorm:"type:jsonb;serializer:json;not null" json:"merged_ids"`
+	ClusterID      *uint64           `json:"cluster_id,omitempty"`
+	FieldSources   map[string]uint64 `gorm:"type:jsonb;serializer:json" json:"field_sources,omitempty"` // 字段 JSON 名 → 取值的客户
+	SurvivorBefore Customer          `gorm:"type:jsonb;serializer:json;not null" json:"-"`
+	Moved          []MergeMovedRows  `gorm:"type:jsonb;serializer:json" json:"moved"`
+	Status         string            `gorm:"not null;size:16;default:'merged'" json:"status"`
+	UndoUntil      time.Time         `json:"undo_until"`
+	UndoneAt       *time.Time        `json:"undone_at,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// TableName specifies the table name for CustomerMerge model
+func (CustomerMerge) TableName() string {
+	return "customer_merges"
+}
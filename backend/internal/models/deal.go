@@ -12,6 +12,7 @@ type Deal struct {
 	RecordNo         string          `gorm:"not null;uniqueIndex" json:"record_no"`
 	UserID           uint64          `gorm:"not null;index" json:"user_id"`
 	CustomerID       uint64          `gorm:"not null;index" json:"customer_id"`
+	AccountID        *uint64         `gorm:"index" json:"account_id,omitempty"` // 客户所属账户，创建时确定
 	DealType         string          `gorm:"not null;default:'sale'" json:"deal_type"`
 	ProductOrService string          `gorm:"not null" json:"product_or_service"`
 	Quantity         float64         `gorm:"not null;default:1" json:"quantity"`
@@ -26,6 +27,7 @@ type Deal struct {
 	IsRepeatPurchase bool            `gorm:"not null;default:false" json:"is_repeat_purchase"`
 	DealAt           time.Time       `gorm:"not null;index" json:"deal_at"`
 	Notes            string          `json:"notes,omitempty"`
+	CustomFields     map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"custom_fields,omitempty"`
 	CreatedAt        time.Time       `json:"created_at"`
 	UpdatedAt        time.Time       `json:"updated_at"`
 	DeletedAt        gorm.DeletedAt  `gorm:"index" json:"-"`
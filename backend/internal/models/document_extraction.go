@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// 识别的单据类型
+const (
+	DocumentContract   = "contract"
+	DocumentVATInvoice = "vat_invoice"
+)
+
+// 识别结果状态
+const (
+	DocumentDraft     = "draft"
+	DocumentConfirmed = "confirmed"
+	DocumentDiscarded = "discarded"
+)
+
+// 发票中客户所在的一方
+const (
+	InvoicePartyBuyer  = "buyer"
+	InvoicePartySeller = "seller"
+)
+
+// DocumentField 识别出的一个字段；Confidence 为 0~1，Note 为后端校验发现的问题
+type DocumentField struct {
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+	Note       string  `json:"note,omitempty"`
+}
+
+// DocumentExtraction 合同或增值税发票的识别结果（文件本身不保存）；
+// 确认后合同生成成交记录，发票写入客户的开票信息
+type DocumentExtraction struct {
+	ID         uint64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint64  `gorm:"not null;index" json:"user_id"`
+	CustomerID *uint64 `gorm:"index" json:"customer_id,omitempty"`
+	DocType    string  `gorm:"not null;size:16" json:"doc_type"`
+	Party      string  `gorm:"size:16" json:"party,omitempty"`
+	FileName   string  `gorm:"size:255" json:"file_name"`
+	MimeType   string  `gorm:"size:64" json:"mime_type"`
+	Status     string  `gorm:"not null;size:16;default:'draft'" json:"status"`
+
+	Fields   map[string]DocumentField `gorm:"type:jsonb;serializer:json" json:"fields"`
+	Provider string                   `gorm:"size:32" json:"provider,omitempty"`
+
+	DealID      *uint64    `json:"deal_id,omitempty"` // 合同确认后创建的成交记录
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for DocumentExtraction model
+func (DocumentExtraction) TableName() string {
+	return "document_extractions"
+}
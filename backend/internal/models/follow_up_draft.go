@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 跟进消息渠道，发送后作为同名类型的跟进记录保存
+const (
+	FollowUpChannelEmail  = "email"
+	FollowUpChannelWechat = "wechat"
+)
+
+// 跟进消息草稿状态
+const (
+	FollowUpDraftPending   = "draft"
+	FollowUpDraftSent      = "sent"
+	FollowUpDraftDiscarded = "discarded"
+)
+
+// FollowUpDraft AI 根据客户历史生成的跟进邮件 / 微信消息草稿，发送后保存为跟进记录
+type FollowUpDraft struct {
+	ID         uint64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint64  `gorm:"not null;index" json:"user_id"`
+	CustomerID uint64  `gorm:"not null;index" json:"customer_id"`
+	DealID     *uint64 `json:"deal_id,omitempty"` // 生成时参考的未结清成交
+	Channel    string  `gorm:"not null;size:16" json:"channel"`
+	Tone       string  `gorm:"size:32" json:"tone"`
+	Language   string  `gorm:"size:16" json:"language"`
+	Status     string  `gorm:"not null;size:16;default:'draft'" json:"status"`
+
+	Subject string         `gorm:"size:255" json:"subject,omitempty"` // 仅邮件
+	Body    string         `gorm:"type:text" json:"body"`
+	Facts   pq.StringArray `gorm:"type:text[]" json:"facts"` // 草稿中引用的历史事实，便于核对
+
+	StyleGuideIDs  pq.Int64Array `gorm:"type:bigint[]" json:"style_guide_ids"` // 生效的团队风格指南（知识库条目）
+	PromptVersions string        `gorm:"size:255" json:"prompt_versions,omitempty"`
+
+	InteractionID *uint64    `json:"interaction_id,omitempty"` // 发送后生成的跟进记录
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for FollowUpDraft model
+func (FollowUpDraft) TableName() string {
+	return "follow_up_drafts"
+}
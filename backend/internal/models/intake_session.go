@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// 新建客户对话会话状态；collecting / ready_for_confirmation 与 AI 回复的 status 一致
+const (
+	IntakeCollecting           = "collecting"
+	IntakeReadyForConfirmation = "ready_for_confirmation"
+	IntakeConfirmed            = "confirmed"
+)
+
+// 会话消息来源：用户输入的文字、名片识别结果、语音转写
+const (
+	IntakeSourceText         = "text"
+	IntakeSourceBusinessCard = "business_card"
+	IntakeSourceVoice        = "voice"
+)
+
+// IntakeMessage 会话中的一条消息
+type IntakeMessage struct {
+	Role      string    `json:"role"` // user | assistant
+	Content   string    `json:"content"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IntakeSession 服务端保存的新建客户对话：消息历史和已提取的字段，确认后创建客户；
+// 每次对话顺延过期时间，过期且未确认的会话定期清理
+type IntakeSession struct {
+	ID       uint64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID   uint64            `gorm:"not null;index" json:"user_id"`
+	Status   string            `gorm:"not null;size:32;default:'collecting'" json:"status"`
+	Messages []IntakeMessage   `gorm:"type:jsonb;serializer:json" json:"messages"`
+	Fields   map[string]string `gorm:"type:jsonb;serializer:json" json:"fields"`
+	Summary  string            `gorm:"type:text" json:"summary,omitempty"`
+
+	CustomerID  *uint64    `json:"customer_id,omitempty"` // 确认后创建的客户
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for IntakeSession model
+func (IntakeSession) TableName() string {
+	return "ai_intake_sessions"
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// 意向建议状态
+const (
+	IntentProposalPending    = "pending"
+	IntentProposalAccepted   = "accepted"
+	IntentProposalRejected   = "rejected"
+	IntentProposalSuperseded = "superseded" // 有了更新的建议，或客户已达到建议值
+)
+
+// IntentEvidence 建议所依据的一条跟进记录及其信号；Weight 为按时间衰减后的权重
+type IntentEvidence struct {
+	InteractionID uint64              `json:"interaction_id"`
+	Date          time.Time           `json:"date"`
+	Type          string              `json:"type"`
+	Score         int                 `json:"score"`
+	Weight        float64             `json:"weight"`
+	Signals       *InteractionSignals `json:"signals"`
+}
+
+// IntentProposal 根据近期跟进信号汇总出的意向等级 / 潜力评分调整建议，客户负责人采纳后才更新客户
+type IntentProposal struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint64 `gorm:"not null;index" json:"user_id"`
+	CustomerID uint64 `gorm:"not null;index" json:"customer_id"`
+	Status     string `gorm:"not null;size:16;default:'pending'" json:"status"`
+
+	CurrentIntentLevel     string `gorm:"size:16" json:"current_intent_level"`
+	ProposedIntentLevel    string `gorm:"size:16" json:"proposed_intent_level"`
+	CurrentPotentialScore  int    `json:"current_potential_score"`
+	ProposedPotentialScore int    `json:"proposed_potential_score"`
+
+	Evidence []IntentEvidence `gorm:"type:jsonb;serializer:json" json:"evidence"`
+
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for IntentProposal model
+func (IntentProposal) TableName() string {
+	return "intent_proposals"
+}
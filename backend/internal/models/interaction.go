@@ -11,13 +11,21 @@ type Interaction struct {
 	ID          uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
 	UserID      uint64         `gorm:"not null;index" json:"user_id"`
 	CustomerID  uint64         `gorm:"not null;index" json:"customer_id"`
+	AccountID   *uint64        `gorm:"index" json:"account_id,omitempty"` // 客户所属账户，创建时确定
 
-	Type        string         `gorm:"not null" json:"type"` // call, email, meeting, note
+	Type        string         `gorm:"not null" json:"type"` // call, email, meeting, note, wechat
 	Content     string         `gorm:"type:text" json:"content"`
 	Outcome     string         `json:"outcome,omitempty"` // positive, neutral, negative
 	NextAction  string         `json:"next_action,omitempty"`
 	NextDate    *time.Time     `json:"next_date,omitempty"`
 
+	// AI 提取的情绪和意向信号，内容保存后异步生成
+	Signals           *InteractionSignals `gorm:"type:jsonb;serializer:json" json:"signals,omitempty"`
+	SignalsAnalyzedAt *time.Time          `json:"signals_analyzed_at,omitempty"`
+
+	// 自定义字段取值（key → 值），定义见 CustomFieldDefinition
+	CustomFields map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"custom_fields,omitempty"`
+
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
@@ -26,6 +34,18 @@ type Interaction struct {
 	Customer    *Customer      `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
 }
 
+// InteractionSignals AI 从跟进内容中提取的情绪和购买意向信号
+type InteractionSignals struct {
+	Sentiment     string   `json:"sentiment"`    // positive, neutral, negative
+	IntentLevel   string   `json:"intent_level"` // 本次沟通体现的意向：High, Medium, Low
+	BuyingSignals []string `json:"buying_signals"`
+	Objections    []string `json:"objections"`
+	Competitors   []string `json:"competitors"`
+	Budget        string   `json:"budget"`   // 预算线索，没有则为空
+	Timeline      string   `json:"timeline"` // 采购 / 决策时间线索，没有则为空
+	Evidence      []string `json:"evidence"` // 支撑判断的原文摘录
+}
+
 // TableName specifies the table name for Interaction model
 func (Interaction) TableName() string {
 	return "interactions"
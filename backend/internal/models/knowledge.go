@@ -7,6 +7,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// KnowledgeTypeStyleGuide 团队写作风格指南：同一团队成员的这类条目在生成跟进消息时共同生效
+const KnowledgeTypeStyleGuide = "style_guide"
+
 // KnowledgeBase represents a knowledge base entry
 type KnowledgeBase struct {
 	ID          uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -14,7 +17,7 @@ type KnowledgeBase struct {
 
 	Title       string         `gorm:"type:text;not null" json:"title"`
 	Content     string         `gorm:"type:text;not null" json:"content"`
-	Type        string         `gorm:"not null" json:"type"` // sales_script, product_info, faq, best_practice, objection_handling
+	Type        string         `gorm:"not null" json:"type"` // sales_script, product_info, faq, best_practice, objection_handling, style_guide
 	Tags        pq.StringArray `gorm:"type:text[]" json:"tags,omitempty"`
 	Description string         `gorm:"type:text" json:"description,omitempty"`
 
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/pkg/logreg"
+)
+
+// LeadScoringModel 基于历史成交 / 流失客户训练的线索评分模型（逻辑回归），每次训练一条记录
+type LeadScoringModel struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	Samples   int    `gorm:"not null" json:"samples"`
+	Positives int    `gorm:"not null" json:"positives"` // 成交样本数
+
+	// 特征取值表（类别特征的 one-hot 取值）和模型参数
+	Categories map[string][]string `gorm:"type:jsonb;serializer:json" json:"categories"`
+	Params     *logreg.Model       `gorm:"type:jsonb;serializer:json" json:"params"`
+
+	// 留出集（约 20%）上的指标，样本太少时为 0
+	HoldoutSamples int     `json:"holdout_samples"`
+	HoldoutAUC     float64 `json:"holdout_auc"`
+	HoldoutLogLoss float64 `json:"holdout_log_loss"`
+
+	TrainedAt time.Time `gorm:"not null" json:"trained_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for LeadScoringModel model
+func (LeadScoringModel) TableName() string {
+	return "lead_scoring_models"
+}
+
+// FeatureContribution 单个特征对评分的贡献；Contribution 为对 log-odds 的影响，正数推高评分
+type FeatureContribution struct {
+	Feature      string  `json:"feature"`
+	Value        string  `json:"value"`
+	Contribution float64 `json:"contribution"`
+}
+
+// LeadScore 客户最新的模型评分（每个客户一条，每晚重算）
+type LeadScore struct {
+	CustomerID    uint64                `gorm:"primaryKey" json:"customer_id"`
+	UserID        uint64                `gorm:"not null;index" json:"user_id"`
+	ModelID       uint64                `gorm:"not null" json:"model_id"`
+	Score         int                   `gorm:"not null" json:"score"` // 0-100
+	Probability   float64               `gorm:"not null" json:"probability"`
+	Contributions []FeatureContribution `gorm:"type:jsonb;serializer:json" json:"contributions"`
+	ScoredAt      time.Time             `gorm:"not null" json:"scored_at"`
+
+	Customer *Customer `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
+}
+
+// TableName specifies the table name for LeadScore model
+func (LeadScore) TableName() string {
+	return "lead_scores"
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 建议行动状态
+const (
+	NextActionPending   = "pending"
+	NextActionAccepted  = "accepted"  // 已生成待办（带下一步行动的跟进记录）
+	NextActionDismissed = "dismissed" // 销售认为不需要
+	NextActionExpired   = "expired"   // 当天未处理，被新的行动清单取代
+)
+
+// 建议来源
+const (
+	NextActionSourceAI    = "ai"    // 规则筛选 + AI 排序和建议
+	NextActionSourceRules = "rules" // AI 不可用时仅按规则生成
+)
+
+// 规则信号
+const (
+	NextActionSignalIdle           = "idle"            // 长时间未跟进
+	NextActionSignalCloseDate      = "close_date"      // 预计成交日期临近或已过
+	NextActionSignalOverduePayment = "overdue_payment" // 成交后超期未回款
+	NextActionSignalRenewal        = "renewal"         // 合同即将到期
+	NextActionSignalNoValue        = "no_contract_value"
+	NextActionSignalLowIntent      = "low_intent"
+	NextActionSignalHighLeadScore  = "high_lead_score" // 线索评分模型给出高分
+)
+
+// NextAction 销售每日行动清单中的一项：规则信号 + AI 结合客户近期历史给出的具体行动
+type NextAction struct {
+	ID         uint64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint64  `gorm:"not null;index" json:"user_id"`
+	CustomerID uint64  `gorm:"not null;index" json:"customer_id"`
+	DealID     *uint64 `json:"deal_id,omitempty"`
+
+	PlanDate time.Time `gorm:"not null" json:"plan_date"` // 当天零点（服务器本地时间）
+	Rank     int       `gorm:"not null" json:"rank"`      // 清单中的位置，从 1 开始
+	Priority string    `gorm:"not null" json:"priority"`  // High, Medium, Low
+	Source   string    `gorm:"not null" json:"source"`
+
+	Signals    pq.StringArray `gorm:"type:text[]" json:"signals"`
+	RuleScore  int            `gorm:"not null" json:"rule_score"`
+	Reason     string         `gorm:"type:text" json:"reason"`
+	Action     string         `gorm:"type:text;not null" json:"action"`
+	ActionType string         `gorm:"not null" json:"action_type"` // call, email, meeting, wechat, visit
+	DueDate    *time.Time     `json:"due_date,omitempty"`
+
+	Status         string     `gorm:"not null;index" json:"status"`
+	DismissReason  string     `json:"dismiss_reason,omitempty"`
+	InteractionID  *uint64    `json:"interaction_id,omitempty"` // 采纳后生成的待办
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	PromptVersions string     `json:"prompt_versions,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	Customer *Customer `gorm:"foreignKey:CustomerID" json:"customer,omitempty"`
+}
+
+// TableName specifies the table name for NextAction model
+func (NextAction) TableName() string {
+	return "next_actions"
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// PromptTemplate 提示词模板的一个版本（Go text/template），TeamID 为空表示全局模板
+type PromptTemplate struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key         string    `gorm:"not null;size:64;index" json:"key"`
+	TeamID      *uint64   `gorm:"index" json:"team_id,omitempty"`
+	Version     int       `gorm:"not null" json:"version"`
+	Content     string    `gorm:"not null;type:text" json:"content"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	CreatedBy   uint64    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for PromptTemplate model
+func (PromptTemplate) TableName() string {
+	return "prompt_templates"
+}
+
+// PromptTemplatePin 固定使用某个版本（回滚即固定到旧版本），没有固定时使用最新版本
+type PromptTemplatePin struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key       string    `gorm:"not null;size:64" json:"key"`
+	TeamID    *uint64   `json:"team_id,omitempty"`
+	Version   int       `gorm:"not null" json:"version"` // 0 表示内置模板
+	PinnedBy  uint64    `json:"pinned_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for PromptTemplatePin model
+func (PromptTemplatePin) TableName() string {
+	return "prompt_template_pins"
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SavedView 保存的列表视图：某个实体列表的过滤树（见 dto.FilterNode）、显示列和排序（dto.SortSpec）。
+// Shared 时同团队成员可以查看和使用，只有创建人可以修改
+type SavedView struct {
+	ID        uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint64          `gorm:"not null;index" json:"user_id"`
+	TeamID    *uint64         `gorm:"index" json:"team_id,omitempty"`
+	Entity    string          `gorm:"not null;size:32" json:"entity"`
+	Name      string          `gorm:"not null;size:128" json:"name"`
+	Filter    json.RawMessage `gorm:"type:jsonb;serializer:json" json:"filter,omitempty"`
+	Columns   []string        `gorm:"type:jsonb;serializer:json" json:"columns"`
+	Sort      json.RawMessage `gorm:"type:jsonb;serializer:json" json:"sort"`
+	Shared    bool            `gorm:"not null;default:false" json:"shared"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt gorm.DeletedAt  `gorm:"index" json:"-"`
+}
+
+// TableName specifies the table name for SavedView model
+func (SavedView) TableName() string {
+	return "saved_views"
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Segment 保存的客户筛选条件（过滤树，见 dto.FilterNode），每次使用时按当前数据重新计算；
+// Shared 时同团队成员可以查看和使用，只有创建人可以修改
+type Segment struct {
+	ID          uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint64          `gorm:"not null;index" json:"user_id"`
+	TeamID      *uint64         `gorm:"index" json:"team_id,omitempty"`
+	Name        string          `gorm:"not null;size:128" json:"name"`
+	Description string          `json:"description,omitempty"`
+	Filter      json.RawMessage `gorm:"type:jsonb;serializer:json" json:"filter"`
+	Shared      bool            `gorm:"not null;default:false" json:"shared"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
+}
+
+// TableName specifies the table name for Segment model
+func (Segment) TableName() string {
+	return "segments"
+}
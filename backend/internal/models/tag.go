@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// TagGroup 客户标签分组；TeamID 为空表示全局，Exclusive 表示同一客户在组内最多一个标签
+type TagGroup struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TeamID    *uint64   `gorm:"index" json:"team_id,omitempty"`
+	Name      string    `gorm:"not null;size:64" json:"name"`
+	Color     string    `gorm:"size:16" json:"color,omitempty"`
+	Exclusive bool      `gorm:"not null;default:false" json:"exclusive"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedBy uint64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for TagGroup model
+func (TagGroup) TableName() string {
+	return "tag_groups"
+}
+
+// Tag 分组中的标签；客户的 Tags 保存标签名，同一范围（全局及各团队）内标签名不区分大小写唯一
+type Tag struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID   uint64    `gorm:"not null;index" json:"group_id"`
+	TeamID    *uint64   `gorm:"index" json:"team_id,omitempty"`
+	Name      string    `gorm:"not null;size:64" json:"name"`
+	Color     string    `gorm:"size:16" json:"color,omitempty"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Tag model
+func (Tag) TableName() string {
+	return "tags"
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Team 销售团队，用户通过 users.team_id 归属团队
+type Team struct {
+	ID        uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string         `gorm:"not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName specifies the table name for Team model
+func (Team) TableName() string {
+	return "teams"
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 长录音转写任务状态
+const (
+	TranscriptionQueued    = "queued"
+	TranscriptionRunning   = "running"
+	TranscriptionSucceeded = "succeeded"
+	TranscriptionFailed    = "failed"
+)
+
+// TranscriptionJob 长录音异步转写任务：音频切片后并行识别，按时间偏移拼接结果（音频文件本身不保存）
+type TranscriptionJob struct {
+	ID       uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID   uint64 `gorm:"not null;index" json:"user_id"`
+	FileName string `gorm:"size:255" json:"file_name"`
+	Format   string `gorm:"size:16" json:"format"`
+	Language string `gorm:"size:16" json:"language"`
+	Status   string `gorm:"not null;size:16;default:'queued'" json:"status"`
+
+	// Progress
+	TotalChunks int `gorm:"not null;default:0" json:"total_chunks"`
+	DoneChunks  int `gorm:"not null;default:0" json:"done_chunks"`
+
+	// Result
+	Providers pq.StringArray      `gorm:"type:text[]" json:"providers"` // 实际完成识别的厂商（含降级）
+	Duration  float64             `gorm:"not null;default:0" json:"duration"`
+	Text      string              `gorm:"type:text" json:"text"`
+	Segments  []TranscriptSegment `gorm:"type:jsonb;serializer:json" json:"segments,omitempty"`
+	Error     string              `gorm:"type:text" json:"error,omitempty"`
+
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for TranscriptionJob model
+func (TranscriptionJob) TableName() string {
+	return "transcription_jobs"
+}
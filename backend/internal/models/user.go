@@ -40,6 +40,7 @@ type User struct {
 	AvatarURL          *string        `json:"avatarUrl,omitempty"`
 	Profile            *UserProfile   `gorm:"type:jsonb" json:"profile,omitempty"`
 	Role               string         `gorm:"default:'USER'" json:"role"`
+	TeamID             *uint64        `gorm:"index" json:"team_id,omitempty"`
 	IsActive           bool           `gorm:"default:true" json:"is_active"`
 	CreatedAt          time.Time      `json:"created_at"`
 	UpdatedAt          time.Time      `json:"updated_at"`
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type AccountRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to tx
+func (r *AccountRepository) WithTx(tx *gorm.DB) *AccountRepository {
+	return &AccountRepository{db: tx}
+}
+
+func (r *AccountRepository) Create(account *models.Account) error {
+	return r.db.Create(account).Error
+}
+
+func (r *AccountRepository) Update(account *models.Account) error {
+	return r.db.Save(account).Error
+}
+
+func (r *AccountRepository) FindByID(id uint64) (*models.Account, error) {
+	var account models.Account
+	if err := r.db.Where("id = ?", id).First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// FindByName finds a user's account by name, ignoring case and surrounding spaces
+func (r *AccountRepository) FindByName(userID uint64, name string) (*models.Account, error) {
+	var account models.Account
+	err := r.db.Where("user_id = ? AND LOWER(TRIM(name)) = LOWER(TRIM(?))", userID, name).
+		Order("id").
+		First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// List lists a user's accounts by name with pagination
+func (r *AccountRepository) List(userID uint64, query *dto.AccountQuery) ([]*models.Account, int64, error) {
+	var accounts []*models.Account
+	var total int64
+
+	db := r.db.Model(&models.Account{}).Where("user_id = ?", userID)
+	if query.Search != "" {
+		search := "%" + query.Search + "%"
+		db = db.Where("name ILIKE ? OR credit_code ILIKE ?", search, search)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("name, id").
+		Offset((query.Page - 1) * query.PerPage).
+		Limit(query.PerPage).
+		Find(&accounts).Error
+	return accounts, total, err
+}
+
+// Delete soft deletes an account and its contacts
+func (r *AccountRepository) Delete(id uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", id).Delete(&models.Contact{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Account{}, id).Error
+	})
+}
+
+// FindCustomers finds the active customers of an account
+func (r *AccountRepository) FindCustomers(accountID uint64) ([]*models.Customer, error) {
+	var customers []*models.Customer
+	err := r.db.Where("account_id = ?", accountID).Order("created_at").Find(&customers).Error
+	return customers, err
+}
+
+// MoveCustomer moves a customer to another account together with its interactions, deals and the contacts split from it
+func (r *AccountRepository) MoveCustomer(customerID, accountID uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"customers", "interactions", "deals"} {
+			column := "customer_id"
+			if table == "customers" {
+				column = "id"
+			}
+			if err := tx.Table(table).Where(column+" = ?", customerID).
+				Update("account_id", accountID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Contact{}).Where("customer_id = ?", customerID).
+			Updates(map[string]interface{}{"account_id": accountID, "is_primary": false}).Error
+	})
+}
+
+func (r *AccountRepository) CreateContact(contact *models.Contact) error {
+	return r.db.Create(contact).Error
+}
+
+func (r *AccountRepository) UpdateContact(contact *models.Contact) error {
+	return r.db.Save(contact).Error
+}
+
+// SetPrimaryContact marks a contact as its account's only primary contact
+func (r *AccountRepository) SetPrimaryContact(contact *models.Contact) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Contact{}).
+			Where("account_id = ? AND id <> ? AND is_primary", contact.AccountID, contact.ID).
+			Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(contact).Update("is_primary", true).Error
+	})
+}
+
+func (r *AccountRepository) DeleteContact(id uint64) error {
+	return r.db.Delete(&models.Contact{}, id).Error
+}
+
+func (r *AccountRepository) FindContactByID(id uint64) (*models.Contact, error) {
+	var contact models.Contact
+	if err := r.db.Where("id = ?", id).First(&contact).Error; err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// FindContactsByAccountID lists an account's contacts, primary contact first
+func (r *AccountRepository) FindContactsByAccountID(accountID uint64) ([]*models.Contact, error) {
+	var contacts []*models.Contact
+	err := r.db.Where("account_id = ?", accountID).Order("is_primary DESC, id").Find(&contacts).Error
+	return contacts, err
+}
+
+// FindContactsByCustomerID finds the contacts split from a customer
+func (r *AccountRepository) FindContactsByCustomerID(customerID uint64) ([]*models.Contact, error) {
+	var contacts []*models.Contact
+	err := r.db.Where("customer_id = ?", customerID).Order("id").Find(&contacts).Error
+	return contacts, err
+}
+
+// CountContacts counts an account's contacts
+func (r *AccountRepository) CountContacts(accountID uint64) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Contact{}).Where("account_id = ?", accountID).Count(&count).Error
+	return count, err
+}
+
+// FindContactIDsInAccount returns which of the given contacts belong to the account
+func (r *AccountRepository) FindContactIDsInAccount(accountID uint64, ids []uint64) ([]uint64, error) {
+	var found []uint64
+	err := r.db.Model(&models.Contact{}).
+		Where("account_id = ? AND id IN ?", accountID, ids).
+		Pluck("id", &found).Error
+	return found, err
+}
+
+// ReplaceInteractionContacts replaces the contacts linked to an interaction
+func (r *AccountRepository) ReplaceInteractionContacts(interactionID uint64, contactIDs []uint64) error {
+	return r.replaceContactLinks("interaction_contacts", "interaction_id", interactionID, contactIDs)
+}
+
+// ReplaceDealContacts replaces the contacts linked to a deal
+func (r *AccountRepository) ReplaceDealContacts(dealID uint64, contactIDs []uint64) error {
+	return r.replaceContactLinks("deal_contacts", "deal_id", dealID, contactIDs)
+}
+
+// InteractionContactIDs returns the live contacts linked to each interaction, primary contact first
+func (r *AccountRepository) InteractionContactIDs(interactionIDs []uint64) (map[uint64][]uint64, error) {
+	return r.linkedContactIDs("interaction_contacts", "interaction_id", interactionIDs)
+}
+
+// DealContactIDs returns the live contacts linked to each deal, primary contact first
+func (r *AccountRepository) DealContactIDs(dealIDs []uint64) (map[uint64][]uint64, error) {
+	return r.linkedContactIDs("deal_contacts", "deal_id", dealIDs)
+}
+
+func (r *AccountRepository) replaceContactLinks(table, column string, id uint64, contactIDs []uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE "+column+" = ?", id).Error; err != nil {
+			return err
+		}
+		if len(contactIDs) == 0 {
+			return nil
+		}
+		return tx.Exec("INSERT INTO "+table+" ("+column+", contact_id) SELECT ?, id FROM contacts WHERE id IN ?",
+			id, contactIDs).Error
+	})
+}
+
+func (r *AccountRepository) linkedContactIDs(table, column string, ids []uint64) (map[uint64][]uint64, error) {
+	out := make(map[uint64][]uint64)
+	if len(ids) == 0 {
+		return out, nil
+	}
+	var rows []struct {
+		OwnerID   uint64
+		ContactID uint64
+	}
+	err := r.db.Table(table+" l").
+		Select("l."+column+" AS owner_id, l.contact_id").
+		Joins("JOIN contacts c ON c.id = l.contact_id AND c.deleted_at IS NULL").
+		Where("l."+column+" IN ?", ids).
+		Order("c.is_primary DESC, c.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		out[row.OwnerID] = append(out[row.OwnerID], row.ContactID)
+	}
+	return out, nil
+}
+
+// 账户时间线各类记录的查询；? 依次为账户 ID 和（可选的）联系人 ID
+var timelineSources = map[string]struct {
+	query        string
+	contactQuery string // 只看涉及某个联系人的记录；为空表示该类记录不关联联系人
+}{
+	"interaction": {
+		query: `SELECT 'interaction' AS type, i.id, i.customer_id, i.created_at AS occurred_at, i.type AS title,
+  COALESCE(i.content, '') AS content, NULL::numeric AS amount, '' AS currency
+FROM interactions i WHERE i.account_id = ? AND i.deleted_at IS NULL`,
+		contactQuery: ` AND EXISTS (SELECT 1 FROM interaction_contacts ic WHERE ic.interaction_id = i.id AND ic.contact_id = ?)`,
+	},
+	"deal": {
+		query: `SELECT 'deal' AS type, d.id, d.customer_id, d.deal_at AS occurred_at, d.product_or_service AS title,
+  COALESCE(d.notes, '') AS content, d.amount, d.currency
+FROM deals d WHERE d.account_id = ? AND d.deleted_at IS NULL`,
+		contactQuery: ` AND EXISTS (SELECT 1 FROM deal_contacts dc WHERE dc.deal_id = d.id AND dc.contact_id = ?)`,
+	},
+	"activity": {
+		query: `SELECT 'activity' AS type, a.id, a.customer_id, a.created_at AS occurred_at, a.action_type AS title,
+  a.description AS content, NULL::numeric AS amount, '' AS currency
+FROM activities a JOIN customers c ON c.id = a.customer_id
+WHERE c.account_id = ? AND a.deleted_at IS NULL`,
+	},
+}
+
+// Timeline lists an account's interactions, deals and customer activities (including those of archived
+// customers), newest first; with contactID only the interactions and deals linked to that contact
+func (r *AccountRepository) Timeline(accountID uint64, types []string, contactID uint64, offset, limit int) ([]*dto.AccountTimelineItem, int64, error) {
+	var parts []string
+	var args []interface{}
+	for _, t := range types {
+		src, ok := timelineSources[t]
+		if !ok {
+			continue
+		}
+		if contactID > 0 {
+			if src.contactQuery == "" {
+				continue
+			}
+			parts = append(parts, src.query+src.contactQuery)
+			args = append(args, accountID, contactID)
+			continue
+		}
+		parts = append(parts, src.query)
+		args = append(args, accountID)
+	}
+	items := []*dto.AccountTimelineItem{}
+	if len(parts) == 0 {
+		return items, 0, nil
+	}
+	union := strings.Join(parts, "\nUNION ALL\n")
+
+	var total int64
+	if err := r.db.Raw("SELECT COUNT(*) FROM ("+union+") t", args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := r.db.Raw("SELECT * FROM ("+union+") t ORDER BY occurred_at DESC, id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...).Scan(&items).Error
+	return items, total, err
+}
@@ -67,6 +67,16 @@ func (r *ActivityRepository) GetByCustomerID(customerID uint64, limit int) ([]*m
 	return activities, err
 }
 
+// GetByCustomerAndAction retrieves a customer's activities of one action type
+func (r *ActivityRepository) GetByCustomerAndAction(customerID uint64, actionType string, limit int) ([]*models.Activity, error) {
+	var activities []*models.Activity
+	err := r.db.Where("customer_id = ? AND action_type = ? AND deleted_at IS NULL", customerID, actionType).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&activities).Error
+	return activities, err
+}
+
 // GetRevenueHistory retrieves revenue history for a user
 func (r *ActivityRepository) GetRevenueHistory(userID uint64, months int) ([]*models.RevenueHistory, error) {
 	var history []*models.RevenueHistory
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AICacheRepository struct {
+	db *gorm.DB
+}
+
+func NewAICacheRepository(db *gorm.DB) *AICacheRepository {
+	return &AICacheRepository{db: db}
+}
+
+// FindEmbedding finds a cached embedding for the given provider, model and text hash
+func (r *AICacheRepository) FindEmbedding(provider, model, textHash string) (*models.AIEmbeddingCache, error) {
+	var cached models.AIEmbeddingCache
+	err := r.db.Where("provider = ? AND model = ? AND text_hash = ?", provider, model, textHash).First(&cached).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// SaveEmbedding stores an embedding, keeping the existing row if one is already cached
+func (r *AICacheRepository) SaveEmbedding(cached *models.AIEmbeddingCache) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(cached).Error
+}
+
+// DeleteEmbeddings removes cached embeddings of a text for all providers and models
+func (r *AICacheRepository) DeleteEmbeddings(textHash string) (int64, error) {
+	result := r.db.Delete(&models.AIEmbeddingCache{}, "text_hash = ?", textHash)
+	return result.RowsAffected, result.Error
+}
+
+// CountEmbeddings returns the number of cached embeddings
+func (r *AICacheRepository) CountEmbeddings() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.AIEmbeddingCache{}).Count(&count).Error
+	return count, err
+}
+
+// PurgeEmbeddings removes all cached embeddings
+func (r *AICacheRepository) PurgeEmbeddings() (int64, error) {
+	result := r.db.Where("1 = 1").Delete(&models.AIEmbeddingCache{})
+	return result.RowsAffected, result.Error
+}
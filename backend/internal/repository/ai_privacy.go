@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AIPrivacyRepository struct {
+	db *gorm.DB
+}
+
+func NewAIPrivacyRepository(db *gorm.DB) *AIPrivacyRepository {
+	return &AIPrivacyRepository{db: db}
+}
+
+// FindPolicy finds the privacy policy override for a team
+func (r *AIPrivacyRepository) FindPolicy(teamID uint64) (*models.AIPrivacyPolicy, error) {
+	var policy models.AIPrivacyPolicy
+	err := r.db.Where("team_id = ?", teamID).First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ListPolicies returns all team privacy policies
+func (r *AIPrivacyRepository) ListPolicies() ([]*models.AIPrivacyPolicy, error) {
+	var policies []*models.AIPrivacyPolicy
+	err := r.db.Order("team_id ASC").Find(&policies).Error
+	return policies, err
+}
+
+// UpsertPolicy creates or replaces the privacy policy of a team
+func (r *AIPrivacyRepository) UpsertPolicy(policy *models.AIPrivacyPolicy) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "team_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"redaction", "redact_types", "forbidden_fields", "block_images", "updated_at"}),
+	}).Create(policy).Error
+}
+
+// DeletePolicy removes the privacy policy of a team
+func (r *AIPrivacyRepository) DeletePolicy(teamID uint64) error {
+	result := r.db.Delete(&models.AIPrivacyPolicy{}, "team_id = ?", teamID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateAuditLog records one request sent to an external model
+func (r *AIPrivacyRepository) CreateAuditLog(log *models.AIAuditLog) error {
+	return r.db.Create(log).Error
+}
+
+// ListAuditLogs retrieves audit logs matching the query with pagination, newest first
+func (r *AIPrivacyRepository) ListAuditLogs(query *dto.AIAuditLogQuery) ([]*models.AIAuditLog, int64, error) {
+	var logs []*models.AIAuditLog
+	var total int64
+
+	db := r.db.Model(&models.AIAuditLog{})
+	if query.UserID != 0 {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.TeamID != 0 {
+		db = db.Where("team_id = ?", query.TeamID)
+	}
+	if query.Feature != "" {
+		db = db.Where("feature = ?", query.Feature)
+	}
+	if query.From != nil {
+		db = db.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("created_at < ?", query.To.AddDate(0, 0, 1))
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (query.Page - 1) * query.PerPage
+	err := db.Order("created_at DESC, id DESC").
+		Limit(query.PerPage).
+		Offset(offset).
+		Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
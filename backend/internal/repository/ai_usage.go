@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AIUsageRepository struct {
+	db *gorm.DB
+}
+
+func NewAIUsageRepository(db *gorm.DB) *AIUsageRepository {
+	return &AIUsageRepository{db: db}
+}
+
+// Create records one AI provider call
+func (r *AIUsageRepository) Create(log *models.AIUsageLog) error {
+	return r.db.Create(log).Error
+}
+
+// SumTokensByUser returns the tokens a user has consumed since the given time
+func (r *AIUsageRepository) SumTokensByUser(userID uint64, since time.Time) (int64, error) {
+	return r.sumTokens("user_id = ?", userID, since)
+}
+
+// SumTokensByTeam returns the tokens a team has consumed since the given time
+func (r *AIUsageRepository) SumTokensByTeam(teamID uint64, since time.Time) (int64, error) {
+	return r.sumTokens("team_id = ?", teamID, since)
+}
+
+func (r *AIUsageRepository) sumTokens(where string, id uint64, since time.Time) (int64, error) {
+	var total int64
+	err := r.db.Model(&models.AIUsageLog{}).
+		Where(where, id).
+		Where("created_at >= ?", since).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// Report aggregates calls, tokens and cost by team and feature within [from, to)
+func (r *AIUsageRepository) Report(from, to time.Time) ([]*dto.AIUsageReportRow, error) {
+	var rows []*dto.AIUsageReportRow
+	err := r.db.Table("ai_usage_logs AS l").
+		Select(`l.team_id, COALESCE(t.name, '') AS team_name, l.feature,
+			COUNT(*) AS calls,
+			COUNT(*) FILTER (WHERE NOT l.success) AS failed_calls,
+			COALESCE(SUM(l.prompt_tokens), 0) AS prompt_tokens,
+			COALESCE(SUM(l.completion_tokens), 0) AS completion_tokens,
+			COALESCE(SUM(l.total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(l.cost), 0) AS cost,
+			COALESCE(AVG(l.latency_ms), 0) AS avg_latency_ms`).
+		Joins("LEFT JOIN teams t ON t.id = l.team_id").
+		Where("l.created_at >= ? AND l.created_at < ?", from, to).
+		Group("l.team_id, t.name, l.feature").
+		Order("cost DESC, total_tokens DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// FindQuota finds the quota override for a user or team
+func (r *AIUsageRepository) FindQuota(scope string, scopeID uint64) (*models.AIQuota, error) {
+	var quota models.AIQuota
+	err := r.db.Where("scope = ? AND scope_id = ?", scope, scopeID).First(&quota).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// ListQuotas returns all quota overrides
+func (r *AIUsageRepository) ListQuotas() ([]*models.AIQuota, error) {
+	var quotas []*models.AIQuota
+	err := r.db.Order("scope ASC, scope_id ASC").Find(&quotas).Error
+	return quotas, err
+}
+
+// UpsertQuota creates or replaces the quota override for (scope, scope_id)
+func (r *AIUsageRepository) UpsertQuota(quota *models.AIQuota) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}, {Name: "scope_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"daily_token_limit", "monthly_token_limit", "updated_at"}),
+	}).Create(quota).Error
+}
+
+// DeleteQuota removes a quota override
+func (r *AIUsageRepository) DeleteQuota(id uint64) error {
+	result := r.db.Delete(&models.AIQuota{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
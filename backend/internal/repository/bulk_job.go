@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type BulkJobRepository struct {
+	db *gorm.DB
+}
+
+func NewBulkJobRepository(db *gorm.DB) *BulkJobRepository {
+	return &BulkJobRepository{db: db}
+}
+
+// Create saves a new job
+func (r *BulkJobRepository) Create(job *models.BulkJob) error {
+	return r.db.Create(job).Error
+}
+
+// FindByID finds a job by ID, including its per-row results
+func (r *BulkJobRepository) FindByID(id uint64) (*models.BulkJob, error) {
+	var job models.BulkJob
+	if err := r.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListByUserID lists a user's jobs, newest first, without per-row results
+func (r *BulkJobRepository) ListByUserID(userID uint64, limit int) ([]*models.BulkJob, error) {
+	var jobs []*models.BulkJob
+	err := r.db.Omit("results", "customer_ids").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Start marks a job as running
+func (r *BulkJobRepository) Start(id uint64) error {
+	return r.db.Model(&models.BulkJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.BulkJobRunning,
+		"started_at": time.Now(),
+	}).Error
+}
+
+// UpdateProgress records the counters of a running job
+func (r *BulkJobRepository) UpdateProgress(job *models.BulkJob) error {
+	return r.db.Model(&models.BulkJob{}).Where("id = ?", job.ID).UpdateColumns(map[string]interface{}{
+		"done":      job.Done,
+		"succeeded": job.Succeeded,
+		"failed":    job.Failed,
+	}).Error
+}
+
+// Update saves all fields of a job
+func (r *BulkJobRepository) Update(job *models.BulkJob) error {
+	return r.db.Save(job).Error
+}
+
+// FailUnfinished marks queued or running jobs as failed; used at startup since jobs run in-process
+func (r *BulkJobRepository) FailUnfinished(reason string) (int64, error) {
+	result := r.db.Model(&models.BulkJob{}).
+		Where("status IN ?", []string{models.BulkJobQueued, models.BulkJobRunning}).
+		Updates(map[string]interface{}{
+			"status":      models.BulkJobFailed,
+			"error":       reason,
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
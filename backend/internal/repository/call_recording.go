@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type CallRecordingRepository struct {
+	db *gorm.DB
+}
+
+func NewCallRecordingRepository(db *gorm.DB) *CallRecordingRepository {
+	return &CallRecordingRepository{db: db}
+}
+
+// Create saves a call recording
+func (r *CallRecordingRepository) Create(recording *models.CallRecording) error {
+	return r.db.Create(recording).Error
+}
+
+// FindByID finds a call recording by ID
+func (r *CallRecordingRepository) FindByID(id uint64) (*models.CallRecording, error) {
+	var recording models.CallRecording
+	if err := r.db.First(&recording, id).Error; err != nil {
+		return nil, err
+	}
+	return &recording, nil
+}
+
+// ListByCustomerID lists a customer's call recordings, newest first
+func (r *CallRecordingRepository) ListByCustomerID(customerID uint64) ([]*models.CallRecording, error) {
+	var recordings []*models.CallRecording
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Find(&recordings).Error
+	return recordings, err
+}
+
+// Update saves all fields of a call recording
+func (r *CallRecordingRepository) Update(recording *models.CallRecording) error {
+	return r.db.Save(recording).Error
+}
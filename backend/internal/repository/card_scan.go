@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type CardScanRepository struct {
+	db *gorm.DB
+}
+
+func NewCardScanRepository(db *gorm.DB) *CardScanRepository {
+	return &CardScanRepository{db: db}
+}
+
+// CreateBatch saves a batch together with its pending items
+func (r *CardScanRepository) CreateBatch(batch *models.CardScanBatch, items []*models.CardScanItem) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			item.BatchID = batch.ID
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(items, 100).Error
+	})
+}
+
+// FindBatchByID finds a batch by ID
+func (r *CardScanRepository) FindBatchByID(id uint64) (*models.CardScanBatch, error) {
+	var batch models.CardScanBatch
+	if err := r.db.First(&batch, id).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListBatches lists a user's batches, newest first
+func (r *CardScanRepository) ListBatches(userID uint64, limit int) ([]*models.CardScanBatch, error) {
+	var batches []*models.CardScanBatch
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&batches).Error
+	return batches, err
+}
+
+// StartBatch marks a batch as running
+func (r *CardScanRepository) StartBatch(id uint64) error {
+	return r.db.Model(&models.CardScanBatch{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.CardScanRunning,
+		"started_at": time.Now(),
+	}).Error
+}
+
+// IncrementProgress records one more processed card; failed cards are also counted as done
+func (r *CardScanRepository) IncrementProgress(id uint64, failed bool) error {
+	updates := map[string]interface{}{"done_cards": gorm.Expr("done_cards + 1")}
+	if failed {
+		updates["failed_cards"] = gorm.Expr("failed_cards + 1")
+	}
+	return r.db.Model(&models.CardScanBatch{}).Where("id = ?", id).UpdateColumns(updates).Error
+}
+
+// UpdateBatch saves all fields of a batch
+func (r *CardScanRepository) UpdateBatch(batch *models.CardScanBatch) error {
+	return r.db.Save(batch).Error
+}
+
+// ListItems lists the cards of a batch in upload order, optionally filtered by status
+func (r *CardScanRepository) ListItems(batchID uint64, status string) ([]*models.CardScanItem, error) {
+	query := r.db.Where("batch_id = ?", batchID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var items []*models.CardScanItem
+	err := query.Order("id ASC").Find(&items).Error
+	return items, err
+}
+
+// FindItemByID finds a card by ID
+func (r *CardScanRepository) FindItemByID(id uint64) (*models.CardScanItem, error) {
+	var item models.CardScanItem
+	if err := r.db.First(&item, id).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateItem saves all fields of a card
+func (r *CardScanRepository) UpdateItem(item *models.CardScanItem) error {
+	return r.db.Save(item).Error
+}
+
+// FailUnfinished marks queued or running batches and their pending cards as failed;
+// used at startup since images are not stored and batches run in-process
+func (r *CardScanRepository) FailUnfinished(reason string) (int64, error) {
+	var n int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.CardScanBatch{}).
+			Where("status IN ?", []string{models.CardScanQueued, models.CardScanRunning}).
+			Updates(map[string]interface{}{
+				"status":      models.CardScanFailed,
+				"error":       reason,
+				"finished_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		n = result.RowsAffected
+		return tx.Model(&models.CardScanItem{}).
+			Where("status = ?", models.CardItemPending).
+			Updates(map[string]interface{}{
+				"status": models.CardItemFailed,
+				"error":  reason,
+			}).Error
+	})
+	return n, err
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CustomFieldRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomFieldRepository(db *gorm.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
+
+// List 管理端列出定义；entity 为空时列出所有实体，teamID 为空时列出全局和所有团队的定义
+func (r *CustomFieldRepository) List(entity string, teamID *uint64) ([]*models.CustomFieldDefinition, error) {
+	var defs []*models.CustomFieldDefinition
+	db := r.db
+	if entity != "" {
+		db = db.Where("entity = ?", entity)
+	}
+	if teamID != nil {
+		db = db.Where("team_id = ?", *teamID)
+	}
+	err := db.Order("entity, team_id NULLS FIRST, position, id").Find(&defs).Error
+	return defs, err
+}
+
+// FindEffective 某个团队可用的定义：全局定义和该团队的定义（teamID 为空时只有全局定义）
+func (r *CustomFieldRepository) FindEffective(entity string, teamID *uint64) ([]*models.CustomFieldDefinition, error) {
+	var defs []*models.CustomFieldDefinition
+	db := r.db.Where("entity = ?", entity)
+	if teamID == nil {
+		db = db.Where("team_id IS NULL")
+	} else {
+		db = db.Where("team_id IS NULL OR team_id = ?", *teamID)
+	}
+	err := db.Order("position, id").Find(&defs).Error
+	return defs, err
+}
+
+func (r *CustomFieldRepository) FindByID(id uint64) (*models.CustomFieldDefinition, error) {
+	var def models.CustomFieldDefinition
+	if err := r.db.Where("id = ?", id).First(&def).Error; err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// FindByKey 同一实体、同一范围（全局或某个团队）内的定义
+func (r *CustomFieldRepository) FindByKey(entity string, teamID *uint64, key string) (*models.CustomFieldDefinition, error) {
+	var def models.CustomFieldDefinition
+	err := byTeam(r.db.Where("entity = ? AND key = ?", entity, key), teamID).First(&def).Error
+	if err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+func (r *CustomFieldRepository) Create(def *models.CustomFieldDefinition) error {
+	return r.db.Create(def).Error
+}
+
+func (r *CustomFieldRepository) Update(def *models.CustomFieldDefinition) error {
+	return r.db.Save(def).Error
+}
+
+// Delete 删除定义；记录中已保存的取值保留，重新定义同 key 的字段后恢复可用
+func (r *CustomFieldRepository) Delete(id uint64) error {
+	return r.db.Delete(&models.CustomFieldDefinition{}, id).Error
+}
+
+// customFieldExpr 取自定义字段值的 SQL 表达式，key 作为参数绑定；数字字段转成 numeric，非数字值视为空
+func customFieldExpr(table, key, fieldType string) (string, []interface{}) {
+	col := table + ".custom_fields"
+	if fieldType == models.CustomFieldNumber {
+		return "(CASE WHEN jsonb_typeof(" + col + " -> ?) = 'number' THEN (" + col + " ->> ?)::numeric END)", []interface{}{key, key}
+	}
+	return "(" + col + " ->> ?)", []interface{}{key}
+}
+
+// applyCustomFieldFilters 按自定义字段过滤；精确匹配用 jsonb @>（走 GIN 索引），范围按数字或日期字符串比较
+func applyCustomFieldFilters(db *gorm.DB, table string, filters []dto.CustomFieldFilter) *gorm.DB {
+	for _, f := range filters {
+		if len(f.Values) > 0 {
+			conds := make([]string, 0, len(f.Values))
+			args := make([]interface{}, 0, len(f.Values))
+			for _, v := range f.Values {
+				if f.Type == models.CustomFieldMultiSelect {
+					v = []interface{}{v}
+				}
+				doc, _ := json.Marshal(map[string]interface{}{f.Key: v})
+				conds = append(conds, table+".custom_fields @> ?::jsonb")
+				args = append(args, string(doc))
+			}
+			db = db.Where("("+strings.Join(conds, " OR ")+")", args...)
+			continue
+		}
+
+		expr, keyArgs := customFieldExpr(table, f.Key, f.Type)
+		if f.From != nil {
+			db = db.Where(expr+" >= ?", append(append([]interface{}{}, keyArgs...), f.From)...)
+		}
+		if f.To != nil {
+			db = db.Where(expr+" <= ?", append(append([]interface{}{}, keyArgs...), f.To)...)
+		}
+	}
+	return db
+}
+
+// customFieldOrder 按自定义字段排序，空值排在最后，值相同时按 id 倒序；
+// 需通过 db.Clauses 添加（db.Order 不接受表达式），且不能再叠加其他 Order
+func customFieldOrder(table string, sort *dto.CustomFieldSort) clause.OrderBy {
+	expr, args := customFieldExpr(table, sort.Key, sort.Type)
+	if sort.Desc {
+		expr += " DESC NULLS LAST"
+	} else {
+		expr += " ASC NULLS LAST"
+	}
+	return clause.OrderBy{Expression: clause.Expr{SQL: expr + ", " + table + ".id DESC", Vars: args}}
+}
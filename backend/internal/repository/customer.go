@@ -3,6 +3,7 @@ package repository
 import (
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/xia/nextcrm/internal/models"
 	"github.com/xia/nextcrm/internal/dto"
 	"gorm.io/gorm"
@@ -16,6 +17,16 @@ func NewCustomerRepository(db *gorm.DB) *CustomerRepository {
 	return &CustomerRepository{db: db}
 }
 
+// Transaction runs fn in a database transaction; repositories bound with WithTx(tx) write inside it
+func (r *CustomerRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+// WithTx returns a copy of the repository bound to tx
+func (r *CustomerRepository) WithTx(tx *gorm.DB) *CustomerRepository {
+	return &CustomerRepository{db: tx}
+}
+
 // Create creates a new customer
 func (r *CustomerRepository) Create(customer *models.Customer) error {
 	return r.db.Create(customer).Error
@@ -60,18 +71,42 @@ func (r *CustomerRepository) FindByUserID(userID uint64, query *dto.CustomerQuer
 		db = db.Where("industry = ?", query.Industry)
 	}
 
+	if query.AccountID > 0 {
+		db = db.Where("account_id = ?", query.AccountID)
+	}
+
+	if tags := splitTagList(query.Tags); len(tags) > 0 {
+		db = db.Where("tags && ?", pq.StringArray(tags))
+	}
+
+	if query.IDs != nil {
+		db = db.Where("id IN ?", query.IDs)
+	}
+
+	db = applyCustomFieldFilters(db, "customers", query.CustomFieldFilters)
+
+	db, err := applyListFilter(db, "customers", &query.ListFilter)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Count total
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Apply pagination and sorting
-	order := query.SortBy
-	if query.SortOrder == "desc" {
-		order += " DESC"
+	if query.CustomFieldSort != nil {
+		db = db.Clauses(customFieldOrder("customers", query.CustomFieldSort))
+	} else {
+		order, err := listOrder("customers", &query.ListFilter, "created_at DESC")
+		if err != nil {
+			return nil, 0, err
+		}
+		db = db.Order(order)
 	}
 
-	err := db.Order(order).
+	err = db.
 		Offset((query.Page - 1) * query.PerPage).
 		Limit(query.PerPage).
 		Find(&customers).Error
@@ -88,6 +123,23 @@ func (r *CustomerRepository) Update(customer *models.Customer) error {
 	return r.db.Save(customer).Error
 }
 
+// UpdateIntent updates only the intent level and potential score
+func (r *CustomerRepository) UpdateIntent(id uint64, intentLevel string, potentialScore int) error {
+	return r.db.Model(&models.Customer{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"intent_level":    intentLevel,
+			"potential_score": potentialScore,
+		}).Error
+}
+
+// FindAllByUserID finds all of a user's active (not archived) customers
+func (r *CustomerRepository) FindAllByUserID(userID uint64) ([]*models.Customer, error) {
+	var customers []*models.Customer
+	err := r.db.Where("user_id = ?", userID).Find(&customers).Error
+	return customers, err
+}
+
 // Delete soft deletes a customer
 func (r *CustomerRepository) Delete(id uint64) error {
 	return r.db.Delete(&models.Customer{}, id).Error
@@ -106,10 +158,48 @@ func (r *CustomerRepository) SoftDelete(id uint64) error {
 	return r.db.Delete(&models.Customer{}, id).Error
 }
 
+// FindArchivedByID finds an archived (soft deleted, not merged) customer by ID
+func (r *CustomerRepository) FindArchivedByID(id uint64) (*models.Customer, error) {
+	var customer models.Customer
+	err := r.db.Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL AND merged_into_id IS NULL", id).
+		First(&customer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// Reassign hands a customer over to toUserID together with its interactions, deals and the contacts split from it.
+// accountID is the new owner's account the customer is filed under; nil leaves account_id untouched
+func (r *CustomerRepository) Reassign(id, toUserID uint64, accountID *uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"user_id": toUserID}
+		if accountID != nil {
+			updates["account_id"] = *accountID
+		}
+		if err := tx.Model(&models.Customer{}).Where("id = ?", id).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+		for _, table := range []string{"interactions", "deals"} {
+			if err := tx.Table(table).Where("customer_id = ?", id).
+				Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if accountID != nil {
+			updates["is_primary"] = false
+		}
+		return tx.Model(&models.Contact{}).Where("customer_id = ?", id).
+			Updates(updates).Error
+	})
+}
+
 // Restore restores a soft deleted customer
 func (r *CustomerRepository) Restore(id uint64) error {
 	return r.db.Unscoped().Model(&models.Customer{}).
-		Where("id = ?", id).
+		Where("id = ? AND merged_into_id IS NULL", id).
 		Update("deleted_at", nil).
 		Error
 }
@@ -121,7 +211,7 @@ func (r *CustomerRepository) FindArchivedByUserID(userID uint64, query *dto.Cust
 
 	db := r.db.Model(&models.Customer{}).
 		Unscoped().
-		Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+		Where("user_id = ? AND deleted_at IS NOT NULL AND merged_into_id IS NULL", userID)
 
 	// Apply filters
 	if query.Search != "" {
@@ -145,18 +235,42 @@ func (r *CustomerRepository) FindArchivedByUserID(userID uint64, query *dto.Cust
 		db = db.Where("industry = ?", query.Industry)
 	}
 
+	if query.AccountID > 0 {
+		db = db.Where("account_id = ?", query.AccountID)
+	}
+
+	if tags := splitTagList(query.Tags); len(tags) > 0 {
+		db = db.Where("tags && ?", pq.StringArray(tags))
+	}
+
+	if query.IDs != nil {
+		db = db.Where("id IN ?", query.IDs)
+	}
+
+	db = applyCustomFieldFilters(db, "customers", query.CustomFieldFilters)
+
+	db, err := applyListFilter(db, "customers", &query.ListFilter)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Count total
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Apply pagination and sorting
-	order := query.SortBy
-	if query.SortOrder == "desc" {
-		order += " DESC"
+	if query.CustomFieldSort != nil {
+		db = db.Clauses(customFieldOrder("customers", query.CustomFieldSort))
+	} else {
+		order, err := listOrder("customers", &query.ListFilter, "created_at DESC")
+		if err != nil {
+			return nil, 0, err
+		}
+		db = db.Order(order)
 	}
 
-	err := db.Order(order).
+	err = db.
 		Offset((query.Page - 1) * query.PerPage).
 		Limit(query.PerPage).
 		Find(&customers).Error
@@ -240,3 +354,28 @@ func (r *CustomerRepository) CountNewThisMonth(userID uint64) (int, error) {
 		Count(&count).Error
 	return int(count), err
 }
+
+// FindCardMatches finds a user's customers sharing a phone, email (case-insensitive) or company with a scanned card
+func (r *CustomerRepository) FindCardMatches(userID uint64, phone, email, company string) ([]*models.Customer, error) {
+	if phone == "" && email == "" && company == "" {
+		return nil, nil
+	}
+	cond := r.db
+	if phone != "" {
+		cond = cond.Or("phone = ?", phone)
+	}
+	if email != "" {
+		cond = cond.Or("LOWER(email) = LOWER(?)", email)
+	}
+	if company != "" {
+		cond = cond.Or("LOWER(company) = LOWER(?)", company)
+	}
+
+	var customers []*models.Customer
+	err := r.db.Where("user_id = ?", userID).
+		Where(cond).
+		Order("updated_at DESC").
+		Limit(20).
+		Find(&customers).Error
+	return customers, err
+}
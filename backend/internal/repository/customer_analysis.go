@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type CustomerAnalysisRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomerAnalysisRepository(db *gorm.DB) *CustomerAnalysisRepository {
+	return &CustomerAnalysisRepository{db: db}
+}
+
+// Create saves an analysis snapshot
+func (r *CustomerAnalysisRepository) Create(analysis *models.CustomerAnalysis) error {
+	return r.db.Create(analysis).Error
+}
+
+// ListByCustomerID returns the newest snapshots of a customer
+func (r *CustomerAnalysisRepository) ListByCustomerID(customerID uint64, limit int) ([]*models.CustomerAnalysis, error) {
+	var analyses []*models.CustomerAnalysis
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&analyses).Error
+	return analyses, err
+}
+
+// FindLatestMatching finds the newest snapshot of a customer with the given type and summary
+func (r *CustomerAnalysisRepository) FindLatestMatching(customerID uint64, analysisType, summary string) (*models.CustomerAnalysis, error) {
+	var analysis models.CustomerAnalysis
+	err := r.db.Where("customer_id = ? AND analysis_type = ? AND summary = ?", customerID, analysisType, summary).
+		Order("created_at DESC").
+		First(&analysis).Error
+	if err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+// 姓名和公司的 pg_trgm 相似度都不低于阈值时视为疑似重复
+const (
+	DuplicateNameSimilarity    = 0.6
+	DuplicateCompanySimilarity = 0.5
+)
+
+// maxDuplicatePairs 一次扫描最多返回的重复客户对
+const maxDuplicatePairs = 5000
+
+// duplicateKey 精确匹配的规范化字段：SQL 表达式（{t} 为表别名前缀）和对应的 Go 规范化
+type duplicateKey struct {
+	on        string
+	expr      string
+	normalize func(c *models.Customer) string
+}
+
+var duplicateKeys = []duplicateKey{
+	{
+		on:        models.DuplicateOnPhone,
+		expr:      `regexp_replace(regexp_replace(COALESCE({t}phone, ''), '[^0-9]', '', 'g'), '^86([0-9]{11})$', '\1')`,
+		normalize: func(c *models.Customer) string { return NormalizePhone(c.Phone) },
+	},
+	{
+		on:        models.DuplicateOnEmail,
+		expr:      `LOWER(TRIM(COALESCE({t}email, '')))`,
+		normalize: func(c *models.Customer) string { return strings.ToLower(strings.TrimSpace(c.Email)) },
+	},
+	{
+		on:        models.DuplicateOnWechatID,
+		expr:      `LOWER(TRIM(COALESCE({t}wechat_id, '')))`,
+		normalize: func(c *models.Customer) string { return strings.ToLower(strings.TrimSpace(c.WechatID)) },
+	},
+	{
+		on:        models.DuplicateOnCreditCode,
+		expr:      `UPPER(TRIM(COALESCE({t}credit_code, '')))`,
+		normalize: func(c *models.Customer) string { return strings.ToUpper(strings.TrimSpace(c.CreditCode)) },
+	},
+}
+
+func (k duplicateKey) sql(alias string) string {
+	if alias != "" {
+		alias += "."
+	}
+	return strings.ReplaceAll(k.expr, "{t}", alias)
+}
+
+// NormalizePhone 只保留数字，去掉 +86 / 86 国家码（与查重索引的 SQL 表达式一致）
+func NormalizePhone(phone string) string {
+	var sb strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+		}
+	}
+	digits := sb.String()
+	if len(digits) == 13 && strings.HasPrefix(digits, "86") {
+		digits = digits[2:]
+	}
+	return digits
+}
+
+// DuplicateMatchedOn 两个客户规范化后相同的字段（phone、email、wechat_id、credit_code）
+func DuplicateMatchedOn(a, b *models.Customer) []string {
+	var on []string
+	for _, k := range duplicateKeys {
+		if v := k.normalize(a); v != "" && v == k.normalize(b) {
+			on = append(on, k.on)
+		}
+	}
+	return on
+}
+
+// DuplicateCandidate 疑似重复的客户和姓名、公司的相似度
+type DuplicateCandidate struct {
+	models.Customer
+	NameSimilarity    float64 `json:"name_similarity"`
+	CompanySimilarity float64 `json:"company_similarity"`
+}
+
+// DuplicatePair 扫描得到的一对疑似重复客户
+type DuplicatePair struct {
+	CustomerID        uint64
+	OtherID           uint64
+	NameSimilarity    float64
+	CompanySimilarity float64
+}
+
+// FindDuplicates finds a user's customers that look like c: same normalized phone, email, WeChat ID or
+// credit code, or a similar name at a similar company; excludeID (e.g. c itself) is skipped
+func (r *CustomerRepository) FindDuplicates(userID uint64, c *models.Customer, excludeID uint64) ([]*DuplicateCandidate, error) {
+	cond := r.db.Where("LOWER(TRIM(name)) = LOWER(TRIM(?)) AND LOWER(TRIM(company)) = LOWER(TRIM(?))", c.Name, c.Company)
+	if strings.TrimSpace(c.Name) != "" && strings.TrimSpace(c.Company) != "" {
+		cond = cond.Or("LOWER(name) % LOWER(?) AND similarity(LOWER(name), LOWER(?)) >= ? AND similarity(LOWER(company), LOWER(?)) >= ?",
+			c.Name, c.Name, DuplicateNameSimilarity, c.Company, DuplicateCompanySimilarity)
+	}
+	for _, k := range duplicateKeys {
+		if v := k.normalize(c); v != "" {
+			cond = cond.Or(k.sql("")+" = ?", v)
+		}
+	}
+
+	var candidates []*DuplicateCandidate
+	err := r.db.Model(&models.Customer{}).
+		Select("customers.*, similarity(LOWER(name), LOWER(?)) AS name_similarity, similarity(LOWER(company), LOWER(?)) AS company_similarity", c.Name, c.Company).
+		Where("user_id = ? AND id <> ?", userID, excludeID).
+		Where(cond).
+		Order("updated_at DESC").
+		Limit(10).
+		Find(&candidates).Error
+	return candidates, err
+}
+
+// FindDuplicatePairs finds all pairs of a user's customers that look like the same person
+func (r *CustomerRepository) FindDuplicatePairs(userID uint64) ([]*DuplicatePair, error) {
+	conds := []string{
+		"(LOWER(TRIM(a.name)) = LOWER(TRIM(b.name)) AND LOWER(TRIM(a.company)) = LOWER(TRIM(b.company)))",
+		"(LOWER(a.name) % LOWER(b.name) AND similarity(LOWER(a.name), LOWER(b.name)) >= @name AND similarity(LOWER(a.company), LOWER(b.company)) >= @company)",
+	}
+	for _, k := range duplicateKeys {
+		conds = append(conds, "("+k.sql("a")+" <> '' AND "+k.sql("a")+" = "+k.sql("b")+")")
+	}
+
+	var pairs []*DuplicatePair
+	err := r.db.Raw(`
+		SELECT a.id AS customer_id, b.id AS other_id,
+			similarity(LOWER(a.name), LOWER(b.name)) AS name_similarity,
+			similarity(LOWER(a.company), LOWER(b.company)) AS company_similarity
+		FROM customers a
+		JOIN customers b ON b.user_id = a.user_id AND b.id > a.id AND b.deleted_at IS NULL
+		WHERE a.user_id = @user AND a.deleted_at IS NULL AND (`+strings.Join(conds, " OR ")+`)
+		ORDER BY a.id, b.id
+		LIMIT @limit`,
+		map[string]interface{}{
+			"user":    userID,
+			"name":    DuplicateNameSimilarity,
+			"company": DuplicateCompanySimilarity,
+			"limit":   maxDuplicatePairs,
+		}).Scan(&pairs).Error
+	return pairs, err
+}
+
+// FindByIDs finds a user's active customers by ID
+func (r *CustomerRepository) FindByIDs(userID uint64, ids []uint64) ([]*models.Customer, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var customers []*models.Customer
+	err := r.db.Where("user_id = ? AND id IN ?", userID, ids).Find(&customers).Error
+	return customers, err
+}
+
+// FindUserIDsWithCustomers lists users that have at least one active customer
+func (r *CustomerRepository) FindUserIDsWithCustomers() ([]uint64, error) {
+	var ids []uint64
+	err := r.db.Model(&models.Customer{}).Distinct("user_id").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// mergeTables 合并客户时迁移到保留客户的关联表（都有 id 和 customer_id 列）
+var mergeTables = []string{
+	"interactions", "deals", "activities", "call_recordings", "customer_analyses", "follow_up_drafts",
+	"intent_proposals", "next_actions", "ai_intake_sessions", "card_scan_items", "document_extractions",
+	"contacts",
+}
+
+// accountTables 迁移后还要同步 account_id（跟随客户所属账户）的表
+var accountTables = map[string]bool{"interactions": true, "deals": true, "contacts": true}
+
+// moveToAccount 迁移后的记录跟随保留客户的账户。联系人的 account_id 不能为空，保留客户没有账户时留在原账户；
+// 换了账户的联系人不再是主要联系人
+func moveToAccount(tx *gorm.DB, table string, ids []uint64, accountID *uint64) error {
+	if table != "contacts" {
+		return tx.Table(table).Where("id IN ?", ids).Update("account_id", accountID).Error
+	}
+	if accountID == nil {
+		return nil
+	}
+	return tx.Table(table).Where("id IN ? AND account_id <> ?", ids, *accountID).
+		Updates(map[string]interface{}{"account_id": *accountID, "is_primary": false}).Error
+}
+
+type CustomerDuplicateRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomerDuplicateRepository(db *gorm.DB) *CustomerDuplicateRepository {
+	return &CustomerDuplicateRepository{db: db}
+}
+
+// ReplaceOpenClusters replaces a user's open clusters with the result of a new scan;
+// clusters with the same members as a dismissed or merged one are skipped
+func (r *CustomerDuplicateRepository) ReplaceOpenClusters(userID uint64, clusters []*models.DuplicateCluster) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND status = ?", userID, models.DuplicateClusterOpen).
+			Delete(&models.DuplicateCluster{}).Error; err != nil {
+			return err
+		}
+
+		var closed []string
+		if err := tx.Model(&models.DuplicateCluster{}).
+			Where("user_id = ?", userID).
+			Pluck("member_key", &closed).Error; err != nil {
+			return err
+		}
+		skip := make(map[string]bool, len(closed))
+		for _, key := range closed {
+			skip[key] = true
+		}
+
+		var fresh []*models.DuplicateCluster
+		for _, c := range clusters {
+			if !skip[c.MemberKey] {
+				fresh = append(fresh, c)
+			}
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(fresh, 100).Error
+	})
+}
+
+// ListClusters lists a user's clusters with the given status, highest score first
+func (r *CustomerDuplicateRepository) ListClusters(userID uint64, status string, limit int) ([]*models.DuplicateCluster, error) {
+	var clusters []*models.DuplicateCluster
+	err := r.db.Where("user_id = ? AND status = ?", userID, status).
+		Order("score DESC, id").
+		Limit(limit).
+		Find(&clusters).Error
+	return clusters, err
+}
+
+// FindClusterByID finds a cluster by ID
+func (r *CustomerDuplicateRepository) FindClusterByID(id uint64) (*models.DuplicateCluster, error) {
+	var cluster models.DuplicateCluster
+	if err := r.db.First(&cluster, id).Error; err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+// UpdateCluster saves all fields of a cluster
+func (r *CustomerDuplicateRepository) UpdateCluster(cluster *models.DuplicateCluster) error {
+	return r.db.Save(cluster).Error
+}
+
+// Merge saves the survivor, moves the related records of the merged customers to it, drops their
+// lead scores, archives them and records the merge, all in one transaction; merge.Moved is filled in
+func (r *CustomerDuplicateRepository) Merge(survivor *models.Customer, merge *models.CustomerMerge) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		merge.Moved = nil
+		for _, table := range mergeTables {
+			for _, from := range merge.MergedIDs {
+				var ids []uint64
+				if err := tx.Table(table).Where("customer_id = ?", from).Pluck("id", &ids).Error; err != nil {
+					return err
+				}
+				if len(ids) == 0 {
+					continue
+				}
+				if err := tx.Table(table).Where("id IN ?", ids).
+					Update("customer_id", survivor.ID).Error; err != nil {
+					return err
+				}
+				if accountTables[table] {
+					if err := moveToAccount(tx, table, ids, survivor.AccountID); err != nil {
+						return err
+					}
+				}
+				merge.Moved = append(merge.Moved, models.MergeMovedRows{Table: table, FromCustomerID: from, IDs: ids})
+			}
+		}
+
+		if err := tx.Where("customer_id IN ?", merge.MergedIDs).
+			Delete(&models.LeadScore{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(survivor).Error; err != nil {
+			return err
+		}
+		// Save 不写标签列，单独更新
+		if err := tx.Model(survivor).Update("tags", tagsValue(survivor.Tags)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Customer{}).Where("id IN ?", merge.MergedIDs).
+			Update("merged_into_id", survivor.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Customer{}, merge.MergedIDs).Error; err != nil {
+			return err
+		}
+		if merge.ClusterID != nil {
+			if err := tx.Model(&models.DuplicateCluster{}).Where("id = ?", *merge.ClusterID).
+				Update("status", models.DuplicateClusterMerged).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(merge).Error
+	})
+}
+
+// UndoMerge restores the merged customers, moves their records back and restores the survivor's fields
+func (r *CustomerDuplicateRepository) UndoMerge(merge *models.CustomerMerge) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Customer{}).
+			Where("id IN ? AND merged_into_id = ?", merge.MergedIDs, merge.SurvivorID).
+			Updates(map[string]interface{}{"deleted_at": nil, "merged_into_id": nil}).Error; err != nil {
+			return err
+		}
+		for _, moved := range merge.Moved {
+			if err := tx.Table(moved.Table).
+				Where("id IN ? AND customer_id = ?", moved.IDs, merge.SurvivorID).
+				Update("customer_id", moved.FromCustomerID).Error; err != nil {
+				return err
+			}
+			if accountTables[moved.Table] {
+				accountID := gorm.Expr("(SELECT account_id FROM customers WHERE id = ?)", moved.FromCustomerID)
+				if moved.Table == "contacts" {
+					accountID = gorm.Expr("COALESCE((SELECT account_id FROM customers WHERE id = ?), account_id)", moved.FromCustomerID)
+				}
+				if err := tx.Table(moved.Table).Where("id IN ?", moved.IDs).
+					Update("account_id", accountID).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		before := merge.SurvivorBefore
+		if err := tx.Save(&before).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&before).Update("tags", tagsValue(before.Tags)).Error; err != nil {
+			return err
+		}
+		if merge.ClusterID != nil {
+			if err := tx.Model(&models.DuplicateCluster{}).Where("id = ?", *merge.ClusterID).
+				Update("status", models.DuplicateClusterOpen).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		merge.Status = models.CustomerMergeUndone
+		merge.UndoneAt = &now
+		return tx.Save(merge).Error
+	})
+}
+
+// FindMergeByID finds a merge by ID
+func (r *CustomerDuplicateRepository) FindMergeByID(id uint64) (*models.CustomerMerge, error) {
+	var merge models.CustomerMerge
+	if err := r.db.First(&merge, id).Error; err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// ListMerges lists a user's merges, newest first
+func (r *CustomerDuplicateRepository) ListMerges(userID uint64, limit int) ([]*models.CustomerMerge, error) {
+	var merges []*models.CustomerMerge
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&merges).Error
+	return merges, err
+}
@@ -49,22 +49,30 @@ func (r *DealRepository) List(query *dto.DealListQuery, userID uint64) ([]*model
 	if query.CustomerID > 0 {
 		db = db.Where("customer_id = ?", query.CustomerID)
 	}
+	if query.AccountID > 0 {
+		db = db.Where("account_id = ?", query.AccountID)
+	}
 	if query.DealType != "" {
 		db = db.Where("deal_type = ?", query.DealType)
 	}
+	db = applyCustomFieldFilters(db, "deals", query.CustomFieldFilters)
+	db, err := applyListFilter(db, "deals", &query.ListFilter)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	order := "deal_at DESC"
-	if query.SortBy != "" {
-		order = query.SortBy
-		if query.SortOrder == "asc" {
-			order += " ASC"
-		} else {
-			order += " DESC"
+	if query.CustomFieldSort != nil {
+		db = db.Clauses(customFieldOrder("deals", query.CustomFieldSort))
+	} else {
+		order, err := listOrder("deals", &query.ListFilter, "deal_at DESC")
+		if err != nil {
+			return nil, 0, err
 		}
+		db = db.Order(order)
 	}
 
 	page, perPage := query.Page, query.PerPage
@@ -75,8 +83,7 @@ func (r *DealRepository) List(query *dto.DealListQuery, userID uint64) ([]*model
 		perPage = 20
 	}
 
-	err := db.Order(order).
-		Offset((page - 1) * perPage).
+	err = db.Offset((page - 1) * perPage).
 		Limit(perPage).
 		Find(&deals).Error
 	if err != nil {
@@ -96,6 +103,40 @@ func (r *DealRepository) ListByCustomerID(customerID uint64, userID uint64) ([]*
 	return deals, nil
 }
 
+// FindRecentByCustomerID returns the newest deals of a customer regardless of owner
+func (r *DealRepository) FindRecentByCustomerID(customerID uint64, limit int) ([]*models.Deal, error) {
+	var deals []*models.Deal
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("deal_at DESC").
+		Limit(limit).
+		Find(&deals).Error
+	if err != nil {
+		return nil, err
+	}
+	return deals, nil
+}
+
+// FindOpenByCustomerID finds the customer's most recent deal that is not fully paid
+func (r *DealRepository) FindOpenByCustomerID(customerID uint64) (*models.Deal, error) {
+	var deal models.Deal
+	err := r.db.Where("customer_id = ? AND payment_status <> ?", customerID, "paid").
+		Order("deal_at DESC").
+		First(&deal).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deal, nil
+}
+
+// FindUnpaidByUserID finds a user's deals that are not fully paid, oldest first
+func (r *DealRepository) FindUnpaidByUserID(userID uint64) ([]*models.Deal, error) {
+	var deals []*models.Deal
+	err := r.db.Where("user_id = ? AND payment_status <> ?", userID, "paid").
+		Order("deal_at").
+		Find(&deals).Error
+	return deals, err
+}
+
 func (r *DealRepository) Delete(id uint64) error {
 	return r.db.Delete(&models.Deal{}, id).Error
 }
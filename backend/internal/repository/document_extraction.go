@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type DocumentExtractionRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentExtractionRepository(db *gorm.DB) *DocumentExtractionRepository {
+	return &DocumentExtractionRepository{db: db}
+}
+
+// Create saves a document extraction
+func (r *DocumentExtractionRepository) Create(doc *models.DocumentExtraction) error {
+	return r.db.Create(doc).Error
+}
+
+// FindByID finds a document extraction by ID
+func (r *DocumentExtractionRepository) FindByID(id uint64) (*models.DocumentExtraction, error) {
+	var doc models.DocumentExtraction
+	if err := r.db.First(&doc, id).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// List lists a user's document extractions, newest first; customerID 0 means all customers
+func (r *DocumentExtractionRepository) List(userID, customerID uint64, status string, limit int) ([]*models.DocumentExtraction, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if customerID != 0 {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var docs []*models.DocumentExtraction
+	err := query.Order("created_at DESC").Limit(limit).Find(&docs).Error
+	return docs, err
+}
+
+// Update saves all fields of a document extraction
+func (r *DocumentExtractionRepository) Update(doc *models.DocumentExtraction) error {
+	return r.db.Save(doc).Error
+}
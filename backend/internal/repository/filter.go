@@ -0,0 +1,715 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+// 过滤字段类型
+const (
+	filterString = "string"
+	filterNumber = "number"
+	filterTime   = "time"
+	filterBool   = "bool"
+	filterTags   = "tags" // 字符串数组，取值为标签名
+)
+
+// 过滤条件的规模限制，防止构造出过于复杂的查询
+const (
+	maxFilterDepth      = 5
+	maxFilterConditions = 30
+	maxFilterInValues   = 50
+	maxSortFields       = 3
+	defaultQueryLimit   = 50
+	maxQueryLimit       = 200
+)
+
+// filterField 可过滤字段：expr 为白名单内的 SQL 表达式，不接受任何外部输入
+type filterField struct {
+	expr string
+	typ  string
+	enum []string
+	desc string
+}
+
+// filterEntity 可查询的实体
+type filterEntity struct {
+	table   string
+	newRows func() interface{} // 返回结果切片的指针
+	fields  map[string]filterField
+}
+
+var filterEntities = map[string]*filterEntity{
+	"customers": {
+		table:   "customers",
+		newRows: func() interface{} { return &[]*models.Customer{} },
+		fields: map[string]filterField{
+			"id":                  {expr: "customers.id", typ: filterNumber},
+			"name":                {expr: "customers.name", typ: filterString, desc: "客户姓名"},
+			"company":             {expr: "customers.company", typ: filterString, desc: "公司"},
+			"position":            {expr: "customers.position", typ: filterString, desc: "职位"},
+			"phone":               {expr: "customers.phone", typ: filterString},
+			"email":               {expr: "customers.email", typ: filterString},
+			"industry":            {expr: "customers.industry", typ: filterString, desc: "行业，如 SaaS、制造业"},
+			"budget":              {expr: "customers.budget", typ: filterString},
+			"intent_level":        {expr: "customers.intent_level", typ: filterString, enum: []string{"High", "Medium", "Low"}, desc: "意向等级"},
+			"stage":               {expr: "customers.stage", typ: filterString, enum: []string{"Leads", "Qualified", "Proposal", "Negotiation", "Closed"}, desc: "销售阶段"},
+			"source":              {expr: "customers.source", typ: filterString, desc: "来源"},
+			"contract_status":     {expr: "customers.contract_status", typ: filterString, enum: []string{"Pending", "Signed", "Active", "Expired"}},
+			"customer_level":      {expr: "customers.customer_level", typ: filterString, desc: "客户等级，如 A/B/C、VIP"},
+			"customer_status":     {expr: "customers.customer_status", typ: filterString, desc: "客户状态，如 活跃/休眠/流失"},
+			"probability":         {expr: "customers.probability", typ: filterNumber, desc: "成交概率 0-100"},
+			"potential_score":     {expr: "customers.potential_score", typ: filterNumber, desc: "潜力评分 0-100"},
+			"follow_up_count":     {expr: "customers.follow_up_count", typ: filterNumber, desc: "跟进次数"},
+			"last_contact":        {expr: "customers.last_contact", typ: filterTime, desc: "最近联系时间，未联系过为空"},
+			"last_contact_days":   {expr: "(CURRENT_DATE - customers.last_contact::date)", typ: filterNumber, desc: "距最近联系的天数，未联系过为空"},
+			"tags":                {expr: "customers.tags", typ: filterTags, desc: "标签"},
+			"expected_close_date": {expr: "customers.expected_close_date", typ: filterTime, desc: "预计成交日期"},
+			"created_at":          {expr: "customers.created_at", typ: filterTime, desc: "创建时间"},
+			"updated_at":          {expr: "customers.updated_at", typ: filterTime},
+			"last_interaction_at": {
+				expr: "(SELECT MAX(i.created_at) FROM interactions i WHERE i.customer_id = customers.id AND i.deleted_at IS NULL)",
+				typ:  filterTime, desc: "最近一条跟进记录的时间，没有跟进记录为空",
+			},
+			"interaction_count": {
+				expr: "(SELECT COUNT(*) FROM interactions i WHERE i.customer_id = customers.id AND i.deleted_at IS NULL)",
+				typ:  filterNumber, desc: "跟进记录条数",
+			},
+			"deal_count": {
+				expr: "(SELECT COUNT(*) FROM deals d WHERE d.customer_id = customers.id AND d.deleted_at IS NULL)",
+				typ:  filterNumber, desc: "成交记录条数",
+			},
+			"deal_total": {
+				expr: "(SELECT COALESCE(SUM(d.amount), 0) FROM deals d WHERE d.customer_id = customers.id AND d.deleted_at IS NULL)",
+				typ:  filterNumber, desc: "成交总金额",
+			},
+		},
+	},
+	"deals": {
+		table:   "deals",
+		newRows: func() interface{} { return &[]*models.Deal{} },
+		fields: map[string]filterField{
+			"id":                 {expr: "deals.id", typ: filterNumber},
+			"customer_id":        {expr: "deals.customer_id", typ: filterNumber},
+			"customer_name":      {expr: "(SELECT c.name FROM customers c WHERE c.id = deals.customer_id)", typ: filterString, desc: "客户姓名"},
+			"customer_company":   {expr: "(SELECT c.company FROM customers c WHERE c.id = deals.customer_id)", typ: filterString, desc: "客户公司"},
+			"record_no":          {expr: "deals.record_no", typ: filterString},
+			"deal_type":          {expr: "deals.deal_type", typ: filterString, desc: "成交类型，如 sale"},
+			"product_or_service": {expr: "deals.product_or_service", typ: filterString, desc: "产品或服务"},
+			"amount":             {expr: "deals.amount", typ: filterNumber, desc: "金额"},
+			"currency":           {expr: "deals.currency", typ: filterString},
+			"payment_status":     {expr: "deals.payment_status", typ: filterString, desc: "回款状态，如 pending / partial / paid"},
+			"paid_amount":        {expr: "deals.paid_amount", typ: filterNumber, desc: "已回款金额"},
+			"is_repeat_purchase": {expr: "deals.is_repeat_purchase", typ: filterBool, desc: "是否复购"},
+			"contract_no":        {expr: "deals.contract_no", typ: filterString},
+			"deal_at":            {expr: "deals.deal_at", typ: filterTime, desc: "成交时间"},
+			"signed_at":          {expr: "deals.signed_at", typ: filterTime, desc: "签约时间"},
+			"paid_at":            {expr: "deals.paid_at", typ: filterTime, desc: "回款时间"},
+			"created_at":         {expr: "deals.created_at", typ: filterTime},
+		},
+	},
+	"interactions": {
+		table:   "interactions",
+		newRows: func() interface{} { return &[]*models.Interaction{} },
+		fields: map[string]filterField{
+			"id":               {expr: "interactions.id", typ: filterNumber},
+			"customer_id":      {expr: "interactions.customer_id", typ: filterNumber},
+			"customer_name":    {expr: "(SELECT c.name FROM customers c WHERE c.id = interactions.customer_id)", typ: filterString, desc: "客户姓名"},
+			"customer_company": {expr: "(SELECT c.company FROM customers c WHERE c.id = interactions.customer_id)", typ: filterString, desc: "客户公司"},
+			"type":             {expr: "interactions.type", typ: filterString, enum: []string{"call", "email", "meeting", "note"}, desc: "跟进方式"},
+			"content":          {expr: "interactions.content", typ: filterString, desc: "跟进内容"},
+			"outcome":          {expr: "interactions.outcome", typ: filterString, enum: []string{"positive", "neutral", "negative"}, desc: "跟进结果"},
+			"next_action":      {expr: "interactions.next_action", typ: filterString},
+			"next_date":        {expr: "interactions.next_date", typ: filterTime, desc: "下次跟进时间"},
+			"created_at":       {expr: "interactions.created_at", typ: filterTime, desc: "跟进时间"},
+		},
+	},
+	"knowledge": {
+		table:   "knowledge_base",
+		newRows: func() interface{} { return &[]*models.KnowledgeBase{} },
+		fields: map[string]filterField{
+			"id":          {expr: "knowledge_base.id", typ: filterNumber},
+			"title":       {expr: "knowledge_base.title", typ: filterString, desc: "标题"},
+			"content":     {expr: "knowledge_base.content", typ: filterString, desc: "正文"},
+			"type":        {expr: "knowledge_base.type", typ: filterString, enum: []string{"sales_script", "product_info", "faq", "best_practice", "objection_handling", "style_guide"}, desc: "知识类型"},
+			"tags":        {expr: "knowledge_base.tags", typ: filterTags, desc: "标签"},
+			"description": {expr: "knowledge_base.description", typ: filterString},
+			"created_at":  {expr: "knowledge_base.created_at", typ: filterTime, desc: "创建时间"},
+			"updated_at":  {expr: "knowledge_base.updated_at", typ: filterTime, desc: "更新时间"},
+		},
+	},
+}
+
+// FilterCatalog 列出各实体可过滤 / 排序的字段
+func FilterCatalog() map[string][]dto.FilterFieldInfo {
+	catalog := make(map[string][]dto.FilterFieldInfo, len(filterEntities))
+	for name, e := range filterEntities {
+		infos := make([]dto.FilterFieldInfo, 0, len(e.fields))
+		for field, f := range e.fields {
+			infos = append(infos, dto.FilterFieldInfo{Name: field, Type: f.typ, Enum: f.enum, Description: f.desc})
+		}
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+		catalog[name] = infos
+	}
+	return catalog
+}
+
+// ValidateQuerySpec 校验查询（实体、字段、操作符、取值、规模），并补全默认 limit
+func ValidateQuerySpec(spec *dto.QuerySpec) error {
+	_, err := compileQuery(spec, time.Now())
+	return err
+}
+
+// compiledQuery 编译后的查询，where 中只有白名单表达式和 ? 占位符
+type compiledQuery struct {
+	entity *filterEntity
+	where  string
+	args   []interface{}
+	order  string
+	limit  int
+}
+
+func compileQuery(spec *dto.QuerySpec, now time.Time) (*compiledQuery, error) {
+	e, err := lookupEntity(spec.Entity)
+	if err != nil {
+		return nil, err
+	}
+	q := &compiledQuery{entity: e}
+
+	if q.where, q.args, err = compileFilter(e, spec.Filter, now); err != nil {
+		return nil, err
+	}
+	orders, err := compileSort(e, spec.Sort)
+	if err != nil {
+		return nil, err
+	}
+	q.order = strings.Join(append(orders, e.table+".id DESC"), ", ")
+
+	if spec.Limit <= 0 {
+		spec.Limit = defaultQueryLimit
+	}
+	if spec.Limit > maxQueryLimit {
+		spec.Limit = maxQueryLimit
+	}
+	q.limit = spec.Limit
+	return q, nil
+}
+
+func lookupEntity(name string) (*filterEntity, error) {
+	e, ok := filterEntities[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown entity %q (expected customers, deals, interactions or knowledge)", name)
+	}
+	return e, nil
+}
+
+// compileFilter 编译过滤树，filter 为空时返回空条件
+func compileFilter(e *filterEntity, filter *dto.FilterNode, now time.Time) (string, []interface{}, error) {
+	if filter == nil {
+		return "", nil, nil
+	}
+	c := &filterCompiler{entity: e, now: now}
+	return c.node(filter, 1)
+}
+
+// compileSort 编译排序，只接受白名单字段
+func compileSort(e *filterEntity, sorts []dto.SortSpec) ([]string, error) {
+	if len(sorts) > maxSortFields {
+		return nil, fmt.Errorf("at most %d sort fields are allowed", maxSortFields)
+	}
+	orders := make([]string, 0, len(sorts)+1)
+	for _, s := range sorts {
+		f, ok := e.fields[s.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort field %q", s.Field)
+		}
+		switch strings.ToLower(s.Order) {
+		case "", "asc":
+			orders = append(orders, f.expr+" ASC")
+		case "desc":
+			orders = append(orders, f.expr+" DESC")
+		default:
+			return nil, fmt.Errorf("sort order must be asc or desc, got %q", s.Order)
+		}
+	}
+	return orders, nil
+}
+
+// HasFilterField 实体是否有该可过滤 / 排序字段
+func HasFilterField(entity, field string) bool {
+	e, ok := filterEntities[entity]
+	if !ok {
+		return false
+	}
+	_, ok = e.fields[field]
+	return ok
+}
+
+// ValidateListFilter 校验列表接口的过滤树和排序（entity 为 customers / deals / interactions / knowledge）
+func ValidateListFilter(entity string, filter *dto.FilterNode, sorts []dto.SortSpec) error {
+	e, err := lookupEntity(entity)
+	if err != nil {
+		return err
+	}
+	if _, _, err := compileFilter(e, filter, time.Now()); err != nil {
+		return err
+	}
+	_, err = compileSort(e, sorts)
+	return err
+}
+
+// applyListFilter 在列表查询上加上高级过滤条件，lf 已由 service 校验过
+func applyListFilter(db *gorm.DB, entity string, lf *dto.ListFilter) (*gorm.DB, error) {
+	if lf == nil || lf.Filter == nil {
+		return db, nil
+	}
+	e, err := lookupEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+	where, args, err := compileFilter(e, lf.Filter, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return db.Where(where, args...), nil
+}
+
+// listOrder 列表排序：有 lf.Sort 时按白名单编译（id 兜底保证分页稳定），否则用 fallback
+func listOrder(entity string, lf *dto.ListFilter, fallback string) (string, error) {
+	if lf == nil || len(lf.Sort) == 0 {
+		return fallback, nil
+	}
+	e, err := lookupEntity(entity)
+	if err != nil {
+		return "", err
+	}
+	orders, err := compileSort(e, lf.Sort)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append(orders, e.table+".id DESC"), ", "), nil
+}
+
+type filterCompiler struct {
+	entity     *filterEntity
+	now        time.Time
+	conditions int
+}
+
+func (c *filterCompiler) node(n *dto.FilterNode, depth int) (string, []interface{}, error) {
+	if depth > maxFilterDepth {
+		return "", nil, fmt.Errorf("filter is nested deeper than %d levels", maxFilterDepth)
+	}
+
+	kinds := 0
+	for _, set := range []bool{len(n.And) > 0, len(n.Or) > 0, n.Not != nil, n.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return "", nil, fmt.Errorf("each filter node must have exactly one of and, or, not, field")
+	}
+
+	switch {
+	case len(n.And) > 0:
+		return c.group(n.And, " AND ", depth)
+	case len(n.Or) > 0:
+		return c.group(n.Or, " OR ", depth)
+	case n.Not != nil:
+		sql, args, err := c.node(n.Not, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + sql + ")", args, nil
+	}
+	return c.leaf(n)
+}
+
+func (c *filterCompiler) group(nodes []*dto.FilterNode, sep string, depth int) (string, []interface{}, error) {
+	parts := make([]string, 0, len(nodes))
+	var args []interface{}
+	for _, child := range nodes {
+		if child == nil {
+			return "", nil, fmt.Errorf("empty filter node")
+		}
+		sql, childArgs, err := c.node(child, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, sql)
+		args = append(args, childArgs...)
+	}
+	return "(" + strings.Join(parts, sep) + ")", args, nil
+}
+
+func (c *filterCompiler) leaf(n *dto.FilterNode) (string, []interface{}, error) {
+	c.conditions++
+	if c.conditions > maxFilterConditions {
+		return "", nil, fmt.Errorf("filter has more than %d conditions", maxFilterConditions)
+	}
+
+	f, ok := c.entity.fields[n.Field]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown field %q for %s", n.Field, c.entity.table)
+	}
+	fail := func(format string, args ...interface{}) (string, []interface{}, error) {
+		return "", nil, fmt.Errorf("%s %s: %s", n.Field, n.Op, fmt.Sprintf(format, args...))
+	}
+
+	var value interface{}
+	if len(n.Value) > 0 {
+		if err := json.Unmarshal(n.Value, &value); err != nil {
+			return fail("invalid value")
+		}
+	}
+
+	if f.typ == filterTags {
+		return c.tagsLeaf(f, n, value)
+	}
+
+	switch n.Op {
+	case "eq", "ne":
+		v, err := c.scalar(f, value)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if n.Op == "eq" {
+			return f.expr + " = ?", []interface{}{v}, nil
+		}
+		return f.expr + " IS DISTINCT FROM ?", []interface{}{v}, nil
+
+	case "gt", "gte", "lt", "lte":
+		if f.typ != filterNumber && f.typ != filterTime {
+			return fail("only applies to number and time fields")
+		}
+		v, err := c.scalar(f, value)
+		if err != nil {
+			return fail("%v", err)
+		}
+		op := map[string]string{"gt": " > ?", "gte": " >= ?", "lt": " < ?", "lte": " <= ?"}[n.Op]
+		return f.expr + op, []interface{}{v}, nil
+
+	case "between":
+		if f.typ != filterNumber && f.typ != filterTime {
+			return fail("only applies to number and time fields")
+		}
+		list, ok := value.([]interface{})
+		if !ok || len(list) != 2 {
+			return fail("value must be a [from, to] array")
+		}
+		from, err := c.scalar(f, list[0])
+		if err != nil {
+			return fail("%v", err)
+		}
+		to, err := c.scalar(f, list[1])
+		if err != nil {
+			return fail("%v", err)
+		}
+		return f.expr + " BETWEEN ? AND ?", []interface{}{from, to}, nil
+
+	case "in", "not_in":
+		if f.typ == filterBool {
+			return fail("does not apply to bool fields")
+		}
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 || len(list) > maxFilterInValues {
+			return fail("value must be an array of 1-%d items", maxFilterInValues)
+		}
+		values := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			v, err := c.scalar(f, item)
+			if err != nil {
+				return fail("%v", err)
+			}
+			values = append(values, v)
+		}
+		if n.Op == "in" {
+			return f.expr + " IN ?", []interface{}{values}, nil
+		}
+		return f.expr + " NOT IN ?", []interface{}{values}, nil
+
+	case "contains":
+		if f.typ != filterString {
+			return fail("only applies to string fields")
+		}
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return fail("value must be a non-empty string")
+		}
+		return f.expr + " ILIKE ?", []interface{}{"%" + escapeLike(s) + "%"}, nil
+
+	case "is_empty":
+		if f.typ == filterString {
+			return "(" + f.expr + " IS NULL OR " + f.expr + " = '')", nil, nil
+		}
+		return f.expr + " IS NULL", nil, nil
+
+	case "not_empty":
+		if f.typ == filterString {
+			return "(" + f.expr + " IS NOT NULL AND " + f.expr + " <> '')", nil, nil
+		}
+		return f.expr + " IS NOT NULL", nil, nil
+
+	case "older_than", "within":
+		if f.typ != filterTime {
+			return fail("only applies to time fields")
+		}
+		s, _ := value.(string)
+		cutoff, err := shiftTime(c.now, s, -1)
+		if err != nil {
+			return fail("value must be a duration like 14d, 2w, 3m, 1y or 12h")
+		}
+		if n.Op == "older_than" {
+			return f.expr + " < ?", []interface{}{cutoff}, nil
+		}
+		return f.expr + " >= ?", []interface{}{cutoff}, nil
+	}
+
+	return fail("unknown operator (expected one of %s)", strings.Join(dto.FilterOps, ", "))
+}
+
+// tagsLeaf 标签字段的条件：eq / ne 为有 / 没有某个标签，in / not_in 为有其中任一 / 一个都没有，
+// contains 为有标签名包含该文本的标签，is_empty / not_empty 为没有 / 有标签
+func (c *filterCompiler) tagsLeaf(f filterField, n *dto.FilterNode, value interface{}) (string, []interface{}, error) {
+	fail := func(format string, args ...interface{}) (string, []interface{}, error) {
+		return "", nil, fmt.Errorf("%s %s: %s", n.Field, n.Op, fmt.Sprintf(format, args...))
+	}
+
+	switch n.Op {
+	case "eq", "ne":
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return fail("value must be a tag name")
+		}
+		if n.Op == "eq" {
+			return "? = ANY(" + f.expr + ")", []interface{}{s}, nil
+		}
+		return "NOT (? = ANY(" + f.expr + "))", []interface{}{s}, nil
+
+	case "in", "not_in":
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 || len(list) > maxFilterInValues {
+			return fail("value must be an array of 1-%d tag names", maxFilterInValues)
+		}
+		names := make(pq.StringArray, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return fail("value must be an array of tag names")
+			}
+			names = append(names, s)
+		}
+		if n.Op == "in" {
+			return f.expr + " && ?", []interface{}{names}, nil
+		}
+		return "NOT (" + f.expr + " && ?)", []interface{}{names}, nil
+
+	case "contains":
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return fail("value must be a non-empty string")
+		}
+		return "EXISTS (SELECT 1 FROM unnest(" + f.expr + ") AS tag WHERE tag ILIKE ?)", []interface{}{"%" + escapeLike(s) + "%"}, nil
+
+	case "is_empty":
+		return "cardinality(" + f.expr + ") = 0", nil, nil
+
+	case "not_empty":
+		return "cardinality(" + f.expr + ") > 0", nil, nil
+	}
+
+	return fail("only eq, ne, in, not_in, contains, is_empty and not_empty apply to tags")
+}
+
+// scalar 按字段类型检查并转换单个取值
+func (c *filterCompiler) scalar(f filterField, value interface{}) (interface{}, error) {
+	switch f.typ {
+	case filterString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value must be a string")
+		}
+		if len(f.enum) > 0 {
+			for _, allowed := range f.enum {
+				if s == allowed {
+					return s, nil
+				}
+			}
+			return nil, fmt.Errorf("value must be one of %s, got %q", strings.Join(f.enum, ", "), s)
+		}
+		return s, nil
+	case filterNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("value must be a number")
+		}
+		return n, nil
+	case filterBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("value must be true or false")
+		}
+		return b, nil
+	case filterTime:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value must be a date string")
+		}
+		t, err := parseTimeValue(c.now, s)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("unsupported field type")
+}
+
+// parseTimeValue 支持 now、today、相对时间（-14d、+1w）以及 2006-01-02 / RFC3339 格式
+func parseTimeValue(now time.Time, s string) (time.Time, error) {
+	switch s {
+	case "now":
+		return now, nil
+	case "today":
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	}
+	if strings.HasPrefix(s, "-") {
+		return shiftTime(now, s[1:], -1)
+	}
+	if strings.HasPrefix(s, "+") {
+		return shiftTime(now, s[1:], 1)
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use 2006-01-02, RFC3339, today, now or offsets like -14d)", s)
+}
+
+// shiftTime 把 now 按 14d / 2w / 3m / 1y / 12h 形式的时长前移（sign=-1）或后移（sign=1）
+func shiftTime(now time.Time, duration string, sign int) (time.Time, error) {
+	if len(duration) < 2 {
+		return time.Time{}, fmt.Errorf("invalid duration %q", duration)
+	}
+	n, err := strconv.Atoi(duration[:len(duration)-1])
+	if err != nil || n < 0 || n > 10000 {
+		return time.Time{}, fmt.Errorf("invalid duration %q", duration)
+	}
+	n *= sign
+	switch duration[len(duration)-1] {
+	case 'h':
+		return now.Add(time.Duration(n) * time.Hour), nil
+	case 'd':
+		return now.AddDate(0, 0, n), nil
+	case 'w':
+		return now.AddDate(0, 0, 7*n), nil
+	case 'm':
+		return now.AddDate(0, n, 0), nil
+	case 'y':
+		return now.AddDate(n, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid duration %q", duration)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+type FilterRepository struct {
+	db *gorm.DB
+}
+
+func NewFilterRepository(db *gorm.DB) *FilterRepository {
+	return &FilterRepository{db: db}
+}
+
+// Query 执行结构化查询，只返回 userID 名下的记录
+func (r *FilterRepository) Query(spec *dto.QuerySpec, userID uint64) (interface{}, int64, error) {
+	q, err := compileQuery(spec, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.page(q, userID, 0, q.limit)
+}
+
+// QueryPage 执行结构化查询并分页，忽略 spec.Limit
+func (r *FilterRepository) QueryPage(spec *dto.QuerySpec, userID uint64, offset, limit int) (interface{}, int64, error) {
+	q, err := compileQuery(spec, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+	return r.page(q, userID, offset, limit)
+}
+
+// Count 统计结构化查询匹配的记录数
+func (r *FilterRepository) Count(spec *dto.QuerySpec, userID uint64) (int64, error) {
+	q, err := compileQuery(spec, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = r.scoped(q, userID).Count(&total).Error
+	return total, err
+}
+
+// IDs 结构化查询匹配的记录 ID，按查询的排序，最多 limit 个
+func (r *FilterRepository) IDs(spec *dto.QuerySpec, userID uint64, limit int) ([]uint64, error) {
+	q, err := compileQuery(spec, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ids := []uint64{}
+	err = r.scoped(q, userID).Order(q.order).Limit(limit).Pluck(q.entity.table+".id", &ids).Error
+	return ids, err
+}
+
+// ArchivedIDs 与 IDs 相同，但只在已归档（未被合并）的客户中查找，仅支持 customers
+func (r *FilterRepository) ArchivedIDs(spec *dto.QuerySpec, userID uint64, limit int) ([]uint64, error) {
+	if spec.Entity != "customers" {
+		return nil, fmt.Errorf("archived records are only available for customers")
+	}
+	q, err := compileQuery(spec, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ids := []uint64{}
+	err = r.scoped(q, userID).Unscoped().
+		Where("customers.deleted_at IS NOT NULL AND customers.merged_into_id IS NULL").
+		Order(q.order).Limit(limit).Pluck("customers.id", &ids).Error
+	return ids, err
+}
+
+func (r *FilterRepository) scoped(q *compiledQuery, userID uint64) *gorm.DB {
+	db := r.db.Model(q.entity.newRows()).Where(q.entity.table+".user_id = ?", userID)
+	if q.where != "" {
+		db = db.Where(q.where, q.args...)
+	}
+	return db
+}
+
+func (r *FilterRepository) page(q *compiledQuery, userID uint64, offset, limit int) (interface{}, int64, error) {
+	rows := q.entity.newRows()
+	db := r.scoped(q, userID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order(q.order).Offset(offset).Limit(limit).Find(rows).Error; err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
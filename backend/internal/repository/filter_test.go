@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/xia/nextcrm/internal/dto"
+)
+
+var filterNow = time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+
+func leaf(field, op string, value interface{}) *dto.FilterNode {
+	n := &dto.FilterNode{Field: field, Op: op}
+	if value != nil {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			panic(err)
+		}
+		n.Value = raw
+	}
+	return n
+}
+
+// nested 把 n 包进 levels 层 not，整棵树共 levels+1 层
+func nested(n *dto.FilterNode, levels int) *dto.FilterNode {
+	for i := 0; i < levels; i++ {
+		n = &dto.FilterNode{Not: n}
+	}
+	return n
+}
+
+func TestCompileFilter(t *testing.T) {
+	cases := []struct {
+		name   string
+		entity string
+		filter *dto.FilterNode
+		where  string
+		args   []interface{}
+	}{
+		{
+			name:   "nil filter",
+			entity: "customers",
+		},
+		{
+			name:   "enum eq",
+			entity: "customers",
+			filter: leaf("stage", "eq", "Qualified"),
+			where:  "customers.stage = ?",
+			args:   []interface{}{"Qualified"},
+		},
+		{
+			name:   "ne keeps nulls",
+			entity: "deals",
+			filter: leaf("payment_status", "ne", "paid"),
+			where:  "deals.payment_status IS DISTINCT FROM ?",
+			args:   []interface{}{"paid"},
+		},
+		{
+			name:   "number between",
+			entity: "deals",
+			filter: leaf("amount", "between", []float64{1000, 5000}),
+			where:  "deals.amount BETWEEN ? AND ?",
+			args:   []interface{}{1000.0, 5000.0},
+		},
+		{
+			name:   "in",
+			entity: "interactions",
+			filter: leaf("type", "in", []string{"call", "meeting"}),
+			where:  "interactions.type IN ?",
+			args:   []interface{}{[]interface{}{"call", "meeting"}},
+		},
+		{
+			name:   "contains escapes wildcards",
+			entity: "customers",
+			filter: leaf("company", "contains", `50%_off\`),
+			where:  "customers.company ILIKE ?",
+			args:   []interface{}{`%50\%\_off\\%`},
+		},
+		{
+			name:   "string is_empty",
+			entity: "customers",
+			filter: leaf("email", "is_empty", nil),
+			where:  "(customers.email IS NULL OR customers.email = '')",
+		},
+		{
+			name:   "tags in",
+			entity: "knowledge",
+			filter: leaf("tags", "in", []string{"pricing", "demo"}),
+			where:  "knowledge_base.tags && ?",
+			args:   []interface{}{pq.StringArray{"pricing", "demo"}},
+		},
+		{
+			name:   "tags ne",
+			entity: "customers",
+			filter: leaf("tags", "ne", "vip"),
+			where:  "NOT (? = ANY(customers.tags))",
+			args:   []interface{}{"vip"},
+		},
+		{
+			name:   "relative date",
+			entity: "customers",
+			filter: leaf("created_at", "gte", "-14d"),
+			where:  "customers.created_at >= ?",
+			args:   []interface{}{time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:   "within",
+			entity: "interactions",
+			filter: leaf("created_at", "within", "2w"),
+			where:  "interactions.created_at >= ?",
+			args:   []interface{}{time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:   "older_than",
+			entity: "customers",
+			filter: leaf("last_contact", "older_than", "1m"),
+			where:  "customers.last_contact < ?",
+			args:   []interface{}{time.Date(2024, 2, 15, 10, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:   "depth limit reached exactly",
+			entity: "customers",
+			filter: nested(leaf("stage", "eq", "Leads"), maxFilterDepth-1),
+			where:  "NOT (NOT (NOT (NOT (customers.stage = ?))))",
+			args:   []interface{}{"Leads"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := lookupEntity(tc.entity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			where, args, err := compileFilter(e, tc.filter, filterNow)
+			if err != nil {
+				t.Fatalf("compileFilter: %v", err)
+			}
+			if where != tc.where {
+				t.Errorf("where = %q, want %q", where, tc.where)
+			}
+			if !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("args = %#v, want %#v", args, tc.args)
+			}
+		})
+	}
+}
+
+func TestCompileFilterGroups(t *testing.T) {
+	e, _ := lookupEntity("deals")
+	filter := &dto.FilterNode{And: []*dto.FilterNode{
+		leaf("currency", "eq", "CNY"),
+		{Or: []*dto.FilterNode{
+			leaf("paid_amount", "lt", 100),
+			{Not: leaf("is_repeat_purchase", "eq", true)},
+		}},
+	}}
+	where, args, err := compileFilter(e, filter, filterNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "(deals.currency = ? AND (deals.paid_amount < ? OR NOT (deals.is_repeat_purchase = ?)))"
+	if where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"CNY", 100.0, true}) {
+		t.Errorf("args = %#v", args)
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	many := func(n int) *dto.FilterNode {
+		children := make([]*dto.FilterNode, n)
+		for i := range children {
+			children[i] = leaf("probability", "gte", i)
+		}
+		return &dto.FilterNode{Or: children}
+	}
+	values := make([]string, maxFilterInValues+1)
+	for i := range values {
+		values[i] = "tag"
+	}
+
+	cases := []struct {
+		name   string
+		filter *dto.FilterNode
+		want   string
+	}{
+		{"unknown field", leaf("password", "eq", "x"), `unknown field "password"`},
+		{"unknown operator", leaf("name", "like", "x"), "unknown operator"},
+		{"missing operator", leaf("name", "", "x"), "unknown operator"},
+		{"range on string", leaf("name", "gt", "a"), "only applies to number and time fields"},
+		{"contains on number", leaf("probability", "contains", "5"), "only applies to string fields"},
+		{"relative on number", leaf("probability", "within", "14d"), "only applies to time fields"},
+		{"enum value", leaf("stage", "eq", "Won"), "value must be one of"},
+		{"number type", leaf("probability", "eq", "high"), "value must be a number"},
+		{"bad date", leaf("created_at", "lt", "yesterday"), "invalid date"},
+		{"bad duration", leaf("created_at", "within", "14x"), "value must be a duration"},
+		{"negative duration", leaf("created_at", "older_than", "-3d"), "value must be a duration"},
+		{"between needs two", leaf("probability", "between", []int{1}), "[from, to]"},
+		{"empty in", leaf("stage", "in", []string{}), "array of 1-50 items"},
+		{"too many in values", leaf("tags", "in", values), "array of 1-50 tag names"},
+		{"tags range", leaf("tags", "gt", "a"), "apply to tags"},
+		{"invalid json", &dto.FilterNode{Field: "name", Op: "eq", Value: json.RawMessage(`{`)}, "invalid value"},
+		{"empty node", &dto.FilterNode{}, "exactly one of"},
+		{"two kinds", &dto.FilterNode{Field: "name", Op: "eq", Not: leaf("name", "eq", "x")}, "exactly one of"},
+		{"nil child", &dto.FilterNode{And: []*dto.FilterNode{nil}}, "empty filter node"},
+		{"too deep", nested(leaf("stage", "eq", "Leads"), maxFilterDepth), "deeper than 5 levels"},
+		{"too many conditions", many(maxFilterConditions + 1), "more than 30 conditions"},
+	}
+	e, _ := lookupEntity("customers")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := compileFilter(e, tc.filter, filterNow)
+			if err == nil {
+				t.Fatalf("expected error containing %q", tc.want)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %q, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+
+	if _, _, err := compileFilter(e, many(maxFilterConditions), filterNow); err != nil {
+		t.Errorf("%d conditions should be allowed: %v", maxFilterConditions, err)
+	}
+}
+
+func TestCompileSort(t *testing.T) {
+	e, _ := lookupEntity("customers")
+	cases := []struct {
+		name  string
+		sorts []dto.SortSpec
+		want  []string
+		err   string
+	}{
+		{name: "none", want: []string{}},
+		{
+			name:  "default asc",
+			sorts: []dto.SortSpec{{Field: "potential_score", Order: "DESC"}, {Field: "name"}},
+			want:  []string{"customers.potential_score DESC", "customers.name ASC"},
+		},
+		{
+			name:  "computed field",
+			sorts: []dto.SortSpec{{Field: "deal_total", Order: "desc"}},
+			want:  []string{"(SELECT COALESCE(SUM(d.amount), 0) FROM deals d WHERE d.customer_id = customers.id AND d.deleted_at IS NULL) DESC"},
+		},
+		{name: "not whitelisted", sorts: []dto.SortSpec{{Field: "password_hash"}}, err: `unknown sort field "password_hash"`},
+		{name: "raw sql", sorts: []dto.SortSpec{{Field: "name; DROP TABLE customers"}}, err: "unknown sort field"},
+		{name: "bad order", sorts: []dto.SortSpec{{Field: "name", Order: "sideways"}}, err: "asc or desc"},
+		{
+			name:  "too many fields",
+			sorts: []dto.SortSpec{{Field: "name"}, {Field: "stage"}, {Field: "source"}, {Field: "industry"}},
+			err:   "at most 3 sort fields",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			orders, err := compileSort(e, tc.sorts)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("err = %v, want it to contain %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(orders, tc.want) {
+				t.Errorf("orders = %q, want %q", orders, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeValue(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Time
+	}{
+		{"now", filterNow},
+		{"today", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"-14d", time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)},
+		{"+1w", time.Date(2024, 3, 22, 10, 30, 0, 0, time.UTC)},
+		{"-3m", time.Date(2023, 12, 15, 10, 30, 0, 0, time.UTC)},
+		{"+1y", time.Date(2025, 3, 15, 10, 30, 0, 0, time.UTC)},
+		{"-12h", time.Date(2024, 3, 14, 22, 30, 0, 0, time.UTC)},
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-02T08:00:00+08:00", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		got, err := parseTimeValue(filterNow, tc.in)
+		if err != nil {
+			t.Errorf("parseTimeValue(%q): %v", tc.in, err)
+			continue
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("parseTimeValue(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "14d", "-d", "-14", "-14x", "--1d", "-10001d", "2024/01/02"} {
+		if _, err := parseTimeValue(filterNow, in); err == nil {
+			t.Errorf("parseTimeValue(%q) should fail", in)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	cases := map[string]string{
+		"acme":     "acme",
+		"100%":     `100\%`,
+		"a_b":      `a\_b`,
+		`C:\temp`:  `C:\\temp`,
+		`\%_`:      `\\\%\_`,
+		"张三_有限公司%": `张三\_有限公司\%`,
+	}
+	for in, want := range cases {
+		if got := escapeLike(in); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
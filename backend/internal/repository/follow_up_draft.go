@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type FollowUpDraftRepository struct {
+	db *gorm.DB
+}
+
+func NewFollowUpDraftRepository(db *gorm.DB) *FollowUpDraftRepository {
+	return &FollowUpDraftRepository{db: db}
+}
+
+// Create saves a follow-up draft
+func (r *FollowUpDraftRepository) Create(draft *models.FollowUpDraft) error {
+	return r.db.Create(draft).Error
+}
+
+// FindByID finds a follow-up draft by ID
+func (r *FollowUpDraftRepository) FindByID(id uint64) (*models.FollowUpDraft, error) {
+	var draft models.FollowUpDraft
+	if err := r.db.First(&draft, id).Error; err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// ListByCustomerID lists a customer's follow-up drafts, newest first
+func (r *FollowUpDraftRepository) ListByCustomerID(customerID uint64) ([]*models.FollowUpDraft, error) {
+	var drafts []*models.FollowUpDraft
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Find(&drafts).Error
+	return drafts, err
+}
+
+// Update saves all fields of a follow-up draft
+func (r *FollowUpDraftRepository) Update(draft *models.FollowUpDraft) error {
+	return r.db.Save(draft).Error
+}
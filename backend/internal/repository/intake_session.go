@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type IntakeSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewIntakeSessionRepository(db *gorm.DB) *IntakeSessionRepository {
+	return &IntakeSessionRepository{db: db}
+}
+
+// Create saves an intake session
+func (r *IntakeSessionRepository) Create(session *models.IntakeSession) error {
+	return r.db.Create(session).Error
+}
+
+// FindByID finds an intake session by ID
+func (r *IntakeSessionRepository) FindByID(id uint64) (*models.IntakeSession, error) {
+	var session models.IntakeSession
+	if err := r.db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActive lists a user's unconfirmed sessions that have not expired, most recently updated first
+func (r *IntakeSessionRepository) ListActive(userID uint64, now time.Time) ([]*models.IntakeSession, error) {
+	var sessions []*models.IntakeSession
+	err := r.db.Where("user_id = ? AND status <> ? AND expires_at > ?", userID, models.IntakeConfirmed, now).
+		Order("updated_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Update saves all fields of an intake session
+func (r *IntakeSessionRepository) Update(session *models.IntakeSession) error {
+	return r.db.Save(session).Error
+}
+
+// Delete removes an intake session
+func (r *IntakeSessionRepository) Delete(id uint64) error {
+	return r.db.Delete(&models.IntakeSession{}, id).Error
+}
+
+// DeleteExpired removes unconfirmed sessions that expired before now
+func (r *IntakeSessionRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("status <> ? AND expires_at <= ?", models.IntakeConfirmed, now).
+		Delete(&models.IntakeSession{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type IntentProposalRepository struct {
+	db *gorm.DB
+}
+
+func NewIntentProposalRepository(db *gorm.DB) *IntentProposalRepository {
+	return &IntentProposalRepository{db: db}
+}
+
+// Create saves an intent proposal
+func (r *IntentProposalRepository) Create(proposal *models.IntentProposal) error {
+	return r.db.Create(proposal).Error
+}
+
+// FindByID finds an intent proposal by ID
+func (r *IntentProposalRepository) FindByID(id uint64) (*models.IntentProposal, error) {
+	var proposal models.IntentProposal
+	if err := r.db.First(&proposal, id).Error; err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// ListByCustomerID lists a customer's intent proposals, newest first
+func (r *IntentProposalRepository) ListByCustomerID(customerID uint64, limit int) ([]*models.IntentProposal, error) {
+	var proposals []*models.IntentProposal
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&proposals).Error
+	return proposals, err
+}
+
+// SupersedePending marks a customer's pending proposals as superseded
+func (r *IntentProposalRepository) SupersedePending(customerID uint64) error {
+	return r.db.Model(&models.IntentProposal{}).
+		Where("customer_id = ? AND status = ?", customerID, models.IntentProposalPending).
+		Update("status", models.IntentProposalSuperseded).Error
+}
+
+// Update saves all fields of an intent proposal
+func (r *IntentProposalRepository) Update(proposal *models.IntentProposal) error {
+	return r.db.Save(proposal).Error
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"time"
 
+	"github.com/xia/nextcrm/internal/dto"
 	"github.com/xia/nextcrm/internal/models"
 	"gorm.io/gorm"
 )
@@ -42,6 +43,19 @@ func (r *InteractionRepository) FindByCustomerID(customerID uint64) ([]*models.I
 	return interactions, nil
 }
 
+// FindRecentByCustomerID finds the newest interactions for a customer
+func (r *InteractionRepository) FindRecentByCustomerID(customerID uint64, limit int) ([]*models.Interaction, error) {
+	var interactions []*models.Interaction
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&interactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return interactions, nil
+}
+
 // FindByUserID finds all interactions for a user
 func (r *InteractionRepository) FindByUserID(userID uint64) ([]*models.Interaction, error) {
 	var interactions []*models.Interaction
@@ -54,12 +68,28 @@ func (r *InteractionRepository) FindByUserID(userID uint64) ([]*models.Interacti
 	return interactions, nil
 }
 
-// FindByCustomerIDAndUserID finds interactions for a specific customer belonging to a user
-func (r *InteractionRepository) FindByCustomerIDAndUserID(customerID, userID uint64) ([]*models.Interaction, error) {
+// FindByCustomerIDAndUserID finds interactions for a specific customer belonging to a user,
+// optionally filtered (custom fields, filter DSL) and sorted (newest first by default)
+func (r *InteractionRepository) FindByCustomerIDAndUserID(customerID, userID uint64, query *dto.InteractionListQuery) ([]*models.Interaction, error) {
 	var interactions []*models.Interaction
-	err := r.db.Where("customer_id = ? AND user_id = ?", customerID, userID).
-		Order("created_at DESC").
-		Find(&interactions).Error
+	db := r.db.Model(&models.Interaction{}).Where("customer_id = ? AND user_id = ?", customerID, userID)
+	order := "created_at DESC"
+	if query != nil {
+		db = applyCustomFieldFilters(db, "interactions", query.CustomFieldFilters)
+		var err error
+		if db, err = applyListFilter(db, "interactions", &query.ListFilter); err != nil {
+			return nil, err
+		}
+		if order, err = listOrder("interactions", &query.ListFilter, order); err != nil {
+			return nil, err
+		}
+	}
+	if query != nil && query.CustomFieldSort != nil {
+		db = db.Clauses(customFieldOrder("interactions", query.CustomFieldSort))
+	} else {
+		db = db.Order(order)
+	}
+	err := db.Find(&interactions).Error
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +101,32 @@ func (r *InteractionRepository) Update(interaction *models.Interaction) error {
 	return r.db.Save(interaction).Error
 }
 
+// UpdateSignals stores AI-extracted signals without touching other fields or updated_at.
+// Outcome is filled from the sentiment only when the rep left it empty.
+func (r *InteractionRepository) UpdateSignals(id uint64, signals *models.InteractionSignals, analyzedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Interaction{ID: id}).
+			Select("signals", "signals_analyzed_at").
+			UpdateColumns(&models.Interaction{Signals: signals, SignalsAnalyzedAt: &analyzedAt}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Interaction{}).
+			Where("id = ? AND (outcome IS NULL OR outcome = '')", id).
+			UpdateColumn("outcome", signals.Sentiment).Error
+	})
+}
+
+// FindAnalyzedByCustomerID finds a customer's interactions with signals since the given time, newest first
+func (r *InteractionRepository) FindAnalyzedByCustomerID(customerID uint64, since time.Time, limit int) ([]*models.Interaction, error) {
+	var interactions []*models.Interaction
+	err := r.db.Where("customer_id = ? AND signals IS NOT NULL AND created_at >= ?", customerID, since).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&interactions).Error
+	return interactions, err
+}
+
 // Delete soft deletes an interaction
 func (r *InteractionRepository) Delete(id uint64) error {
 	return r.db.Delete(&models.Interaction{}, id).Error
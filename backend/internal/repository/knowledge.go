@@ -50,13 +50,23 @@ func (r *KnowledgeRepository) FindByUserID(userID uint64, query *dto.KnowledgeQu
 		db = db.Where("tags && ?", query.Tags)
 	}
 
+	db, err := applyListFilter(db, "knowledge", &query.ListFilter)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Count total
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
+	order, err := listOrder("knowledge", &query.ListFilter, "created_at DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Apply pagination
-	err := db.Order("created_at DESC").
+	err = db.Order(order).
 		Offset((query.Page - 1) * query.PerPage).
 		Limit(query.PerPage).
 		Find(&knowledges).Error
@@ -68,6 +78,18 @@ func (r *KnowledgeRepository) FindByUserID(userID uint64, query *dto.KnowledgeQu
 	return knowledges, total, nil
 }
 
+// FindTeamEntriesByType finds entries of a type owned by the user or by members of the user's team, newest first
+func (r *KnowledgeRepository) FindTeamEntriesByType(userID uint64, entryType string, limit int) ([]*models.KnowledgeBase, error) {
+	var knowledges []*models.KnowledgeBase
+	err := r.db.Joins("JOIN users ON users.id = knowledge_base.user_id").
+		Where("knowledge_base.type = ?", entryType).
+		Where("knowledge_base.user_id = ? OR users.team_id = (SELECT team_id FROM users WHERE id = ?)", userID, userID).
+		Order("knowledge_base.updated_at DESC").
+		Limit(limit).
+		Find(&knowledges).Error
+	return knowledges, err
+}
+
 // Update updates a knowledge base entry
 func (r *KnowledgeRepository) Update(knowledge *models.KnowledgeBase) error {
 	return r.db.Save(knowledge).Error
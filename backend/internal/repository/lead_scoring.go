@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+// CustomerEvent 客户的一条带时间的事件（跟进、阶段变更）
+type CustomerEvent struct {
+	CustomerID  uint64
+	Description string
+	CreatedAt   time.Time
+}
+
+// LeadScoringData 训练 / 打分需要的全部客户及其跟进、首次成交、阶段变更时间
+type LeadScoringData struct {
+	Customers    []*models.Customer
+	Interactions map[uint64][]time.Time     // 按时间升序
+	StageChanges map[uint64][]CustomerEvent // 按时间升序，Description 为「旧阶段 → 新阶段」
+	FirstDealAt  map[uint64]time.Time
+}
+
+type LeadScoringRepository struct {
+	db *gorm.DB
+}
+
+func NewLeadScoringRepository(db *gorm.DB) *LeadScoringRepository {
+	return &LeadScoringRepository{db: db}
+}
+
+// LoadData loads every active customer together with the timestamps the features are built from
+func (r *LeadScoringRepository) LoadData() (*LeadScoringData, error) {
+	data := &LeadScoringData{
+		Interactions: make(map[uint64][]time.Time),
+		StageChanges: make(map[uint64][]CustomerEvent),
+		FirstDealAt:  make(map[uint64]time.Time),
+	}
+
+	err := r.db.Select("id, user_id, stage, source, industry, company_scale, contract_status, customer_status, created_at").
+		Find(&data.Customers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var interactions []CustomerEvent
+	err = r.db.Model(&models.Interaction{}).
+		Select("customer_id, created_at").
+		Order("created_at").
+		Scan(&interactions).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range interactions {
+		data.Interactions[it.CustomerID] = append(data.Interactions[it.CustomerID], it.CreatedAt)
+	}
+
+	var stageChanges []CustomerEvent
+	err = r.db.Model(&models.Activity{}).
+		Select("customer_id, description, created_at").
+		Where("action_type = ? AND customer_id IS NOT NULL AND deleted_at IS NULL", models.ActivityStageChange).
+		Order("created_at").
+		Scan(&stageChanges).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, ev := range stageChanges {
+		data.StageChanges[ev.CustomerID] = append(data.StageChanges[ev.CustomerID], ev)
+	}
+
+	var firstDeals []struct {
+		CustomerID uint64
+		FirstAt    time.Time
+	}
+	err = r.db.Model(&models.Deal{}).
+		Select("customer_id, MIN(deal_at) AS first_at").
+		Group("customer_id").
+		Scan(&firstDeals).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range firstDeals {
+		data.FirstDealAt[d.CustomerID] = d.FirstAt
+	}
+
+	return data, nil
+}
+
+// CreateModel saves a trained model
+func (r *LeadScoringRepository) CreateModel(model *models.LeadScoringModel) error {
+	return r.db.Create(model).Error
+}
+
+// LatestModel returns the most recently trained model
+func (r *LeadScoringRepository) LatestModel() (*models.LeadScoringModel, error) {
+	var model models.LeadScoringModel
+	if err := r.db.Order("trained_at DESC, id DESC").First(&model).Error; err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// ReplaceScores replaces all lead scores with a fresh scoring run
+func (r *LeadScoringRepository) ReplaceScores(scores []*models.LeadScore) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.LeadScore{}).Error; err != nil {
+			return err
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(scores, 500).Error
+	})
+}
+
+// FindScoreByCustomerID finds a customer's current lead score
+func (r *LeadScoringRepository) FindScoreByCustomerID(customerID uint64) (*models.LeadScore, error) {
+	var score models.LeadScore
+	if err := r.db.Where("customer_id = ?", customerID).First(&score).Error; err != nil {
+		return nil, err
+	}
+	return &score, nil
+}
+
+// ListScoresByUserID lists a user's scored customers, highest score first
+func (r *LeadScoringRepository) ListScoresByUserID(userID uint64, limit int) ([]*models.LeadScore, error) {
+	var scores []*models.LeadScore
+	err := r.db.Preload("Customer").
+		Where("user_id = ?", userID).
+		Order("score DESC, customer_id").
+		Limit(limit).
+		Find(&scores).Error
+	return scores, err
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type NextActionRepository struct {
+	db *gorm.DB
+}
+
+func NewNextActionRepository(db *gorm.DB) *NextActionRepository {
+	return &NextActionRepository{db: db}
+}
+
+// CreateBatch saves the items of a plan
+func (r *NextActionRepository) CreateBatch(actions []*models.NextAction) error {
+	if len(actions) == 0 {
+		return nil
+	}
+	return r.db.Create(&actions).Error
+}
+
+// FindByID finds a next action by ID
+func (r *NextActionRepository) FindByID(id uint64) (*models.NextAction, error) {
+	var action models.NextAction
+	if err := r.db.First(&action, id).Error; err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// ListByPlanDate lists a user's items for one day in rank order, skipping expired ones
+func (r *NextActionRepository) ListByPlanDate(userID uint64, planDate time.Time) ([]*models.NextAction, error) {
+	var actions []*models.NextAction
+	err := r.db.Preload("Customer").
+		Where("user_id = ? AND plan_date = ? AND status <> ?", userID, planDate, models.NextActionExpired).
+		Order("rank, id").
+		Find(&actions).Error
+	return actions, err
+}
+
+// ExpirePending marks a user's pending items up to and including planDate as expired
+func (r *NextActionRepository) ExpirePending(userID uint64, planDate time.Time) error {
+	return r.db.Model(&models.NextAction{}).
+		Where("user_id = ? AND status = ? AND plan_date <= ?", userID, models.NextActionPending, planDate).
+		Update("status", models.NextActionExpired).Error
+}
+
+// Update saves all fields of a next action
+func (r *NextActionRepository) Update(action *models.NextAction) error {
+	return r.db.Omit("Customer").Save(action).Error
+}
+
+// StatsByRank summarizes how items were handled per rank since the given date
+func (r *NextActionRepository) StatsByRank(since time.Time) ([]*dto.NextActionStat, error) {
+	return r.stats("CAST(rank AS TEXT)", "next_actions", since, "MIN(rank)")
+}
+
+// StatsBySource summarizes how items were handled per source (ai / rules) since the given date
+func (r *NextActionRepository) StatsBySource(since time.Time) ([]*dto.NextActionStat, error) {
+	return r.stats("source", "next_actions", since, "source")
+}
+
+// StatsBySignal summarizes how items were handled per rule signal since the given date
+func (r *NextActionRepository) StatsBySignal(since time.Time) ([]*dto.NextActionStat, error) {
+	return r.stats("signal", "next_actions, UNNEST(signals) AS signal", since, "signal")
+}
+
+func (r *NextActionRepository) stats(key, from string, since time.Time, order string) ([]*dto.NextActionStat, error) {
+	var stats []*dto.NextActionStat
+	err := r.db.Raw(`SELECT `+key+` AS key,
+		COUNT(*) AS total,
+		COUNT(*) FILTER (WHERE status = ?) AS accepted,
+		COUNT(*) FILTER (WHERE status = ?) AS dismissed,
+		COUNT(*) FILTER (WHERE status = ?) AS expired,
+		COUNT(*) FILTER (WHERE status = ?) AS pending
+		FROM `+from+`
+		WHERE plan_date >= ?
+		GROUP BY 1
+		ORDER BY `+order,
+		models.NextActionAccepted, models.NextActionDismissed, models.NextActionExpired, models.NextActionPending,
+		since,
+	).Scan(&stats).Error
+	return stats, err
+}
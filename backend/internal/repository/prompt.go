@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type PromptRepository struct {
+	db *gorm.DB
+}
+
+func NewPromptRepository(db *gorm.DB) *PromptRepository {
+	return &PromptRepository{db: db}
+}
+
+// byTeam 按团队过滤，teamID 为 nil 时只匹配全局模板
+func byTeam(db *gorm.DB, teamID *uint64) *gorm.DB {
+	if teamID == nil {
+		return db.Where("team_id IS NULL")
+	}
+	return db.Where("team_id = ?", *teamID)
+}
+
+// ListVersions returns all versions of a template, newest first
+func (r *PromptRepository) ListVersions(key string, teamID *uint64) ([]*models.PromptTemplate, error) {
+	var versions []*models.PromptTemplate
+	err := byTeam(r.db.Where("key = ?", key), teamID).
+		Order("version DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// FindVersion finds a specific version of a template
+func (r *PromptRepository) FindVersion(key string, teamID *uint64, version int) (*models.PromptTemplate, error) {
+	var tpl models.PromptTemplate
+	err := byTeam(r.db.Where("key = ? AND version = ?", key, version), teamID).
+		First(&tpl).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// FindLatest finds the newest version of a template
+func (r *PromptRepository) FindLatest(key string, teamID *uint64) (*models.PromptTemplate, error) {
+	var tpl models.PromptTemplate
+	err := byTeam(r.db.Where("key = ?", key), teamID).
+		Order("version DESC").
+		First(&tpl).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// CreateVersion stores a template as the next version number
+func (r *PromptRepository) CreateVersion(tpl *models.PromptTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var maxVersion int
+		err := byTeam(tx.Model(&models.PromptTemplate{}).Where("key = ?", tpl.Key), tpl.TeamID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&maxVersion).Error
+		if err != nil {
+			return err
+		}
+		tpl.Version = maxVersion + 1
+		return tx.Create(tpl).Error
+	})
+}
+
+// FindPin finds the pinned version of a template
+func (r *PromptRepository) FindPin(key string, teamID *uint64) (*models.PromptTemplatePin, error) {
+	var pin models.PromptTemplatePin
+	err := byTeam(r.db.Where("key = ?", key), teamID).First(&pin).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+// ListPins returns all pins
+func (r *PromptRepository) ListPins() ([]*models.PromptTemplatePin, error) {
+	var pins []*models.PromptTemplatePin
+	err := r.db.Order("key ASC").Find(&pins).Error
+	return pins, err
+}
+
+// SetPin replaces the pin of a template
+func (r *PromptRepository) SetPin(pin *models.PromptTemplatePin) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := byTeam(tx.Where("key = ?", pin.Key), pin.TeamID).Delete(&models.PromptTemplatePin{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(pin).Error
+	})
+}
+
+// DeletePin removes the pin so the latest version is used again
+func (r *PromptRepository) DeletePin(key string, teamID *uint64) error {
+	return byTeam(r.db.Where("key = ?", key), teamID).Delete(&models.PromptTemplatePin{}).Error
+}
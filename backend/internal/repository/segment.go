@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SegmentRepository struct {
+	db *gorm.DB
+}
+
+func NewSegmentRepository(db *gorm.DB) *SegmentRepository {
+	return &SegmentRepository{db: db}
+}
+
+func (r *SegmentRepository) Create(segment *models.Segment) error {
+	return r.db.Create(segment).Error
+}
+
+func (r *SegmentRepository) Update(segment *models.Segment) error {
+	return r.db.Save(segment).Error
+}
+
+func (r *SegmentRepository) Delete(id uint64) error {
+	return r.db.Delete(&models.Segment{}, id).Error
+}
+
+func (r *SegmentRepository) FindByID(id uint64) (*models.Segment, error) {
+	var segment models.Segment
+	if err := r.db.Where("id = ?", id).First(&segment).Error; err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+// FindVisible 用户自己的分群和同团队共享的分群，自己的在前
+func (r *SegmentRepository) FindVisible(userID uint64, teamID *uint64) ([]*models.Segment, error) {
+	var segments []*models.Segment
+	db := r.db.Where("user_id = ?", userID)
+	if teamID != nil {
+		db = r.db.Where("user_id = ? OR (shared AND team_id = ?)", userID, *teamID)
+	}
+	err := db.Clauses(clause.OrderBy{Expression: clause.Expr{SQL: "user_id = ? DESC, name, id", Vars: []interface{}{userID}}}).
+		Find(&segments).Error
+	return segments, err
+}
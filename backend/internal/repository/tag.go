@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type TagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to tx
+func (r *TagRepository) WithTx(tx *gorm.DB) *TagRepository {
+	return &TagRepository{db: tx}
+}
+
+// ListGroups 管理端列出分组；teamID 为空时列出全局和所有团队的分组
+func (r *TagRepository) ListGroups(teamID *uint64) ([]*models.TagGroup, error) {
+	var groups []*models.TagGroup
+	db := r.db
+	if teamID != nil {
+		db = db.Where("team_id = ?", *teamID)
+	}
+	err := db.Order("team_id NULLS FIRST, position, id").Find(&groups).Error
+	return groups, err
+}
+
+// FindEffectiveGroups 某个团队可用的分组：全局分组和该团队的分组（teamID 为空时只有全局分组）
+func (r *TagRepository) FindEffectiveGroups(teamID *uint64) ([]*models.TagGroup, error) {
+	var groups []*models.TagGroup
+	err := effectiveScope(r.db, teamID).Order("position, id").Find(&groups).Error
+	return groups, err
+}
+
+func (r *TagRepository) FindGroupByID(id uint64) (*models.TagGroup, error) {
+	var group models.TagGroup
+	if err := r.db.Where("id = ?", id).First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// FindGroupByName 同一范围（全局或某个团队）内的分组，名称不区分大小写
+func (r *TagRepository) FindGroupByName(teamID *uint64, name string) (*models.TagGroup, error) {
+	var group models.TagGroup
+	err := byTeam(r.db.Where("LOWER(name) = LOWER(?)", name), teamID).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *TagRepository) CreateGroup(group *models.TagGroup) error {
+	return r.db.Create(group).Error
+}
+
+func (r *TagRepository) UpdateGroup(group *models.TagGroup) error {
+	return r.db.Save(group).Error
+}
+
+// DeleteGroup 删除分组及其标签，并从范围内的客户上去掉这些标签
+func (r *TagRepository) DeleteGroup(group *models.TagGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var names []string
+		if err := tx.Model(&models.Tag{}).Where("group_id = ?", group.ID).Pluck("name", &names).Error; err != nil {
+			return err
+		}
+		if len(names) > 0 {
+			if err := customersInScope(tx, group.TeamID).
+				Where("tags && ?", pq.StringArray(names)).
+				Update("tags", gorm.Expr("ARRAY(SELECT t FROM unnest(tags) AS t WHERE t <> ALL(?))", pq.StringArray(names))).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("group_id = ?", group.ID).Delete(&models.Tag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.TagGroup{}, group.ID).Error
+	})
+}
+
+// FindTags 分组下的标签，按分组 ID 归类
+func (r *TagRepository) FindTags(groupIDs []uint64) (map[uint64][]*models.Tag, error) {
+	out := make(map[uint64][]*models.Tag)
+	if len(groupIDs) == 0 {
+		return out, nil
+	}
+	var tags []*models.Tag
+	if err := r.db.Where("group_id IN ?", groupIDs).Order("position, id").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		out[tag.GroupID] = append(out[tag.GroupID], tag)
+	}
+	return out, nil
+}
+
+// FindEffectiveTags 某个团队可用的标签（全局和该团队）
+func (r *TagRepository) FindEffectiveTags(teamID *uint64) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	err := effectiveScope(r.db, teamID).Order("position, id").Find(&tags).Error
+	return tags, err
+}
+
+func (r *TagRepository) FindTagByID(id uint64) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.Where("id = ?", id).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// FindConflictingTag 与 name 同名（不区分大小写）且会同时出现在某个用户可用范围内的其他标签：
+// 团队标签与全局标签和本团队标签冲突，全局标签与任何标签冲突
+func (r *TagRepository) FindConflictingTag(teamID *uint64, name string, excludeID uint64) (*models.Tag, error) {
+	var tag models.Tag
+	db := r.db.Where("LOWER(name) = LOWER(?) AND id <> ?", name, excludeID)
+	if teamID != nil {
+		db = effectiveScope(db, teamID)
+	}
+	if err := db.First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *TagRepository) CreateTag(tag *models.Tag) error {
+	return r.db.Create(tag).Error
+}
+
+// UpdateTag 保存标签；oldName 与新名称不同时，范围内客户上的标签一起改名
+func (r *TagRepository) UpdateTag(tag *models.Tag, oldName string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if oldName != tag.Name {
+			if err := customersInScope(tx, tag.TeamID).
+				Where("? = ANY(tags)", oldName).
+				Update("tags", gorm.Expr("array_replace(tags, ?, ?)", oldName, tag.Name)).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(tag).Error
+	})
+}
+
+// DeleteTag 删除标签，并从范围内的客户上去掉
+func (r *TagRepository) DeleteTag(tag *models.Tag) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := customersInScope(tx, tag.TeamID).
+			Where("? = ANY(tags)", tag.Name).
+			Update("tags", gorm.Expr("array_remove(tags, ?)", tag.Name)).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Tag{}, tag.ID).Error
+	})
+}
+
+// SetCustomerTags 替换客户的全部标签
+func (r *TagRepository) SetCustomerTags(customerID uint64, tags []string) error {
+	return r.db.Model(&models.Customer{ID: customerID}).Update("tags", tagsValue(tags)).Error
+}
+
+// UpdateCustomerTags 给 userID 名下的客户加上 add、去掉 remove 中的标签，返回标签有变化的客户 ID
+func (r *TagRepository) UpdateCustomerTags(userID uint64, customerIDs []uint64, add, remove []string) ([]uint64, error) {
+	if len(customerIDs) == 0 {
+		return nil, nil
+	}
+	if add == nil {
+		add = []string{}
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+	var changed []uint64
+	err := r.db.Raw(`UPDATE customers
+SET tags = ARRAY(SELECT DISTINCT t FROM unnest(array_cat(tags, ?::text[])) AS t WHERE t <> ALL(?::text[]) ORDER BY t),
+  updated_at = NOW()
+WHERE user_id = ? AND id IN ? AND deleted_at IS NULL
+  AND NOT (tags @> ?::text[] AND NOT tags && ?::text[])
+RETURNING id`,
+		pq.StringArray(add), pq.StringArray(remove), userID, customerIDs, pq.StringArray(add), pq.StringArray(remove)).Scan(&changed).Error
+	return changed, err
+}
+
+// tagsValue 标签列的取值；列不允许为 NULL，nil 写成空数组
+func tagsValue(tags []string) pq.StringArray {
+	if tags == nil {
+		return pq.StringArray{}
+	}
+	return pq.StringArray(tags)
+}
+
+// effectiveScope 全局记录和 teamID 团队的记录
+func effectiveScope(db *gorm.DB, teamID *uint64) *gorm.DB {
+	if teamID == nil {
+		return db.Where("team_id IS NULL")
+	}
+	return db.Where("team_id IS NULL OR team_id = ?", *teamID)
+}
+
+// customersInScope 全局标签涉及所有客户，团队标签只涉及团队成员的客户；包括已归档的客户
+func customersInScope(tx *gorm.DB, teamID *uint64) *gorm.DB {
+	db := tx.Unscoped().Model(&models.Customer{})
+	if teamID == nil {
+		return db
+	}
+	return db.Where("user_id IN (SELECT id FROM users WHERE team_id = ?)", *teamID)
+}
+
+// splitTagList 拆分逗号分隔的标签名列表（支持中文逗号），去掉空项
+func splitTagList(s string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
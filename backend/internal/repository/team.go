@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type TeamRepository struct {
+	db *gorm.DB
+}
+
+func NewTeamRepository(db *gorm.DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+// Create creates a new team
+func (r *TeamRepository) Create(team *models.Team) error {
+	return r.db.Create(team).Error
+}
+
+// FindByID finds a team by ID
+func (r *TeamRepository) FindByID(id uint64) (*models.Team, error) {
+	var team models.Team
+	err := r.db.Where("id = ?", id).First(&team).Error
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// List returns all teams ordered by name
+func (r *TeamRepository) List() ([]*models.Team, error) {
+	var teams []*models.Team
+	err := r.db.Order("name ASC").Find(&teams).Error
+	return teams, err
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+)
+
+type TranscriptionJobRepository struct {
+	db *gorm.DB
+}
+
+func NewTranscriptionJobRepository(db *gorm.DB) *TranscriptionJobRepository {
+	return &TranscriptionJobRepository{db: db}
+}
+
+// Create saves a transcription job
+func (r *TranscriptionJobRepository) Create(job *models.TranscriptionJob) error {
+	return r.db.Create(job).Error
+}
+
+// FindByID finds a transcription job by ID
+func (r *TranscriptionJobRepository) FindByID(id uint64) (*models.TranscriptionJob, error) {
+	var job models.TranscriptionJob
+	if err := r.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListByUserID lists a user's transcription jobs, newest first
+func (r *TranscriptionJobRepository) ListByUserID(userID uint64, limit int) ([]*models.TranscriptionJob, error) {
+	var jobs []*models.TranscriptionJob
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Start marks a job as running with its chunk count
+func (r *TranscriptionJobRepository) Start(id uint64, totalChunks int) error {
+	now := time.Now()
+	return r.db.Model(&models.TranscriptionJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.TranscriptionRunning,
+		"total_chunks": totalChunks,
+		"started_at":   now,
+	}).Error
+}
+
+// IncrementDone records one more finished chunk
+func (r *TranscriptionJobRepository) IncrementDone(id uint64) error {
+	return r.db.Model(&models.TranscriptionJob{}).Where("id = ?", id).
+		UpdateColumn("done_chunks", gorm.Expr("done_chunks + 1")).Error
+}
+
+// Update saves all fields of a transcription job
+func (r *TranscriptionJobRepository) Update(job *models.TranscriptionJob) error {
+	return r.db.Save(job).Error
+}
+
+// FailUnfinished marks queued or running jobs as failed; used at startup since jobs run in-process
+func (r *TranscriptionJobRepository) FailUnfinished(reason string) (int64, error) {
+	result := r.db.Model(&models.TranscriptionJob{}).
+		Where("status IN ?", []string{models.TranscriptionQueued, models.TranscriptionRunning}).
+		Updates(map[string]interface{}{
+			"status":      models.TranscriptionFailed,
+			"error":       reason,
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
@@ -101,3 +101,24 @@ func (r *UserRepository) FindOrCreateByAuthCenter(authCenterUserID, unionID, nic
 	return user, nil
 }
 
+// FindTeamID returns the team a user belongs to (nil if none)
+func (r *UserRepository) FindTeamID(userID uint64) (*uint64, error) {
+	var user models.User
+	err := r.db.Select("team_id").Where("id = ?", userID).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return user.TeamID, nil
+}
+
+// UpdateTeam assigns a user to a team (nil removes the user from any team)
+func (r *UserRepository) UpdateTeam(userID uint64, teamID *uint64) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userID).Update("team_id", teamID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
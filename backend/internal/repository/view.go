@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ViewRepository struct {
+	db *gorm.DB
+}
+
+func NewViewRepository(db *gorm.DB) *ViewRepository {
+	return &ViewRepository{db: db}
+}
+
+func (r *ViewRepository) Create(view *models.SavedView) error {
+	return r.db.Create(view).Error
+}
+
+func (r *ViewRepository) Update(view *models.SavedView) error {
+	return r.db.Save(view).Error
+}
+
+func (r *ViewRepository) Delete(id uint64) error {
+	return r.db.Delete(&models.SavedView{}, id).Error
+}
+
+func (r *ViewRepository) FindByID(id uint64) (*models.SavedView, error) {
+	var view models.SavedView
+	if err := r.db.Where("id = ?", id).First(&view).Error; err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// FindVisible 用户自己的视图和同团队共享的视图（entity 为空时不限实体），自己的在前
+func (r *ViewRepository) FindVisible(userID uint64, teamID *uint64, entity string) ([]*models.SavedView, error) {
+	var views []*models.SavedView
+	db := r.db.Where("user_id = ?", userID)
+	if teamID != nil {
+		db = r.db.Where("user_id = ? OR (shared AND team_id = ?)", userID, *teamID)
+	}
+	if entity != "" {
+		db = db.Where("entity = ?", entity)
+	}
+	err := db.Clauses(clause.OrderBy{Expression: clause.Expr{SQL: "user_id = ? DESC, entity, name, id", Vars: []interface{}{userID}}}).
+		Find(&views).Error
+	return views, err
+}
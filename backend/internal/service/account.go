@@ -0,0 +1,535 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrAccountInUse    = errors.New("account still has customers")
+	ErrContactNotFound = errors.New("contact not found")
+	ErrInvalidContact  = errors.New("contact does not belong to the customer's account")
+	ErrInvalidTimeline = errors.New("invalid timeline query")
+)
+
+// accountTimelineTypes 账户时间线的记录类型
+var accountTimelineTypes = []string{"interaction", "deal", "activity"}
+
+// AccountService 账户（公司）和联系人：客户记录保留销售信息，公司信息放在账户上，
+// 个人信息放在联系人上；跟进记录和成交同时关联账户和具体的联系人
+type AccountService struct {
+	accountRepo *repository.AccountRepository
+}
+
+func NewAccountService(accountRepo *repository.AccountRepository) *AccountService {
+	return &AccountService{accountRepo: accountRepo}
+}
+
+// WithTx returns a copy of the service whose reads and writes go through tx
+func (s *AccountService) WithTx(tx *gorm.DB) *AccountService {
+	return &AccountService{accountRepo: s.accountRepo.WithTx(tx)}
+}
+
+// ListAccounts 按名称列出账户
+func (s *AccountService) ListAccounts(userID uint64, query *dto.AccountQuery) ([]*models.Account, int, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 || query.PerPage > 100 {
+		query.PerPage = 10
+	}
+	accounts, total, err := s.accountRepo.List(userID, query)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPages := int(total) / query.PerPage
+	if int(total)%query.PerPage > 0 {
+		totalPages++
+	}
+	return accounts, totalPages, total, nil
+}
+
+// GetAccount 账户详情，包括联系人（主要联系人在前）和客户记录
+func (s *AccountService) GetAccount(id, userID uint64) (*dto.AccountDetail, error) {
+	account, err := s.ownedAccount(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := s.accountRepo.FindContactsByAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+	customers, err := s.accountRepo.FindCustomers(id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &dto.AccountDetail{
+		Account:   account,
+		Contacts:  contacts,
+		Customers: make([]*dto.CustomerSummary, len(customers)),
+	}
+	for i, c := range customers {
+		detail.Customers[i] = &dto.CustomerSummary{ID: c.ID, Name: c.Name, Company: c.Company}
+	}
+	return detail, nil
+}
+
+// CreateAccount 新建账户
+func (s *AccountService) CreateAccount(userID uint64, req *dto.CreateAccountRequest) (*models.Account, error) {
+	account := &models.Account{
+		UserID:            userID,
+		Name:              strings.TrimSpace(req.Name),
+		Industry:          req.Industry,
+		CompanyScale:      req.CompanyScale,
+		RegisteredCapital: req.RegisteredCapital,
+		LegalPerson:       req.LegalPerson,
+		CreditCode:        req.CreditCode,
+		Address:           req.Address,
+		InvoiceTitle:      req.InvoiceTitle,
+		TaxNumber:         req.TaxNumber,
+		BankAccount:       req.BankAccount,
+		Notes:             req.Notes,
+	}
+	if err := s.accountRepo.Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// UpdateAccount 修改账户；客户记录上的公司信息不随之修改
+func (s *AccountService) UpdateAccount(id, userID uint64, req *dto.UpdateAccountRequest) (*models.Account, error) {
+	account, err := s.ownedAccount(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
+		account.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Industry != nil {
+		account.Industry = *req.Industry
+	}
+	if req.CompanyScale != nil {
+		account.CompanyScale = *req.CompanyScale
+	}
+	if req.RegisteredCapital != nil {
+		account.RegisteredCapital = *req.RegisteredCapital
+	}
+	if req.LegalPerson != nil {
+		account.LegalPerson = *req.LegalPerson
+	}
+	if req.CreditCode != nil {
+		account.CreditCode = *req.CreditCode
+	}
+	if req.Address != nil {
+		account.Address = *req.Address
+	}
+	if req.InvoiceTitle != nil {
+		account.InvoiceTitle = *req.InvoiceTitle
+	}
+	if req.TaxNumber != nil {
+		account.TaxNumber = *req.TaxNumber
+	}
+	if req.BankAccount != nil {
+		account.BankAccount = *req.BankAccount
+	}
+	if req.Notes != nil {
+		account.Notes = *req.Notes
+	}
+
+	if err := s.accountRepo.Update(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// DeleteAccount 删除账户和它的联系人；账户下还有客户记录时不能删除，需先把客户移到其他账户或删除
+func (s *AccountService) DeleteAccount(id, userID uint64) error {
+	if _, err := s.ownedAccount(id, userID); err != nil {
+		return err
+	}
+	customers, err := s.accountRepo.FindCustomers(id)
+	if err != nil {
+		return err
+	}
+	if len(customers) > 0 {
+		return fmt.Errorf("%w: %d customers", ErrAccountInUse, len(customers))
+	}
+	return s.accountRepo.Delete(id)
+}
+
+// ListContacts 账户的联系人，主要联系人在前
+func (s *AccountService) ListContacts(accountID, userID uint64) ([]*models.Contact, error) {
+	if _, err := s.ownedAccount(accountID, userID); err != nil {
+		return nil, err
+	}
+	return s.accountRepo.FindContactsByAccountID(accountID)
+}
+
+// CreateContact 在账户下新建联系人；账户的第一个联系人自动成为主要联系人
+func (s *AccountService) CreateContact(accountID, userID uint64, req *dto.CreateContactRequest) (*models.Contact, error) {
+	if _, err := s.ownedAccount(accountID, userID); err != nil {
+		return nil, err
+	}
+	count, err := s.accountRepo.CountContacts(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	contact := &models.Contact{
+		AccountID: accountID,
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Position:  req.Position,
+		Phone:     req.Phone,
+		Email:     req.Email,
+		WechatID:  req.WechatID,
+		Role:      req.Role,
+		Notes:     req.Notes,
+	}
+	if err := s.accountRepo.CreateContact(contact); err != nil {
+		return nil, err
+	}
+	if req.IsPrimary || count == 0 {
+		if err := s.accountRepo.SetPrimaryContact(contact); err != nil {
+			return nil, err
+		}
+		contact.IsPrimary = true
+	}
+	return contact, nil
+}
+
+// UpdateContact 修改联系人；设为主要联系人时取消账户中原来的主要联系人
+func (s *AccountService) UpdateContact(id, userID uint64, req *dto.UpdateContactRequest) (*models.Contact, error) {
+	contact, err := s.ownedContact(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
+		contact.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Position != nil {
+		contact.Position = *req.Position
+	}
+	if req.Phone != nil {
+		contact.Phone = *req.Phone
+	}
+	if req.Email != nil {
+		contact.Email = *req.Email
+	}
+	if req.WechatID != nil {
+		contact.WechatID = *req.WechatID
+	}
+	if req.Role != nil {
+		contact.Role = *req.Role
+	}
+	if req.Notes != nil {
+		contact.Notes = *req.Notes
+	}
+	makePrimary := req.IsPrimary != nil && *req.IsPrimary && !contact.IsPrimary
+	if req.IsPrimary != nil && !*req.IsPrimary {
+		contact.IsPrimary = false
+	}
+
+	if err := s.accountRepo.UpdateContact(contact); err != nil {
+		return nil, err
+	}
+	if makePrimary {
+		if err := s.accountRepo.SetPrimaryContact(contact); err != nil {
+			return nil, err
+		}
+		contact.IsPrimary = true
+	}
+	return contact, nil
+}
+
+// DeleteContact 删除联系人；已关联的跟进记录和成交不再显示该联系人
+func (s *AccountService) DeleteContact(id, userID uint64) error {
+	if _, err := s.ownedContact(id, userID); err != nil {
+		return err
+	}
+	return s.accountRepo.DeleteContact(id)
+}
+
+// Timeline 账户时间线：账户下所有客户记录的跟进记录、成交和客户动态，按时间倒序
+func (s *AccountService) Timeline(accountID, userID uint64, query *dto.AccountTimelineQuery) ([]*dto.AccountTimelineItem, int, int64, error) {
+	if _, err := s.ownedAccount(accountID, userID); err != nil {
+		return nil, 0, 0, err
+	}
+	if query.ContactID > 0 {
+		if _, err := s.contactInAccount(query.ContactID, accountID, userID); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	types := accountTimelineTypes
+	if query.Types != "" {
+		types = nil
+		for _, t := range strings.Split(query.Types, ",") {
+			t = strings.TrimSpace(t)
+			if !containsString(accountTimelineTypes, t) {
+				return nil, 0, 0, fmt.Errorf("%w: unknown type %q", ErrInvalidTimeline, t)
+			}
+			types = append(types, t)
+		}
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 || query.PerPage > 100 {
+		query.PerPage = 20
+	}
+
+	items, total, err := s.accountRepo.Timeline(accountID, types, query.ContactID,
+		(query.Page-1)*query.PerPage, query.PerPage)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := s.fillTimelineContacts(items); err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPages := int(total) / query.PerPage
+	if int(total)%query.PerPage > 0 {
+		totalPages++
+	}
+	return items, totalPages, total, nil
+}
+
+// fillTimelineContacts 为时间线中的跟进记录和成交附上涉及的联系人
+func (s *AccountService) fillTimelineContacts(items []*dto.AccountTimelineItem) error {
+	var interactionIDs, dealIDs []uint64
+	for _, item := range items {
+		switch item.Type {
+		case "interaction":
+			interactionIDs = append(interactionIDs, item.ID)
+		case "deal":
+			dealIDs = append(dealIDs, item.ID)
+		}
+	}
+	interactionContacts, err := s.accountRepo.InteractionContactIDs(interactionIDs)
+	if err != nil {
+		return err
+	}
+	dealContacts, err := s.accountRepo.DealContactIDs(dealIDs)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		switch item.Type {
+		case "interaction":
+			item.ContactIDs = interactionContacts[item.ID]
+		case "deal":
+			item.ContactIDs = dealContacts[item.ID]
+		}
+	}
+	return nil
+}
+
+// AccountForCustomer 新客户所属的账户：指定了账户时校验归属；否则按公司名归入已有账户，
+// 没有则用客户的公司信息新建（没有公司名的客户以姓名为账户名，各自成为一个账户）
+func (s *AccountService) AccountForCustomer(customer *models.Customer, accountID *uint64) (*models.Account, error) {
+	if accountID != nil {
+		return s.ownedAccount(*accountID, customer.UserID)
+	}
+
+	company := strings.TrimSpace(customer.Company)
+	if company != "" {
+		account, err := s.accountRepo.FindByName(customer.UserID, company)
+		if err == nil {
+			return account, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	account := &models.Account{
+		UserID:            customer.UserID,
+		Name:              company,
+		Industry:          customer.Industry,
+		CompanyScale:      customer.CompanyScale,
+		RegisteredCapital: customer.RegisteredCapital,
+		LegalPerson:       customer.LegalPerson,
+		CreditCode:        customer.CreditCode,
+		Address:           customer.Address,
+		InvoiceTitle:      customer.InvoiceTitle,
+		TaxNumber:         customer.TaxNumber,
+		BankAccount:       customer.BankAccount,
+	}
+	if account.Name == "" {
+		account.Name = strings.TrimSpace(customer.Name)
+	}
+	if err := s.accountRepo.Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// AddCustomerContact 把新客户的个人信息保存为所属账户的联系人；账户还没有联系人时成为主要联系人
+func (s *AccountService) AddCustomerContact(customer *models.Customer) error {
+	if customer.AccountID == nil || strings.TrimSpace(customer.Name) == "" {
+		return nil
+	}
+	count, err := s.accountRepo.CountContacts(*customer.AccountID)
+	if err != nil {
+		return err
+	}
+	customerID := customer.ID
+	contact := &models.Contact{
+		AccountID:  *customer.AccountID,
+		UserID:     customer.UserID,
+		CustomerID: &customerID,
+		Name:       strings.TrimSpace(customer.Name),
+		Position:   customer.Position,
+		Phone:      customer.Phone,
+		Email:      customer.Email,
+		WechatID:   customer.WechatID,
+		IsPrimary:  count == 0,
+	}
+	return s.accountRepo.CreateContact(contact)
+}
+
+// SyncCustomerContact 客户的个人信息修改后同步到由它拆分出的联系人
+func (s *AccountService) SyncCustomerContact(customer *models.Customer) error {
+	contacts, err := s.accountRepo.FindContactsByCustomerID(customer.ID)
+	if err != nil {
+		return err
+	}
+	for _, contact := range contacts {
+		if strings.TrimSpace(customer.Name) != "" {
+			contact.Name = strings.TrimSpace(customer.Name)
+		}
+		contact.Position = customer.Position
+		contact.Phone = customer.Phone
+		contact.Email = customer.Email
+		contact.WechatID = customer.WechatID
+		if err := s.accountRepo.UpdateContact(contact); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveCustomer 把客户移到另一个账户，客户的跟进记录、成交和由它拆分出的联系人一起移动
+func (s *AccountService) MoveCustomer(customer *models.Customer, accountID uint64) error {
+	if customer.AccountID != nil && *customer.AccountID == accountID {
+		return nil
+	}
+	if _, err := s.ownedAccount(accountID, customer.UserID); err != nil {
+		return err
+	}
+	if err := s.accountRepo.MoveCustomer(customer.ID, accountID); err != nil {
+		return err
+	}
+	customer.AccountID = &accountID
+	return nil
+}
+
+// ContactsForRecord 跟进记录或成交关联的联系人：未指定（nil）时为由该客户记录拆分出的联系人，
+// 指定时须都属于客户所在的账户
+func (s *AccountService) ContactsForRecord(customer *models.Customer, contactIDs []uint64) ([]uint64, error) {
+	if contactIDs == nil {
+		contacts, err := s.accountRepo.FindContactsByCustomerID(customer.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]uint64, len(contacts))
+		for i, c := range contacts {
+			ids[i] = c.ID
+		}
+		return ids, nil
+	}
+
+	ids := make([]uint64, 0, len(contactIDs))
+	seen := make(map[uint64]bool, len(contactIDs))
+	for _, id := range contactIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	if customer.AccountID == nil {
+		return nil, ErrInvalidContact
+	}
+	found, err := s.accountRepo.FindContactIDsInAccount(*customer.AccountID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) != len(ids) {
+		return nil, ErrInvalidContact
+	}
+	return ids, nil
+}
+
+// LinkInteractionContacts 替换跟进记录关联的联系人
+func (s *AccountService) LinkInteractionContacts(interactionID uint64, contactIDs []uint64) error {
+	return s.accountRepo.ReplaceInteractionContacts(interactionID, contactIDs)
+}
+
+// LinkDealContacts 替换成交关联的联系人
+func (s *AccountService) LinkDealContacts(dealID uint64, contactIDs []uint64) error {
+	return s.accountRepo.ReplaceDealContacts(dealID, contactIDs)
+}
+
+// InteractionContactIDs 各跟进记录关联的联系人
+func (s *AccountService) InteractionContactIDs(interactionIDs []uint64) (map[uint64][]uint64, error) {
+	return s.accountRepo.InteractionContactIDs(interactionIDs)
+}
+
+// DealContactIDs 各成交关联的联系人
+func (s *AccountService) DealContactIDs(dealIDs []uint64) (map[uint64][]uint64, error) {
+	return s.accountRepo.DealContactIDs(dealIDs)
+}
+
+func (s *AccountService) ownedAccount(id, userID uint64) (*models.Account, error) {
+	account, err := s.accountRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if account.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return account, nil
+}
+
+func (s *AccountService) ownedContact(id, userID uint64) (*models.Contact, error) {
+	contact, err := s.accountRepo.FindContactByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+	if contact.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return contact, nil
+}
+
+func (s *AccountService) contactInAccount(id, accountID, userID uint64) (*models.Contact, error) {
+	contact, err := s.ownedContact(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if contact.AccountID != accountID {
+		return nil, ErrContactNotFound
+	}
+	return contact, nil
+}
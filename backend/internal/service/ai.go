@@ -1,310 +1,546 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	"strings"
+	"errors"
 	"log"
+	"strings"
 
 	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
 	"github.com/xia/nextcrm/internal/repository"
-	"github.com/xia/nextcrm/pkg/deepseek"
-	"github.com/xia/nextcrm/pkg/doubao"
+	"github.com/xia/nextcrm/pkg/llm"
+	"github.com/xia/nextcrm/pkg/redact"
+	"github.com/xia/nextcrm/pkg/volcengine"
+	"gorm.io/gorm"
 )
 
 type AIService struct {
-	client       *deepseek.Client
-	customerRepo *repository.CustomerRepository
-	doubaoClient *doubao.Client    // 豆包多模态客户端
+	llm             *llm.Router // 按能力配置的模型厂商降级链
+	usage           *AIUsageService
+	privacy         *PrivacyService
+	cache           *AICacheService
+	prompts         *PromptService
+	queries         *QueryService
+	customerRepo    *repository.CustomerRepository
+	interactionRepo *repository.InteractionRepository
+	dealRepo        *repository.DealRepository
+	activityRepo    *repository.ActivityRepository
+	analysisRepo    *repository.CustomerAnalysisRepository
+	historyTokens   int // 客户分析附带历史记录的 token 预算
+	cardOCR         *volcengine.OCRClient // 名片识别的备用 OCR，可为空
+	customFields    *CustomFieldService   // 客户分析时附带自定义字段，可为空
 }
 
 func NewAIService(
-	client *deepseek.Client,
+	llmRouter *llm.Router,
+	usage *AIUsageService,
+	privacy *PrivacyService,
+	cache *AICacheService,
+	prompts *PromptService,
+	queries *QueryService,
 	customerRepo *repository.CustomerRepository,
-	doubaoClient *doubao.Client,
+	interactionRepo *repository.InteractionRepository,
+	dealRepo *repository.DealRepository,
+	activityRepo *repository.ActivityRepository,
+	analysisRepo *repository.CustomerAnalysisRepository,
+	historyTokens int,
 ) *AIService {
 	return &AIService{
-		client:       client,
-		customerRepo: customerRepo,
-		doubaoClient: doubaoClient,
+		llm:             llmRouter,
+		usage:           usage,
+		privacy:         privacy,
+		cache:           cache,
+		prompts:         prompts,
+		queries:         queries,
+		customerRepo:    customerRepo,
+		interactionRepo: interactionRepo,
+		dealRepo:        dealRepo,
+		activityRepo:    activityRepo,
+		analysisRepo:    analysisRepo,
+		historyTokens:   historyTokens,
 	}
 }
 
-// chatWithFallback 优先使用豆包，失败时降级到 DeepSeek
-func (s *AIService) chatWithFallback(messages []deepseek.ChatMessage) (*deepseek.ChatResponse, error) {
-	// 转换为豆包格式
-	doubaoMessages := make([]doubao.ChatMessage, len(messages))
-	for i, msg := range messages {
-		doubaoMessages[i] = doubao.ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
+// CheckQuota 检查 ctx 中用户的 AI 额度（流式接口在写响应头之前调用）
+func (s *AIService) CheckQuota(ctx context.Context) error {
+	return s.usage.CheckQuota(ctx)
+}
+
+// CheckCustomerAccess 检查客户是否属于 userID（流式接口在写响应头之前调用）
+func (s *AIService) CheckCustomerAccess(customerID, userID uint64) error {
+	_, err := s.ownedCustomer(customerID, userID)
+	return err
+}
+
+func (s *AIService) ownedCustomer(customerID, userID uint64) (*models.Customer, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
 		}
+		return nil, err
 	}
+	if customer.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return customer, nil
+}
 
-	// 优先尝试豆包
-	if s.doubaoClient != nil {
-		resp, err := s.doubaoClient.Chat(doubaoMessages)
-		if err == nil && len(resp.Choices) > 0 {
-			// 豆包成功，转换为 DeepSeek 格式返回
-			return &deepseek.ChatResponse{
-				Choices: []deepseek.ChatChoice{
-					{
-						Message: deepseek.ChatMessage{
-							Role:    resp.Choices[0].Message.Role,
-							Content: resp.Choices[0].Message.Content,
-						},
-						FinishReason: resp.Choices[0].FinishReason,
-					},
-				},
-			}, nil
-		}
-		// 豆包失败，记录日志并降级
-		log.Printf("豆包调用失败，降级到 DeepSeek: %v", err)
+// begin 标记本次调用的功能（用于计量）、检查额度并开启隐私会话
+func (s *AIService) begin(ctx context.Context, feature string) (context.Context, error) {
+	if err := s.usage.CheckQuota(ctx); err != nil {
+		return ctx, err
 	}
+	ctx = withCacheScope(withPromptTrace(withAIFeature(ctx, feature)))
+	if s.privacy != nil {
+		ctx = s.privacy.withSession(ctx)
+	}
+	return ctx, nil
+}
 
-	// 降级到 DeepSeek
-	if s.client != nil {
-		return s.client.Chat(messages)
+// promptMessages 渲染 system + user 模板；模板变量先按隐私策略清空禁止外发的客户字段，
+// 涉及的客户记入缓存标签
+func (s *AIService) promptMessages(ctx context.Context, systemKey, userKey string, data interface{}) ([]llm.Message, error) {
+	system, err := s.prompts.Render(ctx, systemKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	addCacheTags(ctx, promptCacheTags(data)...)
+	if sess := privacyFrom(ctx); sess != nil {
+		data = sess.sanitize(data)
+	}
+	user, err := s.prompts.Render(ctx, userKey, data)
+	if err != nil {
+		return nil, err
 	}
+	return []llm.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: user},
+	}, nil
+}
 
-	return nil, fmt.Errorf("both Doubao and DeepSeek clients are unavailable")
+// chat 按配置的优先级依次调用对话厂商，失败自动降级
+func (s *AIService) chat(ctx context.Context, messages []llm.Message) (*llm.ChatResponse, error) {
+	return s.llmChat(ctx, &llm.ChatRequest{Messages: messages})
 }
 
-// GenerateScript generates a sales script
-func (s *AIService) GenerateScript(req *dto.GenerateScriptRequest) (*dto.GenerateScriptResponse, error) {
-	systemPrompt := `You are an expert sales assistant. Generate professional sales scripts based on the provided context.
-The script should be:
-- Professional and friendly
-- Tailored to the customer's industry and pain points
-- Persuasive but not pushy
-- Structured with clear sections (opening, value proposition, handling objections, closing)`
+// chatStream 流式调用对话厂商，``` 开始的 JSON 块不下发给调用方
+func (s *AIService) chatStream(ctx context.Context, messages []llm.Message, onDelta func(string) error) (*llm.ChatResponse, error) {
+	filter := &fenceFilter{emit: onDelta}
+	resp, err := s.llmChatStream(ctx, &llm.ChatRequest{Messages: messages}, filter.Write)
+	if err != nil {
+		return nil, err
+	}
+	if err := filter.Flush(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// fenceFilter 转发流式片段，遇到代码块标记 ``` 后停止转发。
+// 标记可能被拆在两个片段里，末尾疑似标记的部分先缓存。
+type fenceFilter struct {
+	emit    func(string) error
+	pending string
+	stopped bool
+}
+
+func (f *fenceFilter) Write(delta string) error {
+	if f.stopped {
+		return nil
+	}
+	buf := f.pending + delta
+	f.pending = ""
+
+	if idx := strings.Index(buf, "```"); idx != -1 {
+		f.stopped = true
+		return f.send(buf[:idx])
+	}
+	// 末尾的 ` 或 `` 可能是标记的开头
+	keep := len(buf) - len(strings.TrimRight(buf, "`"))
+	if keep > 2 {
+		keep = 2
+	}
+	f.pending = buf[len(buf)-keep:]
+	return f.send(buf[:len(buf)-keep])
+}
+
+// Flush 流结束时下发缓存的片段
+func (f *fenceFilter) Flush() error {
+	if f.stopped {
+		return nil
+	}
+	buf := f.pending
+	f.pending = ""
+	return f.send(buf)
+}
 
-	userPrompt := fmt.Sprintf(`Generate a sales script with the following details:
-- Customer Name: %s
-- Industry: %s
-- Context: %s
-- Pain Points: %s
-- Scenario: %s
+func (f *fenceFilter) send(text string) error {
+	if text == "" {
+		return nil
+	}
+	return f.emit(text)
+}
 
-Please provide:
-1. A complete sales script
-2. Key talking points (3-5 bullet points)
-3. Tips for success (3-5 bullet points)
+// splitJSONBlock 把流式回复拆成正文和末尾 ```json 块中的 JSON
+func splitJSONBlock(content string) (text, jsonStr string) {
+	start := strings.Index(content, "```")
+	if start == -1 {
+		return strings.TrimSpace(content), ""
+	}
+	text = strings.TrimSpace(content[:start])
 
-Respond in JSON format:
-{
-  "script": "the complete script",
-  "key_points": ["point 1", "point 2", ...],
-  "tips": ["tip 1", "tip 2", ...]
-}`,
-		req.CustomerName, req.Industry, req.Context, req.PainPoints, req.Scenario)
+	rest := strings.TrimPrefix(content[start+3:], "json")
+	if end := strings.Index(rest, "```"); end != -1 {
+		rest = rest[:end]
+	}
+	return text, strings.TrimSpace(rest)
+}
 
-	messages := []deepseek.ChatMessage{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userPrompt},
+func scriptVars(req *dto.GenerateScriptRequest) scriptPromptVars {
+	return scriptPromptVars{
+		CustomerName: req.CustomerName,
+		Industry:     req.Industry,
+		Context:      req.Context,
+		PainPoints:   req.PainPoints,
+		Scenario:     req.Scenario,
 	}
+}
 
-	resp, err := s.chatWithFallback(messages)
+// GenerateScript generates a sales script
+func (s *AIService) GenerateScript(ctx context.Context, req *dto.GenerateScriptRequest) (*dto.GenerateScriptResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureScript)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
+	messages, err := s.promptMessages(ctx, PromptScriptSystem, PromptScriptUser, scriptVars(req))
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse the JSON response
 	var result dto.GenerateScriptResponse
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		// If JSON parsing fails, create a basic response
-		return &dto.GenerateScriptResponse{
-			Script: resp.Choices[0].Message.Content,
-		}, nil
+	if err := s.chatStructured(ctx, messages, dto.GenerateScriptSchema, &result); err != nil {
+		return nil, err
 	}
-
 	return &result, nil
 }
 
-// AnalyzeCustomer analyzes a customer
-func (s *AIService) AnalyzeCustomer(customerID uint64, analysisType string) (*dto.AnalyzeCustomerResponse, error) {
-	// Get customer data
-	customer, err := s.customerRepo.FindByID(customerID)
+// GenerateScriptStream 流式生成话术：先逐段下发话术正文，结束后解析要点和建议
+func (s *AIService) GenerateScriptStream(ctx context.Context, req *dto.GenerateScriptRequest, onDelta func(string) error) (*dto.GenerateScriptResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureScript)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.promptMessages(ctx, PromptScriptSystem, PromptScriptUserStream, scriptVars(req))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.chatStream(ctx, messages, onDelta)
 	if err != nil {
 		return nil, err
 	}
 
-	systemPrompt := `You are an expert sales analyst. Analyze customer data and provide actionable insights.
-Focus on: purchase intent, risk factors, opportunities, and specific recommendations.`
+	// 正文已下发，末尾 JSON 块不合格时单独修复
+	text, _ := splitJSONBlock(resp.Content)
+	var result dto.GenerateScriptResponse
+	if err := s.ensureStructured(ctx, messages, resp.Content, dto.GenerateScriptStreamSchema, &result); err != nil {
+		return nil, err
+	}
+	result.Script = text
+	return &result, nil
+}
+
+// AnalyzeCustomer analyzes a customer owned by userID
+func (s *AIService) AnalyzeCustomer(ctx context.Context, customerID, userID uint64, analysisType string) (*dto.AnalyzeCustomerResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureAnalyze)
+	if err != nil {
+		return nil, err
+	}
 
-	userPrompt := fmt.Sprintf(`Analyze the following customer:
-- Name: %s
-- Company: %s
-- Position: %s
-- Industry: %s
-- Budget: %s
-- Intent Level: %s
-- Stage: %s
-- Source: %s
-- Contract Value: %s
-- Contract Status: %s
-- Probability: %d%%
-- Notes: %s
+	customer, vars, err := s.analyzeVars(customerID, userID, analysisType)
+	if err != nil {
+		return nil, err
+	}
 
-Analysis Type: %s
+	messages, err := s.promptMessages(ctx, PromptAnalyzeSystem, PromptAnalyzeUser, vars)
+	if err != nil {
+		return nil, err
+	}
 
-Provide:
-1. A brief summary (2-3 sentences)
-2. Intent score (0-100)
-3. Risk level (low, medium, high)
-4. Key opportunities (3-5 bullet points)
-5. Specific recommendations (3-5 bullet points)
-6. Suggested next actions (3-5 bullet points)
+	var result dto.AnalyzeCustomerResponse
+	if err := s.chatStructured(ctx, messages, dto.AnalyzeCustomerSchema, &result); err != nil {
+		return nil, err
+	}
+	result.CustomerID = customerID
+	result.AnalysisType = analysisType
+	s.saveAnalysis(ctx, customer, &result)
+	return &result, nil
+}
 
-Respond in JSON format:
-{
-  "summary": "...",
-  "intent_score": 75,
-  "risk_level": "medium",
-  "opportunities": ["opportunity 1", ...],
-  "recommendations": ["recommendation 1", ...],
-  "next_actions": ["action 1", ...]
-}`,
-		customer.Name, customer.Company, customer.Position, customer.Industry,
-		customer.Budget, customer.IntentLevel, customer.Stage, customer.Source,
-		customer.ContractValue, customer.ContractStatus, customer.Probability,
-		customer.Notes, analysisType)
+// AnalyzeCustomerStream 流式分析客户：先逐段下发分析摘要，结束后解析评分、风险和建议
+func (s *AIService) AnalyzeCustomerStream(ctx context.Context, customerID, userID uint64, analysisType string, onDelta func(string) error) (*dto.AnalyzeCustomerResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureAnalyze)
+	if err != nil {
+		return nil, err
+	}
 
-	messages := []deepseek.ChatMessage{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userPrompt},
+	customer, vars, err := s.analyzeVars(customerID, userID, analysisType)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := s.chatWithFallback(messages)
+	messages, err := s.promptMessages(ctx, PromptAnalyzeSystem, PromptAnalyzeStream, vars)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
+	resp, err := s.chatStream(ctx, messages, onDelta)
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse the JSON response
+	// 摘要已下发，末尾 JSON 块不合格时单独修复
+	text, _ := splitJSONBlock(resp.Content)
 	var result dto.AnalyzeCustomerResponse
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		// If JSON parsing fails, return a basic response
-		return &dto.AnalyzeCustomerResponse{
-			CustomerID:   customerID,
-			AnalysisType: analysisType,
-			Summary:      resp.Choices[0].Message.Content,
-		}, nil
+	if err := s.ensureStructured(ctx, messages, resp.Content, dto.AnalyzeCustomerStreamSchema, &result); err != nil {
+		return nil, err
 	}
-
 	result.CustomerID = customerID
 	result.AnalysisType = analysisType
+	result.Summary = text
+	s.saveAnalysis(ctx, customer, &result)
 	return &result, nil
 }
 
+// analyzeVars 加载 userID 名下的客户资料和近期历史，构建分析模板变量
+func (s *AIService) analyzeVars(customerID, userID uint64, analysisType string) (*models.Customer, analyzePromptVars, error) {
+	customer, err := s.ownedCustomer(customerID, userID)
+	if err != nil {
+		return nil, analyzePromptVars{}, err
+	}
+	history, err := s.loadCustomerHistory(customerID, s.historyTokens)
+	if err != nil {
+		return nil, analyzePromptVars{}, err
+	}
+	vars := analyzePromptVars{
+		Customer:     customer,
+		AnalysisType: analysisType,
+		History:      history,
+	}
+	if s.customFields != nil {
+		vars.CustomFields = s.customFields.Describe(customer.UserID, models.CustomFieldEntityCustomer, customer.CustomFields)
+	}
+	return customer, vars, nil
+}
+
+// saveAnalysis 保存分析快照（连同当时的阶段和成交概率）；保存失败只记日志，不影响本次结果。
+// 结果来自缓存时不重复保存，沿用生成该结果时保存的快照
+func (s *AIService) saveAnalysis(ctx context.Context, customer *models.Customer, result *dto.AnalyzeCustomerResponse) {
+	if servedFromCache(ctx) {
+		if previous, err := s.analysisRepo.FindLatestMatching(customer.ID, result.AnalysisType, result.Summary); err == nil {
+			result.AnalysisID = previous.ID
+		}
+		return
+	}
+	analysis := &models.CustomerAnalysis{
+		CustomerID:      customer.ID,
+		UserID:          aiUserFrom(ctx),
+		AnalysisType:    result.AnalysisType,
+		Summary:         result.Summary,
+		IntentScore:     result.IntentScore,
+		RiskLevel:       result.RiskLevel,
+		Opportunities:   result.Opportunities,
+		Recommendations: result.Recommendations,
+		NextActions:     result.NextActions,
+		Stage:           customer.Stage,
+		Probability:     customer.Probability,
+		PromptVersions:  tracedPrompts(ctx),
+	}
+	if err := s.analysisRepo.Create(analysis); err != nil {
+		log.Printf("Failed to save analysis for customer %d: %v", customer.ID, err)
+		return
+	}
+	result.AnalysisID = analysis.ID
+}
+
+// ListCustomerAnalyses 客户的历次分析快照，附当前阶段等实际状态用于对比
+func (s *AIService) ListCustomerAnalyses(customerID, userID uint64, limit int) (*dto.CustomerAnalysisHistoryResponse, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	if customer.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	analyses, err := s.analysisRepo.ListByCustomerID(customerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CustomerAnalysisHistoryResponse{
+		CustomerID:     customer.ID,
+		Stage:          customer.Stage,
+		ContractStatus: customer.ContractStatus,
+		Probability:    customer.Probability,
+		Analyses:       analyses,
+	}, nil
+}
+
 // GenerateEmbedding generates an embedding for the given text
-func (s *AIService) GenerateEmbedding(text string) (*dto.GenerateEmbeddingResponse, error) {
-	resp, err := s.client.CreateEmbedding(text)
+func (s *AIService) GenerateEmbedding(ctx context.Context, text string) (*dto.GenerateEmbeddingResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureEmbedding)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp.Data) == 0 {
-		return nil, fmt.Errorf("no embedding returned")
+	resp, err := s.llmEmbed(ctx, text)
+	if err != nil {
+		return nil, err
 	}
 
 	return &dto.GenerateEmbeddingResponse{
-		Embedding: resp.Data[0].Embedding,
-		Dimension: len(resp.Data[0].Embedding),
+		Embedding: resp.Embedding,
+		Dimension: len(resp.Embedding),
 	}, nil
 }
 
 // SpeechToText 语音识别
-func (s *AIService) SpeechToText(audioData []byte, format, language string) (*dto.SpeechToTextResponse, error) {
-	text, err := s.doubaoClient.SpeechToText(audioData, format)
+func (s *AIService) SpeechToText(ctx context.Context, audioData []byte, format, language string) (*dto.SpeechToTextResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureASR)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.llm.Transcribe(ctx, &llm.SpeechRequest{
+		Audio:    audioData,
+		Format:   format,
+		Language: language,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &dto.SpeechToTextResponse{
-		Text:       strings.TrimSpace(text),
-		Confidence: 0.95, // 豆包不返回置信度，使用默认值
-		Duration:   0,    // 豆包不返回时长，使用默认值
+		Text:       strings.TrimSpace(resp.Text),
+		Confidence: speechConfidence(resp),
+		Duration:   resp.Duration,
+		Segments:   transcriptSegments(resp),
 	}, nil
 }
 
+// transcribeChunk 转写长录音的一个片段，每段单独检查额度并按降级链调用
+func (s *AIService) transcribeChunk(ctx context.Context, audioData []byte, format, language string) (*llm.SpeechResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureASR)
+	if err != nil {
+		return nil, err
+	}
+	return s.llm.Transcribe(ctx, &llm.SpeechRequest{
+		Audio:    audioData,
+		Format:   format,
+		Language: language,
+	})
+}
+
+// speechConfidence 厂商不返回置信度时为 nil
+func speechConfidence(resp *llm.SpeechResponse) *float64 {
+	if resp.Confidence == 0 {
+		return nil
+	}
+	confidence := resp.Confidence
+	return &confidence
+}
+
+func transcriptSegments(resp *llm.SpeechResponse) []models.TranscriptSegment {
+	if len(resp.Segments) == 0 {
+		return nil
+	}
+	segments := make([]models.TranscriptSegment, len(resp.Segments))
+	for i, seg := range resp.Segments {
+		segments[i] = models.TranscriptSegment{Start: seg.Start, End: seg.End, Speaker: seg.Speaker, Text: seg.Text}
+	}
+	return segments
+}
+
 // CustomerIntakeChat 新建客户对话（豆包）：引导用户收集所有信息，最后给出总结等待用户确认
-func (s *AIService) CustomerIntakeChat(req *dto.CustomerIntakeChatRequest) (*dto.CustomerIntakeChatResponse, error) {
-	systemPrompt := `你是「新建客户」助手，帮助用户快速完成客户信息录入。
-
-【必填项】姓名(name)、公司(company)
-【联系方式至少填一个】电话(phone)、邮箱(email)、微信号(wechat_id) - 三选一即可
-【选填项】职位(position)、预算(budget)、意向等级(intent_level: High/Medium/Low)、备注(notes)
-
-【工作流程】
-1. 用简短友好的中文引导用户，优先收集：姓名、公司、联系方式（电话/邮箱/微信号任选其一）
-2. 用户可能一次性说多条信息（如"张三，ABC科技公司，微信abc123"），请准确提取到对应字段
-3. 尽量在一次对话中收集所有信息（包括选填项），可以主动询问选填项
-4. **支持修改和补充**：用户可以说"把姓名改成李四"、"补充一下邮箱是xxx@xxx.com"、"电话错了，应该是13900139000"，请正确更新对应字段
-5. 当必填项（姓名、公司）和至少一种联系方式都收集完成后，生成一份信息总结，格式如下：
-
-━━━━━━━━━━━━━━━━━━
-📋 客户信息确认
-━━━━━━━━━━━━━━━━━━
-姓名：张三
-公司：ABC科技公司
-职位：CTO
-电话：13800138000
-邮箱：zhangsan@abc.com
-微信号：abc123
-预算：¥50,000
-意向等级：High
-备注：有意向采购CRM系统
-━━━━━━━━━━━━━━━━━━
-
-请确认以上信息是否正确？回复"确认"即可创建客户。
-
-5. 在总结之后，附加一个 JSON 块（用于系统处理）：
-JSON格式示例：{"status":"ready_for_confirmation","name":"张三","company":"ABC科技公司","position":"CTO","phone":"13800138000","email":"zhangsan@abc.com","wechat_id":"abc123","budget":"¥50,000","intent_level":"High","notes":"有意向采购CRM系统"}
-
-【JSON 格式说明】
-- status: "collecting"（收集中）或 "ready_for_confirmation"（等待确认）
-- 当姓名、公司和至少一种联系方式（phone/email/wechat_id）都收集完成时，status 设为 "ready_for_confirmation"
-- 只填已确认的字段，未确认的留空字符串 ""`
+func (s *AIService) CustomerIntakeChat(ctx context.Context, req *dto.CustomerIntakeChatRequest) (*dto.CustomerIntakeChatResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureIntake)
+	if err != nil {
+		return nil, err
+	}
 
-	// 构建消息列表
-	messages := make([]doubao.ChatMessage, 0, len(req.Messages)+2)
+	messages, err := s.intakeMessages(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
-	// 当前已收集的字段（供 AI 参考）
+	// 按对话降级链调用（默认豆包优先）
+	resp, err := s.chat(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishIntake(ctx, req, messages, resp.Content)
+}
+
+// CustomerIntakeChatStream 流式新建客户对话：回复文案逐段下发，JSON 块不下发，
+// 解析出的字段和状态在流结束后随返回值一起给出
+func (s *AIService) CustomerIntakeChatStream(ctx context.Context, req *dto.CustomerIntakeChatRequest, onDelta func(string) error) (*dto.CustomerIntakeChatResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureIntake)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.intakeMessages(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.chatStream(ctx, messages, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishIntake(ctx, req, messages, resp.Content)
+}
+
+// intakeMessages 构建新建客户对话的消息列表
+func (s *AIService) intakeMessages(ctx context.Context, req *dto.CustomerIntakeChatRequest) ([]llm.Message, error) {
+	// 当前已收集的字段（供 AI 参考）；姓名无法靠格式识别，登记后脱敏
+	registerPII(ctx, redact.Name, req.CurrentFields["name"])
 	currentJSON, _ := json.Marshal(req.CurrentFields)
-	messages = append(messages, doubao.ChatMessage{
-		Role:    "system",
-		Content: systemPrompt + "\n\n【当前已收集的字段】\n" + string(currentJSON),
-	})
+	systemPrompt, err := s.prompts.Render(ctx, PromptIntakeSystem, intakePromptVars{CurrentFields: string(currentJSON)})
+	if err != nil {
+		return nil, err
+	}
+
+	// 构建消息列表
+	messages := make([]llm.Message, 0, len(req.Messages)+2)
+	messages = append(messages, llm.Message{Role: "system", Content: systemPrompt})
 
 	// 添加对话历史
 	for _, m := range req.Messages {
 		if m.Role == "system" {
 			continue
 		}
-		messages = append(messages, doubao.ChatMessage{Role: m.Role, Content: m.Content})
+		messages = append(messages, llm.Message{Role: m.Role, Content: m.Content})
 	}
+	return messages, nil
+}
 
-	// 调用豆包 API
-	resp, err := s.doubaoClient.Chat(messages)
-	if err != nil {
+// finishIntake 解析 AI 回复，合并字段并由后端修正状态。
+// 回复正文为用户看到的文案，末尾 JSON 块缺失或不合格时单独请求修复
+func (s *AIService) finishIntake(ctx context.Context, req *dto.CustomerIntakeChatRequest, messages []llm.Message, raw string) (*dto.CustomerIntakeChatResponse, error) {
+	replyText, _ := splitJSONBlock(raw)
+
+	var extracted map[string]string
+	if err := s.ensureStructured(ctx, messages, raw, dto.CustomerIntakeFieldsSchema, &extracted); err != nil {
 		return nil, err
 	}
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
-	}
-
-	content := strings.TrimSpace(resp.Choices[0].Message.Content)
-
-	// 解析 AI 响应：提取用户看到的文案和 JSON 数据
-	replyText, extracted, status := parseChatResponse(content)
+	status := extracted["status"]
+	delete(extracted, "status")
 
 	// 合并字段
 	merged := make(map[string]string)
@@ -314,27 +550,13 @@ JSON格式示例：{"status":"ready_for_confirmation","name":"张三","company":
 		}
 	}
 	for k, v := range extracted {
-		if v != "" {
+		if v = strings.TrimSpace(v); v != "" {
 			merged[k] = v
 		}
 	}
 
 	// ===== 后端验证：修正 AI 返回的状态 =====
-	// 必填字段：姓名、公司
-	requiredFields := []string{"name", "company"}
-	requiredFieldsFilled := true
-	for _, field := range requiredFields {
-		if merged[field] == "" {
-			requiredFieldsFilled = false
-			break
-		}
-	}
-
-	// 联系方式至少填一个：phone / email / wechat_id
-	contactMethodFilled := merged["phone"] != "" || merged["email"] != "" || merged["wechat_id"] != ""
-
-	// 判断是否可以进入确认阶段：必填项 + 至少一种联系方式
-	allReady := requiredFieldsFilled && contactMethodFilled
+	allReady := intakeFieldsReady(merged)
 
 	// 如果条件满足但 AI 状态仍是 collecting，修正为 ready_for_confirmation
 	if allReady && status == "collecting" {
@@ -359,42 +581,14 @@ JSON格式示例：{"status":"ready_for_confirmation","name":"张三","company":
 	}, nil
 }
 
-// parseChatResponse 从 AI 回复中解析用户文案、提取的字段和状态
-func parseChatResponse(content string) (reply string, fields map[string]string, status string) {
-	fields = make(map[string]string)
-	status = "collecting" // 默认状态
-	reply = content
-
-	// 查找 ```json ... ``` 块
-	jsonStart := strings.Index(content, "```json")
-	if jsonStart == -1 {
-		jsonStart = strings.Index(content, "```")
-	}
-	if jsonStart != -1 {
-		blockStart := jsonStart
-		if strings.HasPrefix(content[jsonStart:], "```json") {
-			blockStart += 7
-		} else {
-			blockStart += 3
-		}
-		rest := content[blockStart:]
-		jsonEnd := strings.Index(rest, "```")
-		if jsonEnd != -1 {
-			jsonStr := strings.TrimSpace(rest[:jsonEnd])
-			var m map[string]string
-			if err := json.Unmarshal([]byte(jsonStr), &m); err == nil {
-				for k, v := range m {
-					fields[k] = strings.TrimSpace(v)
-				}
-				// 提取状态
-				if s, ok := m["status"]; ok {
-					status = s
-				}
-			}
-			reply = strings.TrimSpace(content[:jsonStart])
+// intakeFieldsReady 是否可以进入确认阶段：必填项（姓名、公司）+ 至少一种联系方式（phone / email / wechat_id）
+func intakeFieldsReady(fields map[string]string) bool {
+	for _, field := range []string{"name", "company"} {
+		if fields[field] == "" {
+			return false
 		}
 	}
-	return reply, fields, status
+	return fields["phone"] != "" || fields["email"] != "" || fields["wechat_id"] != ""
 }
 
 // generateCustomerSummary 生成客户信息总结
@@ -411,12 +605,13 @@ func generateCustomerSummary(fields map[string]string) string {
 		"phone":        "电话",
 		"email":        "邮箱",
 		"wechat_id":    "微信号",
+		"address":      "地址",
 		"budget":       "预算",
 		"intent_level": "意向等级",
 		"notes":        "备注",
 	}
 
-	for _, key := range []string{"name", "company", "position", "phone", "email", "wechat_id", "budget", "intent_level", "notes"} {
+	for _, key := range []string{"name", "company", "position", "phone", "email", "wechat_id", "address", "budget", "intent_level", "notes"} {
 		if val := fields[key]; val != "" {
 			sb.WriteString(fieldLabels[key])
 			sb.WriteString("：")
@@ -431,13 +626,30 @@ func generateCustomerSummary(fields map[string]string) string {
 }
 
 // RecognizeBusinessCard 识别名片
-func (s *AIService) RecognizeBusinessCard(imageData []byte) (*dto.BusinessCardOCRResponse, error) {
-	jsonStr, err := s.doubaoClient.RecognizeBusinessCard(imageData)
+func (s *AIService) RecognizeBusinessCard(ctx context.Context, imageData []byte) (*dto.BusinessCardOCRResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureOCR)
 	if err != nil {
 		return nil, err
 	}
 
-	// 解析 JSON 响应
+	prompt, err := s.prompts.Render(ctx, PromptBusinessCard, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.llmVision(ctx, &llm.VisionRequest{
+		Image:    imageData,
+		MimeType: "image/jpeg",
+		Prompt:   prompt,
+	})
+	if err != nil {
+		// 图片理解模型都不可用时退回火山引擎名片 OCR（已配置时）
+		if s.cardOCR != nil && cardOCRFallbackAllowed(ctx, err) {
+			return s.recognizeCardWithOCR(ctx, imageData, err)
+		}
+		return nil, err
+	}
+	// 输出不合格时修复重试一次（修复请求只带上次输出，不重发图片）
 	var result struct {
 		Name     string `json:"name"`
 		Company  string `json:"company"`
@@ -446,35 +658,19 @@ func (s *AIService) RecognizeBusinessCard(imageData []byte) (*dto.BusinessCardOC
 		Email    string `json:"email"`
 		Address  string `json:"address"`
 	}
-
-	// 尝试从响应中提取 JSON
-	jsonStart := strings.Index(jsonStr, "{")
-	jsonEnd := strings.LastIndex(jsonStr, "}")
-
-	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
-		jsonContent := jsonStr[jsonStart : jsonEnd+1]
-		if err := json.Unmarshal([]byte(jsonContent), &result); err == nil {
-			return &dto.BusinessCardOCRResponse{
-				Name:       result.Name,
-				Company:    result.Company,
-				Position:   result.Position,
-				Phone:      result.Phone,
-				Email:      result.Email,
-				Address:    result.Address,
-				Confidence: 0.92, // 豆包不返回置信度，使用默认值
-			}, nil
-		}
+	messages := []llm.Message{{Role: "user", Content: prompt}}
+	if err := s.ensureStructured(ctx, messages, resp.Content, dto.BusinessCardSchema, &result); err != nil {
+		return nil, err
 	}
 
-	// 如果 JSON 解析失败，返回原始文本
+	// 图片理解模型不返回置信度，Confidence 留空
 	return &dto.BusinessCardOCRResponse{
-		Name:       "",
-		Company:    "",
-		Position:   "",
-		Phone:      "",
-		Email:      "",
-		Address:    "",
-		Confidence: 0,
-		RawText:    jsonStr,
+		Name:     result.Name,
+		Company:  result.Company,
+		Position: result.Position,
+		Phone:    result.Phone,
+		Email:    result.Email,
+		Address:  result.Address,
+		Provider: resp.Provider,
 	}, nil
 }
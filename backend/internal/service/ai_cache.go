@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xia/nextcrm/internal/config"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"github.com/xia/nextcrm/pkg/cache"
+	"github.com/xia/nextcrm/pkg/llm"
+	"gorm.io/gorm"
+)
+
+type aiCacheKey struct{}
+type aiCacheBypassKey struct{}
+
+// cacheScope 收集一次 AI 调用涉及的客户，缓存条目据此打标签，客户数据变化时失效；
+// 同时记录本次调用的结果是否来自缓存
+type cacheScope struct {
+	mu    sync.Mutex
+	tags  []string
+	hit   bool // 有对话结果来自缓存（或与并发的相同请求共享）
+	fresh bool // 有对话结果由厂商新生成
+}
+
+// WithoutAICache 本次调用不读缓存（如用户主动刷新），结果仍会写入缓存
+func WithoutAICache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, aiCacheBypassKey{}, true)
+}
+
+func withCacheScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, aiCacheKey{}, &cacheScope{})
+}
+
+func addCacheTags(ctx context.Context, tags ...string) {
+	if scope, ok := ctx.Value(aiCacheKey{}).(*cacheScope); ok && len(tags) > 0 {
+		scope.mu.Lock()
+		scope.tags = append(scope.tags, tags...)
+		scope.mu.Unlock()
+	}
+}
+
+// markCacheResult 记录一次对话结果是否来自缓存
+func markCacheResult(ctx context.Context, hit bool) {
+	if scope, ok := ctx.Value(aiCacheKey{}).(*cacheScope); ok {
+		scope.mu.Lock()
+		if hit {
+			scope.hit = true
+		} else {
+			scope.fresh = true
+		}
+		scope.mu.Unlock()
+	}
+}
+
+// servedFromCache 本次调用的对话结果是否全部来自缓存
+func servedFromCache(ctx context.Context) bool {
+	scope, ok := ctx.Value(aiCacheKey{}).(*cacheScope)
+	if !ok {
+		return false
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	return scope.hit && !scope.fresh
+}
+
+func cacheTagsFrom(ctx context.Context) []string {
+	scope, ok := ctx.Value(aiCacheKey{}).(*cacheScope)
+	if !ok {
+		return nil
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	return append([]string(nil), scope.tags...)
+}
+
+func customerCacheTag(customerID uint64) string {
+	return fmt.Sprintf("customer:%d", customerID)
+}
+
+// promptCacheTags 模板变量中出现的客户（*models.Customer、[]*models.Customer、*models.Interaction）
+func promptCacheTags(data interface{}) []string {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var tags []string
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		switch val := v.Field(i).Interface().(type) {
+		case *models.Customer:
+			if val != nil {
+				tags = append(tags, customerCacheTag(val.ID))
+			}
+		case []*models.Customer:
+			for _, c := range val {
+				if c != nil {
+					tags = append(tags, customerCacheTag(c.ID))
+				}
+			}
+		case *models.Interaction:
+			if val != nil {
+				tags = append(tags, customerCacheTag(val.CustomerID))
+			}
+		}
+	}
+	return tags
+}
+
+// AICacheService AI 调用结果缓存：对话结果按功能 TTL 缓存在内存，向量结果持久化到数据库；
+// 键包含厂商 / 模型、提示词模板版本和规整后输入的摘要，相同请求并发时只调用一次厂商
+type AICacheService struct {
+	repo       *repository.AICacheRepository
+	router     *llm.Router
+	mem        *cache.Cache
+	flight     cache.Group
+	enabled    bool
+	ttls       map[string]time.Duration
+	embeddings bool
+
+	hits, misses, shared         atomic.Int64
+	embeddingHits, embeddingMiss atomic.Int64
+}
+
+func NewAICacheService(repo *repository.AICacheRepository, router *llm.Router, cfg config.AIConfig) *AICacheService {
+	return &AICacheService{
+		repo:       repo,
+		router:     router,
+		mem:        cache.New(cfg.CacheMaxEntries),
+		enabled:    cfg.CacheEnabled,
+		ttls:       cfg.CacheTTLs,
+		embeddings: cfg.EmbeddingCache,
+	}
+}
+
+// chatKey 计算对话请求的缓存键；返回的 ttl 为 0 表示本次不缓存
+func (s *AICacheService) chatKey(ctx context.Context, req *llm.ChatRequest, stream bool) (string, time.Duration) {
+	if s == nil || !s.enabled {
+		return "", 0
+	}
+	ttl := s.ttls[aiFeatureFrom(ctx)]
+	if ttl <= 0 {
+		return "", 0
+	}
+	messages := make([]llm.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = llm.Message{Role: m.Role, Content: normalizeCacheText(m.Content)}
+	}
+	raw, _ := json.Marshal(struct {
+		Models   []string
+		Prompts  string
+		JSONMode bool
+		Stream   bool
+		Messages []llm.Message
+	}{s.router.Models(llm.CapabilityChat), tracedPrompts(ctx), req.JSONMode, stream, messages})
+	sum := sha256.Sum256(raw)
+	return "chat:" + hex.EncodeToString(sum[:]), ttl
+}
+
+// cachedChat 命中时返回副本；主动刷新的请求不读缓存
+func (s *AICacheService) cachedChat(ctx context.Context, key string) (*llm.ChatResponse, bool) {
+	if bypass, _ := ctx.Value(aiCacheBypassKey{}).(bool); bypass {
+		s.misses.Add(1)
+		return nil, false
+	}
+	v, ok := s.mem.Get(key)
+	if !ok {
+		s.misses.Add(1)
+		return nil, false
+	}
+	s.hits.Add(1)
+	resp := *v.(*llm.ChatResponse)
+	return &resp, true
+}
+
+func (s *AICacheService) storeChat(ctx context.Context, key string, resp *llm.ChatResponse, ttl time.Duration) {
+	stored := *resp
+	s.mem.Set(key, &stored, ttl, cacheTagsFrom(ctx)...)
+}
+
+// normalizeCacheText 统一换行并去掉首尾空白，只用于计算摘要
+func normalizeCacheText(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// embeddingKey 向量缓存按降级链首选的厂商 / 模型查找；ok 为 false 表示不缓存
+func (s *AICacheService) embeddingKey(text string) (provider, model, textHash string, ok bool) {
+	if s == nil || !s.embeddings {
+		return "", "", "", false
+	}
+	chain := s.router.Models(llm.CapabilityEmbedding)
+	if len(chain) == 0 {
+		return "", "", "", false
+	}
+	provider, model, _ = strings.Cut(chain[0], "/")
+	return provider, model, embeddingTextHash(text), true
+}
+
+// embeddingTextHash 连续空白压缩为一个空格后的 SHA-256
+func embeddingTextHash(text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *AICacheService) findEmbedding(provider, model, textHash string) (*llm.EmbeddingResponse, bool) {
+	cached, err := s.repo.FindEmbedding(provider, model, textHash)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to read embedding cache: %v", err)
+		}
+		s.embeddingMiss.Add(1)
+		return nil, false
+	}
+	s.embeddingHits.Add(1)
+	return &llm.EmbeddingResponse{
+		Provider:  cached.Provider,
+		Model:     cached.Model,
+		Embedding: cached.Embedding,
+	}, true
+}
+
+func (s *AICacheService) storeEmbedding(textHash string, resp *llm.EmbeddingResponse) {
+	err := s.repo.SaveEmbedding(&models.AIEmbeddingCache{
+		Provider:  resp.Provider,
+		Model:     resp.Model,
+		TextHash:  textHash,
+		Embedding: resp.Embedding,
+	})
+	if err != nil {
+		log.Printf("Failed to write embedding cache: %v", err)
+	}
+}
+
+// InvalidateCustomer 客户及其跟进、成交变化后，删除涉及该客户的缓存结果
+func (s *AICacheService) InvalidateCustomer(customerID uint64) {
+	if s == nil {
+		return
+	}
+	s.mem.Invalidate(customerCacheTag(customerID))
+}
+
+// ForgetEmbedding 删除某段文本的向量缓存（如知识库条目内容变化或删除）
+func (s *AICacheService) ForgetEmbedding(text string) {
+	if s == nil || !s.embeddings {
+		return
+	}
+	if _, err := s.repo.DeleteEmbeddings(embeddingTextHash(text)); err != nil {
+		log.Printf("Failed to delete embedding cache: %v", err)
+	}
+}
+
+// GetStats 缓存条目数和自启动以来的命中情况
+func (s *AICacheService) GetStats() (*dto.AICacheStatsResponse, error) {
+	stats := &dto.AICacheStatsResponse{
+		Enabled:         s.enabled,
+		Entries:         s.mem.Len(),
+		Hits:            s.hits.Load(),
+		Misses:          s.misses.Load(),
+		Shared:          s.shared.Load(),
+		EmbeddingCache:  s.embeddings,
+		EmbeddingHits:   s.embeddingHits.Load(),
+		EmbeddingMisses: s.embeddingMiss.Load(),
+	}
+	count, err := s.repo.CountEmbeddings()
+	if err != nil {
+		return nil, err
+	}
+	stats.Embeddings = count
+	return stats, nil
+}
+
+// Purge 清空对话缓存；embeddings 为 true 时同时清空向量缓存
+func (s *AICacheService) Purge(embeddings bool) (int64, error) {
+	s.mem.Purge()
+	if !embeddings {
+		return 0, nil
+	}
+	return s.repo.PurgeEmbeddings()
+}
+
+// llmChat 对话调用的出口：按功能读写缓存，相同请求并发时只调用一次厂商
+func (s *AIService) llmChat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	key, ttl := s.cache.chatKey(ctx, req, false)
+	if ttl <= 0 {
+		markCacheResult(ctx, false)
+		return s.redactedChat(ctx, req)
+	}
+	if resp, ok := s.cache.cachedChat(ctx, key); ok {
+		markCacheResult(ctx, true)
+		return resp, nil
+	}
+
+	v, err, shared := s.cache.flight.Do(key, func() (interface{}, error) {
+		resp, err := s.redactedChat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		s.cache.storeChat(ctx, key, resp, ttl)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		s.cache.shared.Add(1)
+	}
+	markCacheResult(ctx, shared)
+	resp := *v.(*llm.ChatResponse)
+	return &resp, nil
+}
+
+// llmChatStream 流式对话的出口：命中缓存时整段下发；流式请求不合并
+func (s *AIService) llmChatStream(ctx context.Context, req *llm.ChatRequest, onDelta func(string) error) (*llm.ChatResponse, error) {
+	key, ttl := s.cache.chatKey(ctx, req, true)
+	if ttl > 0 {
+		if resp, ok := s.cache.cachedChat(ctx, key); ok {
+			if err := onDelta(resp.Content); err != nil {
+				return nil, err
+			}
+			markCacheResult(ctx, true)
+			return resp, nil
+		}
+	}
+
+	resp, err := s.redactedChatStream(ctx, req, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	markCacheResult(ctx, false)
+	if ttl > 0 {
+		s.cache.storeChat(ctx, key, resp, ttl)
+	}
+	return resp, nil
+}
+
+// llmEmbed 向量调用的出口：先查数据库缓存，未命中时调用厂商并写回
+func (s *AIService) llmEmbed(ctx context.Context, text string) (*llm.EmbeddingResponse, error) {
+	provider, model, textHash, ok := s.cache.embeddingKey(text)
+	if !ok {
+		return s.llm.Embed(ctx, text)
+	}
+	if resp, hit := s.cache.findEmbedding(provider, model, textHash); hit {
+		return resp, nil
+	}
+
+	v, err, shared := s.cache.flight.Do("embedding:"+provider+"/"+model+":"+textHash, func() (interface{}, error) {
+		resp, err := s.llm.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		s.cache.storeEmbedding(textHash, resp)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		s.cache.shared.Add(1)
+	}
+	return v.(*llm.EmbeddingResponse), nil
+}
+
+// ForgetEmbedding 删除某段文本的向量缓存
+func (s *AIService) ForgetEmbedding(text string) {
+	s.cache.ForgetEmbedding(text)
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/pkg/doubao"
+	"github.com/xia/nextcrm/pkg/llm"
+	"github.com/xia/nextcrm/pkg/replay"
+)
+
+// 离线评测：testdata/eval/<模板 key>/v<版本>/ 下保存该提示词版本录制的厂商响应（已脱敏）和人工标注的期望字段，
+// 回放录制内容跑完整的解析流程，按版本统计字段提取准确率。
+//
+//	eval.json      min_accuracy：该版本准确率下限，低于时测试失败
+//	prompt.tmpl    该版本的模板内容；不存在时为内置模板（版本 0）
+//	cases/*.json   样例：request 为接口请求，expected 为期望字段，interactions 为录制的交互
+const (
+	evalRoot    = "testdata/eval"
+	evalBaseURL = "https://ark.cn-beijing.volces.com/api/v3"
+)
+
+type evalConfig struct {
+	Description string  `json:"description"`
+	MinAccuracy float64 `json:"min_accuracy"`
+}
+
+type evalCase struct {
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	Request      json.RawMessage       `json:"request"`
+	Stream       bool                  `json:"stream"`
+	ExpectError  bool                  `json:"expect_error"`
+	Expected     map[string]string     `json:"expected"`
+	Interactions []*replay.Interaction `json:"interactions"`
+}
+
+// evalScore 字段级的命中统计
+type evalScore struct {
+	correct, total int
+	fields         map[string][2]int // 字段 -> [命中, 总数]
+}
+
+func (s *evalScore) add(field string, ok bool) {
+	if s.fields == nil {
+		s.fields = make(map[string][2]int)
+	}
+	f := s.fields[field]
+	f[1]++
+	s.total++
+	if ok {
+		f[0]++
+		s.correct++
+	}
+	s.fields[field] = f
+}
+
+func (s *evalScore) accuracy() float64 {
+	if s.total == 0 {
+		return 1
+	}
+	return float64(s.correct) / float64(s.total)
+}
+
+// evalExtractor 对一个样例执行被测功能，返回提取出的字段
+type evalExtractor func(ctx context.Context, s *AIService, c *evalCase) (map[string]string, error)
+
+// newEvalService 用回放传输构建 AIService；提示词全部预先放入缓存，不访问数据库
+func newEvalService(t *testing.T, key string, version int, prompt string, tr *replay.Transport) *AIService {
+	t.Helper()
+	client := doubao.NewClient(evalBaseURL, "eval", "doubao-eval")
+	client.SetTransport(tr)
+	router := llm.NewRouter()
+	provider := llm.NewDoubaoProvider(client)
+	for _, capability := range []llm.Capability{llm.CapabilityChat, llm.CapabilityVision} {
+		if err := router.Register(capability, provider, llm.Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prompts := NewPromptService(nil, nil)
+	for k := range builtinPrompts {
+		p, err := prompts.builtin(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prompts.store(k, p)
+	}
+	if prompt != "" {
+		tpl, err := parsePrompt(key, prompt)
+		if err != nil {
+			t.Fatalf("%s v%d: %v", key, version, err)
+		}
+		prompts.store(key, &resolvedPrompt{tpl: tpl, version: version, source: promptSourceGlobal})
+	}
+
+	return NewAIService(router, nil, nil, nil, prompts, nil, nil, nil, nil, nil, nil, 0)
+}
+
+// runEval 逐个版本回放样例并统计准确率
+func runEval(t *testing.T, key string, extract evalExtractor) {
+	versions, err := filepath.Glob(filepath.Join(evalRoot, key, "v*"))
+	if err != nil || len(versions) == 0 {
+		t.Fatalf("no eval versions for %s", key)
+	}
+	sort.Strings(versions)
+
+	for _, dir := range versions {
+		dir := dir
+		var version int
+		if _, err := fmt.Sscanf(filepath.Base(dir), "v%d", &version); err != nil {
+			t.Fatalf("bad version directory %s", dir)
+		}
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			var cfg evalConfig
+			readEvalJSON(t, filepath.Join(dir, "eval.json"), &cfg)
+			var prompt string
+			if data, err := os.ReadFile(filepath.Join(dir, "prompt.tmpl")); err == nil {
+				prompt = string(data)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				t.Fatal(err)
+			}
+
+			files, _ := filepath.Glob(filepath.Join(dir, "cases", "*.json"))
+			sort.Strings(files)
+			var score evalScore
+			for _, file := range files {
+				var c evalCase
+				readEvalJSON(t, file, &c)
+				t.Run(c.Name, func(t *testing.T) {
+					tr := replay.NewReplayer(&replay.Cassette{Interactions: c.Interactions}, replay.Options{})
+					s := newEvalService(t, key, version, prompt, tr)
+					got, err := extract(context.Background(), s, &c)
+					if c.ExpectError {
+						if !errors.Is(err, ErrInvalidAIOutput) {
+							t.Errorf("expected ErrInvalidAIOutput, got %v", err)
+						}
+					} else if err != nil {
+						t.Fatalf("extract: %v", err)
+					}
+					if n := tr.Remaining(); n > 0 {
+						t.Errorf("%d recorded interactions were not used", n)
+					}
+					for _, field := range sortedKeys(c.Expected) {
+						want := c.Expected[field]
+						ok := strings.TrimSpace(got[field]) == want
+						score.add(field, ok)
+						if !ok {
+							t.Logf("%s: got %q, want %q", field, got[field], want)
+						}
+					}
+				})
+			}
+
+			t.Logf("%s %s (%s): accuracy %.1f%% (%d/%d fields, %d cases)",
+				key, filepath.Base(dir), cfg.Description, score.accuracy()*100, score.correct, score.total, len(files))
+			for _, field := range sortedKeys(score.fields) {
+				f := score.fields[field]
+				t.Logf("  %-12s %d/%d", field, f[0], f[1])
+			}
+			if score.accuracy() < cfg.MinAccuracy {
+				t.Errorf("accuracy %.3f is below min_accuracy %.3f", score.accuracy(), cfg.MinAccuracy)
+			}
+		})
+	}
+}
+
+func TestEvalBusinessCard(t *testing.T) {
+	runEval(t, PromptBusinessCard, func(ctx context.Context, s *AIService, c *evalCase) (map[string]string, error) {
+		resp, err := s.RecognizeBusinessCard(ctx, []byte("card"))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"name":     resp.Name,
+			"company":  resp.Company,
+			"position": resp.Position,
+			"phone":    resp.Phone,
+			"email":    resp.Email,
+			"address":  resp.Address,
+		}, nil
+	})
+}
+
+// TestEvalCustomerIntake 除字段外，status 由后端按必填项修正，属于确定性逻辑，不一致时直接失败
+func TestEvalCustomerIntake(t *testing.T) {
+	runEval(t, PromptIntakeSystem, func(ctx context.Context, s *AIService, c *evalCase) (map[string]string, error) {
+		var req dto.CustomerIntakeChatRequest
+		if err := json.Unmarshal(c.Request, &req); err != nil {
+			return nil, err
+		}
+		var resp *dto.CustomerIntakeChatResponse
+		var err error
+		if c.Stream {
+			var streamed strings.Builder
+			resp, err = s.CustomerIntakeChatStream(ctx, &req, func(delta string) error {
+				streamed.WriteString(delta)
+				return nil
+			})
+			if err == nil && strings.Contains(streamed.String(), "```") {
+				return nil, errors.New("JSON block was streamed to the client")
+			}
+		} else {
+			resp, err = s.CustomerIntakeChat(ctx, &req)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if want, ok := c.Expected["status"]; ok && resp.Status != want {
+			return nil, errors.New("status " + resp.Status + ", want " + want)
+		}
+		if (resp.Status == "ready_for_confirmation") != (resp.Summary != "") {
+			return nil, errors.New("summary must be present exactly when ready_for_confirmation")
+		}
+		got := map[string]string{"status": resp.Status}
+		for k, v := range resp.ExtractedFields {
+			got[k] = v
+		}
+		return got, nil
+	})
+}
+
+func readEvalJSON(t *testing.T, path string, out interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xia/nextcrm/internal/config"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"github.com/xia/nextcrm/pkg/llm"
+	"github.com/xia/nextcrm/pkg/redact"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAIImageBlocked 隐私策略禁止把图片发给外部模型
+	ErrAIImageBlocked = errors.New("sending images to external AI providers is disabled by the privacy policy")
+	// ErrInvalidPrivacyPolicy 隐私策略中有未知的脱敏类型或客户字段
+	ErrInvalidPrivacyPolicy = errors.New("invalid AI privacy policy")
+)
+
+// customerFields 客户字段 JSON 名 → 结构体字段下标，用于按名称清空禁止外发的字段
+var customerFields = func() map[string]int {
+	fields := make(map[string]int)
+	t := reflect.TypeOf(models.Customer{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+// aiPolicy 一次调用生效的隐私策略
+type aiPolicy struct {
+	Redaction       bool
+	RedactTypes     []redact.Type
+	ForbiddenFields []string
+	BlockImages     bool
+}
+
+func (p *aiPolicy) view() dto.AIPrivacyPolicyView {
+	types := make([]string, len(p.RedactTypes))
+	for i, t := range p.RedactTypes {
+		types[i] = string(t)
+	}
+	return dto.AIPrivacyPolicyView{
+		Redaction:       p.Redaction,
+		RedactTypes:     types,
+		ForbiddenFields: p.ForbiddenFields,
+		BlockImages:     p.BlockImages,
+	}
+}
+
+func newAIPolicy(redaction bool, redactTypes, forbiddenFields []string, blockImages bool) (*aiPolicy, error) {
+	types, err := redact.ParseTypes(redactTypes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivacyPolicy, err)
+	}
+	fields := make([]string, 0, len(forbiddenFields))
+	for _, field := range forbiddenFields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if _, ok := customerFields[field]; !ok {
+			return nil, fmt.Errorf("%w: unknown customer field %q", ErrInvalidPrivacyPolicy, field)
+		}
+		fields = append(fields, field)
+	}
+	return &aiPolicy{
+		Redaction:       redaction,
+		RedactTypes:     types,
+		ForbiddenFields: fields,
+		BlockImages:     blockImages,
+	}, nil
+}
+
+type aiPrivacyKey struct{}
+
+// privacySession 一次 AI 调用（含修复重试）的隐私上下文：同一会话内占位符保持一致，回复据此还原
+type privacySession struct {
+	userID   uint64
+	teamID   *uint64
+	policy   *aiPolicy
+	redactor *redact.Redactor
+
+	mu      sync.Mutex
+	dropped map[string]bool // 本次调用实际清空的客户字段
+}
+
+func privacyFrom(ctx context.Context) *privacySession {
+	sess, _ := ctx.Value(aiPrivacyKey{}).(*privacySession)
+	return sess
+}
+
+// registerPII 登记无法靠格式识别的敏感值（如对话中已收集的客户姓名）
+func registerPII(ctx context.Context, t redact.Type, values ...string) {
+	if sess := privacyFrom(ctx); sess != nil {
+		sess.redactor.AddTerms(t, values...)
+	}
+}
+
+// sanitize 复制模板变量：*models.Customer / []*models.Customer 字段换成清空了禁止字段的副本，
+// 并登记其中的姓名、电话等；带 redact:"<type>" 标签的字符串字段按对应类型登记，
+// 带 forbid:"<客户字段>" 标签的字符串字段在该客户字段禁止外发时清空
+func (p *privacySession) sanitize(data interface{}) interface{} {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Struct {
+		return data
+	}
+	out := reflect.New(v.Type()).Elem()
+	out.Set(v)
+	for i := 0; i < out.NumField(); i++ {
+		field := out.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch val := field.Interface().(type) {
+		case *models.Customer:
+			if val != nil {
+				field.Set(reflect.ValueOf(p.customer(val)))
+			}
+		case []*models.Customer:
+			copies := make([]*models.Customer, len(val))
+			for j, c := range val {
+				if c != nil {
+					copies[j] = p.customer(c)
+				}
+			}
+			field.Set(reflect.ValueOf(copies))
+		case string:
+			tag := out.Type().Field(i).Tag
+			if name := tag.Get("forbid"); name != "" && val != "" && containsString(p.policy.ForbiddenFields, name) {
+				field.SetString("")
+				p.mu.Lock()
+				p.dropped[name] = true
+				p.mu.Unlock()
+				continue
+			}
+			if t := tag.Get("redact"); t != "" {
+				p.redactor.AddTerms(redact.Type(t), val)
+			}
+		}
+	}
+	return out.Interface()
+}
+
+// customer 返回清空了禁止字段的副本；被清空的文本值同时从其他内容（如历史记录）中移除
+func (p *privacySession) customer(c *models.Customer) *models.Customer {
+	copied := *c
+	v := reflect.ValueOf(&copied).Elem()
+	for _, name := range p.policy.ForbiddenFields {
+		field := v.Field(customerFields[name])
+		if field.IsZero() {
+			continue
+		}
+		if s, ok := field.Interface().(string); ok {
+			p.redactor.Drop(s)
+		}
+		field.Set(reflect.Zero(field.Type()))
+		p.mu.Lock()
+		p.dropped[name] = true
+		p.mu.Unlock()
+	}
+
+	p.redactor.AddTerms(redact.Name, copied.Name, copied.LegalPerson)
+	p.redactor.AddTerms(redact.Phone, copied.Phone)
+	p.redactor.AddTerms(redact.Email, copied.Email)
+	p.redactor.AddTerms(redact.WeChat, copied.WechatID)
+	p.redactor.AddTerms(redact.CreditCode, copied.CreditCode, copied.TaxNumber)
+	p.redactor.AddTerms(redact.BankAccount, copied.BankAccount)
+	return &copied
+}
+
+func (p *privacySession) redactMessages(messages []llm.Message) []llm.Message {
+	out := make([]llm.Message, len(messages))
+	for i, m := range messages {
+		out[i] = llm.Message{Role: m.Role, Content: p.redactor.Redact(m.Content)}
+	}
+	return out
+}
+
+// restoreStream 还原流式回复：正文按原文还原，``` 之后的 JSON 块按 JSON 转义还原
+func (p *privacySession) restoreStream(content string) string {
+	if idx := strings.Index(content, "```"); idx != -1 {
+		return p.redactor.Restore(content[:idx]) + p.redactor.RestoreJSON(content[idx:])
+	}
+	return p.redactor.Restore(content)
+}
+
+func (p *privacySession) droppedFields() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fields := make([]string, 0, len(p.dropped))
+	for name := range p.dropped {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// PrivacyService 发往外部模型前的隐私处理：按团队策略脱敏、清空禁止外发的字段，并记录审计日志
+type PrivacyService struct {
+	repo     *repository.AIPrivacyRepository
+	userRepo *repository.UserRepository
+	defaults *aiPolicy
+	payload  bool // 审计日志是否保存脱敏后的内容
+}
+
+func NewPrivacyService(
+	repo *repository.AIPrivacyRepository,
+	userRepo *repository.UserRepository,
+	cfg config.AIConfig,
+) (*PrivacyService, error) {
+	defaults, err := newAIPolicy(cfg.Redaction, cfg.RedactTypes, cfg.ForbiddenFields, cfg.BlockImages)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivacyService{
+		repo:     repo,
+		userRepo: userRepo,
+		defaults: defaults,
+		payload:  cfg.AuditPayload,
+	}, nil
+}
+
+// withSession 按 ctx 中用户所在团队的策略开启隐私会话；查询失败时退回默认策略
+func (s *PrivacyService) withSession(ctx context.Context) context.Context {
+	sess := &privacySession{
+		userID:  aiUserFrom(ctx),
+		policy:  s.defaults,
+		dropped: make(map[string]bool),
+	}
+	if sess.userID != 0 {
+		teamID, err := s.userRepo.FindTeamID(sess.userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load team for AI privacy policy (user %d): %v", sess.userID, err)
+		}
+		sess.teamID = teamID
+		if teamID != nil {
+			sess.policy = s.teamPolicy(*teamID)
+		}
+	}
+
+	var types []redact.Type
+	if sess.policy.Redaction {
+		types = sess.policy.RedactTypes
+	}
+	sess.redactor = redact.New(types)
+	return context.WithValue(ctx, aiPrivacyKey{}, sess)
+}
+
+func (s *PrivacyService) teamPolicy(teamID uint64) *aiPolicy {
+	stored, err := s.repo.FindPolicy(teamID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load AI privacy policy for team %d: %v", teamID, err)
+		}
+		return s.defaults
+	}
+	policy, err := newAIPolicy(stored.Redaction, stored.RedactTypes, stored.ForbiddenFields, stored.BlockImages)
+	if err != nil {
+		log.Printf("Ignoring invalid AI privacy policy for team %d: %v", teamID, err)
+		return s.defaults
+	}
+	return policy
+}
+
+// audit 记录一次发往外部模型的请求，写库失败只打日志
+func (s *PrivacyService) audit(ctx context.Context, sess *privacySession, entry *models.AIAuditLog, resp *llm.ChatResponse, err error) {
+	entry.Feature = aiFeatureFrom(ctx)
+	entry.Success = err == nil
+	entry.Redactions = sess.redactor.Counts()
+	entry.DroppedFields = sess.droppedFields()
+	if sess.userID != 0 {
+		userID := sess.userID
+		entry.UserID = &userID
+		entry.TeamID = sess.teamID
+	}
+	if resp != nil {
+		entry.Provider = resp.Provider
+	}
+	if err != nil {
+		entry.ErrorMessage = err.Error()
+	}
+	if !s.payload {
+		entry.Payload = ""
+	}
+	if err := s.repo.CreateAuditLog(entry); err != nil {
+		log.Printf("Failed to record AI audit log (%s): %v", entry.Feature, err)
+	}
+}
+
+// GetPolicies 返回默认策略和各团队的覆盖
+func (s *PrivacyService) GetPolicies() (*dto.AIPrivacyPoliciesResponse, error) {
+	teams, err := s.repo.ListPolicies()
+	if err != nil {
+		return nil, err
+	}
+	return &dto.AIPrivacyPoliciesResponse{Default: s.defaults.view(), Teams: teams}, nil
+}
+
+// SetPolicy 设置团队的隐私策略
+func (s *PrivacyService) SetPolicy(req *dto.SetAIPrivacyPolicyRequest) (*models.AIPrivacyPolicy, error) {
+	policy, err := newAIPolicy(req.Redaction, req.RedactTypes, req.ForbiddenFields, req.BlockImages)
+	if err != nil {
+		return nil, err
+	}
+	stored := &models.AIPrivacyPolicy{
+		TeamID:          req.TeamID,
+		Redaction:       policy.Redaction,
+		RedactTypes:     policy.view().RedactTypes,
+		ForbiddenFields: policy.ForbiddenFields,
+		BlockImages:     policy.BlockImages,
+	}
+	if err := s.repo.UpsertPolicy(stored); err != nil {
+		return nil, err
+	}
+	return s.repo.FindPolicy(req.TeamID)
+}
+
+// DeletePolicy 删除团队的隐私策略，恢复默认配置
+func (s *PrivacyService) DeletePolicy(teamID uint64) error {
+	return s.repo.DeletePolicy(teamID)
+}
+
+// ListAuditLogs 分页查询审计日志
+func (s *PrivacyService) ListAuditLogs(query *dto.AIAuditLogQuery) (*dto.AIAuditLogListResponse, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PerPage <= 0 || query.PerPage > 100 {
+		query.PerPage = 20
+	}
+	logs, total, err := s.repo.ListAuditLogs(query)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.AIAuditLogListResponse{
+		Logs:    logs,
+		Total:   total,
+		Page:    query.Page,
+		PerPage: query.PerPage,
+	}, nil
+}
+
+// redactedChat 对话调用：脱敏 → 调用 → 审计 → 还原占位符
+func (s *AIService) redactedChat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	sess := privacyFrom(ctx)
+	if sess == nil {
+		return s.llm.Chat(ctx, req)
+	}
+	sent := sess.redactMessages(req.Messages)
+	resp, err := s.llm.Chat(ctx, &llm.ChatRequest{Messages: sent, JSONMode: req.JSONMode})
+	s.privacy.audit(ctx, sess, &models.AIAuditLog{
+		Capability: string(llm.CapabilityChat),
+		Payload:    auditPayload(sent),
+	}, resp, err)
+	if err != nil {
+		return nil, err
+	}
+	if req.JSONMode {
+		resp.Content = sess.redactor.RestoreJSON(resp.Content)
+	} else {
+		resp.Content = sess.redactor.Restore(resp.Content)
+	}
+	return resp, nil
+}
+
+// redactedChatStream 同 redactedChat，流式片段在下发前还原
+func (s *AIService) redactedChatStream(ctx context.Context, req *llm.ChatRequest, onDelta func(string) error) (*llm.ChatResponse, error) {
+	sess := privacyFrom(ctx)
+	if sess == nil {
+		return s.llm.ChatStream(ctx, req, onDelta)
+	}
+	sent := sess.redactMessages(req.Messages)
+	restorer := redact.NewStreamRestorer(sess.redactor, onDelta)
+	resp, err := s.llm.ChatStream(ctx, &llm.ChatRequest{Messages: sent, JSONMode: req.JSONMode}, restorer.Write)
+	s.privacy.audit(ctx, sess, &models.AIAuditLog{
+		Capability: string(llm.CapabilityChat),
+		Payload:    auditPayload(sent),
+	}, resp, err)
+	if err != nil {
+		return nil, err
+	}
+	if err := restorer.Flush(); err != nil {
+		return nil, err
+	}
+	resp.Content = sess.restoreStream(resp.Content)
+	return resp, nil
+}
+
+// llmVision 图片理解调用的出口：图片无法脱敏，策略禁止时直接拒绝，否则只审计图片摘要
+func (s *AIService) llmVision(ctx context.Context, req *llm.VisionRequest) (*llm.ChatResponse, error) {
+	sess := privacyFrom(ctx)
+	if sess == nil {
+		return s.llm.Vision(ctx, req)
+	}
+	if sess.policy.BlockImages {
+		return nil, ErrAIImageBlocked
+	}
+	sum := sha256.Sum256(req.Image)
+	sent := *req
+	sent.Prompt = sess.redactor.Redact(req.Prompt)
+	resp, err := s.llm.Vision(ctx, &sent)
+	s.privacy.audit(ctx, sess, &models.AIAuditLog{
+		Capability:  string(llm.CapabilityVision),
+		Payload:     sent.Prompt,
+		ImageSHA256: hex.EncodeToString(sum[:]),
+		ImageBytes:  len(req.Image),
+	}, resp, err)
+	if err != nil {
+		return nil, err
+	}
+	resp.Content = sess.redactor.Restore(resp.Content)
+	return resp, nil
+}
+
+func auditPayload(messages []llm.Message) string {
+	var sb strings.Builder
+	for i, m := range messages {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(m.Role)
+		sb.WriteString(": ")
+		sb.WriteString(m.Content)
+	}
+	return sb.String()
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xia/nextcrm/internal/config"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"github.com/xia/nextcrm/pkg/llm"
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded 用户或团队的 AI 额度已用完
+var ErrQuotaExceeded = errors.New("AI quota exceeded")
+
+type aiUserKey struct{}
+type aiFeatureKey struct{}
+type aiPromptKey struct{}
+
+// promptTrace 收集一次 AI 调用用到的模板版本
+type promptTrace struct {
+	mu   sync.Mutex
+	refs []string
+}
+
+// WithAIUser 标记 AI 调用的发起用户，用于计量和额度检查
+func WithAIUser(ctx context.Context, userID uint64) context.Context {
+	return context.WithValue(ctx, aiUserKey{}, userID)
+}
+
+func withAIFeature(ctx context.Context, feature string) context.Context {
+	return context.WithValue(ctx, aiFeatureKey{}, feature)
+}
+
+func withPromptTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, aiPromptKey{}, &promptTrace{})
+}
+
+// tracePrompt 记录本次调用使用的模板版本
+func tracePrompt(ctx context.Context, ref string) {
+	if t, ok := ctx.Value(aiPromptKey{}).(*promptTrace); ok {
+		t.mu.Lock()
+		t.refs = append(t.refs, ref)
+		t.mu.Unlock()
+	}
+}
+
+func tracedPrompts(ctx context.Context) string {
+	t, ok := ctx.Value(aiPromptKey{}).(*promptTrace)
+	if !ok {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.refs, ",")
+}
+
+func aiUserFrom(ctx context.Context) uint64 {
+	userID, _ := ctx.Value(aiUserKey{}).(uint64)
+	return userID
+}
+
+func aiFeatureFrom(ctx context.Context) string {
+	if feature, ok := ctx.Value(aiFeatureKey{}).(string); ok {
+		return feature
+	}
+	return "other"
+}
+
+// AIUsageService 记录每次 AI 调用，检查额度，生成成本报表
+type AIUsageService struct {
+	usageRepo *repository.AIUsageRepository
+	userRepo  *repository.UserRepository
+	cfg       config.AIConfig
+}
+
+func NewAIUsageService(
+	usageRepo *repository.AIUsageRepository,
+	userRepo *repository.UserRepository,
+	cfg config.AIConfig,
+) *AIUsageService {
+	return &AIUsageService{
+		usageRepo: usageRepo,
+		userRepo:  userRepo,
+		cfg:       cfg,
+	}
+}
+
+// Record 记录一次厂商调用（作为 llm.Router 的 Observer），写库失败只打日志不影响业务
+func (s *AIUsageService) Record(ctx context.Context, rec llm.CallRecord) {
+	entry := &models.AIUsageLog{
+		Feature:          aiFeatureFrom(ctx),
+		Capability:       string(rec.Capability),
+		Provider:         rec.Provider,
+		Model:            rec.Model,
+		PromptTokens:     rec.Usage.PromptTokens,
+		CompletionTokens: rec.Usage.CompletionTokens,
+		TotalTokens:      rec.Usage.TotalTokens,
+		LatencyMs:        rec.Latency.Milliseconds(),
+		Success:          rec.Err == nil,
+		Cost:             s.cost(rec.Provider, rec.Usage),
+		PromptVersions:   tracedPrompts(ctx),
+	}
+	if entry.TotalTokens == 0 {
+		entry.TotalTokens = entry.PromptTokens + entry.CompletionTokens
+	}
+	if rec.Err != nil {
+		entry.ErrorMessage = rec.Err.Error()
+	}
+	if userID := aiUserFrom(ctx); userID != 0 {
+		entry.UserID = &userID
+		if teamID, err := s.userRepo.FindTeamID(userID); err == nil {
+			entry.TeamID = teamID
+		}
+	}
+
+	if err := s.usageRepo.Create(entry); err != nil {
+		log.Printf("Failed to record AI usage (%s/%s): %v", entry.Feature, entry.Provider, err)
+	}
+}
+
+// cost 按配置的厂商单价计算费用，未配置单价的厂商记为 0
+func (s *AIUsageService) cost(provider string, usage llm.Usage) float64 {
+	price, ok := s.cfg.Pricing[provider]
+	if !ok {
+		return 0
+	}
+	return float64(usage.PromptTokens)/1000*price.InputPer1K +
+		float64(usage.CompletionTokens)/1000*price.OutputPer1K
+}
+
+// CheckQuota 调用前检查用户及其团队的日 / 月额度，超出时返回 ErrQuotaExceeded。
+// 额度按已记录的用量判断，正在进行的调用可能让用量略微超出额度。
+func (s *AIUsageService) CheckQuota(ctx context.Context) error {
+	userID := aiUserFrom(ctx)
+	if userID == 0 {
+		return nil // 系统内部调用不限额
+	}
+
+	summary, err := s.GetUsageSummary(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkLimits("user", &summary.User); err != nil {
+		return err
+	}
+	if summary.Team != nil {
+		return checkLimits("team", summary.Team)
+	}
+	return nil
+}
+
+func checkLimits(scope string, u *dto.AIQuotaUsage) error {
+	if u.DailyLimit > 0 && u.DailyUsed >= u.DailyLimit {
+		return fmt.Errorf("%w: daily %s limit of %d tokens reached (used %d)", ErrQuotaExceeded, scope, u.DailyLimit, u.DailyUsed)
+	}
+	if u.MonthlyLimit > 0 && u.MonthlyUsed >= u.MonthlyLimit {
+		return fmt.Errorf("%w: monthly %s limit of %d tokens reached (used %d)", ErrQuotaExceeded, scope, u.MonthlyLimit, u.MonthlyUsed)
+	}
+	return nil
+}
+
+// GetUsageSummary 返回用户及其团队当天、当月的用量和额度
+func (s *AIUsageService) GetUsageSummary(userID uint64) (*dto.AIUsageSummaryResponse, error) {
+	dayStart, monthStart := periodStarts(time.Now())
+
+	user, err := s.quotaUsage(models.AIQuotaScopeUser, userID, dayStart, monthStart,
+		s.cfg.UserDailyTokenLimit, s.cfg.UserMonthlyTokenLimit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &dto.AIUsageSummaryResponse{User: *user}
+
+	teamID, err := s.userRepo.FindTeamID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if teamID != nil {
+		team, err := s.quotaUsage(models.AIQuotaScopeTeam, *teamID, dayStart, monthStart,
+			s.cfg.TeamDailyTokenLimit, s.cfg.TeamMonthlyTokenLimit)
+		if err != nil {
+			return nil, err
+		}
+		resp.TeamID = teamID
+		resp.Team = team
+	}
+	return resp, nil
+}
+
+func (s *AIUsageService) quotaUsage(scope string, id uint64, dayStart, monthStart time.Time, dailyLimit, monthlyLimit int64) (*dto.AIQuotaUsage, error) {
+	// 表中有覆盖值时优先使用
+	quota, err := s.usageRepo.FindQuota(scope, id)
+	if err == nil {
+		dailyLimit, monthlyLimit = quota.DailyTokenLimit, quota.MonthlyTokenLimit
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	sum := s.usageRepo.SumTokensByUser
+	if scope == models.AIQuotaScopeTeam {
+		sum = s.usageRepo.SumTokensByTeam
+	}
+	daily, err := sum(id, dayStart)
+	if err != nil {
+		return nil, err
+	}
+	monthly, err := sum(id, monthStart)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.AIQuotaUsage{
+		DailyUsed:    daily,
+		DailyLimit:   dailyLimit,
+		MonthlyUsed:  monthly,
+		MonthlyLimit: monthlyLimit,
+	}, nil
+}
+
+func periodStarts(now time.Time) (dayStart, monthStart time.Time) {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location()),
+		time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+}
+
+// GetReport 按团队和功能汇总区间内的调用次数、token 和成本，默认本月
+func (s *AIUsageService) GetReport(query *dto.AIUsageReportQuery) (*dto.AIUsageReportResponse, error) {
+	_, from := periodStarts(time.Now())
+	to := time.Now()
+	if query.From != nil {
+		from = *query.From
+	}
+	if query.To != nil {
+		to = query.To.AddDate(0, 0, 1) // 包含结束当天
+	}
+
+	rows, err := s.usageRepo.Report(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.AIUsageReportResponse{From: from, To: to, Rows: rows}
+	for _, row := range rows {
+		resp.TotalCost += row.Cost
+	}
+	return resp, nil
+}
+
+// ListQuotas 返回所有额度覆盖配置
+func (s *AIUsageService) ListQuotas() ([]*models.AIQuota, error) {
+	return s.usageRepo.ListQuotas()
+}
+
+// SetQuota 设置用户或团队的额度
+func (s *AIUsageService) SetQuota(req *dto.SetAIQuotaRequest) (*models.AIQuota, error) {
+	quota := &models.AIQuota{
+		Scope:             req.Scope,
+		ScopeID:           req.ScopeID,
+		DailyTokenLimit:   req.DailyTokenLimit,
+		MonthlyTokenLimit: req.MonthlyTokenLimit,
+	}
+	if err := s.usageRepo.UpsertQuota(quota); err != nil {
+		return nil, err
+	}
+	return s.usageRepo.FindQuota(req.Scope, req.ScopeID)
+}
+
+// DeleteQuota 删除额度覆盖，恢复为默认额度
+func (s *AIUsageService) DeleteQuota(id uint64) error {
+	return s.usageRepo.DeleteQuota(id)
+}
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBulkJobNotFound = errors.New("bulk job not found")
+	ErrInvalidBulk     = errors.New("invalid bulk operation")
+)
+
+const (
+	// bulkInlineLimit 选中的客户不超过这个数时在请求内直接执行，否则在后台执行
+	bulkInlineLimit = 100
+	// bulkProgressEvery 后台执行时每处理这么多个客户保存一次进度
+	bulkProgressEvery = 50
+)
+
+// bulkOperationLabels 批量操作在汇总记录中的名称
+var bulkOperationLabels = map[string]string{
+	models.BulkUpdate:   "更新",
+	models.BulkArchive:  "归档",
+	models.BulkRestore:  "恢复",
+	models.BulkDelete:   "删除",
+	models.BulkReassign: "转移",
+}
+
+// BulkService 客户批量操作：更新字段和标签、归档、恢复、删除、转移负责人。
+// 每个客户都走单条操作的 CustomerService 方法（所有权检查、阶段变更记录、缓存失效都一致），
+// 逐条记录结果，完成后写一条汇总 Activity
+type BulkService struct {
+	jobRepo      *repository.BulkJobRepository
+	filterRepo   *repository.FilterRepository
+	userRepo     *repository.UserRepository
+	activityRepo *repository.ActivityRepository
+	customers    *CustomerService
+	// 标签和分群，可为空（为空时不支持批量改标签和按分群选择）
+	tags     *TagService
+	segments *SegmentService
+}
+
+func NewBulkService(
+	jobRepo *repository.BulkJobRepository,
+	filterRepo *repository.FilterRepository,
+	userRepo *repository.UserRepository,
+	activityRepo *repository.ActivityRepository,
+	customers *CustomerService,
+) *BulkService {
+	return &BulkService{
+		jobRepo:      jobRepo,
+		filterRepo:   filterRepo,
+		userRepo:     userRepo,
+		activityRepo: activityRepo,
+		customers:    customers,
+	}
+}
+
+// SetTags enables adding and removing tags in bulk updates
+func (s *BulkService) SetTags(tags *TagService) {
+	s.tags = tags
+}
+
+// SetSegments allows selecting the customers of a saved segment
+func (s *BulkService) SetSegments(segments *SegmentService) {
+	s.segments = segments
+}
+
+// RecoverUnfinished 进程重启后未完成的任务不再继续（已处理的客户无法区分），标记为失败
+func (s *BulkService) RecoverUnfinished() {
+	n, err := s.jobRepo.FailUnfinished("interrupted by server restart, check the customers and submit again")
+	if err != nil {
+		log.Printf("failed to recover bulk jobs: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("marked %d unfinished bulk jobs as failed", n)
+	}
+}
+
+// bulkPlan 校验后的操作参数
+type bulkPlan struct {
+	update     *dto.UpdateCustomerRequest
+	addTags    []string
+	removeTags []string
+	toUserID   uint64
+}
+
+// Submit 确定目标客户并创建任务。选中的客户不多时直接执行完再返回，否则在后台执行，返回排队中的任务
+func (s *BulkService) Submit(userID uint64, req *dto.BulkCustomerRequest) (*dto.BulkJobResponse, error) {
+	if _, ok := bulkOperationLabels[req.Operation]; !ok {
+		return nil, fmt.Errorf("%w: unknown operation %q (expected update, archive, restore, delete or reassign)", ErrInvalidBulk, req.Operation)
+	}
+	plan, params, err := s.prepare(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.targets(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: no customers selected", ErrInvalidBulk)
+	}
+
+	job := &models.BulkJob{
+		UserID:      userID,
+		Operation:   req.Operation,
+		Status:      models.BulkJobQueued,
+		Params:      params,
+		CustomerIDs: ids,
+		Total:       len(ids),
+		Results:     []models.BulkRowResult{},
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, err
+	}
+
+	if len(ids) <= bulkInlineLimit {
+		s.run(job, plan)
+		return &dto.BulkJobResponse{BulkJob: job, Inline: true}, nil
+	}
+
+	// 返回副本，后台执行会修改原对象
+	jobCopy := *job
+	go s.run(job, plan)
+	return &dto.BulkJobResponse{BulkJob: &jobCopy}, nil
+}
+
+// GetJob 任务进度和逐条结果
+func (s *BulkService) GetJob(id, userID uint64) (*models.BulkJob, error) {
+	job, err := s.jobRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBulkJobNotFound
+		}
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return job, nil
+}
+
+// ListJobs 用户最近的任务（不含逐条结果）
+func (s *BulkService) ListJobs(userID uint64, limit int) ([]*models.BulkJob, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.jobRepo.ListByUserID(userID, limit)
+}
+
+// prepare 校验操作参数，返回执行计划和保存到任务上的参数
+func (s *BulkService) prepare(userID uint64, req *dto.BulkCustomerRequest) (*bulkPlan, json.RawMessage, error) {
+	plan := &bulkPlan{}
+	switch req.Operation {
+	case models.BulkUpdate:
+		u := req.Update
+		if u == nil {
+			return nil, nil, fmt.Errorf("%w: update is required", ErrInvalidBulk)
+		}
+		fields := &dto.UpdateCustomerRequest{
+			Stage:          u.Stage,
+			IntentLevel:    u.IntentLevel,
+			CustomerLevel:  u.CustomerLevel,
+			CustomerStatus: u.CustomerStatus,
+			Source:         u.Source,
+			Industry:       u.Industry,
+		}
+		if u.Stage != nil || u.IntentLevel != nil || u.CustomerLevel != nil ||
+			u.CustomerStatus != nil || u.Source != nil || u.Industry != nil {
+			plan.update = fields
+		}
+		if len(u.AddTags) > 0 || len(u.RemoveTags) > 0 {
+			if s.tags == nil {
+				return nil, nil, fmt.Errorf("%w: tags are not available", ErrInvalidBulk)
+			}
+			var err error
+			if plan.addTags, plan.removeTags, err = s.tags.planChange(userID, u.AddTags, u.RemoveTags); err != nil {
+				return nil, nil, err
+			}
+		}
+		if plan.update == nil && plan.addTags == nil {
+			return nil, nil, fmt.Errorf("%w: nothing to update", ErrInvalidBulk)
+		}
+		params, err := json.Marshal(u)
+		return plan, params, err
+
+	case models.BulkReassign:
+		if err := s.checkNewOwner(userID, req.ToUserID); err != nil {
+			return nil, nil, err
+		}
+		plan.toUserID = req.ToUserID
+		params, err := json.Marshal(&dto.BulkReassignParams{ToUserID: req.ToUserID})
+		return plan, params, err
+	}
+	return plan, nil, nil
+}
+
+// checkNewOwner 转移的目标负责人须是同一团队的其他成员
+func (s *BulkService) checkNewOwner(userID, toUserID uint64) error {
+	if toUserID == 0 {
+		return fmt.Errorf("%w: to_user_id is required", ErrInvalidBulk)
+	}
+	if toUserID == userID {
+		return fmt.Errorf("%w: the customers already belong to you", ErrInvalidBulk)
+	}
+	teamID, err := s.userRepo.FindTeamID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	toTeamID, err := s.userRepo.FindTeamID(toUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: user %d not found", ErrInvalidBulk, toUserID)
+		}
+		return err
+	}
+	if teamID == nil || toTeamID == nil || *teamID != *toTeamID {
+		return fmt.Errorf("%w: customers can only be reassigned within your team", ErrInvalidBulk)
+	}
+	return nil
+}
+
+// targets 目标客户：customer_ids、segment_id、filter 三者之一，最多 maxSegmentTargets 个
+func (s *BulkService) targets(userID uint64, req *dto.BulkCustomerRequest) ([]uint64, error) {
+	selectors := 0
+	for _, set := range []bool{len(req.CustomerIDs) > 0, req.SegmentID > 0, req.Filter != nil} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return nil, fmt.Errorf("%w: exactly one of customer_ids, segment_id and filter is required", ErrInvalidBulk)
+	}
+
+	switch {
+	case len(req.CustomerIDs) > 0:
+		ids := uniqueIDs(req.CustomerIDs)
+		if len(ids) > maxSegmentTargets {
+			return nil, fmt.Errorf("%w: at most %d customers per request", ErrInvalidBulk, maxSegmentTargets)
+		}
+		return ids, nil
+
+	case req.SegmentID > 0:
+		if req.Operation == models.BulkRestore {
+			return nil, fmt.Errorf("%w: segments only contain active customers, select archived customers by customer_ids or filter", ErrInvalidBulk)
+		}
+		if s.segments == nil {
+			return nil, fmt.Errorf("%w: segments are not available", ErrInvalidBulk)
+		}
+		return s.segments.CustomerIDs(req.SegmentID, userID)
+	}
+
+	spec := &dto.QuerySpec{Entity: "customers", Filter: req.Filter}
+	if err := repository.ValidateQuerySpec(spec); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBulk, err)
+	}
+	var ids []uint64
+	var err error
+	if req.Operation == models.BulkRestore {
+		ids, err = s.filterRepo.ArchivedIDs(spec, userID, maxSegmentTargets+1)
+	} else {
+		ids, err = s.filterRepo.IDs(spec, userID, maxSegmentTargets+1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > maxSegmentTargets {
+		return nil, fmt.Errorf("%w: the filter matches more than %d customers, narrow it down", ErrInvalidBulk, maxSegmentTargets)
+	}
+	return ids, nil
+}
+
+func (s *BulkService) run(job *models.BulkJob, plan *bulkPlan) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.finish(job, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	if err := s.jobRepo.Start(job.ID); err != nil {
+		log.Printf("failed to start bulk job %d: %v", job.ID, err)
+	}
+	now := time.Now()
+	job.Status = models.BulkJobRunning
+	job.StartedAt = &now
+
+	for i, id := range job.CustomerIDs {
+		result := models.BulkRowResult{CustomerID: id, OK: true}
+		if err := s.apply(job, plan, id); err != nil {
+			result.OK = false
+			result.Error = bulkRowError(err)
+			job.Failed++
+		} else {
+			job.Succeeded++
+		}
+		job.Done++
+		job.Results = append(job.Results, result)
+
+		if (i+1)%bulkProgressEvery == 0 {
+			if err := s.jobRepo.UpdateProgress(job); err != nil {
+				log.Printf("failed to update bulk job %d progress: %v", job.ID, err)
+			}
+		}
+	}
+	s.finish(job, nil)
+}
+
+// apply 对单个客户执行操作，所有权由 CustomerService 检查
+func (s *BulkService) apply(job *models.BulkJob, plan *bulkPlan, customerID uint64) error {
+	userID := job.UserID
+	switch job.Operation {
+	case models.BulkUpdate:
+		if plan.update != nil {
+			if _, err := s.customers.UpdateCustomer(customerID, userID, plan.update); err != nil {
+				return err
+			}
+		} else if _, err := s.customers.GetCustomerByID(customerID, userID); err != nil {
+			return err
+		}
+		if plan.addTags != nil {
+			_, err := s.tags.applyChange(userID, []uint64{customerID}, plan.addTags, plan.removeTags)
+			return err
+		}
+		return nil
+	case models.BulkArchive:
+		return s.customers.ArchiveCustomer(customerID, userID)
+	case models.BulkRestore:
+		_, err := s.customers.RestoreCustomer(customerID, userID)
+		return err
+	case models.BulkDelete:
+		return s.customers.DeleteCustomer(customerID, userID)
+	case models.BulkReassign:
+		return s.customers.ReassignCustomer(customerID, userID, plan.toUserID)
+	}
+	return fmt.Errorf("%w: unknown operation %q", ErrInvalidBulk, job.Operation)
+}
+
+// finish 保存结果并写一条汇总 Activity；汇总记录失败不影响任务结果
+func (s *BulkService) finish(job *models.BulkJob, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = models.BulkJobFailed
+		job.Error = err.Error()
+		log.Printf("bulk job %d failed: %v", job.ID, err)
+	} else {
+		job.Status = models.BulkJobCompleted
+		job.Error = ""
+	}
+
+	activity := &models.Activity{
+		UserID:      job.UserID,
+		ActionType:  models.ActivityBulkOperation,
+		EntityType:  "bulk_job",
+		EntityID:    &job.ID,
+		Description: fmt.Sprintf("批量%s %d 个客户：成功 %d，失败 %d", bulkOperationLabels[job.Operation], job.Total, job.Succeeded, job.Failed),
+		Metadata: map[string]interface{}{
+			"operation": job.Operation,
+			"total":     job.Total,
+			"succeeded": job.Succeeded,
+			"failed":    job.Failed,
+		},
+	}
+	if err := s.activityRepo.Create(activity); err != nil {
+		log.Printf("failed to record activity for bulk job %d: %v", job.ID, err)
+	} else {
+		activityID := uint64(activity.ID)
+		job.ActivityID = &activityID
+	}
+
+	if err := s.jobRepo.Update(job); err != nil {
+		log.Printf("failed to save bulk job %d: %v", job.ID, err)
+	}
+}
+
+// bulkRowError 单个客户失败的原因
+func bulkRowError(err error) string {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return "customer not found"
+	case errors.Is(err, ErrUnauthorized):
+		return "access denied"
+	}
+	return err.Error()
+}
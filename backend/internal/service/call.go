@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/pkg/llm"
+)
+
+// AnalyzeCall 转写通话录音（厂商支持时区分说话人），再总结要点、异议、承诺和下一步
+func (s *AIService) AnalyzeCall(ctx context.Context, customer *models.Customer, audio []byte, format, language string) (*llm.SpeechResponse, *dto.CallSummary, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureCall)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	speech, err := s.llm.Transcribe(ctx, &llm.SpeechRequest{
+		Audio:    audio,
+		Format:   format,
+		Language: language,
+		Diarize:  true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	transcript := formatTranscript(speech)
+	if transcript == "" {
+		return nil, nil, ErrEmptyTranscript
+	}
+
+	messages, err := s.promptMessages(ctx, PromptCallSystem, PromptCallUser, callPromptVars{
+		Customer:   customer,
+		Transcript: transcript,
+		Today:      time.Now().Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var summary dto.CallSummary
+	check := func() []string {
+		if summary.NextDate == "" {
+			return nil
+		}
+		if _, err := time.Parse("2006-01-02", summary.NextDate); err != nil {
+			return []string{"$.next_date: must be YYYY-MM-DD or empty"}
+		}
+		return nil
+	}
+	if err := s.chatStructuredChecked(ctx, messages, dto.CallSummarySchema, &summary, check); err != nil {
+		return nil, nil, err
+	}
+	return speech, &summary, nil
+}
+
+// formatTranscript 有说话人时按「说话人：内容」逐行输出，否则使用原文
+func formatTranscript(speech *llm.SpeechResponse) string {
+	diarized := false
+	for _, seg := range speech.Segments {
+		if seg.Speaker != "" {
+			diarized = true
+			break
+		}
+	}
+	if !diarized {
+		return strings.TrimSpace(speech.Text)
+	}
+
+	lines := make([]string, 0, len(speech.Segments))
+	for _, seg := range speech.Segments {
+		if seg.Speaker == "" {
+			lines = append(lines, seg.Text)
+			continue
+		}
+		lines = append(lines, seg.Speaker+"："+seg.Text)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
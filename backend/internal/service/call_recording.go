@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCallRecordingNotFound   = errors.New("call recording not found")
+	ErrCallRecordingNotPending = errors.New("call recording has already been confirmed or discarded")
+	ErrEmptyTranscript         = errors.New("no speech recognized in recording")
+	ErrCustomerNotFound        = errors.New("customer not found")
+)
+
+// CallRecordingService 通话录音：转写 + AI 总结生成跟进记录草稿，销售确认后写入跟进记录
+type CallRecordingService struct {
+	aiService          *AIService
+	recordingRepo      *repository.CallRecordingRepository
+	customerRepo       *repository.CustomerRepository
+	interactionService *InteractionService
+}
+
+func NewCallRecordingService(
+	aiService *AIService,
+	recordingRepo *repository.CallRecordingRepository,
+	customerRepo *repository.CustomerRepository,
+	interactionService *InteractionService,
+) *CallRecordingService {
+	return &CallRecordingService{
+		aiService:          aiService,
+		recordingRepo:      recordingRepo,
+		customerRepo:       customerRepo,
+		interactionService: interactionService,
+	}
+}
+
+// Upload 处理一段通话录音，返回待确认的跟进记录草稿
+func (s *CallRecordingService) Upload(ctx context.Context, userID, customerID uint64, fileName string, audio []byte, format, language string) (*dto.CallRecordingResponse, error) {
+	customer, err := s.ownedCustomer(customerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	speech, summary, err := s.aiService.AnalyzeCall(ctx, customer, audio, format, language)
+	if err != nil {
+		return nil, err
+	}
+
+	recording := &models.CallRecording{
+		UserID:      userID,
+		CustomerID:  customerID,
+		FileName:    fileName,
+		Status:      models.CallRecordingPending,
+		Provider:    speech.Provider,
+		Duration:    speech.Duration,
+		Confidence:  speechConfidence(speech),
+		Transcript:  formatTranscript(speech),
+		Segments:    transcriptSegments(speech),
+		Summary:     summary.Summary,
+		KeyPoints:   summary.KeyPoints,
+		Objections:  summary.Objections,
+		Commitments: summary.Commitments,
+		NextSteps:   summary.NextSteps,
+		Outcome:     summary.Outcome,
+		NextAction:  summary.NextAction,
+	}
+	if summary.NextDate != "" {
+		if d, err := time.ParseInLocation("2006-01-02", summary.NextDate, time.Local); err == nil {
+			recording.NextDate = &d
+		}
+	}
+
+	if err := s.recordingRepo.Create(recording); err != nil {
+		return nil, err
+	}
+	return s.toResponse(recording), nil
+}
+
+// GetRecording 获取通话录音处理结果
+func (s *CallRecordingService) GetRecording(id, userID uint64) (*dto.CallRecordingResponse, error) {
+	recording, err := s.ownedRecording(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(recording), nil
+}
+
+// ListByCustomer 客户的通话录音，按时间倒序
+func (s *CallRecordingService) ListByCustomer(customerID, userID uint64) ([]*dto.CallRecordingResponse, error) {
+	if _, err := s.ownedCustomer(customerID, userID); err != nil {
+		return nil, err
+	}
+	recordings, err := s.recordingRepo.ListByCustomerID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*dto.CallRecordingResponse, len(recordings))
+	for i, recording := range recordings {
+		responses[i] = s.toResponse(recording)
+	}
+	return responses, nil
+}
+
+// Confirm 确认草稿，创建跟进记录；req 中的字段覆盖 AI 生成的内容
+func (s *CallRecordingService) Confirm(id, userID uint64, req *dto.ConfirmCallRecordingRequest) (*dto.InteractionResponse, error) {
+	recording, err := s.ownedRecording(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if recording.Status != models.CallRecordingPending {
+		return nil, ErrCallRecordingNotPending
+	}
+
+	draft := interactionDraft(recording)
+	if req.Content != nil {
+		draft.Content = *req.Content
+	}
+	if req.Outcome != nil {
+		draft.Outcome = *req.Outcome
+	}
+	if req.NextAction != nil {
+		draft.NextAction = *req.NextAction
+	}
+	if req.NextDate != nil {
+		draft.NextDate = req.NextDate
+	}
+
+	interaction, err := s.interactionService.CreateInteraction(userID, draft)
+	if err != nil {
+		return nil, err
+	}
+
+	recording.Status = models.CallRecordingConfirmed
+	recording.InteractionID = &interaction.ID
+	if err := s.recordingRepo.Update(recording); err != nil {
+		return nil, err
+	}
+	return interaction, nil
+}
+
+// Discard 放弃草稿，不创建跟进记录
+func (s *CallRecordingService) Discard(id, userID uint64) error {
+	recording, err := s.ownedRecording(id, userID)
+	if err != nil {
+		return err
+	}
+	if recording.Status != models.CallRecordingPending {
+		return ErrCallRecordingNotPending
+	}
+	recording.Status = models.CallRecordingDiscarded
+	return s.recordingRepo.Update(recording)
+}
+
+func (s *CallRecordingService) ownedCustomer(customerID, userID uint64) (*models.Customer, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+	if customer.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return customer, nil
+}
+
+func (s *CallRecordingService) ownedRecording(id, userID uint64) (*models.CallRecording, error) {
+	recording, err := s.recordingRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCallRecordingNotFound
+		}
+		return nil, err
+	}
+	if recording.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return recording, nil
+}
+
+func (s *CallRecordingService) toResponse(recording *models.CallRecording) *dto.CallRecordingResponse {
+	resp := &dto.CallRecordingResponse{CallRecording: recording}
+	if recording.Status == models.CallRecordingPending {
+		resp.Draft = interactionDraft(recording)
+	}
+	return resp
+}
+
+// interactionDraft 由 AI 总结生成的跟进记录草稿
+func interactionDraft(recording *models.CallRecording) *dto.CreateInteractionRequest {
+	var sb strings.Builder
+	sb.WriteString("【通话摘要】")
+	sb.WriteString(recording.Summary)
+	sb.WriteString("\n")
+	for _, section := range []struct {
+		title string
+		items []string
+	}{
+		{"【要点】", recording.KeyPoints},
+		{"【客户异议】", recording.Objections},
+		{"【双方承诺】", recording.Commitments},
+		{"【下一步】", recording.NextSteps},
+	} {
+		if len(section.items) == 0 {
+			continue
+		}
+		sb.WriteString(section.title)
+		sb.WriteString("\n")
+		for _, item := range section.items {
+			sb.WriteString("- ")
+			sb.WriteString(item)
+			sb.WriteString("\n")
+		}
+	}
+
+	return &dto.CreateInteractionRequest{
+		CustomerID: recording.CustomerID,
+		Type:       "call",
+		Content:    strings.TrimSpace(sb.String()),
+		Outcome:    recording.Outcome,
+		NextAction: recording.NextAction,
+		NextDate:   recording.NextDate,
+	}
+}
@@ -0,0 +1,651 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"github.com/xia/nextcrm/pkg/llm"
+	"github.com/xia/nextcrm/pkg/volcengine"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCardScanNotFound   = errors.New("card scan batch not found")
+	ErrCardItemNotFound   = errors.New("scanned card not found")
+	ErrNoCardImages       = errors.New("no business card images were uploaded")
+	ErrTooManyCards       = errors.New("too many business cards in one batch")
+	ErrCardUploadTooLarge = errors.New("business card upload is too large")
+	ErrCardNotReviewable  = errors.New("card is not waiting for review")
+	ErrCardNameRequired   = errors.New("name is required to create a customer")
+	ErrCardNoMatch        = errors.New("no matching customer to merge into, pass customer_id")
+	ErrNothingRecognized  = errors.New("nothing was recognized on the card")
+)
+
+// cardMatchLimit 每张名片最多保留的匹配客户数
+const cardMatchLimit = 10
+
+// cardImageExtensions ZIP 中按扩展名识别的图片
+var cardImageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".bmp": true}
+
+// cardMatchPriority 匹配字段的强弱顺序
+var cardMatchPriority = []string{"phone", "email", "company"}
+
+// SetCardOCR 配置火山引擎名片 OCR，作为图片理解模型都不可用时的备用
+func (s *AIService) SetCardOCR(client *volcengine.OCRClient) {
+	s.cardOCR = client
+}
+
+// cardOCRFallbackAllowed 额度用完、隐私策略禁止图片或请求已取消时不再尝试备用 OCR
+func cardOCRFallbackAllowed(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, ErrQuotaExceeded) && !errors.Is(err, ErrAIImageBlocked)
+}
+
+// recognizeCardWithOCR 用火山引擎名片 OCR 识别，同样记录审计日志
+func (s *AIService) recognizeCardWithOCR(ctx context.Context, imageData []byte, visionErr error) (*dto.BusinessCardOCRResponse, error) {
+	result, err := s.cardOCR.RecognizeBusinessCard(imageData)
+	if sess := privacyFrom(ctx); sess != nil {
+		sum := sha256.Sum256(imageData)
+		s.privacy.audit(ctx, sess, &models.AIAuditLog{
+			Capability:  string(llm.CapabilityVision),
+			ImageSHA256: hex.EncodeToString(sum[:]),
+			ImageBytes:  len(imageData),
+		}, &llm.ChatResponse{Provider: "volcengine"}, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v; volcengine OCR fallback: %w", visionErr, err)
+	}
+	log.Printf("Business card recognized by volcengine OCR after vision failure: %v", visionErr)
+	return &dto.BusinessCardOCRResponse{
+		Name:     result.Name,
+		Company:  result.Company,
+		Position: result.Position,
+		Phone:    result.Phone,
+		Email:    result.Email,
+		Address:  result.Address,
+		Provider: "volcengine",
+	}, nil
+}
+
+// CardScanService 批量名片识别：上传的图片由有限个 worker 并行识别（豆包图片理解，失败时火山引擎 OCR），
+// 每张名片按电话、邮箱、公司匹配已有客户后进入审核队列，由用户逐张确认创建、合并或丢弃
+type CardScanService struct {
+	aiService       *AIService
+	scanRepo        *repository.CardScanRepository
+	customerRepo    *repository.CustomerRepository
+	customerService *CustomerService
+	workers         int
+	maxCards        int
+	maxBytes        int64
+}
+
+func NewCardScanService(
+	aiService *AIService,
+	scanRepo *repository.CardScanRepository,
+	customerRepo *repository.CustomerRepository,
+	customerService *CustomerService,
+	workers int,
+	maxCards int,
+	maxBytes int64,
+) *CardScanService {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &CardScanService{
+		aiService:       aiService,
+		scanRepo:        scanRepo,
+		customerRepo:    customerRepo,
+		customerService: customerService,
+		workers:         workers,
+		maxCards:        maxCards,
+		maxBytes:        maxBytes,
+	}
+}
+
+// RecoverUnfinished 进程重启后未完成的批次无法继续（图片不落盘），标记为失败
+func (s *CardScanService) RecoverUnfinished() {
+	n, err := s.scanRepo.FailUnfinished("interrupted by server restart, please upload again")
+	if err != nil {
+		log.Printf("failed to recover card scan batches: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("marked %d unfinished card scan batches as failed", n)
+	}
+}
+
+// ExtractCardImages 解压 ZIP 中的图片，忽略目录、隐藏文件和非图片文件；解压后的总大小同样受上传上限约束
+func (s *CardScanService) ExtractCardImages(data []byte) ([]dto.CardScanImage, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	var (
+		images []dto.CardScanImage
+		total  int64
+	)
+	for _, f := range r.File {
+		name := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		if !cardImageExtensions[strings.ToLower(path.Ext(name))] {
+			continue
+		}
+		if s.maxCards > 0 && len(images) >= s.maxCards {
+			return nil, fmt.Errorf("%w (max %d)", ErrTooManyCards, s.maxCards)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", f.Name, err)
+		}
+		limit := int64(1<<63 - 1)
+		if s.maxBytes > 0 {
+			limit = s.maxBytes - total + 1
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, limit))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", f.Name, err)
+		}
+		total += int64(len(content))
+		if s.maxBytes > 0 && total > s.maxBytes {
+			return nil, fmt.Errorf("%w (max %d MB)", ErrCardUploadTooLarge, s.maxBytes>>20)
+		}
+		images = append(images, dto.CardScanImage{FileName: f.Name, Data: content})
+	}
+	return images, nil
+}
+
+// Submit 创建批次并在后台识别，立即返回排队中的批次
+func (s *CardScanService) Submit(ctx context.Context, userID uint64, images []dto.CardScanImage) (*dto.CardScanBatchResponse, error) {
+	if len(images) == 0 {
+		return nil, ErrNoCardImages
+	}
+	if s.maxCards > 0 && len(images) > s.maxCards {
+		return nil, fmt.Errorf("%w (max %d)", ErrTooManyCards, s.maxCards)
+	}
+	var total int64
+	for _, img := range images {
+		total += int64(len(img.Data))
+	}
+	if s.maxBytes > 0 && total > s.maxBytes {
+		return nil, fmt.Errorf("%w (max %d MB)", ErrCardUploadTooLarge, s.maxBytes>>20)
+	}
+	// 提交时先检查一次额度，避免排进去才失败
+	if err := s.aiService.usage.CheckQuota(ctx); err != nil {
+		return nil, err
+	}
+
+	batch := &models.CardScanBatch{
+		UserID:     userID,
+		Status:     models.CardScanQueued,
+		TotalCards: len(images),
+	}
+	items := make([]*models.CardScanItem, len(images))
+	for i, img := range images {
+		items[i] = &models.CardScanItem{
+			UserID:   userID,
+			FileName: img.FileName,
+			Status:   models.CardItemPending,
+		}
+	}
+	if err := s.scanRepo.CreateBatch(batch, items); err != nil {
+		return nil, err
+	}
+
+	// 返回副本，后台识别会修改原对象
+	resp := &dto.CardScanBatchResponse{Items: make([]*models.CardScanItem, len(items))}
+	batchCopy := *batch
+	resp.CardScanBatch = &batchCopy
+	for i, item := range items {
+		itemCopy := *item
+		resp.Items[i] = &itemCopy
+	}
+
+	go s.run(batch, items, images)
+
+	return resp, nil
+}
+
+// GetBatch 查询批次进度和名片，status 为空时返回全部名片
+func (s *CardScanService) GetBatch(id, userID uint64, status string) (*dto.CardScanBatchResponse, error) {
+	batch, err := s.ownedBatch(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.scanRepo.ListItems(batch.ID, status)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CardScanBatchResponse{CardScanBatch: batch, Items: items}, nil
+}
+
+// ListBatches 用户最近的批次
+func (s *CardScanService) ListBatches(userID uint64, limit int) ([]*models.CardScanBatch, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.scanRepo.ListBatches(userID, limit)
+}
+
+// Approve 确认名片并创建新客户
+func (s *CardScanService) Approve(userID, batchID, itemID uint64, req *dto.ApproveCardRequest) (*dto.CardReviewResponse, error) {
+	item, err := s.reviewableItem(userID, batchID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	applyCardEdits(item, &req.CardEdits)
+	return s.approve(userID, item)
+}
+
+// Merge 把名片合并到已有客户：默认只补充空字段，不一致的电话、邮箱等追加到备注
+func (s *CardScanService) Merge(userID, batchID, itemID uint64, req *dto.MergeCardRequest) (*dto.CardReviewResponse, error) {
+	item, err := s.reviewableItem(userID, batchID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	applyCardEdits(item, &req.CardEdits)
+
+	customerID := req.CustomerID
+	if customerID == 0 {
+		if len(item.Matches) == 0 {
+			return nil, ErrCardNoMatch
+		}
+		customerID = item.Matches[0].CustomerID
+	}
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+	if customer.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	update := &dto.UpdateCustomerRequest{}
+	var conflicts []string
+	for _, f := range []struct {
+		label    string
+		card     string
+		existing string
+		target   **string
+	}{
+		{"姓名", item.Name, customer.Name, &update.Name},
+		{"公司", item.Company, customer.Company, &update.Company},
+		{"职位", item.Position, customer.Position, &update.Position},
+		{"电话", item.Phone, customer.Phone, &update.Phone},
+		{"邮箱", item.Email, customer.Email, &update.Email},
+		{"地址", item.Address, customer.Address, &update.Address},
+	} {
+		card := f.card
+		switch {
+		case card == "" || card == f.existing:
+		case f.existing == "" || req.Overwrite:
+			*f.target = &card
+		default:
+			conflicts = append(conflicts, fmt.Sprintf("%s：%s", f.label, card))
+		}
+	}
+	if len(conflicts) > 0 {
+		notes := strings.TrimSpace(customer.Notes + "\n" + fmt.Sprintf("名片（批量识别 #%d）", item.BatchID) + "\n" + strings.Join(conflicts, "\n"))
+		update.Notes = &notes
+	}
+
+	resp, err := s.customerService.UpdateCustomer(customer.ID, userID, update)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.review(item, models.CardItemMerged, &resp.ID); err != nil {
+		return nil, err
+	}
+	return &dto.CardReviewResponse{Item: item, Customer: resp}, nil
+}
+
+// Discard 丢弃名片
+func (s *CardScanService) Discard(userID, batchID, itemID uint64) (*models.CardScanItem, error) {
+	item, err := s.reviewableItem(userID, batchID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.review(item, models.CardItemDiscarded, nil); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ApproveUnmatched 批量确认没有匹配到已有客户、且识别出姓名的名片，其余留在审核队列
+func (s *CardScanService) ApproveUnmatched(userID, batchID uint64) (*dto.ApproveUnmatchedResponse, error) {
+	batch, err := s.ownedBatch(batchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.scanRepo.ListItems(batch.ID, models.CardItemReview)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ApproveUnmatchedResponse{Items: []*models.CardScanItem{}}
+	for _, item := range items {
+		if len(item.Matches) > 0 || strings.TrimSpace(item.Name) == "" {
+			resp.Skipped++
+			continue
+		}
+		if _, err := s.approve(userID, item); err != nil {
+			log.Printf("failed to approve scanned card %d: %v", item.ID, err)
+			resp.Skipped++
+			continue
+		}
+		resp.Approved++
+		resp.Items = append(resp.Items, item)
+	}
+	return resp, nil
+}
+
+func (s *CardScanService) approve(userID uint64, item *models.CardScanItem) (*dto.CardReviewResponse, error) {
+	if strings.TrimSpace(item.Name) == "" {
+		return nil, ErrCardNameRequired
+	}
+	customer, err := s.customerService.CreateCustomer(userID, &dto.CreateCustomerRequest{
+		Name:     item.Name,
+		Company:  item.Company,
+		Position: item.Position,
+		Phone:    item.Phone,
+		Email:    item.Email,
+		Address:  item.Address,
+		Source:   "Business Card",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.review(item, models.CardItemApproved, &customer.ID); err != nil {
+		return nil, err
+	}
+	return &dto.CardReviewResponse{Item: item, Customer: customer}, nil
+}
+
+func (s *CardScanService) review(item *models.CardScanItem, status string, customerID *uint64) error {
+	now := time.Now()
+	item.Status = status
+	item.CustomerID = customerID
+	item.ReviewedAt = &now
+	return s.scanRepo.UpdateItem(item)
+}
+
+// run 后台识别批次；请求上下文已结束，用户信息重新放进新的上下文以便计量用量
+func (s *CardScanService) run(batch *models.CardScanBatch, items []*models.CardScanItem, images []dto.CardScanImage) {
+	ctx, cancel := context.WithCancel(WithAIUser(context.Background(), batch.UserID))
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.finish(batch, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	if err := s.scanRepo.StartBatch(batch.ID); err != nil {
+		log.Printf("failed to start card scan batch %d: %v", batch.ID, err)
+	}
+	now := time.Now()
+	batch.Status = models.CardScanRunning
+	batch.StartedAt = &now
+
+	queue := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		stopOnce sync.Once
+		stopErr  error
+	)
+	workers := s.workers
+	if workers > len(items) {
+		workers = len(items)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if ctx.Err() != nil {
+					continue
+				}
+				err := s.scan(ctx, items[i], images[i].Data)
+				// 额度用完后其余名片都会失败，直接停止
+				if errors.Is(err, ErrQuotaExceeded) {
+					stopOnce.Do(func() {
+						stopErr = err
+						cancel()
+					})
+				}
+				mu.Lock()
+				batch.DoneCards++
+				if err != nil {
+					batch.FailedCards++
+				}
+				mu.Unlock()
+				if err := s.scanRepo.IncrementProgress(batch.ID, err != nil); err != nil {
+					log.Printf("failed to update card scan batch %d progress: %v", batch.ID, err)
+				}
+			}
+		}()
+	}
+
+	for i := range items {
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	// 停止后尚未识别的名片标记为失败
+	if stopErr == nil {
+		stopErr = errors.New("card scan stopped")
+	}
+	for _, item := range items {
+		if item.Status != models.CardItemPending {
+			continue
+		}
+		item.Status = models.CardItemFailed
+		item.Error = stopErr.Error()
+		batch.DoneCards++
+		batch.FailedCards++
+		if err := s.scanRepo.UpdateItem(item); err != nil {
+			log.Printf("failed to save scanned card %d: %v", item.ID, err)
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// 中途停止：已识别的名片照常审核，批次记录停止原因
+		if batch.FailedCards == batch.TotalCards {
+			s.finish(batch, stopErr)
+			return
+		}
+		s.finish(batch, nil)
+		batch.Error = stopErr.Error()
+		if err := s.scanRepo.UpdateBatch(batch); err != nil {
+			log.Printf("failed to save card scan batch %d: %v", batch.ID, err)
+		}
+	case batch.FailedCards == batch.TotalCards:
+		s.finish(batch, errors.New("no business card could be recognized"))
+	default:
+		s.finish(batch, nil)
+	}
+}
+
+// scan 识别一张名片并匹配已有客户；识别失败时记录错误，名片不进入审核队列
+func (s *CardScanService) scan(ctx context.Context, item *models.CardScanItem, imageData []byte) error {
+	card, err := s.aiService.RecognizeBusinessCard(ctx, imageData)
+	if err == nil && card.Name == "" && card.Company == "" && card.Phone == "" && card.Email == "" {
+		err = ErrNothingRecognized
+	}
+	if err != nil {
+		item.Status = models.CardItemFailed
+		item.Error = err.Error()
+		if saveErr := s.scanRepo.UpdateItem(item); saveErr != nil {
+			log.Printf("failed to save scanned card %d: %v", item.ID, saveErr)
+		}
+		return err
+	}
+
+	item.Name = strings.TrimSpace(card.Name)
+	item.Company = strings.TrimSpace(card.Company)
+	item.Position = strings.TrimSpace(card.Position)
+	item.Phone = strings.TrimSpace(card.Phone)
+	item.Email = strings.TrimSpace(card.Email)
+	item.Address = strings.TrimSpace(card.Address)
+	item.Provider = card.Provider
+	if card.Confidence != nil {
+		item.Confidence = *card.Confidence
+	}
+	item.Status = models.CardItemReview
+
+	matches, err := s.matchCustomers(item)
+	if err != nil {
+		// 匹配失败不影响审核，用户仍可手动选择合并的客户
+		log.Printf("failed to match scanned card %d against customers: %v", item.ID, err)
+	}
+	item.Matches = matches
+
+	if err := s.scanRepo.UpdateItem(item); err != nil {
+		log.Printf("failed to save scanned card %d: %v", item.ID, err)
+	}
+	return nil
+}
+
+// matchCustomers 按电话、邮箱、公司匹配已有客户；电话或邮箱命中的排在只有公司相同的前面
+func (s *CardScanService) matchCustomers(item *models.CardScanItem) ([]models.CardMatch, error) {
+	phone := repository.NormalizePhone(item.Phone)
+	customers, err := s.customerRepo.FindCardMatches(item.UserID, phone, item.Email, item.Company)
+	if err != nil || len(customers) == 0 {
+		return nil, err
+	}
+
+	matches := make([]models.CardMatch, 0, len(customers))
+	for _, c := range customers {
+		m := models.CardMatch{CustomerID: c.ID, Name: c.Name, Company: c.Company, Phone: c.Phone, Email: c.Email}
+		if phone != "" && repository.NormalizePhone(c.Phone) == phone {
+			m.MatchedOn = append(m.MatchedOn, "phone")
+		}
+		if item.Email != "" && strings.EqualFold(c.Email, item.Email) {
+			m.MatchedOn = append(m.MatchedOn, "email")
+		}
+		if item.Company != "" && strings.EqualFold(strings.TrimSpace(c.Company), item.Company) {
+			m.MatchedOn = append(m.MatchedOn, "company")
+		}
+		if len(m.MatchedOn) > 0 {
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return cardMatchRank(matches[i]) < cardMatchRank(matches[j])
+	})
+	if len(matches) > cardMatchLimit {
+		matches = matches[:cardMatchLimit]
+	}
+	return matches, nil
+}
+
+// cardMatchRank 按最强的命中字段排序
+func cardMatchRank(m models.CardMatch) int {
+	for rank, field := range cardMatchPriority {
+		if containsString(m.MatchedOn, field) {
+			return rank
+		}
+	}
+	return len(cardMatchPriority)
+}
+
+// finish 写入批次最终状态
+func (s *CardScanService) finish(batch *models.CardScanBatch, err error) {
+	now := time.Now()
+	batch.FinishedAt = &now
+	if err != nil {
+		batch.Status = models.CardScanFailed
+		batch.Error = err.Error()
+		log.Printf("card scan batch %d failed: %v", batch.ID, err)
+	} else {
+		batch.Status = models.CardScanCompleted
+		batch.Error = ""
+	}
+	if err := s.scanRepo.UpdateBatch(batch); err != nil {
+		log.Printf("failed to save card scan batch %d: %v", batch.ID, err)
+	}
+}
+
+func (s *CardScanService) ownedBatch(id, userID uint64) (*models.CardScanBatch, error) {
+	batch, err := s.scanRepo.FindBatchByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCardScanNotFound
+		}
+		return nil, err
+	}
+	if batch.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return batch, nil
+}
+
+// reviewableItem 批次中等待审核的名片
+func (s *CardScanService) reviewableItem(userID, batchID, itemID uint64) (*models.CardScanItem, error) {
+	item, err := s.scanRepo.FindItemByID(itemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCardItemNotFound
+		}
+		return nil, err
+	}
+	if item.BatchID != batchID {
+		return nil, ErrCardItemNotFound
+	}
+	if item.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if item.Status != models.CardItemReview {
+		return nil, ErrCardNotReviewable
+	}
+	return item, nil
+}
+
+// applyCardEdits 审核时的修改覆盖识别值
+func applyCardEdits(item *models.CardScanItem, edits *dto.CardEdits) {
+	for _, f := range []struct {
+		edit  *string
+		field *string
+	}{
+		{edits.Name, &item.Name},
+		{edits.Company, &item.Company},
+		{edits.Position, &item.Position},
+		{edits.Phone, &item.Phone},
+		{edits.Email, &item.Email},
+		{edits.Address, &item.Address},
+	} {
+		if f.edit != nil {
+			*f.field = strings.TrimSpace(*f.edit)
+		}
+	}
+}
@@ -0,0 +1,573 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCustomFieldNotFound     = errors.New("custom field not found")
+	ErrCustomFieldExists       = errors.New("custom field already exists")
+	ErrInvalidCustomField      = errors.New("invalid custom field definition")
+	ErrInvalidCustomFieldValue = errors.New("invalid custom field value")
+)
+
+const (
+	maxCustomFieldOptions = 200
+	// customFieldSortPrefix sort_by=cf.<key> 按自定义字段排序
+	customFieldSortPrefix = "cf."
+)
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// customFieldListSeparators 多选值、导入单元格和列表过滤参数中的分隔符
+var customFieldListSeparators = regexp.MustCompile(`\s*[,，、;；]\s*`)
+
+var customFieldEntities = []string{
+	models.CustomFieldEntityCustomer,
+	models.CustomFieldEntityDeal,
+	models.CustomFieldEntityInteraction,
+}
+
+// CustomFieldService 自定义字段：管理员按实体和团队定义字段，记录保存时按定义校验取值
+type CustomFieldService struct {
+	repo     *repository.CustomFieldRepository
+	userRepo *repository.UserRepository
+}
+
+func NewCustomFieldService(repo *repository.CustomFieldRepository, userRepo *repository.UserRepository) *CustomFieldService {
+	return &CustomFieldService{repo: repo, userRepo: userRepo}
+}
+
+// SetCustomFields 客户分析提示词附带客户的自定义字段
+func (s *AIService) SetCustomFields(customFields *CustomFieldService) {
+	s.customFields = customFields
+}
+
+// ListDefinitions 管理端列出定义
+func (s *CustomFieldService) ListDefinitions(query *dto.CustomFieldListQuery) ([]*models.CustomFieldDefinition, error) {
+	if query.Entity != "" && !containsString(customFieldEntities, query.Entity) {
+		return nil, fmt.Errorf("%w: unknown entity %q", ErrInvalidCustomField, query.Entity)
+	}
+	return s.repo.List(query.Entity, query.TeamID)
+}
+
+// CreateDefinition 新建定义，同一实体、同一范围内 key 不能重复
+func (s *CustomFieldService) CreateDefinition(userID uint64, req *dto.CreateCustomFieldRequest) (*models.CustomFieldDefinition, error) {
+	def := &models.CustomFieldDefinition{
+		Entity:     req.Entity,
+		TeamID:     req.TeamID,
+		Key:        strings.TrimSpace(req.Key),
+		Label:      strings.TrimSpace(req.Label),
+		Type:       req.Type,
+		Options:    req.Options,
+		Required:   req.Required,
+		Validation: req.Validation,
+		Position:   req.Position,
+		CreatedBy:  userID,
+	}
+	if !customFieldKeyPattern.MatchString(def.Key) {
+		return nil, fmt.Errorf("%w: key must be lowercase letters, digits and underscores, starting with a letter", ErrInvalidCustomField)
+	}
+	if err := validateDefinition(def); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.FindByKey(def.Entity, def.TeamID, def.Key); err == nil {
+		return nil, fmt.Errorf("%w: %s.%s", ErrCustomFieldExists, def.Entity, def.Key)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := s.repo.Create(def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// UpdateDefinition 修改标签、选项、必填、校验规则和顺序；已保存的取值不会重新校验
+func (s *CustomFieldService) UpdateDefinition(id uint64, req *dto.UpdateCustomFieldRequest) (*models.CustomFieldDefinition, error) {
+	def, err := s.findDefinition(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Label != nil {
+		def.Label = strings.TrimSpace(*req.Label)
+	}
+	if req.Options != nil {
+		def.Options = req.Options
+	}
+	if req.Required != nil {
+		def.Required = *req.Required
+	}
+	if req.Validation != nil {
+		def.Validation = *req.Validation
+	}
+	if req.Position != nil {
+		def.Position = *req.Position
+	}
+	if err := validateDefinition(def); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// DeleteDefinition 删除定义
+func (s *CustomFieldService) DeleteDefinition(id uint64) error {
+	if _, err := s.findDefinition(id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *CustomFieldService) findDefinition(id uint64) (*models.CustomFieldDefinition, error) {
+	def, err := s.repo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomFieldNotFound
+		}
+		return nil, err
+	}
+	return def, nil
+}
+
+// validateDefinition 检查标签、选项和校验规则是否与字段类型匹配，并去掉空选项
+func validateDefinition(def *models.CustomFieldDefinition) error {
+	if def.Label == "" {
+		return fmt.Errorf("%w: label is required", ErrInvalidCustomField)
+	}
+
+	selectable := def.Type == models.CustomFieldSelect || def.Type == models.CustomFieldMultiSelect
+	options := make([]string, 0, len(def.Options))
+	for _, o := range def.Options {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			continue
+		}
+		if containsString(options, o) {
+			return fmt.Errorf("%w: option %q is listed twice", ErrInvalidCustomField, o)
+		}
+		options = append(options, o)
+	}
+	switch {
+	case selectable && len(options) == 0:
+		return fmt.Errorf("%w: %s fields need at least one option", ErrInvalidCustomField, def.Type)
+	case !selectable && len(options) > 0:
+		return fmt.Errorf("%w: options only apply to select and multi_select fields", ErrInvalidCustomField)
+	case len(options) > maxCustomFieldOptions:
+		return fmt.Errorf("%w: at most %d options are allowed", ErrInvalidCustomField, maxCustomFieldOptions)
+	}
+	def.Options = options
+
+	v := def.Validation
+	if (v.Min != nil || v.Max != nil) && def.Type != models.CustomFieldNumber {
+		return fmt.Errorf("%w: min and max only apply to number fields", ErrInvalidCustomField)
+	}
+	if v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+		return fmt.Errorf("%w: min is greater than max", ErrInvalidCustomField)
+	}
+	if (v.MaxLength != 0 || v.Pattern != "") && def.Type != models.CustomFieldText {
+		return fmt.Errorf("%w: max_length and pattern only apply to text fields", ErrInvalidCustomField)
+	}
+	if v.MaxLength < 0 {
+		return fmt.Errorf("%w: max_length must not be negative", ErrInvalidCustomField)
+	}
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return fmt.Errorf("%w: invalid pattern: %v", ErrInvalidCustomField, err)
+		}
+	}
+	return nil
+}
+
+// Definitions 用户可用的字段定义（全局 + 所在团队，团队定义覆盖同 key 的全局定义），按 position 排序
+func (s *CustomFieldService) Definitions(userID uint64, entity string) ([]*models.CustomFieldDefinition, error) {
+	if !containsString(customFieldEntities, entity) {
+		return nil, fmt.Errorf("%w: unknown entity %q", ErrInvalidCustomField, entity)
+	}
+	teamID, err := s.userRepo.FindTeamID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	defs, err := s.repo.FindEffective(entity, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := make(map[string]bool)
+	for _, def := range defs {
+		if def.TeamID != nil {
+			overridden[def.Key] = true
+		}
+	}
+	out := make([]*models.CustomFieldDefinition, 0, len(defs))
+	for _, def := range defs {
+		if def.TeamID == nil && overridden[def.Key] {
+			continue
+		}
+		out = append(out, def)
+	}
+	return out, nil
+}
+
+// Apply 按定义校验 input 并合并到 current 的副本中：值为 null 或空表示清空，未定义的 key 报错；
+// 合并后检查必填字段。input 为 nil 表示系统生成的记录（如 AI 起草的跟进、通话记录），不检查必填。
+// ownerID 为记录所属用户，用于校验用户类型字段
+func (s *CustomFieldService) Apply(ownerID uint64, entity string, current, input map[string]interface{}) (map[string]interface{}, error) {
+	defs, err := s.Definitions(ownerID, entity)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*models.CustomFieldDefinition, len(defs))
+	for _, def := range defs {
+		byKey[def.Key] = def
+	}
+
+	out := make(map[string]interface{}, len(current)+len(input))
+	for k, v := range current {
+		out[k] = v
+	}
+	for key, raw := range input {
+		def, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidCustomFieldValue, key)
+		}
+		if customFieldEmpty(raw) {
+			delete(out, key)
+			continue
+		}
+		v, err := s.normalize(ownerID, def, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCustomFieldValue, def.Label, err)
+		}
+		out[key] = v
+	}
+
+	for _, def := range defs {
+		if input != nil && def.Required && customFieldEmpty(out[def.Key]) {
+			return nil, fmt.Errorf("%w: %s is required", ErrInvalidCustomFieldValue, def.Label)
+		}
+	}
+	return out, nil
+}
+
+func customFieldEmpty(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(val) == ""
+	case []interface{}:
+		return len(val) == 0
+	case []string:
+		return len(val) == 0
+	}
+	return false
+}
+
+// normalize 把 JSON 取值或导入的文本转换成保存格式：文本和单选为字符串，数字为 float64，
+// 日期为 YYYY-MM-DD，多选为去重的字符串数组，用户为用户 ID
+func (s *CustomFieldService) normalize(ownerID uint64, def *models.CustomFieldDefinition, raw interface{}) (interface{}, error) {
+	switch def.Type {
+	case models.CustomFieldText:
+		str, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		str = strings.TrimSpace(str)
+		if max := def.Validation.MaxLength; max > 0 && utf8.RuneCountInString(str) > max {
+			return nil, fmt.Errorf("must be at most %d characters", max)
+		}
+		if def.Validation.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + def.Validation.Pattern + `)$`)
+			if err != nil || !re.MatchString(str) {
+				return nil, fmt.Errorf("does not match the required format")
+			}
+		}
+		return str, nil
+
+	case models.CustomFieldNumber:
+		n, err := customFieldNumber(raw)
+		if err != nil {
+			return nil, err
+		}
+		if min := def.Validation.Min; min != nil && n < *min {
+			return nil, fmt.Errorf("must be at least %v", *min)
+		}
+		if max := def.Validation.Max; max != nil && n > *max {
+			return nil, fmt.Errorf("must be at most %v", *max)
+		}
+		return n, nil
+
+	case models.CustomFieldDate:
+		str, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a date string")
+		}
+		return customFieldDate(str)
+
+	case models.CustomFieldSelect:
+		str, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		str = strings.TrimSpace(str)
+		if !containsString(def.Options, str) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(def.Options, ", "))
+		}
+		return str, nil
+
+	case models.CustomFieldMultiSelect:
+		var items []string
+		switch val := raw.(type) {
+		case string:
+			items = customFieldListSeparators.Split(strings.TrimSpace(val), -1)
+		case []interface{}:
+			for _, item := range val {
+				str, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("must be an array of strings")
+				}
+				items = append(items, strings.TrimSpace(str))
+			}
+		default:
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			if item == "" || containsString(values, item) {
+				continue
+			}
+			if !containsString(def.Options, item) {
+				return nil, fmt.Errorf("%q is not one of %s", item, strings.Join(def.Options, ", "))
+			}
+			values = append(values, item)
+		}
+		return values, nil
+
+	case models.CustomFieldUser:
+		n, err := customFieldNumber(raw)
+		if err != nil || n <= 0 || n != float64(uint64(n)) {
+			return nil, fmt.Errorf("must be a user ID")
+		}
+		id := uint64(n)
+		if err := s.checkUserReference(ownerID, id); err != nil {
+			return nil, err
+		}
+		return id, nil
+	}
+	return nil, fmt.Errorf("unsupported field type %q", def.Type)
+}
+
+func customFieldNumber(raw interface{}) (float64, error) {
+	switch val := raw.(type) {
+	case float64:
+		return val, nil
+	case string:
+		n, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(val), ",", ""), 64)
+		if err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("must be a number")
+}
+
+func customFieldDate(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006/01/02", "2006.01.02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("must be a date like 2006-01-02")
+}
+
+// checkUserReference 用户字段只能引用同团队的用户；没有团队时只能引用记录所属用户自己
+func (s *CustomFieldService) checkUserReference(ownerID, userID uint64) error {
+	if userID == ownerID {
+		return nil
+	}
+	user, err := s.userRepo.FindByID(strconv.FormatUint(userID, 10))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("user %d not found", userID)
+		}
+		return err
+	}
+	ownerTeam, err := s.userRepo.FindTeamID(ownerID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if ownerTeam == nil || user.TeamID == nil || *user.TeamID != *ownerTeam {
+		return fmt.Errorf("user %d is not in your team", userID)
+	}
+	return nil
+}
+
+// ParseQuery 把列表参数 cf[key]=value 和 sort_by=cf.<key> 解析成过滤和排序条件：
+// 单选、多选和用户字段可用逗号分隔多个值（匹配任一值），数字和日期字段可用 from..to 取范围（可只给一端）
+func (s *CustomFieldService) ParseQuery(userID uint64, entity string, raw map[string]string, sortBy string, desc bool) ([]dto.CustomFieldFilter, *dto.CustomFieldSort, error) {
+	sortKey, sortByField := strings.CutPrefix(sortBy, customFieldSortPrefix)
+	if len(raw) == 0 && !sortByField {
+		return nil, nil, nil
+	}
+	defs, err := s.Definitions(userID, entity)
+	if err != nil {
+		return nil, nil, err
+	}
+	byKey := make(map[string]*models.CustomFieldDefinition, len(defs))
+	for _, def := range defs {
+		byKey[def.Key] = def
+	}
+
+	var fieldSort *dto.CustomFieldSort
+	if sortByField {
+		def, ok := byKey[sortKey]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidCustomFieldValue, sortKey)
+		}
+		if def.Type == models.CustomFieldMultiSelect {
+			return nil, nil, fmt.Errorf("%w: cannot sort by multi_select field %q", ErrInvalidCustomFieldValue, sortKey)
+		}
+		fieldSort = &dto.CustomFieldSort{Key: def.Key, Type: def.Type, Desc: desc}
+	}
+
+	filters := make([]dto.CustomFieldFilter, 0, len(raw))
+	for key, value := range raw {
+		def, ok := byKey[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: unknown filter field %q", ErrInvalidCustomFieldValue, key)
+		}
+		f, err := s.parseFilter(def, strings.TrimSpace(value))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: filter %s: %v", ErrInvalidCustomFieldValue, key, err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, fieldSort, nil
+}
+
+func (s *CustomFieldService) parseFilter(def *models.CustomFieldDefinition, value string) (dto.CustomFieldFilter, error) {
+	f := dto.CustomFieldFilter{Key: def.Key, Type: def.Type}
+	if value == "" {
+		return f, fmt.Errorf("value is empty")
+	}
+
+	switch def.Type {
+	case models.CustomFieldNumber, models.CustomFieldDate:
+		from, to, isRange := strings.Cut(value, "..")
+		if !isRange {
+			v, err := parseFilterBound(def.Type, value)
+			if err != nil {
+				return f, err
+			}
+			f.Values = []interface{}{v}
+			return f, nil
+		}
+		if from = strings.TrimSpace(from); from != "" {
+			v, err := parseFilterBound(def.Type, from)
+			if err != nil {
+				return f, err
+			}
+			f.From = v
+		}
+		if to = strings.TrimSpace(to); to != "" {
+			v, err := parseFilterBound(def.Type, to)
+			if err != nil {
+				return f, err
+			}
+			f.To = v
+		}
+		if f.From == nil && f.To == nil {
+			return f, fmt.Errorf("range needs at least one bound")
+		}
+
+	case models.CustomFieldSelect, models.CustomFieldMultiSelect, models.CustomFieldUser:
+		for _, item := range customFieldListSeparators.Split(value, -1) {
+			if item == "" {
+				continue
+			}
+			if def.Type == models.CustomFieldUser {
+				id, err := strconv.ParseUint(item, 10, 64)
+				if err != nil {
+					return f, fmt.Errorf("%q is not a user ID", item)
+				}
+				f.Values = append(f.Values, id)
+				continue
+			}
+			if !containsString(def.Options, item) {
+				return f, fmt.Errorf("%q is not one of %s", item, strings.Join(def.Options, ", "))
+			}
+			f.Values = append(f.Values, item)
+		}
+
+	default:
+		f.Values = []interface{}{value}
+	}
+	return f, nil
+}
+
+func parseFilterBound(fieldType, value string) (interface{}, error) {
+	if fieldType == models.CustomFieldNumber {
+		return customFieldNumber(value)
+	}
+	return customFieldDate(value)
+}
+
+// FormatValue 导出和提示词中显示的文本：多选用逗号连接，数字去掉多余的小数位
+func formatCustomFieldValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case []string:
+		return strings.Join(val, ", ")
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = formatCustomFieldValue(item)
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+// Describe 把记录的自定义字段按定义顺序写成 "- 标签: 值" 行，供 AI 提示词使用；没有取值时返回空字符串
+func (s *CustomFieldService) Describe(ownerID uint64, entity string, values map[string]interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	defs, err := s.Definitions(ownerID, entity)
+	if err != nil {
+		log.Printf("Failed to load custom fields for user %d: %v", ownerID, err)
+		return ""
+	}
+	var lines []string
+	for _, def := range defs {
+		if v, ok := values[def.Key]; ok && !customFieldEmpty(v) {
+			lines = append(lines, fmt.Sprintf("- %s: %s", def.Label, formatCustomFieldValue(v)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
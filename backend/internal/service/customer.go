@@ -2,19 +2,71 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"log"
 
 	"github.com/xia/nextcrm/internal/dto"
 	"github.com/xia/nextcrm/internal/models"
 	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
 )
 
 type CustomerService struct {
 	customerRepo *repository.CustomerRepository
+	activityRepo *repository.ActivityRepository
+	// 客户资料变化后回调（如让 AI 缓存失效）
+	changeObserver func(customerID uint64)
+	// 自定义字段校验，可为空（为空时忽略请求中的自定义字段）
+	customFields *CustomFieldService
+	// 账户和联系人，可为空（为空时客户不归入账户）
+	accounts *AccountService
+	// 标签和分群，可为空（为空时忽略请求中的标签和 segment_id）
+	tags     *TagService
+	segments *SegmentService
+	// 保存的列表视图，可为空（为空时不支持 view_id）
+	views *ViewService
 }
 
-func NewCustomerService(customerRepo *repository.CustomerRepository) *CustomerService {
+func NewCustomerService(customerRepo *repository.CustomerRepository, activityRepo *repository.ActivityRepository) *CustomerService {
 	return &CustomerService{
 		customerRepo: customerRepo,
+		activityRepo: activityRepo,
+	}
+}
+
+// SetChangeObserver registers a callback invoked after a customer is updated, archived, restored or deleted
+func (s *CustomerService) SetChangeObserver(observer func(customerID uint64)) {
+	s.changeObserver = observer
+}
+
+// SetCustomFields enables custom field validation, filtering and sorting for customers
+func (s *CustomerService) SetCustomFields(customFields *CustomFieldService) {
+	s.customFields = customFields
+}
+
+// SetAccounts files new customers under accounts and keeps the contacts split from customers in sync
+func (s *CustomerService) SetAccounts(accounts *AccountService) {
+	s.accounts = accounts
+}
+
+// SetTags enables tagging customers on create and update
+func (s *CustomerService) SetTags(tags *TagService) {
+	s.tags = tags
+}
+
+// SetSegments enables listing the customers of a saved segment
+func (s *CustomerService) SetSegments(segments *SegmentService) {
+	s.segments = segments
+}
+
+// SetViews enables listing customers through a saved view (view_id)
+func (s *CustomerService) SetViews(views *ViewService) {
+	s.views = views
+}
+
+func (s *CustomerService) notifyChange(customerID uint64) {
+	if s.changeObserver != nil {
+		s.changeObserver(customerID)
 	}
 }
 
@@ -65,8 +117,38 @@ func (s *CustomerService) CreateCustomer(userID uint64, req *dto.CreateCustomerR
 	if customer.Source == "" {
 		customer.Source = "Manual"
 	}
-
-	if err := s.customerRepo.Create(customer); err != nil {
+	if s.customFields != nil {
+		values, err := s.customFields.Apply(userID, models.CustomFieldEntityCustomer, nil, req.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		customer.CustomFields = values
+	}
+	if s.tags != nil && len(req.Tags) > 0 {
+		tags, err := s.tags.Normalize(userID, req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		customer.Tags = tags
+	}
+
+	// 账户、客户和拆分出的联系人一起写入，失败时不留下没有客户的账户
+	err := s.customerRepo.Transaction(func(tx *gorm.DB) error {
+		if s.accounts == nil {
+			return s.customerRepo.WithTx(tx).Create(customer)
+		}
+		accounts := s.accounts.WithTx(tx)
+		account, err := accounts.AccountForCustomer(customer, req.AccountID)
+		if err != nil {
+			return err
+		}
+		customer.AccountID = &account.ID
+		if err := s.customerRepo.WithTx(tx).Create(customer); err != nil {
+			return err
+		}
+		return accounts.AddCustomerContact(customer)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -90,6 +172,20 @@ func (s *CustomerService) GetCustomerByID(id, userID uint64) (*dto.CustomerRespo
 
 // ListCustomers retrieves customers with pagination and filters
 func (s *CustomerService) ListCustomers(userID uint64, query *dto.CustomerQuery) ([]*dto.CustomerResponse, int, int64, error) {
+	if err := s.parseCustomFieldQuery(userID, query); err != nil {
+		return nil, 0, 0, err
+	}
+	if err := resolveListFilter(s.views, userID, "customers", &query.ListFilter, query.SortBy, query.SortOrder == "desc"); err != nil {
+		return nil, 0, 0, err
+	}
+	if s.segments != nil && query.SegmentID > 0 {
+		ids, err := s.segments.CustomerIDs(query.SegmentID, userID)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		query.IDs = ids
+	}
+
 	customers, total, err := s.customerRepo.FindByUserID(userID, query)
 	if err != nil {
 		return nil, 0, 0, err
@@ -120,6 +216,8 @@ func (s *CustomerService) UpdateCustomer(id, userID uint64, req *dto.UpdateCusto
 		return nil, ErrUnauthorized
 	}
 
+	previousStage := customer.Stage
+
 	// Update fields
 	if req.Name != nil {
 		customer.Name = *req.Name
@@ -226,10 +324,56 @@ func (s *CustomerService) UpdateCustomer(id, userID uint64, req *dto.UpdateCusto
 	if req.PaymentTerms != nil {
 		customer.PaymentTerms = *req.PaymentTerms
 	}
-
-	if err := s.customerRepo.Update(customer); err != nil {
+	if s.customFields != nil && req.CustomFields != nil {
+		values, err := s.customFields.Apply(userID, models.CustomFieldEntityCustomer, customer.CustomFields, req.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		customer.CustomFields = values
+	}
+	var tags []string
+	if s.tags != nil && req.Tags != nil {
+		if tags, err = s.tags.Normalize(userID, req.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	// 换账户、客户字段、标签和联系人同步在一个事务中，部分失败时联系人不会与客户不一致
+	personChanged := req.Name != nil || req.Position != nil || req.Phone != nil || req.Email != nil || req.WechatID != nil
+	err = s.customerRepo.Transaction(func(tx *gorm.DB) error {
+		var accounts *AccountService
+		if s.accounts != nil {
+			accounts = s.accounts.WithTx(tx)
+		}
+		if accounts != nil && req.AccountID != nil {
+			if err := accounts.MoveCustomer(customer, *req.AccountID); err != nil {
+				return err
+			}
+		}
+		if err := s.customerRepo.WithTx(tx).Update(customer); err != nil {
+			return err
+		}
+		if tags != nil {
+			if err := s.tags.WithTx(tx).SetCustomerTags(customer.ID, tags); err != nil {
+				return err
+			}
+		}
+		if accounts != nil && personChanged {
+			return accounts.SyncCustomerContact(customer)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	if tags != nil {
+		customer.Tags = tags
+	}
+
+	if customer.Stage != previousStage {
+		s.recordStageChange(userID, customer, previousStage)
+	}
+	s.notifyChange(customer.ID)
 
 	return s.toResponse(customer), nil
 }
@@ -246,7 +390,11 @@ func (s *CustomerService) DeleteCustomer(id, userID uint64) error {
 		return ErrUnauthorized
 	}
 
-	return s.customerRepo.Delete(id)
+	if err := s.customerRepo.Delete(id); err != nil {
+		return err
+	}
+	s.notifyChange(id)
+	return nil
 }
 
 // IncrementFollowUp increments the follow-up count
@@ -276,12 +424,16 @@ func (s *CustomerService) ArchiveCustomer(id, userID uint64) error {
 		return ErrUnauthorized
 	}
 
-	return s.customerRepo.SoftDelete(id)
+	if err := s.customerRepo.SoftDelete(id); err != nil {
+		return err
+	}
+	s.notifyChange(id)
+	return nil
 }
 
 // RestoreCustomer restores an archived customer
 func (s *CustomerService) RestoreCustomer(id, userID uint64) (*dto.CustomerResponse, error) {
-	customer, err := s.customerRepo.FindByID(id)
+	customer, err := s.customerRepo.FindArchivedByID(id)
 	if err != nil {
 		return nil, err
 	}
@@ -294,6 +446,7 @@ func (s *CustomerService) RestoreCustomer(id, userID uint64) (*dto.CustomerRespo
 	if err := s.customerRepo.Restore(id); err != nil {
 		return nil, err
 	}
+	s.notifyChange(id)
 
 	// Fetch the restored customer
 	customer, err = s.customerRepo.FindByID(id)
@@ -304,8 +457,51 @@ func (s *CustomerService) RestoreCustomer(id, userID uint64) (*dto.CustomerRespo
 	return s.toResponse(customer), nil
 }
 
+// ReassignCustomer hands a customer, with its interactions, deals and the contacts split from it, over to another owner.
+// The customer is re-filed under the new owner's account the same way a new customer is. The caller checks that toUserID is a valid owner
+func (s *CustomerService) ReassignCustomer(id, userID, toUserID uint64) error {
+	customer, err := s.customerRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	// Check if customer belongs to user
+	if customer.UserID != userID {
+		return ErrUnauthorized
+	}
+	if toUserID == userID {
+		return nil
+	}
+
+	err = s.customerRepo.Transaction(func(tx *gorm.DB) error {
+		var accountID *uint64
+		if s.accounts != nil {
+			moved := *customer
+			moved.UserID = toUserID
+			account, err := s.accounts.WithTx(tx).AccountForCustomer(&moved, nil)
+			if err != nil {
+				return err
+			}
+			accountID = &account.ID
+		}
+		return s.customerRepo.WithTx(tx).Reassign(id, toUserID, accountID)
+	})
+	if err != nil {
+		return err
+	}
+	s.notifyChange(id)
+	return nil
+}
+
 // ListArchivedCustomers retrieves archived customers with pagination
 func (s *CustomerService) ListArchivedCustomers(userID uint64, query *dto.CustomerQuery) ([]*dto.CustomerResponse, int, int64, error) {
+	if err := s.parseCustomFieldQuery(userID, query); err != nil {
+		return nil, 0, 0, err
+	}
+	if err := resolveListFilter(s.views, userID, "customers", &query.ListFilter, query.SortBy, query.SortOrder == "desc"); err != nil {
+		return nil, 0, 0, err
+	}
+
 	customers, total, err := s.customerRepo.FindArchivedByUserID(userID, query)
 	if err != nil {
 		return nil, 0, 0, err
@@ -324,6 +520,20 @@ func (s *CustomerService) ListArchivedCustomers(userID uint64, query *dto.Custom
 	return responses, totalPages, total, nil
 }
 
+// parseCustomFieldQuery 解析列表的自定义字段过滤和排序参数
+func (s *CustomerService) parseCustomFieldQuery(userID uint64, query *dto.CustomerQuery) error {
+	if s.customFields == nil {
+		return nil
+	}
+	filters, sort, err := s.customFields.ParseQuery(userID, models.CustomFieldEntityCustomer,
+		query.CustomFields, query.SortBy, query.SortOrder == "desc")
+	if err != nil {
+		return err
+	}
+	query.CustomFieldFilters, query.CustomFieldSort = filters, sort
+	return nil
+}
+
 func (s *CustomerService) toResponse(customer *models.Customer) *dto.CustomerResponse {
 	return &dto.CustomerResponse{
 		ID:                customer.ID,
@@ -363,9 +573,28 @@ func (s *CustomerService) toResponse(customer *models.Customer) *dto.CustomerRes
 		TaxNumber:          customer.TaxNumber,
 		BankAccount:        customer.BankAccount,
 		PaymentTerms:       customer.PaymentTerms,
+		AccountID:          customer.AccountID,
+		CustomFields:       customer.CustomFields,
+		Tags:               append([]string{}, customer.Tags...),
 		CreatedAt:          customer.CreatedAt,
 		UpdatedAt:          customer.UpdatedAt,
 	}
 }
 
 var ErrUnauthorized = errors.New("unauthorized")
+
+// recordStageChange 记录阶段变更，供 AI 分析回顾客户推进过程；记录失败不影响更新
+func (s *CustomerService) recordStageChange(userID uint64, customer *models.Customer, from string) {
+	customerID := customer.ID
+	activity := &models.Activity{
+		UserID:      userID,
+		CustomerID:  &customerID,
+		ActionType:  models.ActivityStageChange,
+		EntityType:  "customer",
+		EntityID:    &customerID,
+		Description: fmt.Sprintf("%s → %s", from, customer.Stage),
+	}
+	if err := s.activityRepo.Create(activity); err != nil {
+		log.Printf("Failed to record stage change for customer %d: %v", customerID, err)
+	}
+}
@@ -0,0 +1,551 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDuplicateClusterNotFound = errors.New("duplicate cluster not found")
+	ErrDuplicateClusterClosed   = errors.New("duplicate cluster has already been dismissed or merged")
+	ErrMergeNotFound            = errors.New("merge not found")
+	ErrInvalidMerge             = errors.New("invalid merge")
+	ErrMergeUndone              = errors.New("merge has already been undone")
+	ErrMergeUndoExpired         = errors.New("the undo window of this merge has passed")
+	ErrMergeSurvivorGone        = errors.New("the surviving customer has since been archived or merged; undo that first")
+)
+
+// maxMergeCustomers 一次最多合并的客户数（不含保留的客户）
+const maxMergeCustomers = 20
+
+// 合并时不能选择来源的字段；保留的客户不换账户，迁移过来的跟进记录和成交归入它的账户
+var mergeProtectedFields = map[string]bool{
+	"id": true, "user_id": true, "created_at": true, "updated_at": true, "deleted_at": true, "merged_into_id": true,
+	"account_id": true,
+}
+
+// DuplicateService 客户查重与合并：新建时实时提示，每晚扫描生成疑似重复客户组，
+// 合并时在一个事务里迁移跟进、成交、动态等关联记录，撤销期内可以撤销
+type DuplicateService struct {
+	customerRepo    *repository.CustomerRepository
+	duplicateRepo   *repository.CustomerDuplicateRepository
+	activityRepo    *repository.ActivityRepository
+	customerService *CustomerService
+	undoWindow      time.Duration
+
+	// 同一用户的扫描和合并串行执行
+	locks sync.Map
+}
+
+func NewDuplicateService(
+	customerRepo *repository.CustomerRepository,
+	duplicateRepo *repository.CustomerDuplicateRepository,
+	activityRepo *repository.ActivityRepository,
+	customerService *CustomerService,
+	undoWindow time.Duration,
+) *DuplicateService {
+	return &DuplicateService{
+		customerRepo:    customerRepo,
+		duplicateRepo:   duplicateRepo,
+		activityRepo:    activityRepo,
+		customerService: customerService,
+		undoWindow:      undoWindow,
+	}
+}
+
+func (s *DuplicateService) lock(userID uint64) func() {
+	mu, _ := s.locks.LoadOrStore(userID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// Check 查找与 c 疑似重复的客户，按匹配度从高到低排列；excludeID 为 c 本身（新建后或编辑时）
+func (s *DuplicateService) Check(userID uint64, c *models.Customer, excludeID uint64) ([]*dto.DuplicateMatch, error) {
+	candidates, err := s.customerRepo.FindDuplicates(userID, c, excludeID)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]*dto.DuplicateMatch, 0, len(candidates))
+	for _, cand := range candidates {
+		matchedOn, score := duplicateLink(c, &cand.Customer, cand.NameSimilarity, cand.CompanySimilarity)
+		matches = append(matches, &dto.DuplicateMatch{
+			Customer:          s.customerService.toResponse(&cand.Customer),
+			MatchedOn:         matchedOn,
+			NameSimilarity:    cand.NameSimilarity,
+			CompanySimilarity: cand.CompanySimilarity,
+			Score:             score,
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
+
+// CheckRequest 按请求中的字段实时查重
+func (s *DuplicateService) CheckRequest(userID uint64, req *dto.CheckDuplicatesRequest) ([]*dto.DuplicateMatch, error) {
+	return s.Check(userID, &models.Customer{
+		Name:       req.Name,
+		Company:    req.Company,
+		Phone:      req.Phone,
+		Email:      req.Email,
+		WechatID:   req.WechatID,
+		CreditCode: req.CreditCode,
+	}, req.ExcludeID)
+}
+
+// duplicateLink 两个客户的重复依据和匹配度
+func duplicateLink(a, b *models.Customer, nameSimilarity, companySimilarity float64) ([]string, float64) {
+	matchedOn := repository.DuplicateMatchedOn(a, b)
+	exact := len(matchedOn) > 0
+	sameName := strings.EqualFold(strings.TrimSpace(a.Name), strings.TrimSpace(b.Name)) &&
+		strings.EqualFold(strings.TrimSpace(a.Company), strings.TrimSpace(b.Company))
+	if sameName || (nameSimilarity >= repository.DuplicateNameSimilarity && companySimilarity >= repository.DuplicateCompanySimilarity) {
+		matchedOn = append(matchedOn, models.DuplicateOnName)
+	}
+
+	if exact || sameName {
+		return matchedOn, 1
+	}
+	return matchedOn, (nameSimilarity + companySimilarity) / 2
+}
+
+// StartNightly 每天 hour 点扫描所有用户的客户
+func (s *DuplicateService) StartNightly(hour int) {
+	go func() {
+		for {
+			time.Sleep(time.Until(nextDailyRun(time.Now(), hour)))
+			s.ScanAll()
+		}
+	}()
+}
+
+// ScanAll 扫描所有有客户的用户；单个用户失败只记录日志
+func (s *DuplicateService) ScanAll() {
+	userIDs, err := s.customerRepo.FindUserIDsWithCustomers()
+	if err != nil {
+		log.Printf("Duplicate scan failed to list users: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		if _, err := s.Scan(userID); err != nil {
+			log.Printf("Duplicate scan failed for user %d: %v", userID, err)
+		}
+	}
+	log.Printf("Duplicate scan finished for %d users", len(userIDs))
+}
+
+// Scan 扫描用户的全部客户，把疑似重复的客户对连成组，替换未处理的旧结果；
+// 与忽略过的组成员完全相同的组不再生成
+func (s *DuplicateService) Scan(userID uint64) ([]*dto.DuplicateClusterResponse, error) {
+	unlock := s.lock(userID)
+	defer unlock()
+
+	pairs, err := s.customerRepo.FindDuplicatePairs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	seen := make(map[uint64]bool)
+	for _, p := range pairs {
+		for _, id := range []uint64{p.CustomerID, p.OtherID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	customers, err := s.customerRepo.FindByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]*models.Customer, len(customers))
+	for _, c := range customers {
+		byID[c.ID] = c
+	}
+
+	// 并查集：有重复依据的两个客户归入同一组
+	parent := make(map[uint64]uint64)
+	var find func(id uint64) uint64
+	find = func(id uint64) uint64 {
+		if p, ok := parent[id]; ok && p != id {
+			parent[id] = find(p)
+			return parent[id]
+		}
+		parent[id] = id
+		return id
+	}
+	links := make(map[uint64][]models.DuplicateLink)
+	var linked []models.DuplicateLink
+	for _, p := range pairs {
+		a, b := byID[p.CustomerID], byID[p.OtherID]
+		if a == nil || b == nil {
+			continue
+		}
+		matchedOn, score := duplicateLink(a, b, p.NameSimilarity, p.CompanySimilarity)
+		if len(matchedOn) == 0 {
+			continue
+		}
+		parent[find(a.ID)] = find(b.ID)
+		linked = append(linked, models.DuplicateLink{
+			CustomerID: a.ID,
+			OtherID:    b.ID,
+			MatchedOn:  matchedOn,
+			Similarity: score,
+		})
+	}
+	for _, l := range linked {
+		root := find(l.CustomerID)
+		links[root] = append(links[root], l)
+	}
+
+	now := time.Now()
+	clusters := make([]*models.DuplicateCluster, 0, len(links))
+	for _, group := range links {
+		members := make(map[uint64]bool)
+		cluster := &models.DuplicateCluster{
+			UserID:    userID,
+			Links:     group,
+			Status:    models.DuplicateClusterOpen,
+			ScannedAt: now,
+		}
+		for _, l := range group {
+			members[l.CustomerID], members[l.OtherID] = true, true
+			if l.Similarity > cluster.Score {
+				cluster.Score = l.Similarity
+			}
+		}
+		for id := range members {
+			cluster.CustomerIDs = append(cluster.CustomerIDs, id)
+		}
+		sort.Slice(cluster.CustomerIDs, func(i, j int) bool { return cluster.CustomerIDs[i] < cluster.CustomerIDs[j] })
+		cluster.MemberKey = duplicateMemberKey(cluster.CustomerIDs)
+		clusters = append(clusters, cluster)
+	}
+
+	if err := s.duplicateRepo.ReplaceOpenClusters(userID, clusters); err != nil {
+		return nil, err
+	}
+	return s.ListClusters(userID, 0)
+}
+
+func duplicateMemberKey(ids []uint64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ListClusters 未处理的疑似重复客户组，匹配度高的在前；成员已被归档或合并、不足两人的组不返回
+func (s *DuplicateService) ListClusters(userID uint64, limit int) ([]*dto.DuplicateClusterResponse, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	clusters, err := s.duplicateRepo.ListClusters(userID, models.DuplicateClusterOpen, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, c := range clusters {
+		ids = append(ids, c.CustomerIDs...)
+	}
+	customers, err := s.customerRepo.FindByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]*models.Customer, len(customers))
+	for _, c := range customers {
+		byID[c.ID] = c
+	}
+
+	responses := make([]*dto.DuplicateClusterResponse, 0, len(clusters))
+	for _, c := range clusters {
+		resp := &dto.DuplicateClusterResponse{DuplicateCluster: c}
+		for _, id := range c.CustomerIDs {
+			if customer := byID[id]; customer != nil {
+				resp.Customers = append(resp.Customers, s.customerService.toResponse(customer))
+			}
+		}
+		if len(resp.Customers) >= 2 {
+			responses = append(responses, resp)
+		}
+	}
+	return responses, nil
+}
+
+// DismissCluster 忽略一组疑似重复客户（不是同一人），之后的扫描不再提示
+func (s *DuplicateService) DismissCluster(userID, id uint64) (*models.DuplicateCluster, error) {
+	cluster, err := s.ownedCluster(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Status != models.DuplicateClusterOpen {
+		return nil, ErrDuplicateClusterClosed
+	}
+	cluster.Status = models.DuplicateClusterDismissed
+	if err := s.duplicateRepo.UpdateCluster(cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// Merge 把 merged_ids 合并到保留客户：按 fields 选择各字段的取值，迁移关联记录，
+// 归档被合并的客户；撤销期内可以用 UndoMerge 撤销
+func (s *DuplicateService) Merge(userID uint64, req *dto.MergeCustomersRequest) (*dto.MergeCustomersResponse, error) {
+	unlock := s.lock(userID)
+	defer unlock()
+
+	if len(req.MergedIDs) > maxMergeCustomers {
+		return nil, fmt.Errorf("%w: at most %d customers can be merged at once", ErrInvalidMerge, maxMergeCustomers)
+	}
+	all := []uint64{req.SurvivorID}
+	seen := map[uint64]bool{req.SurvivorID: true}
+	for _, id := range req.MergedIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: customer %d is listed twice", ErrInvalidMerge, id)
+		}
+		seen[id] = true
+		all = append(all, id)
+	}
+	for name, id := range req.Fields {
+		if _, ok := customerFields[name]; !ok || mergeProtectedFields[name] {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidMerge, name)
+		}
+		if !seen[id] {
+			return nil, fmt.Errorf("%w: field %q takes its value from customer %d, which is not being merged", ErrInvalidMerge, name, id)
+		}
+	}
+
+	customers, err := s.customerRepo.FindByIDs(userID, all)
+	if err != nil {
+		return nil, err
+	}
+	if len(customers) != len(all) {
+		return nil, ErrCustomerNotFound
+	}
+	byID := make(map[uint64]*models.Customer, len(customers))
+	for _, c := range customers {
+		byID[c.ID] = c
+	}
+
+	var clusterID *uint64
+	if req.ClusterID != 0 {
+		cluster, err := s.ownedCluster(req.ClusterID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if cluster.Status != models.DuplicateClusterOpen {
+			return nil, ErrDuplicateClusterClosed
+		}
+		clusterID = &cluster.ID
+	}
+
+	survivor := byID[req.SurvivorID]
+	merged := make([]*models.Customer, len(req.MergedIDs))
+	for i, id := range req.MergedIDs {
+		merged[i] = byID[id]
+	}
+
+	now := time.Now()
+	merge := &models.CustomerMerge{
+		UserID:         userID,
+		SurvivorID:     survivor.ID,
+		MergedIDs:      req.MergedIDs,
+		ClusterID:      clusterID,
+		FieldSources:   req.Fields,
+		SurvivorBefore: *survivor,
+		Status:         models.CustomerMergeMerged,
+		UndoUntil:      now.Add(s.undoWindow),
+	}
+	mergeCustomerFields(survivor, merged, byID, req.Fields)
+
+	if err := s.duplicateRepo.Merge(survivor, merge); err != nil {
+		return nil, err
+	}
+	s.recordMerge(userID, survivor, merged, merge.ID)
+	for _, id := range all {
+		s.customerService.notifyChange(id)
+	}
+
+	return &dto.MergeCustomersResponse{Merge: merge, Customer: s.customerService.toResponse(survivor)}, nil
+}
+
+// mergeCustomerFields 按来源选择合并后的字段值：指定了来源的取来源客户的值；备注拼接，
+// 跟进次数相加，最近联系时间取最晚；其余字段保留客户为空时取第一个有值的被合并客户
+func mergeCustomerFields(survivor *models.Customer, merged []*models.Customer, byID map[uint64]*models.Customer, sources map[string]uint64) {
+	dst := reflect.ValueOf(survivor).Elem()
+	for name, i := range customerFields {
+		if mergeProtectedFields[name] {
+			continue
+		}
+		if id, ok := sources[name]; ok {
+			dst.Field(i).Set(reflect.ValueOf(byID[id]).Elem().Field(i))
+			continue
+		}
+
+		switch name {
+		case "notes":
+			notes := []string{}
+			if n := strings.TrimSpace(survivor.Notes); n != "" {
+				notes = append(notes, n)
+			}
+			for _, c := range merged {
+				if n := strings.TrimSpace(c.Notes); n != "" && !containsString(notes, n) {
+					notes = append(notes, n)
+				}
+			}
+			survivor.Notes = strings.Join(notes, "\n\n")
+		case "follow_up_count":
+			for _, c := range merged {
+				survivor.FollowUpCount += c.FollowUpCount
+			}
+		case "last_contact":
+			for _, c := range merged {
+				if c.LastContact != nil && (survivor.LastContact == nil || c.LastContact.After(*survivor.LastContact)) {
+					survivor.LastContact = c.LastContact
+				}
+			}
+		case "custom_fields":
+			// 按 key 补齐保留客户没有的值；复制一份，避免改到合并前的快照
+			values := make(map[string]interface{}, len(survivor.CustomFields))
+			for k, v := range survivor.CustomFields {
+				values[k] = v
+			}
+			for _, c := range merged {
+				for k, v := range c.CustomFields {
+					if customFieldEmpty(values[k]) && !customFieldEmpty(v) {
+						values[k] = v
+					}
+				}
+			}
+			survivor.CustomFields = values
+		case "tags":
+			tags := append(pq.StringArray{}, survivor.Tags...)
+			for _, c := range merged {
+				for _, tag := range c.Tags {
+					if !containsString(tags, tag) {
+						tags = append(tags, tag)
+					}
+				}
+			}
+			survivor.Tags = tags
+		default:
+			if !dst.Field(i).IsZero() {
+				continue
+			}
+			for _, c := range merged {
+				if v := reflect.ValueOf(c).Elem().Field(i); !v.IsZero() {
+					dst.Field(i).Set(v)
+					break
+				}
+			}
+		}
+	}
+}
+
+// recordMerge 在保留的客户上记录合并动态；记录失败不影响合并
+func (s *DuplicateService) recordMerge(userID uint64, survivor *models.Customer, merged []*models.Customer, mergeID uint64) {
+	names := make([]string, len(merged))
+	ids := make([]uint64, len(merged))
+	for i, c := range merged {
+		names[i] = c.Name
+		ids[i] = c.ID
+	}
+	customerID := survivor.ID
+	activity := &models.Activity{
+		UserID:      userID,
+		CustomerID:  &customerID,
+		ActionType:  models.ActivityCustomerMerge,
+		EntityType:  "customer",
+		EntityID:    &customerID,
+		Description: fmt.Sprintf("合并了 %d 个重复客户：%s", len(merged), strings.Join(names, "、")),
+		Metadata:    map[string]interface{}{"merge_id": mergeID, "merged_ids": ids},
+	}
+	if err := s.activityRepo.Create(activity); err != nil {
+		log.Printf("Failed to record merge %d for customer %d: %v", mergeID, customerID, err)
+	}
+}
+
+// ListMerges 用户最近的合并记录
+func (s *DuplicateService) ListMerges(userID uint64, limit int) ([]*models.CustomerMerge, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.duplicateRepo.ListMerges(userID, limit)
+}
+
+// UndoMerge 撤销合并：恢复被合并的客户、迁回关联记录，保留客户的字段还原为合并前的值
+// （合并之后对保留客户的修改会丢失）
+func (s *DuplicateService) UndoMerge(userID, id uint64) (*dto.UndoMergeResponse, error) {
+	unlock := s.lock(userID)
+	defer unlock()
+
+	merge, err := s.duplicateRepo.FindMergeByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMergeNotFound
+		}
+		return nil, err
+	}
+	if merge.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if merge.Status == models.CustomerMergeUndone {
+		return nil, ErrMergeUndone
+	}
+	if time.Now().After(merge.UndoUntil) {
+		return nil, ErrMergeUndoExpired
+	}
+	if _, err := s.customerRepo.FindByID(merge.SurvivorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMergeSurvivorGone
+		}
+		return nil, err
+	}
+
+	if err := s.duplicateRepo.UndoMerge(merge); err != nil {
+		return nil, err
+	}
+
+	ids := append([]uint64{merge.SurvivorID}, merge.MergedIDs...)
+	customers, err := s.customerRepo.FindByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(customers, func(i, j int) bool { return customers[i].ID == merge.SurvivorID })
+	resp := &dto.UndoMergeResponse{Merge: merge, Customers: make([]*dto.CustomerResponse, len(customers))}
+	for i, c := range customers {
+		resp.Customers[i] = s.customerService.toResponse(c)
+		s.customerService.notifyChange(c.ID)
+	}
+	return resp, nil
+}
+
+func (s *DuplicateService) ownedCluster(id, userID uint64) (*models.DuplicateCluster, error) {
+	cluster, err := s.duplicateRepo.FindClusterByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDuplicateClusterNotFound
+		}
+		return nil, err
+	}
+	if cluster.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return cluster, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xia/nextcrm/internal/models"
+)
+
+// 每类历史最多取的条数；最终条数由 token 预算决定
+const historyFetchLimit = 50
+
+// 单条跟进记录内容最多保留的字符数
+const historyContentRunes = 200
+
+// loadCustomerHistory 汇总客户的跟进记录、成交记录和阶段变更（均按时间倒序），
+// 总长度控制在 budget 个 token 以内。跟进记录占一半预算，成交和阶段变更各占四分之一，
+// 某一类用不完的预算顺延给后面的类别
+func (s *AIService) loadCustomerHistory(customerID uint64, budget int) (string, error) {
+	interactions, err := s.interactionRepo.FindRecentByCustomerID(customerID, historyFetchLimit)
+	if err != nil {
+		return "", err
+	}
+	deals, err := s.dealRepo.FindRecentByCustomerID(customerID, historyFetchLimit)
+	if err != nil {
+		return "", err
+	}
+	stageChanges, err := s.activityRepo.GetByCustomerAndAction(customerID, models.ActivityStageChange, historyFetchLimit)
+	if err != nil {
+		return "", err
+	}
+
+	sections := []struct {
+		title string
+		share int // 占总预算的比例（百分比）
+		lines []string
+	}{
+		{"Interactions", 50, interactionLines(interactions)},
+		{"Deals", 25, dealLines(deals)},
+		{"Stage changes", 25, stageChangeLines(stageChanges)},
+	}
+
+	var sb strings.Builder
+	carry := 0
+	for _, sec := range sections {
+		if len(sec.lines) == 0 {
+			carry += budget * sec.share / 100
+			continue
+		}
+		header := sec.title + " (newest first):\n"
+		remaining := budget*sec.share/100 + carry - estimateTokens(header)
+		var body strings.Builder
+		for _, line := range sec.lines {
+			cost := estimateTokens(line) + 1
+			if cost > remaining {
+				break
+			}
+			body.WriteString(line)
+			body.WriteString("\n")
+			remaining -= cost
+		}
+		if body.Len() > 0 {
+			sb.WriteString(header)
+			sb.WriteString(body.String())
+		}
+		carry = remaining
+		if carry < 0 {
+			carry = 0
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func interactionLines(interactions []*models.Interaction) []string {
+	lines := make([]string, 0, len(interactions))
+	for _, it := range interactions {
+		line := fmt.Sprintf("- %s %s", it.CreatedAt.Format("2006-01-02"), it.Type)
+		if it.Outcome != "" {
+			line += " (" + it.Outcome + ")"
+		}
+		if content := truncateRunes(strings.Join(strings.Fields(it.Content), " "), historyContentRunes); content != "" {
+			line += ": " + content
+		}
+		if it.NextAction != "" {
+			line += "; next: " + it.NextAction
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func dealLines(deals []*models.Deal) []string {
+	lines := make([]string, 0, len(deals))
+	for _, d := range deals {
+		line := fmt.Sprintf("- %s %s %s x%g %s, %.2f %s, payment %s",
+			d.DealAt.Format("2006-01-02"), d.DealType, d.ProductOrService, d.Quantity, d.Unit,
+			d.Amount, d.Currency, d.PaymentStatus)
+		if d.IsRepeatPurchase {
+			line += ", repeat purchase"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func stageChangeLines(activities []*models.Activity) []string {
+	lines := make([]string, 0, len(activities))
+	for _, a := range activities {
+		lines = append(lines, fmt.Sprintf("- %s %s", a.CreatedAt.Format("2006-01-02"), a.Description))
+	}
+	return lines
+}
+
+// estimateTokens 粗略估算 token 数：ASCII 约 4 个字符一个 token，中文等约 1 个字符一个 token
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
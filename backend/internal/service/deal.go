@@ -18,6 +18,14 @@ var ErrDealUnauthorized = errors.New("deal access denied")
 type DealService struct {
 	dealRepo     *repository.DealRepository
 	customerRepo *repository.CustomerRepository
+	// 成交新建、修改或删除后回调（如让 AI 缓存失效）
+	changeObserver func(customerID uint64)
+	// 自定义字段校验，可为空（为空时忽略请求中的自定义字段）
+	customFields *CustomFieldService
+	// 账户和联系人，可为空（为空时不关联账户和联系人）
+	accounts *AccountService
+	// 保存的列表视图，可为空（为空时不支持 view_id）
+	views *ViewService
 }
 
 func NewDealService(dealRepo *repository.DealRepository, customerRepo *repository.CustomerRepository) *DealService {
@@ -27,6 +35,32 @@ func NewDealService(dealRepo *repository.DealRepository, customerRepo *repositor
 	}
 }
 
+// SetChangeObserver registers a callback invoked after a deal of a customer is created, updated or deleted
+func (s *DealService) SetChangeObserver(observer func(customerID uint64)) {
+	s.changeObserver = observer
+}
+
+// SetCustomFields enables custom field validation, filtering and sorting for deals
+func (s *DealService) SetCustomFields(customFields *CustomFieldService) {
+	s.customFields = customFields
+}
+
+// SetAccounts links deals to the customer's account and to specific contacts
+func (s *DealService) SetAccounts(accounts *AccountService) {
+	s.accounts = accounts
+}
+
+// SetViews enables listing deals through a saved view (view_id)
+func (s *DealService) SetViews(views *ViewService) {
+	s.views = views
+}
+
+func (s *DealService) notifyChange(customerID uint64) {
+	if s.changeObserver != nil {
+		s.changeObserver(customerID)
+	}
+}
+
 func (s *DealService) generateRecordNo() (string, error) {
 	b := make([]byte, 4)
 	if _, err := rand.Read(b); err != nil {
@@ -35,19 +69,20 @@ func (s *DealService) generateRecordNo() (string, error) {
 	return fmt.Sprintf("DL%d%s", time.Now().Unix(), hex.EncodeToString(b)), nil
 }
 
-func (s *DealService) ensureCustomerBelongsToUser(customerID, userID uint64) error {
+func (s *DealService) ensureCustomerBelongsToUser(customerID, userID uint64) (*models.Customer, error) {
 	c, err := s.customerRepo.FindByID(customerID)
 	if err != nil || c == nil {
-		return errors.New("customer not found")
+		return nil, errors.New("customer not found")
 	}
 	if c.UserID != userID {
-		return ErrDealUnauthorized
+		return nil, ErrDealUnauthorized
 	}
-	return nil
+	return c, nil
 }
 
 func (s *DealService) CreateDeal(userID uint64, req *dto.CreateDealRequest) (*dto.DealResponse, error) {
-	if err := s.ensureCustomerBelongsToUser(req.CustomerID, userID); err != nil {
+	customer, err := s.ensureCustomerBelongsToUser(req.CustomerID, userID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -90,11 +125,33 @@ func (s *DealService) CreateDeal(userID uint64, req *dto.CreateDealRequest) (*dt
 	if deal.PaymentStatus == "" {
 		deal.PaymentStatus = "pending"
 	}
+	if s.customFields != nil {
+		values, err := s.customFields.Apply(userID, models.CustomFieldEntityDeal, nil, req.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		deal.CustomFields = values
+	}
+	var contactIDs []uint64
+	if s.accounts != nil {
+		deal.AccountID = customer.AccountID
+		if contactIDs, err = s.accounts.ContactsForRecord(customer, req.ContactIDs); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.dealRepo.Create(deal); err != nil {
 		return nil, err
 	}
-	return s.toResponse(deal, ""), nil
+	if s.accounts != nil {
+		if err := s.accounts.LinkDealContacts(deal.ID, contactIDs); err != nil {
+			return nil, err
+		}
+	}
+	s.notifyChange(deal.CustomerID)
+	resp := s.toResponse(deal, "")
+	resp.ContactIDs = contactIDs
+	return resp, nil
 }
 
 func (s *DealService) UpdateDeal(dealID, userID uint64, req *dto.UpdateDealRequest) (*dto.DealResponse, error) {
@@ -148,11 +205,35 @@ func (s *DealService) UpdateDeal(dealID, userID uint64, req *dto.UpdateDealReque
 	if req.Notes != nil {
 		deal.Notes = *req.Notes
 	}
+	if s.customFields != nil && req.CustomFields != nil {
+		values, err := s.customFields.Apply(userID, models.CustomFieldEntityDeal, deal.CustomFields, req.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		deal.CustomFields = values
+	}
+	var contactIDs []uint64
+	relink := s.accounts != nil && req.ContactIDs != nil
+	if relink {
+		customer, err := s.customerRepo.FindByID(deal.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+		if contactIDs, err = s.accounts.ContactsForRecord(customer, req.ContactIDs); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.dealRepo.Update(deal); err != nil {
 		return nil, err
 	}
-	return s.toResponse(deal, ""), nil
+	if relink {
+		if err := s.accounts.LinkDealContacts(deal.ID, contactIDs); err != nil {
+			return nil, err
+		}
+	}
+	s.notifyChange(deal.CustomerID)
+	return s.withContacts(s.toResponse(deal, ""))
 }
 
 func (s *DealService) GetDealByID(dealID, userID uint64) (*dto.DealResponse, error) {
@@ -163,10 +244,22 @@ func (s *DealService) GetDealByID(dealID, userID uint64) (*dto.DealResponse, err
 	if deal.UserID != userID {
 		return nil, ErrDealUnauthorized
 	}
-	return s.toResponse(deal, ""), nil
+	return s.withContacts(s.toResponse(deal, ""))
 }
 
 func (s *DealService) ListDeals(userID uint64, query *dto.DealListQuery) ([]dto.DealResponse, int, int64, error) {
+	if s.customFields != nil {
+		filters, sort, err := s.customFields.ParseQuery(userID, models.CustomFieldEntityDeal,
+			query.CustomFields, query.SortBy, query.SortOrder != "asc")
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		query.CustomFieldFilters, query.CustomFieldSort = filters, sort
+	}
+	if err := resolveListFilter(s.views, userID, "deals", &query.ListFilter, query.SortBy, query.SortOrder != "asc"); err != nil {
+		return nil, 0, 0, err
+	}
+
 	deals, total, err := s.dealRepo.List(query, userID)
 	if err != nil {
 		return nil, 0, 0, err
@@ -184,11 +277,14 @@ func (s *DealService) ListDeals(userID uint64, query *dto.DealListQuery) ([]dto.
 	for _, d := range deals {
 		resp = append(resp, *s.toResponse(d, ""))
 	}
+	if err := s.fillContacts(resp); err != nil {
+		return nil, 0, 0, err
+	}
 	return resp, totalPages, total, nil
 }
 
 func (s *DealService) ListDealsByCustomerID(customerID, userID uint64) (*dto.CustomerDealsSummary, error) {
-	if err := s.ensureCustomerBelongsToUser(customerID, userID); err != nil {
+	if _, err := s.ensureCustomerBelongsToUser(customerID, userID); err != nil {
 		return nil, err
 	}
 	deals, err := s.dealRepo.ListByCustomerID(customerID, userID)
@@ -204,6 +300,9 @@ func (s *DealService) ListDealsByCustomerID(customerID, userID uint64) (*dto.Cus
 			out.RepeatCount++
 		}
 	}
+	if err := s.fillContacts(out.Deals); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
 
@@ -215,7 +314,38 @@ func (s *DealService) DeleteDeal(dealID, userID uint64) error {
 	if deal.UserID != userID {
 		return ErrDealUnauthorized
 	}
-	return s.dealRepo.Delete(dealID)
+	if err := s.dealRepo.Delete(dealID); err != nil {
+		return err
+	}
+	s.notifyChange(deal.CustomerID)
+	return nil
+}
+
+// fillContacts 附上各成交涉及的联系人
+func (s *DealService) fillContacts(deals []dto.DealResponse) error {
+	if s.accounts == nil || len(deals) == 0 {
+		return nil
+	}
+	ids := make([]uint64, len(deals))
+	for i := range deals {
+		ids[i] = deals[i].ID
+	}
+	contacts, err := s.accounts.DealContactIDs(ids)
+	if err != nil {
+		return err
+	}
+	for i := range deals {
+		deals[i].ContactIDs = contacts[deals[i].ID]
+	}
+	return nil
+}
+
+func (s *DealService) withContacts(resp *dto.DealResponse) (*dto.DealResponse, error) {
+	deals := []dto.DealResponse{*resp}
+	if err := s.fillContacts(deals); err != nil {
+		return nil, err
+	}
+	return &deals[0], nil
 }
 
 func (s *DealService) toResponse(d *models.Deal, customerName string) *dto.DealResponse {
@@ -224,6 +354,7 @@ func (s *DealService) toResponse(d *models.Deal, customerName string) *dto.DealR
 		RecordNo:         d.RecordNo,
 		UserID:           d.UserID,
 		CustomerID:       d.CustomerID,
+		AccountID:        d.AccountID,
 		DealType:         d.DealType,
 		ProductOrService: d.ProductOrService,
 		Quantity:         d.Quantity,
@@ -238,6 +369,7 @@ func (s *DealService) toResponse(d *models.Deal, customerName string) *dto.DealR
 		IsRepeatPurchase: d.IsRepeatPurchase,
 		DealAt:           d.DealAt,
 		Notes:            d.Notes,
+		CustomFields:     d.CustomFields,
 		CreatedAt:        d.CreatedAt,
 		UpdatedAt:        d.UpdatedAt,
 		CustomerName:     customerName,
@@ -0,0 +1,478 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"github.com/xia/nextcrm/pkg/llm"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDocumentNotFound         = errors.New("document not found")
+	ErrDocumentTooLarge         = errors.New("document is too large")
+	ErrUnsupportedDocument      = errors.New("unsupported document format, upload a JPEG, PNG, WebP image or a PDF")
+	ErrDocumentNotDraft         = errors.New("document has already been confirmed or discarded")
+	ErrDocumentCustomerRequired = errors.New("customer_id is required")
+	ErrDocumentIncomplete       = errors.New("required fields are missing")
+	ErrUnknownDocumentField     = errors.New("unknown document field")
+)
+
+// maxDocumentBytes 单个合同 / 发票文件的大小上限
+const maxDocumentBytes = 20 << 20
+
+// documentReviewConfidence 低于该置信度的字段需要用户核对
+const documentReviewConfidence = 0.8
+
+var (
+	taxNumberPattern    = regexp.MustCompile(`^[0-9A-Z]{15}$|^[0-9A-Z]{17,18}$|^[0-9A-Z]{20}$`)
+	documentDateLayouts = []string{
+		"2006-01-02", "2006-1-2", "2006/01/02", "2006/1/2", "2006.01.02", "2006.1.2",
+		"2006年01月02日", "2006年1月2日",
+	}
+)
+
+// ExtractDocument 识别合同或增值税发票，返回各字段的值和置信度以及完成识别的厂商；
+// 输出格式不合格时修复重试一次（修复请求只带上次输出，不重发文件）
+func (s *AIService) ExtractDocument(ctx context.Context, docType string, data []byte, mimeType string) (map[string]models.DocumentField, string, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureDocument)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, names := PromptContractExtract, dto.ContractFields
+	if docType == models.DocumentVATInvoice {
+		key, names = PromptInvoiceExtract, dto.VATInvoiceFields
+	}
+	prompt, err := s.prompts.Render(ctx, key, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := s.llmVision(ctx, &llm.VisionRequest{
+		Image:    data,
+		MimeType: mimeType,
+		Prompt:   prompt,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var extracted map[string]models.DocumentField
+	messages := []llm.Message{{Role: "user", Content: prompt}}
+	if err := s.ensureStructured(ctx, messages, resp.Content, dto.DocumentFieldsSchema(names), &extracted); err != nil {
+		return nil, "", err
+	}
+
+	// 只保留约定的字段，缺失的字段视为未识别
+	fields := make(map[string]models.DocumentField, len(names))
+	for _, name := range names {
+		f := extracted[name]
+		f.Value = strings.TrimSpace(f.Value)
+		if f.Value == "" {
+			f.Confidence = 0
+		}
+		fields[name] = f
+	}
+	return fields, resp.Provider, nil
+}
+
+// DocumentService 合同 / 增值税发票识别：逐字段给出置信度和校验提示，
+// 用户核对确认后合同生成成交记录，发票写入客户的抬头、税号和开户行账号
+type DocumentService struct {
+	aiService       *AIService
+	docRepo         *repository.DocumentExtractionRepository
+	customerRepo    *repository.CustomerRepository
+	customerService *CustomerService
+	dealService     *DealService
+}
+
+func NewDocumentService(
+	aiService *AIService,
+	docRepo *repository.DocumentExtractionRepository,
+	customerRepo *repository.CustomerRepository,
+	customerService *CustomerService,
+	dealService *DealService,
+) *DocumentService {
+	return &DocumentService{
+		aiService:       aiService,
+		docRepo:         docRepo,
+		customerRepo:    customerRepo,
+		customerService: customerService,
+		dealService:     dealService,
+	}
+}
+
+// Extract 识别上传的单据并保存为草稿
+func (s *DocumentService) Extract(ctx context.Context, userID uint64, fileName string, data []byte, req *dto.ExtractDocumentRequest) (*dto.DocumentExtractionResponse, error) {
+	if len(data) > maxDocumentBytes {
+		return nil, fmt.Errorf("%w (max %d MB)", ErrDocumentTooLarge, maxDocumentBytes>>20)
+	}
+	mimeType := http.DetectContentType(data)
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/webp", "application/pdf":
+	default:
+		return nil, ErrUnsupportedDocument
+	}
+
+	doc := &models.DocumentExtraction{
+		UserID:   userID,
+		DocType:  req.DocType,
+		FileName: fileName,
+		MimeType: mimeType,
+		Status:   models.DocumentDraft,
+	}
+	if req.CustomerID != 0 {
+		if _, err := s.ownedCustomer(req.CustomerID, userID); err != nil {
+			return nil, err
+		}
+		customerID := req.CustomerID
+		doc.CustomerID = &customerID
+	}
+	if doc.DocType == models.DocumentVATInvoice {
+		doc.Party = req.Party
+		if doc.Party == "" {
+			doc.Party = models.InvoicePartyBuyer
+		}
+	}
+
+	fields, provider, err := s.aiService.ExtractDocument(ctx, doc.DocType, data, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	normalizeDocumentFields(fields)
+	doc.Fields = fields
+	doc.Provider = provider
+
+	if err := s.docRepo.Create(doc); err != nil {
+		return nil, err
+	}
+	return s.response(doc), nil
+}
+
+// Get 查看识别结果和确认后将要写入的内容
+func (s *DocumentService) Get(userID, id uint64) (*dto.DocumentExtractionResponse, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.response(doc), nil
+}
+
+// List 用户最近的识别结果，可按客户和状态过滤
+func (s *DocumentService) List(userID, customerID uint64, status string, limit int) ([]*models.DocumentExtraction, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.docRepo.List(userID, customerID, status, limit)
+}
+
+// Confirm 按用户修正后的字段确认：合同创建成交记录，发票更新客户开票信息
+func (s *DocumentService) Confirm(userID, id uint64, req *dto.ConfirmDocumentRequest) (*dto.ConfirmDocumentResponse, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Status != models.DocumentDraft {
+		return nil, ErrDocumentNotDraft
+	}
+
+	for name, value := range req.Fields {
+		if _, ok := doc.Fields[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownDocumentField, name)
+		}
+		doc.Fields[name] = models.DocumentField{Value: strings.TrimSpace(value), Confidence: 1}
+	}
+	normalizeDocumentFields(doc.Fields)
+	if req.Party != "" && doc.DocType == models.DocumentVATInvoice {
+		doc.Party = req.Party
+	}
+
+	customerID := req.CustomerID
+	if customerID == 0 && doc.CustomerID != nil {
+		customerID = *doc.CustomerID
+	}
+	if customerID == 0 {
+		return nil, ErrDocumentCustomerRequired
+	}
+	if _, err := s.ownedCustomer(customerID, userID); err != nil {
+		return nil, err
+	}
+	doc.CustomerID = &customerID
+
+	resp := &dto.ConfirmDocumentResponse{Document: doc}
+	switch doc.DocType {
+	case models.DocumentContract:
+		draft := contractDealDraft(doc)
+		if draft.Amount <= 0 {
+			return nil, fmt.Errorf("%w: amount", ErrDocumentIncomplete)
+		}
+		deal, err := s.dealService.CreateDeal(userID, draft)
+		if err != nil {
+			return nil, err
+		}
+		doc.DealID = &deal.ID
+		resp.Deal = deal
+
+	case models.DocumentVATInvoice:
+		billing := invoiceBillingDraft(doc)
+		update := &dto.UpdateCustomerRequest{}
+		if billing.InvoiceTitle != "" {
+			update.InvoiceTitle = &billing.InvoiceTitle
+		}
+		if billing.TaxNumber != "" {
+			update.TaxNumber = &billing.TaxNumber
+		}
+		if billing.BankAccount != "" {
+			update.BankAccount = &billing.BankAccount
+		}
+		if update.InvoiceTitle == nil && update.TaxNumber == nil && update.BankAccount == nil {
+			return nil, fmt.Errorf("%w: %s_name, %s_tax_number or %s_bank_account", ErrDocumentIncomplete, doc.Party, doc.Party, doc.Party)
+		}
+		customer, err := s.customerService.UpdateCustomer(customerID, userID, update)
+		if err != nil {
+			return nil, err
+		}
+		resp.Customer = customer
+	}
+
+	now := time.Now()
+	doc.Status = models.DocumentConfirmed
+	doc.ConfirmedAt = &now
+	if err := s.docRepo.Update(doc); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Discard 放弃识别结果
+func (s *DocumentService) Discard(userID, id uint64) (*models.DocumentExtraction, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Status != models.DocumentDraft {
+		return nil, ErrDocumentNotDraft
+	}
+	doc.Status = models.DocumentDiscarded
+	if err := s.docRepo.Update(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// response 附上确认后将要写入的草稿和需要核对的字段
+func (s *DocumentService) response(doc *models.DocumentExtraction) *dto.DocumentExtractionResponse {
+	resp := &dto.DocumentExtractionResponse{DocumentExtraction: doc}
+	names := dto.ContractFields
+	required := []string{"amount"}
+	switch doc.DocType {
+	case models.DocumentContract:
+		resp.DealDraft = contractDealDraft(doc)
+		if doc.CustomerID != nil {
+			resp.DealDraft.CustomerID = *doc.CustomerID
+		}
+	case models.DocumentVATInvoice:
+		names = dto.VATInvoiceFields
+		required = []string{doc.Party + "_name", doc.Party + "_tax_number"}
+		resp.BillingDraft = invoiceBillingDraft(doc)
+		if doc.CustomerID != nil {
+			if customer, err := s.customerRepo.FindByID(*doc.CustomerID); err == nil {
+				resp.CurrentBilling = &dto.CustomerBilling{
+					InvoiceTitle: customer.InvoiceTitle,
+					TaxNumber:    customer.TaxNumber,
+					BankAccount:  customer.BankAccount,
+				}
+			}
+		}
+	}
+
+	resp.NeedsReview = []string{}
+	for _, name := range names {
+		f := doc.Fields[name]
+		if (f.Value == "" && containsString(required, name)) || (f.Value != "" && f.Confidence < documentReviewConfidence) {
+			resp.NeedsReview = append(resp.NeedsReview, name)
+		}
+	}
+	return resp
+}
+
+// contractDealDraft 合同字段映射为成交记录草稿
+func contractDealDraft(doc *models.DocumentExtraction) *dto.CreateDealRequest {
+	value := func(name string) string { return doc.Fields[name].Value }
+
+	draft := &dto.CreateDealRequest{
+		ProductOrService: value("subject"),
+		Currency:         value("currency"),
+		ContractNo:       value("contract_no"),
+		DealAt:           time.Now(),
+	}
+	if draft.ProductOrService == "" {
+		draft.ProductOrService = strings.TrimSpace("合同 " + draft.ContractNo)
+	}
+	if amount, ok := parseDocumentAmount(value("amount")); ok {
+		draft.Amount = amount
+	}
+	if signed, ok := parseDocumentDate(value("signing_date")); ok {
+		draft.SignedAt = &signed
+		draft.DealAt = signed
+	}
+
+	var notes []string
+	for _, f := range []struct{ label, name string }{
+		{"甲方", "party_a"}, {"乙方", "party_b"}, {"付款条款", "payment_terms"},
+	} {
+		if v := value(f.name); v != "" {
+			notes = append(notes, f.label+"："+v)
+		}
+	}
+	notes = append(notes, fmt.Sprintf("（合同识别 #%d）", doc.ID))
+	draft.Notes = strings.Join(notes, "\n")
+	return draft
+}
+
+// invoiceBillingDraft 发票中客户一方的名称、税号、开户行账号映射为开票信息
+func invoiceBillingDraft(doc *models.DocumentExtraction) *dto.CustomerBilling {
+	party := doc.Party
+	if party == "" {
+		party = models.InvoicePartyBuyer
+	}
+	return &dto.CustomerBilling{
+		InvoiceTitle: doc.Fields[party+"_name"].Value,
+		TaxNumber:    doc.Fields[party+"_tax_number"].Value,
+		BankAccount:  doc.Fields[party+"_bank_account"].Value,
+	}
+}
+
+// normalizeDocumentFields 统一金额、日期、税号、币种的格式；无法解析或前后矛盾的字段降低置信度并注明原因
+func normalizeDocumentFields(fields map[string]models.DocumentField) {
+	for name, f := range fields {
+		f.Note = ""
+		if f.Value == "" {
+			fields[name] = f
+			continue
+		}
+		switch name {
+		case "amount", "tax_amount", "total_amount":
+			if amount, ok := parseDocumentAmount(f.Value); ok {
+				f.Value = strconv.FormatFloat(amount, 'f', 2, 64)
+			} else {
+				lowerConfidence(&f, 0.3, "无法解析为金额")
+			}
+		case "signing_date", "invoice_date":
+			if date, ok := parseDocumentDate(f.Value); ok {
+				f.Value = date.Format("2006-01-02")
+			} else {
+				lowerConfidence(&f, 0.3, "无法解析为日期")
+			}
+		case "buyer_tax_number", "seller_tax_number":
+			f.Value = strings.ToUpper(strings.Join(strings.Fields(f.Value), ""))
+			if !taxNumberPattern.MatchString(f.Value) {
+				lowerConfidence(&f, 0.3, "纳税人识别号应为 15、17、18 或 20 位数字和大写字母")
+			}
+		case "currency":
+			switch strings.ToUpper(f.Value) {
+			case "人民币", "RMB", "¥", "￥", "元":
+				f.Value = "CNY"
+			default:
+				f.Value = strings.ToUpper(f.Value)
+			}
+		}
+		fields[name] = f
+	}
+
+	// 发票：合计金额 + 合计税额 应等于价税合计
+	amount, okAmount := parseDocumentAmount(fields["amount"].Value)
+	tax, okTax := parseDocumentAmount(fields["tax_amount"].Value)
+	total, okTotal := parseDocumentAmount(fields["total_amount"].Value)
+	if okAmount && okTax && okTotal && math.Abs(amount+tax-total) > 0.01 {
+		for _, name := range []string{"amount", "tax_amount", "total_amount"} {
+			f := fields[name]
+			lowerConfidence(&f, 0.5, "合计金额 + 合计税额 与价税合计不一致")
+			fields[name] = f
+		}
+	}
+}
+
+func lowerConfidence(f *models.DocumentField, max float64, note string) {
+	if f.Confidence > max {
+		f.Confidence = max
+	}
+	f.Note = note
+}
+
+// parseDocumentAmount 解析金额：去掉货币符号、千分位和单位，支持“万”
+func parseDocumentAmount(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	multiplier := 1.0
+	for _, unit := range []string{"万元", "万"} {
+		if strings.HasSuffix(s, unit) {
+			s, multiplier = strings.TrimSuffix(s, unit), 10000
+			break
+		}
+	}
+	s = strings.NewReplacer("¥", "", "￥", "", ",", "", "，", "", "元", "", "RMB", "", "CNY", "", " ", "").Replace(s)
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return math.Round(v*multiplier*100) / 100, true
+}
+
+// parseDocumentDate 解析合同和发票上常见的日期写法
+func parseDocumentDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range documentDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s *DocumentService) ownedDocument(id, userID uint64) (*models.DocumentExtraction, error) {
+	doc, err := s.docRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, err
+	}
+	if doc.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if doc.Fields == nil {
+		doc.Fields = make(map[string]models.DocumentField)
+	}
+	return doc, nil
+}
+
+func (s *DocumentService) ownedCustomer(id, userID uint64) (*models.Customer, error) {
+	customer, err := s.customerRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+	if customer.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return customer, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+)
+
+// DraftFollowUp 根据客户历史、未结清成交和团队风格指南生成跟进邮件 / 微信消息，同时返回使用的提示词版本
+func (s *AIService) DraftFollowUp(ctx context.Context, vars followUpPromptVars) (*dto.FollowUpContent, string, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureFollowUp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	messages, err := s.promptMessages(ctx, PromptFollowUpSystem, PromptFollowUpUser, vars)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var content dto.FollowUpContent
+	check := func() []string {
+		var problems []string
+		if strings.TrimSpace(content.Body) == "" {
+			problems = append(problems, "$.body: must not be empty")
+		}
+		if vars.Channel == models.FollowUpChannelEmail && strings.TrimSpace(content.Subject) == "" {
+			problems = append(problems, "$.subject: an email needs a subject")
+		}
+		return problems
+	}
+	if err := s.chatStructuredChecked(ctx, messages, dto.FollowUpContentSchema, &content, check); err != nil {
+		return nil, "", err
+	}
+	if vars.Channel == models.FollowUpChannelWechat {
+		content.Subject = ""
+	}
+	return &content, tracedPrompts(ctx), nil
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFollowUpDraftNotFound   = errors.New("follow-up draft not found")
+	ErrFollowUpDraftNotPending = errors.New("follow-up draft has already been sent or discarded")
+)
+
+const (
+	// 默认参考的跟进记录条数和上限
+	followUpDefaultInteractions = 5
+	followUpMaxInteractions     = 20
+	// 最多合并的风格指南条数和总字符数
+	styleGuideLimit = 5
+	styleGuideRunes = 3000
+)
+
+// FollowUpService 跟进消息草稿：结合客户历史生成邮件 / 微信消息，销售发出后保存为跟进记录
+type FollowUpService struct {
+	aiService          *AIService
+	draftRepo          *repository.FollowUpDraftRepository
+	customerRepo       *repository.CustomerRepository
+	interactionRepo    *repository.InteractionRepository
+	dealRepo           *repository.DealRepository
+	knowledgeRepo      *repository.KnowledgeRepository
+	interactionService *InteractionService
+}
+
+func NewFollowUpService(
+	aiService *AIService,
+	draftRepo *repository.FollowUpDraftRepository,
+	customerRepo *repository.CustomerRepository,
+	interactionRepo *repository.InteractionRepository,
+	dealRepo *repository.DealRepository,
+	knowledgeRepo *repository.KnowledgeRepository,
+	interactionService *InteractionService,
+) *FollowUpService {
+	return &FollowUpService{
+		aiService:          aiService,
+		draftRepo:          draftRepo,
+		customerRepo:       customerRepo,
+		interactionRepo:    interactionRepo,
+		dealRepo:           dealRepo,
+		knowledgeRepo:      knowledgeRepo,
+		interactionService: interactionService,
+	}
+}
+
+// Draft 生成跟进消息草稿并保存
+func (s *FollowUpService) Draft(ctx context.Context, userID, customerID uint64, req *dto.DraftFollowUpRequest) (*dto.FollowUpDraftResponse, error) {
+	customer, err := s.ownedCustomer(customerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tone := strings.TrimSpace(req.Tone)
+	if tone == "" {
+		tone = "professional"
+	}
+	language := strings.TrimSpace(req.Language)
+	if language == "" {
+		language = "zh"
+	}
+	limit := req.InteractionLimit
+	if limit <= 0 {
+		limit = followUpDefaultInteractions
+	}
+	if limit > followUpMaxInteractions {
+		limit = followUpMaxInteractions
+	}
+
+	interactions, err := s.interactionRepo.FindRecentByCustomerID(customerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	deal, err := s.openDeal(customerID, userID, req.DealID)
+	if err != nil {
+		return nil, err
+	}
+	guides, err := s.knowledgeRepo.FindTeamEntriesByType(userID, models.KnowledgeTypeStyleGuide, styleGuideLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := followUpPromptVars{
+		Customer:     customer,
+		Channel:      req.Channel,
+		Tone:         tone,
+		Language:     language,
+		Interactions: strings.Join(interactionLines(interactions), "\n"),
+		StyleGuide:   formatStyleGuides(guides),
+		Instructions: strings.TrimSpace(req.Instructions),
+		Today:        time.Now().Format("2006-01-02"),
+	}
+	if deal != nil {
+		vars.Deal = strings.Join(dealLines([]*models.Deal{deal}), "\n") +
+			fmt.Sprintf(", paid %.2f", deal.PaidAmount)
+	}
+
+	content, promptVersions, err := s.aiService.DraftFollowUp(ctx, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := &models.FollowUpDraft{
+		UserID:         userID,
+		CustomerID:     customerID,
+		Channel:        req.Channel,
+		Tone:           tone,
+		Language:       language,
+		Status:         models.FollowUpDraftPending,
+		Subject:        content.Subject,
+		Body:           content.Body,
+		Facts:          content.Facts,
+		PromptVersions: promptVersions,
+	}
+	if deal != nil {
+		draft.DealID = &deal.ID
+	}
+	for _, guide := range guides {
+		draft.StyleGuideIDs = append(draft.StyleGuideIDs, int64(guide.ID))
+	}
+
+	if err := s.draftRepo.Create(draft); err != nil {
+		return nil, err
+	}
+	return s.toResponse(draft), nil
+}
+
+// GetDraft 查看草稿
+func (s *FollowUpService) GetDraft(id, userID uint64) (*dto.FollowUpDraftResponse, error) {
+	draft, err := s.ownedDraft(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(draft), nil
+}
+
+// ListByCustomer 客户的跟进消息草稿，按时间倒序
+func (s *FollowUpService) ListByCustomer(customerID, userID uint64) ([]*dto.FollowUpDraftResponse, error) {
+	if _, err := s.ownedCustomer(customerID, userID); err != nil {
+		return nil, err
+	}
+	drafts, err := s.draftRepo.ListByCustomerID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*dto.FollowUpDraftResponse, len(drafts))
+	for i, draft := range drafts {
+		responses[i] = s.toResponse(draft)
+	}
+	return responses, nil
+}
+
+// MarkSent 销售发出消息后调用：按实际发出的内容创建跟进记录
+func (s *FollowUpService) MarkSent(id, userID uint64, req *dto.MarkFollowUpSentRequest) (*dto.InteractionResponse, error) {
+	draft, err := s.ownedDraft(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Status != models.FollowUpDraftPending {
+		return nil, ErrFollowUpDraftNotPending
+	}
+
+	if req.Subject != nil {
+		draft.Subject = *req.Subject
+	}
+	if req.Body != nil {
+		draft.Body = *req.Body
+	}
+	interaction := followUpInteraction(draft)
+	if req.Outcome != nil {
+		interaction.Outcome = *req.Outcome
+	}
+	if req.NextAction != nil {
+		interaction.NextAction = *req.NextAction
+	}
+	if req.NextDate != nil {
+		interaction.NextDate = req.NextDate
+	}
+
+	created, err := s.interactionService.CreateInteraction(userID, interaction)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	draft.Status = models.FollowUpDraftSent
+	draft.SentAt = &now
+	draft.InteractionID = &created.ID
+	if err := s.draftRepo.Update(draft); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Discard 放弃草稿
+func (s *FollowUpService) Discard(id, userID uint64) error {
+	draft, err := s.ownedDraft(id, userID)
+	if err != nil {
+		return err
+	}
+	if draft.Status != models.FollowUpDraftPending {
+		return ErrFollowUpDraftNotPending
+	}
+	draft.Status = models.FollowUpDraftDiscarded
+	return s.draftRepo.Update(draft)
+}
+
+// openDeal 指定了成交时校验归属，否则取最近一笔未结清的成交；没有时返回 nil
+func (s *FollowUpService) openDeal(customerID, userID uint64, dealID *uint64) (*models.Deal, error) {
+	if dealID != nil {
+		deal, err := s.dealRepo.FindByID(*dealID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrDealNotFound
+			}
+			return nil, err
+		}
+		if deal.CustomerID != customerID || deal.UserID != userID {
+			return nil, ErrDealNotFound
+		}
+		return deal, nil
+	}
+
+	deal, err := s.dealRepo.FindOpenByCustomerID(customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return deal, nil
+}
+
+func (s *FollowUpService) ownedCustomer(customerID, userID uint64) (*models.Customer, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+	if customer.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return customer, nil
+}
+
+func (s *FollowUpService) ownedDraft(id, userID uint64) (*models.FollowUpDraft, error) {
+	draft, err := s.draftRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFollowUpDraftNotFound
+		}
+		return nil, err
+	}
+	if draft.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return draft, nil
+}
+
+func (s *FollowUpService) toResponse(draft *models.FollowUpDraft) *dto.FollowUpDraftResponse {
+	resp := &dto.FollowUpDraftResponse{FollowUpDraft: draft}
+	if draft.Status == models.FollowUpDraftPending {
+		resp.Interaction = followUpInteraction(draft)
+	}
+	return resp
+}
+
+// followUpInteraction 发送后要创建的跟进记录；类型与渠道一致（email / wechat）
+func followUpInteraction(draft *models.FollowUpDraft) *dto.CreateInteractionRequest {
+	content := draft.Body
+	if draft.Subject != "" {
+		content = "【主题】" + draft.Subject + "\n" + draft.Body
+	}
+	return &dto.CreateInteractionRequest{
+		CustomerID: draft.CustomerID,
+		Type:       draft.Channel,
+		Content:    strings.TrimSpace(content),
+	}
+}
+
+// formatStyleGuides 合并团队风格指南，超出总长度时截断
+func formatStyleGuides(guides []*models.KnowledgeBase) string {
+	var sb strings.Builder
+	remaining := styleGuideRunes
+	for _, guide := range guides {
+		if remaining <= 0 {
+			break
+		}
+		section := truncateRunes(strings.TrimSpace("### "+guide.Title+"\n"+guide.Content), remaining)
+		sb.WriteString(section)
+		sb.WriteString("\n\n")
+		remaining -= len([]rune(section))
+	}
+	return strings.TrimSpace(sb.String())
+}
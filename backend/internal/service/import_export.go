@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"strings"
 
@@ -16,6 +17,12 @@ import (
 
 type ImportExportService struct {
 	customerRepo *repository.CustomerRepository
+	// 自定义字段：导入时按表头匹配并校验，导出时追加列；可为空
+	customFields *CustomFieldService
+	// 账户和联系人：导入的客户按公司名归入账户；可为空
+	accounts *AccountService
+	// 分群：可以只导出某个分群中的客户；可为空
+	segments *SegmentService
 }
 
 func NewImportExportService(customerRepo *repository.CustomerRepository) *ImportExportService {
@@ -24,6 +31,52 @@ func NewImportExportService(customerRepo *repository.CustomerRepository) *Import
 	}
 }
 
+// SetCustomFields enables custom field columns in imports and exports
+func (s *ImportExportService) SetCustomFields(customFields *CustomFieldService) {
+	s.customFields = customFields
+}
+
+// SetAccounts files imported customers under accounts by company name
+func (s *ImportExportService) SetAccounts(accounts *AccountService) {
+	s.accounts = accounts
+}
+
+// SetSegments allows exporting only the customers of a segment
+func (s *ImportExportService) SetSegments(segments *SegmentService) {
+	s.segments = segments
+}
+
+// exportCustomers 导出的客户：全部客户，或 segmentID 分群中的客户
+func (s *ImportExportService) exportCustomers(userID, segmentID uint64) ([]*models.Customer, error) {
+	query := &dto.CustomerQuery{
+		Page:    1,
+		PerPage: 10000, // Get all customers
+	}
+	if segmentID > 0 {
+		if s.segments == nil {
+			return nil, fmt.Errorf("%w: segments are not available", ErrInvalidSegment)
+		}
+		ids, err := s.segments.CustomerIDs(segmentID, userID)
+		if err != nil {
+			return nil, err
+		}
+		query.IDs = ids
+	}
+	customers, _, err := s.customerRepo.FindByUserID(userID, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch customers: %w", err)
+	}
+	return customers, nil
+}
+
+// customFieldDefinitions 导入 / 导出用到的客户自定义字段，未启用时为空
+func (s *ImportExportService) customFieldDefinitions(userID uint64) ([]*models.CustomFieldDefinition, error) {
+	if s.customFields == nil {
+		return nil, nil
+	}
+	return s.customFields.Definitions(userID, models.CustomFieldEntityCustomer)
+}
+
 // ImportCustomers imports customers from Excel or CSV file
 func (s *ImportExportService) ImportCustomers(userID uint64, file multipart.File, fileType string) (*dto.ImportResult, error) {
 	// Read file content
@@ -55,6 +108,7 @@ func (s *ImportExportService) ImportCustomers(userID uint64, file multipart.File
 				Stage:       row.Stage,
 				Source:      row.Source,
 				Notes:       row.Notes,
+				Extra:       row.Extra,
 			})
 		}
 	} else if fileType == "csv" {
@@ -77,12 +131,24 @@ func (s *ImportExportService) ImportCustomers(userID uint64, file multipart.File
 				Stage:       row.Stage,
 				Source:      row.Source,
 				Notes:       row.Notes,
+				Extra:       row.Extra,
 			})
 		}
 	} else {
 		return nil, fmt.Errorf("unsupported file type: %s", fileType)
 	}
 
+	// 固定列之后的表头按自定义字段的标签或 key 匹配（不区分大小写），匹配不上的列忽略
+	defs, err := s.customFieldDefinitions(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load custom fields: %w", err)
+	}
+	columns := make(map[string]string, 2*len(defs))
+	for _, def := range defs {
+		columns[strings.ToLower(def.Key)] = def.Key
+		columns[strings.ToLower(def.Label)] = def.Key
+	}
+
 	// Import customers
 	result := &dto.ImportResult{
 		Total: len(rows),
@@ -138,6 +204,40 @@ func (s *ImportExportService) ImportCustomers(userID uint64, file multipart.File
 			customer.Source = "Manual"
 		}
 
+		if s.customFields != nil {
+			input := make(map[string]interface{})
+			for header, cell := range row.Extra {
+				if key, ok := columns[strings.ToLower(header)]; ok && cell != "" {
+					input[key] = cell
+				}
+			}
+			values, err := s.customFields.Apply(userID, models.CustomFieldEntityCustomer, nil, input)
+			if err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, dto.ImportError{
+					Row:   row.RowNumber,
+					Name:  row.Name,
+					Error: fmt.Sprintf("自定义字段无效: %s", err.Error()),
+				})
+				continue
+			}
+			customer.CustomFields = values
+		}
+
+		if s.accounts != nil {
+			account, err := s.accounts.AccountForCustomer(customer, nil)
+			if err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, dto.ImportError{
+					Row:   row.RowNumber,
+					Name:  row.Name,
+					Error: fmt.Sprintf("创建账户失败: %s", err.Error()),
+				})
+				continue
+			}
+			customer.AccountID = &account.ID
+		}
+
 		if err := s.customerRepo.Create(customer); err != nil {
 			result.Failed++
 			result.Errors = append(result.Errors, dto.ImportError{
@@ -147,6 +247,11 @@ func (s *ImportExportService) ImportCustomers(userID uint64, file multipart.File
 			})
 			continue
 		}
+		if s.accounts != nil {
+			if err := s.accounts.AddCustomerContact(customer); err != nil {
+				log.Printf("Failed to add contact for imported customer %d: %v", customer.ID, err)
+			}
+		}
 
 		result.Imported++
 	}
@@ -154,15 +259,20 @@ func (s *ImportExportService) ImportCustomers(userID uint64, file multipart.File
 	return result, nil
 }
 
-// ExportCustomersToExcel exports customers to Excel file
-func (s *ImportExportService) ExportCustomersToExcel(userID uint64) ([]byte, string, error) {
-	// Get all customers for user
-	customers, _, err := s.customerRepo.FindByUserID(userID, &dto.CustomerQuery{
-		Page:    1,
-		PerPage: 10000, // Get all customers
-	})
+// ExportCustomersToExcel exports customers (all of them, or those in segmentID) to Excel file
+func (s *ImportExportService) ExportCustomersToExcel(userID, segmentID uint64) ([]byte, string, error) {
+	customers, err := s.exportCustomers(userID, segmentID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defs, err := s.customFieldDefinitions(userID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch customers: %w", err)
+		return nil, "", fmt.Errorf("failed to load custom fields: %w", err)
+	}
+	extraColumns := make([]excel.Column, len(defs))
+	for i, def := range defs {
+		extraColumns[i] = excel.Column{Key: "cf." + def.Key, Header: def.Label}
 	}
 
 	// Convert to map format for Excel writer
@@ -181,10 +291,13 @@ func (s *ImportExportService) ExportCustomersToExcel(userID uint64) ([]byte, str
 			"source":       customer.Source,
 			"notes":        customer.Notes,
 		}
+		for _, def := range defs {
+			data[i]["cf."+def.Key] = formatCustomFieldValue(customer.CustomFields[def.Key])
+		}
 	}
 
 	// Generate Excel file
-	fileData, err := excel.WriteCustomersToExcel(data)
+	fileData, err := excel.WriteCustomersToExcel(data, extraColumns...)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to generate Excel file: %w", err)
 	}
@@ -194,34 +307,39 @@ func (s *ImportExportService) ExportCustomersToExcel(userID uint64) ([]byte, str
 	return fileData, filename, nil
 }
 
-// ExportCustomersToCSV exports customers to CSV file
-func (s *ImportExportService) ExportCustomersToCSV(userID uint64) ([]byte, string, error) {
-	// Get all customers for user
-	customers, _, err := s.customerRepo.FindByUserID(userID, &dto.CustomerQuery{
-		Page:    1,
-		PerPage: 10000,
-	})
+// ExportCustomersToCSV exports customers (all of them, or those in segmentID) to CSV file
+func (s *ImportExportService) ExportCustomersToCSV(userID, segmentID uint64) ([]byte, string, error) {
+	customers, err := s.exportCustomers(userID, segmentID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to fetch customers: %w", err)
+		return nil, "", err
+	}
+
+	defs, err := s.customFieldDefinitions(userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load custom fields: %w", err)
 	}
 
 	// Generate CSV content
 	var csvContent strings.Builder
 
+	// Escape fields that contain commas, quotes or line breaks
+	escapeField := func(field string) string {
+		if strings.ContainsAny(field, ",\"\r\n") {
+			return fmt.Sprintf("\"%s\"", strings.ReplaceAll(field, "\"", "\"\""))
+		}
+		return field
+	}
+
 	// Write header
-	csvContent.WriteString("姓名,公司,职位,电话,邮箱,行业,预算,意向度,阶段,来源,备注\n")
+	csvContent.WriteString("姓名,公司,职位,电话,邮箱,行业,预算,意向度,阶段,来源,备注")
+	for _, def := range defs {
+		csvContent.WriteString("," + escapeField(def.Label))
+	}
+	csvContent.WriteString("\n")
 
 	// Write data rows
 	for _, customer := range customers {
-		// Escape fields that contain commas or quotes
-		escapeField := func(field string) string {
-			if strings.Contains(field, ",") || strings.Contains(field, "\"") {
-				return fmt.Sprintf("\"%s\"", strings.ReplaceAll(field, "\"", "\"\""))
-			}
-			return field
-		}
-
-		csvContent.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s\n",
+		csvContent.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s",
 			escapeField(customer.Name),
 			escapeField(customer.Company),
 			escapeField(customer.Position),
@@ -234,6 +352,10 @@ func (s *ImportExportService) ExportCustomersToCSV(userID uint64) ([]byte, strin
 			escapeField(customer.Source),
 			escapeField(customer.Notes),
 		))
+		for _, def := range defs {
+			csvContent.WriteString("," + escapeField(formatCustomFieldValue(customer.CustomFields[def.Key])))
+		}
+		csvContent.WriteString("\n")
 	}
 
 	fileData := []byte(csvContent.String())
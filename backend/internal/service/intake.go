@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIntakeSessionNotFound   = errors.New("intake session not found")
+	ErrIntakeSessionExpired    = errors.New("intake session has expired")
+	ErrIntakeSessionConfirmed  = errors.New("intake session has already been confirmed")
+	ErrIntakeNotReady          = errors.New("name, company and at least one of phone, email or wechat_id are required")
+	ErrIntakeNothingRecognized = errors.New("nothing was recognized from the upload")
+	ErrDuplicateCustomer       = errors.New("a matching customer already exists")
+)
+
+// DuplicateCustomerError 确认时发现的疑似重复客户，errors.Is(err, ErrDuplicateCustomer) 为真
+type DuplicateCustomerError struct {
+	Matches []*dto.CustomerResponse
+}
+
+func (e *DuplicateCustomerError) Error() string {
+	return fmt.Sprintf("%s (%d match(es)); confirm again with force to create anyway", ErrDuplicateCustomer, len(e.Matches))
+}
+
+func (e *DuplicateCustomerError) Unwrap() error {
+	return ErrDuplicateCustomer
+}
+
+// intakeCleanupInterval 清理过期会话的间隔
+const intakeCleanupInterval = 10 * time.Minute
+
+// IntakeService 服务端保存的新建客户对话：每轮把历史和已提取字段交给 AI，
+// 可中途上传名片或语音补充信息，用户确认后经 CustomerService 创建客户
+type IntakeService struct {
+	aiService       *AIService
+	sessionRepo     *repository.IntakeSessionRepository
+	customerRepo    *repository.CustomerRepository
+	customerService *CustomerService
+	ttl             time.Duration
+}
+
+func NewIntakeService(
+	aiService *AIService,
+	sessionRepo *repository.IntakeSessionRepository,
+	customerRepo *repository.CustomerRepository,
+	customerService *CustomerService,
+	ttl time.Duration,
+) *IntakeService {
+	return &IntakeService{
+		aiService:       aiService,
+		sessionRepo:     sessionRepo,
+		customerRepo:    customerRepo,
+		customerService: customerService,
+		ttl:             ttl,
+	}
+}
+
+// StartCleanup 定期删除过期且未确认的会话
+func (s *IntakeService) StartCleanup() {
+	go func() {
+		for {
+			time.Sleep(intakeCleanupInterval)
+			n, err := s.sessionRepo.DeleteExpired(time.Now())
+			if err != nil {
+				log.Printf("Failed to delete expired intake sessions: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Deleted %d expired intake sessions", n)
+			}
+		}
+	}()
+}
+
+// Start 开始会话；带了第一条消息时先完成一轮对话再保存，AI 调用失败不留下空会话
+func (s *IntakeService) Start(ctx context.Context, userID uint64, req *dto.StartIntakeSessionRequest) (*dto.IntakeSessionResponse, error) {
+	session := &models.IntakeSession{
+		UserID:   userID,
+		Status:   models.IntakeCollecting,
+		Messages: []models.IntakeMessage{},
+		Fields:   make(map[string]string),
+	}
+	mergeIntakeFields(session.Fields, req.Fields)
+
+	resp := &dto.IntakeSessionResponse{IntakeSession: session}
+	if content := strings.TrimSpace(req.Message); content != "" {
+		reply, err := s.turn(ctx, session, intakeUserMessage(content, models.IntakeSourceText), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp.Reply = reply
+	}
+	session.ExpiresAt = time.Now().Add(s.ttl)
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Send 用户发送一条消息
+func (s *IntakeService) Send(ctx context.Context, userID, id uint64, content string) (*dto.IntakeSessionResponse, error) {
+	return s.reply(ctx, userID, id, intakeUserMessage(content, models.IntakeSourceText), nil)
+}
+
+// SendStream 流式回复，回复文案逐段下发，会话状态在流结束后返回
+func (s *IntakeService) SendStream(ctx context.Context, userID, id uint64, content string, onDelta func(string) error) (*dto.IntakeSessionResponse, error) {
+	return s.reply(ctx, userID, id, intakeUserMessage(content, models.IntakeSourceText), onDelta)
+}
+
+// AttachBusinessCard 识别名片：识别出的字段只补充空缺项，与已收集的值不一致时由 AI 在回复中向用户确认
+func (s *IntakeService) AttachBusinessCard(ctx context.Context, userID, id uint64, imageData []byte) (*dto.IntakeSessionResponse, error) {
+	session, err := s.activeSession(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	card, err := s.aiService.RecognizeBusinessCard(ctx, imageData)
+	if err != nil {
+		return nil, err
+	}
+	cardFields := map[string]string{
+		"name":     card.Name,
+		"company":  card.Company,
+		"position": card.Position,
+		"phone":    card.Phone,
+		"email":    card.Email,
+		"address":  card.Address,
+	}
+
+	var sb strings.Builder
+	for _, key := range []string{"name", "company", "position", "phone", "email", "address"} {
+		if v := strings.TrimSpace(cardFields[key]); v != "" {
+			if session.Fields[key] == "" {
+				session.Fields[key] = v
+			}
+			fmt.Fprintf(&sb, "\n%s：%s", key, v)
+		}
+	}
+	if sb.Len() == 0 {
+		return nil, ErrIntakeNothingRecognized
+	}
+
+	content := "我上传了一张名片，识别结果：" + sb.String()
+	resp, err := s.continueSession(ctx, session, intakeUserMessage(content, models.IntakeSourceBusinessCard), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Card = card
+	return resp, nil
+}
+
+// AttachVoice 语音消息：转写后作为用户消息继续对话
+func (s *IntakeService) AttachVoice(ctx context.Context, userID, id uint64, audioData []byte, format, language string) (*dto.IntakeSessionResponse, error) {
+	session, err := s.activeSession(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transcript, err := s.aiService.SpeechToText(ctx, audioData, format, language)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(transcript.Text)
+	if text == "" {
+		return nil, ErrIntakeNothingRecognized
+	}
+
+	resp, err := s.continueSession(ctx, session, intakeUserMessage(text, models.IntakeSourceVoice), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Transcript = text
+	return resp, nil
+}
+
+// Get 查看会话
+func (s *IntakeService) Get(userID, id uint64) (*models.IntakeSession, error) {
+	session, err := s.ownedSession(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.IntakeConfirmed && time.Now().After(session.ExpiresAt) {
+		return nil, ErrIntakeSessionExpired
+	}
+	return session, nil
+}
+
+// List 用户未确认且未过期的会话
+func (s *IntakeService) List(userID uint64) ([]*models.IntakeSession, error) {
+	return s.sessionRepo.ListActive(userID, time.Now())
+}
+
+// Confirm 用户确认后创建客户；发现疑似重复的客户（见 CustomerRepository.FindDuplicates）时，
+// 除非 force 为 true，否则返回 DuplicateCustomerError
+func (s *IntakeService) Confirm(userID, id uint64, req *dto.ConfirmIntakeRequest) (*dto.ConfirmIntakeResponse, error) {
+	session, err := s.activeSession(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	mergeIntakeFields(session.Fields, req.Fields)
+	if !intakeFieldsReady(session.Fields) {
+		return nil, ErrIntakeNotReady
+	}
+
+	create := intakeCustomerRequest(session.Fields)
+	matches, err := s.customerRepo.FindDuplicates(userID, &models.Customer{
+		Name:     create.Name,
+		Company:  create.Company,
+		Phone:    create.Phone,
+		Email:    create.Email,
+		WechatID: create.WechatID,
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) > 0 && !req.Force {
+		dup := &DuplicateCustomerError{Matches: make([]*dto.CustomerResponse, len(matches))}
+		for i, m := range matches {
+			dup.Matches[i] = s.customerService.toResponse(&m.Customer)
+		}
+		return nil, dup
+	}
+
+	customer, err := s.customerService.CreateCustomer(userID, create)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session.Status = models.IntakeConfirmed
+	session.CustomerID = &customer.ID
+	session.ConfirmedAt = &now
+	if err := s.sessionRepo.Update(session); err != nil {
+		return nil, err
+	}
+	return &dto.ConfirmIntakeResponse{Session: session, Customer: customer}, nil
+}
+
+// Cancel 放弃会话；已确认的会话保留，作为客户的创建记录
+func (s *IntakeService) Cancel(userID, id uint64) error {
+	session, err := s.ownedSession(id, userID)
+	if err != nil {
+		return err
+	}
+	if session.Status == models.IntakeConfirmed {
+		return ErrIntakeSessionConfirmed
+	}
+	return s.sessionRepo.Delete(session.ID)
+}
+
+func (s *IntakeService) reply(ctx context.Context, userID, id uint64, msg models.IntakeMessage, onDelta func(string) error) (*dto.IntakeSessionResponse, error) {
+	session, err := s.activeSession(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.continueSession(ctx, session, msg, onDelta)
+}
+
+// continueSession 完成一轮对话并保存会话
+func (s *IntakeService) continueSession(ctx context.Context, session *models.IntakeSession, msg models.IntakeMessage, onDelta func(string) error) (*dto.IntakeSessionResponse, error) {
+	reply, err := s.turn(ctx, session, msg, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	session.ExpiresAt = time.Now().Add(s.ttl)
+	if err := s.sessionRepo.Update(session); err != nil {
+		return nil, err
+	}
+	return &dto.IntakeSessionResponse{IntakeSession: session, Reply: reply}, nil
+}
+
+// turn 追加用户消息，把完整历史和已收集字段交给 AI，再追加 AI 回复并更新字段和状态
+func (s *IntakeService) turn(ctx context.Context, session *models.IntakeSession, msg models.IntakeMessage, onDelta func(string) error) (string, error) {
+	messages := append(session.Messages, msg)
+	req := &dto.CustomerIntakeChatRequest{
+		Messages:      make([]dto.CustomerIntakeChatMessage, len(messages)),
+		CurrentFields: session.Fields,
+	}
+	for i, m := range messages {
+		req.Messages[i] = dto.CustomerIntakeChatMessage{Role: m.Role, Content: m.Content}
+	}
+
+	var resp *dto.CustomerIntakeChatResponse
+	var err error
+	if onDelta != nil {
+		resp, err = s.aiService.CustomerIntakeChatStream(ctx, req, onDelta)
+	} else {
+		resp, err = s.aiService.CustomerIntakeChat(ctx, req)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	session.Messages = append(messages, models.IntakeMessage{
+		Role:      "assistant",
+		Content:   resp.Reply,
+		CreatedAt: time.Now(),
+	})
+	if resp.ExtractedFields != nil {
+		session.Fields = resp.ExtractedFields
+	}
+	session.Status = resp.Status
+	session.Summary = resp.Summary
+	return resp.Reply, nil
+}
+
+func (s *IntakeService) ownedSession(id, userID uint64) (*models.IntakeSession, error) {
+	session, err := s.sessionRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIntakeSessionNotFound
+		}
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if session.Fields == nil {
+		session.Fields = make(map[string]string)
+	}
+	return session, nil
+}
+
+// activeSession 可以继续对话的会话：未确认且未过期
+func (s *IntakeService) activeSession(id, userID uint64) (*models.IntakeSession, error) {
+	session, err := s.ownedSession(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.IntakeConfirmed {
+		return nil, ErrIntakeSessionConfirmed
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrIntakeSessionExpired
+	}
+	return session, nil
+}
+
+func intakeUserMessage(content, source string) models.IntakeMessage {
+	return models.IntakeMessage{
+		Role:      "user",
+		Content:   strings.TrimSpace(content),
+		Source:    source,
+		CreatedAt: time.Now(),
+	}
+}
+
+// mergeIntakeFields 用户直接修改的字段覆盖已收集的值，空值表示清除
+func mergeIntakeFields(fields, updates map[string]string) {
+	for k, v := range updates {
+		if v = strings.TrimSpace(v); v != "" {
+			fields[k] = v
+		} else {
+			delete(fields, k)
+		}
+	}
+}
+
+// intakeCustomerRequest 会话字段转换为创建客户的请求
+func intakeCustomerRequest(fields map[string]string) *dto.CreateCustomerRequest {
+	return &dto.CreateCustomerRequest{
+		Name:        fields["name"],
+		Company:     fields["company"],
+		Position:    fields["position"],
+		Phone:       fields["phone"],
+		Email:       fields["email"],
+		WechatID:    fields["wechat_id"],
+		Address:     fields["address"],
+		Budget:      fields["budget"],
+		IntentLevel: fields["intent_level"],
+		Notes:       fields["notes"],
+		Source:      "AI Intake",
+	}
+}
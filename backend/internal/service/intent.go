@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIntentProposalNotFound   = errors.New("intent proposal not found")
+	ErrIntentProposalNotPending = errors.New("intent proposal has already been decided")
+)
+
+const (
+	// 汇总信号时参考的跟进记录范围
+	intentSignalWindow = 90 * 24 * time.Hour
+	intentSignalLimit  = 10
+	// 权重按时间衰减的半衰期（天）
+	intentHalfLifeDays = 30.0
+	// 评分变化小于该值且等级不变时不提建议
+	intentScoreThreshold = 10
+	// 单条信号提取的超时
+	signalTimeout = 2 * time.Minute
+)
+
+// IntentService 跟进记录保存后异步提取信号，按近期信号汇总出意向等级 / 潜力评分建议，客户负责人采纳后更新客户
+type IntentService struct {
+	aiService       *AIService
+	interactionRepo *repository.InteractionRepository
+	customerRepo    *repository.CustomerRepository
+	proposalRepo    *repository.IntentProposalRepository
+	activityRepo    *repository.ActivityRepository
+}
+
+func NewIntentService(
+	aiService *AIService,
+	interactionRepo *repository.InteractionRepository,
+	customerRepo *repository.CustomerRepository,
+	proposalRepo *repository.IntentProposalRepository,
+	activityRepo *repository.ActivityRepository,
+) *IntentService {
+	return &IntentService{
+		aiService:       aiService,
+		interactionRepo: interactionRepo,
+		customerRepo:    customerRepo,
+		proposalRepo:    proposalRepo,
+		activityRepo:    activityRepo,
+	}
+}
+
+// OnInteractionSaved 作为 InteractionService 的回调：后台提取信号并刷新建议，失败只记日志
+func (s *IntentService) OnInteractionSaved(interaction *models.Interaction) {
+	interactionID, userID := interaction.ID, interaction.UserID
+	go func() {
+		ctx, cancel := context.WithTimeout(WithAIUser(context.Background(), userID), signalTimeout)
+		defer cancel()
+		if _, err := s.AnalyzeInteraction(ctx, interactionID, userID); err != nil {
+			log.Printf("Failed to extract signals for interaction %d: %v", interactionID, err)
+		}
+	}()
+}
+
+// AnalyzeInteraction 提取（或重新提取）一条跟进记录的信号，并刷新客户的意向建议
+func (s *IntentService) AnalyzeInteraction(ctx context.Context, interactionID, userID uint64) (*models.InteractionSignals, error) {
+	interaction, err := s.interactionRepo.FindByID(interactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInteractionNotFound
+		}
+		return nil, err
+	}
+	if interaction.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	customer, err := s.customerRepo.FindByID(interaction.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	signals, err := s.aiService.ExtractSignals(ctx, customer, interaction)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.interactionRepo.UpdateSignals(interaction.ID, signals, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshProposal(customer); err != nil {
+		log.Printf("Failed to refresh intent proposal for customer %d: %v", customer.ID, err)
+	}
+	return signals, nil
+}
+
+// ListProposals 客户的意向建议，按时间倒序
+func (s *IntentService) ListProposals(customerID, userID uint64, limit int) ([]*models.IntentProposal, error) {
+	if _, err := s.ownedCustomer(customerID, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.proposalRepo.ListByCustomerID(customerID, limit)
+}
+
+// Accept 采纳建议：更新客户的意向等级和潜力评分，并记录动态
+func (s *IntentService) Accept(id, userID uint64) (*models.IntentProposal, error) {
+	proposal, err := s.pendingProposal(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := s.ownedCustomer(proposal.CustomerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.customerRepo.UpdateIntent(customer.ID, proposal.ProposedIntentLevel, proposal.ProposedPotentialScore); err != nil {
+		return nil, err
+	}
+	s.recordIntentChange(userID, customer, proposal)
+
+	return s.decide(proposal, models.IntentProposalAccepted)
+}
+
+// Reject 拒绝建议，客户保持不变
+func (s *IntentService) Reject(id, userID uint64) (*models.IntentProposal, error) {
+	proposal, err := s.pendingProposal(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decide(proposal, models.IntentProposalRejected)
+}
+
+// refreshProposal 按近期信号重新计算建议；与客户当前值差别不大时只作废旧建议
+func (s *IntentService) refreshProposal(customer *models.Customer) error {
+	interactions, err := s.interactionRepo.FindAnalyzedByCustomerID(customer.ID, time.Now().Add(-intentSignalWindow), intentSignalLimit)
+	if err != nil {
+		return err
+	}
+	if err := s.proposalRepo.SupersedePending(customer.ID); err != nil {
+		return err
+	}
+	if len(interactions) == 0 {
+		return nil
+	}
+
+	score, evidence := aggregateIntent(interactions, time.Now())
+	level := intentLevelForScore(score)
+	if level == customer.IntentLevel && absInt(score-customer.PotentialScore) < intentScoreThreshold {
+		return nil
+	}
+
+	return s.proposalRepo.Create(&models.IntentProposal{
+		UserID:                 customer.UserID,
+		CustomerID:             customer.ID,
+		Status:                 models.IntentProposalPending,
+		CurrentIntentLevel:     customer.IntentLevel,
+		ProposedIntentLevel:    level,
+		CurrentPotentialScore:  customer.PotentialScore,
+		ProposedPotentialScore: score,
+		Evidence:               evidence,
+	})
+}
+
+// aggregateIntent 逐条打分后按时间衰减加权平均，返回 0-100 的潜力评分和依据
+func aggregateIntent(interactions []*models.Interaction, now time.Time) (int, []models.IntentEvidence) {
+	var weighted, totalWeight float64
+	evidence := make([]models.IntentEvidence, 0, len(interactions))
+	for _, it := range interactions {
+		ageDays := now.Sub(it.CreatedAt).Hours() / 24
+		if ageDays < 0 {
+			ageDays = 0
+		}
+		weight := math.Pow(0.5, ageDays/intentHalfLifeDays)
+		score := signalScore(it.Signals)
+
+		weighted += weight * float64(score)
+		totalWeight += weight
+		evidence = append(evidence, models.IntentEvidence{
+			InteractionID: it.ID,
+			Date:          it.CreatedAt,
+			Type:          it.Type,
+			Score:         score,
+			Weight:        math.Round(weight*100) / 100,
+			Signals:       it.Signals,
+		})
+	}
+	if totalWeight == 0 {
+		return 50, evidence
+	}
+	return int(math.Round(weighted / totalWeight)), evidence
+}
+
+// signalScore 单条跟进的意向分：以 50 为中性，按意向、情绪、购买信号、异议、预算和时间线索加减
+func signalScore(signals *models.InteractionSignals) int {
+	if signals == nil {
+		return 50
+	}
+	score := 50
+	switch signals.IntentLevel {
+	case "High":
+		score += 15
+	case "Low":
+		score -= 15
+	}
+	switch signals.Sentiment {
+	case "positive":
+		score += 10
+	case "negative":
+		score -= 10
+	}
+	score += 5 * minInt(len(signals.BuyingSignals), 3)
+	score -= 4 * minInt(len(signals.Objections), 3)
+	if signals.Budget != "" {
+		score += 5
+	}
+	if signals.Timeline != "" {
+		score += 5
+	}
+	if score < 0 {
+		return 0
+	}
+	if score > 100 {
+		return 100
+	}
+	return score
+}
+
+// intentLevelForScore 潜力评分对应的意向等级
+func intentLevelForScore(score int) string {
+	switch {
+	case score >= 70:
+		return "High"
+	case score >= 40:
+		return "Medium"
+	default:
+		return "Low"
+	}
+}
+
+func (s *IntentService) recordIntentChange(userID uint64, customer *models.Customer, proposal *models.IntentProposal) {
+	customerID := customer.ID
+	activity := &models.Activity{
+		UserID:     userID,
+		CustomerID: &customerID,
+		ActionType: models.ActivityIntentChange,
+		EntityType: "customer",
+		EntityID:   &customerID,
+		Description: fmt.Sprintf("%s → %s, %d → %d",
+			customer.IntentLevel, proposal.ProposedIntentLevel,
+			customer.PotentialScore, proposal.ProposedPotentialScore),
+	}
+	if err := s.activityRepo.Create(activity); err != nil {
+		log.Printf("Failed to record intent change for customer %d: %v", customerID, err)
+	}
+}
+
+func (s *IntentService) decide(proposal *models.IntentProposal, status string) (*models.IntentProposal, error) {
+	now := time.Now()
+	proposal.Status = status
+	proposal.DecidedAt = &now
+	if err := s.proposalRepo.Update(proposal); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+func (s *IntentService) pendingProposal(id, userID uint64) (*models.IntentProposal, error) {
+	proposal, err := s.proposalRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIntentProposalNotFound
+		}
+		return nil, err
+	}
+	if proposal.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if proposal.Status != models.IntentProposalPending {
+		return nil, ErrIntentProposalNotPending
+	}
+	return proposal, nil
+}
+
+func (s *IntentService) ownedCustomer(customerID, userID uint64) (*models.Customer, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+	if customer.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return customer, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
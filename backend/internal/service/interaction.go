@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/xia/nextcrm/internal/dto"
@@ -17,6 +18,16 @@ var (
 type InteractionService struct {
 	interactionRepo *repository.InteractionRepository
 	customerRepo    *repository.CustomerRepository
+	// 内容新建或修改后回调（如 AI 提取跟进信号），需自行异步处理
+	observer func(*models.Interaction)
+	// 任意新建、修改或删除后回调（如让 AI 缓存失效）
+	changeObserver func(customerID uint64)
+	// 自定义字段校验，可为空（为空时忽略请求中的自定义字段）
+	customFields *CustomFieldService
+	// 账户和联系人，可为空（为空时不关联账户和联系人）
+	accounts *AccountService
+	// 保存的列表视图，可为空（为空时不支持 view_id）
+	views *ViewService
 }
 
 func NewInteractionService(
@@ -29,6 +40,43 @@ func NewInteractionService(
 	}
 }
 
+// SetObserver registers a callback invoked after an interaction's content is created or changed
+func (s *InteractionService) SetObserver(observer func(*models.Interaction)) {
+	s.observer = observer
+}
+
+// SetChangeObserver registers a callback invoked after any interaction of a customer is created, updated or deleted
+func (s *InteractionService) SetChangeObserver(observer func(customerID uint64)) {
+	s.changeObserver = observer
+}
+
+// SetCustomFields enables custom field validation, filtering and sorting for interactions
+func (s *InteractionService) SetCustomFields(customFields *CustomFieldService) {
+	s.customFields = customFields
+}
+
+// SetAccounts links interactions to the customer's account and to specific contacts
+func (s *InteractionService) SetAccounts(accounts *AccountService) {
+	s.accounts = accounts
+}
+
+// SetViews enables listing interactions through a saved view (view_id)
+func (s *InteractionService) SetViews(views *ViewService) {
+	s.views = views
+}
+
+func (s *InteractionService) notifyChange(customerID uint64) {
+	if s.changeObserver != nil {
+		s.changeObserver(customerID)
+	}
+}
+
+func (s *InteractionService) notify(interaction *models.Interaction) {
+	if s.observer != nil && strings.TrimSpace(interaction.Content) != "" {
+		s.observer(interaction)
+	}
+}
+
 // CreateInteraction creates a new interaction
 func (s *InteractionService) CreateInteraction(userID uint64, req *dto.CreateInteractionRequest) (*dto.InteractionResponse, error) {
 	// Verify customer belongs to user
@@ -49,12 +97,35 @@ func (s *InteractionService) CreateInteraction(userID uint64, req *dto.CreateInt
 		NextAction: req.NextAction,
 		NextDate:   req.NextDate,
 	}
+	if s.customFields != nil {
+		values, err := s.customFields.Apply(userID, models.CustomFieldEntityInteraction, nil, req.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		interaction.CustomFields = values
+	}
+	var contactIDs []uint64
+	if s.accounts != nil {
+		interaction.AccountID = customer.AccountID
+		if contactIDs, err = s.accounts.ContactsForRecord(customer, req.ContactIDs); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.interactionRepo.Create(interaction); err != nil {
 		return nil, err
 	}
+	if s.accounts != nil {
+		if err := s.accounts.LinkInteractionContacts(interaction.ID, contactIDs); err != nil {
+			return nil, err
+		}
+	}
+	s.notifyChange(interaction.CustomerID)
+	s.notify(interaction)
 
-	return s.toInteractionResponse(interaction, customer), nil
+	response := s.toInteractionResponse(interaction, customer)
+	response.ContactIDs = contactIDs
+	return response, nil
 }
 
 // GetInteractionByID retrieves an interaction by ID
@@ -70,11 +141,16 @@ func (s *InteractionService) GetInteractionByID(id, userID uint64) (*dto.Interac
 	}
 
 	customer, _ := s.customerRepo.FindByID(interaction.CustomerID)
-	return s.toInteractionResponse(interaction, customer), nil
+	response := s.toInteractionResponse(interaction, customer)
+	if err := s.fillContacts([]*dto.InteractionResponse{response}); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
-// GetInteractionsByCustomerID retrieves all interactions for a customer
-func (s *InteractionService) GetInteractionsByCustomerID(customerID, userID uint64) ([]*dto.InteractionResponse, error) {
+// GetInteractionsByCustomerID retrieves all interactions for a customer, optionally filtered and sorted
+// by custom fields, the filter DSL or a saved view
+func (s *InteractionService) GetInteractionsByCustomerID(customerID, userID uint64, query *dto.InteractionListQuery) ([]*dto.InteractionResponse, error) {
 	// Verify customer belongs to user
 	customer, err := s.customerRepo.FindByID(customerID)
 	if err != nil {
@@ -84,7 +160,19 @@ func (s *InteractionService) GetInteractionsByCustomerID(customerID, userID uint
 		return nil, ErrUnauthorized
 	}
 
-	interactions, err := s.interactionRepo.FindByCustomerIDAndUserID(customerID, userID)
+	if s.customFields != nil {
+		filters, sort, err := s.customFields.ParseQuery(userID, models.CustomFieldEntityInteraction,
+			query.CustomFields, query.SortBy, query.SortOrder != "asc")
+		if err != nil {
+			return nil, err
+		}
+		query.CustomFieldFilters, query.CustomFieldSort = filters, sort
+	}
+	if err := resolveListFilter(s.views, userID, "interactions", &query.ListFilter, query.SortBy, query.SortOrder != "asc"); err != nil {
+		return nil, err
+	}
+
+	interactions, err := s.interactionRepo.FindByCustomerIDAndUserID(customerID, userID, query)
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +181,9 @@ func (s *InteractionService) GetInteractionsByCustomerID(customerID, userID uint
 	for i, interaction := range interactions {
 		responses[i] = s.toInteractionResponse(interaction, customer)
 	}
+	if err := s.fillContacts(responses); err != nil {
+		return nil, err
+	}
 
 	return responses, nil
 }
@@ -113,8 +204,12 @@ func (s *InteractionService) UpdateInteraction(id, userID uint64, req *dto.Updat
 	if req.Type != "" {
 		interaction.Type = req.Type
 	}
-	if req.Content != "" {
+	contentChanged := req.Content != "" && req.Content != interaction.Content
+	if contentChanged {
 		interaction.Content = req.Content
+		// 旧信号已不对应新内容，等待重新提取
+		interaction.Signals = nil
+		interaction.SignalsAnalyzedAt = nil
 	}
 	if req.Outcome != "" {
 		interaction.Outcome = req.Outcome
@@ -125,13 +220,40 @@ func (s *InteractionService) UpdateInteraction(id, userID uint64, req *dto.Updat
 	if req.NextDate != nil {
 		interaction.NextDate = req.NextDate
 	}
+	if s.customFields != nil && req.CustomFields != nil {
+		values, err := s.customFields.Apply(userID, models.CustomFieldEntityInteraction, interaction.CustomFields, req.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		interaction.CustomFields = values
+	}
+	customer, _ := s.customerRepo.FindByID(interaction.CustomerID)
+	var contactIDs []uint64
+	relink := s.accounts != nil && req.ContactIDs != nil && customer != nil
+	if relink {
+		if contactIDs, err = s.accounts.ContactsForRecord(customer, req.ContactIDs); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.interactionRepo.Update(interaction); err != nil {
 		return nil, err
 	}
+	if relink {
+		if err := s.accounts.LinkInteractionContacts(interaction.ID, contactIDs); err != nil {
+			return nil, err
+		}
+	}
+	s.notifyChange(interaction.CustomerID)
+	if contentChanged {
+		s.notify(interaction)
+	}
 
-	customer, _ := s.customerRepo.FindByID(interaction.CustomerID)
-	return s.toInteractionResponse(interaction, customer), nil
+	response := s.toInteractionResponse(interaction, customer)
+	if err := s.fillContacts([]*dto.InteractionResponse{response}); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
 // DeleteInteraction deletes an interaction
@@ -146,7 +268,11 @@ func (s *InteractionService) DeleteInteraction(id, userID uint64) error {
 		return ErrUnauthorized
 	}
 
-	return s.interactionRepo.Delete(id)
+	if err := s.interactionRepo.Delete(id); err != nil {
+		return err
+	}
+	s.notifyChange(interaction.CustomerID)
+	return nil
 }
 
 // GetUpcomingInteractions retrieves upcoming interactions for a user
@@ -161,22 +287,48 @@ func (s *InteractionService) GetUpcomingInteractions(userID uint64, fromDate tim
 		customer, _ := s.customerRepo.FindByID(interaction.CustomerID)
 		responses[i] = s.toInteractionResponse(interaction, customer)
 	}
+	if err := s.fillContacts(responses); err != nil {
+		return nil, err
+	}
 
 	return responses, nil
 }
 
+// fillContacts 附上各跟进记录涉及的联系人
+func (s *InteractionService) fillContacts(responses []*dto.InteractionResponse) error {
+	if s.accounts == nil || len(responses) == 0 {
+		return nil
+	}
+	ids := make([]uint64, len(responses))
+	for i, r := range responses {
+		ids[i] = r.ID
+	}
+	contacts, err := s.accounts.InteractionContactIDs(ids)
+	if err != nil {
+		return err
+	}
+	for _, r := range responses {
+		r.ContactIDs = contacts[r.ID]
+	}
+	return nil
+}
+
 // Helper function to convert model to response
 func (s *InteractionService) toInteractionResponse(interaction *models.Interaction, customer *models.Customer) *dto.InteractionResponse {
 	response := &dto.InteractionResponse{
-		ID:         interaction.ID,
-		CustomerID: interaction.CustomerID,
-		Type:       interaction.Type,
-		Content:    interaction.Content,
-		Outcome:    interaction.Outcome,
-		NextAction: interaction.NextAction,
-		NextDate:   interaction.NextDate,
-		CreatedAt:  interaction.CreatedAt,
-		UpdatedAt:  interaction.UpdatedAt,
+		ID:                interaction.ID,
+		CustomerID:        interaction.CustomerID,
+		AccountID:         interaction.AccountID,
+		Type:              interaction.Type,
+		Content:           interaction.Content,
+		Outcome:           interaction.Outcome,
+		NextAction:        interaction.NextAction,
+		NextDate:          interaction.NextDate,
+		Signals:           interaction.Signals,
+		SignalsAnalyzedAt: interaction.SignalsAnalyzedAt,
+		CustomFields:      interaction.CustomFields,
+		CreatedAt:         interaction.CreatedAt,
+		UpdatedAt:         interaction.UpdatedAt,
 	}
 
 	if customer != nil {
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/xia/nextcrm/internal/dto"
 	"github.com/xia/nextcrm/internal/models"
 	"github.com/xia/nextcrm/internal/repository"
@@ -10,6 +12,8 @@ type KnowledgeService struct {
 	knowledgeRepo *repository.KnowledgeRepository
 	vectorRepo    *repository.VectorRepository
 	aiService     *AIService
+	// 保存的列表视图，可为空（为空时不支持 view_id）
+	views *ViewService
 }
 
 func NewKnowledgeService(
@@ -24,6 +28,11 @@ func NewKnowledgeService(
 	}
 }
 
+// SetViews enables listing knowledge entries through a saved view (view_id)
+func (s *KnowledgeService) SetViews(views *ViewService) {
+	s.views = views
+}
+
 // CreateKnowledge creates a new knowledge base entry
 func (s *KnowledgeService) CreateKnowledge(userID uint64, req *dto.CreateKnowledgeRequest) (*dto.KnowledgeResponse, error) {
 	knowledge := &models.KnowledgeBase{
@@ -40,7 +49,7 @@ func (s *KnowledgeService) CreateKnowledge(userID uint64, req *dto.CreateKnowled
 	}
 
 	// Generate embedding asynchronously
-	go s.generateEmbedding(knowledge.ID, req.Content)
+	go s.generateEmbedding(userID, knowledge.ID, req.Content)
 
 	return s.toResponse(knowledge), nil
 }
@@ -62,6 +71,10 @@ func (s *KnowledgeService) GetKnowledgeByID(id, userID uint64) (*dto.KnowledgeRe
 
 // ListKnowledge retrieves knowledge base entries with pagination
 func (s *KnowledgeService) ListKnowledge(userID uint64, query *dto.KnowledgeQuery) ([]*dto.KnowledgeResponse, int, int64, error) {
+	if err := resolveListFilter(s.views, userID, "knowledge", &query.ListFilter, query.SortBy, query.SortOrder != "asc"); err != nil {
+		return nil, 0, 0, err
+	}
+
 	knowledges, total, err := s.knowledgeRepo.FindByUserID(userID, query)
 	if err != nil {
 		return nil, 0, 0, err
@@ -93,6 +106,7 @@ func (s *KnowledgeService) UpdateKnowledge(id, userID uint64, req *dto.UpdateKno
 	}
 
 	// Update fields
+	previousContent := knowledge.Content
 	if req.Title != nil {
 		knowledge.Title = *req.Title
 	}
@@ -114,8 +128,9 @@ func (s *KnowledgeService) UpdateKnowledge(id, userID uint64, req *dto.UpdateKno
 	}
 
 	// Regenerate embedding if content changed
-	if req.Content != nil {
-		go s.generateEmbedding(knowledge.ID, *req.Content)
+	if req.Content != nil && *req.Content != previousContent {
+		s.aiService.ForgetEmbedding(previousContent)
+		go s.generateEmbedding(userID, knowledge.ID, *req.Content)
 	}
 
 	return s.toResponse(knowledge), nil
@@ -133,13 +148,17 @@ func (s *KnowledgeService) DeleteKnowledge(id, userID uint64) error {
 		return ErrUnauthorized
 	}
 
-	return s.knowledgeRepo.Delete(id)
+	if err := s.knowledgeRepo.Delete(id); err != nil {
+		return err
+	}
+	s.aiService.ForgetEmbedding(knowledge.Content)
+	return nil
 }
 
 // SearchKnowledge performs vector similarity search
 func (s *KnowledgeService) SearchKnowledge(userID uint64, req *dto.KnowledgeSearchRequest) ([]*dto.KnowledgeSearchResponse, error) {
 	// Generate embedding for search query
-	embeddingResp, err := s.aiService.GenerateEmbedding(req.Query)
+	embeddingResp, err := s.aiService.GenerateEmbedding(WithAIUser(context.Background(), userID), req.Query)
 	if err != nil {
 		return nil, err
 	}
@@ -173,8 +192,8 @@ func (s *KnowledgeService) SearchKnowledge(userID uint64, req *dto.KnowledgeSear
 }
 
 // generateEmbedding generates and stores embedding for knowledge
-func (s *KnowledgeService) generateEmbedding(id uint64, content string) {
-	embeddingResp, err := s.aiService.GenerateEmbedding(content)
+func (s *KnowledgeService) generateEmbedding(userID, id uint64, content string) {
+	embeddingResp, err := s.aiService.GenerateEmbedding(WithAIUser(context.Background(), userID), content)
 	if err != nil {
 		return
 	}
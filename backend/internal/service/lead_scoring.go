@@ -0,0 +1,476 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"github.com/xia/nextcrm/pkg/logreg"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotEnoughTrainingData    = errors.New("not enough won and lost customers to train a lead scoring model")
+	ErrLeadScoringModelNotFound = errors.New("lead scoring model has not been trained yet")
+	ErrLeadScoreNotFound        = errors.New("lead score not found")
+)
+
+const (
+	// 每个类别至少要有这么多训练样本才单独成为一个特征，其余归入「其他」
+	leadMinCategoryCount = 3
+	// 成交 / 流失各自至少需要的样本数
+	leadMinClassSamples = 5
+	// 近期跟进的统计窗口
+	leadRecentWindow = 30 * 24 * time.Hour
+)
+
+// 参与建模的类别特征（客户字段）
+var leadCategoricalFeatures = []string{"source", "industry", "company_scale"}
+
+// 参与建模的数值特征；计数和天数先取 log1p 再进模型
+var leadNumericFeatures = []string{
+	"interactions",
+	"interactions_30d",
+	"days_since_last_interaction",
+	"stage_changes_per_month",
+	"days_in_stage",
+	"customer_age_days",
+}
+
+type leadOutcome int
+
+const (
+	leadOpen leadOutcome = iota
+	leadWon
+	leadLost
+)
+
+// leadExample 一个客户及其结果；Cutoff 为特征的截止时间，成交 / 流失客户只用结果出现之前的数据，避免泄漏
+type leadExample struct {
+	customer *models.Customer
+	outcome  leadOutcome
+	cutoff   time.Time
+}
+
+// LeadScoringService 用自己历史上成交 / 流失的客户训练逻辑回归，给所有未结客户打分（完全本地计算，不调用 AI 厂商）
+type LeadScoringService struct {
+	repo       *repository.LeadScoringRepository
+	customers  *repository.CustomerRepository
+	lostAfter  time.Duration
+	minSamples int
+
+	mu sync.Mutex // 训练和打分串行执行
+}
+
+func NewLeadScoringService(
+	repo *repository.LeadScoringRepository,
+	customers *repository.CustomerRepository,
+	lostAfterDays int,
+	minSamples int,
+) *LeadScoringService {
+	if lostAfterDays <= 0 {
+		lostAfterDays = 180
+	}
+	if minSamples < 2*leadMinClassSamples {
+		minSamples = 2 * leadMinClassSamples
+	}
+	return &LeadScoringService{
+		repo:       repo,
+		customers:  customers,
+		lostAfter:  time.Duration(lostAfterDays) * 24 * time.Hour,
+		minSamples: minSamples,
+	}
+}
+
+// StartNightly 每天在指定小时（服务器本地时间）重新训练并给所有未结客户打分
+func (s *LeadScoringService) StartNightly(hour int) {
+	go func() {
+		for {
+			time.Sleep(time.Until(nextDailyRun(time.Now(), hour)))
+			model, scored, err := s.Run()
+			if err != nil {
+				log.Printf("Lead scoring run failed: %v", err)
+				continue
+			}
+			log.Printf("Lead scoring model %d trained on %d customers (holdout AUC %.3f), %d open customers scored",
+				model.ID, model.Samples, model.HoldoutAUC, scored)
+		}
+	}()
+}
+
+// nextDailyRun 下一次运行时间：今天或明天的 hour 点整
+func nextDailyRun(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Run 训练新模型并给所有未结客户重新打分，返回模型和打分的客户数
+func (s *LeadScoringService) Run() (*models.LeadScoringModel, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := s.repo.LoadData()
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	examples := s.classify(data, now)
+
+	model, err := s.train(data, examples, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.repo.CreateModel(model); err != nil {
+		return nil, 0, err
+	}
+
+	scores := s.score(model, data, examples, now)
+	if err := s.repo.ReplaceScores(scores); err != nil {
+		return nil, 0, err
+	}
+	return model, len(scores), nil
+}
+
+// LatestModel 当前使用的模型
+func (s *LeadScoringService) LatestModel() (*models.LeadScoringModel, error) {
+	model, err := s.repo.LatestModel()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadScoringModelNotFound
+		}
+		return nil, err
+	}
+	return model, nil
+}
+
+// GetScore 客户最新的模型评分
+func (s *LeadScoringService) GetScore(customerID, userID uint64) (*models.LeadScore, error) {
+	customer, err := s.customers.FindByID(customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+	if customer.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	score, err := s.repo.FindScoreByCustomerID(customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadScoreNotFound
+		}
+		return nil, err
+	}
+	return score, nil
+}
+
+// ListScores 当前用户的未结客户，按模型评分从高到低
+func (s *LeadScoringService) ListScores(userID uint64, limit int) ([]*models.LeadScore, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListScoresByUserID(userID, limit)
+}
+
+// classify 按结果给客户分类：
+// 有成交记录、阶段已成交或合同已签 → 成交；阶段为丢单、状态为流失，或超过 lostAfter 没有任何动静 → 流失；其余为未结
+func (s *LeadScoringService) classify(data *repository.LeadScoringData, now time.Time) []leadExample {
+	examples := make([]leadExample, 0, len(data.Customers))
+	for _, c := range data.Customers {
+		interactions := data.Interactions[c.ID]
+		stageChanges := data.StageChanges[c.ID]
+
+		lastActivity := c.CreatedAt
+		if n := len(interactions); n > 0 && interactions[n-1].After(lastActivity) {
+			lastActivity = interactions[n-1]
+		}
+		if n := len(stageChanges); n > 0 && stageChanges[n-1].CreatedAt.After(lastActivity) {
+			lastActivity = stageChanges[n-1].CreatedAt
+		}
+
+		ex := leadExample{customer: c, outcome: leadOpen, cutoff: now}
+		switch {
+		case !data.FirstDealAt[c.ID].IsZero():
+			ex.outcome, ex.cutoff = leadWon, data.FirstDealAt[c.ID]
+		case isWonStage(c.Stage) || c.ContractStatus == "Signed" || c.ContractStatus == "Active":
+			ex.outcome, ex.cutoff = leadWon, lastActivity
+			// 有阶段变更记录时以最后一次进入成交阶段的时间为准
+			for i := len(stageChanges) - 1; i >= 0; i-- {
+				if isWonStage(stageChangeTarget(stageChanges[i].Description)) {
+					ex.cutoff = stageChanges[i].CreatedAt
+					break
+				}
+			}
+		case isLostStage(c.Stage) || c.CustomerStatus == "流失" || now.Sub(lastActivity) > s.lostAfter:
+			ex.outcome, ex.cutoff = leadLost, lastActivity
+		}
+		examples = append(examples, ex)
+	}
+	return examples
+}
+
+// train 在成交 / 流失客户上训练；约 20% 样本留出评估（按客户 ID 固定划分），最终模型用全部样本训练
+func (s *LeadScoringService) train(data *repository.LeadScoringData, examples []leadExample, now time.Time) (*models.LeadScoringModel, error) {
+	var labelled []leadExample
+	positives := 0
+	for _, ex := range examples {
+		if ex.outcome == leadOpen {
+			continue
+		}
+		labelled = append(labelled, ex)
+		if ex.outcome == leadWon {
+			positives++
+		}
+	}
+	if len(labelled) < s.minSamples || positives < leadMinClassSamples || len(labelled)-positives < leadMinClassSamples {
+		return nil, ErrNotEnoughTrainingData
+	}
+
+	categories := leadCategories(labelled)
+	names := leadFeatureNames(categories)
+	X := make([][]float64, len(labelled))
+	y := make([]float64, len(labelled))
+	for i, ex := range labelled {
+		X[i], _ = leadFeatureVector(categories, data, ex)
+		if ex.outcome == leadWon {
+			y[i] = 1
+		}
+	}
+
+	opts := logreg.Options{Iterations: 1000, LearningRate: 0.1, L2: 0.01}
+	model := &models.LeadScoringModel{
+		Samples:    len(labelled),
+		Positives:  positives,
+		Categories: categories,
+		TrainedAt:  now,
+	}
+
+	var trainX, holdX [][]float64
+	var trainY, holdY []float64
+	holdPos := 0
+	for i, ex := range labelled {
+		if ex.customer.ID%5 == 0 {
+			holdX, holdY = append(holdX, X[i]), append(holdY, y[i])
+			holdPos += int(y[i])
+		} else {
+			trainX, trainY = append(trainX, X[i]), append(trainY, y[i])
+		}
+	}
+	// 留出集两类都有样本时才评估
+	if holdPos > 0 && holdPos < len(holdY) && len(trainY) > 0 {
+		evalModel, err := logreg.Train(names, trainX, trainY, opts)
+		if err != nil {
+			return nil, err
+		}
+		model.HoldoutSamples = len(holdY)
+		model.HoldoutAUC = roundTo(evalModel.AUC(holdX, holdY), 4)
+		model.HoldoutLogLoss = roundTo(evalModel.LogLoss(holdX, holdY), 4)
+	}
+
+	params, err := logreg.Train(names, X, y, opts)
+	if err != nil {
+		return nil, err
+	}
+	model.Params = params
+	return model, nil
+}
+
+// score 给所有未结客户打分，并拆出每个特征的贡献（类别特征的 one-hot 列合并为一项）
+func (s *LeadScoringService) score(model *models.LeadScoringModel, data *repository.LeadScoringData, examples []leadExample, now time.Time) []*models.LeadScore {
+	var scores []*models.LeadScore
+	for _, ex := range examples {
+		if ex.outcome != leadOpen {
+			continue
+		}
+		x, values := leadFeatureVector(model.Categories, data, ex)
+		probability := model.Params.Predict(x)
+		raw := model.Params.Contributions(x)
+
+		byFeature := make(map[string]float64)
+		for j, name := range model.Params.Features {
+			feature := name
+			if i := strings.Index(name, "="); i >= 0 {
+				feature = name[:i]
+			}
+			byFeature[feature] += raw[j]
+		}
+		contributions := make([]models.FeatureContribution, 0, len(byFeature))
+		for feature, contribution := range byFeature {
+			contributions = append(contributions, models.FeatureContribution{
+				Feature:      feature,
+				Value:        values[feature],
+				Contribution: roundTo(contribution, 3),
+			})
+		}
+		sort.Slice(contributions, func(i, j int) bool {
+			return math.Abs(contributions[i].Contribution) > math.Abs(contributions[j].Contribution)
+		})
+
+		scores = append(scores, &models.LeadScore{
+			CustomerID:    ex.customer.ID,
+			UserID:        ex.customer.UserID,
+			ModelID:       model.ID,
+			Score:         int(math.Round(probability * 100)),
+			Probability:   roundTo(probability, 4),
+			Contributions: contributions,
+			ScoredAt:      now,
+		})
+	}
+	return scores
+}
+
+// leadCategories 每个类别特征中样本足够多的取值
+func leadCategories(examples []leadExample) map[string][]string {
+	categories := make(map[string][]string, len(leadCategoricalFeatures))
+	for _, feature := range leadCategoricalFeatures {
+		counts := make(map[string]int)
+		for _, ex := range examples {
+			counts[leadCategoryValue(ex.customer, feature)]++
+		}
+		values := []string{}
+		for value, n := range counts {
+			if n >= leadMinCategoryCount {
+				values = append(values, value)
+			}
+		}
+		sort.Strings(values)
+		categories[feature] = values
+	}
+	return categories
+}
+
+// leadFeatureNames 特征列名：类别特征为「字段=取值」，顺序与 leadFeatureVector 一致
+func leadFeatureNames(categories map[string][]string) []string {
+	var names []string
+	for _, feature := range leadCategoricalFeatures {
+		for _, value := range categories[feature] {
+			names = append(names, feature+"="+value)
+		}
+	}
+	return append(names, leadNumericFeatures...)
+}
+
+// leadFeatureVector 客户在截止时间的特征向量，以及每个特征便于阅读的原始取值
+func leadFeatureVector(categories map[string][]string, data *repository.LeadScoringData, ex leadExample) ([]float64, map[string]string) {
+	c := ex.customer
+	values := make(map[string]string, len(leadCategoricalFeatures)+len(leadNumericFeatures))
+	var x []float64
+
+	for _, feature := range leadCategoricalFeatures {
+		value := leadCategoryValue(c, feature)
+		values[feature] = value
+		for _, known := range categories[feature] {
+			if value == known {
+				x = append(x, 1)
+			} else {
+				x = append(x, 0)
+			}
+		}
+	}
+
+	interactions, recent := 0, 0
+	lastInteraction := c.CreatedAt
+	for _, at := range data.Interactions[c.ID] {
+		if !at.Before(ex.cutoff) {
+			break
+		}
+		interactions++
+		if ex.cutoff.Sub(at) <= leadRecentWindow {
+			recent++
+		}
+		lastInteraction = at
+	}
+
+	stageChanges := 0
+	stageSince := c.CreatedAt
+	for _, ev := range data.StageChanges[c.ID] {
+		if !ev.CreatedAt.Before(ex.cutoff) {
+			break
+		}
+		stageChanges++
+		stageSince = ev.CreatedAt
+	}
+
+	ageDays := daysBetween(c.CreatedAt, ex.cutoff)
+	months := math.Max(ageDays/30, 1)
+	numeric := map[string]float64{
+		"interactions":                float64(interactions),
+		"interactions_30d":            float64(recent),
+		"days_since_last_interaction": math.Round(daysBetween(lastInteraction, ex.cutoff)),
+		"stage_changes_per_month":     roundTo(float64(stageChanges)/months, 2),
+		"days_in_stage":               math.Round(daysBetween(stageSince, ex.cutoff)),
+		"customer_age_days":           math.Round(ageDays),
+	}
+	for _, feature := range leadNumericFeatures {
+		v := numeric[feature]
+		values[feature] = formatFeatureValue(v)
+		if feature == "stage_changes_per_month" {
+			x = append(x, v)
+		} else {
+			x = append(x, math.Log1p(v))
+		}
+	}
+	return x, values
+}
+
+func leadCategoryValue(c *models.Customer, feature string) string {
+	var value string
+	switch feature {
+	case "source":
+		value = c.Source
+	case "industry":
+		value = c.Industry
+	case "company_scale":
+		value = c.CompanyScale
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// stageChangeTarget 阶段变更记录「旧阶段 → 新阶段」中的新阶段
+func stageChangeTarget(description string) string {
+	if i := strings.LastIndex(description, "→"); i >= 0 {
+		return strings.TrimSpace(description[i+len("→"):])
+	}
+	return strings.TrimSpace(description)
+}
+
+func isWonStage(stage string) bool {
+	return stage == "Closed Won" || stage == "Closed"
+}
+
+func isLostStage(stage string) bool {
+	return stage == "Closed Lost" || stage == "Lost"
+}
+
+func daysBetween(from, to time.Time) float64 {
+	days := to.Sub(from).Hours() / 24
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+func roundTo(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
+
+func formatFeatureValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
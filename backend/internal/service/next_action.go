@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+)
+
+// RankNextActions 在规则筛出的客户中排序并给出具体行动，同时返回使用的提示词版本；
+// candidates 为允许出现的客户 ID
+func (s *AIService) RankNextActions(ctx context.Context, vars nextActionPromptVars, candidates map[uint64]bool) ([]dto.NextActionSuggestion, string, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureNextAction)
+	if err != nil {
+		return nil, "", err
+	}
+
+	messages, err := s.promptMessages(ctx, PromptNextActionSystem, PromptNextActionUser, vars)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var plan dto.NextActionPlan
+	check := func() []string {
+		var problems []string
+		if len(plan.Actions) > vars.Limit {
+			problems = append(problems, fmt.Sprintf("$.actions: at most %d actions", vars.Limit))
+		}
+		seen := make(map[uint64]bool, len(plan.Actions))
+		for i, a := range plan.Actions {
+			if !candidates[a.CustomerID] {
+				problems = append(problems, fmt.Sprintf("$.actions[%d].customer_id: %d is not one of the flagged accounts", i, a.CustomerID))
+			} else if seen[a.CustomerID] {
+				problems = append(problems, fmt.Sprintf("$.actions[%d].customer_id: %d is used more than once", i, a.CustomerID))
+			}
+			seen[a.CustomerID] = true
+			if strings.TrimSpace(a.Action) == "" {
+				problems = append(problems, fmt.Sprintf("$.actions[%d].action: must not be empty", i))
+			}
+		}
+		return problems
+	}
+	if err := s.chatStructuredChecked(ctx, messages, dto.NextActionPlanSchema, &plan, check); err != nil {
+		return nil, "", err
+	}
+	return plan.Actions, tracedPrompts(ctx), nil
+}
@@ -0,0 +1,498 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNextActionNotFound   = errors.New("next action not found")
+	ErrNextActionNotPending = errors.New("next action has already been accepted, dismissed or expired")
+)
+
+const (
+	// 交给 AI 排序的候选客户数和清单最多的行动数
+	nextActionCandidateLimit = 15
+	nextActionPlanLimit      = 10
+	// 每个候选客户附带的跟进记录条数
+	nextActionHistoryLimit = 3
+	// 规则分低于该值的客户不进入候选
+	nextActionMinRuleScore = 20
+
+	// 规则阈值（天）
+	nextActionIdleDays        = 14
+	nextActionCloseDateDays   = 7
+	nextActionOverdueDays     = 30
+	nextActionRenewalDays     = 30
+	nextActionHighLeadScore   = 70
+	nextActionLeadScoresLimit = 500
+)
+
+// actionCandidate 规则筛出的客户：命中的信号（按权重从高到低）、说明和总分
+type actionCandidate struct {
+	customer *models.Customer
+	deal     *models.Deal // 超期未回款的成交
+	signals  []string
+	notes    []string
+	score    int
+}
+
+// ruleActions AI 不可用时，按最主要的信号给出的默认行动
+var ruleActions = map[string]struct{ action, actionType string }{
+	models.NextActionSignalOverduePayment: {"联系客户确认回款安排和时间", "call"},
+	models.NextActionSignalRenewal:        {"联系客户沟通续约方案", "meeting"},
+	models.NextActionSignalCloseDate:      {"与客户确认决策时间表，推动签约", "call"},
+	models.NextActionSignalIdle:           {"电话跟进，了解最新进展和决策进度", "call"},
+	models.NextActionSignalNoValue:        {"确认合同细节和金额", "call"},
+	models.NextActionSignalHighLeadScore:  {"安排会议，推进到下一阶段", "meeting"},
+	models.NextActionSignalLowIntent:      {"提供更有针对性的方案或案例", "email"},
+}
+
+// NextActionService 销售每日行动清单：规则筛出需要关注的客户，AI 结合近期历史排序并给出具体行动，
+// 销售采纳后生成待办（带下一步行动的跟进记录），采纳 / 忽略记录用于评估排序效果
+type NextActionService struct {
+	aiService          *AIService
+	actionRepo         *repository.NextActionRepository
+	customerRepo       *repository.CustomerRepository
+	interactionRepo    *repository.InteractionRepository
+	dealRepo           *repository.DealRepository
+	leadScoringRepo    *repository.LeadScoringRepository
+	interactionService *InteractionService
+
+	locks sync.Map // userID -> *sync.Mutex，同一用户的清单串行生成
+}
+
+func NewNextActionService(
+	aiService *AIService,
+	actionRepo *repository.NextActionRepository,
+	customerRepo *repository.CustomerRepository,
+	interactionRepo *repository.InteractionRepository,
+	dealRepo *repository.DealRepository,
+	leadScoringRepo *repository.LeadScoringRepository,
+	interactionService *InteractionService,
+) *NextActionService {
+	return &NextActionService{
+		aiService:          aiService,
+		actionRepo:         actionRepo,
+		customerRepo:       customerRepo,
+		interactionRepo:    interactionRepo,
+		dealRepo:           dealRepo,
+		leadScoringRepo:    leadScoringRepo,
+		interactionService: interactionService,
+	}
+}
+
+// Today 当天的行动清单，当天第一次查看时生成
+func (s *NextActionService) Today(ctx context.Context, userID uint64) ([]*dto.NextActionResponse, error) {
+	unlock := s.lock(userID)
+	defer unlock()
+
+	planDate := startOfDay(time.Now())
+	actions, err := s.actionRepo.ListByPlanDate(userID, planDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) == 0 {
+		if actions, err = s.generate(ctx, userID, planDate); err != nil {
+			return nil, err
+		}
+	}
+	return s.toResponses(actions), nil
+}
+
+// Refresh 重新生成当天清单：未处理的建议作废，已采纳 / 忽略的客户不再出现
+func (s *NextActionService) Refresh(ctx context.Context, userID uint64) ([]*dto.NextActionResponse, error) {
+	unlock := s.lock(userID)
+	defer unlock()
+
+	actions, err := s.generate(ctx, userID, startOfDay(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponses(actions), nil
+}
+
+// Accept 采纳建议：为客户创建带下一步行动和日期的跟进记录作为待办
+func (s *NextActionService) Accept(id, userID uint64, req *dto.AcceptNextActionRequest) (*dto.InteractionResponse, error) {
+	action, err := s.pendingAction(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Action != nil && strings.TrimSpace(*req.Action) != "" {
+		action.Action = strings.TrimSpace(*req.Action)
+	}
+	if req.DueDate != nil {
+		action.DueDate = req.DueDate
+	}
+
+	created, err := s.interactionService.CreateInteraction(userID, nextActionTask(action))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	action.Status = models.NextActionAccepted
+	action.InteractionID = &created.ID
+	action.DecidedAt = &now
+	if err := s.actionRepo.Update(action); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Dismiss 忽略建议
+func (s *NextActionService) Dismiss(id, userID uint64, reason string) error {
+	action, err := s.pendingAction(id, userID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	action.Status = models.NextActionDismissed
+	action.DismissReason = strings.TrimSpace(reason)
+	action.DecidedAt = &now
+	return s.actionRepo.Update(action)
+}
+
+// Report 最近 days 天建议的处理情况，按排名、来源和信号汇总
+func (s *NextActionService) Report(days int) (*dto.NextActionReport, error) {
+	if days <= 0 || days > 365 {
+		days = 30
+	}
+	since := startOfDay(time.Now()).AddDate(0, 0, -days)
+
+	report := &dto.NextActionReport{Since: since}
+	var err error
+	if report.ByRank, err = s.actionRepo.StatsByRank(since); err != nil {
+		return nil, err
+	}
+	if report.BySource, err = s.actionRepo.StatsBySource(since); err != nil {
+		return nil, err
+	}
+	if report.BySignal, err = s.actionRepo.StatsBySignal(since); err != nil {
+		return nil, err
+	}
+	for _, stats := range [][]*dto.NextActionStat{report.ByRank, report.BySource, report.BySignal} {
+		for _, stat := range stats {
+			if decided := stat.Accepted + stat.Dismissed; decided > 0 {
+				stat.AcceptRate = roundTo(float64(stat.Accepted)/float64(decided), 4)
+			}
+		}
+	}
+	return report, nil
+}
+
+// generate 作废当天未处理的建议，按规则筛选候选客户（跳过当天已处理的客户），交给 AI 排序；
+// AI 失败时退回纯规则排序。新建议排在当天已处理的建议之后
+func (s *NextActionService) generate(ctx context.Context, userID uint64, planDate time.Time) ([]*models.NextAction, error) {
+	if err := s.actionRepo.ExpirePending(userID, planDate); err != nil {
+		return nil, err
+	}
+	decided, err := s.actionRepo.ListByPlanDate(userID, planDate)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make(map[uint64]bool, len(decided))
+	for _, action := range decided {
+		exclude[action.CustomerID] = true
+	}
+
+	candidates, err := s.candidates(userID, time.Now(), exclude)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return decided, nil
+	}
+
+	actions, err := s.aiPlan(ctx, candidates, planDate)
+	if err != nil {
+		log.Printf("AI next actions failed for user %d, falling back to rules: %v", userID, err)
+		actions = rulePlan(candidates, planDate)
+	}
+	for _, action := range actions {
+		action.Rank += len(decided)
+	}
+	if err := s.actionRepo.CreateBatch(actions); err != nil {
+		return nil, err
+	}
+	return s.actionRepo.ListByPlanDate(userID, planDate)
+}
+
+// candidates 对用户的全部客户跑规则，按规则分取前 nextActionCandidateLimit 个
+func (s *NextActionService) candidates(userID uint64, now time.Time, exclude map[uint64]bool) ([]*actionCandidate, error) {
+	customers, err := s.customerRepo.FindAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	deals, err := s.dealRepo.FindUnpaidByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	unpaid := make(map[uint64][]*models.Deal)
+	for _, d := range deals {
+		unpaid[d.CustomerID] = append(unpaid[d.CustomerID], d)
+	}
+	scores, err := s.leadScoringRepo.ListScoresByUserID(userID, nextActionLeadScoresLimit)
+	if err != nil {
+		return nil, err
+	}
+	leadScores := make(map[uint64]int, len(scores))
+	for _, score := range scores {
+		leadScores[score.CustomerID] = score.Score
+	}
+
+	var candidates []*actionCandidate
+	for _, c := range customers {
+		if exclude[c.ID] {
+			continue
+		}
+		leadScore, scored := leadScores[c.ID]
+		if !scored {
+			leadScore = -1
+		}
+		if candidate := evaluateActionRules(c, unpaid[c.ID], leadScore, now); candidate.score >= nextActionMinRuleScore {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	if len(candidates) > nextActionCandidateLimit {
+		candidates = candidates[:nextActionCandidateLimit]
+	}
+	return candidates, nil
+}
+
+// evaluateActionRules 规则信号：长时间未跟进、预计成交日期临近、超期未回款、合同即将到期、
+// 谈判阶段未定金额、意向低、线索评分高；leadScore 为 -1 表示没有评分
+func evaluateActionRules(c *models.Customer, unpaid []*models.Deal, leadScore int, now time.Time) *actionCandidate {
+	candidate := &actionCandidate{customer: c}
+	type hit struct {
+		signal, note string
+		weight       int
+	}
+	var hits []hit
+	open := !isWonStage(c.Stage) && !isLostStage(c.Stage) && c.CustomerStatus != "流失"
+
+	if open {
+		lastContact := c.CreatedAt
+		if c.LastContact != nil {
+			lastContact = *c.LastContact
+		}
+		if idle := int(daysBetween(lastContact, now)); idle > nextActionIdleDays {
+			hits = append(hits, hit{models.NextActionSignalIdle,
+				fmt.Sprintf("no contact for %d days", idle), 20 + minInt(idle-nextActionIdleDays, 30)})
+		}
+		if c.ExpectedCloseDate != nil {
+			days := int(c.ExpectedCloseDate.Sub(startOfDay(now)).Hours() / 24)
+			switch {
+			case days < 0:
+				hits = append(hits, hit{models.NextActionSignalCloseDate,
+					fmt.Sprintf("expected close date %s passed %d days ago", c.ExpectedCloseDate.Format("2006-01-02"), -days), 35})
+			case days <= nextActionCloseDateDays:
+				hits = append(hits, hit{models.NextActionSignalCloseDate,
+					fmt.Sprintf("expected close date %s is in %d days", c.ExpectedCloseDate.Format("2006-01-02"), days), 30})
+			}
+		}
+		if c.Stage == "Negotiation" && strings.TrimSpace(c.ContractValue) == "" {
+			hits = append(hits, hit{models.NextActionSignalNoValue, "in negotiation without a contract value", 20})
+		}
+		if c.IntentLevel == "Low" {
+			hits = append(hits, hit{models.NextActionSignalLowIntent, "low intent", 10})
+		}
+		if leadScore >= nextActionHighLeadScore {
+			hits = append(hits, hit{models.NextActionSignalHighLeadScore,
+				fmt.Sprintf("lead scoring model gives %d/100", leadScore), 15})
+		}
+	}
+
+	var outstanding float64
+	for _, d := range unpaid {
+		if daysBetween(d.DealAt, now) <= nextActionOverdueDays {
+			continue
+		}
+		if candidate.deal == nil {
+			candidate.deal = d
+		}
+		outstanding += d.Amount - d.PaidAmount
+	}
+	if d := candidate.deal; d != nil {
+		hits = append(hits, hit{models.NextActionSignalOverduePayment,
+			fmt.Sprintf("deal %s (%s, %.2f %s) from %s is %s, outstanding %.2f",
+				d.RecordNo, d.ProductOrService, d.Amount, d.Currency, d.DealAt.Format("2006-01-02"), d.PaymentStatus, outstanding), 40})
+	}
+
+	if c.ContractEndDate != nil {
+		if days := int(c.ContractEndDate.Sub(startOfDay(now)).Hours() / 24); days >= 0 && days <= nextActionRenewalDays {
+			hits = append(hits, hit{models.NextActionSignalRenewal,
+				fmt.Sprintf("contract ends on %s (in %d days)", c.ContractEndDate.Format("2006-01-02"), days), 40})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].weight > hits[j].weight })
+	for _, h := range hits {
+		candidate.signals = append(candidate.signals, h.signal)
+		candidate.notes = append(candidate.notes, h.note)
+		candidate.score += h.weight
+	}
+	return candidate
+}
+
+// aiPlan 让 AI 排序并给出具体行动
+func (s *NextActionService) aiPlan(ctx context.Context, candidates []*actionCandidate, planDate time.Time) ([]*models.NextAction, error) {
+	byID := make(map[uint64]*actionCandidate, len(candidates))
+	allowed := make(map[uint64]bool, len(candidates))
+	blocks := make([]string, 0, len(candidates))
+	customers := make([]*models.Customer, 0, len(candidates))
+	for _, candidate := range candidates {
+		c := candidate.customer
+		byID[c.ID] = candidate
+		customers = append(customers, c)
+		allowed[c.ID] = true
+
+		interactions, err := s.interactionRepo.FindRecentByCustomerID(c.ID, nextActionHistoryLimit)
+		if err != nil {
+			return nil, err
+		}
+		block := fmt.Sprintf("[customer_id=%d] %s / %s, stage %s, intent %s", c.ID, c.Name, c.Company, c.Stage, c.IntentLevel)
+		if c.ContractValue != "" {
+			block += ", contract value " + c.ContractValue
+		}
+		block += "\nFlags: " + strings.Join(candidate.notes, "; ")
+		if lines := interactionLines(interactions); len(lines) > 0 {
+			block += "\nRecent interactions (newest first):\n" + strings.Join(lines, "\n")
+		} else {
+			block += "\nNo interactions recorded yet."
+		}
+		blocks = append(blocks, block)
+	}
+
+	suggestions, promptVersions, err := s.aiService.RankNextActions(ctx, nextActionPromptVars{
+		Candidates: strings.Join(blocks, "\n\n"),
+		Limit:      nextActionPlanLimit,
+		Today:      planDate.Format("2006-01-02"),
+		Customers:  customers,
+	}, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]*models.NextAction, 0, len(suggestions))
+	for i, suggestion := range suggestions {
+		candidate := byID[suggestion.CustomerID]
+		due := planDate.AddDate(0, 0, suggestion.DueInDays)
+		action := newNextAction(candidate, planDate, i+1)
+		action.Source = models.NextActionSourceAI
+		action.Priority = suggestion.Priority
+		action.Reason = strings.TrimSpace(suggestion.Reason)
+		action.Action = strings.TrimSpace(suggestion.Action)
+		action.ActionType = suggestion.ActionType
+		action.DueDate = &due
+		action.PromptVersions = promptVersions
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// rulePlan 纯规则清单：按规则分排序，行动取最主要信号的默认建议
+func rulePlan(candidates []*actionCandidate, planDate time.Time) []*models.NextAction {
+	if len(candidates) > nextActionPlanLimit {
+		candidates = candidates[:nextActionPlanLimit]
+	}
+	actions := make([]*models.NextAction, 0, len(candidates))
+	for i, candidate := range candidates {
+		action := newNextAction(candidate, planDate, i+1)
+		action.Source = models.NextActionSourceRules
+		action.Reason = strings.Join(candidate.notes, "; ")
+		action.Action = ruleActions[candidate.signals[0]].action
+		action.ActionType = ruleActions[candidate.signals[0]].actionType
+		switch {
+		case candidate.score >= 60:
+			action.Priority = "High"
+		case candidate.score >= 35:
+			action.Priority = "Medium"
+		default:
+			action.Priority = "Low"
+		}
+		due := planDate
+		action.DueDate = &due
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+func newNextAction(candidate *actionCandidate, planDate time.Time, rank int) *models.NextAction {
+	action := &models.NextAction{
+		UserID:     candidate.customer.UserID,
+		CustomerID: candidate.customer.ID,
+		PlanDate:   planDate,
+		Rank:       rank,
+		Signals:    candidate.signals,
+		RuleScore:  candidate.score,
+		Status:     models.NextActionPending,
+	}
+	if candidate.deal != nil {
+		action.DealID = &candidate.deal.ID
+	}
+	return action
+}
+
+func (s *NextActionService) pendingAction(id, userID uint64) (*models.NextAction, error) {
+	action, err := s.actionRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNextActionNotFound
+		}
+		return nil, err
+	}
+	if action.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if action.Status != models.NextActionPending {
+		return nil, ErrNextActionNotPending
+	}
+	return action, nil
+}
+
+func (s *NextActionService) lock(userID uint64) func() {
+	value, _ := s.locks.LoadOrStore(userID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+func (s *NextActionService) toResponses(actions []*models.NextAction) []*dto.NextActionResponse {
+	responses := make([]*dto.NextActionResponse, len(actions))
+	for i, action := range actions {
+		responses[i] = &dto.NextActionResponse{NextAction: action}
+		if action.Status == models.NextActionPending {
+			responses[i].Task = nextActionTask(action)
+		}
+	}
+	return responses
+}
+
+// nextActionTask 采纳后要创建的待办：不带内容的备注，下一步行动和日期即为建议
+// （内容为空，不会触发跟进信号提取）
+func nextActionTask(action *models.NextAction) *dto.CreateInteractionRequest {
+	return &dto.CreateInteractionRequest{
+		CustomerID: action.CustomerID,
+		Type:       "note",
+		NextAction: action.Action,
+		NextDate:   action.DueDate,
+	}
+}
+
+// startOfDay 当天零点（服务器本地时间）
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/pkg/llm"
+)
+
+// nlQueryPlan 模型输出：结构化查询 + 一句说明
+type nlQueryPlan struct {
+	dto.QuerySpec
+	Explanation string `json:"explanation"`
+}
+
+// NaturalLanguageQuery 把自然语言问题翻译成过滤树并执行。
+// 模型只能输出 QuerySpec，字段和操作符不合法时会带着校验问题让模型修复一次
+func (s *AIService) NaturalLanguageQuery(ctx context.Context, userID uint64, question string) (*dto.NaturalLanguageQueryResponse, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	system, err := s.prompts.Render(ctx, PromptQuerySystem, queryPromptVars{
+		Catalog: formatFilterCatalog(s.queries.Fields()),
+		Today:   time.Now().Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages := []llm.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: question},
+	}
+
+	var plan nlQueryPlan
+	check := func() []string {
+		if err := s.queries.Validate(&plan.QuerySpec); err != nil {
+			return []string{err.Error()}
+		}
+		return nil
+	}
+	if err := s.chatStructuredChecked(ctx, messages, dto.NaturalLanguageQuerySchema, &plan, check); err != nil {
+		return nil, err
+	}
+
+	result, err := s.queries.Run(userID, &plan.QuerySpec)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.NaturalLanguageQueryResponse{
+		Question:    question,
+		Explanation: plan.Explanation,
+		Query:       result.Query,
+		Total:       result.Total,
+		Results:     result.Results,
+	}, nil
+}
+
+// formatFilterCatalog 把字段目录写成提示词中的列表
+func formatFilterCatalog(catalog map[string][]dto.FilterFieldInfo) string {
+	entities := make([]string, 0, len(catalog))
+	for name := range catalog {
+		entities = append(entities, name)
+	}
+	sort.Strings(entities)
+
+	var sb strings.Builder
+	for _, entity := range entities {
+		sb.WriteString(entity)
+		sb.WriteString(":\n")
+		for _, f := range catalog[entity] {
+			fmt.Fprintf(&sb, "- %s (%s", f.Name, f.Type)
+			if len(f.Enum) > 0 {
+				sb.WriteString(": ")
+				sb.WriteString(strings.Join(f.Enum, "|"))
+			}
+			sb.WriteString(")")
+			if f.Description != "" {
+				sb.WriteString(" ")
+				sb.WriteString(f.Description)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"text/template"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrPromptNotFound = errors.New("prompt template not found")
+var ErrPromptVersionNotFound = errors.New("prompt template version not found")
+var ErrPromptInvalid = errors.New("invalid prompt template")
+
+// 模板来源
+const (
+	promptSourceBuiltin = "builtin"
+	promptSourceGlobal  = "global"
+	promptSourceTeam    = "team"
+)
+
+// resolvedPrompt 解析后的生效模板
+type resolvedPrompt struct {
+	tpl     *template.Template
+	version int
+	source  string
+	teamID  *uint64
+}
+
+// ref 记录到用量日志的模板版本，如 script.user@3、script.user@team2:1
+func (p *resolvedPrompt) ref(key string) string {
+	if p.source == promptSourceTeam {
+		return fmt.Sprintf("%s@team%d:%d", key, *p.teamID, p.version)
+	}
+	return fmt.Sprintf("%s@%d", key, p.version)
+}
+
+// PromptService 提示词模板注册表：内置模板为版本 0，数据库中按全局 / 团队保存新版本，
+// 生效顺序：团队固定版本 > 团队最新版本 > 全局固定版本 > 全局最新版本 > 内置模板
+type PromptService struct {
+	promptRepo *repository.PromptRepository
+	userRepo   *repository.UserRepository
+
+	mu    sync.RWMutex
+	cache map[string]*resolvedPrompt
+}
+
+func NewPromptService(promptRepo *repository.PromptRepository, userRepo *repository.UserRepository) *PromptService {
+	return &PromptService{
+		promptRepo: promptRepo,
+		userRepo:   userRepo,
+		cache:      make(map[string]*resolvedPrompt),
+	}
+}
+
+func parsePrompt(key, content string) (*template.Template, error) {
+	return template.New(key).Option("missingkey=error").Parse(content)
+}
+
+// Render 按 ctx 中用户所属团队渲染模板，并把使用的版本记到 ctx 的调用记录里
+func (s *PromptService) Render(ctx context.Context, key string, data interface{}) (string, error) {
+	var teamID *uint64
+	if userID := aiUserFrom(ctx); userID != 0 {
+		teamID, _ = s.userRepo.FindTeamID(userID)
+	}
+
+	p, err := s.resolve(key, teamID)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := p.tpl.Execute(&buf, data); err != nil {
+		if p.source == promptSourceBuiltin {
+			return "", err
+		}
+		// 自定义模板渲染失败时退回内置模板，避免影响业务
+		log.Printf("Prompt %s failed to render, falling back to builtin: %v", p.ref(key), err)
+		if p, err = s.builtin(key); err != nil {
+			return "", err
+		}
+		buf.Reset()
+		if err := p.tpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+	}
+
+	tracePrompt(ctx, p.ref(key))
+	return buf.String(), nil
+}
+
+func (s *PromptService) resolve(key string, teamID *uint64) (*resolvedPrompt, error) {
+	cacheKey := key
+	if teamID != nil {
+		cacheKey = fmt.Sprintf("%s|%d", key, *teamID)
+	}
+
+	s.mu.RLock()
+	p, ok := s.cache[cacheKey]
+	s.mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+
+	if teamID != nil {
+		p, err := s.resolveScope(key, teamID)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			p, err = s.resolve(key, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		s.store(cacheKey, p)
+		return p, nil
+	}
+
+	p, err := s.resolveScope(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		if p, err = s.builtin(key); err != nil {
+			return nil, err
+		}
+	}
+	s.store(cacheKey, p)
+	return p, nil
+}
+
+// resolveScope 查找某个范围（全局 / 团队）的生效版本，没有时返回 nil
+func (s *PromptService) resolveScope(key string, teamID *uint64) (*resolvedPrompt, error) {
+	var tpl *models.PromptTemplate
+	pin, err := s.promptRepo.FindPin(key, teamID)
+	switch {
+	case err == nil && pin.Version == 0:
+		return s.builtin(key)
+	case err == nil:
+		tpl, err = s.promptRepo.FindVersion(key, teamID, pin.Version)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		tpl, err = s.promptRepo.FindLatest(key, teamID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parsePrompt(key, tpl.Content)
+	if err != nil {
+		return nil, err
+	}
+	source := promptSourceGlobal
+	if teamID != nil {
+		source = promptSourceTeam
+	}
+	return &resolvedPrompt{tpl: parsed, version: tpl.Version, source: source, teamID: teamID}, nil
+}
+
+func (s *PromptService) builtin(key string) (*resolvedPrompt, error) {
+	b, ok := builtinPrompts[key]
+	if !ok {
+		return nil, ErrPromptNotFound
+	}
+	parsed, err := parsePrompt(key, b.Content)
+	if err != nil {
+		return nil, err
+	}
+	return &resolvedPrompt{tpl: parsed, version: 0, source: promptSourceBuiltin}, nil
+}
+
+func (s *PromptService) store(cacheKey string, p *resolvedPrompt) {
+	s.mu.Lock()
+	s.cache[cacheKey] = p
+	s.mu.Unlock()
+}
+
+// invalidate 模板变更后清空缓存
+func (s *PromptService) invalidate() {
+	s.mu.Lock()
+	s.cache = make(map[string]*resolvedPrompt)
+	s.mu.Unlock()
+}
+
+// ListPrompts 列出所有模板在全局或某团队下的生效情况
+func (s *PromptService) ListPrompts(teamID *uint64) ([]*dto.PromptSummary, error) {
+	keys := make([]string, 0, len(builtinPrompts))
+	for key := range builtinPrompts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]*dto.PromptSummary, 0, len(keys))
+	for _, key := range keys {
+		summary, err := s.summary(key, teamID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, summary)
+	}
+	return out, nil
+}
+
+func (s *PromptService) summary(key string, teamID *uint64) (*dto.PromptSummary, error) {
+	b, ok := builtinPrompts[key]
+	if !ok {
+		return nil, ErrPromptNotFound
+	}
+
+	p, err := s.resolve(key, teamID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &dto.PromptSummary{
+		Key:           key,
+		Description:   b.Description,
+		TeamID:        teamID,
+		ActiveVersion: p.version,
+		ActiveSource:  p.source,
+	}
+
+	if latest, err := s.promptRepo.FindLatest(key, teamID); err == nil {
+		summary.LatestVersion = latest.Version
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if _, err := s.promptRepo.FindPin(key, teamID); err == nil {
+		summary.Pinned = true
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// GetPrompt 返回模板详情和全局 / 团队下的所有版本
+func (s *PromptService) GetPrompt(key string, teamID *uint64) (*dto.PromptDetailResponse, error) {
+	summary, err := s.summary(key, teamID)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.promptRepo.ListVersions(key, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.PromptDetailResponse{
+		PromptSummary:  *summary,
+		BuiltinContent: builtinPrompts[key].Content,
+		Versions:       versions,
+	}, nil
+}
+
+// CreateVersion 校验模板语法和变量后保存为新版本；未固定版本时新版本立即生效
+func (s *PromptService) CreateVersion(userID uint64, key string, req *dto.CreatePromptVersionRequest) (*models.PromptTemplate, error) {
+	b, ok := builtinPrompts[key]
+	if !ok {
+		return nil, ErrPromptNotFound
+	}
+
+	parsed, err := parsePrompt(key, req.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPromptInvalid, err)
+	}
+	// 用示例数据试渲染，提前发现引用了不存在的变量
+	if err := parsed.Execute(&bytes.Buffer{}, b.Sample); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPromptInvalid, err)
+	}
+
+	tpl := &models.PromptTemplate{
+		Key:         key,
+		TeamID:      req.TeamID,
+		Content:     req.Content,
+		Description: req.Description,
+		CreatedBy:   userID,
+	}
+	if err := s.promptRepo.CreateVersion(tpl); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return tpl, nil
+}
+
+// Pin 固定到某个版本（回滚即固定到旧版本），0 表示内置模板
+func (s *PromptService) Pin(userID uint64, key string, req *dto.PinPromptRequest) error {
+	if _, ok := builtinPrompts[key]; !ok {
+		return ErrPromptNotFound
+	}
+	if req.Version > 0 {
+		if _, err := s.promptRepo.FindVersion(key, req.TeamID, req.Version); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPromptVersionNotFound
+			}
+			return err
+		}
+	}
+
+	err := s.promptRepo.SetPin(&models.PromptTemplatePin{
+		Key:      key,
+		TeamID:   req.TeamID,
+		Version:  req.Version,
+		PinnedBy: userID,
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// Unpin 取消固定，恢复使用最新版本
+func (s *PromptService) Unpin(key string, teamID *uint64) error {
+	if _, ok := builtinPrompts[key]; !ok {
+		return ErrPromptNotFound
+	}
+	if err := s.promptRepo.DeletePin(key, teamID); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/pkg/doubao"
+)
+
+// 提示词模板 key
+const (
+	PromptScriptSystem     = "script.system"
+	PromptScriptUser       = "script.user"
+	PromptScriptUserStream = "script.user_stream"
+	PromptAnalyzeSystem    = "analyze.system"
+	PromptAnalyzeUser      = "analyze.user"
+	PromptAnalyzeStream    = "analyze.user_stream"
+	PromptIntakeSystem     = "intake.system"
+	PromptBusinessCard     = "ocr.business_card"
+	PromptContractExtract  = "ocr.contract"
+	PromptInvoiceExtract   = "ocr.vat_invoice"
+	PromptStructuredRepair = "structured.repair"
+	PromptQuerySystem      = "query.system"
+	PromptCallSystem       = "call.system"
+	PromptCallUser         = "call.user"
+	PromptFollowUpSystem   = "followup.system"
+	PromptFollowUpUser     = "followup.user"
+	PromptSignalsSystem    = "signals.system"
+	PromptSignalsUser      = "signals.user"
+	PromptNextActionSystem = "nextaction.system"
+	PromptNextActionUser   = "nextaction.user"
+)
+
+// scriptPromptVars 话术生成模板变量
+type scriptPromptVars struct {
+	CustomerName string `redact:"name"`
+	Industry     string
+	Context      string
+	PainPoints   string
+	Scenario     string
+}
+
+// analyzePromptVars 客户分析模板变量，Customer 的所有字段都可在模板中引用
+type analyzePromptVars struct {
+	Customer     *models.Customer
+	AnalysisType string
+	History      string // 近期跟进、成交和阶段变更摘要，可能为空
+	CustomFields string `forbid:"custom_fields"` // 自定义字段，每行 "- 标签: 值"，可能为空
+}
+
+// intakePromptVars 新建客户对话模板变量
+type intakePromptVars struct {
+	CurrentFields string // 当前已收集字段的 JSON
+}
+
+// structuredRepairVars 结构化输出修复模板变量
+type structuredRepairVars struct {
+	Schema   string   // 期望的 JSON Schema
+	Problems []string // 上一次输出的校验问题
+}
+
+// queryPromptVars 自然语言查询模板变量
+type queryPromptVars struct {
+	Catalog string // 可查询实体和字段说明
+	Today   string // 当前日期，2006-01-02
+}
+
+// callPromptVars 通话总结模板变量
+type callPromptVars struct {
+	Customer   *models.Customer
+	Transcript string // 转写文本，区分说话人时每行为「说话人：内容」
+	Today      string // 当前日期，2006-01-02
+}
+
+// followUpPromptVars 跟进消息模板变量
+type followUpPromptVars struct {
+	Customer     *models.Customer
+	Channel      string // email, wechat
+	Tone         string
+	Language     string
+	Interactions string // 最近的跟进记录，可能为空
+	Deal         string // 未结清的成交，可能为空
+	StyleGuide   string // 团队风格指南，可能为空
+	Instructions string // 本次额外要求，可能为空
+	Today        string // 当前日期，2006-01-02
+}
+
+// signalsPromptVars 跟进信号提取模板变量
+type signalsPromptVars struct {
+	Customer    *models.Customer
+	Interaction *models.Interaction
+}
+
+// nextActionPromptVars 每日行动清单模板变量
+type nextActionPromptVars struct {
+	Candidates string             // 规则筛出的客户：信号、基本情况和近期历史
+	Limit      int                // 最多返回的行动数
+	Today      string             // 当前日期，2006-01-02
+	Customers  []*models.Customer // 候选客户，模板中不直接使用，用于脱敏时识别姓名等
+}
+
+// builtinPrompt 内置模板（版本 0），数据库中没有版本时使用；
+// Sample 用于校验管理员提交的模板能否正常渲染
+type builtinPrompt struct {
+	Description string
+	Content     string
+	Sample      interface{}
+}
+
+const scriptDetailsTemplate = `Generate a sales script with the following details:
+- Customer Name: {{.CustomerName}}
+- Industry: {{.Industry}}
+- Context: {{.Context}}
+- Pain Points: {{.PainPoints}}
+- Scenario: {{.Scenario}}`
+
+const analyzeDetailsTemplate = `Analyze the following customer:
+- Name: {{.Customer.Name}}
+- Company: {{.Customer.Company}}
+- Position: {{.Customer.Position}}
+- Industry: {{.Customer.Industry}}
+- Budget: {{.Customer.Budget}}
+- Intent Level: {{.Customer.IntentLevel}}
+- Stage: {{.Customer.Stage}}
+- Source: {{.Customer.Source}}
+- Contract Value: {{.Customer.ContractValue}}
+- Contract Status: {{.Customer.ContractStatus}}
+- Probability: {{.Customer.Probability}}%
+- Notes: {{.Customer.Notes}}
+{{if .CustomFields}}{{.CustomFields}}
+{{end}}{{if .History}}
+Recent history:
+{{.History}}
+{{end}}
+Analysis Type: {{.AnalysisType}}`
+
+// documentFieldRules 合同 / 发票识别共用的输出要求
+const documentFieldRules = `以JSON格式返回，每个字段都是 {"value": "识别结果", "confidence": 0到1之间的数字}，例如：
+{"contract_no": {"value": "HT-2024-001", "confidence": 0.95}}
+
+confidence 表示你对该字段的把握：文字清晰且有明确标注时接近 1，需要推断、字迹模糊或有涂改时降低。
+找不到的字段 value 为 ""，confidence 为 0。不要编造内容。
+
+只返回JSON，不要添加其他说明。`
+
+var builtinPrompts = map[string]builtinPrompt{
+	PromptScriptSystem: {
+		Description: "话术生成 system prompt",
+		Content: `You are an expert sales assistant. Generate professional sales scripts based on the provided context.
+The script should be:
+- Professional and friendly
+- Tailored to the customer's industry and pain points
+- Persuasive but not pushy
+- Structured with clear sections (opening, value proposition, handling objections, closing)`,
+	},
+	PromptScriptUser: {
+		Description: "话术生成 user prompt（JSON 输出）",
+		Content: scriptDetailsTemplate + `
+
+Please provide:
+1. A complete sales script
+2. Key talking points (3-5 bullet points)
+3. Tips for success (3-5 bullet points)
+
+Respond in JSON format:
+{
+  "script": "the complete script",
+  "key_points": ["point 1", "point 2", ...],
+  "tips": ["tip 1", "tip 2", ...]
+}`,
+		Sample: scriptPromptVars{},
+	},
+	PromptScriptUserStream: {
+		Description: "话术生成 user prompt（流式：正文 + 末尾 JSON 块）",
+		Content: scriptDetailsTemplate + `
+
+First write the complete sales script as plain text (no JSON, no code block).
+After the script, append a JSON code block with key talking points (3-5) and tips for success (3-5):
+` + "```json" + `
+{"key_points": ["point 1", ...], "tips": ["tip 1", ...]}
+` + "```",
+		Sample: scriptPromptVars{},
+	},
+	PromptAnalyzeSystem: {
+		Description: "客户分析 system prompt",
+		Content: `You are an expert sales analyst. Analyze customer data and provide actionable insights.
+Focus on: purchase intent, risk factors, opportunities, and specific recommendations.`,
+	},
+	PromptAnalyzeUser: {
+		Description: "客户分析 user prompt（JSON 输出）",
+		Content: analyzeDetailsTemplate + `
+
+Provide:
+1. A brief summary (2-3 sentences)
+2. Intent score (0-100)
+3. Risk level (low, medium, high)
+4. Key opportunities (3-5 bullet points)
+5. Specific recommendations (3-5 bullet points)
+6. Suggested next actions (3-5 bullet points)
+
+Respond in JSON format:
+{
+  "summary": "...",
+  "intent_score": 75,
+  "risk_level": "medium",
+  "opportunities": ["opportunity 1", ...],
+  "recommendations": ["recommendation 1", ...],
+  "next_actions": ["action 1", ...]
+}`,
+		Sample: analyzePromptVars{Customer: &models.Customer{}},
+	},
+	PromptAnalyzeStream: {
+		Description: "客户分析 user prompt（流式：摘要 + 末尾 JSON 块）",
+		Content: analyzeDetailsTemplate + `
+
+First write a brief summary (2-3 sentences) as plain text (no JSON, no code block).
+After the summary, append a JSON code block with intent score (0-100), risk level (low, medium, high),
+key opportunities (3-5), specific recommendations (3-5) and suggested next actions (3-5):
+` + "```json" + `
+{"intent_score": 75, "risk_level": "medium", "opportunities": ["..."], "recommendations": ["..."], "next_actions": ["..."]}
+` + "```",
+		Sample: analyzePromptVars{Customer: &models.Customer{}},
+	},
+	PromptIntakeSystem: {
+		Description: "新建客户对话 system prompt，{{.CurrentFields}} 为已收集字段 JSON",
+		Content: `你是「新建客户」助手，帮助用户快速完成客户信息录入。
+
+【必填项】姓名(name)、公司(company)
+【联系方式至少填一个】电话(phone)、邮箱(email)、微信号(wechat_id) - 三选一即可
+【选填项】职位(position)、预算(budget)、意向等级(intent_level: High/Medium/Low)、备注(notes)
+
+【工作流程】
+1. 用简短友好的中文引导用户，优先收集：姓名、公司、联系方式（电话/邮箱/微信号任选其一）
+2. 用户可能一次性说多条信息（如"张三，ABC科技公司，微信abc123"），请准确提取到对应字段
+3. 尽量在一次对话中收集所有信息（包括选填项），可以主动询问选填项
+4. **支持修改和补充**：用户可以说"把姓名改成李四"、"补充一下邮箱是xxx@xxx.com"、"电话错了，应该是13900139000"，请正确更新对应字段
+5. 当必填项（姓名、公司）和至少一种联系方式都收集完成后，生成一份信息总结，格式如下：
+
+━━━━━━━━━━━━━━━━━━
+📋 客户信息确认
+━━━━━━━━━━━━━━━━━━
+姓名：张三
+公司：ABC科技公司
+职位：CTO
+电话：13800138000
+邮箱：zhangsan@abc.com
+微信号：abc123
+预算：¥50,000
+意向等级：High
+备注：有意向采购CRM系统
+━━━━━━━━━━━━━━━━━━
+
+请确认以上信息是否正确？回复"确认"即可创建客户。
+
+6. 每次回复末尾都附加一个 JSON 代码块（用于系统处理，收集中也需要）：
+JSON格式示例：{"status":"ready_for_confirmation","name":"张三","company":"ABC科技公司","position":"CTO","phone":"13800138000","email":"zhangsan@abc.com","wechat_id":"abc123","budget":"¥50,000","intent_level":"High","notes":"有意向采购CRM系统"}
+
+【JSON 格式说明】
+- status: "collecting"（收集中）或 "ready_for_confirmation"（等待确认）
+- 当姓名、公司和至少一种联系方式（phone/email/wechat_id）都收集完成时，status 设为 "ready_for_confirmation"
+- 只填已确认的字段，未确认的留空字符串 ""` + "\n\n【当前已收集的字段】\n{{.CurrentFields}}",
+		Sample: intakePromptVars{CurrentFields: "{}"},
+	},
+	PromptBusinessCard: {
+		Description: "名片识别 prompt",
+		Content:     doubao.BusinessCardPrompt,
+	},
+	PromptContractExtract: {
+		Description: "合同识别 prompt（图片或 PDF），每个字段返回值和置信度",
+		Content: `请识别这份合同（可能有多页），提取以下字段：
+- contract_no: 合同编号
+- party_a: 甲方名称
+- party_b: 乙方名称
+- subject: 合同标的（产品或服务名称）
+- amount: 合同总金额，只保留数字，如 "128000.00"
+- currency: 币种代码，如 CNY、USD
+- signing_date: 签订日期，格式 2006-01-02
+- payment_terms: 付款条款摘要，一两句话
+
+` + documentFieldRules,
+	},
+	PromptInvoiceExtract: {
+		Description: "增值税发票识别 prompt（图片或 PDF），每个字段返回值和置信度",
+		Content: `请识别这张增值税发票（专用发票或普通发票，纸质或电子），提取以下字段：
+- invoice_type: 发票类型，如 增值税专用发票、增值税普通发票
+- invoice_code: 发票代码（全电发票没有代码时留空）
+- invoice_number: 发票号码
+- invoice_date: 开票日期，格式 2006-01-02
+- buyer_name: 购买方名称
+- buyer_tax_number: 购买方纳税人识别号
+- buyer_bank_account: 购买方开户行及账号
+- seller_name: 销售方名称
+- seller_tax_number: 销售方纳税人识别号
+- seller_bank_account: 销售方开户行及账号
+- amount: 合计金额（不含税），只保留数字
+- tax_amount: 合计税额，只保留数字
+- total_amount: 价税合计（小写），只保留数字
+
+` + documentFieldRules,
+	},
+	PromptQuerySystem: {
+		Description: "自然语言查询 system prompt，{{.Catalog}} 为可用字段，{{.Today}} 为当前日期",
+		Content: `You translate a CRM sales rep's question into a structured query. Never write SQL.
+Results are always limited to the rep's own records, so do not add owner or permission conditions.
+
+Entities and fields (name, type, allowed values, meaning):
+{{.Catalog}}
+
+Filter tree: a node is either a group {"and": [nodes]}, {"or": [nodes]}, {"not": node},
+or a condition {"field": "...", "op": "...", "value": ...}.
+Operators:
+- eq, ne: single value
+- gt, gte, lt, lte: number or time fields
+- between: [from, to] for number or time fields
+- in, not_in: array of values
+- contains: case-insensitive substring match on string fields
+- is_empty, not_empty: no value
+- older_than, within: time fields, value is a duration like "14d", "2w", "3m", "1y"
+- on tags fields, eq / ne mean has / lacks the tag, in / not_in mean has any / none of the tags
+Time values: "2006-01-02", "today", "now", or offsets such as "-7d" / "+1m".
+Use only listed fields and allowed values. Today is {{.Today}}.
+
+Respond with ONLY a JSON object:
+{"entity": "customers", "filter": <node>, "sort": [{"field": "...", "order": "asc"}], "limit": 50, "explanation": "用一句中文说明查询条件"}
+
+Example question: 高意向的SaaS客户里哪些两周没联系了
+Example answer:
+{"entity": "customers", "filter": {"and": [{"field": "intent_level", "op": "eq", "value": "High"}, {"field": "industry", "op": "contains", "value": "SaaS"}, {"or": [{"field": "last_contact", "op": "older_than", "value": "14d"}, {"field": "last_contact", "op": "is_empty"}]}]}, "sort": [{"field": "last_contact", "order": "asc"}], "limit": 50, "explanation": "意向等级为 High、行业包含 SaaS，且超过 14 天未联系（或从未联系）的客户"}`,
+		Sample: queryPromptVars{Catalog: "customers:\n- stage (string)", Today: "2026-01-01"},
+	},
+	PromptCallSystem: {
+		Description: "通话录音总结 system prompt",
+		Content: `You are an expert sales assistant reviewing a recorded sales call.
+Extract what matters for the CRM: a short summary, key points, the customer's objections,
+commitments made by either side, and concrete next steps. Judge the call outcome from the
+customer's attitude. Write all text in the language of the call. Do not invent facts that
+are not in the transcript.`,
+	},
+	PromptCallUser: {
+		Description: "通话录音总结 user prompt（JSON 输出），{{.Transcript}} 为转写文本",
+		Content: `Customer: {{.Customer.Name}} ({{.Customer.Company}}), stage {{.Customer.Stage}}
+Today is {{.Today}}.
+
+Call transcript:
+{{.Transcript}}
+
+Respond in JSON format:
+{
+  "summary": "2-3 sentences",
+  "key_points": ["..."],
+  "objections": ["..."],
+  "commitments": ["..."],
+  "next_steps": ["..."],
+  "outcome": "positive | neutral | negative",
+  "next_action": "the single most important follow-up action, or empty",
+  "next_date": "YYYY-MM-DD if a follow-up date was agreed or implied, otherwise empty"
+}`,
+		Sample: callPromptVars{Customer: &models.Customer{}},
+	},
+	PromptFollowUpSystem: {
+		Description: "跟进邮件 / 微信消息 system prompt",
+		Content: `You are a sales rep writing a personal follow-up message to a customer you already know.
+Ground the message in the CRM history you are given: mention at least one concrete fact
+(a date, a topic discussed, a quote, an open payment, an agreed next step) so it clearly
+continues the conversation. Never invent facts, prices, dates or promises that are not in
+the history. Keep it ready to send: no placeholders, no notes to the rep.
+When a team style guide is given, follow it over your own habits.`,
+	},
+	PromptFollowUpUser: {
+		Description: "跟进消息 user prompt（JSON 输出），{{.StyleGuide}} 为团队风格指南",
+		Content: `Write a follow-up {{if eq .Channel "wechat"}}WeChat message{{else}}email{{end}}.
+- Tone: {{.Tone}}
+- Language: {{.Language}}
+- Today: {{.Today}}
+
+Customer:
+- Name: {{.Customer.Name}}
+- Company: {{.Customer.Company}}
+- Position: {{.Customer.Position}}
+- Industry: {{.Customer.Industry}}
+- Stage: {{.Customer.Stage}}
+{{if .Deal}}
+Open deal:
+{{.Deal}}
+{{end}}{{if .Interactions}}
+Recent interactions (newest first):
+{{.Interactions}}
+{{end}}{{if .StyleGuide}}
+Team style guide:
+{{.StyleGuide}}
+{{end}}{{if .Instructions}}
+Additional instructions: {{.Instructions}}
+{{end}}
+{{if eq .Channel "wechat"}}A WeChat message is short (under 150 Chinese characters or 80 words), conversational, with no subject line and no email-style greeting or signature.{{else}}An email needs a specific subject line, a greeting, 2-4 short paragraphs and a sign-off.{{end}}
+
+Respond in JSON format:
+{
+  "subject": {{if eq .Channel "wechat"}}""{{else}}"email subject"{{end}},
+  "body": "the message, ready to send",
+  "facts": ["each fact from the history that the message refers to"]
+}`,
+		Sample: followUpPromptVars{Customer: &models.Customer{}, Channel: "email"},
+	},
+	PromptSignalsSystem: {
+		Description: "跟进记录情绪和意向信号提取 system prompt",
+		Content: `You analyze notes that sales reps write after talking to a customer and extract
+signals for the CRM. Only report what the note actually says or clearly implies; leave a
+field empty rather than guessing. Keep each item short and in the language of the note.
+Evidence must be short verbatim quotes from the note.`,
+	},
+	PromptSignalsUser: {
+		Description: "跟进记录信号提取 user prompt（JSON 输出），{{.Interaction}} 为跟进记录",
+		Content: `Customer: {{.Customer.Name}} ({{.Customer.Company}}), stage {{.Customer.Stage}}, current intent {{.Customer.IntentLevel}}
+Interaction ({{.Interaction.Type}}{{if .Interaction.Outcome}}, rep marked outcome {{.Interaction.Outcome}}{{end}}):
+{{.Interaction.Content}}{{if .Interaction.NextAction}}
+Next action: {{.Interaction.NextAction}}{{end}}
+
+Respond in JSON format:
+{
+  "sentiment": "positive | neutral | negative (the customer's attitude)",
+  "intent_level": "High | Medium | Low (buying intent shown in this interaction)",
+  "buying_signals": ["e.g. asked for a quote, involved decision maker, asked about onboarding"],
+  "objections": ["e.g. price too high, needs approval"],
+  "competitors": ["competitor names mentioned"],
+  "budget": "budget hint, or empty",
+  "timeline": "purchase or decision timeline hint, or empty",
+  "evidence": ["short quotes supporting the above"]
+}`,
+		Sample: signalsPromptVars{Customer: &models.Customer{}, Interaction: &models.Interaction{}},
+	},
+	PromptNextActionSystem: {
+		Description: "每日行动清单 system prompt",
+		Content: `You are a sales manager planning a rep's day. You get the accounts that the CRM rules
+flagged, with the reasons they were flagged and their recent history. Decide which accounts
+deserve attention today and what exactly the rep should do for each one.
+Each action must be concrete and specific to the account: who to contact, through which
+channel, and what to say, ask or send, grounded in the history. Avoid generic advice such as
+"keep in touch". Rank by expected impact on revenue and the cost of waiting.
+Write reason and action in Chinese unless the history is in another language.`,
+	},
+	PromptNextActionUser: {
+		Description: "每日行动清单 user prompt（JSON 输出），{{.Candidates}} 为规则筛出的客户",
+		Content: `Today is {{.Today}}.
+
+Flagged accounts:
+{{.Candidates}}
+
+Pick at most {{.Limit}} accounts, most important first. Skip an account only if nothing
+useful can be done today. Use each customer_id at most once, and only ids listed above.
+
+Respond in JSON format:
+{
+  "actions": [
+    {
+      "customer_id": 123,
+      "priority": "High | Medium | Low",
+      "reason": "why this account needs attention now, one sentence",
+      "action": "the concrete next step",
+      "action_type": "call | email | meeting | wechat | visit",
+      "due_in_days": 0
+    }
+  ]
+}`,
+		Sample: nextActionPromptVars{Limit: 10},
+	},
+	PromptStructuredRepair: {
+		Description: "结构化输出校验失败后的修复 prompt，{{.Schema}} 为 JSON Schema，{{.Problems}} 为校验问题列表",
+		Content: `Your previous response did not contain valid JSON matching the required schema.
+Problems:
+{{range .Problems}}- {{.}}
+{{end}}
+Respond again with ONLY a single JSON object (no prose, no code block) that conforms to this JSON Schema:
+{{.Schema}}`,
+		Sample: structuredRepairVars{Schema: "{}", Problems: []string{"$: expected object"}},
+	},
+}
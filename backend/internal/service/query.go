@@ -0,0 +1,49 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/repository"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// QueryService 执行结构化查询（过滤树），字段和操作符均经白名单校验
+type QueryService struct {
+	filterRepo *repository.FilterRepository
+}
+
+func NewQueryService(filterRepo *repository.FilterRepository) *QueryService {
+	return &QueryService{filterRepo: filterRepo}
+}
+
+// Fields 列出各实体可用于过滤和排序的字段
+func (s *QueryService) Fields() map[string][]dto.FilterFieldInfo {
+	return repository.FilterCatalog()
+}
+
+// Validate 校验查询并补全默认值
+func (s *QueryService) Validate(spec *dto.QuerySpec) error {
+	if err := repository.ValidateQuerySpec(spec); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return nil
+}
+
+// Run 校验并执行查询，只返回 userID 名下的记录
+func (s *QueryService) Run(userID uint64, spec *dto.QuerySpec) (*dto.QueryResultResponse, error) {
+	if err := s.Validate(spec); err != nil {
+		return nil, err
+	}
+	results, total, err := s.filterRepo.Query(spec, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.QueryResultResponse{
+		Query:   spec,
+		Total:   total,
+		Results: results,
+	}, nil
+}
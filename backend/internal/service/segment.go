@@ -0,0 +1,257 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSegmentNotFound = errors.New("segment not found")
+	ErrInvalidSegment  = errors.New("invalid segment")
+)
+
+// maxSegmentTargets 导出、批量操作时一次最多处理的客户数
+const maxSegmentTargets = 10000
+
+// SegmentService 客户分群：保存的过滤条件（可以用标签、距上次联系天数、成交总额等任意可过滤字段），
+// 每次使用时按当前数据计算。共享分群对同团队成员可见，按查看者自己的客户计算
+type SegmentService struct {
+	segmentRepo *repository.SegmentRepository
+	filterRepo  *repository.FilterRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewSegmentService(segmentRepo *repository.SegmentRepository, filterRepo *repository.FilterRepository, userRepo *repository.UserRepository) *SegmentService {
+	return &SegmentService{segmentRepo: segmentRepo, filterRepo: filterRepo, userRepo: userRepo}
+}
+
+// ListSegments 自己的和同团队共享的分群，带当前客户数
+func (s *SegmentService) ListSegments(userID uint64) ([]*dto.SegmentResponse, error) {
+	teamID, err := s.teamOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := s.segmentRepo.FindVisible(userID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*dto.SegmentResponse, 0, len(segments))
+	for _, segment := range segments {
+		resp, err := s.toResponse(segment, userID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, resp)
+	}
+	return out, nil
+}
+
+// GetSegment 分群详情和当前客户数
+func (s *SegmentService) GetSegment(id, userID uint64) (*dto.SegmentResponse, error) {
+	segment, err := s.findVisible(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(segment, userID)
+}
+
+// CreateSegment 保存分群
+func (s *SegmentService) CreateSegment(userID uint64, req *dto.CreateSegmentRequest) (*dto.SegmentResponse, error) {
+	teamID, err := s.teamOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	segment := &models.Segment{
+		UserID:      userID,
+		TeamID:      teamID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Shared:      req.Shared,
+	}
+	if segment.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidSegment)
+	}
+	if segment.Filter, err = encodeSegmentFilter(req.Filter); err != nil {
+		return nil, err
+	}
+	if err := s.segmentRepo.Create(segment); err != nil {
+		return nil, err
+	}
+	return s.toResponse(segment, userID)
+}
+
+// UpdateSegment 修改分群，只有创建人可以修改
+func (s *SegmentService) UpdateSegment(id, userID uint64, req *dto.UpdateSegmentRequest) (*dto.SegmentResponse, error) {
+	segment, err := s.findOwned(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		segment.Name = strings.TrimSpace(*req.Name)
+		if segment.Name == "" {
+			return nil, fmt.Errorf("%w: name is required", ErrInvalidSegment)
+		}
+	}
+	if req.Description != nil {
+		segment.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Filter != nil {
+		if segment.Filter, err = encodeSegmentFilter(req.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if req.Shared != nil {
+		segment.Shared = *req.Shared
+	}
+	// 共享范围跟随创建人当前所在的团队
+	if segment.TeamID, err = s.teamOf(userID); err != nil {
+		return nil, err
+	}
+	if err := s.segmentRepo.Update(segment); err != nil {
+		return nil, err
+	}
+	return s.toResponse(segment, userID)
+}
+
+// DeleteSegment 删除分群，只有创建人可以删除
+func (s *SegmentService) DeleteSegment(id, userID uint64) error {
+	if _, err := s.findOwned(id, userID); err != nil {
+		return err
+	}
+	return s.segmentRepo.Delete(id)
+}
+
+// Preview 保存前计算过滤条件匹配的客户数
+func (s *SegmentService) Preview(userID uint64, filter *dto.FilterNode) (int64, error) {
+	spec := &dto.QuerySpec{Entity: "customers", Filter: filter}
+	if err := repository.ValidateQuerySpec(spec); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidSegment, err)
+	}
+	return s.filterRepo.Count(spec, userID)
+}
+
+// Customers 分群中当前用户的客户，分页
+func (s *SegmentService) Customers(id, userID uint64, query *dto.SegmentCustomersQuery) (interface{}, int, int64, error) {
+	segment, err := s.findVisible(id, userID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	spec, err := segmentSpec(segment)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 || query.PerPage > 100 {
+		query.PerPage = 20
+	}
+
+	customers, total, err := s.filterRepo.QueryPage(spec, userID, (query.Page-1)*query.PerPage, query.PerPage)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	totalPages := int(total) / query.PerPage
+	if int(total)%query.PerPage > 0 {
+		totalPages++
+	}
+	return customers, totalPages, total, nil
+}
+
+// CustomerIDs 分群中当前用户的客户 ID（最多 maxSegmentTargets 个），供导出和批量操作使用
+func (s *SegmentService) CustomerIDs(id, userID uint64) ([]uint64, error) {
+	segment, err := s.findVisible(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := segmentSpec(segment)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterRepo.IDs(spec, userID, maxSegmentTargets)
+}
+
+func (s *SegmentService) toResponse(segment *models.Segment, userID uint64) (*dto.SegmentResponse, error) {
+	resp := &dto.SegmentResponse{Segment: segment, Editable: segment.UserID == userID}
+	spec, err := segmentSpec(segment)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count, err = s.filterRepo.Count(spec, userID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// findVisible 自己的分群，或同团队共享的分群
+func (s *SegmentService) findVisible(id, userID uint64) (*models.Segment, error) {
+	segment, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if segment.UserID == userID {
+		return segment, nil
+	}
+	teamID, err := s.teamOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !segment.Shared || segment.TeamID == nil || teamID == nil || *segment.TeamID != *teamID {
+		return nil, ErrUnauthorized
+	}
+	return segment, nil
+}
+
+func (s *SegmentService) findOwned(id, userID uint64) (*models.Segment, error) {
+	segment, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if segment.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return segment, nil
+}
+
+func (s *SegmentService) find(id uint64) (*models.Segment, error) {
+	segment, err := s.segmentRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSegmentNotFound
+		}
+		return nil, err
+	}
+	return segment, nil
+}
+
+func (s *SegmentService) teamOf(userID uint64) (*uint64, error) {
+	teamID, err := s.userRepo.FindTeamID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return teamID, nil
+}
+
+// encodeSegmentFilter 校验过滤树（按客户字段）并序列化保存
+func encodeSegmentFilter(filter *dto.FilterNode) (json.RawMessage, error) {
+	if err := repository.ValidateQuerySpec(&dto.QuerySpec{Entity: "customers", Filter: filter}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSegment, err)
+	}
+	return json.Marshal(filter)
+}
+
+// segmentSpec 分群对应的客户查询
+func segmentSpec(segment *models.Segment) (*dto.QuerySpec, error) {
+	var filter dto.FilterNode
+	if err := json.Unmarshal(segment.Filter, &filter); err != nil {
+		return nil, fmt.Errorf("%w: segment %d has a malformed filter: %v", ErrInvalidSegment, segment.ID, err)
+	}
+	return &dto.QuerySpec{Entity: "customers", Filter: &filter}, nil
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+)
+
+// ExtractSignals 从一条跟进记录中提取情绪、购买信号、异议、竞品、预算和时间线索
+func (s *AIService) ExtractSignals(ctx context.Context, customer *models.Customer, interaction *models.Interaction) (*models.InteractionSignals, error) {
+	ctx, err := s.begin(ctx, models.AIFeatureSignals)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.promptMessages(ctx, PromptSignalsSystem, PromptSignalsUser, signalsPromptVars{
+		Customer:    customer,
+		Interaction: interaction,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var signals models.InteractionSignals
+	if err := s.chatStructured(ctx, messages, dto.InteractionSignalsSchema, &signals); err != nil {
+		return nil, err
+	}
+	return &signals, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/xia/nextcrm/pkg/llm"
+	"github.com/xia/nextcrm/pkg/schema"
+)
+
+// ErrInvalidAIOutput 模型输出经一次修复重试后仍不符合约定的 JSON 结构
+var ErrInvalidAIOutput = errors.New("AI returned invalid structured output")
+
+// StructuredOutputError 记录校验失败的原始输出和问题，errors.Is(err, ErrInvalidAIOutput) 为真
+type StructuredOutputError struct {
+	Feature  string
+	Raw      string
+	Problems []string
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", ErrInvalidAIOutput, e.Feature, strings.Join(e.Problems, "; "))
+}
+
+func (e *StructuredOutputError) Unwrap() error {
+	return ErrInvalidAIOutput
+}
+
+// extractJSON 从模型回复中取出 JSON：优先取 ``` 代码块，否则取第一个 { 到最后一个 }
+func extractJSON(content string) string {
+	if _, block := splitJSONBlock(content); block != "" {
+		return block
+	}
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start == -1 || end <= start {
+		return ""
+	}
+	return content[start : end+1]
+}
+
+// decodeStructured 提取并校验 JSON，通过后解码到 out，再执行业务校验 check（可为 nil）；返回校验问题
+func decodeStructured(content string, sch *schema.Schema, out interface{}, check func() []string) []string {
+	jsonStr := extractJSON(content)
+	if jsonStr == "" {
+		return []string{"no JSON object found in response"}
+	}
+	if problems := sch.ValidateJSON([]byte(jsonStr)); len(problems) > 0 {
+		return problems
+	}
+	// 清空上一次解码的结果，避免修复重试时新旧字段混在一起
+	target := reflect.ValueOf(out).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	if err := json.Unmarshal([]byte(jsonStr), out); err != nil {
+		return []string{err.Error()}
+	}
+	if check != nil {
+		return check()
+	}
+	return nil
+}
+
+// chatStructured 以 JSON 模式调用对话模型，输出按 sch 校验后解码到 out，不合格时修复重试一次
+func (s *AIService) chatStructured(ctx context.Context, messages []llm.Message, sch *schema.Schema, out interface{}) error {
+	return s.chatStructuredChecked(ctx, messages, sch, out, nil)
+}
+
+// chatStructuredChecked 同 chatStructured，结构校验通过后再执行 check，其返回的问题同样触发修复重试
+func (s *AIService) chatStructuredChecked(ctx context.Context, messages []llm.Message, sch *schema.Schema, out interface{}, check func() []string) error {
+	resp, err := s.llmChat(ctx, &llm.ChatRequest{Messages: messages, JSONMode: true})
+	if err != nil {
+		return err
+	}
+	return s.ensureStructuredChecked(ctx, messages, resp.Content, sch, out, check)
+}
+
+// ensureStructured 校验已有回复 raw；不合格时把校验问题反馈给模型，以 JSON 模式重新请求一次
+func (s *AIService) ensureStructured(ctx context.Context, messages []llm.Message, raw string, sch *schema.Schema, out interface{}) error {
+	return s.ensureStructuredChecked(ctx, messages, raw, sch, out, nil)
+}
+
+func (s *AIService) ensureStructuredChecked(ctx context.Context, messages []llm.Message, raw string, sch *schema.Schema, out interface{}, check func() []string) error {
+	problems := decodeStructured(raw, sch, out, check)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	prompt, err := s.prompts.Render(ctx, PromptStructuredRepair, structuredRepairVars{
+		Schema:   sch.String(),
+		Problems: problems,
+	})
+	if err != nil {
+		return err
+	}
+	repair := make([]llm.Message, 0, len(messages)+2)
+	repair = append(repair, messages...)
+	repair = append(repair,
+		llm.Message{Role: "assistant", Content: raw},
+		llm.Message{Role: "user", Content: prompt},
+	)
+
+	resp, err := s.llmChat(ctx, &llm.ChatRequest{Messages: repair, JSONMode: true})
+	if err != nil {
+		return err
+	}
+	if problems := decodeStructured(resp.Content, sch, out, check); len(problems) > 0 {
+		return &StructuredOutputError{
+			Feature:  aiFeatureFrom(ctx),
+			Raw:      resp.Content,
+			Problems: problems,
+		}
+	}
+	return nil
+}
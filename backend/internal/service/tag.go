@@ -0,0 +1,486 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTagGroupNotFound = errors.New("tag group not found")
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrTagExists        = errors.New("tag already exists")
+	ErrInvalidTag       = errors.New("invalid tag")
+)
+
+const maxTagNameLength = 64
+
+// TagService 客户标签：管理员按团队维护标签分组和标签，销售给客户打标签（单个或批量）
+type TagService struct {
+	tagRepo  *repository.TagRepository
+	userRepo *repository.UserRepository
+	// 分群：批量打标签的目标可以是一个分群；可为空
+	segments *SegmentService
+	// 客户标签变化后回调（用于失效 AI 缓存）；可为空
+	changeObserver func(customerID uint64)
+}
+
+func NewTagService(tagRepo *repository.TagRepository, userRepo *repository.UserRepository) *TagService {
+	return &TagService{tagRepo: tagRepo, userRepo: userRepo}
+}
+
+// SetSegments allows bulk tagging all customers of a segment
+func (s *TagService) SetSegments(segments *SegmentService) {
+	s.segments = segments
+}
+
+// WithTx returns a copy of the service whose tag writes go through tx
+func (s *TagService) WithTx(tx *gorm.DB) *TagService {
+	copied := *s
+	copied.tagRepo = s.tagRepo.WithTx(tx)
+	return &copied
+}
+
+// SetChangeObserver registers a callback invoked after the tags of a customer change
+func (s *TagService) SetChangeObserver(observer func(customerID uint64)) {
+	s.changeObserver = observer
+}
+
+func (s *TagService) notifyChange(customerIDs ...uint64) {
+	if s.changeObserver == nil {
+		return
+	}
+	for _, id := range customerIDs {
+		s.changeObserver(id)
+	}
+}
+
+// ListGroups 管理端列出分组及其标签
+func (s *TagService) ListGroups(query *dto.TagGroupListQuery) ([]*dto.TagGroupWithTags, error) {
+	groups, err := s.tagRepo.ListGroups(query.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withTags(groups)
+}
+
+// CreateGroup 新建分组，同一范围内名称不能重复
+func (s *TagService) CreateGroup(userID uint64, req *dto.CreateTagGroupRequest) (*models.TagGroup, error) {
+	group := &models.TagGroup{
+		TeamID:    req.TeamID,
+		Name:      strings.TrimSpace(req.Name),
+		Color:     strings.TrimSpace(req.Color),
+		Exclusive: req.Exclusive,
+		Position:  req.Position,
+		CreatedBy: userID,
+	}
+	if err := s.checkGroupName(group, 0); err != nil {
+		return nil, err
+	}
+	if err := s.tagRepo.CreateGroup(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UpdateGroup 修改分组名称、颜色、互斥和顺序
+func (s *TagService) UpdateGroup(id uint64, req *dto.UpdateTagGroupRequest) (*models.TagGroup, error) {
+	group, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		group.Name = strings.TrimSpace(*req.Name)
+		if err := s.checkGroupName(group, group.ID); err != nil {
+			return nil, err
+		}
+	}
+	if req.Color != nil {
+		group.Color = strings.TrimSpace(*req.Color)
+	}
+	if req.Exclusive != nil {
+		group.Exclusive = *req.Exclusive
+	}
+	if req.Position != nil {
+		group.Position = *req.Position
+	}
+	if err := s.tagRepo.UpdateGroup(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeleteGroup 删除分组及其标签，客户上的这些标签一起去掉
+func (s *TagService) DeleteGroup(id uint64) error {
+	group, err := s.findGroup(id)
+	if err != nil {
+		return err
+	}
+	return s.tagRepo.DeleteGroup(group)
+}
+
+// CreateTag 在分组下新建标签，团队沿用分组的团队
+func (s *TagService) CreateTag(req *dto.CreateTagRequest) (*models.Tag, error) {
+	group, err := s.findGroup(req.GroupID)
+	if err != nil {
+		if errors.Is(err, ErrTagGroupNotFound) {
+			return nil, fmt.Errorf("%w: unknown group %d", ErrInvalidTag, req.GroupID)
+		}
+		return nil, err
+	}
+	tag := &models.Tag{
+		GroupID:  group.ID,
+		TeamID:   group.TeamID,
+		Name:     strings.TrimSpace(req.Name),
+		Color:    strings.TrimSpace(req.Color),
+		Position: req.Position,
+	}
+	if err := s.checkTagName(tag); err != nil {
+		return nil, err
+	}
+	if err := s.tagRepo.CreateTag(tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// UpdateTag 修改标签；改名时已打此标签的客户一起改
+func (s *TagService) UpdateTag(id uint64, req *dto.UpdateTagRequest) (*models.Tag, error) {
+	tag, err := s.findTag(id)
+	if err != nil {
+		return nil, err
+	}
+	oldName := tag.Name
+	if req.Name != nil {
+		tag.Name = strings.TrimSpace(*req.Name)
+		if err := s.checkTagName(tag); err != nil {
+			return nil, err
+		}
+	}
+	if req.Color != nil {
+		tag.Color = strings.TrimSpace(*req.Color)
+	}
+	if req.Position != nil {
+		tag.Position = *req.Position
+	}
+	if err := s.tagRepo.UpdateTag(tag, oldName); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// DeleteTag 删除标签，客户上的该标签一起去掉
+func (s *TagService) DeleteTag(id uint64) error {
+	tag, err := s.findTag(id)
+	if err != nil {
+		return err
+	}
+	return s.tagRepo.DeleteTag(tag)
+}
+
+// Catalog 用户可用的标签（全局 + 所在团队），按分组排列
+func (s *TagService) Catalog(userID uint64) ([]*dto.TagGroupWithTags, error) {
+	teamID, err := s.teamOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.tagRepo.FindEffectiveGroups(teamID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withTags(groups)
+}
+
+// Normalize 把标签名换成用户可用标签的规范写法并去重；未知标签或同一互斥分组中有多个标签时报错
+func (s *TagService) Normalize(userID uint64, names []string) ([]string, error) {
+	vocab, err := s.vocabulary(userID)
+	if err != nil {
+		return nil, err
+	}
+	out, err := vocab.resolve(names)
+	if err != nil {
+		return nil, err
+	}
+	if err := vocab.checkExclusive(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetCustomerTags 替换客户的全部标签；names 须已经过 Normalize
+func (s *TagService) SetCustomerTags(customerID uint64, names []string) error {
+	if err := s.tagRepo.SetCustomerTags(customerID, names); err != nil {
+		return err
+	}
+	s.notifyChange(customerID)
+	return nil
+}
+
+// BulkUpdate 给一批客户（customer_ids 或分群中的客户）加上 / 去掉标签。
+// 加上互斥分组中的标签时，同组的其他标签自动去掉；不属于当前用户的客户会被跳过
+func (s *TagService) BulkUpdate(userID uint64, req *dto.BulkTagRequest) (*dto.BulkTagResult, error) {
+	if (len(req.CustomerIDs) > 0) == (req.SegmentID > 0) {
+		return nil, fmt.Errorf("%w: exactly one of customer_ids and segment_id is required", ErrInvalidTag)
+	}
+	add, remove, err := s.planChange(userID, req.Add, req.Remove)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	if req.SegmentID > 0 {
+		if s.segments == nil {
+			return nil, fmt.Errorf("%w: segments are not available", ErrInvalidTag)
+		}
+		if ids, err = s.segments.CustomerIDs(req.SegmentID, userID); err != nil {
+			return nil, err
+		}
+	} else {
+		ids = uniqueIDs(req.CustomerIDs)
+		if len(ids) > maxSegmentTargets {
+			return nil, fmt.Errorf("%w: at most %d customers per request", ErrInvalidTag, maxSegmentTargets)
+		}
+	}
+
+	changed, err := s.applyChange(userID, ids, add, remove)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.BulkTagResult{Targeted: len(ids), Updated: int64(len(changed))}, nil
+}
+
+// applyChange 给用户的一批客户加上 / 去掉标签，add / remove 须已经过 planChange；返回标签有变化的客户 ID
+func (s *TagService) applyChange(userID uint64, customerIDs []uint64, add, remove []string) ([]uint64, error) {
+	changed, err := s.tagRepo.UpdateCustomerTags(userID, customerIDs, add, remove)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyChange(changed...)
+	return changed, nil
+}
+
+// planChange 把要加上 / 去掉的标签换成规范名称；加上互斥分组中的标签时，同组的其他标签也要去掉
+func (s *TagService) planChange(userID uint64, addNames, removeNames []string) ([]string, []string, error) {
+	if len(addNames) == 0 && len(removeNames) == 0 {
+		return nil, nil, fmt.Errorf("%w: nothing to add or remove", ErrInvalidTag)
+	}
+
+	vocab, err := s.vocabulary(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	add, err := vocab.resolve(addNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := vocab.checkExclusive(add); err != nil {
+		return nil, nil, err
+	}
+
+	// 要去掉的标签可以是已经不在标签库里的旧标签，原样去掉
+	removeSet := make(map[string]bool)
+	for _, name := range removeNames {
+		name = strings.TrimSpace(name)
+		if tag, ok := vocab.tags[strings.ToLower(name)]; ok {
+			name = tag.Name
+		}
+		if name != "" {
+			removeSet[name] = true
+		}
+	}
+	for _, name := range add {
+		tag := vocab.tags[strings.ToLower(name)]
+		if group := vocab.groups[tag.GroupID]; group != nil && group.Exclusive {
+			for _, sibling := range vocab.byGroup[group.ID] {
+				removeSet[sibling] = true
+			}
+		}
+	}
+	for _, name := range add {
+		delete(removeSet, name)
+	}
+	remove := make([]string, 0, len(removeSet))
+	for name := range removeSet {
+		remove = append(remove, name)
+	}
+	return add, remove, nil
+}
+
+func (s *TagService) withTags(groups []*models.TagGroup) ([]*dto.TagGroupWithTags, error) {
+	groupIDs := make([]uint64, len(groups))
+	for i, g := range groups {
+		groupIDs[i] = g.ID
+	}
+	tags, err := s.tagRepo.FindTags(groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*dto.TagGroupWithTags, len(groups))
+	for i, g := range groups {
+		groupTags := tags[g.ID]
+		if groupTags == nil {
+			groupTags = []*models.Tag{}
+		}
+		out[i] = &dto.TagGroupWithTags{TagGroup: g, Tags: groupTags}
+	}
+	return out, nil
+}
+
+func (s *TagService) checkGroupName(group *models.TagGroup, excludeID uint64) error {
+	if err := checkTagNameFormat(group.Name); err != nil {
+		return err
+	}
+	existing, err := s.tagRepo.FindGroupByName(group.TeamID, group.Name)
+	if err == nil && existing.ID != excludeID {
+		return fmt.Errorf("%w: group %q", ErrTagExists, group.Name)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// checkTagName 标签名在任何用户可用的范围内都不能重名，否则客户上保存的标签名会有歧义
+func (s *TagService) checkTagName(tag *models.Tag) error {
+	if err := checkTagNameFormat(tag.Name); err != nil {
+		return err
+	}
+	existing, err := s.tagRepo.FindConflictingTag(tag.TeamID, tag.Name, tag.ID)
+	if err == nil {
+		return fmt.Errorf("%w: %q conflicts with tag %d", ErrTagExists, tag.Name, existing.ID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+func checkTagNameFormat(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTag)
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLength {
+		return fmt.Errorf("%w: name is longer than %d characters", ErrInvalidTag, maxTagNameLength)
+	}
+	if strings.ContainsAny(name, ",，") {
+		return fmt.Errorf("%w: name must not contain commas", ErrInvalidTag)
+	}
+	return nil
+}
+
+func (s *TagService) findGroup(id uint64) (*models.TagGroup, error) {
+	group, err := s.tagRepo.FindGroupByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagGroupNotFound
+		}
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *TagService) findTag(id uint64) (*models.Tag, error) {
+	tag, err := s.tagRepo.FindTagByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *TagService) teamOf(userID uint64) (*uint64, error) {
+	teamID, err := s.userRepo.FindTeamID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return teamID, nil
+}
+
+// tagVocabulary 用户可用的标签：小写名称 → 标签，以及分组和各分组的标签名
+type tagVocabulary struct {
+	tags    map[string]*models.Tag
+	groups  map[uint64]*models.TagGroup
+	byGroup map[uint64][]string
+}
+
+func (s *TagService) vocabulary(userID uint64) (*tagVocabulary, error) {
+	teamID, err := s.teamOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.tagRepo.FindEffectiveGroups(teamID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.tagRepo.FindEffectiveTags(teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &tagVocabulary{
+		tags:    make(map[string]*models.Tag, len(tags)),
+		groups:  make(map[uint64]*models.TagGroup, len(groups)),
+		byGroup: make(map[uint64][]string, len(groups)),
+	}
+	for _, g := range groups {
+		v.groups[g.ID] = g
+	}
+	for _, t := range tags {
+		v.tags[strings.ToLower(t.Name)] = t
+		v.byGroup[t.GroupID] = append(v.byGroup[t.GroupID], t.Name)
+	}
+	return v, nil
+}
+
+// resolve 按名称（不区分大小写）找到标签，返回去重后的规范名称
+func (v *tagVocabulary) resolve(names []string) ([]string, error) {
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		tag, ok := v.tags[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown tag %q", ErrInvalidTag, name)
+		}
+		if !containsString(out, tag.Name) {
+			out = append(out, tag.Name)
+		}
+	}
+	return out, nil
+}
+
+// checkExclusive 互斥分组中最多一个标签
+func (v *tagVocabulary) checkExclusive(names []string) error {
+	seen := make(map[uint64]string)
+	for _, name := range names {
+		tag := v.tags[strings.ToLower(name)]
+		group := v.groups[tag.GroupID]
+		if group == nil || !group.Exclusive {
+			continue
+		}
+		if other, ok := seen[group.ID]; ok {
+			return fmt.Errorf("%w: %q and %q are both in exclusive group %q", ErrInvalidTag, other, name, group.Name)
+		}
+		seen[group.ID] = name
+	}
+	return nil
+}
+
+// uniqueIDs 去掉重复的 ID，保持原顺序
+func uniqueIDs(ids []uint64) []uint64 {
+	out := make([]uint64, 0, len(ids))
+	seen := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
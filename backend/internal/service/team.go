@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrTeamNotFound = errors.New("team not found")
+var ErrUserNotFound = errors.New("user not found")
+
+type TeamService struct {
+	teamRepo *repository.TeamRepository
+	userRepo *repository.UserRepository
+}
+
+func NewTeamService(teamRepo *repository.TeamRepository, userRepo *repository.UserRepository) *TeamService {
+	return &TeamService{
+		teamRepo: teamRepo,
+		userRepo: userRepo,
+	}
+}
+
+// CreateTeam creates a new team
+func (s *TeamService) CreateTeam(name string) (*models.Team, error) {
+	team := &models.Team{Name: name}
+	if err := s.teamRepo.Create(team); err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// ListTeams returns all teams
+func (s *TeamService) ListTeams() ([]*models.Team, error) {
+	return s.teamRepo.List()
+}
+
+// AssignUser moves a user into a team; nil teamID removes the user from any team
+func (s *TeamService) AssignUser(userID uint64, teamID *uint64) error {
+	if teamID != nil {
+		if _, err := s.teamRepo.FindByID(*teamID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+	}
+
+	if err := s.userRepo.UpdateTeam(userID, teamID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
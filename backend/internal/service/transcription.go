@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"github.com/xia/nextcrm/pkg/audio"
+	"github.com/xia/nextcrm/pkg/llm"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTranscriptionNotFound = errors.New("transcription job not found")
+	ErrAudioTooLarge         = errors.New("audio file is too large")
+)
+
+// TranscriptionService 长录音异步转写：切片后由有限个 worker 并行识别（每片按降级链在豆包 / 火山引擎间切换），
+// 按片段时间偏移拼接成带时间戳的全文。任务在本进程内执行，进度写入 done_chunks。
+type TranscriptionService struct {
+	aiService *AIService
+	jobRepo   *repository.TranscriptionJobRepository
+	splitter  *audio.Splitter
+	workers   int
+	timeout   time.Duration
+	maxBytes  int64
+}
+
+func NewTranscriptionService(
+	aiService *AIService,
+	jobRepo *repository.TranscriptionJobRepository,
+	splitter *audio.Splitter,
+	workers int,
+	timeout time.Duration,
+	maxBytes int64,
+) *TranscriptionService {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &TranscriptionService{
+		aiService: aiService,
+		jobRepo:   jobRepo,
+		splitter:  splitter,
+		workers:   workers,
+		timeout:   timeout,
+		maxBytes:  maxBytes,
+	}
+}
+
+// RecoverUnfinished 进程重启后未完成的任务无法继续（音频不落盘），标记为失败
+func (s *TranscriptionService) RecoverUnfinished() {
+	n, err := s.jobRepo.FailUnfinished("interrupted by server restart, please upload again")
+	if err != nil {
+		log.Printf("failed to recover transcription jobs: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("marked %d unfinished transcription jobs as failed", n)
+	}
+}
+
+// Submit 创建转写任务并在后台执行，立即返回排队中的任务
+func (s *TranscriptionService) Submit(ctx context.Context, userID uint64, fileName string, data []byte, format, language string) (*models.TranscriptionJob, error) {
+	if s.maxBytes > 0 && int64(len(data)) > s.maxBytes {
+		return nil, fmt.Errorf("%w (max %d MB)", ErrAudioTooLarge, s.maxBytes>>20)
+	}
+	// 提交时先检查一次额度，避免排进去才失败
+	if err := s.aiService.usage.CheckQuota(ctx); err != nil {
+		return nil, err
+	}
+
+	job := &models.TranscriptionJob{
+		UserID:   userID,
+		FileName: fileName,
+		Format:   format,
+		Language: language,
+		Status:   models.TranscriptionQueued,
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, err
+	}
+
+	go s.run(job, data)
+
+	return job, nil
+}
+
+// GetJob 查询任务状态和结果
+func (s *TranscriptionService) GetJob(id, userID uint64) (*models.TranscriptionJob, error) {
+	job, err := s.jobRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTranscriptionNotFound
+		}
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return job, nil
+}
+
+// ListJobs 用户最近的转写任务
+func (s *TranscriptionService) ListJobs(userID uint64, limit int) ([]*models.TranscriptionJob, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.jobRepo.ListByUserID(userID, limit)
+}
+
+// run 后台执行任务；请求上下文已结束，用户信息重新放进新的上下文以便计量用量
+func (s *TranscriptionService) run(job *models.TranscriptionJob, data []byte) {
+	ctx := WithAIUser(context.Background(), job.UserID)
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.finish(job, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	chunks, err := s.splitter.Split(ctx, data, job.Format)
+	if err != nil {
+		s.finish(job, fmt.Errorf("split audio: %w", err))
+		return
+	}
+	if err := s.jobRepo.Start(job.ID, len(chunks)); err != nil {
+		log.Printf("failed to start transcription job %d: %v", job.ID, err)
+	}
+	now := time.Now()
+	job.Status = models.TranscriptionRunning
+	job.TotalChunks = len(chunks)
+	job.StartedAt = &now
+
+	results, err := s.transcribeAll(ctx, job, chunks)
+	if err != nil {
+		s.finish(job, err)
+		return
+	}
+
+	stitchTranscript(job, chunks, results)
+	if strings.TrimSpace(job.Text) == "" {
+		s.finish(job, ErrEmptyTranscript)
+		return
+	}
+	s.finish(job, nil)
+}
+
+// transcribeAll 有限并发地识别所有片段；任一片段在所有厂商都失败时取消其余片段
+func (s *TranscriptionService) transcribeAll(ctx context.Context, job *models.TranscriptionJob, chunks []audio.Chunk) ([]*llm.SpeechResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*llm.SpeechResponse, len(chunks))
+	queue := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		done     int32
+	)
+
+	workers := s.workers
+	if workers > len(chunks) {
+		workers = len(chunks)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				chunk := chunks[i]
+				resp, err := s.aiService.transcribeChunk(ctx, chunk.Data, chunk.Format, job.Language)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("chunk %d at %.0fs: %w", chunk.Index+1, chunk.Offset, err)
+						cancel()
+					})
+					continue
+				}
+				results[i] = resp
+				atomic.AddInt32(&done, 1)
+				if err := s.jobRepo.IncrementDone(job.ID); err != nil {
+					log.Printf("failed to update transcription job %d progress: %v", job.ID, err)
+				}
+			}
+		}()
+	}
+
+	for i := range chunks {
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+	job.DoneChunks = int(done)
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// stitchTranscript 按片段偏移拼接；厂商返回了分段时间戳就平移到全局时间，否则整片作为一段
+func stitchTranscript(job *models.TranscriptionJob, chunks []audio.Chunk, results []*llm.SpeechResponse) {
+	var (
+		texts     []string
+		segments  []models.TranscriptSegment
+		providers []string
+		duration  float64
+	)
+	for i, resp := range results {
+		chunk := chunks[i]
+		chunkDuration := chunk.Duration
+		if chunkDuration == 0 {
+			chunkDuration = resp.Duration
+		}
+		duration = chunk.Offset + chunkDuration
+
+		if !containsString(providers, resp.Provider) {
+			providers = append(providers, resp.Provider)
+		}
+
+		text := strings.TrimSpace(resp.Text)
+		if text == "" {
+			continue
+		}
+		texts = append(texts, text)
+
+		if len(resp.Segments) == 0 {
+			segments = append(segments, models.TranscriptSegment{
+				Start: chunk.Offset,
+				End:   chunk.Offset + chunkDuration,
+				Text:  text,
+			})
+			continue
+		}
+		for _, seg := range resp.Segments {
+			start, end := chunk.Offset+seg.Start, chunk.Offset+seg.End
+			if seg.End == 0 {
+				start, end = chunk.Offset, chunk.Offset+chunkDuration
+			}
+			segments = append(segments, models.TranscriptSegment{Start: start, End: end, Speaker: seg.Speaker, Text: seg.Text})
+		}
+	}
+
+	job.Text = strings.Join(texts, "\n")
+	job.Segments = segments
+	job.Providers = providers
+	job.Duration = duration
+}
+
+// finish 写入最终状态
+func (s *TranscriptionService) finish(job *models.TranscriptionJob, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = models.TranscriptionFailed
+		job.Error = err.Error()
+		log.Printf("transcription job %d failed: %v", job.ID, err)
+	} else {
+		job.Status = models.TranscriptionSucceeded
+		job.Error = ""
+	}
+	if err := s.jobRepo.Update(job); err != nil {
+		log.Printf("failed to save transcription job %d: %v", job.ID, err)
+	}
+}
+
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xia/nextcrm/internal/dto"
+	"github.com/xia/nextcrm/internal/models"
+	"github.com/xia/nextcrm/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrViewNotFound = errors.New("view not found")
+	ErrInvalidView  = errors.New("invalid view")
+)
+
+// maxViewColumns 视图最多保存的显示列数
+const maxViewColumns = 50
+
+// viewEntities 支持保存视图的列表
+var viewEntities = []string{"customers", "deals", "interactions", "knowledge"}
+
+// ViewService 保存的列表视图：过滤条件、显示列和排序。共享视图对同团队成员可见，
+// 按查看者自己的数据计算；只有创建人可以修改和删除
+type ViewService struct {
+	viewRepo *repository.ViewRepository
+	userRepo *repository.UserRepository
+}
+
+func NewViewService(viewRepo *repository.ViewRepository, userRepo *repository.UserRepository) *ViewService {
+	return &ViewService{viewRepo: viewRepo, userRepo: userRepo}
+}
+
+// ListViews 自己的和同团队共享的视图，entity 为空时列出全部
+func (s *ViewService) ListViews(userID uint64, entity string) ([]*dto.ViewResponse, error) {
+	if entity != "" && !isViewEntity(entity) {
+		return nil, fmt.Errorf("%w: unknown entity %q", ErrInvalidView, entity)
+	}
+	teamID, err := s.teamOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	views, err := s.viewRepo.FindVisible(userID, teamID, entity)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*dto.ViewResponse, 0, len(views))
+	for _, view := range views {
+		out = append(out, toViewResponse(view, userID))
+	}
+	return out, nil
+}
+
+// GetView 视图详情
+func (s *ViewService) GetView(id, userID uint64) (*dto.ViewResponse, error) {
+	view, err := s.findVisible(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toViewResponse(view, userID), nil
+}
+
+// CreateView 保存视图
+func (s *ViewService) CreateView(userID uint64, req *dto.CreateViewRequest) (*dto.ViewResponse, error) {
+	if !isViewEntity(req.Entity) {
+		return nil, fmt.Errorf("%w: unknown entity %q (expected %s)", ErrInvalidView, req.Entity, strings.Join(viewEntities, ", "))
+	}
+	teamID, err := s.teamOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	view := &models.SavedView{
+		UserID: userID,
+		TeamID: teamID,
+		Entity: req.Entity,
+		Name:   strings.TrimSpace(req.Name),
+		Shared: req.Shared,
+	}
+	if view.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidView)
+	}
+	if err := setViewFilter(view, req.Filter); err != nil {
+		return nil, err
+	}
+	if err := setViewColumns(view, req.Columns); err != nil {
+		return nil, err
+	}
+	if err := setViewSort(view, req.Sort); err != nil {
+		return nil, err
+	}
+	if err := s.viewRepo.Create(view); err != nil {
+		return nil, err
+	}
+	return toViewResponse(view, userID), nil
+}
+
+// UpdateView 修改视图，只有创建人可以修改
+func (s *ViewService) UpdateView(id, userID uint64, req *dto.UpdateViewRequest) (*dto.ViewResponse, error) {
+	view, err := s.findOwned(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		view.Name = strings.TrimSpace(*req.Name)
+		if view.Name == "" {
+			return nil, fmt.Errorf("%w: name is required", ErrInvalidView)
+		}
+	}
+	if req.ClearFilter {
+		view.Filter = nil
+	} else if req.Filter != nil {
+		if err := setViewFilter(view, req.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if req.Columns != nil {
+		if err := setViewColumns(view, req.Columns); err != nil {
+			return nil, err
+		}
+	}
+	if req.Sort != nil {
+		if err := setViewSort(view, req.Sort); err != nil {
+			return nil, err
+		}
+	}
+	if req.Shared != nil {
+		view.Shared = *req.Shared
+	}
+	// 共享范围跟随创建人当前所在的团队
+	if view.TeamID, err = s.teamOf(userID); err != nil {
+		return nil, err
+	}
+	if err := s.viewRepo.Update(view); err != nil {
+		return nil, err
+	}
+	return toViewResponse(view, userID), nil
+}
+
+// DeleteView 删除视图，只有创建人可以删除
+func (s *ViewService) DeleteView(id, userID uint64) error {
+	if _, err := s.findOwned(id, userID); err != nil {
+		return err
+	}
+	return s.viewRepo.Delete(id)
+}
+
+// resolveListFilter 解析列表的高级过滤参数：filter 参数与 view_id 视图的过滤条件取 AND；
+// 排序取 sort_by（cf.<key> 由自定义字段排序处理），没有时取视图的排序。结果写回 lf 并按白名单校验。
+// views 为空时不支持 view_id
+func resolveListFilter(views *ViewService, userID uint64, entity string, lf *dto.ListFilter, sortBy string, desc bool) error {
+	var filters []*dto.FilterNode
+	var sorts []dto.SortSpec
+
+	if lf.ViewID > 0 {
+		if views == nil {
+			return fmt.Errorf("%w: saved views are not available", ErrInvalidQuery)
+		}
+		view, err := views.findVisible(lf.ViewID, userID)
+		if err != nil {
+			return err
+		}
+		if view.Entity != entity {
+			return fmt.Errorf("%w: view %d is for %s, not %s", ErrInvalidQuery, view.ID, view.Entity, entity)
+		}
+		filter, viewSorts, err := decodeView(view)
+		if err != nil {
+			return err
+		}
+		if filter != nil {
+			filters = append(filters, filter)
+		}
+		sorts = viewSorts
+	}
+
+	if lf.FilterJSON != "" {
+		var filter dto.FilterNode
+		if err := json.Unmarshal([]byte(lf.FilterJSON), &filter); err != nil {
+			return fmt.Errorf("%w: filter is not valid JSON: %v", ErrInvalidQuery, err)
+		}
+		filters = append(filters, &filter)
+	}
+
+	switch len(filters) {
+	case 1:
+		lf.Filter = filters[0]
+	case 2:
+		lf.Filter = &dto.FilterNode{And: filters}
+	}
+
+	if sortBy != "" && !strings.HasPrefix(sortBy, customFieldSortPrefix) {
+		order := "asc"
+		if desc {
+			order = "desc"
+		}
+		sorts = []dto.SortSpec{{Field: sortBy, Order: order}}
+	}
+	lf.Sort = sorts
+
+	if err := repository.ValidateListFilter(entity, lf.Filter, lf.Sort); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return nil
+}
+
+// findVisible 自己的视图，或同团队共享的视图
+func (s *ViewService) findVisible(id, userID uint64) (*models.SavedView, error) {
+	view, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if view.UserID == userID {
+		return view, nil
+	}
+	teamID, err := s.teamOf(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !view.Shared || view.TeamID == nil || teamID == nil || *view.TeamID != *teamID {
+		return nil, ErrUnauthorized
+	}
+	return view, nil
+}
+
+func (s *ViewService) findOwned(id, userID uint64) (*models.SavedView, error) {
+	view, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if view.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return view, nil
+}
+
+func (s *ViewService) find(id uint64) (*models.SavedView, error) {
+	view, err := s.viewRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrViewNotFound
+		}
+		return nil, err
+	}
+	return view, nil
+}
+
+func (s *ViewService) teamOf(userID uint64) (*uint64, error) {
+	teamID, err := s.userRepo.FindTeamID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return teamID, nil
+}
+
+func toViewResponse(view *models.SavedView, userID uint64) *dto.ViewResponse {
+	return &dto.ViewResponse{SavedView: view, Editable: view.UserID == userID}
+}
+
+func isViewEntity(entity string) bool {
+	for _, e := range viewEntities {
+		if e == entity {
+			return true
+		}
+	}
+	return false
+}
+
+// setViewFilter 按视图的实体校验过滤树并序列化保存
+func setViewFilter(view *models.SavedView, filter *dto.FilterNode) error {
+	if filter == nil {
+		view.Filter = nil
+		return nil
+	}
+	if err := repository.ValidateListFilter(view.Entity, filter, nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidView, err)
+	}
+	raw, err := json.Marshal(filter)
+	if err != nil {
+		return err
+	}
+	view.Filter = raw
+	return nil
+}
+
+// setViewColumns 显示列只能是实体的可过滤字段或 cf.<key>，去重并保持顺序
+func setViewColumns(view *models.SavedView, columns []string) error {
+	if len(columns) > maxViewColumns {
+		return fmt.Errorf("%w: at most %d columns are allowed", ErrInvalidView, maxViewColumns)
+	}
+	seen := make(map[string]bool, len(columns))
+	out := make([]string, 0, len(columns))
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		if column == "" || seen[column] {
+			continue
+		}
+		custom := strings.HasPrefix(column, customFieldSortPrefix) && len(column) > len(customFieldSortPrefix)
+		if !custom && !repository.HasFilterField(view.Entity, column) {
+			return fmt.Errorf("%w: unknown column %q for %s", ErrInvalidView, column, view.Entity)
+		}
+		seen[column] = true
+		out = append(out, column)
+	}
+	view.Columns = out
+	return nil
+}
+
+// setViewSort 按白名单校验排序并序列化保存
+func setViewSort(view *models.SavedView, sorts []dto.SortSpec) error {
+	if sorts == nil {
+		sorts = []dto.SortSpec{}
+	}
+	if err := repository.ValidateListFilter(view.Entity, nil, sorts); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidView, err)
+	}
+	raw, err := json.Marshal(sorts)
+	if err != nil {
+		return err
+	}
+	view.Sort = raw
+	return nil
+}
+
+// decodeView 视图保存的过滤树和排序
+func decodeView(view *models.SavedView) (*dto.FilterNode, []dto.SortSpec, error) {
+	var filter *dto.FilterNode
+	if len(view.Filter) > 0 && string(view.Filter) != "null" {
+		filter = &dto.FilterNode{}
+		if err := json.Unmarshal(view.Filter, filter); err != nil {
+			return nil, nil, fmt.Errorf("%w: view %d has a malformed filter: %v", ErrInvalidView, view.ID, err)
+		}
+	}
+	var sorts []dto.SortSpec
+	if len(view.Sort) > 0 {
+		if err := json.Unmarshal(view.Sort, &sorts); err != nil {
+			return nil, nil, fmt.Errorf("%w: view %d has a malformed sort: %v", ErrInvalidView, view.ID, err)
+		}
+	}
+	return filter, sorts, nil
+}
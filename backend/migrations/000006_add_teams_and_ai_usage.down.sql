@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_ai_quotas_scope;
+DROP TABLE IF EXISTS ai_quotas;
+
+DROP INDEX IF EXISTS idx_ai_usage_logs_created_at;
+DROP INDEX IF EXISTS idx_ai_usage_logs_team_created;
+DROP INDEX IF EXISTS idx_ai_usage_logs_user_created;
+DROP TABLE IF EXISTS ai_usage_logs;
+
+DROP INDEX IF EXISTS idx_users_team_id;
+ALTER TABLE users DROP COLUMN IF EXISTS team_id;
+
+DROP INDEX IF EXISTS idx_teams_deleted_at;
+DROP TABLE IF EXISTS teams;
@@ -0,0 +1,49 @@
+-- Teams (团队)
+CREATE TABLE IF NOT EXISTS teams (
+  id BIGSERIAL PRIMARY KEY,
+  name VARCHAR(128) NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_teams_deleted_at ON teams(deleted_at);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS team_id BIGINT REFERENCES teams(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_users_team_id ON users(team_id);
+
+-- AI usage log (每次厂商调用一条，降级时每个尝试过的厂商各一条)
+CREATE TABLE IF NOT EXISTS ai_usage_logs (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  team_id BIGINT REFERENCES teams(id) ON DELETE SET NULL,
+  feature VARCHAR(32) NOT NULL, -- script, analyze, intake, ocr, asr, embedding
+  capability VARCHAR(16) NOT NULL, -- chat, embedding, speech, vision
+  provider VARCHAR(64) NOT NULL,
+  model VARCHAR(128) NOT NULL DEFAULT '',
+  prompt_tokens INT NOT NULL DEFAULT 0,
+  completion_tokens INT NOT NULL DEFAULT 0,
+  total_tokens INT NOT NULL DEFAULT 0,
+  latency_ms INT NOT NULL DEFAULT 0,
+  success BOOLEAN NOT NULL DEFAULT true,
+  error_message TEXT DEFAULT '',
+  cost DECIMAL(18,6) NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ai_usage_logs_user_created ON ai_usage_logs(user_id, created_at);
+CREATE INDEX idx_ai_usage_logs_team_created ON ai_usage_logs(team_id, created_at);
+CREATE INDEX idx_ai_usage_logs_created_at ON ai_usage_logs(created_at);
+
+-- AI quotas (按用户 / 团队覆盖默认额度，0 表示不限)
+CREATE TABLE IF NOT EXISTS ai_quotas (
+  id BIGSERIAL PRIMARY KEY,
+  scope VARCHAR(16) NOT NULL, -- user, team
+  scope_id BIGINT NOT NULL,
+  daily_token_limit BIGINT NOT NULL DEFAULT 0,
+  monthly_token_limit BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_ai_quotas_scope ON ai_quotas(scope, scope_id);
@@ -0,0 +1,8 @@
+ALTER TABLE ai_usage_logs DROP COLUMN IF EXISTS prompt_versions;
+
+DROP INDEX IF EXISTS idx_prompt_template_pins_key_team;
+DROP TABLE IF EXISTS prompt_template_pins;
+
+DROP INDEX IF EXISTS idx_prompt_templates_team_id;
+DROP INDEX IF EXISTS idx_prompt_templates_key_team_version;
+DROP TABLE IF EXISTS prompt_templates;
@@ -0,0 +1,29 @@
+-- Prompt templates (提示词模板，按 key + 团队分版本)
+CREATE TABLE IF NOT EXISTS prompt_templates (
+  id BIGSERIAL PRIMARY KEY,
+  key VARCHAR(64) NOT NULL,
+  team_id BIGINT REFERENCES teams(id) ON DELETE CASCADE, -- NULL 表示全局
+  version INT NOT NULL,
+  content TEXT NOT NULL,
+  description TEXT DEFAULT '',
+  created_by BIGINT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_prompt_templates_key_team_version ON prompt_templates(key, COALESCE(team_id, 0), version);
+CREATE INDEX idx_prompt_templates_team_id ON prompt_templates(team_id);
+
+-- Pinned versions (固定 / 回滚)
+CREATE TABLE IF NOT EXISTS prompt_template_pins (
+  id BIGSERIAL PRIMARY KEY,
+  key VARCHAR(64) NOT NULL,
+  team_id BIGINT REFERENCES teams(id) ON DELETE CASCADE,
+  version INT NOT NULL, -- 0 表示内置模板
+  pinned_by BIGINT,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_prompt_template_pins_key_team ON prompt_template_pins(key, COALESCE(team_id, 0));
+
+-- 每次 AI 调用使用的模板版本，如 "script.system@0,script.user@3"
+ALTER TABLE ai_usage_logs ADD COLUMN IF NOT EXISTS prompt_versions VARCHAR(255) DEFAULT '';
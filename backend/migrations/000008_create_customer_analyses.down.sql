@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_activities_customer_action;
+DROP TABLE IF EXISTS customer_analyses;
@@ -0,0 +1,23 @@
+-- AI customer analysis snapshots (客户分析快照)
+CREATE TABLE IF NOT EXISTS customer_analyses (
+  id BIGSERIAL PRIMARY KEY,
+  customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL,
+  analysis_type VARCHAR(32) DEFAULT '',
+  summary TEXT DEFAULT '',
+  intent_score INT NOT NULL DEFAULT 0,
+  risk_level VARCHAR(16) DEFAULT '',
+  opportunities TEXT[],
+  recommendations TEXT[],
+  next_actions TEXT[],
+  stage VARCHAR(50) DEFAULT '',      -- 分析时的客户阶段
+  probability INT NOT NULL DEFAULT 0, -- 分析时的成交概率
+  prompt_versions VARCHAR(255) DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_customer_analyses_customer_created ON customer_analyses(customer_id, created_at DESC);
+CREATE INDEX idx_customer_analyses_user_id ON customer_analyses(user_id);
+
+-- 阶段变更记录在 activities 中（action_type = 'stage_change'）
+CREATE INDEX IF NOT EXISTS idx_activities_customer_action ON activities(customer_id, action_type);
@@ -0,0 +1,27 @@
+-- Call recordings (通话录音转写、AI 总结与跟进记录草稿)
+CREATE TABLE IF NOT EXISTS call_recordings (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  file_name VARCHAR(255) DEFAULT '',
+  status VARCHAR(32) NOT NULL DEFAULT 'pending_confirmation', -- pending_confirmation, confirmed, discarded
+  provider VARCHAR(64) DEFAULT '',
+  duration DOUBLE PRECISION NOT NULL DEFAULT 0,
+  confidence DOUBLE PRECISION,
+  transcript TEXT DEFAULT '',
+  segments JSONB,
+  summary TEXT DEFAULT '',
+  key_points TEXT[],
+  objections TEXT[],
+  commitments TEXT[],
+  next_steps TEXT[],
+  outcome VARCHAR(16) DEFAULT '',
+  next_action TEXT DEFAULT '',
+  next_date TIMESTAMPTZ,
+  interaction_id BIGINT REFERENCES interactions(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_call_recordings_customer_id ON call_recordings(customer_id, created_at DESC);
+CREATE INDEX idx_call_recordings_user_status ON call_recordings(user_id, status);
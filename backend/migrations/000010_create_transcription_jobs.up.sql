@@ -0,0 +1,23 @@
+-- Transcription jobs (长录音异步转写任务)
+CREATE TABLE IF NOT EXISTS transcription_jobs (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  file_name VARCHAR(255) DEFAULT '',
+  format VARCHAR(16) DEFAULT '',
+  language VARCHAR(16) DEFAULT '',
+  status VARCHAR(16) NOT NULL DEFAULT 'queued', -- queued, running, succeeded, failed
+  total_chunks INT NOT NULL DEFAULT 0,
+  done_chunks INT NOT NULL DEFAULT 0,
+  providers TEXT[],
+  duration DOUBLE PRECISION NOT NULL DEFAULT 0,
+  text TEXT DEFAULT '',
+  segments JSONB,
+  error TEXT DEFAULT '',
+  started_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transcription_jobs_user_id ON transcription_jobs(user_id, created_at DESC);
+CREATE INDEX idx_transcription_jobs_status ON transcription_jobs(status);
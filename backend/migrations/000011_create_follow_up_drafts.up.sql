@@ -0,0 +1,22 @@
+-- Follow-up drafts (AI 生成的跟进邮件 / 微信消息草稿)
+CREATE TABLE IF NOT EXISTS follow_up_drafts (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  deal_id BIGINT REFERENCES deals(id) ON DELETE SET NULL,
+  channel VARCHAR(16) NOT NULL, -- email, wechat
+  tone VARCHAR(32) DEFAULT '',
+  language VARCHAR(16) DEFAULT '',
+  status VARCHAR(16) NOT NULL DEFAULT 'draft', -- draft, sent, discarded
+  subject VARCHAR(255) DEFAULT '',
+  body TEXT DEFAULT '',
+  facts TEXT[],
+  style_guide_ids BIGINT[],
+  prompt_versions VARCHAR(255) DEFAULT '',
+  interaction_id BIGINT REFERENCES interactions(id) ON DELETE SET NULL,
+  sent_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_follow_up_drafts_customer_id ON follow_up_drafts(customer_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS intent_proposals;
+ALTER TABLE interactions DROP COLUMN IF EXISTS signals_analyzed_at;
+ALTER TABLE interactions DROP COLUMN IF EXISTS signals;
@@ -0,0 +1,21 @@
+-- Interaction signals (AI 提取的情绪和意向信号)
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS signals JSONB;
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS signals_analyzed_at TIMESTAMPTZ;
+
+-- Intent proposals (意向等级 / 潜力评分调整建议，采纳后更新客户)
+CREATE TABLE IF NOT EXISTS intent_proposals (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending', -- pending, accepted, rejected, superseded
+  current_intent_level VARCHAR(16) DEFAULT '',
+  proposed_intent_level VARCHAR(16) DEFAULT '',
+  current_potential_score INT NOT NULL DEFAULT 0,
+  proposed_potential_score INT NOT NULL DEFAULT 0,
+  evidence JSONB,
+  decided_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_intent_proposals_customer_status ON intent_proposals(customer_id, status);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS lead_scores;
+DROP TABLE IF EXISTS lead_scoring_models;
@@ -0,0 +1,26 @@
+-- Lead scoring models (基于历史成交训练的线索评分模型)
+CREATE TABLE IF NOT EXISTS lead_scoring_models (
+  id BIGSERIAL PRIMARY KEY,
+  samples INT NOT NULL,
+  positives INT NOT NULL,
+  categories JSONB,
+  params JSONB,
+  holdout_samples INT NOT NULL DEFAULT 0,
+  holdout_auc DOUBLE PRECISION NOT NULL DEFAULT 0,
+  holdout_log_loss DOUBLE PRECISION NOT NULL DEFAULT 0,
+  trained_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Lead scores (每个未结客户最新的模型评分)
+CREATE TABLE IF NOT EXISTS lead_scores (
+  customer_id BIGINT PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL,
+  model_id BIGINT NOT NULL REFERENCES lead_scoring_models(id) ON DELETE CASCADE,
+  score INT NOT NULL,
+  probability DOUBLE PRECISION NOT NULL,
+  contributions JSONB,
+  scored_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_lead_scores_user_score ON lead_scores(user_id, score DESC);
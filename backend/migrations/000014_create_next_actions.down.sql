@@ -0,0 +1 @@
+DROP TABLE IF EXISTS next_actions;
@@ -0,0 +1,27 @@
+-- Next-best-action items (销售每日行动清单)
+CREATE TABLE IF NOT EXISTS next_actions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  deal_id BIGINT,
+  plan_date TIMESTAMPTZ NOT NULL,
+  rank INT NOT NULL,
+  priority VARCHAR(20) NOT NULL,
+  source VARCHAR(20) NOT NULL,
+  signals TEXT[],
+  rule_score INT NOT NULL DEFAULT 0,
+  reason TEXT,
+  action TEXT NOT NULL,
+  action_type VARCHAR(20) NOT NULL,
+  due_date TIMESTAMPTZ,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  dismiss_reason VARCHAR(500),
+  interaction_id BIGINT,
+  decided_at TIMESTAMPTZ,
+  prompt_versions VARCHAR(255),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_next_actions_user_plan ON next_actions(user_id, plan_date);
+CREATE INDEX idx_next_actions_customer_id ON next_actions(customer_id);
+CREATE INDEX idx_next_actions_status ON next_actions(status);
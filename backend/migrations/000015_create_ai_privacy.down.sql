@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ai_audit_logs;
+DROP TABLE IF EXISTS ai_privacy_policies;
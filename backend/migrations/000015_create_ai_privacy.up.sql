@@ -0,0 +1,33 @@
+-- AI privacy policies (按团队覆盖默认的脱敏 / 禁止外发配置)
+CREATE TABLE IF NOT EXISTS ai_privacy_policies (
+  id BIGSERIAL PRIMARY KEY,
+  team_id BIGINT NOT NULL UNIQUE REFERENCES teams(id) ON DELETE CASCADE,
+  redaction BOOLEAN NOT NULL DEFAULT true,
+  redact_types TEXT[], -- name, phone, email, wechat, id_number, credit_code, bank_account
+  forbidden_fields TEXT[], -- 客户字段的 JSON 名，如 notes, bank_account
+  block_images BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- AI audit log (每次发往外部模型的请求一条，记录脱敏后的内容)
+CREATE TABLE IF NOT EXISTS ai_audit_logs (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  team_id BIGINT REFERENCES teams(id) ON DELETE SET NULL,
+  feature VARCHAR(32) NOT NULL,
+  capability VARCHAR(16) NOT NULL, -- chat, vision
+  provider VARCHAR(64) NOT NULL DEFAULT '',
+  success BOOLEAN NOT NULL DEFAULT true,
+  error_message TEXT DEFAULT '',
+  payload TEXT,
+  redactions JSONB,
+  dropped_fields TEXT[],
+  image_sha256 VARCHAR(64),
+  image_bytes INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ai_audit_logs_user_created ON ai_audit_logs(user_id, created_at);
+CREATE INDEX idx_ai_audit_logs_team_created ON ai_audit_logs(team_id, created_at);
+CREATE INDEX idx_ai_audit_logs_created_at ON ai_audit_logs(created_at);
@@ -0,0 +1,12 @@
+-- Embedding cache (相同文本在同一厂商 / 模型下只计算一次向量)
+CREATE TABLE IF NOT EXISTS ai_embedding_cache (
+  id BIGSERIAL PRIMARY KEY,
+  provider VARCHAR(64) NOT NULL,
+  model VARCHAR(128) NOT NULL DEFAULT '',
+  text_hash CHAR(64) NOT NULL, -- 规整后文本的 SHA-256
+  embedding REAL[] NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_ai_embedding_cache_key ON ai_embedding_cache(provider, model, text_hash);
+CREATE INDEX idx_ai_embedding_cache_text_hash ON ai_embedding_cache(text_hash);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ai_intake_sessions;
@@ -0,0 +1,17 @@
+-- AI intake sessions (服务端保存的新建客户对话：消息历史、已提取字段，确认后创建客户)
+CREATE TABLE IF NOT EXISTS ai_intake_sessions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status VARCHAR(32) NOT NULL DEFAULT 'collecting', -- collecting, ready_for_confirmation, confirmed
+  messages JSONB NOT NULL DEFAULT '[]',
+  fields JSONB NOT NULL DEFAULT '{}',
+  summary TEXT DEFAULT '',
+  customer_id BIGINT REFERENCES customers(id) ON DELETE SET NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  confirmed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ai_intake_sessions_user_id ON ai_intake_sessions(user_id, updated_at DESC);
+CREATE INDEX idx_ai_intake_sessions_expires_at ON ai_intake_sessions(expires_at) WHERE status <> 'confirmed';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS card_scan_items;
+DROP TABLE IF EXISTS card_scan_batches;
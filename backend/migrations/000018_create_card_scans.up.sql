@@ -0,0 +1,42 @@
+-- Business card scan batches (批量名片识别：识别结果进入审核队列，逐张确认后创建或合并客户)
+CREATE TABLE IF NOT EXISTS card_scan_batches (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status VARCHAR(16) NOT NULL DEFAULT 'queued', -- queued, running, completed, failed
+  total_cards INT NOT NULL DEFAULT 0,
+  done_cards INT NOT NULL DEFAULT 0,
+  failed_cards INT NOT NULL DEFAULT 0,
+  error TEXT DEFAULT '',
+  started_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_card_scan_batches_user_id ON card_scan_batches(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS card_scan_items (
+  id BIGSERIAL PRIMARY KEY,
+  batch_id BIGINT NOT NULL REFERENCES card_scan_batches(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  file_name VARCHAR(255) DEFAULT '',
+  status VARCHAR(16) NOT NULL DEFAULT 'pending', -- pending, failed, review, approved, merged, discarded
+  name VARCHAR(255) DEFAULT '',
+  company VARCHAR(255) DEFAULT '',
+  position VARCHAR(255) DEFAULT '',
+  phone VARCHAR(64) DEFAULT '',
+  email VARCHAR(255) DEFAULT '',
+  address TEXT DEFAULT '',
+  raw_text TEXT DEFAULT '',
+  provider VARCHAR(32) DEFAULT '',
+  confidence DOUBLE PRECISION NOT NULL DEFAULT 0,
+  matches JSONB,
+  error TEXT DEFAULT '',
+  customer_id BIGINT REFERENCES customers(id) ON DELETE SET NULL,
+  reviewed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_card_scan_items_batch_id ON card_scan_items(batch_id, id);
+CREATE INDEX idx_card_scan_items_review ON card_scan_items(user_id) WHERE status = 'review';
@@ -0,0 +1,20 @@
+-- Document extractions (合同 / 增值税发票识别结果：逐字段置信度，确认后生成成交记录或写入客户开票信息)
+CREATE TABLE IF NOT EXISTS document_extractions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  customer_id BIGINT REFERENCES customers(id) ON DELETE SET NULL,
+  doc_type VARCHAR(16) NOT NULL, -- contract, vat_invoice
+  party VARCHAR(16) DEFAULT '', -- 发票：客户是 buyer（购买方）还是 seller（销售方）
+  file_name VARCHAR(255) DEFAULT '',
+  mime_type VARCHAR(64) DEFAULT '',
+  status VARCHAR(16) NOT NULL DEFAULT 'draft', -- draft, confirmed, discarded
+  fields JSONB NOT NULL DEFAULT '{}',
+  provider VARCHAR(32) DEFAULT '',
+  deal_id BIGINT REFERENCES deals(id) ON DELETE SET NULL,
+  confirmed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_document_extractions_user_id ON document_extractions(user_id, created_at DESC);
+CREATE INDEX idx_document_extractions_customer_id ON document_extractions(customer_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS customer_merges;
+DROP TABLE IF EXISTS customer_duplicate_clusters;
+ALTER TABLE customers DROP COLUMN IF EXISTS merged_into_id;
+DROP INDEX IF EXISTS idx_customers_company_trgm;
+DROP INDEX IF EXISTS idx_customers_name_trgm;
+DROP INDEX IF EXISTS idx_customers_credit_code_key;
+DROP INDEX IF EXISTS idx_customers_wechat_key;
+DROP INDEX IF EXISTS idx_customers_email_key;
+DROP INDEX IF EXISTS idx_customers_phone_key;
@@ -0,0 +1,49 @@
+-- Customer duplicate detection (客户查重：规范化的电话 / 邮箱 / 微信号 / 统一社会信用代码精确匹配，姓名和公司用 pg_trgm 模糊匹配)
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_customers_phone_key
+  ON customers (user_id, (regexp_replace(regexp_replace(COALESCE(phone, ''), '[^0-9]', '', 'g'), '^86([0-9]{11})$', '\1')));
+CREATE INDEX IF NOT EXISTS idx_customers_email_key ON customers (user_id, (LOWER(TRIM(COALESCE(email, '')))));
+CREATE INDEX IF NOT EXISTS idx_customers_wechat_key ON customers (user_id, (LOWER(TRIM(COALESCE(wechat_id, '')))));
+CREATE INDEX IF NOT EXISTS idx_customers_credit_code_key ON customers (user_id, (UPPER(TRIM(COALESCE(credit_code, '')))));
+CREATE INDEX IF NOT EXISTS idx_customers_name_trgm ON customers USING GIN (LOWER(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_customers_company_trgm ON customers USING GIN (LOWER(company) gin_trgm_ops);
+
+-- 被合并的客户软删除并指向保留的客户，不出现在归档列表中
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS merged_into_id BIGINT REFERENCES customers(id) ON DELETE SET NULL;
+
+-- Duplicate clusters (后台扫描得到的疑似重复客户组)
+CREATE TABLE IF NOT EXISTS customer_duplicate_clusters (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  member_key TEXT NOT NULL, -- 排序后的客户 ID，用于记住忽略过的组
+  customer_ids JSONB NOT NULL,
+  links JSONB,
+  score DOUBLE PRECISION NOT NULL DEFAULT 0,
+  status VARCHAR(16) NOT NULL DEFAULT 'open', -- open, dismissed, merged
+  scanned_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_customer_duplicate_clusters_member_key ON customer_duplicate_clusters(user_id, member_key);
+CREATE INDEX idx_customer_duplicate_clusters_open ON customer_duplicate_clusters(user_id, score DESC) WHERE status = 'open';
+
+-- Customer merges (合并记录，撤销期内可恢复被合并的客户和迁移的关联记录)
+CREATE TABLE IF NOT EXISTS customer_merges (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  survivor_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  merged_ids JSONB NOT NULL,
+  cluster_id BIGINT REFERENCES customer_duplicate_clusters(id) ON DELETE SET NULL,
+  field_sources JSONB,
+  survivor_before JSONB NOT NULL,
+  moved JSONB,
+  status VARCHAR(16) NOT NULL DEFAULT 'merged', -- merged, undone
+  undo_until TIMESTAMPTZ NOT NULL,
+  undone_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_customer_merges_user_id ON customer_merges(user_id, created_at DESC);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_interactions_custom_fields;
+DROP INDEX IF EXISTS idx_deals_custom_fields;
+DROP INDEX IF EXISTS idx_customers_custom_fields;
+ALTER TABLE interactions DROP COLUMN IF EXISTS custom_fields;
+ALTER TABLE deals DROP COLUMN IF EXISTS custom_fields;
+ALTER TABLE customers DROP COLUMN IF EXISTS custom_fields;
+DROP TABLE IF EXISTS custom_field_definitions;
@@ -0,0 +1,28 @@
+-- Custom field definitions (管理员按实体和团队定义的自定义字段，team_id 为 NULL 表示全局，团队定义覆盖同 key 的全局定义)
+CREATE TABLE IF NOT EXISTS custom_field_definitions (
+  id BIGSERIAL PRIMARY KEY,
+  entity VARCHAR(16) NOT NULL, -- customer, deal, interaction
+  team_id BIGINT REFERENCES teams(id) ON DELETE CASCADE,
+  key VARCHAR(64) NOT NULL,
+  label VARCHAR(128) NOT NULL,
+  type VARCHAR(16) NOT NULL, -- text, number, date, select, multi_select, user
+  options JSONB NOT NULL DEFAULT '[]', -- select / multi_select 的可选值
+  required BOOLEAN NOT NULL DEFAULT FALSE,
+  validation JSONB NOT NULL DEFAULT '{}', -- {"min", "max", "max_length", "pattern"}
+  position INT NOT NULL DEFAULT 0,
+  created_by BIGINT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_custom_field_definitions_entity_team_key ON custom_field_definitions(entity, COALESCE(team_id, 0), key);
+CREATE INDEX idx_custom_field_definitions_team_id ON custom_field_definitions(team_id);
+
+-- 自定义字段取值：key → 值（数字、YYYY-MM-DD 日期、字符串、字符串数组或用户 ID）
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS custom_fields JSONB DEFAULT '{}';
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS custom_fields JSONB DEFAULT '{}';
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS custom_fields JSONB DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_customers_custom_fields ON customers USING GIN (custom_fields jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_deals_custom_fields ON deals USING GIN (custom_fields jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_interactions_custom_fields ON interactions USING GIN (custom_fields jsonb_path_ops);
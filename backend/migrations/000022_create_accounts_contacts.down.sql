@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS deal_contacts;
+DROP TABLE IF EXISTS interaction_contacts;
+ALTER TABLE deals DROP COLUMN IF EXISTS account_id;
+ALTER TABLE interactions DROP COLUMN IF EXISTS account_id;
+ALTER TABLE customers DROP COLUMN IF EXISTS account_id;
+DROP TABLE IF EXISTS contacts;
+DROP TABLE IF EXISTS accounts;
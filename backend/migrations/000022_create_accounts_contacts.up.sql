@@ -0,0 +1,121 @@
+-- Accounts (公司) and contacts (公司里的联系人)：客户记录保留销售信息（阶段、意向、合同），
+-- 公司信息放在账户上，个人信息放在联系人上
+CREATE TABLE IF NOT EXISTS accounts (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  industry VARCHAR(100),
+  company_scale VARCHAR(64),
+  registered_capital VARCHAR(64),
+  legal_person VARCHAR(128),
+  credit_code VARCHAR(64),
+  address TEXT,
+  invoice_title VARCHAR(255),
+  tax_number VARCHAR(64),
+  bank_account VARCHAR(255),
+  notes TEXT,
+  split_key TEXT, -- 仅拆分现有客户时使用，结束后删除
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_accounts_user_name ON accounts(user_id, (LOWER(TRIM(name))));
+CREATE INDEX idx_accounts_deleted_at ON accounts(deleted_at);
+
+CREATE TABLE IF NOT EXISTS contacts (
+  id BIGSERIAL PRIMARY KEY,
+  account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  customer_id BIGINT REFERENCES customers(id) ON DELETE SET NULL, -- 由该客户记录拆分而来，客户的个人信息修改后同步
+  name VARCHAR(255) NOT NULL,
+  position VARCHAR(255),
+  phone VARCHAR(50),
+  email VARCHAR(255),
+  wechat_id VARCHAR(128),
+  role VARCHAR(32), -- decision_maker, influencer, champion, blocker
+  is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+  notes TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_contacts_account_id ON contacts(account_id);
+CREATE INDEX idx_contacts_customer_id ON contacts(customer_id);
+CREATE INDEX idx_contacts_deleted_at ON contacts(deleted_at);
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS account_id BIGINT REFERENCES accounts(id) ON DELETE SET NULL;
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS account_id BIGINT REFERENCES accounts(id) ON DELETE SET NULL;
+ALTER TABLE deals ADD COLUMN IF NOT EXISTS account_id BIGINT REFERENCES accounts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_customers_account_id ON customers(account_id);
+CREATE INDEX IF NOT EXISTS idx_interactions_account_id ON interactions(account_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_deals_account_id ON deals(account_id, deal_at DESC);
+
+-- 跟进记录和成交关联的具体联系人
+CREATE TABLE IF NOT EXISTS interaction_contacts (
+  interaction_id BIGINT NOT NULL REFERENCES interactions(id) ON DELETE CASCADE,
+  contact_id BIGINT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+  PRIMARY KEY (interaction_id, contact_id)
+);
+
+CREATE TABLE IF NOT EXISTS deal_contacts (
+  deal_id BIGINT NOT NULL REFERENCES deals(id) ON DELETE CASCADE,
+  contact_id BIGINT NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+  PRIMARY KEY (deal_id, contact_id)
+);
+
+CREATE INDEX idx_interaction_contacts_contact_id ON interaction_contacts(contact_id);
+CREATE INDEX idx_deal_contacts_contact_id ON deal_contacts(contact_id);
+
+-- 拆分现有客户：同一用户下公司名相同的客户归入同一个账户，没有公司名的客户各自成为一个账户
+-- （以姓名为账户名）；公司信息取组内最新的非空值。被合并的客户不再单独拆分
+CREATE TEMP TABLE customer_account_split AS
+SELECT id AS customer_id, user_id,
+       CASE WHEN TRIM(COALESCE(company, '')) <> '' THEN 'company:' || LOWER(TRIM(company))
+            ELSE 'customer:' || id END AS split_key
+FROM customers
+WHERE merged_into_id IS NULL;
+
+INSERT INTO accounts (user_id, name, industry, company_scale, registered_capital, legal_person, credit_code,
+                      address, invoice_title, tax_number, bank_account, split_key, created_at, updated_at)
+SELECT s.user_id,
+       COALESCE(NULLIF(TRIM((ARRAY_AGG(c.company ORDER BY c.updated_at DESC))[1]), ''), MIN(c.name)),
+       (ARRAY_AGG(c.industry ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.industry, '') <> ''))[1],
+       (ARRAY_AGG(c.company_scale ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.company_scale, '') <> ''))[1],
+       (ARRAY_AGG(c.registered_capital ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.registered_capital, '') <> ''))[1],
+       (ARRAY_AGG(c.legal_person ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.legal_person, '') <> ''))[1],
+       (ARRAY_AGG(c.credit_code ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.credit_code, '') <> ''))[1],
+       (ARRAY_AGG(c.address ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.address, '') <> ''))[1],
+       (ARRAY_AGG(c.invoice_title ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.invoice_title, '') <> ''))[1],
+       (ARRAY_AGG(c.tax_number ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.tax_number, '') <> ''))[1],
+       (ARRAY_AGG(c.bank_account ORDER BY c.updated_at DESC) FILTER (WHERE COALESCE(c.bank_account, '') <> ''))[1],
+       s.split_key, MIN(c.created_at), MAX(c.updated_at)
+FROM customer_account_split s
+JOIN customers c ON c.id = s.customer_id
+GROUP BY s.user_id, s.split_key;
+
+UPDATE customers c SET account_id = a.id
+FROM customer_account_split s
+JOIN accounts a ON a.user_id = s.user_id AND a.split_key = s.split_key
+WHERE c.id = s.customer_id;
+
+-- 每个客户记录的个人信息成为一个联系人，账户中最早的一个为主要联系人
+INSERT INTO contacts (account_id, user_id, customer_id, name, position, phone, email, wechat_id, is_primary, created_at, updated_at)
+SELECT account_id, user_id, id, name, position, phone, email, wechat_id,
+       ROW_NUMBER() OVER (PARTITION BY account_id ORDER BY created_at, id) = 1,
+       created_at, updated_at
+FROM customers
+WHERE account_id IS NOT NULL AND TRIM(COALESCE(name, '')) <> '';
+
+UPDATE interactions i SET account_id = c.account_id FROM customers c WHERE c.id = i.customer_id;
+UPDATE deals d SET account_id = c.account_id FROM customers c WHERE c.id = d.customer_id;
+
+INSERT INTO interaction_contacts (interaction_id, contact_id)
+SELECT i.id, ct.id FROM interactions i JOIN contacts ct ON ct.customer_id = i.customer_id;
+INSERT INTO deal_contacts (deal_id, contact_id)
+SELECT d.id, ct.id FROM deals d JOIN contacts ct ON ct.customer_id = d.customer_id;
+
+ALTER TABLE accounts DROP COLUMN split_key;
+DROP TABLE customer_account_split;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS segments;
+DROP INDEX IF EXISTS idx_customers_tags;
+ALTER TABLE customers DROP COLUMN IF EXISTS tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS tag_groups;
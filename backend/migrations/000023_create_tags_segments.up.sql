@@ -0,0 +1,50 @@
+-- Tag groups (管理员按团队维护的客户标签分组，team_id 为 NULL 表示全局；exclusive 组内的标签互斥)
+CREATE TABLE IF NOT EXISTS tag_groups (
+  id BIGSERIAL PRIMARY KEY,
+  team_id BIGINT REFERENCES teams(id) ON DELETE CASCADE,
+  name VARCHAR(64) NOT NULL,
+  color VARCHAR(16),
+  exclusive BOOLEAN NOT NULL DEFAULT FALSE,
+  position INT NOT NULL DEFAULT 0,
+  created_by BIGINT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_tag_groups_team_name ON tag_groups(COALESCE(team_id, 0), LOWER(name));
+
+-- Tags（客户上保存的是标签名，改名和删除时同步到客户）
+CREATE TABLE IF NOT EXISTS tags (
+  id BIGSERIAL PRIMARY KEY,
+  group_id BIGINT NOT NULL REFERENCES tag_groups(id) ON DELETE CASCADE,
+  team_id BIGINT REFERENCES teams(id) ON DELETE CASCADE, -- 与所属分组相同
+  name VARCHAR(64) NOT NULL,
+  color VARCHAR(16),
+  position INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_tags_team_name ON tags(COALESCE(team_id, 0), LOWER(name));
+CREATE INDEX idx_tags_group_id ON tags(group_id);
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_customers_tags ON customers USING GIN(tags);
+
+-- Segments (保存的客户筛选条件，每次使用时按当前数据重新计算；shared 时同团队成员可见)
+CREATE TABLE IF NOT EXISTS segments (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  team_id BIGINT REFERENCES teams(id) ON DELETE SET NULL,
+  name VARCHAR(128) NOT NULL,
+  description TEXT,
+  filter JSONB NOT NULL, -- 过滤树，与 /query 的 filter 相同
+  shared BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_segments_user_id ON segments(user_id);
+CREATE INDEX idx_segments_team_id ON segments(team_id) WHERE shared;
+CREATE INDEX idx_segments_deleted_at ON segments(deleted_at);
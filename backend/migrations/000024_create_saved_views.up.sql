@@ -0,0 +1,19 @@
+-- 保存的列表视图：过滤条件 + 显示列 + 排序，可共享给同团队成员
+CREATE TABLE IF NOT EXISTS saved_views (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  team_id BIGINT REFERENCES teams(id) ON DELETE SET NULL,
+  entity VARCHAR(32) NOT NULL, -- customers, deals, interactions, knowledge
+  name VARCHAR(128) NOT NULL,
+  filter JSONB, -- 过滤树，与 /query 的 filter 相同
+  columns JSONB NOT NULL DEFAULT '[]',
+  sort JSONB NOT NULL DEFAULT '[]',
+  shared BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_saved_views_user_entity ON saved_views(user_id, entity);
+CREATE INDEX idx_saved_views_team_entity ON saved_views(team_id, entity) WHERE shared;
+CREATE INDEX idx_saved_views_deleted_at ON saved_views(deleted_at);
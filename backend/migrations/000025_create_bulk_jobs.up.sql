@@ -0,0 +1,23 @@
+-- 客户批量操作：更新、归档、恢复、删除、转移负责人，逐条记录结果
+CREATE TABLE IF NOT EXISTS bulk_jobs (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  operation VARCHAR(16) NOT NULL, -- update, archive, restore, delete, reassign
+  status VARCHAR(16) NOT NULL DEFAULT 'queued', -- queued, running, completed, failed
+  params JSONB, -- 更新的字段或转移的目标负责人
+  customer_ids JSONB NOT NULL DEFAULT '[]',
+  total INT NOT NULL DEFAULT 0,
+  done INT NOT NULL DEFAULT 0,
+  succeeded INT NOT NULL DEFAULT 0,
+  failed INT NOT NULL DEFAULT 0,
+  results JSONB NOT NULL DEFAULT '[]', -- [{"customer_id": 1, "ok": false, "error": "access denied"}]
+  activity_id BIGINT,
+  error TEXT,
+  started_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_bulk_jobs_user_id ON bulk_jobs(user_id, created_at DESC);
+CREATE INDEX idx_bulk_jobs_status ON bulk_jobs(status) WHERE status IN ('queued', 'running');
@@ -0,0 +1,193 @@
+// Package audio 把长录音切成固定时长的片段，供语音识别逐段转写
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ErrInvalidWAV WAV 文件头无法解析
+var ErrInvalidWAV = errors.New("invalid wav file")
+
+// Chunk 一段音频；Offset / Duration 为秒，Duration 未知时为 0
+type Chunk struct {
+	Index    int
+	Data     []byte
+	Format   string
+	Offset   float64
+	Duration float64
+}
+
+// Splitter 按 ChunkSeconds 切分音频。WAV 直接按 PCM 数据切；其他格式先用 ffmpeg
+// 转成 16kHz 单声道 WAV 再切。没有 ffmpeg 时整段作为一个片段返回。
+type Splitter struct {
+	FFmpegPath   string
+	ChunkSeconds int
+}
+
+func NewSplitter(ffmpegPath string, chunkSeconds int) *Splitter {
+	if chunkSeconds <= 0 {
+		chunkSeconds = 60
+	}
+	return &Splitter{FFmpegPath: ffmpegPath, ChunkSeconds: chunkSeconds}
+}
+
+// CanTranscode 是否找得到 ffmpeg
+func (s *Splitter) CanTranscode() bool {
+	if s.FFmpegPath == "" {
+		return false
+	}
+	_, err := exec.LookPath(s.FFmpegPath)
+	return err == nil
+}
+
+// Split 切分音频，返回按时间顺序排列的片段
+func (s *Splitter) Split(ctx context.Context, data []byte, format string) ([]Chunk, error) {
+	if format != "wav" {
+		if !s.CanTranscode() {
+			return []Chunk{{Data: data, Format: format}}, nil
+		}
+		wav, err := s.toWAV(ctx, data, format)
+		if err != nil {
+			return nil, err
+		}
+		data = wav
+	}
+	return SplitWAV(data, s.ChunkSeconds)
+}
+
+// toWAV 用 ffmpeg 转码；输入写临时文件（m4a 等容器格式无法从管道读取），输出走 stdout
+func (s *Splitter) toWAV(ctx context.Context, data []byte, format string) ([]byte, error) {
+	in, err := os.CreateTemp("", "nextcrm-audio-*."+format)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(in.Name())
+	if _, err := in.Write(data); err != nil {
+		in.Close()
+		return nil, err
+	}
+	if err := in.Close(); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.FFmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-i", in.Name(),
+		"-ac", "1", "-ar", "16000", "-f", "wav", "pipe:1")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// wavFormat fmt 块中切分需要的字段
+type wavFormat struct {
+	raw        []byte // 原始 fmt 块内容，写回每个片段
+	byteRate   uint32
+	blockAlign uint16
+}
+
+// SplitWAV 按 PCM 数据切分 WAV，每个片段带独立的文件头
+func SplitWAV(data []byte, chunkSeconds int) ([]Chunk, error) {
+	format, pcm, err := parseWAV(data)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSeconds <= 0 {
+		chunkSeconds = 60
+	}
+	pcm = pcm[:len(pcm)-len(pcm)%int(format.blockAlign)] // 丢掉末尾不完整的采样帧
+
+	chunkBytes := int(format.byteRate) * chunkSeconds
+	chunkBytes -= chunkBytes % int(format.blockAlign)
+	if chunkBytes <= 0 {
+		return nil, ErrInvalidWAV
+	}
+
+	var chunks []Chunk
+	for offset := 0; offset < len(pcm); offset += chunkBytes {
+		end := offset + chunkBytes
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+		chunks = append(chunks, Chunk{
+			Index:    len(chunks),
+			Data:     buildWAV(format.raw, pcm[offset:end]),
+			Format:   "wav",
+			Offset:   float64(offset) / float64(format.byteRate),
+			Duration: float64(end-offset) / float64(format.byteRate),
+		})
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: no audio data", ErrInvalidWAV)
+	}
+	return chunks, nil
+}
+
+// parseWAV 解析 RIFF 块，返回 fmt 信息和 PCM 数据。ffmpeg 写管道时不知道总长度，
+// data 块长度可能是 0 或 0xFFFFFFFF，此时取到文件末尾。
+func parseWAV(data []byte) (*wavFormat, []byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, nil, ErrInvalidWAV
+	}
+
+	var format *wavFormat
+	pos := 12
+	for pos+8 <= len(data) {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := pos + 8
+
+		switch id {
+		case "fmt ":
+			if size < 16 || body+size > len(data) {
+				return nil, nil, fmt.Errorf("%w: bad fmt chunk", ErrInvalidWAV)
+			}
+			raw := data[body : body+size]
+			format = &wavFormat{
+				raw:        raw,
+				byteRate:   binary.LittleEndian.Uint32(raw[8:12]),
+				blockAlign: binary.LittleEndian.Uint16(raw[12:14]),
+			}
+			if format.byteRate == 0 || format.blockAlign == 0 {
+				return nil, nil, fmt.Errorf("%w: bad fmt chunk", ErrInvalidWAV)
+			}
+		case "data":
+			if format == nil {
+				return nil, nil, fmt.Errorf("%w: data before fmt", ErrInvalidWAV)
+			}
+			end := body + size
+			if size == 0 || end > len(data) || end < body {
+				end = len(data)
+			}
+			return format, data[body:end], nil
+		}
+
+		pos = body + size + size%2 // 块按偶数字节对齐
+	}
+	return nil, nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWAV)
+}
+
+// buildWAV 用原 fmt 块和一段 PCM 数据拼出完整的 WAV 文件
+func buildWAV(fmtChunk, pcm []byte) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 20+len(fmtChunk)+8+len(pcm)))
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(4+8+len(fmtChunk)+8+len(pcm)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(len(fmtChunk)))
+	buf.Write(fmtChunk)
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
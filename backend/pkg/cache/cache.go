@@ -0,0 +1,173 @@
+// Package cache 进程内的 TTL 缓存：条目可打标签按标签批量失效，
+// 并提供 singleflight，同一个 key 的并发请求只执行一次
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value     interface{}
+	expiresAt time.Time
+	tags      []string
+}
+
+// Cache 并发安全的 TTL 缓存，超过容量时先清理过期条目，再淘汰最早过期的条目
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*entry
+	tags       map[string]map[string]struct{}
+	now        func() time.Time
+}
+
+// New maxEntries <= 0 表示不限容量
+func New(maxEntries int) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*entry),
+		tags:       make(map[string]map[string]struct{}),
+		now:        time.Now,
+	}
+}
+
+// Get 返回未过期的值
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.expiresAt) {
+		c.remove(key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set 写入值，ttl <= 0 时不缓存
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration, tags ...string) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		c.remove(key)
+	}
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[key] = &entry{value: value, expiresAt: c.now().Add(ttl), tags: tags}
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// Invalidate 删除带有该标签的所有条目，返回删除的条数
+func (c *Cache) Invalidate(tag string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := c.tags[tag]
+	// remove 会从 keys 中删除，先记下条数
+	n := len(keys)
+	for key := range keys {
+		c.remove(key)
+	}
+	return n
+}
+
+// Purge 清空缓存
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*entry)
+	c.tags = make(map[string]map[string]struct{})
+}
+
+// Len 当前条目数（含尚未清理的过期条目）
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// evict 先清理过期条目，仍然满时淘汰最早过期的一条
+func (c *Cache) evict() {
+	now := c.now()
+	var oldestKey string
+	var oldest time.Time
+	for key, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			c.remove(key)
+			continue
+		}
+		if oldestKey == "" || e.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, e.expiresAt
+		}
+	}
+	if len(c.entries) >= c.maxEntries && oldestKey != "" {
+		c.remove(oldestKey)
+	}
+}
+
+func (c *Cache) remove(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	for _, tag := range e.tags {
+		if keys, ok := c.tags[tag]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
+
+type call struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
+}
+
+// Group 合并同一个 key 的并发调用：第一个调用执行 fn，其余的等待并共享结果
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// Do 执行或等待 key 对应的调用；shared 表示结果来自其他调用方
+func (g *Group) Do(key string, fn func() (interface{}, error)) (value interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.value, c.err, true
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.value, c.err = fn()
+	return c.value, c.err, false
+}
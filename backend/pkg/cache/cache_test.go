@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newTestCache(maxEntries int) (*Cache, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := New(maxEntries)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestSetGetExpiry(t *testing.T) {
+	c, clock := newTestCache(0)
+	c.Set("a", 1, time.Minute)
+	c.Set("skip", 2, 0)
+
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if _, ok := c.Get("skip"); ok {
+		t.Fatal("ttl <= 0 should not be cached")
+	}
+
+	clock.advance(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("entry should expire once its ttl has passed")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expired entry should be removed on Get, Len = %d", c.Len())
+	}
+}
+
+func TestSetReplacesTags(t *testing.T) {
+	c, _ := newTestCache(0)
+	c.Set("a", 1, time.Minute, "customer:1")
+	c.Set("a", 2, time.Minute, "customer:2")
+
+	if n := c.Invalidate("customer:1"); n != 0 {
+		t.Fatalf("old tag should no longer point at the entry, Invalidate = %d", n)
+	}
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Fatalf("Get(a) = %v, %v; want 2, true", v, ok)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	c, _ := newTestCache(0)
+	c.Set("a", 1, time.Minute, "customer:1")
+	c.Set("b", 2, time.Minute, "customer:1", "user:7")
+	c.Set("c", 3, time.Minute, "user:7")
+
+	if n := c.Invalidate("customer:1"); n != 2 {
+		t.Fatalf("Invalidate(customer:1) = %d, want 2", n)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a should be invalidated")
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should be invalidated")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatal("c should survive")
+	}
+	// b 已删除，user:7 只剩 c
+	if n := c.Invalidate("user:7"); n != 1 {
+		t.Fatalf("Invalidate(user:7) = %d, want 1", n)
+	}
+	if n := c.Invalidate("unknown"); n != 0 {
+		t.Fatalf("Invalidate(unknown) = %d, want 0", n)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	t.Run("expired entries first", func(t *testing.T) {
+		c, clock := newTestCache(2)
+		c.Set("short", 1, time.Second)
+		c.Set("long", 2, time.Hour)
+		clock.advance(2 * time.Second)
+		c.Set("new", 3, time.Hour)
+
+		if _, ok := c.Get("long"); !ok {
+			t.Fatal("live entry should be kept while an expired one can be dropped")
+		}
+		if _, ok := c.Get("new"); !ok {
+			t.Fatal("new entry should be stored")
+		}
+		if c.Len() != 2 {
+			t.Fatalf("Len = %d, want 2", c.Len())
+		}
+	})
+
+	t.Run("earliest expiry when full", func(t *testing.T) {
+		c, _ := newTestCache(2)
+		c.Set("soon", 1, time.Minute, "tag")
+		c.Set("later", 2, time.Hour)
+		c.Set("new", 3, time.Hour)
+
+		if _, ok := c.Get("soon"); ok {
+			t.Fatal("entry expiring first should be evicted")
+		}
+		if _, ok := c.Get("later"); !ok {
+			t.Fatal("later should be kept")
+		}
+		if n := c.Invalidate("tag"); n != 0 {
+			t.Fatalf("evicted entry should be dropped from its tags, Invalidate = %d", n)
+		}
+	})
+}
+
+func TestGroupDo(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	const waiters = 5
+	var wg sync.WaitGroup
+	results := make([]interface{}, waiters+1)
+	shared := make([]bool, waiters+1)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], _, shared[0] = g.Do("k", func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			close(started)
+			<-release
+			return "v", nil
+		})
+	}()
+	<-started
+
+	for i := 1; i <= waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, shared[i] = g.Do("k", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				return "other", nil
+			})
+		}(i)
+	}
+	// 给等待者时间进入等待，再放行第一个调用
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("fn ran %d times, want 1", calls)
+	}
+	if shared[0] {
+		t.Fatal("the first caller should not be marked shared")
+	}
+	for i, v := range results {
+		if v != "v" {
+			t.Fatalf("caller %d got %v, want v", i, v)
+		}
+		if i > 0 && !shared[i] {
+			t.Fatalf("caller %d should be marked shared", i)
+		}
+	}
+
+	// 完成后 key 被释放，下次调用重新执行，错误原样返回
+	boom := errors.New("boom")
+	if _, err, sh := g.Do("k", func() (interface{}, error) { return nil, boom }); err != boom || sh {
+		t.Fatalf("Do after completion = %v, shared %v; want boom, false", err, sh)
+	}
+}
@@ -21,8 +21,13 @@ type CustomerRow struct {
 	Stage       string
 	Source      string
 	Notes       string
+	// Extra 固定列之后的列：表头 → 单元格内容（如自定义字段）
+	Extra       map[string]string
 }
 
+// fixedColumns 固定列数：姓名,公司,职位,电话,邮箱,行业,预算,意向度,阶段,来源,备注
+const fixedColumns = 11
+
 // ParseCustomersFromCSV parses customer data from a CSV file
 func ParseCustomersFromCSV(reader io.Reader) ([]*CustomerRow, error) {
 	r := csv.NewReader(reader)
@@ -88,6 +93,16 @@ func ParseCustomersFromCSV(reader io.Reader) ([]*CustomerRow, error) {
 		if len(record) > 10 {
 			customer.Notes = strings.TrimSpace(record[10])
 		}
+		for col := fixedColumns; col < len(record) && col < len(records[0]); col++ {
+			header := strings.TrimSpace(records[0][col])
+			if header == "" {
+				continue
+			}
+			if customer.Extra == nil {
+				customer.Extra = make(map[string]string)
+			}
+			customer.Extra[header] = strings.TrimSpace(record[col])
+		}
 
 		// Skip rows without required fields
 		if customer.Name == "" || customer.Company == "" || customer.Phone == "" {
@@ -2,11 +2,15 @@ package deepseek
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/xia/nextcrm/pkg/sse"
 )
 
 type Client struct {
@@ -15,6 +19,7 @@ type Client struct {
 	model     string
 	embedModel string
 	client    *http.Client
+	streamClient *http.Client // 流式请求不设整体超时，由 ctx 控制
 }
 
 func NewClient(apiKey, baseURL, model, embedModel string) *Client {
@@ -26,15 +31,35 @@ func NewClient(apiKey, baseURL, model, embedModel string) *Client {
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+		streamClient: &http.Client{},
 	}
 }
 
+// SetTransport replaces the underlying HTTP transport (e.g. record/replay) for both plain and streaming requests
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.client.Transport = rt
+	c.streamClient.Transport = rt
+}
+
 // ChatRequest represents a chat completion request
 type ChatRequest struct {
 	Model       string        `json:"model"`
 	Messages    []ChatMessage `json:"messages"`
 	Temperature float64       `json:"temperature,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat {"type": "json_object"} enables JSON output mode
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// StreamOptions asks the server to append a usage chunk at the end of the stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type ChatMessage struct {
@@ -64,21 +89,45 @@ type ChatUsage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// Model returns the chat model name
+func (c *Client) Model() string {
+	return c.model
+}
+
+// EmbeddingModel returns the embedding model name
+func (c *Client) EmbeddingModel() string {
+	return c.embedModel
+}
+
 // Chat sends a chat completion request
-func (c *Client) Chat(messages []ChatMessage) (*ChatResponse, error) {
-	req := ChatRequest{
+func (c *Client) Chat(ctx context.Context, messages []ChatMessage) (*ChatResponse, error) {
+	return c.chat(ctx, ChatRequest{
 		Model:       c.model,
 		Messages:    messages,
 		Temperature: 0.7,
 		MaxTokens:   2000,
-	}
+	})
+}
+
+// ChatJSON sends a chat completion request in JSON output mode.
+// The prompt must mention "json" and describe the expected object.
+func (c *Client) ChatJSON(ctx context.Context, messages []ChatMessage) (*ChatResponse, error) {
+	return c.chat(ctx, ChatRequest{
+		Model:          c.model,
+		Messages:       messages,
+		Temperature:    0.7,
+		MaxTokens:      2000,
+		ResponseFormat: &ResponseFormat{Type: "json_object"},
+	})
+}
 
+func (c *Client) chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequest("POST", c.baseURL+"/v1/chat/completions", bytes.NewReader(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/chat/completions", bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -108,3 +157,94 @@ func (c *Client) Chat(messages []ChatMessage) (*ChatResponse, error) {
 
 	return &chatResp, nil
 }
+
+// ChatStreamChunk represents one chunk of a streamed chat completion
+type ChatStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *ChatUsage `json:"usage"`
+}
+
+// ChatStream sends a streaming chat completion request (stream: true).
+// onDelta is called for every content fragment; the returned response carries
+// the full content, finish reason and usage.
+func (c *Client) ChatStream(ctx context.Context, messages []ChatMessage, onDelta func(string) error) (*ChatResponse, error) {
+	req := ChatRequest{
+		Model:         c.model,
+		Messages:      messages,
+		Temperature:   0.7,
+		MaxTokens:     2000,
+		Stream:        true,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	}
+
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/chat/completions", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.streamClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error: %s", string(body))
+	}
+
+	var content strings.Builder
+	out := &ChatResponse{Model: c.model}
+	choice := ChatChoice{Message: ChatMessage{Role: "assistant"}}
+
+	err = sse.Read(resp.Body, func(ev sse.Event) error {
+		if ev.Data == "[DONE]" {
+			return io.EOF
+		}
+		var chunk ChatStreamChunk
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.Usage = *chunk.Usage
+		}
+		for _, ch := range chunk.Choices {
+			if ch.FinishReason != "" {
+				choice.FinishReason = ch.FinishReason
+			}
+			if ch.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(ch.Delta.Content)
+			if err := onDelta(ch.Delta.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	choice.Message.Content = content.String()
+	out.Choices = []ChatChoice{choice}
+	return out, nil
+}
@@ -2,6 +2,7 @@ package deepseek
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -34,7 +35,7 @@ type EmbedUsage struct {
 }
 
 // CreateEmbedding creates embeddings for the given texts
-func (c *Client) CreateEmbedding(text string) (*EmbeddingResponse, error) {
+func (c *Client) CreateEmbedding(ctx context.Context, text string) (*EmbeddingResponse, error) {
 	req := EmbeddingRequest{
 		Model: c.embedModel,
 		Input: []string{text},
@@ -45,7 +46,7 @@ func (c *Client) CreateEmbedding(text string) (*EmbeddingResponse, error) {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequest("POST", c.baseURL+"/v1/embeddings", bytes.NewReader(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/embeddings", bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
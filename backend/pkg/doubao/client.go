@@ -2,12 +2,16 @@ package doubao
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/xia/nextcrm/pkg/sse"
 )
 
 // ChatMessage 聊天消息（兼容 OpenAI 格式）
@@ -25,6 +29,14 @@ type ChatResponse struct {
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+// Usage token 用量（Responses API 格式）
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
 }
 
 // Client 豆包多模态客户端
@@ -33,6 +45,7 @@ type Client struct {
 	APIKey     string
 	Model      string
 	httpClient *http.Client
+	streamClient *http.Client // 流式请求不设整体超时，由 ctx 控制
 }
 
 // NewClient 创建豆包客户端
@@ -44,15 +57,24 @@ func NewClient(baseURL, apiKey, model string) *Client {
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+		streamClient: &http.Client{},
 	}
 }
 
+// SetTransport 替换底层 HTTP 传输（如录制 / 回放），普通请求和流式请求共用
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+	c.streamClient.Transport = rt
+}
+
 // ContentItem 消息内容项
 type ContentItem struct {
 	Type      string `json:"type"`
 	Text      string `json:"text,omitempty"`
 	ImageURL  string `json:"image_url,omitempty"`
 	AudioURL  string `json:"audio_url,omitempty"`
+	FileData  string `json:"file_data,omitempty"` // input_file：base64 数据 URL（PDF）
+	Filename  string `json:"filename,omitempty"`
 }
 
 // Message 消息
@@ -80,24 +102,10 @@ type Choice struct {
 }
 
 // Chat 文本对话（兼容 OpenAI 格式）
-func (c *Client) Chat(messages []ChatMessage) (*ChatResponse, error) {
-	// 转换为豆包格式
-	input := make([]Message, len(messages))
-	for i, msg := range messages {
-		input[i] = Message{
-			Role: msg.Role,
-			Content: []ContentItem{
-				{
-					Type: "input_text",
-					Text: msg.Content,
-				},
-			},
-		}
-	}
-
+func (c *Client) Chat(ctx context.Context, messages []ChatMessage) (*ChatResponse, error) {
 	reqBody := map[string]interface{}{
 		"model": c.Model,
-		"input": input,
+		"input": toInput(messages),
 	}
 
 	body, err := json.Marshal(reqBody)
@@ -107,7 +115,7 @@ func (c *Client) Chat(messages []ChatMessage) (*ChatResponse, error) {
 
 	// 创建 HTTP 请求 - 使用正确的端点 /responses
 	url := fmt.Sprintf("%s/responses", c.BaseURL)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -142,6 +150,7 @@ func (c *Client) Chat(messages []ChatMessage) (*ChatResponse, error) {
 				Text string `json:"text,omitempty"`
 			} `json:"content"`
 		} `json:"output"`
+		Usage Usage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
@@ -181,11 +190,135 @@ func (c *Client) Chat(messages []ChatMessage) (*ChatResponse, error) {
 				FinishReason: "stop",
 			},
 		},
+		Usage: apiResp.Usage,
 	}, nil
 }
 
-// SpeechToText 语音识别
-func (c *Client) SpeechToText(audioData []byte, format string) (string, error) {
+// toInput 转换为豆包 Responses API 的 input 格式
+func toInput(messages []ChatMessage) []Message {
+	input := make([]Message, len(messages))
+	for i, msg := range messages {
+		input[i] = Message{
+			Role: msg.Role,
+			Content: []ContentItem{
+				{
+					Type: "input_text",
+					Text: msg.Content,
+				},
+			},
+		}
+	}
+	return input
+}
+
+// ChatStream 流式文本对话（Responses API stream: true）
+// onDelta 在每个增量文本片段到达时调用，返回完整内容
+func (c *Client) ChatStream(ctx context.Context, messages []ChatMessage, onDelta func(string) error) (*ChatResponse, error) {
+	reqBody := map[string]interface{}{
+		"model":  c.Model,
+		"input":  toInput(messages),
+		"stream": true,
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/responses", c.BaseURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+
+	resp, err := c.streamClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var content strings.Builder
+	finishReason := "stop"
+	var usage Usage
+	err = sse.Read(resp.Body, func(ev sse.Event) error {
+		if ev.Data == "[DONE]" {
+			return io.EOF
+		}
+		var chunk struct {
+			Type  string `json:"type"`
+			Delta string `json:"delta"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+			Response *struct {
+				Usage *Usage `json:"usage"`
+			} `json:"response"`
+		}
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream event: %w", err)
+		}
+		if chunk.Response != nil && chunk.Response.Usage != nil {
+			usage = *chunk.Response.Usage
+		}
+		switch chunk.Type {
+		case "response.output_text.delta":
+			if chunk.Delta == "" {
+				return nil
+			}
+			content.WriteString(chunk.Delta)
+			return onDelta(chunk.Delta)
+		case "response.incomplete":
+			finishReason = "length"
+		case "response.failed", "error":
+			if chunk.Error != nil {
+				return fmt.Errorf("stream error: %s", chunk.Error.Message)
+			}
+			return fmt.Errorf("stream error: %s", ev.Data)
+		case "response.completed":
+			return io.EOF
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	out := &ChatResponse{}
+	out.Choices = make([]struct {
+		Message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	}, 1)
+	out.Choices[0].Message.Role = "assistant"
+	out.Choices[0].Message.Content = content.String()
+	out.Choices[0].FinishReason = finishReason
+	out.Usage = usage
+	return out, nil
+}
+
+// SpeechToText 语音识别，返回识别文本和 token 用量
+func (c *Client) SpeechToText(ctx context.Context, audioData []byte, format string) (string, Usage, error) {
+	return c.SpeechToTextWithPrompt(ctx, audioData, format, SpeechPrompt)
+}
+
+// SpeechPrompt 语音转文字提示词
+const SpeechPrompt = "请将这段语音转换为文字，只返回转录的文字内容，不要添加任何解释或说明。"
+
+// DiarizedSpeechPrompt 区分说话人的语音转文字提示词
+const DiarizedSpeechPrompt = "请将这段语音转换为文字，并区分不同的说话人。按说话顺序逐行输出，每行格式为「说话人1：内容」，同一说话人始终使用同一编号。只返回转录内容，不要添加任何解释或说明。"
+
+// SpeechToTextWithPrompt 按指定提示词转写语音，返回文本和 token 用量
+func (c *Client) SpeechToTextWithPrompt(ctx context.Context, audioData []byte, format, prompt string) (string, Usage, error) {
 	// 将音频转换为 base64
 	audioBase64 := base64.StdEncoding.EncodeToString(audioData)
 	// 创建数据 URL
@@ -203,22 +336,55 @@ func (c *Client) SpeechToText(audioData []byte, format string) (string, error) {
 					},
 					{
 						Type: "input_text",
-						Text: "请将这段语音转换为文字，只返回转录的文字内容，不要添加任何解释或说明。",
+						Text: prompt,
 					},
 				},
 			},
 		},
 	}
 
-	return c.sendRequest(req)
+	return c.sendRequest(ctx, req)
 }
 
+// BusinessCardPrompt 名片识别提示词
+const BusinessCardPrompt = `请识别这张名片上的信息，并以JSON格式返回，包含以下字段：
+- name: 姓名
+- company: 公司名称
+- position: 职位
+- phone: 电话
+- email: 邮箱
+- address: 地址
+
+只返回JSON，不要添加其他说明。`
+
 // RecognizeBusinessCard 名片识别
-func (c *Client) RecognizeBusinessCard(imageData []byte) (string, error) {
+func (c *Client) RecognizeBusinessCard(ctx context.Context, imageData []byte) (string, error) {
+	text, _, err := c.RecognizeImage(ctx, imageData, "image/jpeg", BusinessCardPrompt)
+	return text, err
+}
+
+// RecognizeImage 图片理解：按提示词识别图片内容，返回识别文本和 token 用量；
+// mimeType 为 application/pdf 时以 input_file 发送整份文档（扫描件可多页）
+func (c *Client) RecognizeImage(ctx context.Context, imageData []byte, mimeType, prompt string) (string, Usage, error) {
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
 	// 将图片转换为 base64
 	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
 	// 创建数据 URL
-	dataURL := fmt.Sprintf("data:image/jpeg;base64,%s", imageBase64)
+	dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, imageBase64)
+
+	media := ContentItem{
+		Type:     "input_image",
+		ImageURL: dataURL,
+	}
+	if mimeType == "application/pdf" {
+		media = ContentItem{
+			Type:     "input_file",
+			FileData: dataURL,
+			Filename: "document.pdf",
+		}
+	}
 
 	req := Request{
 		Model: c.Model,
@@ -226,42 +392,31 @@ func (c *Client) RecognizeBusinessCard(imageData []byte) (string, error) {
 			{
 				Role: "user",
 				Content: []ContentItem{
-					{
-						Type:     "input_image",
-						ImageURL: dataURL,
-					},
+					media,
 					{
 						Type: "input_text",
-						Text: `请识别这张名片上的信息，并以JSON格式返回，包含以下字段：
-- name: 姓名
-- company: 公司名称
-- position: 职位
-- phone: 电话
-- email: 邮箱
-- address: 地址
-
-只返回JSON，不要添加其他说明。`,
+						Text: prompt,
 					},
 				},
 			},
 		},
 	}
 
-	return c.sendRequest(req)
+	return c.sendRequest(ctx, req)
 }
 
 // sendRequest 发送请求（多模态：语音、图片）
-func (c *Client) sendRequest(req Request) (string, error) {
+func (c *Client) sendRequest(ctx context.Context, req Request) (string, Usage, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// 创建 HTTP 请求 - 使用正确的端点 /responses
 	url := fmt.Sprintf("%s/responses", c.BaseURL)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// 设置请求头
@@ -271,17 +426,17 @@ func (c *Client) sendRequest(req Request) (string, error) {
 	// 发送请求
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return "", Usage{}, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
 	// 解析豆包 API 响应格式
@@ -293,20 +448,21 @@ func (c *Client) sendRequest(req Request) (string, error) {
 				Text string `json:"text,omitempty"`
 			} `json:"content"`
 		} `json:"output"`
+		Usage Usage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	// 提取消息内容（查找 output_text 类型）
 	for _, output := range apiResp.Output {
 		for _, item := range output.Content {
 			if item.Type == "output_text" && item.Text != "" {
-				return item.Text, nil
+				return item.Text, apiResp.Usage, nil
 			}
 		}
 	}
 
-	return "", fmt.Errorf("no content in response")
+	return "", Usage{}, fmt.Errorf("no content in response")
 }
@@ -22,8 +22,13 @@ type CustomerRow struct {
 	Stage       string
 	Source      string
 	Notes       string
+	// Extra 固定列之后的列：表头 → 单元格内容（如自定义字段）
+	Extra       map[string]string
 }
 
+// fixedColumns 固定列数：姓名,公司,职位,电话,邮箱,行业,预算,意向度,阶段,来源,备注
+const fixedColumns = 11
+
 // ParseCustomersFromExcel parses customer data from an Excel file
 func ParseCustomersFromExcel(fileData []byte) ([]*CustomerRow, error) {
 	f, err := excelize.OpenReader(bytes.NewReader(fileData))
@@ -103,6 +108,16 @@ func ParseCustomersFromExcel(fileData []byte) ([]*CustomerRow, error) {
 		if len(row) > 10 {
 			customer.Notes = strings.TrimSpace(row[10])
 		}
+		for col := fixedColumns; col < len(row) && col < len(rows[0]); col++ {
+			header := strings.TrimSpace(rows[0][col])
+			if header == "" {
+				continue
+			}
+			if customer.Extra == nil {
+				customer.Extra = make(map[string]string)
+			}
+			customer.Extra[header] = strings.TrimSpace(row[col])
+		}
 
 		// Skip rows without required fields
 		if customer.Name == "" || customer.Company == "" || customer.Phone == "" {
@@ -119,8 +134,14 @@ func ParseCustomersFromExcel(fileData []byte) ([]*CustomerRow, error) {
 	return customers, nil
 }
 
-// WriteCustomersToExcel writes customers to an Excel file
-func WriteCustomersToExcel(customers []map[string]string) ([]byte, error) {
+// Column 固定列之后追加的导出列，Key 为数据 map 中的键
+type Column struct {
+	Key    string
+	Header string
+}
+
+// WriteCustomersToExcel writes customers to an Excel file, with optional extra columns after the fixed ones
+func WriteCustomersToExcel(customers []map[string]string, extraColumns ...Column) ([]byte, error) {
 	f := excelize.NewFile()
 	defer f.Close()
 
@@ -133,6 +154,9 @@ func WriteCustomersToExcel(customers []map[string]string) ([]byte, error) {
 
 	// Set headers
 	headers := []string{"姓名", "公司", "职位", "电话", "邮箱", "行业", "预算", "意向度", "阶段", "来源", "备注"}
+	for _, col := range extraColumns {
+		headers = append(headers, col.Header)
+	}
 	for i, header := range headers {
 		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
 		f.SetCellValue(sheetName, cell, header)
@@ -159,10 +183,15 @@ func WriteCustomersToExcel(customers []map[string]string) ([]byte, error) {
 		f.SetCellValue(sheetName, fmt.Sprintf("I%d", rowNum), customer["stage"])
 		f.SetCellValue(sheetName, fmt.Sprintf("J%d", rowNum), customer["source"])
 		f.SetCellValue(sheetName, fmt.Sprintf("K%d", rowNum), customer["notes"])
+		for j, col := range extraColumns {
+			cell, _ := excelize.CoordinatesToCellName(fixedColumns+j+1, rowNum)
+			f.SetCellValue(sheetName, cell, customer[col.Key])
+		}
 	}
 
 	// Set column widths
-	f.SetColWidth(sheetName, "A", "K", 15)
+	lastCol, _ := excelize.ColumnNumberToName(len(headers))
+	f.SetColWidth(sheetName, "A", lastCol, 15)
 
 	// Set active sheet
 	f.SetActiveSheet(index)
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker 简单的熔断器：连续失败达到阈值后打开，冷却期结束后放行一次试探请求
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+// NewBreaker 创建熔断器，threshold <= 0 表示不熔断
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow 判断当前是否允许请求通过
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	// 熔断中：冷却期内拒绝；冷却期后只放行一个试探请求
+	if b.now().Sub(b.openedAt) < b.cooldown || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Success 记录一次成功，关闭熔断
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure 记录一次失败
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
+
+// Release 放弃本次请求的结果（如调用方取消）：不计成功或失败，只释放试探名额
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State 返回熔断器状态：closed, open, half_open
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return "closed"
+	}
+	if b.now().Sub(b.openedAt) < b.cooldown {
+		return "open"
+	}
+	return "half_open"
+}
@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"testing"
+	"time"
+)
+
+// breakerStep 熔断器上的一步操作：allow 检查 Allow 的返回值，state 检查状态，wait 推进时钟
+type breakerStep struct {
+	op    string // allow, success, failure, release, wait, state
+	allow bool
+	state string
+	wait  time.Duration
+}
+
+func TestBreaker(t *testing.T) {
+	const cooldown = time.Minute
+	allow := func(want bool) breakerStep { return breakerStep{op: "allow", allow: want} }
+	state := func(want string) breakerStep { return breakerStep{op: "state", state: want} }
+	wait := func(d time.Duration) breakerStep { return breakerStep{op: "wait", wait: d} }
+	failure := breakerStep{op: "failure"}
+	success := breakerStep{op: "success"}
+	release := breakerStep{op: "release"}
+
+	cases := []struct {
+		name      string
+		threshold int
+		steps     []breakerStep
+	}{
+		{
+			name:      "disabled",
+			threshold: 0,
+			steps:     []breakerStep{failure, failure, failure, allow(true), state("closed")},
+		},
+		{
+			name:      "opens at threshold",
+			threshold: 2,
+			steps:     []breakerStep{failure, allow(true), state("closed"), failure, state("open"), allow(false)},
+		},
+		{
+			name:      "success resets the count",
+			threshold: 2,
+			steps:     []breakerStep{failure, success, failure, allow(true), state("closed")},
+		},
+		{
+			name:      "half open lets one probe through",
+			threshold: 1,
+			steps: []breakerStep{
+				failure, wait(cooldown - time.Second), allow(false),
+				wait(time.Second), state("half_open"), allow(true), allow(false),
+			},
+		},
+		{
+			name:      "successful probe closes",
+			threshold: 1,
+			steps:     []breakerStep{failure, wait(cooldown), allow(true), success, state("closed"), allow(true), allow(true)},
+		},
+		{
+			name:      "failed probe reopens for another cooldown",
+			threshold: 1,
+			steps: []breakerStep{
+				failure, wait(cooldown), allow(true), failure, state("open"), allow(false),
+				wait(cooldown), allow(true),
+			},
+		},
+		{
+			name:      "release frees the probe",
+			threshold: 1,
+			steps: []breakerStep{
+				failure, wait(cooldown), allow(true), allow(false),
+				release, state("half_open"), allow(true), allow(false),
+			},
+		},
+		{
+			name:      "release when closed is a no-op",
+			threshold: 3,
+			steps:     []breakerStep{failure, release, failure, allow(true), failure, state("open")},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			b := NewBreaker(tc.threshold, cooldown)
+			b.now = func() time.Time { return now }
+			for i, step := range tc.steps {
+				switch step.op {
+				case "allow":
+					if got := b.Allow(); got != step.allow {
+						t.Fatalf("step %d: Allow() = %v, want %v", i, got, step.allow)
+					}
+				case "state":
+					if got := b.State(); got != step.state {
+						t.Fatalf("step %d: State() = %q, want %q", i, got, step.state)
+					}
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure()
+				case "release":
+					b.Release()
+				case "wait":
+					now = now.Add(step.wait)
+				}
+			}
+		})
+	}
+}
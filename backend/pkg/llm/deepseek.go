@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xia/nextcrm/pkg/deepseek"
+)
+
+// DeepSeekProvider DeepSeek 适配器（对话、向量）
+type DeepSeekProvider struct {
+	client *deepseek.Client
+}
+
+func NewDeepSeekProvider(client *deepseek.Client) *DeepSeekProvider {
+	return &DeepSeekProvider{client: client}
+}
+
+func (p *DeepSeekProvider) Name() string {
+	return "deepseek"
+}
+
+func (p *DeepSeekProvider) ModelFor(capability Capability) string {
+	if capability == CapabilityEmbedding {
+		return p.client.EmbeddingModel()
+	}
+	return p.client.Model()
+}
+
+func (p *DeepSeekProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	messages := make([]deepseek.ChatMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = deepseek.ChatMessage{Role: m.Role, Content: m.Content}
+	}
+
+	chat := p.client.Chat
+	if req.JSONMode {
+		chat = p.client.ChatJSON
+	}
+	resp, err := chat(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return p.toChatResponse(resp)
+}
+
+func (p *DeepSeekProvider) toChatResponse(resp *deepseek.ChatResponse) (*ChatResponse, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = p.client.Model()
+	}
+	return &ChatResponse{
+		Provider:     p.Name(),
+		Model:        model,
+		Content:      resp.Choices[0].Message.Content,
+		FinishReason: resp.Choices[0].FinishReason,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+func (p *DeepSeekProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string) error) (*ChatResponse, error) {
+	messages := make([]deepseek.ChatMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = deepseek.ChatMessage{Role: m.Role, Content: m.Content}
+	}
+
+	resp, err := p.client.ChatStream(ctx, messages, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return p.toChatResponse(resp)
+}
+
+func (p *DeepSeekProvider) Embed(ctx context.Context, text string) (*EmbeddingResponse, error) {
+	resp, err := p.client.CreateEmbedding(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = p.client.EmbeddingModel()
+	}
+	return &EmbeddingResponse{
+		Provider:  p.Name(),
+		Model:     model,
+		Embedding: resp.Data[0].Embedding,
+		Usage: Usage{
+			PromptTokens: resp.Usage.PromptTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		},
+	}, nil
+}
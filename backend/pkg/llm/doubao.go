@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xia/nextcrm/pkg/doubao"
+)
+
+// DoubaoProvider 豆包适配器（对话、语音识别、图片理解）
+type DoubaoProvider struct {
+	client *doubao.Client
+}
+
+func NewDoubaoProvider(client *doubao.Client) *DoubaoProvider {
+	return &DoubaoProvider{client: client}
+}
+
+func (p *DoubaoProvider) Name() string {
+	return "doubao"
+}
+
+func (p *DoubaoProvider) ModelFor(capability Capability) string {
+	return p.client.Model
+}
+
+// Chat 豆包未接入 JSON 输出模式，req.JSONMode 只靠提示词约束
+func (p *DoubaoProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	messages := make([]doubao.ChatMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = doubao.ChatMessage{Role: m.Role, Content: m.Content}
+	}
+
+	resp, err := p.client.Chat(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return p.toChatResponse(resp)
+}
+
+func (p *DoubaoProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string) error) (*ChatResponse, error) {
+	messages := make([]doubao.ChatMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = doubao.ChatMessage{Role: m.Role, Content: m.Content}
+	}
+
+	resp, err := p.client.ChatStream(ctx, messages, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return p.toChatResponse(resp)
+}
+
+func (p *DoubaoProvider) toChatResponse(resp *doubao.ChatResponse) (*ChatResponse, error) {
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	return &ChatResponse{
+		Provider:     p.Name(),
+		Model:        p.client.Model,
+		Content:      resp.Choices[0].Message.Content,
+		FinishReason: resp.Choices[0].FinishReason,
+		Usage:        toDoubaoUsage(resp.Usage),
+	}, nil
+}
+
+// Transcribe 豆包没有时间戳；Diarize 时让模型按「说话人N：内容」逐行输出，再拆成片段
+func (p *DoubaoProvider) Transcribe(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error) {
+	prompt := doubao.SpeechPrompt
+	if req.Diarize {
+		prompt = doubao.DiarizedSpeechPrompt
+	}
+	text, usage, err := p.client.SpeechToTextWithPrompt(ctx, req.Audio, req.Format, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &SpeechResponse{
+		Provider: p.Name(),
+		Model:    p.client.Model,
+		Text:     strings.TrimSpace(text),
+		Usage:    toDoubaoUsage(usage),
+	}
+	if req.Diarize {
+		resp.Segments = parseSpeakerLines(resp.Text)
+	}
+	return resp, nil
+}
+
+// parseSpeakerLines 解析「说话人1：内容」格式的逐行转写，没有说话人前缀的行并入上一段
+func parseSpeakerLines(text string) []SpeechSegment {
+	var segments []SpeechSegment
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if idx := strings.IndexAny(line, ":："); idx > 0 && strings.HasPrefix(line, "说话人") {
+			content := strings.TrimLeft(line[idx:], ":：")
+			segments = append(segments, SpeechSegment{
+				Speaker: strings.TrimSpace(line[:idx]),
+				Text:    strings.TrimSpace(content),
+			})
+			continue
+		}
+		if len(segments) == 0 {
+			segments = append(segments, SpeechSegment{Text: line})
+			continue
+		}
+		segments[len(segments)-1].Text += " " + line
+	}
+	return segments
+}
+
+func (p *DoubaoProvider) Vision(ctx context.Context, req *VisionRequest) (*ChatResponse, error) {
+	text, usage, err := p.client.RecognizeImage(ctx, req.Image, req.MimeType, req.Prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChatResponse{
+		Provider:     p.Name(),
+		Model:        p.client.Model,
+		Content:      text,
+		FinishReason: "stop",
+		Usage:        toDoubaoUsage(usage),
+	}, nil
+}
+
+// toDoubaoUsage Responses API 的 input/output tokens 对应 prompt/completion tokens
+func toDoubaoUsage(u doubao.Usage) Usage {
+	return Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/xia/nextcrm/pkg/openai"
+)
+
+// OpenAIProvider 任意 OpenAI 兼容接口的适配器（对话、向量、语音识别、图片理解）
+type OpenAIProvider struct {
+	name   string
+	client *openai.Client
+}
+
+// NewOpenAIProvider name 用于日志和降级链配置，例如 "openai"、"qwen"
+func NewOpenAIProvider(name string, client *openai.Client) *OpenAIProvider {
+	return &OpenAIProvider{name: name, client: client}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+func (p *OpenAIProvider) ModelFor(capability Capability) string {
+	switch capability {
+	case CapabilityEmbedding:
+		return p.client.EmbeddingModel()
+	case CapabilitySpeech:
+		return p.client.AudioModel()
+	default:
+		return p.client.Model()
+	}
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	messages := make([]openai.ChatMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = openai.ChatMessage{Role: m.Role, Content: m.Content}
+	}
+
+	chat := p.client.Chat
+	if req.JSONMode {
+		chat = p.client.ChatJSON
+	}
+	resp, err := chat(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return p.toChatResponse(resp)
+}
+
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string) error) (*ChatResponse, error) {
+	messages := make([]openai.ChatMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = openai.ChatMessage{Role: m.Role, Content: m.Content}
+	}
+
+	resp, err := p.client.ChatStream(ctx, messages, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return p.toChatResponse(resp)
+}
+
+func (p *OpenAIProvider) Embed(ctx context.Context, text string) (*EmbeddingResponse, error) {
+	resp, err := p.client.CreateEmbedding(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = p.client.EmbeddingModel()
+	}
+	return &EmbeddingResponse{
+		Provider:  p.Name(),
+		Model:     model,
+		Embedding: resp.Data[0].Embedding,
+		Usage: Usage{
+			PromptTokens: resp.Usage.PromptTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+func (p *OpenAIProvider) Transcribe(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error) {
+	resp, err := p.client.Transcribe(ctx, req.Audio, req.Format, req.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	// 置信度取各片段平均对数概率的均值再取指数；Whisper 不区分说话人
+	segments := make([]SpeechSegment, 0, len(resp.Segments))
+	var logprob float64
+	for _, seg := range resp.Segments {
+		segments = append(segments, SpeechSegment{Start: seg.Start, End: seg.End, Text: strings.TrimSpace(seg.Text)})
+		logprob += seg.AvgLogprob
+	}
+	var confidence float64
+	if len(resp.Segments) > 0 {
+		confidence = math.Exp(logprob / float64(len(resp.Segments)))
+	}
+
+	return &SpeechResponse{
+		Provider:   p.Name(),
+		Model:      p.client.AudioModel(),
+		Text:       resp.Text,
+		Duration:   resp.Duration,
+		Confidence: confidence,
+		Segments:   segments,
+	}, nil
+}
+
+func (p *OpenAIProvider) Vision(ctx context.Context, req *VisionRequest) (*ChatResponse, error) {
+	resp, err := p.client.ChatWithImage(ctx, req.Image, req.MimeType, req.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	return p.toChatResponse(resp)
+}
+
+func (p *OpenAIProvider) toChatResponse(resp *openai.ChatResponse) (*ChatResponse, error) {
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = p.client.Model()
+	}
+	return &ChatResponse{
+		Provider:     p.Name(),
+		Model:        model,
+		Content:      resp.Choices[0].Message.Content,
+		FinishReason: resp.Choices[0].FinishReason,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	}, nil
+}
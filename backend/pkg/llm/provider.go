@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+// Capability 模型能力类型，每种能力独立配置优先级和降级链
+type Capability string
+
+const (
+	CapabilityChat      Capability = "chat"
+	CapabilityEmbedding Capability = "embedding"
+	CapabilitySpeech    Capability = "speech"
+	CapabilityVision    Capability = "vision"
+)
+
+var (
+	ErrNoProvider  = errors.New("no AI provider configured for this capability")
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+// Message 对话消息（与具体厂商无关）
+type Message struct {
+	Role    string `json:"role"` // system, user, assistant
+	Content string `json:"content"`
+}
+
+// Usage token 用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatRequest 对话请求
+type ChatRequest struct {
+	Messages []Message
+	// JSONMode 要求输出 JSON 对象，支持的厂商会开启 JSON 输出模式，
+	// 不支持的厂商忽略该选项（只靠提示词约束），调用方需自行校验
+	JSONMode bool
+}
+
+// ChatResponse 对话 / 图片理解响应
+type ChatResponse struct {
+	Provider     string
+	Model        string
+	Content      string
+	FinishReason string
+	Usage        Usage
+}
+
+// EmbeddingResponse 向量响应
+type EmbeddingResponse struct {
+	Provider  string
+	Model     string
+	Embedding []float32
+	Usage     Usage
+}
+
+// SpeechRequest 语音识别请求
+type SpeechRequest struct {
+	Audio    []byte
+	Format   string // webm, mp3, wav ...
+	Language string
+	Diarize  bool // 需要区分说话人，不支持的厂商忽略
+}
+
+// SpeechSegment 识别片段；Start / End 为秒，厂商不返回时为 0；Speaker 仅在区分说话人时有值
+type SpeechSegment struct {
+	Start   float64
+	End     float64
+	Speaker string
+	Text    string
+}
+
+// SpeechResponse 语音识别响应
+type SpeechResponse struct {
+	Provider   string
+	Model      string
+	Text       string
+	Duration   float64 // 秒，未知时为 0
+	Confidence float64 // 厂商不返回时为 0
+	Segments   []SpeechSegment
+	Usage      Usage // 按 token 计费的厂商才有
+}
+
+// VisionRequest 图片理解请求
+type VisionRequest struct {
+	Image    []byte
+	MimeType string
+	Prompt   string
+}
+
+// Provider 所有模型厂商适配器的公共接口
+type Provider interface {
+	Name() string
+}
+
+// ModelNamer 可选接口：返回厂商在某能力上配置的模型名（用于缓存键等）
+type ModelNamer interface {
+	ModelFor(capability Capability) string
+}
+
+// ChatProvider 文本对话能力
+type ChatProvider interface {
+	Provider
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+}
+
+// StreamingChatProvider 流式文本对话能力（可选），onDelta 在每个增量片段到达时调用
+type StreamingChatProvider interface {
+	ChatProvider
+	ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string) error) (*ChatResponse, error)
+}
+
+// EmbeddingProvider 向量化能力
+type EmbeddingProvider interface {
+	Provider
+	Embed(ctx context.Context, text string) (*EmbeddingResponse, error)
+}
+
+// SpeechProvider 语音识别能力
+type SpeechProvider interface {
+	Provider
+	Transcribe(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error)
+}
+
+// VisionProvider 图片理解能力
+type VisionProvider interface {
+	Provider
+	Vision(ctx context.Context, req *VisionRequest) (*ChatResponse, error)
+}
+
+// supports 判断适配器是否实现了某种能力
+func supports(p Provider, capability Capability) bool {
+	switch capability {
+	case CapabilityChat:
+		_, ok := p.(ChatProvider)
+		return ok
+	case CapabilityEmbedding:
+		_, ok := p.(EmbeddingProvider)
+		return ok
+	case CapabilitySpeech:
+		_, ok := p.(SpeechProvider)
+		return ok
+	case CapabilityVision:
+		_, ok := p.(VisionProvider)
+		return ok
+	}
+	return false
+}
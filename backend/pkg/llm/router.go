@@ -0,0 +1,302 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options 单个厂商在某条能力链上的调用参数
+type Options struct {
+	Timeout          time.Duration // 单次调用超时，0 表示只受调用方 ctx 控制
+	FailureThreshold int           // 连续失败多少次后熔断，0 表示不熔断
+	Cooldown         time.Duration // 熔断后多久放行试探请求
+}
+
+// streamBrokenError 流式输出已开始后厂商出错：不能再降级，只记这个厂商失败并直接返回原错误
+type streamBrokenError struct {
+	err error
+}
+
+func (e *streamBrokenError) Error() string { return e.err.Error() }
+func (e *streamBrokenError) Unwrap() error { return e.err }
+
+type entry struct {
+	provider Provider
+	timeout  time.Duration
+	breaker  *Breaker
+}
+
+// ProviderStatus 厂商状态（用于排查降级情况）
+type ProviderStatus struct {
+	Capability Capability `json:"capability"`
+	Provider   string     `json:"provider"`
+	Breaker    string     `json:"breaker"`
+}
+
+// ChainError 整条降级链都失败时返回，包含每个厂商的错误
+type ChainError struct {
+	Capability Capability
+	Errors     map[string]error
+	order      []string
+}
+
+func (e *ChainError) Error() string {
+	parts := make([]string, 0, len(e.order))
+	for _, name := range e.order {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e.Errors[name]))
+	}
+	return fmt.Sprintf("all %s providers failed (%s)", e.Capability, strings.Join(parts, "; "))
+}
+
+// CallRecord 单个厂商的一次调用结果，成功失败都会上报（熔断跳过的不上报）
+type CallRecord struct {
+	Capability Capability
+	Provider   string
+	Model      string // 失败时可能为空
+	Usage      Usage
+	Latency    time.Duration
+	Err        error
+}
+
+// Observer 每次厂商调用结束后回调（用于用量计量），ctx 为调用方传入的 ctx
+type Observer func(ctx context.Context, rec CallRecord)
+
+// Router 按能力维护有序的厂商降级链，依次尝试直到成功
+type Router struct {
+	mu       sync.RWMutex
+	chains   map[Capability][]*entry
+	observer Observer
+}
+
+// callResult 厂商调用成功时上报的模型和用量
+type callResult struct {
+	model string
+	usage Usage
+}
+
+func NewRouter() *Router {
+	return &Router{
+		chains: make(map[Capability][]*entry),
+	}
+}
+
+// Register 把厂商追加到某能力降级链的末尾（注册顺序即优先级）
+func (r *Router) Register(capability Capability, p Provider, opts Options) error {
+	if !supports(p, capability) {
+		return fmt.Errorf("provider %s does not support %s", p.Name(), capability)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chains[capability] = append(r.chains[capability], &entry{
+		provider: p,
+		timeout:  opts.Timeout,
+		breaker:  NewBreaker(opts.FailureThreshold, opts.Cooldown),
+	})
+	return nil
+}
+
+// SetObserver 设置调用回调，传 nil 取消
+func (r *Router) SetObserver(o Observer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observer = o
+}
+
+// Providers 返回某能力链上的厂商名（按优先级）
+func (r *Router) Providers(capability Capability) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.chains[capability]))
+	for _, e := range r.chains[capability] {
+		names = append(names, e.provider.Name())
+	}
+	return names
+}
+
+// Models 返回某能力链上的「厂商/模型」（按优先级），未实现 ModelNamer 的厂商只有厂商名
+func (r *Router) Models(capability Capability) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.chains[capability]))
+	for _, e := range r.chains[capability] {
+		name := e.provider.Name()
+		if m, ok := e.provider.(ModelNamer); ok {
+			name += "/" + m.ModelFor(capability)
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// Status 返回所有能力链上厂商的熔断状态
+func (r *Router) Status() []ProviderStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []ProviderStatus
+	for _, capability := range []Capability{CapabilityChat, CapabilityEmbedding, CapabilitySpeech, CapabilityVision} {
+		for _, e := range r.chains[capability] {
+			out = append(out, ProviderStatus{
+				Capability: capability,
+				Provider:   e.provider.Name(),
+				Breaker:    e.breaker.State(),
+			})
+		}
+	}
+	return out
+}
+
+// Chat 按对话降级链调用
+func (r *Router) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var resp *ChatResponse
+	err := r.call(ctx, CapabilityChat, func(ctx context.Context, p Provider) (*callResult, error) {
+		out, err := p.(ChatProvider).Chat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		resp = out
+		return &callResult{model: out.Model, usage: out.Usage}, nil
+	})
+	return resp, err
+}
+
+// ChatStream 按对话降级链流式调用。不支持流式的厂商整段返回后作为一个片段下发。
+// 一旦已有片段下发给调用方，就不再降级到其他厂商，避免内容重复。
+func (r *Router) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string) error) (*ChatResponse, error) {
+	var resp *ChatResponse
+	started := false
+	err := r.call(ctx, CapabilityChat, func(ctx context.Context, p Provider) (*callResult, error) {
+		emit := func(delta string) error {
+			started = true
+			return onDelta(delta)
+		}
+
+		var out *ChatResponse
+		var err error
+		if sp, ok := p.(StreamingChatProvider); ok {
+			out, err = sp.ChatStream(ctx, req, emit)
+		} else if out, err = p.(ChatProvider).Chat(ctx, req); err == nil {
+			err = emit(out.Content)
+		}
+		if err != nil {
+			if started {
+				return nil, &streamBrokenError{err: err}
+			}
+			return nil, err
+		}
+		resp = out
+		return &callResult{model: out.Model, usage: out.Usage}, nil
+	})
+	return resp, err
+}
+
+// Embed 按向量降级链调用
+func (r *Router) Embed(ctx context.Context, text string) (*EmbeddingResponse, error) {
+	var resp *EmbeddingResponse
+	err := r.call(ctx, CapabilityEmbedding, func(ctx context.Context, p Provider) (*callResult, error) {
+		out, err := p.(EmbeddingProvider).Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		resp = out
+		return &callResult{model: out.Model, usage: out.Usage}, nil
+	})
+	return resp, err
+}
+
+// Transcribe 按语音识别降级链调用
+func (r *Router) Transcribe(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error) {
+	var resp *SpeechResponse
+	err := r.call(ctx, CapabilitySpeech, func(ctx context.Context, p Provider) (*callResult, error) {
+		out, err := p.(SpeechProvider).Transcribe(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		resp = out
+		return &callResult{model: out.Model, usage: out.Usage}, nil
+	})
+	return resp, err
+}
+
+// Vision 按图片理解降级链调用
+func (r *Router) Vision(ctx context.Context, req *VisionRequest) (*ChatResponse, error) {
+	var resp *ChatResponse
+	err := r.call(ctx, CapabilityVision, func(ctx context.Context, p Provider) (*callResult, error) {
+		out, err := p.(VisionProvider).Vision(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		resp = out
+		return &callResult{model: out.Model, usage: out.Usage}, nil
+	})
+	return resp, err
+}
+
+// call 依次尝试链上的厂商：跳过已熔断的，单次调用套上超时，失败则降级到下一个
+func (r *Router) call(ctx context.Context, capability Capability, fn func(ctx context.Context, p Provider) (*callResult, error)) error {
+	r.mu.RLock()
+	chain := r.chains[capability]
+	observer := r.observer
+	r.mu.RUnlock()
+
+	if len(chain) == 0 {
+		return ErrNoProvider
+	}
+
+	chainErr := &ChainError{Capability: capability, Errors: make(map[string]error)}
+	for _, e := range chain {
+		name := e.provider.Name()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !e.breaker.Allow() {
+			chainErr.add(name, ErrCircuitOpen)
+			continue
+		}
+
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if e.timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, e.timeout)
+		}
+		start := time.Now()
+		res, err := fn(callCtx, e.provider)
+		cancel()
+
+		if observer != nil {
+			rec := CallRecord{Capability: capability, Provider: name, Latency: time.Since(start), Err: err}
+			if res != nil {
+				rec.Model, rec.Usage = res.model, res.usage
+			}
+			observer(ctx, rec)
+		}
+
+		if err == nil {
+			e.breaker.Success()
+			return nil
+		}
+		// 调用方主动取消不算厂商故障，但要释放半开状态下的试探名额
+		if ctx.Err() != nil {
+			e.breaker.Release()
+			return ctx.Err()
+		}
+		e.breaker.Failure()
+		var broken *streamBrokenError
+		if errors.As(err, &broken) {
+			log.Printf("AI 厂商 %s 流式输出中断（%s），已下发内容，不再降级: %v", name, capability, broken.err)
+			return broken.err
+		}
+		chainErr.add(name, err)
+		log.Printf("AI 厂商 %s 调用失败（%s），尝试降级: %v", name, capability, err)
+	}
+	return chainErr
+}
+
+func (e *ChainError) add(name string, err error) {
+	e.order = append(e.order, name)
+	e.Errors[name] = err
+}
@@ -0,0 +1,331 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+// fakeProvider 按顺序返回 results 中的错误（nil 为成功，用完后一直成功）；block 为 true 时等到 ctx 结束
+type fakeProvider struct {
+	name    string
+	results []error
+	block   bool
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) next(ctx context.Context) error {
+	p.mu.Lock()
+	p.calls++
+	var err error
+	if len(p.results) > 0 {
+		err, p.results = p.results[0], p.results[1:]
+	}
+	p.mu.Unlock()
+
+	if p.block {
+		<-ctx.Done()
+		err = ctx.Err()
+	}
+	return err
+}
+
+func (p *fakeProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	if err := p.next(ctx); err != nil {
+		return nil, err
+	}
+	return &ChatResponse{Provider: p.name, Model: p.name + "-model", Content: "from " + p.name}, nil
+}
+
+func (p *fakeProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// fakeStreamer 先下发 deltas，再按 fakeProvider 的结果结束
+type fakeStreamer struct {
+	*fakeProvider
+	deltas []string
+}
+
+func (p *fakeStreamer) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string) error) (*ChatResponse, error) {
+	for _, d := range p.deltas {
+		if err := onDelta(d); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.next(ctx); err != nil {
+		return nil, err
+	}
+	return &ChatResponse{Provider: p.name, Model: p.name + "-model", Content: strings.Join(p.deltas, "")}, nil
+}
+
+func newRouter(t *testing.T, opts Options, providers ...ChatProvider) *Router {
+	t.Helper()
+	r := NewRouter()
+	for _, p := range providers {
+		if err := r.Register(CapabilityChat, p, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestRouterFallbackOrder(t *testing.T) {
+	cases := []struct {
+		name      string
+		results   [][]error // 每个厂商依次返回的结果
+		threshold int
+		calls     int    // 调用 Chat 的次数，只检查最后一次
+		served    string // 为空表示整条链失败
+		chainErrs []string
+		want      []int // 各厂商被调用的次数
+	}{
+		{
+			name:    "first provider answers",
+			results: [][]error{nil, nil},
+			calls:   1,
+			served:  "a",
+			want:    []int{1, 0},
+		},
+		{
+			name:    "falls back in registration order",
+			results: [][]error{{errBoom}, {errBoom}, nil},
+			calls:   1,
+			served:  "c",
+			want:    []int{1, 1, 1},
+		},
+		{
+			name:      "whole chain fails",
+			results:   [][]error{{errBoom}, {errBoom}},
+			calls:     1,
+			chainErrs: []string{"a", "b"},
+			want:      []int{1, 1},
+		},
+		{
+			name:      "open breaker is skipped",
+			results:   [][]error{{errBoom}, nil},
+			threshold: 1,
+			calls:     2,
+			served:    "b",
+			want:      []int{1, 2},
+		},
+		{
+			name:      "every breaker open",
+			results:   [][]error{{errBoom}, {errBoom}},
+			threshold: 1,
+			calls:     2,
+			chainErrs: []string{"a", "b"},
+			want:      []int{1, 1},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var providers []ChatProvider
+			var fakes []*fakeProvider
+			for i, results := range tc.results {
+				p := &fakeProvider{name: string(rune('a' + i)), results: results}
+				providers = append(providers, p)
+				fakes = append(fakes, p)
+			}
+			r := newRouter(t, Options{FailureThreshold: tc.threshold, Cooldown: time.Hour}, providers...)
+
+			var resp *ChatResponse
+			var err error
+			for i := 0; i < tc.calls; i++ {
+				resp, err = r.Chat(context.Background(), &ChatRequest{})
+			}
+
+			if tc.served != "" {
+				if err != nil {
+					t.Fatalf("Chat: %v", err)
+				}
+				if resp.Provider != tc.served {
+					t.Errorf("served by %q, want %q", resp.Provider, tc.served)
+				}
+			} else {
+				var chainErr *ChainError
+				if !errors.As(err, &chainErr) {
+					t.Fatalf("err = %v, want *ChainError", err)
+				}
+				if !reflect.DeepEqual(chainErr.order, tc.chainErrs) {
+					t.Errorf("chain order = %v, want %v", chainErr.order, tc.chainErrs)
+				}
+			}
+			for i, p := range fakes {
+				if got := p.callCount(); got != tc.want[i] {
+					t.Errorf("provider %s called %d times, want %d", p.name, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRouterCircuitOpenError(t *testing.T) {
+	a := &fakeProvider{name: "a", results: []error{errBoom}}
+	r := newRouter(t, Options{FailureThreshold: 1, Cooldown: time.Hour}, a)
+	if _, err := r.Chat(context.Background(), &ChatRequest{}); err == nil {
+		t.Fatal("expected the first call to fail")
+	}
+	_, err := r.Chat(context.Background(), &ChatRequest{})
+	var chainErr *ChainError
+	if !errors.As(err, &chainErr) || !errors.Is(chainErr.Errors["a"], ErrCircuitOpen) {
+		t.Fatalf("err = %v, want circuit open for a", err)
+	}
+	if a.callCount() != 1 {
+		t.Errorf("open provider was called %d times", a.callCount())
+	}
+}
+
+func TestRouterNoProvider(t *testing.T) {
+	if _, err := NewRouter().Chat(context.Background(), &ChatRequest{}); !errors.Is(err, ErrNoProvider) {
+		t.Errorf("err = %v, want ErrNoProvider", err)
+	}
+}
+
+func TestRouterTimeoutFallsBack(t *testing.T) {
+	slow := &fakeProvider{name: "slow", block: true}
+	fast := &fakeProvider{name: "fast"}
+	r := newRouter(t, Options{Timeout: 10 * time.Millisecond, FailureThreshold: 1, Cooldown: time.Hour}, slow, fast)
+
+	resp, err := r.Chat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "fast" {
+		t.Errorf("served by %q, want fast", resp.Provider)
+	}
+	// 超时算厂商故障
+	if state := r.chains[CapabilityChat][0].breaker.State(); state != "open" {
+		t.Errorf("slow breaker = %s, want open", state)
+	}
+}
+
+// 半开状态下的试探请求被调用方取消时，要释放试探名额，也不能算作失败
+func TestRouterCancelReleasesProbe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := &fakeProvider{name: "a", results: []error{errBoom}}
+	b := &fakeProvider{name: "b"}
+	r := newRouter(t, Options{FailureThreshold: 1, Cooldown: time.Minute}, a, b)
+
+	now := time.Now()
+	breaker := r.chains[CapabilityChat][0].breaker
+	breaker.now = func() time.Time { return now }
+
+	if _, err := r.Chat(ctx, &ChatRequest{}); err != nil {
+		t.Fatalf("fallback to b failed: %v", err)
+	}
+	if breaker.State() != "open" {
+		t.Fatalf("a breaker = %s, want open", breaker.State())
+	}
+
+	now = now.Add(time.Minute)
+	a.block = true
+	go func() {
+		for a.callCount() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	if _, err := r.Chat(ctx, &ChatRequest{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if b.callCount() != 1 {
+		t.Errorf("cancelled call fell back to b")
+	}
+	if state := breaker.State(); state != "half_open" {
+		t.Errorf("a breaker = %s, want half_open", state)
+	}
+	if !breaker.Allow() {
+		t.Error("probe slot was not released after cancellation")
+	}
+}
+
+func TestRouterChatStream(t *testing.T) {
+	cases := []struct {
+		name      string
+		first     *fakeStreamer
+		deltas    []string
+		err       error
+		secondRun int    // 第二个厂商被调用的次数
+		firstOpen bool   // 第一个厂商的熔断器是否记了失败
+		observed  []bool // 观察者收到的各次调用是否出错
+	}{
+		{
+			name:      "streams from the first provider",
+			first:     &fakeStreamer{fakeProvider: &fakeProvider{name: "a"}, deltas: []string{"he", "llo"}},
+			deltas:    []string{"he", "llo"},
+			observed:  []bool{false},
+			secondRun: 0,
+		},
+		{
+			name:      "falls back before anything was sent",
+			first:     &fakeStreamer{fakeProvider: &fakeProvider{name: "a", results: []error{errBoom}}},
+			deltas:    []string{"from b"},
+			secondRun: 1,
+			firstOpen: true,
+			observed:  []bool{true, false},
+		},
+		{
+			name:      "stops after a mid-stream failure",
+			first:     &fakeStreamer{fakeProvider: &fakeProvider{name: "a", results: []error{errBoom}}, deltas: []string{"hel"}},
+			deltas:    []string{"hel"},
+			err:       errBoom,
+			secondRun: 0,
+			firstOpen: true,
+			observed:  []bool{true},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			second := &fakeProvider{name: "b"} // 不支持流式，整段作为一个片段下发
+			r := newRouter(t, Options{FailureThreshold: 1, Cooldown: time.Hour}, tc.first, second)
+			var observed []bool
+			r.SetObserver(func(ctx context.Context, rec CallRecord) {
+				observed = append(observed, rec.Err != nil)
+			})
+
+			var deltas []string
+			_, err := r.ChatStream(context.Background(), &ChatRequest{}, func(d string) error {
+				deltas = append(deltas, d)
+				return nil
+			})
+
+			if tc.err != nil {
+				// 直接返回厂商的原错误，而不是包装后的错误或整条链的错误
+				if err != tc.err {
+					t.Fatalf("err = %#v, want the provider error %v", err, tc.err)
+				}
+			} else if err != nil {
+				t.Fatalf("ChatStream: %v", err)
+			}
+			if !reflect.DeepEqual(deltas, tc.deltas) {
+				t.Errorf("deltas = %q, want %q", deltas, tc.deltas)
+			}
+			if got := second.callCount(); got != tc.secondRun {
+				t.Errorf("second provider called %d times, want %d", got, tc.secondRun)
+			}
+			if got := r.chains[CapabilityChat][0].breaker.State() == "open"; got != tc.firstOpen {
+				t.Errorf("first breaker open = %v, want %v", got, tc.firstOpen)
+			}
+			if state := r.chains[CapabilityChat][1].breaker.State(); state != "closed" {
+				t.Errorf("second breaker = %s, want closed", state)
+			}
+			if !reflect.DeepEqual(observed, tc.observed) {
+				t.Errorf("observed = %v, want %v", observed, tc.observed)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/xia/nextcrm/pkg/volcengine"
+)
+
+// VolcEngineProvider 火山引擎录音文件识别适配器（仅语音识别），用作豆包 ASR 的备用
+type VolcEngineProvider struct {
+	client *volcengine.ASRClient
+}
+
+func NewVolcEngineProvider(client *volcengine.ASRClient) *VolcEngineProvider {
+	return &VolcEngineProvider{client: client}
+}
+
+func (p *VolcEngineProvider) Name() string {
+	return "volcengine"
+}
+
+// Transcribe 火山引擎 ASR 不区分说话人，也不返回置信度和分段时间戳
+func (p *VolcEngineProvider) Transcribe(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error) {
+	language := req.Language
+	if language == "" {
+		language = "zh-CN"
+	}
+	result, err := p.client.RecognizeContext(ctx, req.Audio, req.Format, language)
+	if err != nil {
+		return nil, err
+	}
+	return &SpeechResponse{
+		Provider: p.Name(),
+		Model:    "asr",
+		Text:     strings.TrimSpace(result.Text),
+		Duration: result.Duration,
+	}, nil
+}
@@ -0,0 +1,185 @@
+// Package logreg 纯 Go 实现的 L2 正则逻辑回归，特征先标准化再训练，
+// 预测时可拆出每个特征对 log-odds 的贡献，便于解释
+package logreg
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrNoData = errors.New("logreg: no training data")
+
+// Options 训练参数
+type Options struct {
+	Iterations   int     // 梯度下降轮数，默认 500
+	LearningRate float64 // 默认 0.1
+	L2           float64 // 正则强度，默认 0.01
+}
+
+// Model 训练好的模型；Mean / Std 用于标准化输入，Weights 对应标准化后的特征
+type Model struct {
+	Features []string  `json:"features"`
+	Mean     []float64 `json:"mean"`
+	Std      []float64 `json:"std"`
+	Weights  []float64 `json:"weights"`
+	Bias     float64   `json:"bias"`
+}
+
+// Train 用批量梯度下降训练；X 为 n×d 样本矩阵，y 为 0/1 标签
+func Train(features []string, X [][]float64, y []float64, opts Options) (*Model, error) {
+	if len(X) == 0 || len(X) != len(y) {
+		return nil, ErrNoData
+	}
+	if opts.Iterations <= 0 {
+		opts.Iterations = 500
+	}
+	if opts.LearningRate <= 0 {
+		opts.LearningRate = 0.1
+	}
+	if opts.L2 < 0 {
+		opts.L2 = 0
+	} else if opts.L2 == 0 {
+		opts.L2 = 0.01
+	}
+
+	n, d := len(X), len(features)
+	m := &Model{
+		Features: features,
+		Mean:     make([]float64, d),
+		Std:      make([]float64, d),
+		Weights:  make([]float64, d),
+	}
+
+	// 标准化参数
+	for _, row := range X {
+		for j := 0; j < d; j++ {
+			m.Mean[j] += row[j]
+		}
+	}
+	for j := range m.Mean {
+		m.Mean[j] /= float64(n)
+	}
+	for _, row := range X {
+		for j := 0; j < d; j++ {
+			diff := row[j] - m.Mean[j]
+			m.Std[j] += diff * diff
+		}
+	}
+	for j := range m.Std {
+		m.Std[j] = math.Sqrt(m.Std[j] / float64(n))
+		if m.Std[j] < 1e-9 {
+			m.Std[j] = 1 // 常量特征，权重会被正则压到 0
+		}
+	}
+
+	Z := make([][]float64, n)
+	for i, row := range X {
+		Z[i] = m.standardize(row)
+	}
+
+	// 偏置从正样本比例的 log-odds 起步，收敛更快
+	pos := 0.0
+	for _, label := range y {
+		pos += label
+	}
+	rate := (pos + 0.5) / (float64(n) + 1)
+	m.Bias = math.Log(rate / (1 - rate))
+
+	grad := make([]float64, d)
+	for iter := 0; iter < opts.Iterations; iter++ {
+		for j := range grad {
+			grad[j] = 0
+		}
+		gradBias := 0.0
+		for i, z := range Z {
+			errTerm := sigmoid(m.logit(z)) - y[i]
+			for j := 0; j < d; j++ {
+				grad[j] += errTerm * z[j]
+			}
+			gradBias += errTerm
+		}
+		for j := 0; j < d; j++ {
+			m.Weights[j] -= opts.LearningRate * (grad[j]/float64(n) + opts.L2*m.Weights[j])
+		}
+		m.Bias -= opts.LearningRate * gradBias / float64(n)
+	}
+	return m, nil
+}
+
+// Predict 返回正类概率
+func (m *Model) Predict(x []float64) float64 {
+	return sigmoid(m.logit(m.standardize(x)))
+}
+
+// Contributions 每个特征对 log-odds 的贡献（相对训练集平均样本），之和加上 Bias 即为 logit
+func (m *Model) Contributions(x []float64) []float64 {
+	z := m.standardize(x)
+	out := make([]float64, len(z))
+	for j := range z {
+		out[j] = m.Weights[j] * z[j]
+	}
+	return out
+}
+
+// LogLoss 在给定样本上的平均对数损失
+func (m *Model) LogLoss(X [][]float64, y []float64) float64 {
+	if len(X) == 0 {
+		return 0
+	}
+	const eps = 1e-12
+	total := 0.0
+	for i, row := range X {
+		p := math.Min(math.Max(m.Predict(row), eps), 1-eps)
+		total -= y[i]*math.Log(p) + (1-y[i])*math.Log(1-p)
+	}
+	return total / float64(len(X))
+}
+
+// AUC ROC 曲线下面积（按成对比较计算，样本量小时足够）
+func (m *Model) AUC(X [][]float64, y []float64) float64 {
+	var pos, neg []float64
+	for i, row := range X {
+		if y[i] >= 0.5 {
+			pos = append(pos, m.Predict(row))
+		} else {
+			neg = append(neg, m.Predict(row))
+		}
+	}
+	if len(pos) == 0 || len(neg) == 0 {
+		return 0
+	}
+	wins := 0.0
+	for _, p := range pos {
+		for _, q := range neg {
+			switch {
+			case p > q:
+				wins++
+			case p == q:
+				wins += 0.5
+			}
+		}
+	}
+	return wins / float64(len(pos)*len(neg))
+}
+
+func (m *Model) standardize(x []float64) []float64 {
+	z := make([]float64, len(m.Weights))
+	for j := range z {
+		if j < len(x) {
+			z[j] = (x[j] - m.Mean[j]) / m.Std[j]
+		}
+	}
+	return z
+}
+
+func (m *Model) logit(z []float64) float64 {
+	sum := m.Bias
+	for j, w := range m.Weights {
+		sum += w * z[j]
+	}
+	return sum
+}
+
+func sigmoid(v float64) float64 {
+	return 1 / (1 + math.Exp(-v))
+}
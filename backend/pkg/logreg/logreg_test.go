@@ -0,0 +1,130 @@
+package logreg
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var fixtureFeatures = []string{"follow_ups", "noise", "constant"}
+
+// separableFixture 第一个特征大于 5 时为正样本，第二个特征与标签无关，第三个特征是常量
+func separableFixture() ([][]float64, []float64) {
+	var X [][]float64
+	var y []float64
+	for i := 0; i < 40; i++ {
+		v := float64(i%10) + 0.5
+		label := 0.0
+		if v > 5 {
+			label = 1
+		}
+		X = append(X, []float64{v, float64((i * 7) % 3), 4})
+		y = append(y, label)
+	}
+	return X, y
+}
+
+func TestTrainConverges(t *testing.T) {
+	X, y := separableFixture()
+
+	short, err := Train(fixtureFeatures, X, y, Options{Iterations: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Train(fixtureFeatures, X, y, Options{Iterations: 2000, LearningRate: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loss, before := m.LogLoss(X, y), short.LogLoss(X, y); loss >= before || loss > 0.2 {
+		t.Errorf("log loss = %.4f after 2000 iterations, %.4f after 20; want it to keep falling below 0.2", loss, before)
+	}
+	if auc := m.AUC(X, y); auc != 1 {
+		t.Errorf("AUC = %v, want 1 on separable data", auc)
+	}
+	for i, row := range X {
+		if got := m.Predict(row) >= 0.5; got != (y[i] == 1) {
+			t.Errorf("sample %d (%v): p = %.3f, label %v", i, row, m.Predict(row), y[i])
+		}
+	}
+
+	if m.Weights[0] <= 0 {
+		t.Errorf("informative weight = %v, want positive", m.Weights[0])
+	}
+	if math.Abs(m.Weights[1]) >= math.Abs(m.Weights[0])/5 {
+		t.Errorf("noise weight %v is not small next to %v", m.Weights[1], m.Weights[0])
+	}
+	if m.Weights[2] != 0 || m.Std[2] != 1 {
+		t.Errorf("constant feature: weight = %v, std = %v; want 0 and 1", m.Weights[2], m.Std[2])
+	}
+
+	again, _ := Train(fixtureFeatures, X, y, Options{Iterations: 2000, LearningRate: 0.5})
+	if !reflect.DeepEqual(m, again) {
+		t.Error("training the same data twice gave different models")
+	}
+}
+
+func TestPredictInRange(t *testing.T) {
+	X, y := separableFixture()
+	m, err := Train(fixtureFeatures, X, y, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := [][]float64{
+		{0, 0, 4},
+		{10, 2, 4},
+		{1e9, 0, 4},
+		{-1e9, 0, 4},
+		{5.5},         // 缺少的特征按 0 处理
+		{3, 1, 4, 99}, // 多余的特征忽略
+	}
+	for _, x := range inputs {
+		p := m.Predict(x)
+		if math.IsNaN(p) || p < 0 || p > 1 {
+			t.Errorf("Predict(%v) = %v, want a probability", x, p)
+		}
+	}
+	if lo, hi := m.Predict([]float64{1, 0, 4}), m.Predict([]float64{9, 0, 4}); lo >= hi {
+		t.Errorf("Predict not monotonic in the informative feature: %v >= %v", lo, hi)
+	}
+}
+
+func TestContributionsSumToLogit(t *testing.T) {
+	X, y := separableFixture()
+	m, err := Train(fixtureFeatures, X, y, Options{Iterations: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range [][]float64{{2.5, 0, 4}, {5.5, 1, 4}, {7, 2, 4}, {4.5, 0, 4}} {
+		contributions := m.Contributions(x)
+		if len(contributions) != len(fixtureFeatures) {
+			t.Fatalf("got %d contributions, want %d", len(contributions), len(fixtureFeatures))
+		}
+		sum := m.Bias
+		for _, c := range contributions {
+			sum += c
+		}
+		p := m.Predict(x)
+		if logit := math.Log(p / (1 - p)); math.Abs(sum-logit) > 1e-9 {
+			t.Errorf("x = %v: bias + contributions = %v, logit = %v", x, sum, logit)
+		}
+	}
+
+	// 训练集平均样本的各项贡献为 0
+	for j, c := range m.Contributions(m.Mean) {
+		if c != 0 {
+			t.Errorf("contribution %d of the mean sample = %v, want 0", j, c)
+		}
+	}
+}
+
+func TestTrainNoData(t *testing.T) {
+	if _, err := Train(fixtureFeatures, nil, nil, Options{}); !errors.Is(err, ErrNoData) {
+		t.Errorf("empty data: err = %v, want ErrNoData", err)
+	}
+	X, y := separableFixture()
+	if _, err := Train(fixtureFeatures, X, y[:3], Options{}); !errors.Is(err, ErrNoData) {
+		t.Errorf("mismatched labels: err = %v, want ErrNoData", err)
+	}
+}
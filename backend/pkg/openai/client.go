@@ -0,0 +1,376 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xia/nextcrm/pkg/sse"
+)
+
+// Client talks to any endpoint that implements the OpenAI REST API
+// (chat completions, embeddings and audio transcriptions).
+// BaseURL must include the version prefix, e.g. https://api.openai.com/v1
+type Client struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	embedModel string
+	audioModel string
+	client     *http.Client
+	// 流式请求不设整体超时，由 ctx 控制
+	streamClient *http.Client
+}
+
+func NewClient(apiKey, baseURL, model, embedModel, audioModel string) *Client {
+	return &Client{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		embedModel: embedModel,
+		audioModel: audioModel,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		streamClient: &http.Client{},
+	}
+}
+
+// SetTransport replaces the underlying HTTP transport (e.g. record/replay) for both plain and streaming requests
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.client.Transport = rt
+	c.streamClient.Transport = rt
+}
+
+// Model returns the chat model name
+func (c *Client) Model() string {
+	return c.model
+}
+
+// EmbeddingModel returns the embedding model name
+func (c *Client) EmbeddingModel() string {
+	return c.embedModel
+}
+
+// AudioModel returns the transcription model name
+func (c *Client) AudioModel() string {
+	return c.audioModel
+}
+
+// ChatMessage is a chat message. Content is either a string or a list of ContentPart.
+type ChatMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// ContentPart is one part of a multimodal message
+type ContentPart struct {
+	Type     string    `json:"type"` // text, image_url
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by URL or data URL
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// ChatRequest represents a chat completion request
+type ChatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature float64       `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+	// StreamOptions asks for a trailing usage chunk when streaming
+	StreamOptions map[string]bool `json:"stream_options,omitempty"`
+	// ResponseFormat {"type": "json_object"} enables JSON mode
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+// ChatResponse represents a chat completion response
+type ChatResponse struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   Usage        `json:"usage"`
+}
+
+type ChatChoice struct {
+	Index   int `json:"index"`
+	Message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"message"`
+	FinishReason string `json:"finish_reason"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Chat sends a chat completion request
+func (c *Client) Chat(ctx context.Context, messages []ChatMessage) (*ChatResponse, error) {
+	req := ChatRequest{
+		Model:       c.model,
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   2000,
+	}
+
+	var chatResp ChatResponse
+	if err := c.postJSON(ctx, "/chat/completions", req, &chatResp); err != nil {
+		return nil, err
+	}
+	return &chatResp, nil
+}
+
+// ChatJSON sends a chat completion request in JSON mode
+func (c *Client) ChatJSON(ctx context.Context, messages []ChatMessage) (*ChatResponse, error) {
+	req := ChatRequest{
+		Model:          c.model,
+		Messages:       messages,
+		Temperature:    0.7,
+		MaxTokens:      2000,
+		ResponseFormat: map[string]string{"type": "json_object"},
+	}
+
+	var chatResp ChatResponse
+	if err := c.postJSON(ctx, "/chat/completions", req, &chatResp); err != nil {
+		return nil, err
+	}
+	return &chatResp, nil
+}
+
+// ChatWithImage sends a single user turn containing an image and a text prompt
+func (c *Client) ChatWithImage(ctx context.Context, imageData []byte, mimeType, prompt string) (*ChatResponse, error) {
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
+	dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(imageData))
+
+	return c.Chat(ctx, []ChatMessage{
+		{
+			Role: "user",
+			Content: []ContentPart{
+				{Type: "image_url", ImageURL: &ImageURL{URL: dataURL}},
+				{Type: "text", Text: prompt},
+			},
+		},
+	})
+}
+
+// ChatStream sends a streaming chat completion request (stream: true).
+// onDelta is called for every content fragment; the returned response carries
+// the full content, finish reason and usage.
+func (c *Client) ChatStream(ctx context.Context, messages []ChatMessage, onDelta func(string) error) (*ChatResponse, error) {
+	req := ChatRequest{
+		Model:         c.model,
+		Messages:      messages,
+		Temperature:   0.7,
+		MaxTokens:     2000,
+		Stream:        true,
+		StreamOptions: map[string]bool{"include_usage": true},
+	}
+
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.streamClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var content strings.Builder
+	out := &ChatResponse{Model: c.model}
+	var choice ChatChoice
+	choice.Message.Role = "assistant"
+
+	err = sse.Read(resp.Body, func(ev sse.Event) error {
+		if ev.Data == "[DONE]" {
+			return io.EOF
+		}
+		var chunk struct {
+			Model   string `json:"model"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *Usage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.Usage = *chunk.Usage
+		}
+		for _, ch := range chunk.Choices {
+			if ch.FinishReason != "" {
+				choice.FinishReason = ch.FinishReason
+			}
+			if ch.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(ch.Delta.Content)
+			if err := onDelta(ch.Delta.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	choice.Message.Content = content.String()
+	out.Choices = []ChatChoice{choice}
+	return out, nil
+}
+
+// EmbeddingResponse represents an embedding response
+type EmbeddingResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage Usage  `json:"usage"`
+}
+
+// CreateEmbedding creates an embedding for the given text
+func (c *Client) CreateEmbedding(ctx context.Context, text string) (*EmbeddingResponse, error) {
+	req := map[string]interface{}{
+		"model": c.embedModel,
+		"input": []string{text},
+	}
+
+	var embedResp EmbeddingResponse
+	if err := c.postJSON(ctx, "/embeddings", req, &embedResp); err != nil {
+		return nil, err
+	}
+	return &embedResp, nil
+}
+
+// TranscriptionResponse represents an audio transcription response (verbose_json)
+type TranscriptionResponse struct {
+	Text     string                 `json:"text"`
+	Language string                 `json:"language"`
+	Duration float64                `json:"duration"`
+	Segments []TranscriptionSegment `json:"segments"`
+}
+
+// TranscriptionSegment is a timestamped segment of a verbose_json transcription
+type TranscriptionSegment struct {
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Text       string  `json:"text"`
+	AvgLogprob float64 `json:"avg_logprob"`
+}
+
+// Transcribe transcribes audio through /audio/transcriptions
+func (c *Client) Transcribe(ctx context.Context, audioData []byte, format, language string) (*TranscriptionResponse, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("file", "audio."+format)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(audioData); err != nil {
+		return nil, err
+	}
+	writer.WriteField("model", c.audioModel)
+	writer.WriteField("response_format", "verbose_json")
+	if language != "" {
+		writer.WriteField("language", language)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/audio/transcriptions", body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	respBody, err := c.do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var result TranscriptionResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) postJSON(ctx context.Context, path string, payload interface{}, out interface{}) error {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	respBody, err := c.do(httpReq)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func (c *Client) do(httpReq *http.Request) ([]byte, error) {
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
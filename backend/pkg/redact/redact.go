@@ -0,0 +1,253 @@
+// Package redact 把文本中的个人信息替换为可还原的占位符（如 [PHONE_1]），
+// 发送给外部模型前脱敏，拿到回复后再还原
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Type 个人信息类型
+type Type string
+
+const (
+	Name        Type = "name"
+	Phone       Type = "phone"
+	Email       Type = "email"
+	WeChat      Type = "wechat"
+	IDNumber    Type = "id_number"
+	CreditCode  Type = "credit_code"
+	BankAccount Type = "bank_account"
+)
+
+// Removed 禁止外发的值被替换成的文本，不可还原
+const Removed = "[REMOVED]"
+
+// AllTypes 支持的全部类型
+var AllTypes = []Type{Name, Phone, Email, WeChat, IDNumber, CreditCode, BankAccount}
+
+// 按顺序匹配：先匹配较长、较具体的格式，避免身份证号 / 银行卡号中的一段被当成手机号；
+// 带 86 国家码的手机号和 4 位区号的座机号也有 12-13 位数字，要在银行卡号之前匹配
+var patterns = []struct {
+	typ Type
+	re  *regexp.Regexp
+}{
+	{Email, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{IDNumber, regexp.MustCompile(`\b\d{6}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`)},
+	{CreditCode, regexp.MustCompile(`\b[0-9A-HJ-NPQRTUWXY]{2}\d{6}[0-9A-HJ-NPQRTUWXY]{10}\b`)},
+	{Phone, regexp.MustCompile(`\+?\b86[- ]?1[3-9]\d(?:[- ]?\d{4}){2}\b`)},
+	{Phone, regexp.MustCompile(`\b0\d{2,3}-\d{7,8}\b`)},
+	{BankAccount, regexp.MustCompile(`\b\d{4}(?:[ -]?\d{4}){2,3}(?:[ -]?\d{1,3})?\b`)},
+	{Phone, regexp.MustCompile(`\+?\b(?:86[- ]?)?1[3-9]\d(?:[- ]?\d{4}){2}\b`)},
+}
+
+// placeholderPattern 匹配本包生成的占位符
+var placeholderPattern = regexp.MustCompile(`\[(?:NAME|PHONE|EMAIL|WECHAT|ID_NUMBER|CREDIT_CODE|BANK_ACCOUNT)_\d+\]`)
+
+// maxPlaceholderLen 占位符的最大长度，流式还原时据此判断末尾是否可能是被拆开的占位符
+const maxPlaceholderLen = len("[BANK_ACCOUNT_9999]")
+
+// ParseTypes 校验并转换类型名
+func ParseTypes(names []string) ([]Type, error) {
+	types := make([]Type, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, t := range AllTypes {
+			if string(t) == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown redaction type %q", name)
+		}
+		types = append(types, Type(name))
+	}
+	return types, nil
+}
+
+// Redactor 一次会话内的脱敏映射：同一个值始终对应同一个占位符，便于多轮调用和还原
+type Redactor struct {
+	mu            sync.Mutex
+	enabled       map[Type]bool
+	terms         map[string]Type // 已知的敏感值（如客户姓名），不依赖格式识别
+	dropped       map[string]bool // 禁止外发的值，替换为 Removed
+	byOriginal    map[string]string
+	byPlaceholder map[string]string
+	seq           map[Type]int
+}
+
+// New 只脱敏 types 中的类型
+func New(types []Type) *Redactor {
+	r := &Redactor{
+		enabled:       make(map[Type]bool, len(types)),
+		terms:         make(map[string]Type),
+		dropped:       make(map[string]bool),
+		byOriginal:    make(map[string]string),
+		byPlaceholder: make(map[string]string),
+		seq:           make(map[Type]int),
+	}
+	for _, t := range types {
+		r.enabled[t] = true
+	}
+	return r
+}
+
+// AddTerms 登记已知的敏感值；姓名等无法靠格式识别的信息必须登记后才会被替换
+func (r *Redactor) AddTerms(t Type, values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.enabled[t] {
+		return
+	}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		// 单个字符太容易误伤
+		if len([]rune(v)) < 2 {
+			continue
+		}
+		r.terms[v] = t
+	}
+}
+
+// Drop 登记禁止外发的值，不论是否启用了对应类型都会被替换为 Removed
+func (r *Redactor) Drop(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range values {
+		if v = strings.TrimSpace(v); len([]rune(v)) >= 2 {
+			r.dropped[v] = true
+		}
+	}
+}
+
+// Redact 替换文本中的个人信息
+func (r *Redactor) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range longestFirst(r.dropped) {
+		text = strings.ReplaceAll(text, v, Removed)
+	}
+	// 已知值从长到短替换，避免「张三丰」被「张三」截断
+	for _, v := range longestFirst(r.terms) {
+		if strings.Contains(text, v) {
+			text = strings.ReplaceAll(text, v, r.placeholder(r.terms[v], v))
+		}
+	}
+
+	for _, p := range patterns {
+		if !r.enabled[p.typ] {
+			continue
+		}
+		text = p.re.ReplaceAllStringFunc(text, func(match string) string {
+			return r.placeholder(p.typ, match)
+		})
+	}
+	return text
+}
+
+// Restore 把占位符还原为原值；不认识的占位符保持原样
+func (r *Redactor) Restore(text string) string {
+	return r.restore(text, false)
+}
+
+// RestoreJSON 同 Restore，原值按 JSON 字符串转义，用于还原 JSON 输出
+func (r *Redactor) RestoreJSON(text string) string {
+	return r.restore(text, true)
+}
+
+func (r *Redactor) restore(text string, escapeJSON bool) string {
+	if !strings.Contains(text, "[") {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return placeholderPattern.ReplaceAllStringFunc(text, func(ph string) string {
+		original, ok := r.byPlaceholder[ph]
+		if !ok {
+			return ph
+		}
+		if escapeJSON {
+			quoted, _ := json.Marshal(original)
+			return string(quoted[1 : len(quoted)-1])
+		}
+		return original
+	})
+}
+
+// Counts 每种类型被替换的不同值个数
+func (r *Redactor) Counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int, len(r.seq))
+	for t, n := range r.seq {
+		counts[string(t)] = n
+	}
+	return counts
+}
+
+func (r *Redactor) placeholder(t Type, original string) string {
+	if ph, ok := r.byOriginal[original]; ok {
+		return ph
+	}
+	r.seq[t]++
+	ph := fmt.Sprintf("[%s_%d]", strings.ToUpper(string(t)), r.seq[t])
+	r.byOriginal[original] = ph
+	r.byPlaceholder[ph] = original
+	return ph
+}
+
+func longestFirst[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return keys
+}
+
+// StreamRestorer 流式回复的还原：占位符可能被拆在两个片段里，末尾未闭合的 [ 之后的内容先缓存
+type StreamRestorer struct {
+	r       *Redactor
+	emit    func(string) error
+	pending string
+}
+
+func NewStreamRestorer(r *Redactor, emit func(string) error) *StreamRestorer {
+	return &StreamRestorer{r: r, emit: emit}
+}
+
+func (s *StreamRestorer) Write(delta string) error {
+	buf := s.pending + delta
+	s.pending = ""
+	if i := strings.LastIndex(buf, "["); i != -1 && !strings.Contains(buf[i:], "]") && len(buf)-i < maxPlaceholderLen {
+		s.pending = buf[i:]
+		buf = buf[:i]
+	}
+	if buf == "" {
+		return nil
+	}
+	return s.emit(s.r.Restore(buf))
+}
+
+// Flush 流结束时下发缓存的片段
+func (s *StreamRestorer) Flush() error {
+	buf := s.pending
+	s.pending = ""
+	if buf == "" {
+		return nil
+	}
+	return s.emit(s.r.Restore(buf))
+}
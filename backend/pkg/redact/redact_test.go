@@ -0,0 +1,166 @@
+package redact
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactRoundTrip(t *testing.T) {
+	cases := []struct {
+		name  string
+		types []Type
+		in    string
+		want  string
+	}{
+		{"mobile", AllTypes, "手机13812345678，请回电", "手机[PHONE_1]，请回电"},
+		{"mobile with spaces", AllTypes, "电话 138 1234 5678", "电话 [PHONE_1]"},
+		{"mobile with dashes", AllTypes, "电话 138-1234-5678", "电话 [PHONE_1]"},
+		{"country code", AllTypes, "电话 +86 13812345678", "电话 [PHONE_1]"},
+		{"country code without separator", AllTypes, "电话 +8613812345678", "电话 [PHONE_1]"},
+		{"country code without plus", AllTypes, "电话 86-138-1234-5678", "电话 [PHONE_1]"},
+		{"landline", AllTypes, "座机 0755-86001234", "座机 [PHONE_1]"},
+		{"email", AllTypes, "邮箱 zhang.san+crm@example.com.cn。", "邮箱 [EMAIL_1]。"},
+		{"id number", AllTypes, "身份证 11010519491231002X", "身份证 [ID_NUMBER_1]"},
+		{"credit code", AllTypes, "统一社会信用代码 91350100M000100Y43", "统一社会信用代码 [CREDIT_CODE_1]"},
+		{"bank account", AllTypes, "账号 6222 0212 3456 7890 123", "账号 [BANK_ACCOUNT_1]"},
+		{
+			"same value same placeholder", AllTypes,
+			"13812345678 或 13987654321，首选 13812345678",
+			"[PHONE_1] 或 [PHONE_2]，首选 [PHONE_1]",
+		},
+		{
+			"mixed", AllTypes,
+			"张经理 13812345678 / li@example.com，开户行账号 6222021234567890",
+			"张经理 [PHONE_1] / [EMAIL_1]，开户行账号 [BANK_ACCOUNT_1]",
+		},
+		{"disabled types untouched", []Type{Email}, "13812345678 li@example.com", "13812345678 [EMAIL_1]"},
+		{"nothing enabled", nil, "13812345678 li@example.com", "13812345678 li@example.com"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New(tc.types)
+			got := r.Redact(tc.in)
+			if got != tc.want {
+				t.Fatalf("Redact(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+			if restored := r.Restore(got); restored != tc.in {
+				t.Errorf("Restore(%q) = %q, want %q", got, restored, tc.in)
+			}
+		})
+	}
+}
+
+// 身份证号、银行卡号中间的一段看起来像手机号，不能被单独替换
+func TestRedactNoPartialPhone(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"身份证 513813199003071234", "身份证 [ID_NUMBER_1]"},
+		{"卡号 6222013812345678", "卡号 [BANK_ACCOUNT_1]"},
+		{"卡号 6222-0138-1234-5678-901", "卡号 [BANK_ACCOUNT_1]"},
+	}
+	for _, tc := range cases {
+		r := New(AllTypes)
+		if got := r.Redact(tc.in); got != tc.want {
+			t.Errorf("Redact(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+		if n := r.Counts()[string(Phone)]; n != 0 {
+			t.Errorf("Redact(%q) replaced %d phones", tc.in, n)
+		}
+
+		phoneOnly := New([]Type{Phone})
+		if got := phoneOnly.Redact(tc.in); got != tc.in {
+			t.Errorf("phone only: Redact(%q) = %q, want it unchanged", tc.in, got)
+		}
+	}
+}
+
+func TestRedactTermsLongestFirst(t *testing.T) {
+	r := New([]Type{Name, Phone})
+	r.AddTerms(Name, "张三", " 张三丰 ", "王")
+	r.AddTerms(WeChat, "zhangsan_wx") // 未启用的类型不登记
+
+	in := "张三丰和张三都认识王总，微信 zhangsan_wx"
+	got := r.Redact(in)
+	want := "[NAME_1]和[NAME_2]都认识王总，微信 zhangsan_wx"
+	if got != want {
+		t.Fatalf("Redact = %q, want %q", got, want)
+	}
+	if restored := r.Restore(got); restored != in {
+		t.Errorf("Restore = %q, want %q", restored, in)
+	}
+	if counts := r.Counts(); !reflect.DeepEqual(counts, map[string]int{"name": 2}) {
+		t.Errorf("Counts = %v", counts)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	r := New(nil)
+	r.Drop("内部底价 88 万", "x", "  ")
+	in := "报价时不要提内部底价 88 万，也不要提内部底价 88 万以下"
+	got := r.Redact(in)
+	want := "报价时不要提" + Removed + "，也不要提" + Removed + "以下"
+	if got != want {
+		t.Fatalf("Redact = %q, want %q", got, want)
+	}
+	// 被删除的值不可还原
+	if restored := r.Restore(got); restored != got {
+		t.Errorf("Restore = %q, want %q", restored, got)
+	}
+}
+
+func TestRestoreJSON(t *testing.T) {
+	r := New([]Type{Name, Email})
+	name := `O"Brien\张`
+	r.AddTerms(Name, name)
+	redacted := r.Redact(name + " <ob@example.com>")
+
+	// 模型按占位符原样输出 JSON，还原后仍须是合法的 JSON
+	out := `{"contact":"` + redacted + `","note":"[PHONE_3] 未知"}`
+	var parsed struct {
+		Contact string `json:"contact"`
+		Note    string `json:"note"`
+	}
+	if err := json.Unmarshal([]byte(r.RestoreJSON(out)), &parsed); err != nil {
+		t.Fatalf("RestoreJSON produced invalid JSON: %v", err)
+	}
+	if parsed.Contact != name+" <ob@example.com>" {
+		t.Errorf("contact = %q", parsed.Contact)
+	}
+	if parsed.Note != "[PHONE_3] 未知" {
+		t.Errorf("unknown placeholder should be kept, got %q", parsed.Note)
+	}
+
+	if plain := r.Restore(redacted); plain != name+" <ob@example.com>" {
+		t.Errorf("Restore = %q", plain)
+	}
+}
+
+func TestStreamRestorer(t *testing.T) {
+	r := New([]Type{Phone})
+	redacted := r.Redact("请拨打 13812345678 联系")
+
+	var out strings.Builder
+	s := NewStreamRestorer(r, func(chunk string) error {
+		out.WriteString(chunk)
+		return nil
+	})
+	// 占位符被拆到多个片段里
+	for _, delta := range []string{redacted[:12], redacted[12:16], redacted[16:]} {
+		if err := s.Write(delta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Write(" [未闭合"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "请拨打 13812345678 联系 [未闭合" {
+		t.Errorf("stream = %q", got)
+	}
+}
@@ -0,0 +1,262 @@
+// Package replay 录制 / 回放 HTTP 交互：录制模式把厂商接口的请求和响应写入 JSON 文件，
+// 回放模式按录制内容返回响应，不访问网络，用于离线测试提示词和解析逻辑
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/xia/nextcrm/pkg/redact"
+)
+
+// ErrNoInteraction 回放时找不到匹配的录制
+var ErrNoInteraction = errors.New("no recorded interaction")
+
+// maxStoredBody 超过该长度的请求体（如图片、音频）只保存摘要
+const maxStoredBody = 16 << 10
+
+// Request 录制的请求；不保存请求头，避免泄露密钥
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// BodySHA256 脱敏后请求体的摘要；为空时回放不校验请求体（手写的样例）
+	BodySHA256 string `json:"body_sha256,omitempty"`
+	Body       string `json:"body,omitempty"`
+}
+
+// Response 录制的响应，流式响应保存完整的 SSE 文本
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Interaction 一次请求和对应的响应
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette 按发生顺序保存的交互
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Load 读取录制文件
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save 写入录制文件
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Options 录制 / 回放选项
+type Options struct {
+	// Scrub 在保存和比对前处理请求体与响应体（如替换手机号、邮箱），必须是确定性的，
+	// 回放时对请求体做同样的处理后再比对摘要
+	Scrub func(string) string
+}
+
+// ScrubPII 按格式替换手机号、邮箱、证件号等；姓名无法按格式识别，提交样例前需人工替换
+func ScrubPII(s string) string {
+	return redact.New(redact.AllTypes).Redact(s)
+}
+
+// Transport 实现 http.RoundTripper。录制模式转发给 next 并把交互追加写入文件；
+// 回放模式按方法和路径依次匹配尚未使用的交互
+type Transport struct {
+	cassette *Cassette
+	path     string // 录制模式的文件路径
+	next     http.RoundTripper
+	scrub    func(string) string
+
+	mu   sync.Mutex
+	used []bool
+}
+
+// NewRecorder 录制到 path，文件已存在时在末尾追加；next 为 nil 时使用 http.DefaultTransport
+func NewRecorder(path string, next http.RoundTripper, opts Options) (*Transport, error) {
+	c, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c, err = &Cassette{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{cassette: c, path: path, next: next, scrub: opts.Scrub}, nil
+}
+
+// NewReplayer 按 c 回放
+func NewReplayer(c *Cassette, opts Options) *Transport {
+	return &Transport{cassette: c, scrub: opts.Scrub, used: make([]bool, len(c.Interactions))}
+}
+
+// OpenReplayer 读取 path 并回放
+func OpenReplayer(path string, opts Options) (*Transport, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(c, opts), nil
+}
+
+// Recording 是否为录制模式
+func (t *Transport) Recording() bool {
+	return t.path != ""
+}
+
+// Remaining 回放模式下尚未使用的交互数
+func (t *Transport) Remaining() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, used := range t.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if t.Recording() {
+		return t.record(req, body)
+	}
+	return t.replay(req, body)
+}
+
+func (t *Transport) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	scrubbed := t.scrubbed(string(body))
+	recorded := &Interaction{
+		Request: Request{
+			Method:     req.Method,
+			URL:        req.URL.String(),
+			BodySHA256: bodyHash(scrubbed),
+		},
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        t.scrubbed(string(respBody)),
+		},
+	}
+	if len(scrubbed) <= maxStoredBody {
+		recorded.Request.Body = scrubbed
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, recorded)
+	if err := t.cassette.Save(t.path); err != nil {
+		return nil, fmt.Errorf("failed to save cassette: %w", err)
+	}
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	hash := bodyHash(t.scrubbed(string(body)))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, it := range t.cassette.Interactions {
+		if t.used[i] || !matches(it, req, hash) {
+			continue
+		}
+		t.used[i] = true
+		header := make(http.Header)
+		if it.Response.ContentType != "" {
+			header.Set("Content-Type", it.Response.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", it.Response.Status, http.StatusText(it.Response.Status)),
+			StatusCode:    it.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(it.Response.Body))),
+			ContentLength: int64(len(it.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s %s (body sha256 %s)", ErrNoInteraction, req.Method, req.URL.RequestURI(), hash)
+}
+
+// matches 比较方法、路径和查询参数（不比较主机，便于更换 BaseURL），录制了摘要时再比较请求体
+func matches(it *Interaction, req *http.Request, hash string) bool {
+	if it.Request.Method != req.Method {
+		return false
+	}
+	if u, err := req.URL.Parse(it.Request.URL); err != nil || u.RequestURI() != req.URL.RequestURI() {
+		return false
+	}
+	return it.Request.BodySHA256 == "" || it.Request.BodySHA256 == hash
+}
+
+func (t *Transport) scrubbed(s string) string {
+	if t.scrub == nil {
+		return s
+	}
+	return t.scrub(s)
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func bodyHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,120 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func post(t *testing.T, client *http.Client, url, body string) (string, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-key")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), nil
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doubao.json")
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"echo":` + string(body) + `,"phone":"13800138000"}`)),
+		}, nil
+	})
+
+	rec, err := NewRecorder(path, upstream, Options{Scrub: ScrubPII})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := post(t, &http.Client{Transport: rec}, "https://ark.example.com/api/v3/responses", `"call 13900139000"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 录制不影响调用方拿到的原始响应
+	if !strings.Contains(got, "13800138000") {
+		t.Fatalf("recorder changed the live response: %s", got)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 1 {
+		t.Fatalf("recorded %d interactions, want 1", len(c.Interactions))
+	}
+	it := c.Interactions[0]
+	if strings.Contains(it.Request.Body, "13900139000") || strings.Contains(it.Response.Body, "13800138000") {
+		t.Fatalf("phone numbers were not scrubbed: %+v", it)
+	}
+
+	// 回放：主机不同也能匹配，请求体按同样的规则脱敏后比对
+	rep, err := OpenReplayer(path, Options{Scrub: ScrubPII})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rep}
+	got, err = post(t, client, "http://localhost/api/v3/responses", `"call 13900139000"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != it.Response.Body {
+		t.Fatalf("replayed %q, want %q", got, it.Response.Body)
+	}
+	if rep.Remaining() != 0 {
+		t.Fatalf("remaining %d, want 0", rep.Remaining())
+	}
+
+	// 每条录制只回放一次
+	if _, err := post(t, client, "http://localhost/api/v3/responses", `"call 13900139000"`); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("second replay: got %v, want ErrNoInteraction", err)
+	}
+}
+
+func TestReplayMatchesBody(t *testing.T) {
+	c := &Cassette{Interactions: []*Interaction{
+		{Request: Request{Method: http.MethodPost, URL: "/v1/chat", BodySHA256: bodyHash(`"b"`)}, Response: Response{Status: 200, Body: "second"}},
+		{Request: Request{Method: http.MethodPost, URL: "/v1/chat", BodySHA256: bodyHash(`"a"`)}, Response: Response{Status: 200, Body: "first"}},
+		{Request: Request{Method: http.MethodPost, URL: "/v1/chat"}, Response: Response{Status: 500, Body: "any"}},
+	}}
+	client := &http.Client{Transport: NewReplayer(c, Options{})}
+
+	for _, tc := range []struct{ body, want string }{
+		{`"a"`, "first"},
+		{`"b"`, "second"},
+		{`"c"`, "any"}, // 手写的样例没有摘要，只按方法和路径匹配
+	} {
+		got, err := post(t, client, "https://api.example.com/v1/chat", tc.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("body %s: got %q, want %q", tc.body, got, tc.want)
+		}
+	}
+	if _, err := post(t, client, "https://api.example.com/v1/embeddings", `"a"`); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("unknown path: got %v, want ErrNoInteraction", err)
+	}
+}
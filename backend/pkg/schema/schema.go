@@ -0,0 +1,202 @@
+// Package schema implements the subset of JSON Schema used to validate
+// structured AI output: object / array / string / integer / number / boolean,
+// required properties, enums, numeric ranges and array length.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Schema is a JSON Schema node. It marshals to standard JSON Schema so it can
+// be embedded in prompts.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"` // 为空表示任意类型
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// Object builds an object schema; every listed property is required unless
+// it is named in optional.
+func Object(props map[string]*Schema, optional ...string) *Schema {
+	skip := make(map[string]bool, len(optional))
+	for _, name := range optional {
+		skip[name] = true
+	}
+	required := make([]string, 0, len(props))
+	for name := range props {
+		if !skip[name] {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	return &Schema{Type: "object", Properties: props, Required: required}
+}
+
+// String builds a string schema, optionally restricted to enum values
+func String(enum ...string) *Schema {
+	return &Schema{Type: "string", Enum: enum}
+}
+
+// Integer builds an integer schema within [min, max]
+func Integer(min, max float64) *Schema {
+	return &Schema{Type: "integer", Minimum: &min, Maximum: &max}
+}
+
+// Number builds a number schema within [min, max]
+func Number(min, max float64) *Schema {
+	return &Schema{Type: "number", Minimum: &min, Maximum: &max}
+}
+
+// Array builds an array schema; maxItems 0 means unbounded
+func Array(items *Schema, minItems, maxItems int) *Schema {
+	s := &Schema{Type: "array", Items: items}
+	if minItems > 0 {
+		s.MinItems = &minItems
+	}
+	if maxItems > 0 {
+		s.MaxItems = &maxItems
+	}
+	return s
+}
+
+// String returns the schema as indented JSON
+func (s *Schema) String() string {
+	b, _ := json.MarshalIndent(s, "", "  ")
+	return string(b)
+}
+
+// ValidateJSON decodes data and validates it, returning every problem found
+func (s *Schema) ValidateJSON(data []byte) []string {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []string{"invalid JSON: " + err.Error()}
+	}
+	return s.Validate(v)
+}
+
+// Validate checks a value decoded by encoding/json
+func (s *Schema) Validate(v interface{}) []string {
+	var problems []string
+	s.validate("$", v, &problems)
+	return problems
+}
+
+func (s *Schema) validate(path string, v interface{}, problems *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("expected object, got %s", typeName(v))
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(path+"."+name, obj[name], problems)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(path+"."+name, obj[name], problems)
+			}
+		}
+
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			fail("expected array, got %s", typeName(v))
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			fail("expected at least %d items, got %d", *s.MinItems, len(arr))
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			fail("expected at most %d items, got %d", *s.MaxItems, len(arr))
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("expected string, got %s", typeName(v))
+			return
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			fail("must be one of %s, got %q", strings.Join(s.Enum, ", "), str)
+		}
+
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			fail("expected %s, got %s", s.Type, typeName(v))
+			return
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			fail("expected integer, got %v", n)
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			fail("must be >= %v, got %v", *s.Minimum, n)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			fail("must be <= %v, got %v", *s.Maximum, n)
+		}
+
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("expected boolean, got %s", typeName(v))
+		}
+	}
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Event is one Server-Sent Event
+type Event struct {
+	Event string
+	Data  string
+}
+
+// Read parses a text/event-stream body and calls fn for every event.
+// Multi-line data fields are joined with "\n". Returning an error from fn stops reading.
+func Read(r io.Reader, fn func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		ev := Event{Event: event, Data: strings.Join(data, "\n")}
+		event, data = "", nil
+		return fn(ev)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
@@ -2,7 +2,7 @@ package volcengine
 
 import (
 	"bytes"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -47,6 +47,11 @@ type SpeechRecognitionResponse struct {
 
 // Recognize 识别语音（使用录音文件识别 API）
 func (c *ASRClient) Recognize(audioData []byte, format, language string) (*SpeechRecognitionResult, error) {
+	return c.RecognizeContext(context.Background(), audioData, format, language)
+}
+
+// RecognizeContext 同 Recognize，请求随 ctx 取消
+func (c *ASRClient) RecognizeContext(ctx context.Context, audioData []byte, format, language string) (*SpeechRecognitionResult, error) {
 	// 构造查询参数
 	query := url.Values{}
 	query.Set("app_id", c.AppID)
@@ -59,7 +64,7 @@ func (c *ASRClient) Recognize(audioData []byte, format, language string) (*Speec
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
-	part, err := writer.CreateFormFile("audio", "audio.webm")
+	part, err := writer.CreateFormFile("audio", "audio."+format)
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +86,7 @@ func (c *ASRClient) Recognize(audioData []byte, format, language string) (*Speec
 
 	// 使用 HTTP 客户端直接发送
 	reqURL := fmt.Sprintf("https://%s%s?%s", host, path, query.Encode())
-	req, err := http.NewRequest("POST", reqURL, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +128,7 @@ func (c *ASRClient) Recognize(audioData []byte, format, language string) (*Speec
 	return &SpeechRecognitionResult{
 		Text:       asrResp.Result,
 		Duration:   float64(asrResp.Duration) / 1000.0, // 转换为秒
-		Confidence: 0.95, // 火山引擎不返回置信度，使用默认值
+		Confidence: 0, // 火山引擎不返回置信度
 	}, nil
 }
 
@@ -1,16 +1,12 @@
 package volcengine
 
 import (
-	"bytes"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
-	"strings"
 	"time"
 )
 
@@ -35,6 +31,11 @@ func NewVolcEngineClient(accessKeyID, accessKeySecret, region string) *VolcEngin
 	}
 }
 
+// SetTransport 替换底层 HTTP 传输（如录制 / 回放）
+func (c *VolcEngineClient) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
 // generateSignature 生成火山引擎 API 签名
 func (c *VolcEngineClient) generateSignature(method, queryPath, query string, timestamp int64) string {
 	// 构造待签名字符串
@@ -2,13 +2,12 @@ package volcengine
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"net/url"
+	"time"
 )
 
 // OCRClient OCR 客户端
@@ -37,30 +36,10 @@ type BusinessCardResult struct {
 	Phone     string  `json:"phone"`
 	Email     string  `json:"email"`
 	Address   string  `json:"address"`
-	Confidence float64 `json:"confidence"`
 }
 
 // RecognizeBusinessCard 识别名片（使用通用 OCR + 结构化解析）
 func (c *OCRClient) RecognizeBusinessCard(imageData []byte) (*BusinessCardResult, error) {
-	// 将图片编码为 base64
-	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
-
-	// 构造请求体
-	requestBody := map[string]interface{}{
-		"app_id":  c.AppID,
-		"image":   imageBase64,
-		"service": "BusinessCardScene", // 使用名片识别场景
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, err
-	}
-
-	// 构造查询参数
-	query := url.Values{}
-	query.Set("a_id", c.AppID)
-
 	// 发送请求
 	host := "visual.volcengineapi.com"
 	path := "/api/v1/ocr/business_card"
@@ -154,6 +133,5 @@ func (c *OCRClient) RecognizeBusinessCard(imageData []byte) (*BusinessCardResult
 		Phone:     card.Phone,
 		Email:     card.Email,
 		Address:   card.Address,
-		Confidence: 0.90, // 默认置信度
 	}, nil
 }